@@ -0,0 +1,49 @@
+package agentclient
+
+import (
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+)
+
+// AgentClient adds the agent messages bosh-init needs to the ones of the vendored bosh-agent client,
+// so that the vendored client stays as it is upstream.
+type AgentClient interface {
+	biagentclient.AgentClient
+
+	// GetFullState returns the state of the agent including its processes, its vitals and the last applied spec.
+	// GetState only asks for the job state and the networks, which is all a deploy needs.
+	GetFullState() (AgentState, error)
+
+	// FetchLogs bundles the logs matching the filters into the blobstore
+	FetchLogs(logType string, filters []string) (logsRef biagentclient.BlobRef, err error)
+
+	// Drain runs the drain scripts of the jobs. The "update" drain type is sent with the spec that will be applied,
+	// "shutdown" and "status" without one.
+	Drain(drainType string, newSpec ...bias.ApplySpec) (waitTime int64, err error)
+}
+
+// AgentState is the full state of the agent
+type AgentState struct {
+	AgentID      string
+	JobState     string
+	NetworkSpecs map[string]biagentclient.NetworkSpec
+	Processes    []ProcessState
+	Vitals       Vitals
+	// ApplySpec is the spec last applied to the agent, without its networks (see NetworkSpecs).
+	// It is empty if nothing was applied yet.
+	ApplySpec bias.ApplySpec
+}
+
+type ProcessState struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+type Vitals struct {
+	Disk map[string]DiskVitals `json:"disk"`
+}
+
+type DiskVitals struct {
+	Percent      string `json:"percent"`
+	InodePercent string `json:"inode_percent"`
+}
@@ -0,0 +1,137 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	"github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	fakebiagentclient "github.com/cloudfoundry/bosh-agent/agentclient/fakes"
+	"github.com/cloudfoundry/bosh-init/agentclient"
+)
+
+type FakeAgentClient struct {
+	fakebiagentclient.FakeAgentClient
+	GetFullStateStub        func() (agentclient.AgentState, error)
+	getFullStateMutex       sync.RWMutex
+	getFullStateArgsForCall []struct{}
+	getFullStateReturns     struct {
+		result1 agentclient.AgentState
+		result2 error
+	}
+	FetchLogsStub        func(logType string, filters []string) (biagentclient.BlobRef, error)
+	fetchLogsMutex       sync.RWMutex
+	fetchLogsArgsForCall []struct {
+		logType string
+		filters []string
+	}
+	fetchLogsReturns struct {
+		result1 biagentclient.BlobRef
+		result2 error
+	}
+	DrainStub        func(drainType string, newSpec ...applyspec.ApplySpec) (int64, error)
+	drainMutex       sync.RWMutex
+	drainArgsForCall []struct {
+		drainType string
+		newSpec   []applyspec.ApplySpec
+	}
+	drainReturns struct {
+		result1 int64
+		result2 error
+	}
+}
+
+var _ agentclient.AgentClient = new(FakeAgentClient)
+
+func (fake *FakeAgentClient) GetFullState() (agentclient.AgentState, error) {
+	fake.getFullStateMutex.Lock()
+	fake.getFullStateArgsForCall = append(fake.getFullStateArgsForCall, struct{}{})
+	fake.getFullStateMutex.Unlock()
+	if fake.GetFullStateStub != nil {
+		return fake.GetFullStateStub()
+	} else {
+		return fake.getFullStateReturns.result1, fake.getFullStateReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) GetFullStateCallCount() int {
+	fake.getFullStateMutex.RLock()
+	defer fake.getFullStateMutex.RUnlock()
+	return len(fake.getFullStateArgsForCall)
+}
+
+func (fake *FakeAgentClient) GetFullStateReturns(result1 agentclient.AgentState, result2 error) {
+	fake.GetFullStateStub = nil
+	fake.getFullStateReturns = struct {
+		result1 agentclient.AgentState
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentClient) FetchLogs(logType string, filters []string) (biagentclient.BlobRef, error) {
+	fake.fetchLogsMutex.Lock()
+	fake.fetchLogsArgsForCall = append(fake.fetchLogsArgsForCall, struct {
+		logType string
+		filters []string
+	}{logType, filters})
+	fake.fetchLogsMutex.Unlock()
+	if fake.FetchLogsStub != nil {
+		return fake.FetchLogsStub(logType, filters)
+	} else {
+		return fake.fetchLogsReturns.result1, fake.fetchLogsReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) FetchLogsCallCount() int {
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	return len(fake.fetchLogsArgsForCall)
+}
+
+func (fake *FakeAgentClient) FetchLogsArgsForCall(i int) (string, []string) {
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	return fake.fetchLogsArgsForCall[i].logType, fake.fetchLogsArgsForCall[i].filters
+}
+
+func (fake *FakeAgentClient) FetchLogsReturns(result1 biagentclient.BlobRef, result2 error) {
+	fake.FetchLogsStub = nil
+	fake.fetchLogsReturns = struct {
+		result1 biagentclient.BlobRef
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentClient) Drain(drainType string, newSpec ...applyspec.ApplySpec) (int64, error) {
+	fake.drainMutex.Lock()
+	fake.drainArgsForCall = append(fake.drainArgsForCall, struct {
+		drainType string
+		newSpec   []applyspec.ApplySpec
+	}{drainType, newSpec})
+	fake.drainMutex.Unlock()
+	if fake.DrainStub != nil {
+		return fake.DrainStub(drainType, newSpec...)
+	} else {
+		return fake.drainReturns.result1, fake.drainReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) DrainCallCount() int {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	return len(fake.drainArgsForCall)
+}
+
+func (fake *FakeAgentClient) DrainArgsForCall(i int) (string, []applyspec.ApplySpec) {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	return fake.drainArgsForCall[i].drainType, fake.drainArgsForCall[i].newSpec
+}
+
+func (fake *FakeAgentClient) DrainReturns(result1 int64, result2 error) {
+	fake.DrainStub = nil
+	fake.drainReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"fmt"
+	"time"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
+)
+
+type agentClient struct {
+	biagentclient.AgentClient
+
+	agentRequest        agentRequest
+	getTaskDelay        time.Duration
+	toleratedErrorCount int
+	interruptCh         <-chan struct{}
+	logger              boshlog.Logger
+	logTag              string
+}
+
+func NewAgentClient(
+	endpoint string,
+	directorID string,
+	getTaskDelay time.Duration,
+	toleratedErrorCount int,
+	httpClient httpclient.HTTPClient,
+	interruptCh <-chan struct{},
+	logger boshlog.Logger,
+) biagent.AgentClient {
+	agentRequest := agentRequest{
+		directorID: directorID,
+		endpoint:   fmt.Sprintf("%s/agent", endpoint),
+		httpClient: httpClient,
+	}
+	return &agentClient{
+		AgentClient:         bihttpagent.NewAgentClient(endpoint, directorID, getTaskDelay, toleratedErrorCount, httpClient, logger),
+		agentRequest:        agentRequest,
+		getTaskDelay:        getTaskDelay,
+		toleratedErrorCount: toleratedErrorCount,
+		interruptCh:         interruptCh,
+		logger:              logger,
+		logTag:              "httpAgentClient",
+	}
+}
+
+func (c *agentClient) GetFullState() (biagent.AgentState, error) {
+	var response FullStateResponse
+
+	getStateRetryable := boshretry.NewRetryable(func() (bool, error) {
+		err := c.agentRequest.Send("get_state", []interface{}{"full"}, &response)
+		if err != nil {
+			return true, bosherr.WrapError(err, "Sending get_state to the agent")
+		}
+		return false, nil
+	})
+
+	attemptRetryStrategy := boshretry.NewAttemptRetryStrategy(3, c.getTaskDelay, getStateRetryable, c.logger)
+	err := attemptRetryStrategy.Try()
+	if err != nil {
+		return biagent.AgentState{}, bosherr.WrapError(err, "Sending get_state to the agent")
+	}
+
+	agentState := biagent.AgentState{
+		AgentID:      response.Value.AgentID,
+		JobState:     response.Value.JobState,
+		NetworkSpecs: response.Value.NetworkSpecs,
+		Processes:    response.Value.Processes,
+		Vitals:       response.Value.Vitals,
+		ApplySpec:    response.Value.ApplySpec,
+	}
+
+	return agentState, nil
+}
+
+func (c *agentClient) FetchLogs(logType string, filters []string) (biagentclient.BlobRef, error) {
+	responseValue, err := c.sendAsyncTaskMessage("fetch_logs", []interface{}{logType, filters})
+	if err != nil {
+		return biagentclient.BlobRef{}, bosherr.WrapError(err, "Sending 'fetch_logs' to the agent")
+	}
+
+	result, ok := responseValue.(map[string]interface{})
+	if !ok {
+		return biagentclient.BlobRef{}, bosherr.Errorf("Unable to parse 'fetch_logs' response from the agent: %#v", responseValue)
+	}
+
+	blobstoreID, ok := result["blobstore_id"].(string)
+	if !ok {
+		return biagentclient.BlobRef{}, bosherr.Errorf("Unable to parse 'fetch_logs' response from the agent: %#v", responseValue)
+	}
+
+	// older agents do not report the sha1 of the logs bundle
+	sha1, _ := result["sha1"].(string)
+
+	return biagentclient.BlobRef{
+		BlobstoreID: blobstoreID,
+		SHA1:        sha1,
+	}, nil
+}
+
+func (c *agentClient) Drain(drainType string, newSpec ...bias.ApplySpec) (int64, error) {
+	arguments := []interface{}{drainType}
+	for _, spec := range newSpec {
+		arguments = append(arguments, spec)
+	}
+
+	responseValue, err := c.sendAsyncTaskMessage("drain", arguments)
+	if err != nil {
+		return 0, err
+	}
+
+	waitTime, ok := responseValue.(float64)
+	if !ok {
+		return 0, bosherr.Errorf("Unable to parse 'drain' response from the agent: %#v", responseValue)
+	}
+
+	return int64(waitTime), nil
+}
+
+// CompilePackage replaces the one of the vendored agent client so that waiting for the compilation stops on interrupt
+func (c *agentClient) CompilePackage(packageSource biagentclient.BlobRef, compiledPackageDependencies []biagentclient.BlobRef) (biagentclient.BlobRef, error) {
+	dependencies := make(map[string]bihttpagent.BlobRef, len(compiledPackageDependencies))
+	for _, dependency := range compiledPackageDependencies {
+		dependencies[dependency.Name] = bihttpagent.BlobRef{
+			Name:        dependency.Name,
+			Version:     dependency.Version,
+			SHA1:        dependency.SHA1,
+			BlobstoreID: dependency.BlobstoreID,
+		}
+	}
+
+	arguments := []interface{}{
+		packageSource.BlobstoreID,
+		packageSource.SHA1,
+		packageSource.Name,
+		packageSource.Version,
+		dependencies,
+	}
+
+	responseValue, err := c.sendAsyncTaskMessage("compile_package", arguments)
+	if err != nil {
+		return biagentclient.BlobRef{}, bosherr.WrapError(err, "Sending 'compile_package' to the agent")
+	}
+
+	response, ok := responseValue.(map[string]interface{})
+	if !ok {
+		return biagentclient.BlobRef{}, bosherr.Errorf("Unable to parse 'compile_package' response from the agent: %#v", responseValue)
+	}
+
+	result, ok := response["result"].(map[string]interface{})
+	if !ok {
+		return biagentclient.BlobRef{}, bosherr.Errorf("Unable to parse 'compile_package' response from the agent: %#v", responseValue)
+	}
+
+	sha1, ok := result["sha1"].(string)
+	if !ok {
+		return biagentclient.BlobRef{}, bosherr.Errorf("Unable to parse 'compile_package' response from the agent: %#v", responseValue)
+	}
+
+	blobstoreID, ok := result["blobstore_id"].(string)
+	if !ok {
+		return biagentclient.BlobRef{}, bosherr.Errorf("Unable to parse 'compile_package' response from the agent: %#v", responseValue)
+	}
+
+	return biagentclient.BlobRef{
+		Name:        packageSource.Name,
+		Version:     packageSource.Version,
+		SHA1:        sha1,
+		BlobstoreID: blobstoreID,
+	}, nil
+}
+
+// MigrateDisk replaces the one of the vendored agent client so that waiting for the migration stops on interrupt
+func (c *agentClient) MigrateDisk() error {
+	_, err := c.sendAsyncTaskMessage("migrate_disk", []interface{}{})
+	return err
+}
+
+// sendAsyncTaskMessage sends the message and polls the agent task until it is done, like the vendored agent client.
+// Polling stops once the process is interrupted, the agent task keeps running.
+func (c *agentClient) sendAsyncTaskMessage(method string, arguments []interface{}) (value interface{}, err error) {
+	var response bihttpagent.TaskResponse
+	err = c.agentRequest.Send(method, arguments, &response)
+	if err != nil {
+		return value, bosherr.WrapErrorf(err, "Sending '%s' to the agent", method)
+	}
+
+	agentTaskID, err := response.TaskID()
+	if err != nil {
+		return value, bosherr.WrapError(err, "Getting agent task id")
+	}
+
+	sendErrors := 0
+	getTaskRetryable := boshretry.NewRetryable(func() (bool, error) {
+		select {
+		case <-c.interruptCh:
+			return false, biui.NewInterruptedWaitError(fmt.Sprintf("waiting for agent task '%s'", method))
+		default:
+		}
+
+		var response bihttpagent.TaskResponse
+		err = c.agentRequest.Send("get_task", []interface{}{agentTaskID}, &response)
+		if err != nil {
+			sendErrors++
+			shouldRetry := sendErrors <= c.toleratedErrorCount
+			err = bosherr.WrapError(err, "Sending 'get_task' to the agent")
+			c.logger.Debug(c.logTag, "Error occured sending get_task. Error retry %d of %d: %s", sendErrors, c.toleratedErrorCount, err.Error())
+			return shouldRetry, err
+		}
+		sendErrors = 0
+
+		c.logger.Debug(c.logTag, "get_task response value: %#v", response.Value)
+
+		taskState, err := response.TaskState()
+		if err != nil {
+			return false, bosherr.WrapError(err, "Getting task state")
+		}
+
+		if taskState != "running" {
+			value = response.Value
+			return true, nil
+		}
+
+		return true, bosherr.Errorf("Task %s is still running", method)
+	})
+
+	getTaskRetryStrategy := boshretry.NewUnlimitedRetryStrategy(c.getTaskDelay, getTaskRetryable, c.logger)
+	err = getTaskRetryStrategy.Try()
+	return value, err
+}
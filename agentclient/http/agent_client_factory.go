@@ -0,0 +1,38 @@
+package http
+
+//go:generate mockgen -source=agent_client_factory.go -package=mocks -destination=mocks/mocks.go
+
+import (
+	"time"
+
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type AgentClientFactory interface {
+	NewAgentClient(directorID, mbusURL string) biagent.AgentClient
+}
+
+type agentClientFactory struct {
+	getTaskDelay time.Duration
+	interruptCh  <-chan struct{}
+	logger       boshlog.Logger
+}
+
+func NewAgentClientFactory(
+	getTaskDelay time.Duration,
+	interruptCh <-chan struct{},
+	logger boshlog.Logger,
+) AgentClientFactory {
+	return &agentClientFactory{
+		getTaskDelay: getTaskDelay,
+		interruptCh:  interruptCh,
+		logger:       logger,
+	}
+}
+
+func (f *agentClientFactory) NewAgentClient(directorID, mbusURL string) biagent.AgentClient {
+	httpClient := httpclient.NewHTTPClient(httpclient.DefaultClient, f.logger)
+	return NewAgentClient(mbusURL, directorID, f.getTaskDelay, 10, httpClient, f.interruptCh, f.logger)
+}
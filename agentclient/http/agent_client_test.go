@@ -0,0 +1,358 @@
+package http_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-init/agentclient/http"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	"github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	"github.com/cloudfoundry/bosh-init/agentclient"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+
+	fakehttpclient "github.com/cloudfoundry/bosh-utils/httpclient/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("AgentClient", func() {
+	var (
+		fakeHTTPClient *fakehttpclient.FakeHTTPClient
+		agentClient    agentclient.AgentClient
+
+		agentAddress   string
+		replyToAddress string
+		interruptCh    chan struct{}
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fakeHTTPClient = fakehttpclient.NewFakeHTTPClient()
+
+		agentAddress = "http://localhost:6305"
+		replyToAddress = "fake-reply-to-uuid"
+
+		getTaskDelay := time.Duration(0)
+		toleratedErrorCount := 2
+
+		interruptCh = make(chan struct{})
+
+		agentClient = NewAgentClient(agentAddress, replyToAddress, getTaskDelay, toleratedErrorCount, fakeHTTPClient, interruptCh, logger)
+	})
+
+	var sentRequest = func(i int) bihttpagent.AgentRequestMessage {
+		var request bihttpagent.AgentRequestMessage
+		err := json.Unmarshal(fakeHTTPClient.PostInputs[i].Payload, &request)
+		Expect(err).ToNot(HaveOccurred())
+		return request
+	}
+
+	It("sends the messages of the vendored agent client to the agent endpoint", func() {
+		fakeHTTPClient.SetPostBehavior(`{"value":"pong"}`, 200, nil)
+
+		response, err := agentClient.Ping()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response).To(Equal("pong"))
+		Expect(fakeHTTPClient.PostInputs[0].Endpoint).To(Equal("http://localhost:6305/agent"))
+	})
+
+	Describe("GetState", func() {
+		It("asks the agent for the short state used by the deploy", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"job_state":"running"}}`, 200, nil)
+
+			_, err := agentClient.GetState()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sentRequest(0).Arguments).To(Equal([]interface{}{}))
+		})
+	})
+
+	Describe("GetFullState", func() {
+		Context("when agent responds with a value", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_id":"fake-agent-id","job_state":"running","networks":{"private":{"ip":"192.0.2.10"},"public":{"ip":"192.0.3.11"}},"processes":[{"name":"fake-process","state":"running"}],"vitals":{"disk":{"persistent":{"percent":"12","inode_percent":"3"}}}}}`, 200, nil)
+			})
+
+			It("makes a get_state request for the full state", func() {
+				stateResponse, err := agentClient.GetFullState()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stateResponse).To(Equal(agentclient.AgentState{
+					AgentID:  "fake-agent-id",
+					JobState: "running",
+					NetworkSpecs: map[string]biagentclient.NetworkSpec{
+						"private": {IP: "192.0.2.10"},
+						"public":  {IP: "192.0.3.11"},
+					},
+					Processes: []agentclient.ProcessState{
+						{Name: "fake-process", State: "running"},
+					},
+					Vitals: agentclient.Vitals{
+						Disk: map[string]agentclient.DiskVitals{
+							"persistent": {Percent: "12", InodePercent: "3"},
+						},
+					},
+				}))
+
+				Expect(fakeHTTPClient.PostInputs).To(HaveLen(1))
+				Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+					Method:    "get_state",
+					Arguments: []interface{}{"full"},
+					ReplyTo:   replyToAddress,
+				}))
+			})
+		})
+
+		Context("when the agent has an applied spec", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_id":"fake-agent-id","job_state":"running","deployment":"fake-deployment-name","name":"fake-instance-name","index":0,"id":"fake-node-id","job":{"name":"fake-job-name","templates":[{"name":"fake-template-name","version":"fake-template-version"}]},"networks":{"private":{"ip":"192.0.2.10","type":"manual"}},"configuration_hash":"fake-configuration-hash"}}`, 200, nil)
+			})
+
+			It("includes the applied spec, with the networks only in the network specs", func() {
+				stateResponse, err := agentClient.GetFullState()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stateResponse.NetworkSpecs).To(Equal(map[string]biagentclient.NetworkSpec{"private": {IP: "192.0.2.10"}}))
+				Expect(stateResponse.ApplySpec).To(Equal(applyspec.ApplySpec{
+					Deployment: "fake-deployment-name",
+					Name:       "fake-instance-name",
+					Index:      0,
+					NodeID:     "fake-node-id",
+					Job: applyspec.Job{
+						Name: "fake-job-name",
+						Templates: []applyspec.Blob{
+							{Name: "fake-template-name", Version: "fake-template-version"},
+						},
+					},
+					ConfigurationHash: "fake-configuration-hash",
+				}))
+			})
+		})
+
+		Context("when the agent responds with an error", func() {
+			BeforeEach(func() {
+				for i := 0; i < 3; i++ {
+					fakeHTTPClient.SetPostBehavior(`{"exception":{"message":"fake-state-error"}}`, 200, nil)
+				}
+			})
+
+			It("returns an error after retrying", func() {
+				_, err := agentClient.GetFullState()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-state-error"))
+				Expect(fakeHTTPClient.PostInputs).To(HaveLen(3))
+			})
+		})
+	})
+
+	Describe("MigrateDisk", func() {
+		BeforeEach(func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+		})
+
+		It("makes a migrate_disk request and waits for the agent task", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{}}`, 200, nil)
+
+			err := agentClient.MigrateDisk()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "migrate_disk",
+				Arguments: []interface{}{},
+				ReplyTo:   replyToAddress,
+			}))
+			Expect(sentRequest(1).Method).To(Equal("get_task"))
+		})
+
+		It("stops waiting for the agent task once interrupted", func() {
+			close(interruptCh)
+
+			err := agentClient.MigrateDisk()
+			Expect(err).To(HaveOccurred())
+			Expect(biui.IsInterrupted(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("Interrupted while waiting for agent task 'migrate_disk'"))
+			Expect(fakeHTTPClient.PostInputs).To(HaveLen(1))
+		})
+	})
+
+	Describe("CompilePackage", func() {
+		var (
+			packageSource biagentclient.BlobRef
+			dependencies  []biagentclient.BlobRef
+		)
+
+		BeforeEach(func() {
+			packageSource = biagentclient.BlobRef{
+				Name:        "fake-package-name",
+				Version:     "fake-package-version",
+				SHA1:        "fake-package-sha1",
+				BlobstoreID: "fake-package-blobstore-id",
+			}
+			dependencies = []biagentclient.BlobRef{
+				{
+					Name:        "fake-dependency-name",
+					Version:     "fake-dependency-version",
+					SHA1:        "fake-dependency-sha1",
+					BlobstoreID: "fake-dependency-blobstore-id",
+				},
+			}
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+		})
+
+		It("makes a compile_package request and returns the compiled package", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"result":{"sha1":"fake-compiled-sha1","blobstore_id":"fake-compiled-blobstore-id"}}}`, 200, nil)
+
+			compiledPackageRef, err := agentClient.CompilePackage(packageSource, dependencies)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compiledPackageRef).To(Equal(biagentclient.BlobRef{
+				Name:        "fake-package-name",
+				Version:     "fake-package-version",
+				SHA1:        "fake-compiled-sha1",
+				BlobstoreID: "fake-compiled-blobstore-id",
+			}))
+
+			Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method: "compile_package",
+				Arguments: []interface{}{
+					"fake-package-blobstore-id",
+					"fake-package-sha1",
+					"fake-package-name",
+					"fake-package-version",
+					map[string]interface{}{
+						"fake-dependency-name": map[string]interface{}{
+							"name":         "fake-dependency-name",
+							"version":      "fake-dependency-version",
+							"sha1":         "fake-dependency-sha1",
+							"blobstore_id": "fake-dependency-blobstore-id",
+						},
+					},
+				},
+				ReplyTo: replyToAddress,
+			}))
+		})
+
+		It("returns an error when the response has no result", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{}}`, 200, nil)
+
+			_, err := agentClient.CompilePackage(packageSource, dependencies)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unable to parse 'compile_package' response from the agent"))
+		})
+
+		It("stops waiting for the compilation once interrupted", func() {
+			close(interruptCh)
+
+			_, err := agentClient.CompilePackage(packageSource, dependencies)
+			Expect(err).To(HaveOccurred())
+			Expect(biui.IsInterrupted(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("Interrupted while waiting for agent task 'compile_package'"))
+		})
+	})
+
+	Describe("FetchLogs", func() {
+		Context("when agent responds with the logs bundle", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				fakeHTTPClient.SetPostBehavior(`{"value":{"blobstore_id":"fake-logs-blobstore-id","sha1":"fake-logs-sha1"}}`, 200, nil)
+			})
+
+			It("makes a fetch_logs request and waits for the task to be done", func() {
+				logsRef, err := agentClient.FetchLogs("job", []string{"fake-job/**/*"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(logsRef).To(Equal(biagentclient.BlobRef{
+					BlobstoreID: "fake-logs-blobstore-id",
+					SHA1:        "fake-logs-sha1",
+				}))
+
+				Expect(fakeHTTPClient.PostInputs).To(HaveLen(2))
+
+				Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+					Method:    "fetch_logs",
+					Arguments: []interface{}{"job", []interface{}{"fake-job/**/*"}},
+					ReplyTo:   replyToAddress,
+				}))
+			})
+		})
+
+		Context("when agent response does not contain a blobstore id", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				fakeHTTPClient.SetPostBehavior(`{"value":{}}`, 200, nil)
+			})
+
+			It("returns an error", func() {
+				_, err := agentClient.FetchLogs("job", []string{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unable to parse 'fetch_logs' response"))
+			})
+		})
+	})
+
+	Describe("Drain", func() {
+		Context("when agent responds with a wait time", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				fakeHTTPClient.SetPostBehavior(`{"value":-10}`, 200, nil)
+			})
+
+			It("makes a drain request and returns the wait time", func() {
+				waitTime, err := agentClient.Drain("shutdown")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(waitTime).To(Equal(int64(-10)))
+
+				Expect(fakeHTTPClient.PostInputs).To(HaveLen(2))
+
+				Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+					Method:    "drain",
+					Arguments: []interface{}{"shutdown"},
+					ReplyTo:   replyToAddress,
+				}))
+			})
+		})
+
+		Context("when draining for an update", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				fakeHTTPClient.SetPostBehavior(`{"value":0}`, 200, nil)
+			})
+
+			It("sends the new spec with the drain type", func() {
+				_, err := agentClient.Drain("update", applyspec.ApplySpec{Deployment: "fake-deployment-name"})
+				Expect(err).ToNot(HaveOccurred())
+
+				request := sentRequest(0)
+				Expect(request.Method).To(Equal("drain"))
+				Expect(request.Arguments).To(HaveLen(2))
+				Expect(request.Arguments[0]).To(Equal("update"))
+				Expect(request.Arguments[1]).To(HaveKeyWithValue("deployment", "fake-deployment-name"))
+			})
+		})
+
+		Context("when agent does not respond with a wait time", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				fakeHTTPClient.SetPostBehavior(`{"value":"fake-value"}`, 200, nil)
+			})
+
+			It("returns an error", func() {
+				_, err := agentClient.Drain("status")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unable to parse 'drain' response"))
+			})
+		})
+
+		Context("when sending the request fails", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.SetPostBehavior("", 0, errors.New("connection reset by peer"))
+			})
+
+			It("returns an error", func() {
+				_, err := agentClient.Drain("shutdown")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("connection reset by peer"))
+			})
+		})
+	})
+})
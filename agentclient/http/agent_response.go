@@ -0,0 +1,42 @@
+package http
+
+import (
+	"encoding/json"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type exception struct {
+	Message string
+}
+
+type FullStateResponse struct {
+	Value     FullAgentState
+	Exception *exception
+}
+
+func (r *FullStateResponse) ServerError() error {
+	if r.Exception != nil {
+		return bosherr.Errorf("Agent responded with error: %s", r.Exception.Message)
+	}
+	return nil
+}
+
+func (r *FullStateResponse) Unmarshal(message []byte) error {
+	return json.Unmarshal(message, r)
+}
+
+type FullAgentState struct {
+	AgentID      string                               `json:"agent_id"`
+	JobState     string                               `json:"job_state"`
+	NetworkSpecs map[string]biagentclient.NetworkSpec `json:"networks"`
+	Processes    []biagent.ProcessState               `json:"processes"`
+	Vitals       biagent.Vitals                       `json:"vitals"`
+
+	// the full state includes the fields of the last applied spec,
+	// its networks are shadowed by NetworkSpecs
+	bias.ApplySpec
+}
@@ -1,5 +1,5 @@
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/cloudfoundry/bosh-agent/agentclient (interfaces: AgentClient)
+// Source: github.com/cloudfoundry/bosh-init/agentclient (interfaces: AgentClient)
 
 package mocks
 
@@ -7,6 +7,7 @@ import (
 	agentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	applyspec "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	settings "github.com/cloudfoundry/bosh-agent/settings"
+	agentclient0 "github.com/cloudfoundry/bosh-init/agentclient"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -62,6 +63,44 @@ func (_mr *_MockAgentClientRecorder) DeleteARPEntries(arg0 interface{}) *gomock.
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteARPEntries", arg0)
 }
 
+func (_m *MockAgentClient) Drain(_param0 string, _param1 ...applyspec.ApplySpec) (int64, error) {
+	_s := []interface{}{_param0}
+	for _, _x := range _param1 {
+		_s = append(_s, _x)
+	}
+	ret := _m.ctrl.Call(_m, "Drain", _s...)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) Drain(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	_s := append([]interface{}{arg0}, arg1...)
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Drain", _s...)
+}
+
+func (_m *MockAgentClient) FetchLogs(_param0 string, _param1 []string) (agentclient.BlobRef, error) {
+	ret := _m.ctrl.Call(_m, "FetchLogs", _param0, _param1)
+	ret0, _ := ret[0].(agentclient.BlobRef)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) FetchLogs(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "FetchLogs", arg0, arg1)
+}
+
+func (_m *MockAgentClient) GetFullState() (agentclient0.AgentState, error) {
+	ret := _m.ctrl.Call(_m, "GetFullState")
+	ret0, _ := ret[0].(agentclient0.AgentState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) GetFullState() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetFullState")
+}
+
 func (_m *MockAgentClient) GetState() (agentclient.AgentState, error) {
 	ret := _m.ctrl.Call(_m, "GetState")
 	ret0, _ := ret[0].(agentclient.AgentState)
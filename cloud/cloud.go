@@ -25,6 +25,9 @@ type Cloud interface {
 	AttachDisk(vmCID, diskCID string) error
 	DetachDisk(vmCID, diskCID string) error
 	DeleteDisk(diskCID string) error
+	HasDisk(diskCID string) (bool, error)
+	SnapshotDisk(diskCID string, metadata SnapshotMetadata) (snapshotCID string, err error)
+	DeleteSnapshot(snapshotCID string) error
 	fmt.Stringer
 }
 
@@ -42,6 +45,10 @@ type VMMetadata struct {
 	Index      string `json:"index"`
 }
 
+type SnapshotMetadata struct {
+	Director string `json:"director"`
+}
+
 func NewCloud(
 	cpiCmdRunner CPICmdRunner,
 	directorID string,
@@ -264,6 +271,59 @@ func (c cloud) DeleteDisk(diskCID string) error {
 	return nil
 }
 
+func (c cloud) HasDisk(diskCID string) (bool, error) {
+	method := "has_disk"
+	cmdOutput, err := c.cpiCmdRunner.Run(c.context, method, diskCID)
+	if err != nil {
+		return false, err
+	}
+
+	if cmdOutput.Error != nil {
+		return false, NewCPIError(method, *cmdOutput.Error)
+	}
+
+	found, ok := cmdOutput.Result.(bool)
+	if !ok {
+		return false, bosherr.Errorf("Unexpected external CPI command result: '%#v'", cmdOutput.Result)
+	}
+	return found, nil
+}
+
+func (c cloud) SnapshotDisk(diskCID string, metadata SnapshotMetadata) (string, error) {
+	c.logger.Debug(c.logTag, "Taking snapshot of disk '%s'", diskCID)
+	method := "snapshot_disk"
+	cmdOutput, err := c.cpiCmdRunner.Run(c.context, method, diskCID, metadata)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Calling CPI 'snapshot_disk' method")
+	}
+
+	if cmdOutput.Error != nil {
+		return "", NewCPIError(method, *cmdOutput.Error)
+	}
+
+	// for snapshot_disk, the result is a string of the snapshot cid
+	cidString, ok := cmdOutput.Result.(string)
+	if !ok {
+		return "", bosherr.Errorf("Unexpected external CPI command result: '%#v'", cmdOutput.Result)
+	}
+	return cidString, nil
+}
+
+func (c cloud) DeleteSnapshot(snapshotCID string) error {
+	c.logger.Debug(c.logTag, "Deleting snapshot '%s'", snapshotCID)
+	method := "delete_snapshot"
+	cmdOutput, err := c.cpiCmdRunner.Run(c.context, method, snapshotCID)
+	if err != nil {
+		return bosherr.WrapError(err, "Calling CPI 'delete_snapshot' method")
+	}
+
+	if cmdOutput.Error != nil {
+		return NewCPIError(method, *cmdOutput.Error)
+	}
+
+	return nil
+}
+
 func (c cloud) String() string {
 	return fmt.Sprintf("Cloud{Context=%s}", c.context)
 }
@@ -551,4 +551,143 @@ var _ = Describe("Cloud", func() {
 			return cloud.DeleteDisk("fake-disk-cid")
 		})
 	})
+
+	Describe("HasDisk", func() {
+		It("return true when the disk exists", func() {
+			fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+				Result: true,
+			}
+
+			found, err := cloud.HasDisk("fake-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+
+			Expect(fakeCPICmdRunner.RunInputs).To(Equal([]fakebicloud.RunInput{
+				{
+					Context:   context,
+					Method:    "has_disk",
+					Arguments: []interface{}{"fake-disk-cid"},
+				},
+			}))
+		})
+
+		It("return false when the disk does not exist", func() {
+			fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+				Result: false,
+			}
+
+			found, err := cloud.HasDisk("fake-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns an error when the cpi result is not a boolean", func() {
+			fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+				Result: "fake-result",
+			}
+
+			_, err := cloud.HasDisk("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unexpected external CPI command result"))
+		})
+
+		itHandlesCPIErrors("has_disk", func() error {
+			_, err := cloud.HasDisk("fake-disk-cid")
+			return err
+		})
+	})
+
+	Describe("SnapshotDisk", func() {
+		Context("when the cpi successfully takes the snapshot", func() {
+			BeforeEach(func() {
+				fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+					Result: "fake-snapshot-cid",
+				}
+			})
+
+			It("executes the cpi job script with the correct arguments", func() {
+				_, err := cloud.SnapshotDisk("fake-disk-cid", SnapshotMetadata{Director: "bosh-init"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCPICmdRunner.RunInputs).To(HaveLen(1))
+				Expect(fakeCPICmdRunner.RunInputs[0]).To(Equal(fakebicloud.RunInput{
+					Context: context,
+					Method:  "snapshot_disk",
+					Arguments: []interface{}{
+						"fake-disk-cid",
+						SnapshotMetadata{Director: "bosh-init"},
+					},
+				}))
+			})
+
+			It("returns the cid returned from executing the cpi script", func() {
+				cid, err := cloud.SnapshotDisk("fake-disk-cid", SnapshotMetadata{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cid).To(Equal("fake-snapshot-cid"))
+			})
+		})
+
+		Context("when the result is of an unexpected type", func() {
+			BeforeEach(func() {
+				fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+					Result: 1,
+				}
+			})
+
+			It("returns an error", func() {
+				_, err := cloud.SnapshotDisk("fake-disk-cid", SnapshotMetadata{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unexpected external CPI command result: '1'"))
+			})
+		})
+
+		Context("when the cpi command execution fails", func() {
+			BeforeEach(func() {
+				fakeCPICmdRunner.RunErr = errors.New("fake-run-error")
+			})
+
+			It("returns an error", func() {
+				_, err := cloud.SnapshotDisk("fake-disk-cid", SnapshotMetadata{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-run-error"))
+			})
+		})
+
+		itHandlesCPIErrors("snapshot_disk", func() error {
+			_, err := cloud.SnapshotDisk("fake-disk-cid", SnapshotMetadata{})
+			return err
+		})
+	})
+
+	Describe("DeleteSnapshot", func() {
+		Context("when the cpi successfully deletes the snapshot", func() {
+			It("executes the cpi job script with the correct arguments", func() {
+				err := cloud.DeleteSnapshot("fake-snapshot-cid")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCPICmdRunner.RunInputs).To(HaveLen(1))
+				Expect(fakeCPICmdRunner.RunInputs[0]).To(Equal(fakebicloud.RunInput{
+					Context: context,
+					Method:  "delete_snapshot",
+					Arguments: []interface{}{
+						"fake-snapshot-cid",
+					},
+				}))
+			})
+		})
+
+		Context("when the cpi command execution fails", func() {
+			BeforeEach(func() {
+				fakeCPICmdRunner.RunErr = errors.New("fake-run-error")
+			})
+
+			It("returns an error", func() {
+				err := cloud.DeleteSnapshot("fake-snapshot-cid")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-run-error"))
+			})
+		})
+
+		itHandlesCPIErrors("delete_snapshot", func() error {
+			return cloud.DeleteSnapshot("fake-snapshot-cid")
+		})
+	})
 })
@@ -8,7 +8,8 @@ import (
 )
 
 type Factory interface {
-	NewCloud(installation biinstall.Installation, directorID string) (Cloud, error)
+	// NewCloud returns the cloud of the named cloud provider of the installation, or of the default one for an empty name
+	NewCloud(installation biinstall.Installation, directorID string, cloudProvider string) (Cloud, error)
 }
 
 type factory struct {
@@ -29,12 +30,21 @@ func NewFactory(
 	}
 }
 
-func (f *factory) NewCloud(installation biinstall.Installation, directorID string) (Cloud, error) {
-	cpiJob := installation.Job()
+func (f *factory) NewCloud(installation biinstall.Installation, directorID string, cloudProvider string) (Cloud, error) {
+	cpiJob, found := installation.CloudProviderJob(cloudProvider)
+	if !found {
+		return nil, bosherr.Errorf("Cloud provider '%s' is not in cloud_providers of the installation manifest", cloudProvider)
+	}
+
 	target := installation.Target()
+	jobsDir := target.JobsPath()
+	if cloudProvider != "" {
+		jobsDir = target.CloudProviderJobsPath(cloudProvider)
+	}
+
 	cpi := CPI{
 		JobPath:     cpiJob.Path,
-		JobsDir:     target.JobsPath(),
+		JobsDir:     jobsDir,
 		PackagesDir: target.PackagesPath(),
 	}
 
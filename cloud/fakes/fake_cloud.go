@@ -34,12 +34,23 @@ type FakeCloud struct {
 	DeleteDiskInputs []DeleteDiskInput
 	DeleteDiskErr    error
 
+	HasDiskInput HasDiskInput
+	HasDiskFound bool
+	HasDiskErr   error
+
 	DeleteStemcellInputs []DeleteStemcellInput
 	DeleteStemcellErr    error
 
 	SetVMMetadataCid      string
 	SetVMMetadataMetadata cloud.VMMetadata
 	SetVMMetadataError    error
+
+	SnapshotDiskInputs []SnapshotDiskInput
+	SnapshotDiskCID    string
+	SnapshotDiskErr    error
+
+	DeleteSnapshotInputs []DeleteSnapshotInput
+	DeleteSnapshotErr    error
 }
 
 type CreateStemcellInput struct {
@@ -79,6 +90,10 @@ type DeleteVMInput struct {
 	VMCID string
 }
 
+type HasDiskInput struct {
+	DiskCID string
+}
+
 type DeleteDiskInput struct {
 	DiskCID string
 }
@@ -87,10 +102,21 @@ type DeleteStemcellInput struct {
 	StemcellCID string
 }
 
+type SnapshotDiskInput struct {
+	DiskCID  string
+	Metadata cloud.SnapshotMetadata
+}
+
+type DeleteSnapshotInput struct {
+	SnapshotCID string
+}
+
 func NewFakeCloud() *FakeCloud {
 	return &FakeCloud{
 		CreateStemcellInputs: []CreateStemcellInput{},
 		DeleteDiskInputs:     []DeleteDiskInput{},
+		SnapshotDiskInputs:   []SnapshotDiskInput{},
+		DeleteSnapshotInputs: []DeleteSnapshotInput{},
 	}
 }
 
@@ -185,6 +211,28 @@ func (c *FakeCloud) DeleteDisk(diskCID string) error {
 	return c.DeleteDiskErr
 }
 
+func (c *FakeCloud) HasDisk(diskCID string) (bool, error) {
+	c.HasDiskInput = HasDiskInput{
+		DiskCID: diskCID,
+	}
+	return c.HasDiskFound, c.HasDiskErr
+}
+
+func (c *FakeCloud) SnapshotDisk(diskCID string, metadata cloud.SnapshotMetadata) (string, error) {
+	c.SnapshotDiskInputs = append(c.SnapshotDiskInputs, SnapshotDiskInput{
+		DiskCID:  diskCID,
+		Metadata: metadata,
+	})
+	return c.SnapshotDiskCID, c.SnapshotDiskErr
+}
+
+func (c *FakeCloud) DeleteSnapshot(snapshotCID string) error {
+	c.DeleteSnapshotInputs = append(c.DeleteSnapshotInputs, DeleteSnapshotInput{
+		SnapshotCID: snapshotCID,
+	})
+	return c.DeleteSnapshotErr
+}
+
 func (c *FakeCloud) String() string {
 	return "FakeCloud{}"
 }
@@ -84,6 +84,16 @@ func (_mr *_MockCloudRecorder) DeleteDisk(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteDisk", arg0)
 }
 
+func (_m *MockCloud) DeleteSnapshot(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "DeleteSnapshot", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockCloudRecorder) DeleteSnapshot(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteSnapshot", arg0)
+}
+
 func (_m *MockCloud) DeleteStemcell(_param0 string) error {
 	ret := _m.ctrl.Call(_m, "DeleteStemcell", _param0)
 	ret0, _ := ret[0].(error)
@@ -114,6 +124,17 @@ func (_mr *_MockCloudRecorder) DetachDisk(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DetachDisk", arg0, arg1)
 }
 
+func (_m *MockCloud) HasDisk(_param0 string) (bool, error) {
+	ret := _m.ctrl.Call(_m, "HasDisk", _param0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockCloudRecorder) HasDisk(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "HasDisk", arg0)
+}
+
 func (_m *MockCloud) HasVM(_param0 string) (bool, error) {
 	ret := _m.ctrl.Call(_m, "HasVM", _param0)
 	ret0, _ := ret[0].(bool)
@@ -135,6 +156,17 @@ func (_mr *_MockCloudRecorder) SetVMMetadata(arg0, arg1 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetVMMetadata", arg0, arg1)
 }
 
+func (_m *MockCloud) SnapshotDisk(_param0 string, _param1 cloud.SnapshotMetadata) (string, error) {
+	ret := _m.ctrl.Call(_m, "SnapshotDisk", _param0, _param1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockCloudRecorder) SnapshotDisk(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SnapshotDisk", arg0, arg1)
+}
+
 func (_m *MockCloud) String() string {
 	ret := _m.ctrl.Call(_m, "String")
 	ret0, _ := ret[0].(string)
@@ -166,13 +198,13 @@ func (_m *MockFactory) EXPECT() *_MockFactoryRecorder {
 	return _m.recorder
 }
 
-func (_m *MockFactory) NewCloud(_param0 installation.Installation, _param1 string) (cloud.Cloud, error) {
-	ret := _m.ctrl.Call(_m, "NewCloud", _param0, _param1)
+func (_m *MockFactory) NewCloud(_param0 installation.Installation, _param1 string, _param2 string) (cloud.Cloud, error) {
+	ret := _m.ctrl.Call(_m, "NewCloud", _param0, _param1, _param2)
 	ret0, _ := ret[0].(cloud.Cloud)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (_mr *_MockFactoryRecorder) NewCloud(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "NewCloud", arg0, arg1)
+func (_mr *_MockFactoryRecorder) NewCloud(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "NewCloud", arg0, arg1, arg2)
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	vmCIDFlag       = "--vm-cid="
+	diskCIDFlag     = "--disk-cid="
+	stemcellCIDFlag = "--stemcell-cid="
+)
+
+type adoptCmd struct {
+	deploymentAdopterProvider func(deploymentManifestPath string) (DeploymentAdopter, error)
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	logTag                    string
+}
+
+type adoptInputs struct {
+	deploymentManifestPath string
+	vmCID                  string
+	diskCID                string
+	stemcellCID            string
+	forceLock              bool
+}
+
+func NewAdoptCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	deploymentAdopterProvider func(deploymentManifestPath string) (DeploymentAdopter, error),
+) Cmd {
+	return &adoptCmd{
+		ui:                        ui,
+		fs:                        fs,
+		deploymentAdopterProvider: deploymentAdopterProvider,
+		logger:                    logger,
+		logTag:                    "adoptCmd",
+	}
+}
+
+func (c *adoptCmd) Name() string {
+	return "adopt"
+}
+
+func (c *adoptCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Create a new deployment state for an existing VM and persistent disk, e.g. after the deployment state was lost",
+		Usage:    "<deployment_manifest_path> --vm-cid=<cid> --disk-cid=<cid> [--stemcell-cid=<cid>] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *adoptCmd) Run(stage biui.Stage, args []string) error {
+	inputs, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, inputs.deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentAdopter, err := c.deploymentAdopterProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = deploymentAdopter.Adopt(stage, inputs.vmCID, inputs.diskCID, inputs.stemcellCID, inputs.forceLock)
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("Adopted VM '%s' and disk '%s', run 'bosh-init deploy' to converge the deployment", inputs.vmCID, inputs.diskCID)
+	return nil
+}
+
+func (c *adoptCmd) parseCmdInputs(args []string) (adoptInputs, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "adopt",
+		positional: 1,
+		flags:      []string{"--force-lock"},
+		options:    []string{vmCIDFlag, diskCIDFlag, stemcellCIDFlag},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return adoptInputs{}, err
+	}
+
+	inputs := adoptInputs{
+		deploymentManifestPath: parsed.positional[0],
+		vmCID:                  parsed.Option(vmCIDFlag),
+		diskCID:                parsed.Option(diskCIDFlag),
+		stemcellCID:            parsed.Option(stemcellCIDFlag),
+		forceLock:              parsed.Flag("--force-lock"),
+	}
+
+	if inputs.vmCID == "" || inputs.diskCID == "" {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return adoptInputs{}, errors.New("Invalid usage - adopt command requires --vm-cid and --disk-cid")
+	}
+
+	return inputs, nil
+}
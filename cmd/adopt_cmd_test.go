@@ -0,0 +1,100 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("AdoptCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockDeploymentAdopter *mock_cmd.MockDeploymentAdopter
+			fs                    *fakesys.FakeFileSystem
+			logger                boshlog.Logger
+			fakeUI                *fakebiui.FakeUI
+			fakeStage             *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newAdoptCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.DeploymentAdopter, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockDeploymentAdopter, nil
+			}
+
+			return bicmd.NewAdoptCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockDeploymentAdopter = mock_cmd.NewMockDeploymentAdopter(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("adopts the VM and the disk", func() {
+			mockDeploymentAdopter.EXPECT().Adopt(fakeStage, "fake-vm-cid", "fake-disk-cid", "", false).Return(nil)
+
+			err := newAdoptCmd().Run(fakeStage, []string{deploymentManifestPath, "--vm-cid=fake-vm-cid", "--disk-cid=fake-disk-cid"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Adopted VM 'fake-vm-cid' and disk 'fake-disk-cid', run 'bosh-init deploy' to converge the deployment"))
+		})
+
+		It("adopts the stemcell with --stemcell-cid", func() {
+			mockDeploymentAdopter.EXPECT().Adopt(fakeStage, "fake-vm-cid", "fake-disk-cid", "fake-stemcell-cid", false).Return(nil)
+
+			err := newAdoptCmd().Run(fakeStage, []string{"--vm-cid=fake-vm-cid", "--disk-cid=fake-disk-cid", "--stemcell-cid=fake-stemcell-cid", deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockDeploymentAdopter.EXPECT().Adopt(fakeStage, "fake-vm-cid", "fake-disk-cid", "", true).Return(nil)
+
+			err := newAdoptCmd().Run(fakeStage, []string{deploymentManifestPath, "--vm-cid=fake-vm-cid", "--disk-cid=fake-disk-cid", "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockDeploymentAdopter.EXPECT().Adopt(fakeStage, "fake-vm-cid", "fake-disk-cid", "", false).Return(bosherr.Error("fake-adopt-error"))
+
+			err := newAdoptCmd().Run(fakeStage, []string{deploymentManifestPath, "--vm-cid=fake-vm-cid", "--disk-cid=fake-disk-cid"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-adopt-error"))
+		})
+
+		It("returns err unless the VM and the disk are given", func() {
+			err := newAdoptCmd().Run(fakeStage, []string{deploymentManifestPath, "--vm-cid=fake-vm-cid"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Invalid usage - adopt command requires --vm-cid and --disk-cid"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newAdoptCmd().Run(fakeStage, []string{"--vm-cid=fake-vm-cid", "--disk-cid=fake-disk-cid"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
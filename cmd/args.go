@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// argsSpec describes the arguments a command accepts
+type argsSpec struct {
+	cmdName string
+
+	// positional is the number of positional arguments the command requires
+	positional int
+
+	// flags are the options without a value, e.g. "--skip-drain"
+	flags []string
+
+	// options take a value: in the next argument, e.g. "--job", or in the same one when they end in '=', e.g. "--vm-cid="
+	options []string
+}
+
+type cmdArgs struct {
+	positional []string
+	flags      map[string]bool
+	options    map[string][]string
+}
+
+// Flag returns true if the flag was given
+func (a cmdArgs) Flag(name string) bool {
+	return a.flags[name]
+}
+
+// Option returns the last value given for the option, or "" if it was not given
+func (a cmdArgs) Option(name string) string {
+	values := a.options[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Options returns all values given for the option, in order
+func (a cmdArgs) Options(name string) []string {
+	return a.options[name]
+}
+
+// parseArgs splits the command line arguments of a command into positional arguments, flags and options
+func parseArgs(spec argsSpec, args []string, logger boshlog.Logger, logTag string) (cmdArgs, error) {
+	parsed := cmdArgs{
+		positional: []string{},
+		flags:      map[string]bool{},
+		options:    map[string][]string{},
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if spec.isFlag(arg) {
+			parsed.flags[arg] = true
+			continue
+		}
+
+		option, inline := spec.option(arg)
+		if option == "" {
+			parsed.positional = append(parsed.positional, arg)
+			continue
+		}
+
+		if inline {
+			parsed.options[option] = append(parsed.options[option], strings.TrimPrefix(arg, option))
+			continue
+		}
+
+		if i+1 >= len(args) {
+			logger.Error(logTag, "Invalid arguments: %#v", args)
+			return cmdArgs{}, bosherr.Errorf("Invalid usage - %s command option '%s' requires a value", spec.cmdName, option)
+		}
+		parsed.options[option] = append(parsed.options[option], args[i+1])
+		i++
+	}
+
+	if len(parsed.positional) != spec.positional {
+		logger.Error(logTag, "Invalid arguments: %#v", args)
+		if spec.positional == 1 {
+			return cmdArgs{}, bosherr.Errorf("Invalid usage - %s command requires exactly 1 argument", spec.cmdName)
+		}
+		return cmdArgs{}, bosherr.Errorf("Invalid usage - %s command requires exactly %d arguments", spec.cmdName, spec.positional)
+	}
+
+	return parsed, nil
+}
+
+func (s argsSpec) isFlag(arg string) bool {
+	for _, flag := range s.flags {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// option returns the option that arg starts, and whether its value is inline
+func (s argsSpec) option(arg string) (string, bool) {
+	for _, option := range s.options {
+		if strings.HasSuffix(option, "=") {
+			if strings.HasPrefix(arg, option) {
+				return option, true
+			}
+		} else if arg == option {
+			return option, false
+		}
+	}
+	return "", false
+}
+
+// deploymentManifestAbsPath returns the absolute path of the deployment manifest after checking that it exists, and prints it
+func deploymentManifestAbsPath(ui biui.UI, fs boshsys.FileSystem, deploymentManifestPath string) (string, error) {
+	manifestAbsFilePath, err := filepath.Abs(deploymentManifestPath)
+	if err != nil {
+		ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", deploymentManifestPath)
+		return "", bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", deploymentManifestPath)
+	}
+
+	if !fs.FileExists(manifestAbsFilePath) {
+		ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
+		return "", bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+	}
+
+	ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
+
+	return manifestAbsFilePath, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type cleanUpCmd struct {
+	deploymentCleanerProvider func(deploymentManifestPath string) (DeploymentCleaner, error)
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	logTag                    string
+}
+
+func NewCleanUpCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	deploymentCleanerProvider func(deploymentManifestPath string) (DeploymentCleaner, error),
+) Cmd {
+	return &cleanUpCmd{
+		ui:                        ui,
+		fs:                        fs,
+		deploymentCleanerProvider: deploymentCleanerProvider,
+		logger:                    logger,
+		logTag:                    "cleanUpCmd",
+	}
+}
+
+func (c *cleanUpCmd) Name() string {
+	return "clean-up"
+}
+
+func (c *cleanUpCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Delete orphaned disks, unused stemcells and the resources left in a previous cloud provider, and optionally prune the shared tarball cache",
+		Usage:    "<deployment_manifest_path> [--all] [--prune-cache] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *cleanUpCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, all, pruneCache, forceLock, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentCleaner, err := c.deploymentCleanerProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return deploymentCleaner.CleanUp(stage, all, pruneCache, forceLock)
+}
+
+func (c *cleanUpCmd) parseCmdInputs(args []string) (string, bool, bool, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "clean-up",
+		positional: 1,
+		flags:      []string{"--all", "--prune-cache", "--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", false, false, false, err
+	}
+	return parsed.positional[0], parsed.Flag("--all"), parsed.Flag("--prune-cache"), parsed.Flag("--force-lock"), nil
+}
@@ -0,0 +1,100 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("CleanUpCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockDeploymentCleaner *mock_cmd.MockDeploymentCleaner
+			fs                    *fakesys.FakeFileSystem
+			logger                boshlog.Logger
+			fakeUI                *fakebiui.FakeUI
+			fakeStage             *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newCleanUpCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.DeploymentCleaner, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockDeploymentCleaner, nil
+			}
+
+			return bicmd.NewCleanUpCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockDeploymentCleaner = mock_cmd.NewMockDeploymentCleaner(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("cleans up, keeping the stemcell uploaded last and the tarball cache", func() {
+			mockDeploymentCleaner.EXPECT().CleanUp(fakeStage, false, false, false).Return(nil)
+
+			err := newCleanUpCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("cleans up everything unused with --all", func() {
+			mockDeploymentCleaner.EXPECT().CleanUp(fakeStage, true, false, false).Return(nil)
+
+			err := newCleanUpCmd().Run(fakeStage, []string{deploymentManifestPath, "--all"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("prunes the tarball cache with --prune-cache", func() {
+			mockDeploymentCleaner.EXPECT().CleanUp(fakeStage, false, true, false).Return(nil)
+
+			err := newCleanUpCmd().Run(fakeStage, []string{deploymentManifestPath, "--prune-cache"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockDeploymentCleaner.EXPECT().CleanUp(fakeStage, false, false, true).Return(nil)
+
+			err := newCleanUpCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockDeploymentCleaner.EXPECT().CleanUp(fakeStage, false, false, false).Return(bosherr.Error("fake-clean-up-error"))
+
+			err := newCleanUpCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-clean-up-error"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newCleanUpCmd().Run(fakeStage, []string{"--all"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	biinstall "github.com/cloudfoundry/bosh-init/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// describeCloudProvider names the cloud provider for the user, the default one has an empty name
+func describeCloudProvider(cloudProvider string) string {
+	if cloudProvider == "" {
+		return "the default cloud provider"
+	}
+	return fmt.Sprintf("cloud provider '%s'", cloudProvider)
+}
+
+// deploymentCloudProvider returns the cloud provider selected by the resource pool of the deployment job,
+// after checking that the installation manifest configures it
+func deploymentCloudProvider(deploymentManifest bideplmanifest.Manifest, installationManifest biinstallmanifest.Manifest) (string, error) {
+	if len(deploymentManifest.Jobs) == 0 {
+		return "", nil
+	}
+
+	cloudProvider, err := deploymentManifest.CloudProvider(deploymentManifest.JobName())
+	if err != nil {
+		return "", err
+	}
+
+	if _, found := installationManifest.FindCloudProvider(cloudProvider); !found {
+		return "", bosherr.Errorf("Cloud provider '%s' of the deployment must refer to a cloud provider in cloud_providers", cloudProvider)
+	}
+
+	return cloudProvider, nil
+}
+
+// downloadCpiReleases downloads and extracts the releases of all the CPI configurations of the installation manifest
+func downloadCpiReleases(
+	releaseFetcher birel.Fetcher,
+	releaseSetManifest birelsetmanifest.Manifest,
+	installationManifest biinstallmanifest.Manifest,
+	stage biui.Stage,
+) error {
+	for _, cpiReleaseName := range installationManifest.CPIReleases() {
+		cpiReleaseRef, found := releaseSetManifest.FindByName(cpiReleaseName)
+		if !found {
+			return bosherr.Errorf("installation release '%s' must refer to a release in releases", cpiReleaseName)
+		}
+
+		err := releaseFetcher.DownloadAndExtract(cpiReleaseRef, stage)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteCloudMigration deletes the VM, snapshots, disks and stemcells that the deployment left behind
+// in its previous cloud provider, using the CPI of that cloud provider.
+// The cloud migration record is updated after every deleted resource, so that it can be resumed.
+func deleteCloudMigration(
+	stage biui.Stage,
+	cloudFactory bicloud.Factory,
+	cloudMigrationRepo biconfig.CloudMigrationRepo,
+	installation biinstall.Installation,
+	directorID string,
+) error {
+	record, found, err := cloudMigrationRepo.Find()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding cloud migration")
+	}
+	if !found {
+		return nil
+	}
+
+	cloud, err := cloudFactory.NewCloud(installation, directorID, record.CloudProvider)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating CPI client for %s", describeCloudProvider(record.CloudProvider))
+	}
+
+	if record.VMCID != "" {
+		err = stage.Perform(fmt.Sprintf("Deleting VM '%s' of %s", record.VMCID, describeCloudProvider(record.CloudProvider)), func() error {
+			return ignoreCloudError(cloud.DeleteVM(record.VMCID), bicloud.VMNotFoundError, "VM not found")
+		})
+		if err != nil {
+			return err
+		}
+
+		record.VMCID = ""
+		err = cloudMigrationRepo.Save(record)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving cloud migration")
+		}
+	}
+
+	// snapshots are deleted before the disks they were taken from
+	for len(record.Snapshots) > 0 {
+		snapshot := record.Snapshots[0]
+		err = stage.Perform(fmt.Sprintf("Deleting snapshot '%s' of %s", snapshot.CID, describeCloudProvider(record.CloudProvider)), func() error {
+			return cloud.DeleteSnapshot(snapshot.CID)
+		})
+		if err != nil {
+			return err
+		}
+
+		record.Snapshots = record.Snapshots[1:]
+		err = cloudMigrationRepo.Save(record)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving cloud migration")
+		}
+	}
+
+	for len(record.Disks) > 0 {
+		disk := record.Disks[0]
+		err = stage.Perform(fmt.Sprintf("Deleting disk '%s' of %s", disk.CID, describeCloudProvider(record.CloudProvider)), func() error {
+			return ignoreCloudError(cloud.DeleteDisk(disk.CID), bicloud.DiskNotFoundError, "Disk not found")
+		})
+		if err != nil {
+			return err
+		}
+
+		record.Disks = record.Disks[1:]
+		err = cloudMigrationRepo.Save(record)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving cloud migration")
+		}
+	}
+
+	for len(record.Stemcells) > 0 {
+		stemcell := record.Stemcells[0]
+		err = stage.Perform(fmt.Sprintf("Deleting stemcell '%s' of %s", stemcell.CID, describeCloudProvider(record.CloudProvider)), func() error {
+			return ignoreCloudError(cloud.DeleteStemcell(stemcell.CID), bicloud.StemcellNotFoundError, "Stemcell not found")
+		})
+		if err != nil {
+			return err
+		}
+
+		record.Stemcells = record.Stemcells[1:]
+		err = cloudMigrationRepo.Save(record)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving cloud migration")
+		}
+	}
+
+	err = cloudMigrationRepo.Clear()
+	if err != nil {
+		return bosherr.WrapError(err, "Clearing cloud migration")
+	}
+	return nil
+}
+
+// ignoreCloudError skips the stage step instead of failing when the CPI returns the error type, e.g. for deleted resources
+func ignoreCloudError(err error, errorType string, skipMessage string) error {
+	cloudErr, ok := err.(bicloud.Error)
+	if ok && cloudErr.Type() == errorType {
+		return biui.NewSkipStageError(cloudErr, skipMessage)
+	}
+	return err
+}
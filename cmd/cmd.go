@@ -25,6 +25,11 @@ type MetaEnv struct {
 	Description string
 }
 
+const (
+	statePassphraseEnv = "BOSH_INIT_STATE_PASSPHRASE"
+	stateKeyFileEnv    = "BOSH_INIT_STATE_KEY_FILE"
+)
+
 var genericEnv = map[string]MetaEnv{
 	"BOSH_INIT_LOG_LEVEL": MetaEnv{
 		Example:     "debug",
@@ -36,4 +41,14 @@ var genericEnv = map[string]MetaEnv{
 		Default:     "standard out/err",
 		Description: "The path where logs will be written",
 	},
+	statePassphraseEnv: MetaEnv{
+		Example:     "my-passphrase",
+		Default:     "none",
+		Description: "Passphrase that encrypts the deployment state and the registry store, and decrypts the manifest secrets",
+	},
+	stateKeyFileEnv: MetaEnv{
+		Example:     "/path/to/key-file",
+		Default:     "none",
+		Description: "File whose contents are used as the passphrase, instead of BOSH_INIT_STATE_PASSPHRASE",
+	},
 }
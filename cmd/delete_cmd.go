@@ -1,11 +1,7 @@
 package cmd
 
 import (
-	"errors"
-	"path/filepath"
-
 	biui "github.com/cloudfoundry/bosh-init/ui"
-	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -40,42 +36,38 @@ func (c *deleteCmd) Name() string {
 func (c *deleteCmd) Meta() Meta {
 	return Meta{
 		Synopsis: "Delete existing deployment",
-		Usage:    "<deployment_manifest_path>",
+		Usage:    "<deployment_manifest_path> [--skip-drain] [--force-lock]",
 		Env:      genericEnv,
 	}
 }
 
 func (c *deleteCmd) Run(stage biui.Stage, args []string) error {
-	deploymentManifestPath, err := c.parseCmdInputs(args)
+	deploymentManifestPath, skipDrain, forceLock, err := c.parseCmdInputs(args)
 	if err != nil {
 		return err
 	}
 
-	manifestAbsFilePath, err := filepath.Abs(deploymentManifestPath)
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
 	if err != nil {
-		c.ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", deploymentManifestPath)
-		return bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", deploymentManifestPath)
-	}
-
-	if !c.fs.FileExists(manifestAbsFilePath) {
-		c.ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
-		return bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+		return err
 	}
 
-	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
-
 	deploymentDeleter, err := c.deploymentDeleterProvider(manifestAbsFilePath)
 	if err != nil {
 		return err
 	}
 
-	return deploymentDeleter.DeleteDeployment(stage)
+	return deploymentDeleter.DeleteDeployment(stage, skipDrain, forceLock)
 }
 
-func (c *deleteCmd) parseCmdInputs(args []string) (string, error) {
-	if len(args) != 1 {
-		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
-		return "", errors.New("Invalid usage - delete command requires exactly 1 argument")
+func (c *deleteCmd) parseCmdInputs(args []string) (string, bool, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "delete",
+		positional: 1,
+		flags:      []string{"--skip-drain", "--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", false, false, err
 	}
-	return args[0], nil
+	return parsed.positional[0], parsed.Flag("--skip-drain"), parsed.Flag("--force-lock"), nil
 }
@@ -70,14 +70,26 @@ var _ = Describe("DeleteCmd", func() {
 
 		Context("when the deployment manifest exists", func() {
 			It("sends the manifest on to the deleter", func() {
-				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage).Return(nil)
+				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, false, false).Return(nil)
 				newDeleteCmd().Run(fakeStage, []string{deploymentManifestPath})
 			})
 
+			It("skips draining the jobs when --skip-drain is given", func() {
+				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, true, false).Return(nil)
+				err := newDeleteCmd().Run(fakeStage, []string{deploymentManifestPath, "--skip-drain"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("takes over the locks when --force-lock is given", func() {
+				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, false, true).Return(nil)
+				err := newDeleteCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
 			Context("when the deployment deleter returns an error", func() {
 				It("sends the manifest on to the deleter", func() {
 					err := bosherr.Error("boom")
-					mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage).Return(err)
+					mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, false, false).Return(err)
 					returnedErr := newDeleteCmd().Run(fakeStage, []string{deploymentManifestPath})
 					Expect(returnedErr).To(Equal(err))
 				})
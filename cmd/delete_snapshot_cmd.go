@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type deleteSnapshotCmd struct {
+	deploymentSnapshotterProvider func(deploymentManifestPath string) (DeploymentSnapshotter, error)
+	ui                            biui.UI
+	fs                            boshsys.FileSystem
+	logger                        boshlog.Logger
+	logTag                        string
+}
+
+func NewDeleteSnapshotCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	deploymentSnapshotterProvider func(deploymentManifestPath string) (DeploymentSnapshotter, error),
+) Cmd {
+	return &deleteSnapshotCmd{
+		ui:                            ui,
+		fs:                            fs,
+		deploymentSnapshotterProvider: deploymentSnapshotterProvider,
+		logger:                        logger,
+		logTag:                        "deleteSnapshotCmd",
+	}
+}
+
+func (c *deleteSnapshotCmd) Name() string {
+	return "delete-snapshot"
+}
+
+func (c *deleteSnapshotCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Delete a snapshot of the persistent disk of the deployed instance",
+		Usage:    "<deployment_manifest_path> <snapshot_cid> [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *deleteSnapshotCmd) Run(stage biui.Stage, args []string) error {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "delete-snapshot",
+		positional: 2,
+		flags:      []string{"--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return err
+	}
+
+	deploymentManifestPath, snapshotCID := parsed.positional[0], parsed.positional[1]
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentSnapshotter, err := c.deploymentSnapshotterProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return deploymentSnapshotter.DeleteSnapshot(stage, snapshotCID, parsed.Flag("--force-lock"))
+}
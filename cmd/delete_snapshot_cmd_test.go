@@ -0,0 +1,86 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("DeleteSnapshotCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockDeploymentSnapshotter *mock_cmd.MockDeploymentSnapshotter
+			fs                        *fakesys.FakeFileSystem
+			logger                    boshlog.Logger
+			fakeUI                    *fakebiui.FakeUI
+			fakeStage                 *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newDeleteSnapshotCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.DeploymentSnapshotter, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockDeploymentSnapshotter, nil
+			}
+
+			return bicmd.NewDeleteSnapshotCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockDeploymentSnapshotter = mock_cmd.NewMockDeploymentSnapshotter(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("deletes the snapshot", func() {
+			mockDeploymentSnapshotter.EXPECT().DeleteSnapshot(fakeStage, "fake-snapshot-cid", false).Return(nil)
+
+			err := newDeleteSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockDeploymentSnapshotter.EXPECT().DeleteSnapshot(fakeStage, "fake-snapshot-cid", true).Return(nil)
+
+			err := newDeleteSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid", "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockDeploymentSnapshotter.EXPECT().DeleteSnapshot(fakeStage, "fake-snapshot-cid", false).Return(bosherr.Error("fake-delete-error"))
+
+			err := newDeleteSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-delete-error"))
+		})
+
+		It("returns err unless exactly 2 arguments are given", func() {
+			err := newDeleteSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
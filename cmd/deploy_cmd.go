@@ -1,16 +1,17 @@
 package cmd
 
 import (
-	"errors"
-	"path/filepath"
 	"strings"
 
+	birel "github.com/cloudfoundry/bosh-init/release"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+const stemcellCompatibilityFlag = "--stemcell-compatibility="
+
 type deployCmd struct {
 	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error)
 	ui                         biui.UI
@@ -42,44 +43,57 @@ func (c *deployCmd) Name() string {
 func (c *deployCmd) Meta() Meta {
 	return Meta{
 		Synopsis: "Create or update a deployment",
-		Usage:    "<deployment_manifest_path>",
+		Usage:    "<deployment_manifest_path> [--recreate] [--skip-drain] [--fetch-logs-on-failure] [--force-lock] [--stemcell-compatibility=exact|major]",
 		Env:      genericEnv,
 	}
 }
 
 func (c *deployCmd) Run(stage biui.Stage, args []string) error {
-	deploymentManifestPath, err := c.parseCmdInputs(args)
+	deploymentManifestPath, deployOptions, err := c.parseCmdInputs(args)
 	if err != nil {
 		return err
 	}
 
-	manifestAbsFilePath, err := filepath.Abs(deploymentManifestPath)
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
 	if err != nil {
-		c.ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", deploymentManifestPath)
-		return bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", deploymentManifestPath)
-	}
-
-	if !c.fs.FileExists(manifestAbsFilePath) {
-		c.ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
-		return bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+		return err
 	}
 
-	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
-
 	deploymentPreparer, err := c.deploymentPreparerProvider(manifestAbsFilePath)
 	if err != nil {
 		return err
 	}
 
-	return deploymentPreparer.PrepareDeployment(stage)
+	return deploymentPreparer.PrepareDeployment(stage, deployOptions)
 }
 
-func (c *deployCmd) parseCmdInputs(args []string) (string, error) {
-	if len(args) != 1 {
-		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
-		return "", errors.New("Invalid usage - deploy command requires exactly 1 argument")
+func (c *deployCmd) parseCmdInputs(args []string) (string, DeployOptions, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "deploy",
+		positional: 1,
+		flags:      []string{"--recreate", "--skip-drain", "--fetch-logs-on-failure", "--force-lock"},
+		options:    []string{stemcellCompatibilityFlag},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", DeployOptions{}, err
 	}
-	return args[0], nil
+
+	deployOptions := DeployOptions{
+		Recreate:              parsed.Flag("--recreate"),
+		SkipDrain:             parsed.Flag("--skip-drain"),
+		FetchLogsOnFailure:    parsed.Flag("--fetch-logs-on-failure"),
+		ForceLock:             parsed.Flag("--force-lock"),
+		StemcellCompatibility: birel.StemcellCompatibilityExact,
+	}
+
+	if compatibility := parsed.Option(stemcellCompatibilityFlag); compatibility != "" {
+		deployOptions.StemcellCompatibility, err = birel.ParseStemcellCompatibility(compatibility)
+		if err != nil {
+			return "", DeployOptions{}, bosherr.WrapError(err, "Invalid usage")
+		}
+	}
+
+	return parsed.positional[0], deployOptions, nil
 }
 
 func (c *deployCmd) isBlank(str string) bool {
@@ -14,14 +14,18 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/onsi/gomega/gbytes"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
+	mock_httpagent "github.com/cloudfoundry/bosh-init/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
 	mock_config "github.com/cloudfoundry/bosh-init/config/mocks"
+	mock_logs "github.com/cloudfoundry/bosh-init/deployment/logs/mocks"
 	mock_deployment "github.com/cloudfoundry/bosh-init/deployment/mocks"
 	mock_vm "github.com/cloudfoundry/bosh-init/deployment/vm/mocks"
 	mock_install "github.com/cloudfoundry/bosh-init/installation/mocks"
+	mock_lock "github.com/cloudfoundry/bosh-init/lock/mocks"
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	mock_registry "github.com/cloudfoundry/bosh-init/registry/mocks"
 	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
 	mock_stemcell "github.com/cloudfoundry/bosh-init/stemcell/mocks"
@@ -33,6 +37,7 @@ import (
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	bireljob "github.com/cloudfoundry/bosh-init/release/job"
 	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
@@ -74,6 +79,7 @@ func rootDesc() {
 	Describe("Run", func() {
 		var (
 			command        bicmd.Cmd
+			doGet          func(deploymentManifestPath string) (bicmd.DeploymentPreparer, error)
 			fakeFs         *fakesys.FakeFileSystem
 			stdOut         *gbytes.Buffer
 			stdErr         *gbytes.Buffer
@@ -98,6 +104,11 @@ func rootDesc() {
 			mockBlobstoreFactory *mock_blobstore.MockFactory
 			mockBlobstore        *mock_blobstore.MockBlobstore
 
+			mockLogsFetcher *mock_logs.MockFetcher
+
+			mockLocker *mock_lock.MockLocker
+			mockLock   *mock_lock.MockLock
+
 			mockVMManagerFactory       *mock_vm.MockManagerFactory
 			fakeVMManager              *fakebivm.FakeManager
 			fakeStemcellExtractor      *fakebistemcell.FakeExtractor
@@ -140,6 +151,9 @@ func rootDesc() {
 			expectCPIReleaseExtract    *gomock.Call
 			expectInstall              *gomock.Call
 			expectNewCloud             *gomock.Call
+			expectLockDeploymentState  *gomock.Call
+			expectLockInstallation     *gomock.Call
+			expectReleaseLock          *gomock.Call
 		)
 
 		BeforeEach(func() {
@@ -177,6 +191,14 @@ func rootDesc() {
 			mockBlobstore = mock_blobstore.NewMockBlobstore(mockCtrl)
 			mockBlobstoreFactory.EXPECT().Create(mbusURL, gomock.Any()).Return(mockBlobstore, nil).AnyTimes()
 
+			mockLogsFetcher = mock_logs.NewMockFetcher(mockCtrl)
+
+			mockLocker = mock_lock.NewMockLocker(mockCtrl)
+			mockLock = mock_lock.NewMockLock(mockCtrl)
+			expectLockDeploymentState = mockLocker.EXPECT().Lock("/path/to/manifest-state.json.lock", false).Return(mockLock, nil).AnyTimes()
+			expectLockInstallation = mockLocker.EXPECT().Lock("fake-install-dir/fake-installation-id.lock", false).Return(mockLock, nil).AnyTimes()
+			expectReleaseLock = mockLock.EXPECT().Release().AnyTimes()
+
 			mockVMManagerFactory = mock_vm.NewMockManagerFactory(mockCtrl)
 			fakeVMManager = fakebivm.NewFakeManager()
 			mockVMManagerFactory.EXPECT().NewManager(gomock.Any(), mockAgentClient).Return(fakeVMManager).AnyTimes()
@@ -297,7 +319,7 @@ func rootDesc() {
 
 		JustBeforeEach(func() {
 
-			doGet := func(deploymentManifestPath string) (bicmd.DeploymentPreparer, error) {
+			doGet = func(deploymentManifestPath string) (bicmd.DeploymentPreparer, error) {
 				deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fakeFs, configUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath))
 				deploymentRepo := biconfig.NewDeploymentRepo(deploymentStateService)
 				releaseRepo := biconfig.NewReleaseRepo(deploymentStateService, fakeUUIDGenerator)
@@ -344,12 +366,15 @@ func rootDesc() {
 					"deployCmd",
 					deploymentStateService,
 					mockLegacyDeploymentStateMigrator,
+					biconfig.NewCloudMigrationRepo(deploymentStateService),
 					releaseManager,
 					deploymentRecord,
 					mockCloudFactory,
 					fakeStemcellManagerFactory,
 					mockAgentClientFactory,
 					mockVMManagerFactory,
+					fakeStemcellManagerFactory,
+					mockVMManagerFactory,
 					mockBlobstoreFactory,
 					mockDeployer,
 					deploymentManifestPath,
@@ -360,6 +385,8 @@ func rootDesc() {
 					deploymentManifestParser,
 					tempRootConfigurator,
 					targetProvider,
+					mockLogsFetcher,
+					mockLocker,
 				), nil
 			}
 
@@ -391,7 +418,7 @@ func rootDesc() {
 
 			mockInstallerFactory.EXPECT().NewInstaller(target).Return(mockInstaller).AnyTimes()
 
-			installation := biinstall.NewInstallation(target, installedJob, installationManifest, mockRegistryServerManager)
+			installation := biinstall.NewInstallation(target, installedJob, map[string]biinstall.InstalledJob{}, installationManifest, mockRegistryServerManager)
 
 			expectInstall = mockInstaller.EXPECT().Install(installationManifest, gomock.Any()).Do(func(_ interface{}, stage biui.Stage) {
 				Expect(fakeStage.SubStages).To(ContainElement(stage))
@@ -407,14 +434,16 @@ func rootDesc() {
 				installationManifest.Registry,
 				fakeVMManager,
 				mockBlobstore,
+				false,
+				false,
 				gomock.Any(),
-			).Do(func(_, _, _, _, _, _ interface{}, stage biui.Stage) {
+			).Do(func(_, _, _, _, _, _, _, _ interface{}, stage biui.Stage) {
 				Expect(fakeStage.SubStages).To(ContainElement(stage))
 			}).Return(mockDeployment, nil).AnyTimes()
 
 			expectCPIReleaseExtract = mockReleaseExtractor.EXPECT().Extract(cpiReleaseTarballPath).Return(fakeCPIRelease, nil).AnyTimes()
 
-			expectNewCloud = mockCloudFactory.EXPECT().NewCloud(installation, directorID).Return(cloud, nil).AnyTimes()
+			expectNewCloud = mockCloudFactory.EXPECT().NewCloud(installation, directorID, "").Return(cloud, nil).AnyTimes()
 		})
 
 		It("prints the deployment manifest and state file", func() {
@@ -425,6 +454,44 @@ func rootDesc() {
 			Expect(stdOut).To(gbytes.Say("Deployment state: '/path/to/manifest-state.json'"))
 		})
 
+		It("locks the deployment state and the installation until the deploy is done", func() {
+			gomock.InOrder(
+				expectLockDeploymentState.Times(1),
+				expectLockInstallation.Times(1),
+				expectDeploy.Times(1),
+				expectReleaseLock.Return(nil).Times(2),
+			)
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("takes over the locks when --force-lock is given", func() {
+			mockLocker.EXPECT().Lock("/path/to/manifest-state.json.lock", true).Return(mockLock, nil)
+			mockLocker.EXPECT().Lock("fake-install-dir/fake-installation-id.lock", true).Return(mockLock, nil)
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when another process holds the deployment state lock", func() {
+			BeforeEach(func() {
+				expectLockDeploymentState.Return(nil, bilock.HeldError{
+					Path:   "/path/to/manifest-state.json.lock",
+					Holder: bilock.Holder{PID: 123, Hostname: "fake-host", Command: "bosh-init deploy"},
+				})
+			})
+
+			It("returns an error suggesting --force-lock without deploying", func() {
+				expectDeploy.Times(0)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Another bosh-init process is using the deployment state, use --force-lock if it is no longer running"))
+				Expect(err.Error()).To(ContainSubstring("Lock '/path/to/manifest-state.json.lock' is held by 'bosh-init deploy' (PID 123 on host 'fake-host')"))
+			})
+		})
+
 		It("does not migrate the legacy bosh-deployments.yml if manifest-state.json exists", func() {
 			err := fakeFs.WriteFileString(deploymentStatePath, "{}")
 			Expect(err).ToNot(HaveOccurred())
@@ -556,7 +623,7 @@ func rootDesc() {
 			})
 
 			It("starts & stops the registry", func() {
-				mockRegistryServerManager.EXPECT().Start("fake-username", "fake-password", "fake-host", 123).Return(mockRegistryServer, nil)
+				mockRegistryServerManager.EXPECT().Start(biregistry.ServerConfig{Username: "fake-username", Password: "fake-password", Host: "fake-host", Port: 123}).Return(mockRegistryServer, nil)
 				mockRegistryServer.EXPECT().Stop()
 
 				err := command.Run(fakeStage, []string{deploymentManifestPath})
@@ -602,6 +669,24 @@ func rootDesc() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("asks the deployer to skip draining the jobs when --skip-drain is given", func() {
+			expectDeploy.Times(0)
+			mockDeployer.EXPECT().Deploy(
+				cloud,
+				boshDeploymentManifest,
+				cloudStemcell,
+				installationManifest.Registry,
+				fakeVMManager,
+				mockBlobstore,
+				false,
+				true,
+				gomock.Any(),
+			).Return(mock_deployment.NewMockDeployment(mockCtrl), nil)
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath, "--skip-drain"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
 		It("updates the deployment record", func() {
 			err := command.Run(fakeStage, []string{deploymentManifestPath})
 			Expect(err).NotTo(HaveOccurred())
@@ -626,6 +711,20 @@ func rootDesc() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		Context("when start finds no VM to start and the deployment has changed since the last deploy", func() {
+			It("returns an error without deploying", func() {
+				mockInstanceLifecycle := mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+				mockInstanceLifecycle.EXPECT().Start(fakeStage, false).Return(false, nil)
+				instanceLifecycleProvider := func(string) (bicmd.InstanceLifecycle, error) { return mockInstanceLifecycle, nil }
+
+				expectDeploy.Times(0)
+
+				err := bicmd.NewStartCmd(userInterface, fakeFs, logger, instanceLifecycleProvider, doGet).Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Deployment manifest, releases or stemcell changed since the last deploy, run 'bosh-init deploy /path/to/manifest.yml' to deploy them"))
+			})
+		})
+
 		Context("when deployment has not changed", func() {
 			JustBeforeEach(func() {
 				previousDeploymentState := biconfig.DeploymentState{
@@ -656,6 +755,51 @@ func rootDesc() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(stdOut).To(gbytes.Say("No deployment, stemcell or release changes. Skipping deploy."))
 			})
+
+			Context("when --recreate is given", func() {
+				It("deploys and asks the deployer to recreate the VM", func() {
+					expectDeploy.Times(0)
+					mockDeployer.EXPECT().Deploy(
+						cloud,
+						boshDeploymentManifest,
+						cloudStemcell,
+						installationManifest.Registry,
+						fakeVMManager,
+						mockBlobstore,
+						true,
+						false,
+						gomock.Any(),
+					).Return(mock_deployment.NewMockDeployment(mockCtrl), nil)
+
+					err := command.Run(fakeStage, []string{deploymentManifestPath, "--recreate"})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(stdOut).ToNot(gbytes.Say("Skipping deploy."))
+				})
+			})
+
+			Context("when start finds no VM to start", func() {
+				It("recreates the VM of the recorded deployment", func() {
+					mockInstanceLifecycle := mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+					mockInstanceLifecycle.EXPECT().Start(fakeStage, false).Return(false, nil)
+					instanceLifecycleProvider := func(string) (bicmd.InstanceLifecycle, error) { return mockInstanceLifecycle, nil }
+
+					expectDeploy.Times(0)
+					mockDeployer.EXPECT().Deploy(
+						cloud,
+						boshDeploymentManifest,
+						cloudStemcell,
+						installationManifest.Registry,
+						fakeVMManager,
+						mockBlobstore,
+						true,
+						false,
+						gomock.Any(),
+					).Return(mock_deployment.NewMockDeployment(mockCtrl), nil)
+
+					err := bicmd.NewStartCmd(userInterface, fakeFs, logger, instanceLifecycleProvider, doGet).Run(fakeStage, []string{deploymentManifestPath})
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
 		})
 
 		Context("when parsing the cpi deployment manifest fails", func() {
@@ -995,6 +1139,152 @@ func rootDesc() {
 			})
 		})
 
+		Context("when the deployment moves to another cloud provider", func() {
+			BeforeEach(func() {
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				deploymentState.CloudProvider = "fake-old-cloud-provider"
+				deploymentState.CurrentVMCID = "fake-old-vm-cid"
+				deploymentState.CurrentDiskID = "fake-old-disk-id"
+				deploymentState.Disks = []biconfig.DiskRecord{{ID: "fake-old-disk-id", CID: "fake-old-disk-cid", Size: 1024}}
+				err = setupDeploymentStateService.Save(deploymentState)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockCloudFactory.EXPECT().NewCloud(gomock.Any(), directorID, "fake-old-cloud-provider").Return(cloud, nil).AnyTimes()
+			})
+
+			It("keeps the resources of the previous cloud provider current until the VM runs in the new one", func() {
+				expectDeploy.Do(func(_, _, _, _, _, _, _, _ interface{}, _ biui.Stage) {
+					deploymentState, err := setupDeploymentStateService.Load()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deploymentState.CloudProvider).To(Equal("fake-old-cloud-provider"))
+					Expect(deploymentState.CurrentVMCID).To(Equal("fake-old-vm-cid"))
+					Expect(deploymentState.CurrentDiskID).To(Equal("fake-old-disk-id"))
+					Expect(deploymentState.CloudMigrationTarget).ToNot(BeNil())
+
+					// the VM manager of the move records the new VM apart from the current one
+					moveStateService := biconfig.NewCloudMigrationTargetStateService(setupDeploymentStateService)
+					err = biconfig.NewVMRepo(moveStateService).UpdateCurrent("fake-new-vm-cid")
+					Expect(err).ToNot(HaveOccurred())
+				}).Times(1)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(stdOut).To(gbytes.Say("Moving deployment from cloud provider 'fake-old-cloud-provider' to the default cloud provider"))
+				Expect(stdOut).To(gbytes.Say("The VM, disks and stemcells of cloud provider 'fake-old-cloud-provider' are kept until 'bosh-init clean-up' deletes them"))
+
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CloudProvider).To(BeEmpty())
+				Expect(deploymentState.CurrentVMCID).To(Equal("fake-new-vm-cid"))
+				Expect(deploymentState.CurrentDiskID).To(BeEmpty())
+				Expect(deploymentState.CloudMigrationTarget).To(BeNil())
+				Expect(deploymentState.CloudMigration).To(Equal(&biconfig.CloudMigrationRecord{
+					CloudProvider: "fake-old-cloud-provider",
+					VMCID:         "fake-old-vm-cid",
+					Disks:         []biconfig.DiskRecord{{ID: "fake-old-disk-id", CID: "fake-old-disk-cid", Size: 1024}},
+				}))
+			})
+
+			It("keeps the resources of the previous cloud provider current when the deploy fails", func() {
+				expectDeploy.Return(nil, errors.New("fake-deploy-error")).Times(1)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-deploy-error"))
+
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CloudProvider).To(Equal("fake-old-cloud-provider"))
+				Expect(deploymentState.CurrentVMCID).To(Equal("fake-old-vm-cid"))
+				Expect(deploymentState.CurrentDiskID).To(Equal("fake-old-disk-id"))
+				Expect(deploymentState.CloudMigration).To(BeNil())
+				Expect(deploymentState.CloudMigrationTarget).ToNot(BeNil())
+			})
+
+			It("creates the persistent disks empty and tells to copy their data", func() {
+				boshDeploymentManifest.Jobs[0].PersistentDisk = 2048
+				expectDeploy.Times(1)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(stdOut).To(gbytes.Say("Persistent disks are created empty, copy their data from the disks of cloud provider 'fake-old-cloud-provider' before running 'bosh-init clean-up'"))
+			})
+
+			It("deploys back to the previous cloud provider with its kept resources", func() {
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				deploymentState.CloudMigration = &biconfig.CloudMigrationRecord{VMCID: "fake-previous-vm-cid"}
+				err = setupDeploymentStateService.Save(deploymentState)
+				Expect(err).ToNot(HaveOccurred())
+
+				expectDeploy.Do(func(_, _, _, _, _, _, _, _ interface{}, _ biui.Stage) {
+					deploymentState, err := setupDeploymentStateService.Load()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deploymentState.CurrentVMCID).To(Equal("fake-old-vm-cid"))
+					Expect(deploymentState.CloudMigrationTarget.CurrentVMCID).To(Equal("fake-previous-vm-cid"))
+				}).Times(1)
+
+				err = command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+
+				deploymentState, err = setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CurrentVMCID).To(Equal("fake-previous-vm-cid"))
+				Expect(deploymentState.CloudMigration.VMCID).To(Equal("fake-old-vm-cid"))
+			})
+
+			It("returns an error without deploying when the resources of an earlier move are not deleted yet", func() {
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				deploymentState.CloudMigration = &biconfig.CloudMigrationRecord{CloudProvider: "fake-older-cloud-provider", VMCID: "fake-older-vm-cid"}
+				err = setupDeploymentStateService.Save(deploymentState)
+				Expect(err).ToNot(HaveOccurred())
+
+				expectDeploy.Times(0)
+
+				err = command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("run 'bosh-init clean-up' before moving the deployment to another cloud provider"))
+			})
+		})
+
+		Context("when the deploy is interrupted", func() {
+			BeforeEach(func() {
+				mockDeployer.EXPECT().Deploy(
+					cloud,
+					boshDeploymentManifest,
+					cloudStemcell,
+					installationManifest.Registry,
+					fakeVMManager,
+					mockBlobstore,
+					gomock.Any(),
+					gomock.Any(),
+					gomock.Any(),
+				).Do(func(_, _, _, _, _, _, _, _ interface{}, _ biui.Stage) {
+					deploymentState, err := setupDeploymentStateService.Load()
+					Expect(err).ToNot(HaveOccurred())
+					deploymentState.CurrentVMCID = "fake-vm-cid"
+					deploymentState.Disks = []biconfig.DiskRecord{{ID: "fake-disk-id", Name: "fake-disk-name", CID: "fake-disk-cid"}}
+					err = setupDeploymentStateService.Save(deploymentState)
+					Expect(err).ToNot(HaveOccurred())
+				}).Return(nil, bosherr.WrapError(biui.NewInterruptedError("Creating disk"), "Creating instance")).AnyTimes()
+			})
+
+			It("prints the resources recorded in the deployment state and how to resume", func() {
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(biui.IsInterrupted(err)).To(BeTrue())
+
+				Expect(stdErr).To(gbytes.Say("Interrupted, the deployment state records these resources:"))
+				Expect(stdErr).To(gbytes.Say("  VM 'fake-vm-cid'"))
+				Expect(stdErr).To(gbytes.Say(`  Disk 'fake-disk-cid' \(name: fake-disk-name\)`))
+				Expect(stdErr).To(gbytes.Say("Run 'bosh-init deploy /path/to/manifest.yml' to resume, or 'bosh-init delete /path/to/manifest.yml' to delete them."))
+			})
+		})
+
 		Context("when deploy fails", func() {
 			BeforeEach(func() {
 				mockDeployer.EXPECT().Deploy(
@@ -1005,6 +1295,8 @@ func rootDesc() {
 					fakeVMManager,
 					mockBlobstore,
 					gomock.Any(),
+					gomock.Any(),
+					gomock.Any(),
 				).Return(nil, errors.New("fake-deploy-error")).AnyTimes()
 
 				previousDeploymentState := biconfig.DeploymentState{
@@ -1032,6 +1324,33 @@ func rootDesc() {
 				Expect(deploymentState.Releases).To(Equal([]biconfig.ReleaseRecord{}))
 				Expect(deploymentState.CurrentReleaseIDs).To(Equal([]string{}))
 			})
+
+			It("does not fetch logs", func() {
+				mockLogsFetcher.EXPECT().Fetch(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+			})
+
+			Context("when --fetch-logs-on-failure is given", func() {
+				It("fetches the logs next to the deployment manifest", func() {
+					mockLogsFetcher.EXPECT().Fetch(mockAgentClient, mockBlobstore, "", "/path/to").Return("/path/to/logs-fake-timestamp", nil)
+
+					err := command.Run(fakeStage, []string{deploymentManifestPath, "--fetch-logs-on-failure"})
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-deploy-error"))
+					Expect(stdOut).To(gbytes.Say("Logs saved to '/path/to/logs-fake-timestamp'"))
+				})
+
+				It("returns the deploy error when fetching the logs fails", func() {
+					mockLogsFetcher.EXPECT().Fetch(mockAgentClient, mockBlobstore, "", "/path/to").Return("", errors.New("fake-fetch-error"))
+
+					err := command.Run(fakeStage, []string{deploymentManifestPath, "--fetch-logs-on-failure"})
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-deploy-error"))
+					Expect(stdErr).To(gbytes.Say("Failed to fetch logs from the deployed VM: fake-fetch-error"))
+				})
+			})
 		})
 
 		Context("when compiled releases are being used", func() {
@@ -1110,22 +1429,122 @@ func rootDesc() {
 			It("returns error if compiled package stemcell does not match the deployment stemcell", func() {
 				fakeOtherRelease.ReleasePackages = []*bipkg.Package{
 					{
+						Name:     "fake-package-1",
+						Stemcell: "ubuntu-trusty/fake-stemcell-version",
+					},
+					{
+						Name:     "fake-package-2",
 						Stemcell: "ubuntu-trusty/wrong-version",
 					},
+					{
+						Name:     "fake-package-3",
+						Stemcell: "centos-7/fake-stemcell-version",
+					},
 				}
 
 				err := command.Run(fakeStage, []string{deploymentManifestPath})
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("OS/Version mismatch between deployment stemcell and compiled package stemcell for release 'other-release'"))
+				Expect(err.Error()).To(ContainSubstring("OS/Version mismatch between deployment stemcell 'ubuntu-trusty/fake-stemcell-version' and compiled packages of release 'other-release' (stemcell compatibility: exact)"))
+				Expect(err.Error()).ToNot(ContainSubstring("fake-package-1"))
+				Expect(err.Error()).To(ContainSubstring("Package 'fake-package-2' was compiled against stemcell 'ubuntu-trusty/wrong-version'"))
+				Expect(err.Error()).To(ContainSubstring("Package 'fake-package-3' was compiled against stemcell 'centos-7/fake-stemcell-version'"))
+			})
+
+			Context("when the stemcell has a newer minor version", func() {
+				BeforeEach(func() {
+					fakeOtherRelease.ReleasePackages = []*bipkg.Package{
+						{
+							Name:     "fake-package-1",
+							Stemcell: "ubuntu-trusty/fake-stemcell-version.1",
+						},
+					}
+				})
+
+				It("returns an error with the exact stemcell compatibility", func() {
+					err := command.Run(fakeStage, []string{deploymentManifestPath})
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Package 'fake-package-1' was compiled against stemcell 'ubuntu-trusty/fake-stemcell-version.1'"))
+				})
+
+				It("uses the compiled packages with the major stemcell compatibility", func() {
+					err := command.Run(fakeStage, []string{deploymentManifestPath, "--stemcell-compatibility=major"})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fakeOtherRelease.ReleasePackages[0].Stemcell).To(Equal("ubuntu-trusty/fake-stemcell-version.1"))
+				})
+			})
+
+			It("returns an error for an unknown stemcell compatibility", func() {
+				err := command.Run(fakeStage, []string{deploymentManifestPath, "--stemcell-compatibility=fake-policy"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+				Expect(err.Error()).To(ContainSubstring("Unknown stemcell compatibility 'fake-policy'"))
+			})
+
+			Context("when the compiled release also contains source packages", func() {
+				BeforeEach(func() {
+					fakeOtherRelease.ReleasePackages = []*bipkg.Package{
+						{
+							Name:          "fake-package-1",
+							SHA1:          "fake-compiled-sha1",
+							Stemcell:      "ubuntu-trusty/wrong-version",
+							ArchivePath:   "/release/compiled_packages/fake-package-1.tgz",
+							ExtractedPath: "/release/extracted_packages/fake-package-1",
+							Source: &bipkg.Package{
+								Name:          "fake-package-1",
+								SHA1:          "fake-source-sha1",
+								ArchivePath:   "/release/packages/fake-package-1.tgz",
+								ExtractedPath: "/release/extracted_source_packages/fake-package-1",
+							},
+						},
+					}
+				})
+
+				It("falls back to compiling the source packages", func() {
+					err := command.Run(fakeStage, []string{deploymentManifestPath})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeOtherRelease.ReleasePackages[0]).To(Equal(&bipkg.Package{
+						Name:          "fake-package-1",
+						SHA1:          "fake-source-sha1",
+						ArchivePath:   "/release/packages/fake-package-1.tgz",
+						ExtractedPath: "/release/extracted_source_packages/fake-package-1",
+					}))
+
+					Expect(fakeStage.PerformCalls[0].Stage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{
+						Name: "Falling back to compiling release 'other-release' from source",
+					}))
+				})
 			})
 
 			It("returns error if CPI release is compiled", func() {
 				fakeCPIRelease.ReleaseIsCompiled = true
+				fakeCPIRelease.ReleasePackages = []*bipkg.Package{
+					{
+						Name:     "fake-cpi-package",
+						Stemcell: "ubuntu-trusty/fake-stemcell-version",
+					},
+				}
 
 				err := command.Run(fakeStage, []string{deploymentManifestPath})
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("CPI is not allowed to be a compiled release. The provided CPI release 'fake-cpi-release-name' is compiled"))
 			})
+
+			It("compiles the CPI release from source if it is compiled and contains source packages", func() {
+				fakeCPIRelease.ReleaseIsCompiled = true
+				fakeCPIRelease.ReleasePackages = []*bipkg.Package{
+					{
+						Name:     "fake-cpi-package",
+						Stemcell: "ubuntu-trusty/fake-stemcell-version",
+						Source:   &bipkg.Package{Name: "fake-cpi-package", SHA1: "fake-source-sha1"},
+					},
+				}
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeCPIRelease.ReleasePackages[0].IsCompiled()).To(BeFalse())
+				Expect(fakeCPIRelease.ReleasePackages[0].SHA1).To(Equal("fake-source-sha1"))
+			})
 		})
 	})
 }
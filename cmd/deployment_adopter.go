@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"fmt"
+
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	biinstall "github.com/cloudfoundry/bosh-init/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type DeploymentAdopter interface {
+	// Adopt creates a new deployment state with an existing VM and persistent disk as the deployed instance,
+	// after checking them with the CPI and with the agent on the VM, so that the next deploy keeps the disk.
+	// The stemcell CID is optional; when given, it is recorded as the uploaded stemcell of the deployment manifest
+	// and the VM is recorded with the configuration of the manifest, so that the next deploy updates the VM in place.
+	// Without it, the next deploy uploads the stemcell and recreates the VM.
+	Adopt(stage biui.Stage, vmCID string, diskCID string, stemcellCID string, forceLock bool) error
+}
+
+func NewDeploymentAdopter(
+	deploymentContext DeploymentContext,
+	diskRepo biconfig.DiskRepo,
+	vmRepo biconfig.VMRepo,
+	stemcellRepo biconfig.StemcellRepo,
+	cloudMigrationRepo biconfig.CloudMigrationRepo,
+	stemcellFetcher bistemcell.Fetcher,
+) DeploymentAdopter {
+	return &deploymentAdopter{
+		DeploymentContext:  deploymentContext,
+		diskRepo:           diskRepo,
+		vmRepo:             vmRepo,
+		stemcellRepo:       stemcellRepo,
+		cloudMigrationRepo: cloudMigrationRepo,
+		stemcellFetcher:    stemcellFetcher,
+	}
+}
+
+type deploymentAdopter struct {
+	DeploymentContext
+	diskRepo           biconfig.DiskRepo
+	vmRepo             biconfig.VMRepo
+	stemcellRepo       biconfig.StemcellRepo
+	cloudMigrationRepo biconfig.CloudMigrationRepo
+	stemcellFetcher    bistemcell.Fetcher
+}
+
+func (a *deploymentAdopter) Adopt(stage biui.Stage, vmCID string, diskCID string, stemcellCID string, forceLock bool) (err error) {
+	a.UI.PrintLinef("Deployment state: '%s'", a.DeploymentStateService.Path())
+
+	stateLock, err := a.lockDeploymentState(forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, a.Logger, a.LogTag)
+
+	if a.DeploymentStateService.Exists() {
+		return bosherr.Errorf("Deployment state already exists at '%s', adopt only creates a new deployment state", a.DeploymentStateService.Path())
+	}
+
+	// loading a missing deployment state creates it with a new director ID
+	deploymentState, err := a.DeploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Creating deployment state")
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// leave nothing behind, so that adopt can be run again
+		cleanupErr := a.DeploymentStateService.Cleanup()
+		if cleanupErr != nil {
+			a.Logger.Warn(a.LogTag, "Failed to delete the deployment state of the failed adoption: %s", cleanupErr.Error())
+		}
+	}()
+
+	return a.withInstallation(stage, forceLock, func(installation biinstall.Installation, installationManifest biinstallmanifest.Manifest, deploymentManifest bideplmanifest.Manifest) error {
+		cloudProvider, err := deploymentCloudProvider(deploymentManifest, installationManifest)
+		if err != nil {
+			return err
+		}
+
+		// the new deployment state has no resources yet, only its cloud provider is recorded
+		_, _, err = a.cloudMigrationRepo.StartMove(cloudProvider)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving cloud provider")
+		}
+
+		// the VM manager finds the current VM with a client for its agent
+		err = a.vmRepo.UpdateCurrent(vmCID)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving VM record")
+		}
+
+		cloud, vmManager, _, err := a.newCloud(installation, installationManifest, deploymentState.DirectorID, cloudProvider)
+		if err != nil {
+			return err
+		}
+
+		persistentDisk, err := adoptablePersistentDisk(deploymentManifest)
+		if err != nil {
+			return err
+		}
+
+		vm, found, err := vmManager.FindCurrent()
+		if err != nil {
+			return bosherr.WrapError(err, "Finding VM")
+		}
+		if !found {
+			return bosherr.Errorf("VM '%s' was not recorded", vmCID)
+		}
+
+		err = stage.Perform(fmt.Sprintf("Checking VM '%s'", vmCID), func() error {
+			exists, err := vm.Exists()
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return bosherr.Errorf("VM '%s' does not exist", vmCID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = stage.Perform(fmt.Sprintf("Checking disk '%s'", diskCID), func() error {
+			exists, err := cloud.HasDisk(diskCID)
+			if err != nil {
+				cloudErr, ok := err.(bicloud.Error)
+				if !ok || cloudErr.Type() != bicloud.NotImplementedError {
+					return bosherr.WrapErrorf(err, "Checking existence of disk '%s'", diskCID)
+				}
+
+				// without has_disk only a disk the agent lists as attached is known to exist
+				a.Logger.Info(a.LogTag, "CPI does not implement has_disk, asking the agent on VM '%s' for disk '%s'", vmCID, diskCID)
+				attached, err := diskAttached(vm, diskCID)
+				if err != nil {
+					return err
+				}
+				if !attached {
+					return bosherr.Errorf("Disk '%s' is not attached to VM '%s' and the CPI cannot check that it exists", diskCID, vmCID)
+				}
+				return nil
+			}
+			if !exists {
+				return bosherr.Errorf("Disk '%s' does not exist", diskCID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var agentState biagent.AgentState
+		err = stage.Perform(fmt.Sprintf("Checking agent on VM '%s'", vmCID), func() error {
+			_, err := vm.AgentClient().Ping()
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Contacting the agent on VM '%s'", vmCID)
+			}
+
+			agentState, err = vm.GetFullState()
+			if err != nil {
+				return err
+			}
+
+			err = checkAdoptedApplySpec(agentState, deploymentManifest)
+			if err != nil {
+				return err
+			}
+
+			attached, err := diskAttached(vm, diskCID)
+			if err != nil {
+				return err
+			}
+			if !attached {
+				return bosherr.Errorf("Disk '%s' is not attached to VM '%s'", diskCID, vmCID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if stemcellCID != "" {
+			err = a.adoptStemcell(stage, deploymentManifest, stemcellCID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = stage.Perform("Saving deployment state", func() error {
+			diskRecord, err := a.diskRepo.Save(persistentDisk.Name, diskCID, persistentDisk.DiskPool.DiskSize, persistentDisk.DiskPool.CloudProperties)
+			if err != nil {
+				return bosherr.WrapError(err, "Saving disk record")
+			}
+
+			err = a.diskRepo.UpdateCurrent(diskRecord.ID)
+			if err != nil {
+				return bosherr.WrapError(err, "Updating current disk record")
+			}
+
+			// with its stemcell known, the VM is recorded with the configuration of the manifest,
+			// so that the next deploy updates it in place instead of recreating it
+			if stemcellCID != "" {
+				vmConfig, err := bivm.NewConfigRecord(stemcellCID, deploymentManifest)
+				if err != nil {
+					return err
+				}
+
+				err = a.vmRepo.UpdateCurrentConfig(vmConfig)
+				if err != nil {
+					return bosherr.WrapError(err, "Updating current vm config record")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if agentState.ApplySpec.Job.Name != "" {
+			a.UI.PrintLinef("Agent '%s' runs job '%s' of deployment '%s'", agentState.AgentID, agentState.ApplySpec.Job.Name, agentState.ApplySpec.Deployment)
+		} else {
+			a.UI.PrintLinef("Agent '%s' has no jobs applied yet", agentState.AgentID)
+		}
+		return nil
+	})
+}
+
+// diskAttached asks the agent on the VM whether the disk is attached
+func diskAttached(vm bivm.VM, diskCID string) (bool, error) {
+	disks, err := vm.Disks()
+	if err != nil {
+		return false, err
+	}
+
+	for _, disk := range disks {
+		if disk.CID() == diskCID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// adoptablePersistentDisk returns the persistent disk configured for the job of the deployment manifest.
+// A disk can only be adopted with its configuration, which is taken from the manifest.
+func adoptablePersistentDisk(deploymentManifest bideplmanifest.Manifest) (bideplmanifest.PersistentDisk, error) {
+	persistentDisks, err := deploymentManifest.PersistentDisks(deploymentManifest.JobName())
+	if err != nil {
+		return bideplmanifest.PersistentDisk{}, err
+	}
+
+	if len(persistentDisks) != 1 {
+		return bideplmanifest.PersistentDisk{}, bosherr.Errorf("Adopting a disk requires exactly one persistent disk in the deployment manifest, found %d", len(persistentDisks))
+	}
+
+	return persistentDisks[0], nil
+}
+
+// checkAdoptedApplySpec checks that the agent runs the deployment of the manifest, if anything was applied to it
+func checkAdoptedApplySpec(agentState biagent.AgentState, deploymentManifest bideplmanifest.Manifest) error {
+	applySpec := agentState.ApplySpec
+
+	if applySpec.Deployment != "" && applySpec.Deployment != deploymentManifest.Name {
+		return bosherr.Errorf("Agent '%s' belongs to deployment '%s', not to '%s'", agentState.AgentID, applySpec.Deployment, deploymentManifest.Name)
+	}
+
+	if applySpec.Job.Name != "" && applySpec.Job.Name != deploymentManifest.JobName() {
+		return bosherr.Errorf("Agent '%s' runs job '%s', not '%s'", agentState.AgentID, applySpec.Job.Name, deploymentManifest.JobName())
+	}
+
+	return nil
+}
+
+// adoptStemcell records the stemcell of the deployment manifest as uploaded with the CID
+func (a *deploymentAdopter) adoptStemcell(stage biui.Stage, deploymentManifest bideplmanifest.Manifest, stemcellCID string) error {
+	extractedStemcell, err := a.stemcellFetcher.GetStemcell(deploymentManifest, stage)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		deleteErr := extractedStemcell.Delete()
+		if deleteErr != nil {
+			a.Logger.Warn(a.LogTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
+		}
+	}()
+
+	stemcellManifest := extractedStemcell.Manifest()
+	stepName := fmt.Sprintf("Recording stemcell '%s/%s' as '%s'", stemcellManifest.Name, stemcellManifest.Version, stemcellCID)
+	return stage.Perform(stepName, func() error {
+		stemcellRecord, err := a.stemcellRepo.Save(stemcellManifest.Name, stemcellManifest.Version, stemcellCID)
+		if err != nil {
+			return bosherr.WrapError(err, "Saving stemcell record")
+		}
+
+		err = a.stemcellRepo.UpdateCurrent(stemcellRecord.ID)
+		if err != nil {
+			return bosherr.WrapError(err, "Updating current stemcell record")
+		}
+		return nil
+	})
+}
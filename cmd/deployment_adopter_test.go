@@ -0,0 +1,224 @@
+package cmd_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+
+	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	"github.com/golang/mock/gomock"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("DeploymentAdopter", func() {
+	var (
+		mockCtrl *gomock.Controller
+		fixture  *deploymentContextFixture
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		fixture = newDeploymentContextFixture(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var newDeploymentAdopter = func() bicmd.DeploymentAdopter {
+		deploymentContext := fixture.newDeploymentContext("deploymentAdopter")
+		deploymentStateService := deploymentContext.DeploymentStateService
+		return bicmd.NewDeploymentAdopter(
+			deploymentContext,
+			biconfig.NewDiskRepo(deploymentStateService, fixture.fakeUUIDGenerator),
+			biconfig.NewVMRepo(deploymentStateService),
+			biconfig.NewStemcellRepo(deploymentStateService, fixture.fakeUUIDGenerator),
+			biconfig.NewCloudMigrationRepo(deploymentStateService),
+			fixture.stemcellFetcher(),
+		)
+	}
+
+	Describe("Adopt", func() {
+		BeforeEach(func() {
+			err := fixture.fs.RemoveAll(biconfig.DeploymentStatePath(fixture.deploymentManifestPath))
+			Expect(err).ToNot(HaveOccurred())
+
+			// the new deployment state gets the first generated uuid as its director ID
+			fixture.mockCloudFactory.EXPECT().NewCloud(fixture.fakeInstallation, "fake-uuid-0", "").Return(fixture.mockCloud, nil).AnyTimes()
+			fixture.mockAgentClientFactory.EXPECT().NewAgentClient("fake-uuid-0", fixture.mbusURL).Return(fixture.mockAgentClient).AnyTimes()
+
+			fixture.fakeDeploymentParser.ParseManifest.Name = "fake-deployment-name"
+			fixture.fakeDeploymentParser.ParseManifest.Jobs = []bideplmanifest.Job{{Name: "fake-job-name", ResourcePool: "fake-resource-pool", PersistentDisk: 1024}}
+			fixture.fakeDeploymentParser.ParseManifest.ResourcePools = []bideplmanifest.ResourcePool{{Name: "fake-resource-pool"}}
+
+			fixture.fakeVM.AgentClientReturn = fixture.mockAgentClient
+			fixture.fakeVM.ExistsFound = true
+			fixture.fakeVM.ListDisksDisks = []bidisk.Disk{bidisk.NewDisk(biconfig.DiskRecord{CID: "fake-disk-cid"}, nil, nil)}
+			fixture.fakeVM.GetFullStateResult = biagent.AgentState{
+				AgentID: "fake-agent-id",
+				ApplySpec: bias.ApplySpec{
+					Deployment: "fake-deployment-name",
+					Job:        bias.Job{Name: "fake-job-name"},
+				},
+			}
+		})
+
+		It("does not create a deployment state when locking the installation fails", func() {
+			fixture.mockLocker.EXPECT().Lock("/deployment-dir/fake-deployment-manifest-state.json.lock", true).Return(fixture.mockLock, nil)
+			fixture.mockLocker.EXPECT().Lock("fake-install-dir/fake-installation-id.lock", true).Return(nil, errors.New("fake-lock-error"))
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Locking the installation: fake-lock-error"))
+			Expect(fixture.setupDeploymentStateService.Exists()).To(BeFalse())
+		})
+
+		It("creates a deployment state with the VM and the disk as the current ones", func() {
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(true, nil)
+			fixture.mockAgentClient.EXPECT().Ping().Return("pong", nil)
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.DirectorID).To(Equal("fake-uuid-0"))
+			Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid"))
+			Expect(deploymentState.Disks).To(Equal([]biconfig.DiskRecord{
+				{ID: "fake-uuid-1", CID: "fake-disk-cid", Size: 1024, CloudProperties: biproperty.Map{}},
+			}))
+			Expect(deploymentState.CurrentDiskID).To(Equal("fake-uuid-1"))
+			Expect(deploymentState.CurrentStemcellID).To(BeEmpty())
+			Expect(deploymentState.CurrentVMConfig).To(BeNil())
+
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Checking VM 'fake-vm-cid'"}))
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Checking disk 'fake-disk-cid'"}))
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Checking agent on VM 'fake-vm-cid'"}))
+			Expect(fixture.fakeUI.Said).To(ContainElement("Agent 'fake-agent-id' runs job 'fake-job-name' of deployment 'fake-deployment-name'"))
+		})
+
+		It("records the stemcell of the deployment manifest with --stemcell-cid", func() {
+			fixture.fakeDeploymentParser.ParseManifest.ResourcePools = []bideplmanifest.ResourcePool{
+				{
+					Name:     "fake-resource-pool",
+					Stemcell: bideplmanifest.StemcellRef{URL: "file:///fake-stemcell.tgz"},
+				},
+			}
+			fixture.fs.WriteFileString("/fake-stemcell.tgz", "fake-tgz-content")
+			extractedStemcell := bistemcell.NewExtractedStemcell(
+				bistemcell.Manifest{Name: "fake-stemcell-name", Version: "fake-stemcell-version"},
+				"/fake-stemcell-extracted-dir",
+				fixture.fs,
+			)
+			fixture.fakeStemcellExtractor.SetExtractBehavior("/fake-stemcell.tgz", extractedStemcell, nil)
+
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(true, nil)
+			fixture.mockAgentClient.EXPECT().Ping().Return("pong", nil)
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "fake-stemcell-cid", false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Stemcells).To(HaveLen(1))
+			Expect(deploymentState.Stemcells[0].Name).To(Equal("fake-stemcell-name"))
+			Expect(deploymentState.Stemcells[0].Version).To(Equal("fake-stemcell-version"))
+			Expect(deploymentState.Stemcells[0].CID).To(Equal("fake-stemcell-cid"))
+			Expect(deploymentState.CurrentStemcellID).To(Equal(deploymentState.Stemcells[0].ID))
+
+			// the next deploy compares the manifest with the recorded configuration to update the VM in place
+			Expect(deploymentState.CurrentVMConfig).ToNot(BeNil())
+			Expect(deploymentState.CurrentVMConfig.StemcellCID).To(Equal("fake-stemcell-cid"))
+		})
+
+		It("asks the agent for the disk when the CPI cannot check that it exists", func() {
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(false, bicloud.NewCPIError("has_disk", bicloud.CmdError{Type: bicloud.NotImplementedError}))
+			fixture.mockAgentClient.EXPECT().Ping().Return("pong", nil)
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Disks[0].CID).To(Equal("fake-disk-cid"))
+		})
+
+		It("returns an error when the CPI cannot check the disk and the agent does not list it", func() {
+			fixture.fakeVM.ListDisksDisks = []bidisk.Disk{}
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(false, bicloud.NewCPIError("has_disk", bicloud.CmdError{Type: bicloud.NotImplementedError}))
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Disk 'fake-disk-cid' is not attached to VM 'fake-vm-cid' and the CPI cannot check that it exists"))
+			Expect(fixture.setupDeploymentStateService.Exists()).To(BeFalse())
+		})
+
+		It("returns an error when the deployment state already exists", func() {
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment state already exists at '/deployment-dir/fake-deployment-manifest-state.json', adopt only creates a new deployment state"))
+		})
+
+		It("returns an error and leaves no deployment state behind when the VM does not exist", func() {
+			fixture.fakeVM.ExistsFound = false
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VM 'fake-vm-cid' does not exist"))
+			Expect(fixture.setupDeploymentStateService.Exists()).To(BeFalse())
+		})
+
+		It("returns an error when the disk does not exist", func() {
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(false, nil)
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Disk 'fake-disk-cid' does not exist"))
+			Expect(fixture.setupDeploymentStateService.Exists()).To(BeFalse())
+		})
+
+		It("returns an error when the agent runs another deployment", func() {
+			fixture.fakeVM.GetFullStateResult.ApplySpec.Deployment = "other-deployment-name"
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(true, nil)
+			fixture.mockAgentClient.EXPECT().Ping().Return("pong", nil)
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Agent 'fake-agent-id' belongs to deployment 'other-deployment-name', not to 'fake-deployment-name'"))
+			Expect(fixture.setupDeploymentStateService.Exists()).To(BeFalse())
+		})
+
+		It("returns an error when the disk is not attached to the VM", func() {
+			fixture.fakeVM.ListDisksDisks = []bidisk.Disk{}
+			fixture.mockCloud.EXPECT().HasDisk("fake-disk-cid").Return(true, nil)
+			fixture.mockAgentClient.EXPECT().Ping().Return("pong", nil)
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Disk 'fake-disk-cid' is not attached to VM 'fake-vm-cid'"))
+		})
+
+		It("returns an error when the manifest does not configure exactly one persistent disk", func() {
+			fixture.fakeDeploymentParser.ParseManifest.Jobs[0].PersistentDisk = 0
+
+			err := newDeploymentAdopter().Adopt(fixture.fakeStage, "fake-vm-cid", "fake-disk-cid", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Adopting a disk requires exactly one persistent disk in the deployment manifest, found 0"))
+		})
+	})
+})
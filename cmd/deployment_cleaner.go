@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	biinstall "github.com/cloudfoundry/bosh-init/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type DeploymentCleaner interface {
+	// CleanUp deletes the resources left in the previous cloud provider after the deployment moved to another one,
+	// the orphaned persistent disks and the unused stemcells, except the stemcell uploaded last.
+	// When all is true the stemcell uploaded last is deleted too.
+	// The tarball cache is shared by all deployments, it is only pruned when pruneCache is true:
+	// the cached release and stemcell tarballs that the deployment manifest does not refer to are deleted,
+	// or all of them when all is true.
+	CleanUp(stage biui.Stage, all bool, pruneCache bool, forceLock bool) error
+}
+
+func NewDeploymentCleaner(
+	deploymentContext DeploymentContext,
+	cloudMigrationRepo biconfig.CloudMigrationRepo,
+	stemcellManagerFactory bistemcell.ManagerFactory,
+	diskManagerFactory bidisk.ManagerFactory,
+	tarballCache bitarball.Cache,
+) DeploymentCleaner {
+	return &deploymentCleaner{
+		DeploymentContext:      deploymentContext,
+		cloudMigrationRepo:     cloudMigrationRepo,
+		stemcellManagerFactory: stemcellManagerFactory,
+		diskManagerFactory:     diskManagerFactory,
+		tarballCache:           tarballCache,
+	}
+}
+
+type deploymentCleaner struct {
+	DeploymentContext
+	cloudMigrationRepo     biconfig.CloudMigrationRepo
+	stemcellManagerFactory bistemcell.ManagerFactory
+	diskManagerFactory     bidisk.ManagerFactory
+	tarballCache           bitarball.Cache
+}
+
+func (c *deploymentCleaner) CleanUp(stage biui.Stage, all bool, pruneCache bool, forceLock bool) error {
+	stateLock, err := c.lockDeploymentState(forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, c.Logger, c.LogTag)
+
+	deploymentState, err := c.loadDeploymentState()
+	if err != nil {
+		return err
+	}
+
+	// the disk created by an interrupted migration is not current yet, but must not be deleted
+	if deploymentState.CurrentDiskMigration != nil {
+		return bosherr.Errorf("The migration of disk '%s' was interrupted, run 'bosh-init deploy' to finish it before cleaning up", deploymentState.CurrentDiskMigration.OriginalDiskCID)
+	}
+
+	var keptTarballs []bitarball.Source
+	err = c.withInstallation(stage, forceLock, func(installation biinstall.Installation, installationManifest biinstallmanifest.Manifest, deploymentManifest bideplmanifest.Manifest) error {
+		err := deleteCloudMigration(stage, c.CloudFactory, c.cloudMigrationRepo, installation, deploymentState.DirectorID)
+		if err != nil {
+			return err
+		}
+
+		cloud, _, _, err := c.newCloud(installation, installationManifest, deploymentState.DirectorID, deploymentState.CloudProvider)
+		if err != nil {
+			return err
+		}
+
+		diskManager := c.diskManagerFactory.NewManager(cloud)
+		err = diskManager.DeleteUnused(stage)
+		if err != nil {
+			return err
+		}
+
+		err = diskManager.DeleteOrphaned(stage)
+		if err != nil {
+			return err
+		}
+
+		stemcellManager := c.stemcellManagerFactory.NewManager(cloud)
+		if all {
+			err = stemcellManager.DeleteUnused(stage)
+		} else {
+			err = stemcellManager.DeleteUnusedExceptLatest(stage)
+		}
+		if err != nil {
+			return err
+		}
+
+		if pruneCache && !all {
+			keptTarballs, err = c.tarballsInUse(deploymentManifest)
+		}
+		return err
+	})
+	if err != nil || !pruneCache {
+		return err
+	}
+
+	return stage.Perform("Deleting cached tarballs", func() error {
+		deletedPaths, err := c.tarballCache.Prune(keptTarballs)
+		if err != nil {
+			return err
+		}
+
+		if len(deletedPaths) == 0 {
+			return biui.NewSkipStageError(bosherr.Error("No cached tarballs to delete"), "No cached tarballs")
+		}
+
+		for _, deletedPath := range deletedPaths {
+			c.Logger.Info(c.LogTag, "Deleted cached tarball '%s'", deletedPath)
+		}
+		return nil
+	})
+}
+
+// tarballsInUse returns the releases and stemcell that the deployment manifest refers to
+func (c *deploymentCleaner) tarballsInUse(deploymentManifest bideplmanifest.Manifest) ([]bitarball.Source, error) {
+	releaseSetManifest, _, err := c.ReleaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.DeploymentManifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []bitarball.Source{}
+	for _, releaseRef := range releaseSetManifest.Releases {
+		sources = append(sources, releaseRef)
+	}
+
+	if len(deploymentManifest.Jobs) > 0 {
+		stemcellRef, err := deploymentManifest.Stemcell(deploymentManifest.JobName())
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Finding the stemcell of the deployment manifest")
+		}
+		sources = append(sources, stemcellRef)
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,229 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
+	"github.com/golang/mock/gomock"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("DeploymentCleaner", func() {
+	var (
+		mockCtrl *gomock.Controller
+		fixture  *deploymentContextFixture
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		fixture = newDeploymentContextFixture(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var newDeploymentCleaner = func() bicmd.DeploymentCleaner {
+		deploymentContext := fixture.newDeploymentContext("deploymentCleaner")
+		deploymentStateService := deploymentContext.DeploymentStateService
+		return bicmd.NewDeploymentCleaner(
+			deploymentContext,
+			biconfig.NewCloudMigrationRepo(deploymentStateService),
+			bistemcell.NewManagerFactory(biconfig.NewStemcellRepo(deploymentStateService, fixture.fakeUUIDGenerator)),
+			bidisk.NewManagerFactory(biconfig.NewDiskRepo(deploymentStateService, fixture.fakeUUIDGenerator), fixture.logger),
+			fixture.tarballCache,
+		)
+	}
+
+	Describe("CleanUp", func() {
+		var (
+			unusedTarballSource  bideplmanifest.StemcellRef
+			cachedStemcellSource bideplmanifest.StemcellRef
+		)
+
+		BeforeEach(func() {
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:        "fake-director-id",
+				CurrentVMCID:      "fake-vm-cid",
+				CurrentStemcellID: "fake-current-stemcell-id",
+				CurrentDiskID:     "fake-current-disk-id",
+				Disks: []biconfig.DiskRecord{
+					{ID: "fake-orphaned-disk-id", CID: "fake-orphaned-disk-cid"},
+					{ID: "fake-current-disk-id", CID: "fake-current-disk-cid"},
+				},
+				Stemcells: []biconfig.StemcellRecord{
+					{ID: "fake-old-stemcell-id", Name: "fake-stemcell-name", Version: "fake-old-version", CID: "fake-old-stemcell-cid"},
+					{ID: "fake-current-stemcell-id", Name: "fake-stemcell-name", Version: "fake-current-version", CID: "fake-current-stemcell-cid"},
+					{ID: "fake-uploaded-stemcell-id", Name: "fake-stemcell-name", Version: "fake-uploaded-version", CID: "fake-uploaded-stemcell-cid"},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			fixture.fakeDeploymentParser.ParseManifest.Jobs = []bideplmanifest.Job{{Name: "fake-job-name", ResourcePool: "fake-resource-pool"}}
+			cachedStemcellSource = bideplmanifest.StemcellRef{URL: "https://fake-stemcell-url", SHA1: "fake-stemcell-sha1"}
+			fixture.fakeDeploymentParser.ParseManifest.ResourcePools = []bideplmanifest.ResourcePool{
+				{Name: "fake-resource-pool", Stemcell: cachedStemcellSource},
+			}
+			unusedTarballSource = bideplmanifest.StemcellRef{URL: "https://fake-old-stemcell-url", SHA1: "fake-old-stemcell-sha1"}
+
+			for _, source := range []bitarball.Source{cachedStemcellSource, unusedTarballSource} {
+				fixture.fs.WriteFileString("/fake-download", "")
+				err = fixture.tarballCache.Save("/fake-download", source)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("deletes orphaned disks and unused stemcells except the one uploaded last, keeping the shared tarball cache", func() {
+			fixture.mockCloud.EXPECT().DeleteDisk("fake-orphaned-disk-cid")
+			fixture.mockCloud.EXPECT().DeleteStemcell("fake-old-stemcell-cid")
+
+			err := newDeploymentCleaner().CleanUp(fixture.fakeStage, false, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Disks).To(Equal([]biconfig.DiskRecord{
+				{ID: "fake-current-disk-id", CID: "fake-current-disk-cid"},
+			}))
+			Expect(deploymentState.Stemcells).To(Equal([]biconfig.StemcellRecord{
+				{ID: "fake-current-stemcell-id", Name: "fake-stemcell-name", Version: "fake-current-version", CID: "fake-current-stemcell-cid"},
+				{ID: "fake-uploaded-stemcell-id", Name: "fake-stemcell-name", Version: "fake-uploaded-version", CID: "fake-uploaded-stemcell-cid"},
+			}))
+
+			_, found := fixture.tarballCache.Get(cachedStemcellSource)
+			Expect(found).To(BeTrue())
+			_, found = fixture.tarballCache.Get(unusedTarballSource)
+			Expect(found).To(BeTrue())
+
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Deleting unused disk 'fake-orphaned-disk-cid'"}))
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Deleting unused stemcell 'fake-old-stemcell-cid'"}))
+			Expect(fixture.fakeStage.PerformCalls).ToNot(ContainElement(&fakebiui.PerformCall{Name: "Deleting cached tarballs"}))
+		})
+
+		It("deletes the disks kept by a migration whose content could not be verified", func() {
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			deploymentState.Disks = append(deploymentState.Disks, biconfig.DiskRecord{ID: "fake-kept-disk-id", CID: "fake-kept-disk-cid", Orphaned: true})
+			Expect(fixture.setupDeploymentStateService.Save(deploymentState)).To(Succeed())
+
+			fixture.mockCloud.EXPECT().DeleteDisk("fake-orphaned-disk-cid")
+			fixture.mockCloud.EXPECT().DeleteDisk("fake-kept-disk-cid")
+			fixture.mockCloud.EXPECT().DeleteStemcell("fake-old-stemcell-cid")
+
+			err = newDeploymentCleaner().CleanUp(fixture.fakeStage, false, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err = fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Disks).To(Equal([]biconfig.DiskRecord{
+				{ID: "fake-current-disk-id", CID: "fake-current-disk-cid"},
+			}))
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Deleting orphaned disk 'fake-kept-disk-cid'"}))
+		})
+
+		It("deletes the cached tarballs that the deployment manifest does not refer to with pruneCache", func() {
+			fixture.mockCloud.EXPECT().DeleteDisk("fake-orphaned-disk-cid")
+			fixture.mockCloud.EXPECT().DeleteStemcell("fake-old-stemcell-cid")
+
+			err := newDeploymentCleaner().CleanUp(fixture.fakeStage, false, true, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, found := fixture.tarballCache.Get(cachedStemcellSource)
+			Expect(found).To(BeTrue())
+			_, found = fixture.tarballCache.Get(unusedTarballSource)
+			Expect(found).To(BeFalse())
+
+			Expect(fixture.fakeStage.PerformCalls[len(fixture.fakeStage.PerformCalls)-1]).To(Equal(&fakebiui.PerformCall{Name: "Deleting cached tarballs"}))
+		})
+
+		It("deletes all unused stemcells and, with pruneCache, all cached tarballs with all", func() {
+			fixture.mockCloud.EXPECT().DeleteDisk("fake-orphaned-disk-cid")
+			fixture.mockCloud.EXPECT().DeleteStemcell("fake-old-stemcell-cid")
+			fixture.mockCloud.EXPECT().DeleteStemcell("fake-uploaded-stemcell-cid")
+
+			err := newDeploymentCleaner().CleanUp(fixture.fakeStage, true, true, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Stemcells).To(Equal([]biconfig.StemcellRecord{
+				{ID: "fake-current-stemcell-id", Name: "fake-stemcell-name", Version: "fake-current-version", CID: "fake-current-stemcell-cid"},
+			}))
+
+			_, found := fixture.tarballCache.Get(cachedStemcellSource)
+			Expect(found).To(BeFalse())
+		})
+
+		It("deletes the resources left in the previous cloud provider with its CPI", func() {
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			deploymentState.CloudMigration = &biconfig.CloudMigrationRecord{
+				CloudProvider: "fake-old-cloud-provider",
+				VMCID:         "fake-old-vm-cid",
+				Disks:         []biconfig.DiskRecord{{ID: "fake-old-disk-id", CID: "fake-old-disk-cid"}},
+				Stemcells:     []biconfig.StemcellRecord{{ID: "fake-old-cloud-stemcell-id", CID: "fake-old-cloud-stemcell-cid"}},
+				Snapshots:     []biconfig.SnapshotRecord{{ID: "fake-old-snapshot-id", CID: "fake-old-snapshot-cid", DiskCID: "fake-old-disk-cid"}},
+			}
+			err = fixture.setupDeploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			mockOldCloud := mock_cloud.NewMockCloud(mockCtrl)
+			fixture.mockCloudFactory.EXPECT().NewCloud(fixture.fakeInstallation, "fake-director-id", "fake-old-cloud-provider").Return(mockOldCloud, nil)
+			gomock.InOrder(
+				mockOldCloud.EXPECT().DeleteVM("fake-old-vm-cid"),
+				mockOldCloud.EXPECT().DeleteSnapshot("fake-old-snapshot-cid"),
+				mockOldCloud.EXPECT().DeleteDisk("fake-old-disk-cid").Return(bicloud.NewCPIError("delete_disk", bicloud.CmdError{Type: bicloud.DiskNotFoundError})),
+				mockOldCloud.EXPECT().DeleteStemcell("fake-old-cloud-stemcell-cid"),
+			)
+			fixture.mockCloud.EXPECT().DeleteDisk("fake-orphaned-disk-cid")
+			fixture.mockCloud.EXPECT().DeleteStemcell("fake-old-stemcell-cid")
+
+			err = newDeploymentCleaner().CleanUp(fixture.fakeStage, false, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err = fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.CloudMigration).To(BeNil())
+
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Deleting VM 'fake-old-vm-cid' of cloud provider 'fake-old-cloud-provider'"}))
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Deleting snapshot 'fake-old-snapshot-cid' of cloud provider 'fake-old-cloud-provider'"}))
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{Name: "Deleting stemcell 'fake-old-cloud-stemcell-cid' of cloud provider 'fake-old-cloud-provider'"}))
+
+			var deleteDiskCall *fakebiui.PerformCall
+			for _, call := range fixture.fakeStage.PerformCalls {
+				if call.Name == "Deleting disk 'fake-old-disk-cid' of cloud provider 'fake-old-cloud-provider'" {
+					deleteDiskCall = call
+				}
+			}
+			Expect(deleteDiskCall).ToNot(BeNil())
+			Expect(deleteDiskCall.SkipError.Error()).To(ContainSubstring("Disk not found"))
+		})
+
+		It("does not delete anything while a disk migration is interrupted", func() {
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			deploymentState.CurrentDiskMigration = &biconfig.DiskMigrationRecord{
+				OriginalDiskCID: "fake-current-disk-cid",
+				NewDiskCID:      "fake-orphaned-disk-cid",
+			}
+			err = fixture.setupDeploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = newDeploymentCleaner().CleanUp(fixture.fakeStage, false, false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("The migration of disk 'fake-current-disk-cid' was interrupted, run 'bosh-init deploy' to finish it before cleaning up"))
+			Expect(fixture.fakeStage.PerformCalls).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
+	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	biinstall "github.com/cloudfoundry/bosh-init/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// DeploymentContext locks and loads the deployment state, validates the manifests and installs the CPI
+// for the commands that work on an existing deployment without redeploying it.
+// Each operation holds the locks on the deployment state and the installation while it runs,
+// forceLock takes them over from another process.
+type DeploymentContext struct {
+	UI                                      biui.UI
+	LogTag                                  string
+	Logger                                  boshlog.Logger
+	DeploymentStateService                  biconfig.DeploymentStateService
+	ReleaseManager                          birel.Manager
+	CloudFactory                            bicloud.Factory
+	AgentClientFactory                      bihttpagent.AgentClientFactory
+	BlobstoreFactory                        biblobstore.Factory
+	VMManagerFactory                        bivm.ManagerFactory
+	InstanceManagerFactory                  biinstance.ManagerFactory
+	DeploymentParser                        bideplmanifest.Parser
+	DeploymentManifestPath                  string
+	CpiInstaller                            bicpirel.CpiInstaller
+	ReleaseFetcher                          birel.Fetcher
+	ReleaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	TempRootConfigurator                    TempRootConfigurator
+	TargetProvider                          biinstall.TargetProvider
+	Locker                                  bilock.Locker
+}
+
+type currentVMFunc func(vm bivm.VM, instanceManager biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error
+
+type cloudFunc func(cloud bicloud.Cloud, vmManager bivm.Manager, instanceManager biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error
+
+type installationFunc func(installation biinstall.Installation, installationManifest biinstallmanifest.Manifest, deploymentManifest bideplmanifest.Manifest) error
+
+func (c DeploymentContext) checkExists(vm bivm.VM) error {
+	exists, err := vm.Exists()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking existence of VM '%s'", vm.CID())
+	}
+
+	if !exists {
+		return bosherr.Errorf("Deployed VM '%s' no longer exists", vm.CID())
+	}
+
+	return nil
+}
+
+func (c DeploymentContext) stopJobs(vm bivm.VM, drainOptions biinstance.DrainOptions, stage biui.Stage) error {
+	stepName := fmt.Sprintf("Waiting for the agent on VM '%s'", vm.CID())
+	err := stage.Perform(stepName, func() error {
+		if err := vm.WaitUntilReady(10*time.Second, 500*time.Millisecond, biui.InterruptCh(stage)); err != nil {
+			return bosherr.WrapError(err, "Agent unreachable")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if drainOptions.Skip {
+		c.Logger.Info(c.LogTag, "Skipping drain of jobs on VM '%s'", vm.CID())
+	} else {
+		stepName = fmt.Sprintf("Draining jobs on VM '%s'", vm.CID())
+		err = stage.Perform(stepName, func() error {
+			return vm.Drain(drainOptions.MaxWait, biui.InterruptCh(stage))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	stepName = fmt.Sprintf("Stopping jobs on VM '%s'", vm.CID())
+	return stage.Perform(stepName, func() error {
+		return vm.Stop()
+	})
+}
+
+func (c DeploymentContext) startJobs(vm bivm.VM, deploymentManifest bideplmanifest.Manifest, stage biui.Stage) error {
+	stepName := fmt.Sprintf("Starting jobs on VM '%s'", vm.CID())
+	err := stage.Perform(stepName, func() error {
+		return vm.Start()
+	})
+	if err != nil {
+		return err
+	}
+
+	updateWatchTime := deploymentManifest.Update.UpdateWatchTime
+	start := time.Duration(updateWatchTime.Start) * time.Millisecond
+	end := time.Duration(updateWatchTime.End) * time.Millisecond
+	delayBetweenAttempts := 1 * time.Second
+	maxAttempts := int((end - start) / delayBetweenAttempts)
+
+	stepName = fmt.Sprintf("Waiting for jobs on VM '%s' to be running", vm.CID())
+	return stage.Perform(stepName, func() error {
+		interruptCh := biui.InterruptCh(stage)
+		select {
+		case <-time.After(start):
+		case <-interruptCh:
+			return biui.NewInterruptedWaitError("waiting for the jobs to start")
+		}
+		return vm.WaitToBeRunning(maxAttempts, delayBetweenAttempts, interruptCh)
+	})
+}
+
+// withCurrentVM installs the CPI, starts the registry and calls fn with the currently deployed VM.
+// Returns false without calling fn if the deployment state does not reference a VM.
+func (c DeploymentContext) withCurrentVM(stage biui.Stage, forceLock bool, fn currentVMFunc) (bool, error) {
+	deploymentState, err := c.loadDeploymentState()
+	if err != nil {
+		return false, err
+	}
+
+	if deploymentState.CurrentVMCID == "" {
+		return false, nil
+	}
+
+	found := false
+	err = c.withCloud(stage, deploymentState, forceLock, func(_ bicloud.Cloud, vmManager bivm.Manager, instanceManager biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		var vm bivm.VM
+		vm, found, err = vmManager.FindCurrent()
+		if err != nil {
+			return bosherr.WrapError(err, "Finding current VM")
+		}
+
+		if !found {
+			return nil
+		}
+
+		return fn(vm, instanceManager, deploymentManifest)
+	})
+
+	return found, err
+}
+
+// lockDeploymentState prevents other bosh-init processes from changing the deployment state until the lock is released
+func (c DeploymentContext) lockDeploymentState(forceLock bool) (bilock.Lock, error) {
+	return acquireLock(c.Locker, c.DeploymentStateService.Path()+".lock", "deployment state", forceLock)
+}
+
+func (c DeploymentContext) loadDeploymentState() (biconfig.DeploymentState, error) {
+	c.UI.PrintLinef("Deployment state: '%s'", c.DeploymentStateService.Path())
+
+	if !c.DeploymentStateService.Exists() {
+		return biconfig.DeploymentState{}, bosherr.Errorf("No deployment state found at '%s'", c.DeploymentStateService.Path())
+	}
+
+	deploymentState, err := c.DeploymentStateService.Load()
+	if err != nil {
+		return biconfig.DeploymentState{}, bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	return deploymentState, nil
+}
+
+// withCloud validates the manifests, installs the CPI, starts the registry and calls fn with the CPI client
+// of the cloud provider of the deployment state
+func (c DeploymentContext) withCloud(stage biui.Stage, deploymentState biconfig.DeploymentState, forceLock bool, fn cloudFunc) error {
+	return c.withInstallation(stage, forceLock, func(installation biinstall.Installation, installationManifest biinstallmanifest.Manifest, deploymentManifest bideplmanifest.Manifest) error {
+		cloud, vmManager, instanceManager, err := c.newCloud(installation, installationManifest, deploymentState.DirectorID, deploymentState.CloudProvider)
+		if err != nil {
+			return err
+		}
+
+		return fn(cloud, vmManager, instanceManager, deploymentManifest)
+	})
+}
+
+// withInstallation locks the installation, validates the manifests, installs the CPI, starts the registry
+// and calls fn with the CPI installation
+func (c DeploymentContext) withInstallation(stage biui.Stage, forceLock bool, fn installationFunc) error {
+	target, err := c.TargetProvider.NewTarget()
+	if err != nil {
+		return bosherr.WrapError(err, "Determining installation target")
+	}
+
+	targetLock, err := acquireLock(c.Locker, target.LockPath(), "installation", forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(targetLock, c.Logger, c.LogTag)
+
+	err = c.TempRootConfigurator.PrepareAndSetTempRoot(target.TmpPath(), c.Logger)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting temp root")
+	}
+
+	defer c.deleteExtractedReleases()
+
+	installationManifest, deploymentManifest, err := c.validateManifests(stage)
+	if err != nil {
+		return err
+	}
+
+	return c.CpiInstaller.WithInstalledCpiRelease(installationManifest, target, stage, func(installation biinstall.Installation) error {
+		return installation.WithRunningRegistry(c.Logger, stage, func() error {
+			return fn(installation, installationManifest, deploymentManifest)
+		})
+	})
+}
+
+// validateManifests parses the deployment manifest and validates the CPI release it refers to
+func (c DeploymentContext) validateManifests(stage biui.Stage) (biinstallmanifest.Manifest, bideplmanifest.Manifest, error) {
+	var (
+		installationManifest biinstallmanifest.Manifest
+		deploymentManifest   bideplmanifest.Manifest
+	)
+	err := stage.PerformComplex("validating", func(stage biui.Stage) error {
+		var (
+			releaseSetManifest birelsetmanifest.Manifest
+			err                error
+		)
+		releaseSetManifest, installationManifest, err = c.ReleaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.DeploymentManifestPath)
+		if err != nil {
+			return err
+		}
+
+		err = downloadCpiReleases(c.ReleaseFetcher, releaseSetManifest, installationManifest, stage)
+		if err != nil {
+			return err
+		}
+
+		err = c.CpiInstaller.ValidateCpiRelease(installationManifest, stage)
+		if err != nil {
+			return err
+		}
+
+		deploymentManifest, err = c.DeploymentParser.Parse(c.DeploymentManifestPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Parsing deployment manifest '%s'", c.DeploymentManifestPath)
+		}
+
+		return nil
+	})
+
+	return installationManifest, deploymentManifest, err
+}
+
+func (c DeploymentContext) deleteExtractedReleases() {
+	err := c.ReleaseManager.DeleteAll()
+	if err != nil {
+		c.Logger.Warn(c.LogTag, "Deleting all extracted releases: %s", err.Error())
+	}
+}
+
+// newCloud creates the CPI client of the cloud provider and the managers that use it
+func (c DeploymentContext) newCloud(
+	installation biinstall.Installation,
+	installationManifest biinstallmanifest.Manifest,
+	directorID string,
+	cloudProvider string,
+) (bicloud.Cloud, bivm.Manager, biinstance.Manager, error) {
+	cloud, err := c.CloudFactory.NewCloud(installation, directorID, cloudProvider)
+	if err != nil {
+		return nil, nil, nil, bosherr.WrapError(err, "Creating CPI client from CPI installation")
+	}
+
+	agentClient := c.AgentClientFactory.NewAgentClient(directorID, installationManifest.Mbus)
+	vmManager := c.VMManagerFactory.NewManager(cloud, agentClient)
+
+	blobstore, err := c.BlobstoreFactory.Create(installationManifest.Mbus, bihttpclient.CreateDefaultClientInsecureSkipVerify())
+	if err != nil {
+		return nil, nil, nil, bosherr.WrapError(err, "Creating blobstore client")
+	}
+
+	instanceManager := c.InstanceManagerFactory.NewManager(cloud, vmManager, blobstore)
+	return cloud, vmManager, instanceManager, nil
+}
@@ -1,14 +1,19 @@
 package cmd
 
 import (
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	"fmt"
+
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
 	bidepl "github.com/cloudfoundry/bosh-init/deployment"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
 	biui "github.com/cloudfoundry/bosh-init/ui"
@@ -18,7 +23,7 @@ import (
 )
 
 type DeploymentDeleter interface {
-	DeleteDeployment(stage biui.Stage) (err error)
+	DeleteDeployment(stage biui.Stage, skipDrain bool, forceLock bool) (err error)
 }
 
 func NewDeploymentDeleter(
@@ -31,6 +36,8 @@ func NewDeploymentDeleter(
 	agentClientFactory bihttpagent.AgentClientFactory,
 	blobstoreFactory biblobstore.Factory,
 	deploymentManagerFactory bidepl.ManagerFactory,
+	cloudMigrationRepo biconfig.CloudMigrationRepo,
+	deploymentParser bideplmanifest.Parser,
 	deploymentManifestPath string,
 	cpiInstaller bicpirel.CpiInstaller,
 	cpiUninstaller biinstall.Uninstaller,
@@ -38,6 +45,7 @@ func NewDeploymentDeleter(
 	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
 	tempRootConfigurator TempRootConfigurator,
 	targetProvider biinstall.TargetProvider,
+	locker bilock.Locker,
 ) DeploymentDeleter {
 	return &deploymentDeleter{
 		ui:                                      ui,
@@ -49,6 +57,8 @@ func NewDeploymentDeleter(
 		agentClientFactory:                      agentClientFactory,
 		blobstoreFactory:                        blobstoreFactory,
 		deploymentManagerFactory:                deploymentManagerFactory,
+		cloudMigrationRepo:                      cloudMigrationRepo,
+		deploymentParser:                        deploymentParser,
 		deploymentManifestPath:                  deploymentManifestPath,
 		cpiInstaller:                            cpiInstaller,
 		cpiUninstaller:                          cpiUninstaller,
@@ -56,6 +66,7 @@ func NewDeploymentDeleter(
 		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
 		tempRootConfigurator:                    tempRootConfigurator,
 		targetProvider:                          targetProvider,
+		locker:                                  locker,
 	}
 }
 
@@ -69,6 +80,8 @@ type deploymentDeleter struct {
 	agentClientFactory                      bihttpagent.AgentClientFactory
 	blobstoreFactory                        biblobstore.Factory
 	deploymentManagerFactory                bidepl.ManagerFactory
+	cloudMigrationRepo                      biconfig.CloudMigrationRepo
+	deploymentParser                        bideplmanifest.Parser
 	deploymentManifestPath                  string
 	cpiInstaller                            bicpirel.CpiInstaller
 	cpiUninstaller                          biinstall.Uninstaller
@@ -76,11 +89,25 @@ type deploymentDeleter struct {
 	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
 	tempRootConfigurator                    TempRootConfigurator
 	targetProvider                          biinstall.TargetProvider
+	locker                                  bilock.Locker
 }
 
-func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
+func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage, skipDrain bool, forceLock bool) (err error) {
 	c.ui.PrintLinef("Deployment state: '%s'", c.deploymentStateService.Path())
 
+	stateLock, err := acquireLock(c.locker, c.deploymentStateService.Path()+".lock", "deployment state", forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, c.logger, c.logTag)
+
+	defer func() {
+		if biui.IsInterrupted(err) {
+			resumeHint := fmt.Sprintf("Run 'bosh-init delete %s' to resume deleting them.", c.deploymentManifestPath)
+			printInterruptedState(c.ui, c.deploymentStateService, resumeHint, c.logger, c.logTag)
+		}
+	}()
+
 	if !c.deploymentStateService.Exists() {
 		c.ui.PrintLinef("No deployment state file found.")
 		return nil
@@ -96,6 +123,12 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 		return bosherr.WrapError(err, "Determining installation target")
 	}
 
+	targetLock, err := acquireLock(c.locker, target.LockPath(), "installation", forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(targetLock, c.logger, c.logTag)
+
 	err = c.tempRootConfigurator.PrepareAndSetTempRoot(target.TmpPath(), c.logger)
 	if err != nil {
 		return bosherr.WrapError(err, "Setting temp root")
@@ -108,7 +141,10 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 		}
 	}()
 
-	var installationManifest biinstallmanifest.Manifest
+	var (
+		installationManifest biinstallmanifest.Manifest
+		drainOptions         biinstance.DrainOptions
+	)
 	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
 		var releaseSetManifest birelsetmanifest.Manifest
 		releaseSetManifest, installationManifest, err = c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.deploymentManifestPath)
@@ -116,19 +152,18 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 			return err
 		}
 
-		cpiReleaseName := installationManifest.Template.Release
-		cpiReleaseRef, found := releaseSetManifest.FindByName(cpiReleaseName)
-		if !found {
-			return bosherr.Errorf("installation release '%s' must refer to a release in releases", cpiReleaseName)
+		err = downloadCpiReleases(c.releaseFetcher, releaseSetManifest, installationManifest, stage)
+		if err != nil {
+			return err
 		}
 
-		err = c.releaseFetcher.DownloadAndExtract(cpiReleaseRef, stage)
+		err = c.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
 		if err != nil {
 			return err
 		}
 
-		err = c.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
-		return err
+		drainOptions = c.drainOptions(skipDrain)
+		return nil
 	})
 	if err != nil {
 		return err
@@ -136,12 +171,23 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 
 	err = c.cpiInstaller.WithInstalledCpiRelease(installationManifest, target, stage, func(localCpiInstallation biinstall.Installation) error {
 		return localCpiInstallation.WithRunningRegistry(c.logger, stage, func() error {
-			err = c.findAndDeleteDeployment(stage, localCpiInstallation, deploymentState.DirectorID, installationManifest.Mbus)
+			err = c.findAndDeleteDeployment(stage, localCpiInstallation, deploymentState.DirectorID, deploymentState.CloudProvider, installationManifest.Mbus, drainOptions)
 
 			if err != nil {
 				return err
 			}
 
+			// the resources created by an unfinished move to another cloud provider are deleted with the resources kept by the last move
+			_, _, err = c.cloudMigrationRepo.AbandonMove()
+			if err != nil {
+				return bosherr.WrapError(err, "Abandoning move to another cloud provider")
+			}
+
+			err = deleteCloudMigration(stage, c.cloudFactory, c.cloudMigrationRepo, localCpiInstallation, deploymentState.DirectorID)
+			if err != nil {
+				return bosherr.WrapError(err, "Deleting resources of the previous cloud provider")
+			}
+
 			return stage.Perform("Uninstalling local artifacts for CPI and deployment", func() error {
 				err := c.cpiUninstaller.Uninstall(localCpiInstallation.Target())
 				if err != nil {
@@ -156,19 +202,36 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 	return err
 }
 
-func (c *deploymentDeleter) findAndDeleteDeployment(stage biui.Stage, installation biinstall.Installation, directorID, installationMbus string) error {
-	deploymentManager, err := c.deploymentManager(installation, directorID, installationMbus)
+// drainOptions reads the maximum drain wait from the deployment manifest.
+// The deployment can still be deleted if the rest of the deployment manifest is no longer valid.
+func (c *deploymentDeleter) drainOptions(skipDrain bool) biinstance.DrainOptions {
+	update := bideplmanifest.Update{MaxDrainWait: bideplmanifest.DefaultMaxDrainWait}
+
+	if !skipDrain {
+		deploymentManifest, err := c.deploymentParser.Parse(c.deploymentManifestPath)
+		if err != nil {
+			c.logger.Warn(c.logTag, "Parsing deployment manifest, using the default maximum drain wait: %s", err.Error())
+		} else {
+			update = deploymentManifest.Update
+		}
+	}
+
+	return biinstance.NewDrainOptions(update, skipDrain)
+}
+
+func (c *deploymentDeleter) findAndDeleteDeployment(stage biui.Stage, installation biinstall.Installation, directorID, cloudProvider, installationMbus string, drainOptions biinstance.DrainOptions) error {
+	deploymentManager, err := c.deploymentManager(installation, directorID, cloudProvider, installationMbus)
 	if err != nil {
 		return err
 	}
-	err = c.findCurrentDeploymentAndDelete(stage, deploymentManager)
+	err = c.findCurrentDeploymentAndDelete(stage, deploymentManager, drainOptions)
 	if err != nil {
 		return bosherr.WrapError(err, "Deleting deployment")
 	}
 	return deploymentManager.Cleanup(stage)
 }
 
-func (c *deploymentDeleter) findCurrentDeploymentAndDelete(stage biui.Stage, deploymentManager bidepl.Manager) error {
+func (c *deploymentDeleter) findCurrentDeploymentAndDelete(stage biui.Stage, deploymentManager bidepl.Manager, drainOptions biinstance.DrainOptions) error {
 	c.logger.Debug(c.logTag, "Finding current deployment...")
 	deployment, found, err := deploymentManager.FindCurrent()
 	if err != nil {
@@ -182,13 +245,13 @@ func (c *deploymentDeleter) findCurrentDeploymentAndDelete(stage biui.Stage, dep
 			return nil
 		}
 
-		return deployment.Delete(deleteStage)
+		return deployment.Delete(drainOptions, deleteStage)
 	})
 }
 
-func (c *deploymentDeleter) deploymentManager(installation biinstall.Installation, directorID, installationMbus string) (bidepl.Manager, error) {
+func (c *deploymentDeleter) deploymentManager(installation biinstall.Installation, directorID, cloudProvider, installationMbus string) (bidepl.Manager, error) {
 	c.logger.Debug(c.logTag, "Creating cloud client...")
-	cloud, err := c.cloudFactory.NewCloud(installation, directorID)
+	cloud, err := c.cloudFactory.NewCloud(installation, directorID, cloudProvider)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Creating CPI client from CPI installation")
 	}
@@ -3,23 +3,27 @@ package cmd_test
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	bicmd "github.com/cloudfoundry/bosh-init/cmd"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
+	mock_httpagent "github.com/cloudfoundry/bosh-init/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
 	mock_deployment "github.com/cloudfoundry/bosh-init/deployment/mocks"
 	mock_install "github.com/cloudfoundry/bosh-init/installation/mocks"
+	mock_lock "github.com/cloudfoundry/bosh-init/lock/mocks"
 	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
 	"github.com/golang/mock/gomock"
 
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
@@ -38,6 +42,7 @@ import (
 
 	fakecmd "github.com/cloudfoundry/bosh-init/cmd/fakes"
 	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
+	fakebideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest/fakes"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
 	fakeui "github.com/cloudfoundry/bosh-init/ui/fakes"
 	fakebihttpclient "github.com/cloudfoundry/bosh-utils/httpclient/fakes"
@@ -81,8 +86,17 @@ var _ = Describe("DeploymentDeleter", func() {
 			mockAgentClientFactory *mock_httpagent.MockAgentClientFactory
 			mockCloud              *mock_cloud.MockCloud
 
+			mockLocker                *mock_lock.MockLocker
+			mockLock                  *mock_lock.MockLock
+			expectLockDeploymentState *gomock.Call
+			expectLockInstallation    *gomock.Call
+
 			fakeStage *fakebiui.FakeStage
 
+			fakeDeploymentParser *fakebideplmanifest.FakeParser
+			skipDrain            bool
+			expectedDrainOptions biinstance.DrainOptions
+
 			directorID string
 
 			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
@@ -158,14 +172,14 @@ cloud_provider:
 			}).Return(fakeInstallation, nil).AnyTimes()
 			mockCpiInstaller.EXPECT().Cleanup(fakeInstallation).AnyTimes()
 
-			expectNewCloud = mockCloudFactory.EXPECT().NewCloud(fakeInstallation, directorID).Return(mockCloud, nil).AnyTimes()
+			expectNewCloud = mockCloudFactory.EXPECT().NewCloud(fakeInstallation, directorID, "").Return(mockCloud, nil).AnyTimes()
 		}
 
 		var newDeploymentDeleter = func() bicmd.DeploymentDeleter {
 			releaseSetValidator := birelsetmanifest.NewValidator(logger)
 			releaseSetParser := birelsetmanifest.NewParser(fs, logger, releaseSetValidator)
 			installationValidator := biinstallmanifest.NewValidator(logger)
-			installationParser := biinstallmanifest.NewParser(fs, fakeUUIDGenerator, logger, installationValidator)
+			installationParser := biinstallmanifest.NewParser(fs, fakeUUIDGenerator, logger, installationValidator, nil)
 			fakeHTTPClient := fakebihttpclient.NewFakeHTTPClient()
 			tarballCache := bitarball.NewCache("fake-base-path", fs, logger)
 			fakeSHA1Calculator := fakebicrypto.NewFakeSha1Calculator()
@@ -202,6 +216,8 @@ cloud_provider:
 				mockAgentClientFactory,
 				mockBlobstoreFactory,
 				mockDeploymentManagerFactory,
+				biconfig.NewCloudMigrationRepo(deploymentStateService),
+				fakeDeploymentParser,
 				deploymentManifestPath,
 				cpiInstaller,
 				mockCpiUninstaller,
@@ -209,6 +225,7 @@ cloud_provider:
 				releaseSetAndInstallationManifestParser,
 				tempRootConfigurator,
 				targetProvider,
+				mockLocker,
 			)
 		}
 
@@ -217,7 +234,7 @@ cloud_provider:
 			mockDeploymentManager.EXPECT().FindCurrent().Return(mockDeployment, true, nil)
 
 			gomock.InOrder(
-				mockDeployment.EXPECT().Delete(gomock.Any()).Do(func(stage biui.Stage) {
+				mockDeployment.EXPECT().Delete(expectedDrainOptions, gomock.Any()).Do(func(_ biinstance.DrainOptions, stage biui.Stage) {
 					Expect(fakeStage.SubStages).To(ContainElement(stage))
 				}),
 				mockDeploymentManager.EXPECT().Cleanup(fakeStage),
@@ -311,6 +328,19 @@ cloud_provider:
 
 			mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), gomock.Any()).Return(mockAgentClient).AnyTimes()
 
+			mockLocker = mock_lock.NewMockLocker(mockCtrl)
+			mockLock = mock_lock.NewMockLock(mockCtrl)
+			expectLockDeploymentState = mockLocker.EXPECT().Lock("/deployment-dir/fake-deployment-manifest-state.json.lock", false).Return(mockLock, nil).AnyTimes()
+			expectLockInstallation = mockLocker.EXPECT().Lock("fake-install-dir/fake-installation-id.lock", false).Return(mockLock, nil).AnyTimes()
+			mockLock.EXPECT().Release().AnyTimes()
+
+			fakeDeploymentParser = fakebideplmanifest.NewFakeParser()
+			fakeDeploymentParser.ParseManifest = bideplmanifest.Manifest{
+				Update: bideplmanifest.Update{MaxDrainWait: 120000},
+			}
+			skipDrain = false
+			expectedDrainOptions = biinstance.DrainOptions{MaxWait: 2 * time.Minute}
+
 			directorID = "fake-uuid-0"
 
 			writeDeploymentManifest()
@@ -333,7 +363,7 @@ cloud_provider:
 				})
 
 				It("does not delete anything", func() {
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).ToNot(HaveOccurred())
 
 					Expect(fakeUI.Said).To(Equal([]string{
@@ -356,7 +386,7 @@ cloud_provider:
 				Context("when change temp root fails", func() {
 					It("returns an error", func() {
 						fs.ChangeTempRootErr = errors.New("fake ChangeTempRootErr")
-						err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+						err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 						Expect(err).To(HaveOccurred())
 						Expect(err.Error()).To(Equal("Setting temp root: fake ChangeTempRootErr"))
 					})
@@ -364,7 +394,7 @@ cloud_provider:
 
 				It("sets the temp root", func() {
 					expectDeleteAndCleanup(true)
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(fs.TempRootPath).To(Equal("fake-install-dir/fake-installation-id/tmp"))
 				})
@@ -378,14 +408,14 @@ cloud_provider:
 						expectNewCloud.Times(1),
 					)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).NotTo(HaveOccurred())
 				})
 
 				It("deletes the extracted CPI release", func() {
 					expectDeleteAndCleanup(true)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(fs.FileExists("fake-cpi-extracted-dir")).To(BeFalse())
 				})
@@ -393,7 +423,7 @@ cloud_provider:
 				It("deletes the deployment & cleans up orphans", func() {
 					expectDeleteAndCleanup(true)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(fakeUI.Errors).To(BeEmpty())
 				})
@@ -402,28 +432,82 @@ cloud_provider:
 					expectDeleteAndCleanup(false)
 					mockCpiUninstaller.EXPECT().Uninstall(gomock.Any()).Return(nil)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).ToNot(HaveOccurred())
 				})
 
 				It("logs validating & deleting stages", func() {
 					expectDeleteAndCleanup(true)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).ToNot(HaveOccurred())
 
 					expectValidationInstallationDeletionEvents()
 				})
 
+				It("locks the deployment state and the installation", func() {
+					expectDeleteAndCleanup(true)
+					expectLockDeploymentState.Times(1)
+					expectLockInstallation.Times(1)
+
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("takes over the locks when forced", func() {
+					expectDeleteAndCleanup(true)
+					mockLocker.EXPECT().Lock("/deployment-dir/fake-deployment-manifest-state.json.lock", true).Return(mockLock, nil)
+					mockLocker.EXPECT().Lock("fake-install-dir/fake-installation-id.lock", true).Return(mockLock, nil)
+
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, true)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
 				It("deletes the local deployment state file", func() {
 					expectDeleteAndCleanup(true)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).ToNot(HaveOccurred())
 
 					Expect(fs.FileExists(deploymentStatePath)).To(BeFalse())
 				})
 
+				It("drains the jobs for at most the maximum drain wait of the deployment manifest", func() {
+					expectDeleteAndCleanup(true)
+
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fakeDeploymentParser.ParsePath).To(Equal(deploymentManifestPath))
+				})
+
+				Context("when the deployment manifest can not be parsed", func() {
+					BeforeEach(func() {
+						fakeDeploymentParser.ParseErr = errors.New("fake-parse-error")
+						expectedDrainOptions = biinstance.DrainOptions{MaxWait: 10 * time.Minute}
+					})
+
+					It("drains the jobs for at most the default maximum drain wait", func() {
+						expectDeleteAndCleanup(true)
+
+						err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
+						Expect(err).ToNot(HaveOccurred())
+					})
+				})
+
+				Context("when draining is skipped", func() {
+					BeforeEach(func() {
+						skipDrain = true
+						expectedDrainOptions = biinstance.DrainOptions{Skip: true, MaxWait: 10 * time.Minute}
+					})
+
+					It("deletes the deployment without draining the jobs", func() {
+						expectDeleteAndCleanup(true)
+
+						err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(fakeDeploymentParser.ParsePath).To(BeEmpty())
+					})
+				})
 			})
 
 			Context("when nothing has been deployed", func() {
@@ -434,7 +518,7 @@ cloud_provider:
 				It("cleans up orphans, but does not delete any deployment", func() {
 					expectCleanup()
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(fakeUI.Errors).To(BeEmpty())
 				})
@@ -463,7 +547,7 @@ cloud_provider:
 				}).Return(fakeInstallation, nil).AnyTimes()
 				mockCpiInstaller.EXPECT().Cleanup(fakeInstallation).AnyTimes()
 
-				expectNewCloud = mockCloudFactory.EXPECT().NewCloud(fakeInstallation, directorID).Return(mockCloud, nil).AnyTimes()
+				expectNewCloud = mockCloudFactory.EXPECT().NewCloud(fakeInstallation, directorID, "").Return(mockCloud, nil).AnyTimes()
 			})
 
 			Context("when the call to delete the deployment returns an error", func() {
@@ -473,9 +557,9 @@ cloud_provider:
 
 					deleteError := bosherr.Error("delete error")
 
-					mockDeployment.EXPECT().Delete(gomock.Any()).Return(deleteError)
+					mockDeployment.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(deleteError)
 
-					err := newDeploymentDeleter().DeleteDeployment(fakeStage)
+					err := newDeploymentDeleter().DeleteDeployment(fakeStage, skipDrain, false)
 
 					Expect(err).To(HaveOccurred())
 				})
@@ -1,18 +1,22 @@
 package cmd
 
 import (
-	"strings"
+	"fmt"
+	"path/filepath"
 
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
 	bidepl "github.com/cloudfoundry/bosh-init/deployment"
+	bilogs "github.com/cloudfoundry/bosh-init/deployment/logs"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
@@ -28,12 +32,15 @@ func NewDeploymentPreparer(
 	logTag string,
 	deploymentStateService biconfig.DeploymentStateService,
 	legacyDeploymentStateMigrator biconfig.LegacyDeploymentStateMigrator,
+	cloudMigrationRepo biconfig.CloudMigrationRepo,
 	releaseManager birel.Manager,
 	deploymentRecord bidepl.Record,
 	cloudFactory bicloud.Factory,
 	stemcellManagerFactory bistemcell.ManagerFactory,
 	agentClientFactory bihttpagent.AgentClientFactory,
 	vmManagerFactory bivm.ManagerFactory,
+	moveStemcellManagerFactory bistemcell.ManagerFactory,
+	moveVMManagerFactory bivm.ManagerFactory,
 	blobstoreFactory biblobstore.Factory,
 	deployer bidepl.Deployer,
 	deploymentManifestPath string,
@@ -44,6 +51,8 @@ func NewDeploymentPreparer(
 	deploymentManifestParser DeploymentManifestParser,
 	tempRootConfigurator TempRootConfigurator,
 	targetProvider biinstall.TargetProvider,
+	logsFetcher bilogs.Fetcher,
+	locker bilock.Locker,
 ) DeploymentPreparer {
 	return DeploymentPreparer{
 		ui:                                      ui,
@@ -51,12 +60,15 @@ func NewDeploymentPreparer(
 		logTag:                                  logTag,
 		deploymentStateService:                  deploymentStateService,
 		legacyDeploymentStateMigrator:           legacyDeploymentStateMigrator,
+		cloudMigrationRepo:                      cloudMigrationRepo,
 		releaseManager:                          releaseManager,
 		deploymentRecord:                        deploymentRecord,
 		cloudFactory:                            cloudFactory,
 		stemcellManagerFactory:                  stemcellManagerFactory,
 		agentClientFactory:                      agentClientFactory,
 		vmManagerFactory:                        vmManagerFactory,
+		moveStemcellManagerFactory:              moveStemcellManagerFactory,
+		moveVMManagerFactory:                    moveVMManagerFactory,
 		blobstoreFactory:                        blobstoreFactory,
 		deployer:                                deployer,
 		deploymentManifestPath:                  deploymentManifestPath,
@@ -67,21 +79,48 @@ func NewDeploymentPreparer(
 		deploymentManifestParser:                deploymentManifestParser,
 		tempRootConfigurator:                    tempRootConfigurator,
 		targetProvider:                          targetProvider,
+		logsFetcher:                             logsFetcher,
+		locker:                                  locker,
 	}
 }
 
+// DeployOptions holds the command line options that change how a deployment is performed
+type DeployOptions struct {
+	// FetchLogsOnFailure downloads the job logs from the deployed VM when deploying fails
+	FetchLogsOnFailure bool
+
+	// Recreate deletes and recreates the VM even if it could be updated in place
+	Recreate bool
+
+	// SkipDrain stops the jobs without running their drain scripts first
+	SkipDrain bool
+
+	// ForceLock takes over the locks on the deployment state and installation held by another process
+	ForceLock bool
+
+	// RecordedDeploymentOnly fails instead of deploying when the manifest, releases or stemcell
+	// differ from the ones recorded in the deployment state by the last deploy
+	RecordedDeploymentOnly bool
+
+	// StemcellCompatibility decides which stemcells the packages of compiled releases can be used with
+	StemcellCompatibility birel.StemcellCompatibility
+}
+
 type DeploymentPreparer struct {
 	ui                                      biui.UI
 	logger                                  boshlog.Logger
 	logTag                                  string
 	deploymentStateService                  biconfig.DeploymentStateService
 	legacyDeploymentStateMigrator           biconfig.LegacyDeploymentStateMigrator
+	cloudMigrationRepo                      biconfig.CloudMigrationRepo
 	releaseManager                          birel.Manager
 	deploymentRecord                        bidepl.Record
 	cloudFactory                            bicloud.Factory
 	stemcellManagerFactory                  bistemcell.ManagerFactory
 	agentClientFactory                      bihttpagent.AgentClientFactory
 	vmManagerFactory                        bivm.ManagerFactory
+	moveStemcellManagerFactory              bistemcell.ManagerFactory // records the stemcells of the cloud provider the deployment moves to
+	moveVMManagerFactory                    bivm.ManagerFactory       // records the VM and disks of the cloud provider the deployment moves to
 	blobstoreFactory                        biblobstore.Factory
 	deployer                                bidepl.Deployer
 	deploymentManifestPath                  string
@@ -92,11 +131,26 @@ type DeploymentPreparer struct {
 	deploymentManifestParser                DeploymentManifestParser
 	tempRootConfigurator                    TempRootConfigurator
 	targetProvider                          biinstall.TargetProvider
+	logsFetcher                             bilogs.Fetcher
+	locker                                  bilock.Locker
 }
 
-func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
+func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage, deployOptions DeployOptions) (err error) {
 	c.ui.PrintLinef("Deployment state: '%s'", c.deploymentStateService.Path())
 
+	stateLock, err := acquireLock(c.locker, c.deploymentStateService.Path()+".lock", "deployment state", deployOptions.ForceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, c.logger, c.logTag)
+
+	defer func() {
+		if biui.IsInterrupted(err) {
+			resumeHint := fmt.Sprintf("Run 'bosh-init deploy %s' to resume, or 'bosh-init delete %s' to delete them.", c.deploymentManifestPath, c.deploymentManifestPath)
+			printInterruptedState(c.ui, c.deploymentStateService, resumeHint, c.logger, c.logTag)
+		}
+	}()
+
 	if !c.deploymentStateService.Exists() {
 		migrated, err := c.legacyDeploymentStateMigrator.MigrateIfExists(biconfig.LegacyDeploymentStatePath(c.deploymentManifestPath))
 		if err != nil {
@@ -117,6 +171,12 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 		return bosherr.WrapError(err, "Determining installation target")
 	}
 
+	targetLock, err := acquireLock(c.locker, target.LockPath(), "installation", deployOptions.ForceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(targetLock, c.logger, c.logTag)
+
 	err = c.tempRootConfigurator.PrepareAndSetTempRoot(target.TmpPath(), c.logger)
 	if err != nil {
 		return bosherr.WrapError(err, "Setting temp root")
@@ -133,6 +193,7 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 		extractedStemcell    bistemcell.ExtractedStemcell
 		deploymentManifest   bideplmanifest.Manifest
 		installationManifest biinstallmanifest.Manifest
+		cloudProvider        string
 	)
 	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
 		var releaseSetManifest birelsetmanifest.Manifest
@@ -158,24 +219,34 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 			return err
 		}
 
+		cloudProvider, err = deploymentCloudProvider(deploymentManifest, installationManifest)
+		if err != nil {
+			return err
+		}
+
 		extractedStemcell, err = c.stemcellFetcher.GetStemcell(deploymentManifest, stage)
+		if err != nil {
+			return err
+		}
 
 		nonCpiReleasesMap, _ := deploymentManifest.GetListOfTemplateReleases()
-		delete(nonCpiReleasesMap, installationManifest.Template.Release) // remove CPI release from nonCpiReleasesMap
+		for _, cpiReleaseName := range installationManifest.CPIReleases() {
+			delete(nonCpiReleasesMap, cpiReleaseName) // remove CPI releases from nonCpiReleasesMap
+		}
 
 		for _, release := range c.releaseManager.List() {
+			if !release.IsCompiled() {
+				continue
+			}
+
 			if _, ok := nonCpiReleasesMap[release.Name()]; ok {
-				if release.IsCompiled() {
-					compilationOsAndVersion := release.Packages()[0].Stemcell
-					if strings.ToLower(compilationOsAndVersion) != strings.ToLower(extractedStemcell.OsAndVersion()) {
-						return bosherr.Errorf("OS/Version mismatch between deployment stemcell and compiled package stemcell for release '%s'", release.Name())
-					}
-				}
+				err = c.checkCompiledRelease(release, extractedStemcell, deployOptions.StemcellCompatibility, stage)
 			} else {
-				// It is a CPI release, check if it is compiled
-				if release.IsCompiled() {
-					return bosherr.Errorf("CPI is not allowed to be a compiled release. The provided CPI release '%s' is compiled", release.Name())
-				}
+				// It is a CPI release, it is installed locally and must be compiled from source
+				err = c.checkCompiledCpiRelease(release, stage)
+			}
+			if err != nil {
+				return err
 			}
 		}
 
@@ -196,7 +267,11 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 		return bosherr.WrapError(err, "Checking if deployment has changed")
 	}
 
-	if isDeployed {
+	if !isDeployed && deployOptions.RecordedDeploymentOnly {
+		return bosherr.Errorf("Deployment manifest, releases or stemcell changed since the last deploy, run 'bosh-init deploy %s' to deploy them", c.deploymentManifestPath)
+	}
+
+	if isDeployed && !deployOptions.Recreate {
 		c.ui.PrintLinef("No deployment, stemcell or release changes. Skipping deploy.")
 		return nil
 	}
@@ -209,6 +284,8 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 				extractedStemcell,
 				installationManifest,
 				deploymentManifest,
+				cloudProvider,
+				deployOptions,
 				stage)
 		})
 	})
@@ -223,22 +300,40 @@ func (c *DeploymentPreparer) deploy(
 	extractedStemcell bistemcell.ExtractedStemcell,
 	installationManifest biinstallmanifest.Manifest,
 	deploymentManifest bideplmanifest.Manifest,
+	cloudProvider string,
+	deployOptions DeployOptions,
 	stage biui.Stage,
 ) (err error) {
-	cloud, err := c.cloudFactory.NewCloud(installation, deploymentState.DirectorID)
+	cloud, err := c.cloudFactory.NewCloud(installation, deploymentState.DirectorID, cloudProvider)
 	if err != nil {
 		return bosherr.WrapError(err, "Creating CPI client from CPI installation")
 	}
 
-	stemcellManager := c.stemcellManagerFactory.NewManager(cloud)
+	// the resources of the current cloud provider stay current until the VM runs in the new cloud provider,
+	// the resources created there are recorded apart until then
+	_, moving, err := c.cloudMigrationRepo.StartMove(cloudProvider)
+	if err != nil {
+		return bosherr.WrapError(err, "Moving deployment to another cloud provider")
+	}
+
+	stemcellManagerFactory := c.stemcellManagerFactory
+	vmManagerFactory := c.vmManagerFactory
+	if moving {
+		c.ui.PrintLinef("Moving deployment from %s to %s", describeCloudProvider(deploymentState.CloudProvider), describeCloudProvider(cloudProvider))
+		// a CPI cannot be asked whether it creates disks from the snapshots of another one, so disks are not copied
+		c.ui.PrintLinef("Persistent disks are created empty, copy their data from the disks of %s before running 'bosh-init clean-up'", describeCloudProvider(deploymentState.CloudProvider))
+
+		stemcellManagerFactory = c.moveStemcellManagerFactory
+		vmManagerFactory = c.moveVMManagerFactory
+	}
 
-	cloudStemcell, err := stemcellManager.Upload(extractedStemcell, stage)
+	cloudStemcell, err := stemcellManagerFactory.NewManager(cloud).Upload(extractedStemcell, stage)
 	if err != nil {
 		return err
 	}
 
 	agentClient := c.agentClientFactory.NewAgentClient(deploymentState.DirectorID, installationManifest.Mbus)
-	vmManager := c.vmManagerFactory.NewManager(cloud, agentClient)
+	vmManager := vmManagerFactory.NewManager(cloud, agentClient)
 
 	blobstore, err := c.blobstoreFactory.Create(installationManifest.Mbus, bihttpclient.CreateDefaultClientInsecureSkipVerify())
 	if err != nil {
@@ -258,12 +353,25 @@ func (c *DeploymentPreparer) deploy(
 			installationManifest.Registry,
 			vmManager,
 			blobstore,
+			deployOptions.Recreate,
+			deployOptions.SkipDrain,
 			deployStage,
 		)
 		if err != nil {
+			if deployOptions.FetchLogsOnFailure {
+				c.fetchLogs(agentClient, blobstore)
+			}
 			return bosherr.WrapError(err, "Deploying")
 		}
 
+		if moving {
+			previous, err := c.cloudMigrationRepo.CompleteMove()
+			if err != nil {
+				return bosherr.WrapError(err, "Completing move to another cloud provider")
+			}
+			c.ui.PrintLinef("The VM, disks and stemcells of %s are kept until 'bosh-init clean-up' deletes them", describeCloudProvider(previous.CloudProvider))
+		}
+
 		err = c.deploymentRecord.Update(c.deploymentManifestPath, c.releaseManager.List())
 		if err != nil {
 			return bosherr.WrapError(err, "Updating deployment record")
@@ -277,10 +385,86 @@ func (c *DeploymentPreparer) deploy(
 
 	// TODO: cleanup unused disks here?
 
-	err = stemcellManager.DeleteUnused(stage)
+	err = c.stemcellManagerFactory.NewManager(cloud).DeleteUnused(stage)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// fetchLogs downloads the job logs next to the deployment manifest, only warning when that fails
+// so that the original deploy error is reported
+func (c *DeploymentPreparer) fetchLogs(agentClient biagent.AgentClient, blobstore biblobstore.Blobstore) {
+	logsDir, err := c.logsFetcher.Fetch(agentClient, blobstore, "", filepath.Dir(c.deploymentManifestPath))
+	if err != nil {
+		c.logger.Warn(c.logTag, "Fetching logs after deploy failure: %s", err.Error())
+		c.ui.ErrorLinef("Failed to fetch logs from the deployed VM: %s", err.Error())
+		return
+	}
+
+	c.ui.PrintLinef("Logs saved to '%s'", logsDir)
+}
+
+// checkCompiledRelease makes sure every compiled package of the release can be used on the deployment stemcell.
+// Releases that ship source packages are compiled from source instead when they can not.
+func (c *DeploymentPreparer) checkCompiledRelease(
+	release birel.Release,
+	extractedStemcell bistemcell.ExtractedStemcell,
+	compatibility birel.StemcellCompatibility,
+	stage biui.Stage,
+) error {
+	if compatibility == "" {
+		compatibility = birel.StemcellCompatibilityExact
+	}
+
+	incompatiblePackages := compatibility.IncompatiblePackages(release, extractedStemcell.OsAndVersion())
+	if len(incompatiblePackages) == 0 {
+		return nil
+	}
+
+	if hasSourcePackages(release) {
+		return c.fallBackToSource(release, stage)
+	}
+
+	errs := []error{}
+	for _, pkg := range incompatiblePackages {
+		errs = append(errs, bosherr.Errorf("Package '%s' was compiled against stemcell '%s'", pkg.Name, pkg.Stemcell))
+	}
+	return bosherr.WrapErrorf(
+		bosherr.NewMultiError(errs...),
+		"OS/Version mismatch between deployment stemcell '%s' and compiled packages of release '%s' (stemcell compatibility: %s)",
+		extractedStemcell.OsAndVersion(),
+		release.Name(),
+		compatibility,
+	)
+}
+
+func (c *DeploymentPreparer) checkCompiledCpiRelease(release birel.Release, stage biui.Stage) error {
+	if !hasSourcePackages(release) {
+		return bosherr.Errorf("CPI is not allowed to be a compiled release. The provided CPI release '%s' is compiled", release.Name())
+	}
+
+	return c.fallBackToSource(release, stage)
+}
+
+func (c *DeploymentPreparer) fallBackToSource(release birel.Release, stage biui.Stage) error {
+	return stage.Perform(fmt.Sprintf("Falling back to compiling release '%s' from source", release.Name()), func() error {
+		for _, pkg := range release.Packages() {
+			if pkg.IsCompiled() {
+				c.logger.Debug(c.logTag, "Using source package for '%s' compiled against stemcell '%s'", pkg.Name, pkg.Stemcell)
+				pkg.UseSource()
+			}
+		}
+		return nil
+	})
+}
+
+func hasSourcePackages(release birel.Release) bool {
+	for _, pkg := range release.Packages() {
+		if pkg.IsCompiled() && pkg.Source == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"fmt"
+
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bisnapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot"
+	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// DeploymentSnapshotter takes, deletes and restores snapshots of the persistent disks of the deployed instance.
+type DeploymentSnapshotter interface {
+	// TakeSnapshot takes a snapshot of the current persistent disk with the given name.
+	// The name may be empty if there is only one persistent disk.
+	// Returns false if the CPI does not support snapshots.
+	TakeSnapshot(stage biui.Stage, diskName string, forceLock bool) (biconfig.SnapshotRecord, bool, error)
+
+	// DeleteSnapshot deletes the snapshot in the cloud and from the deployment state.
+	DeleteSnapshot(stage biui.Stage, snapshotCID string, forceLock bool) error
+
+	// RestoreSnapshot replaces the current persistent disk of the deployed VM with a disk created from the snapshot.
+	// The jobs are stopped while the disks are swapped. The replaced disk is kept until clean-up deletes it.
+	RestoreSnapshot(stage biui.Stage, snapshotCID string, skipDrain bool, forceLock bool) error
+}
+
+func NewDeploymentSnapshotter(
+	deploymentContext DeploymentContext,
+	diskRepo biconfig.DiskRepo,
+	snapshotManagerFactory bisnapshot.ManagerFactory,
+) DeploymentSnapshotter {
+	return &deploymentSnapshotter{
+		DeploymentContext:      deploymentContext,
+		diskRepo:               diskRepo,
+		snapshotManagerFactory: snapshotManagerFactory,
+	}
+}
+
+type deploymentSnapshotter struct {
+	DeploymentContext
+	diskRepo               biconfig.DiskRepo
+	snapshotManagerFactory bisnapshot.ManagerFactory
+}
+
+func (s *deploymentSnapshotter) TakeSnapshot(stage biui.Stage, diskName string, forceLock bool) (biconfig.SnapshotRecord, bool, error) {
+	stateLock, err := s.lockDeploymentState(forceLock)
+	if err != nil {
+		return biconfig.SnapshotRecord{}, false, err
+	}
+	defer releaseLock(stateLock, s.Logger, s.LogTag)
+
+	deploymentState, err := s.loadDeploymentState()
+	if err != nil {
+		return biconfig.SnapshotRecord{}, false, err
+	}
+
+	diskRecords, err := s.diskRepo.FindCurrent()
+	if err != nil {
+		return biconfig.SnapshotRecord{}, false, bosherr.WrapError(err, "Finding current disk records")
+	}
+
+	diskRecord, err := selectDiskRecord(diskRecords, diskName)
+	if err != nil {
+		return biconfig.SnapshotRecord{}, false, err
+	}
+
+	var (
+		snapshot biconfig.SnapshotRecord
+		taken    bool
+	)
+	err = s.withCloud(stage, deploymentState, forceLock, func(cloud bicloud.Cloud, _ bivm.Manager, _ biinstance.Manager, _ bideplmanifest.Manifest) error {
+		disk := bidisk.NewDisk(diskRecord, cloud, s.diskRepo)
+
+		stepName := fmt.Sprintf("Taking snapshot of disk '%s'", disk.CID())
+		return stage.Perform(stepName, func() error {
+			snapshot, taken, err = s.snapshotManagerFactory.NewManager(cloud).Take(disk)
+			if err != nil {
+				return err
+			}
+
+			if !taken {
+				return biui.NewSkipStageError(bosherr.Errorf("Taking snapshot of disk '%s'", disk.CID()), "Not supported by the CPI")
+			}
+
+			return nil
+		})
+	})
+
+	return snapshot, taken, err
+}
+
+func (s *deploymentSnapshotter) DeleteSnapshot(stage biui.Stage, snapshotCID string, forceLock bool) error {
+	stateLock, err := s.lockDeploymentState(forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, s.Logger, s.LogTag)
+
+	deploymentState, err := s.loadDeploymentState()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := findSnapshot(deploymentState, snapshotCID)
+	if err != nil {
+		return err
+	}
+
+	return s.withCloud(stage, deploymentState, forceLock, func(cloud bicloud.Cloud, _ bivm.Manager, _ biinstance.Manager, _ bideplmanifest.Manifest) error {
+		stepName := fmt.Sprintf("Deleting snapshot '%s'", snapshot.CID)
+		return stage.Perform(stepName, func() error {
+			return s.snapshotManagerFactory.NewManager(cloud).Delete(snapshot)
+		})
+	})
+}
+
+func (s *deploymentSnapshotter) RestoreSnapshot(stage biui.Stage, snapshotCID string, skipDrain bool, forceLock bool) error {
+	stateLock, err := s.lockDeploymentState(forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, s.Logger, s.LogTag)
+
+	deploymentState, err := s.loadDeploymentState()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := findSnapshot(deploymentState, snapshotCID)
+	if err != nil {
+		return err
+	}
+
+	if deploymentState.CurrentVMCID == "" {
+		return bosherr.Error("No deployed VM found")
+	}
+
+	currentDiskRecords, err := s.diskRepo.FindCurrent()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding current disk records")
+	}
+
+	if len(currentDiskRecords) > 1 {
+		return bosherr.Error("Multiple current disks not supported")
+	}
+
+	// the agent mounts a single persistent disk, so the restored disk replaces the current disk of any name
+	var (
+		currentDiskRecord biconfig.DiskRecord
+		currentDiskFound  bool
+	)
+	if len(currentDiskRecords) == 1 {
+		currentDiskRecord = currentDiskRecords[0]
+		currentDiskFound = true
+	}
+
+	return s.withCloud(stage, deploymentState, forceLock, func(cloud bicloud.Cloud, vmManager bivm.Manager, _ biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		vm, found, err := vmManager.FindCurrent()
+		if err != nil {
+			return bosherr.WrapError(err, "Finding current VM")
+		}
+
+		if !found {
+			return bosherr.Error("No deployed VM found")
+		}
+
+		if err := s.checkExists(vm); err != nil {
+			return err
+		}
+
+		var restoredDisk bidisk.Disk
+		stepName := fmt.Sprintf("Creating disk from snapshot '%s'", snapshot.CID)
+		err = stage.Perform(stepName, func() error {
+			restoredDisk, err = s.snapshotManagerFactory.NewManager(cloud).CreateDisk(snapshot, vm.CID())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		drainOptions := biinstance.NewDrainOptions(deploymentManifest.Update, skipDrain)
+		if err := s.stopJobs(vm, drainOptions, stage); err != nil {
+			return err
+		}
+
+		// the replaced disk stays the current disk until the restored disk is attached
+		var currentDisk bidisk.Disk
+		if currentDiskFound {
+			currentDisk = bidisk.NewDisk(currentDiskRecord, cloud, s.diskRepo)
+			stepName = fmt.Sprintf("Detaching disk '%s'", currentDisk.CID())
+			err = stage.Perform(stepName, func() error {
+				return vm.DetachDisk(currentDisk)
+			})
+			if err != nil {
+				// the disk is still attached, only the jobs have to be started again
+				return s.rollbackRestore(err, restoredDisk, nil, vm, deploymentManifest, stage)
+			}
+		}
+
+		err = s.attachDisk(restoredDisk, vm, stage)
+		if err != nil {
+			return s.rollbackRestore(err, restoredDisk, currentDisk, vm, deploymentManifest, stage)
+		}
+
+		restoredDiskRecord, found, err := s.diskRepo.Find(restoredDisk.CID())
+		if err != nil {
+			return bosherr.WrapError(err, "Finding disk record")
+		}
+
+		if !found {
+			return bosherr.Error("Failed to find disk record for restored disk")
+		}
+
+		err = s.diskRepo.UpdateCurrent(restoredDiskRecord.ID)
+		if err != nil {
+			return bosherr.WrapError(err, "Updating current disk record")
+		}
+
+		// the replaced disk holds the content from before the restore, so only clean-up deletes it
+		if currentDiskFound {
+			err = s.diskRepo.Orphan(currentDiskRecord.ID)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Orphaning disk '%s'", currentDiskRecord.CID)
+			}
+			s.UI.PrintLinef("Disk '%s' is no longer used and is kept until 'bosh-init clean-up' deletes it", currentDiskRecord.CID)
+		}
+
+		return s.startJobs(vm, deploymentManifest, stage)
+	})
+}
+
+func (s *deploymentSnapshotter) attachDisk(disk bidisk.Disk, vm bivm.VM, stage biui.Stage) error {
+	stepName := fmt.Sprintf("Attaching disk '%s' to VM '%s'", disk.CID(), vm.CID())
+	return stage.Perform(stepName, func() error {
+		return vm.AttachDisk(disk)
+	})
+}
+
+// rollbackRestore attaches the replaced disk again, if it was detached, and starts the jobs.
+// The restored disk is deleted, if that fails the next deploy deletes it, since it is not current.
+func (s *deploymentSnapshotter) rollbackRestore(
+	cause error,
+	restoredDisk bidisk.Disk,
+	replacedDisk bidisk.Disk,
+	vm bivm.VM,
+	deploymentManifest bideplmanifest.Manifest,
+	stage biui.Stage,
+) error {
+	s.Logger.Warn(s.LogTag, "Rolling back the restore of disk '%s': %s", restoredDisk.CID(), cause.Error())
+
+	// attaching may have failed before or after the disk was attached in the cloud
+	err := vm.DetachDisk(restoredDisk)
+	if err != nil {
+		s.Logger.Warn(s.LogTag, "Detaching disk '%s' after a failed restore: %s", restoredDisk.CID(), err.Error())
+	}
+
+	if replacedDisk != nil {
+		err = s.attachDisk(replacedDisk, vm, stage)
+		if err != nil {
+			return bosherr.WrapErrorf(cause, "Rolling back the snapshot restore failed (%s)", err.Error())
+		}
+	}
+
+	err = s.startJobs(vm, deploymentManifest, stage)
+	if err != nil {
+		return bosherr.WrapErrorf(cause, "Rolling back the snapshot restore failed (%s)", err.Error())
+	}
+
+	stepName := fmt.Sprintf("Deleting disk '%s'", restoredDisk.CID())
+	err = stage.Perform(stepName, func() error {
+		return restoredDisk.Delete()
+	})
+	if err != nil {
+		s.Logger.Warn(s.LogTag, "Deleting disk '%s' after rolling back the restore: %s", restoredDisk.CID(), err.Error())
+	}
+
+	return bosherr.WrapErrorf(cause, "Restoring disk from snapshot (rolled back to the original disk)")
+}
+
+// selectDiskRecord returns the disk record with the given name,
+// or the only disk record if no name is given
+func selectDiskRecord(diskRecords []biconfig.DiskRecord, diskName string) (biconfig.DiskRecord, error) {
+	if len(diskRecords) == 0 {
+		return biconfig.DiskRecord{}, bosherr.Error("No persistent disk found")
+	}
+
+	if diskName == "" {
+		if len(diskRecords) > 1 {
+			return biconfig.DiskRecord{}, bosherr.Error("Multiple persistent disks found, specify one with --disk")
+		}
+		return diskRecords[0], nil
+	}
+
+	for _, diskRecord := range diskRecords {
+		if diskRecord.Name == diskName {
+			return diskRecord, nil
+		}
+	}
+
+	return biconfig.DiskRecord{}, bosherr.Errorf("Persistent disk '%s' not found", diskName)
+}
+
+func findSnapshot(deploymentState biconfig.DeploymentState, snapshotCID string) (biconfig.SnapshotRecord, error) {
+	for _, snapshot := range deploymentState.Snapshots {
+		if snapshot.CID == snapshotCID {
+			return snapshot, nil
+		}
+	}
+
+	return biconfig.SnapshotRecord{}, bosherr.Errorf("Snapshot '%s' not found", snapshotCID)
+}
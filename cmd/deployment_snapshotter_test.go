@@ -0,0 +1,356 @@
+package cmd_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	"github.com/golang/mock/gomock"
+
+	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
+	fakebivm "github.com/cloudfoundry/bosh-init/deployment/vm/fakes"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("DeploymentSnapshotter", func() {
+	var (
+		mockCtrl *gomock.Controller
+		fixture  *deploymentContextFixture
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		fixture = newDeploymentContextFixture(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var newDeploymentSnapshotter = func() bicmd.DeploymentSnapshotter {
+		deploymentContext := fixture.newDeploymentContext("deploymentSnapshotter")
+		return bicmd.NewDeploymentSnapshotter(
+			deploymentContext,
+			biconfig.NewDiskRepo(deploymentContext.DeploymentStateService, fixture.fakeUUIDGenerator),
+			fixture.mockSnapshotManagerFactory,
+		)
+	}
+
+	var snapshot biconfig.SnapshotRecord
+
+	BeforeEach(func() {
+		snapshot = biconfig.SnapshotRecord{
+			ID:      "fake-snapshot-id",
+			CID:     "fake-snapshot-cid",
+			DiskCID: "fake-disk-cid",
+			Size:    1024,
+		}
+
+		err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+			DirectorID:    "fake-director-id",
+			CurrentVMCID:  "fake-vm-cid",
+			CurrentDiskID: "fake-disk-id",
+			Disks: []biconfig.DiskRecord{
+				{ID: "fake-disk-id", CID: "fake-disk-cid", Size: 1024},
+				{ID: "fake-restored-disk-id", CID: "fake-restored-disk-cid", Size: 1024},
+			},
+			Snapshots: []biconfig.SnapshotRecord{snapshot},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("TakeSnapshot", func() {
+		It("takes a snapshot of the current disk", func() {
+			fixture.mockSnapshotManager.EXPECT().Take(gomock.Any()).Do(func(disk bidisk.Disk) {
+				Expect(disk.CID()).To(Equal("fake-disk-cid"))
+			}).Return(snapshot, true, nil)
+
+			takenSnapshot, taken, err := newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(taken).To(BeTrue())
+			Expect(takenSnapshot).To(Equal(snapshot))
+
+			Expect(fixture.fakeStage.PerformCalls[2:3]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Taking snapshot of disk 'fake-disk-cid'"},
+			}))
+		})
+
+		It("skips the step when the CPI does not support snapshots", func() {
+			fixture.mockSnapshotManager.EXPECT().Take(gomock.Any()).Return(biconfig.SnapshotRecord{}, false, nil)
+
+			_, taken, err := newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(taken).To(BeFalse())
+
+			performCall := fixture.fakeStage.PerformCalls[2]
+			Expect(performCall.Name).To(Equal("Taking snapshot of disk 'fake-disk-cid'"))
+			Expect(performCall.SkipError.Error()).To(Equal("Not supported by the CPI: Taking snapshot of disk 'fake-disk-cid'"))
+		})
+
+		It("returns an error when taking the snapshot fails", func() {
+			fixture.mockSnapshotManager.EXPECT().Take(gomock.Any()).Return(biconfig.SnapshotRecord{}, false, errors.New("fake-take-error"))
+
+			_, _, err := newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-take-error"))
+		})
+
+		It("returns an error when there is no persistent disk", func() {
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:   "fake-director-id",
+				CurrentVMCID: "fake-vm-cid",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No persistent disk found"))
+		})
+	})
+
+	Context("when the instance has multiple named disks", func() {
+		var restoredDisk *fakebidisk.FakeDisk
+
+		BeforeEach(func() {
+			snapshot.DiskCID = "fake-data-disk-cid"
+			snapshot.DiskName = "data"
+			restoredDisk = fakebidisk.NewFakeNamedDisk("data", "fake-restored-disk-cid")
+
+			fixture.fakeDeploymentParser.ParseManifest.Jobs = []bideplmanifest.Job{
+				{
+					Name:      "fake-job-name",
+					Instances: 1,
+					PersistentDisks: []bideplmanifest.JobPersistentDisk{
+						{Name: "data", DiskSize: 1024},
+						{Name: "logs", DiskSize: 1024},
+					},
+				},
+			}
+
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:   "fake-director-id",
+				CurrentVMCID: "fake-vm-cid",
+				CurrentNamedDiskIDs: map[string]string{
+					"data": "fake-data-disk-id",
+					"logs": "fake-logs-disk-id",
+				},
+				Disks: []biconfig.DiskRecord{
+					{ID: "fake-data-disk-id", Name: "data", CID: "fake-data-disk-cid", Size: 1024},
+					{ID: "fake-logs-disk-id", Name: "logs", CID: "fake-logs-disk-cid", Size: 1024},
+					{ID: "fake-restored-disk-id", Name: "data", CID: "fake-restored-disk-cid", Size: 1024},
+				},
+				Snapshots: []biconfig.SnapshotRecord{snapshot},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("takes a snapshot of the disk with the given name", func() {
+			fixture.mockSnapshotManager.EXPECT().Take(gomock.Any()).Do(func(disk bidisk.Disk) {
+				Expect(disk.CID()).To(Equal("fake-logs-disk-cid"))
+			}).Return(snapshot, true, nil)
+
+			_, _, err := newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "logs", false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when no disk name is given", func() {
+			_, _, err := newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Multiple persistent disks found, specify one with --disk"))
+		})
+
+		It("returns an error when there is no disk with the given name", func() {
+			_, _, err := newDeploymentSnapshotter().TakeSnapshot(fixture.fakeStage, "fake-unknown-disk-name", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk 'fake-unknown-disk-name' not found"))
+		})
+
+		It("returns an error when restoring a snapshot", func() {
+			err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Multiple current disks not supported"))
+		})
+
+		Context("when the current disk has another name than the snapshotted disk", func() {
+			BeforeEach(func() {
+				fixture.fakeDeploymentParser.ParseManifest.Jobs = []bideplmanifest.Job{
+					{
+						Name:      "fake-job-name",
+						Instances: 1,
+						PersistentDisks: []bideplmanifest.JobPersistentDisk{
+							{Name: "logs", DiskSize: 1024},
+						},
+					},
+				}
+
+				deploymentState, err := fixture.setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				deploymentState.CurrentNamedDiskIDs = map[string]string{
+					"logs": "fake-logs-disk-id",
+				}
+				err = fixture.setupDeploymentStateService.Save(deploymentState)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("replaces the current disk, since the agent mounts a single persistent disk", func() {
+				fixture.mockSnapshotManager.EXPECT().CreateDisk(snapshot, "fake-vm-cid").Return(restoredDisk, nil)
+
+				err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fixture.fakeVM.DetachDiskInputs).To(HaveLen(1))
+				Expect(fixture.fakeVM.DetachDiskInputs[0].Disk.CID()).To(Equal("fake-logs-disk-cid"))
+				Expect(fixture.fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{{Disk: restoredDisk}}))
+
+				deploymentState, err := fixture.setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CurrentNamedDiskIDs).To(Equal(map[string]string{
+					"data": "fake-restored-disk-id",
+				}))
+			})
+		})
+	})
+
+	Describe("DeleteSnapshot", func() {
+		It("deletes the snapshot", func() {
+			fixture.mockSnapshotManager.EXPECT().Delete(snapshot).Return(nil)
+
+			err := newDeploymentSnapshotter().DeleteSnapshot(fixture.fakeStage, "fake-snapshot-cid", false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fixture.fakeStage.PerformCalls[2:3]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Deleting snapshot 'fake-snapshot-cid'"},
+			}))
+		})
+
+		It("returns an error when the snapshot is unknown", func() {
+			err := newDeploymentSnapshotter().DeleteSnapshot(fixture.fakeStage, "fake-unknown-snapshot-cid", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Snapshot 'fake-unknown-snapshot-cid' not found"))
+		})
+	})
+
+	Describe("RestoreSnapshot", func() {
+		var restoredDisk *fakebidisk.FakeDisk
+
+		BeforeEach(func() {
+			restoredDisk = fakebidisk.NewFakeDisk("fake-restored-disk-cid")
+		})
+
+		It("replaces the current disk with a disk created from the snapshot", func() {
+			fixture.mockSnapshotManager.EXPECT().CreateDisk(snapshot, "fake-vm-cid").Return(restoredDisk, nil)
+
+			err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fixture.fakeVM.StopCalled).To(Equal(1))
+			Expect(fixture.fakeVM.DetachDiskInputs).To(HaveLen(1))
+			Expect(fixture.fakeVM.DetachDiskInputs[0].Disk.CID()).To(Equal("fake-disk-cid"))
+			Expect(fixture.fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{
+				{Disk: restoredDisk},
+			}))
+			Expect(fixture.fakeVM.StartCalled).To(Equal(1))
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.CurrentDiskID).To(Equal("fake-restored-disk-id"))
+			Expect(deploymentState.Disks[0].CID).To(Equal("fake-disk-cid"))
+			Expect(deploymentState.Disks[0].Orphaned).To(BeTrue())
+
+			Expect(fixture.fakeUI.Said).To(ContainElement("Disk 'fake-disk-cid' is no longer used and is kept until 'bosh-init clean-up' deletes it"))
+			Expect(fixture.fakeStage.PerformCalls[2:9]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Creating disk from snapshot 'fake-snapshot-cid'"},
+				{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+				{Name: "Draining jobs on VM 'fake-vm-cid'"},
+				{Name: "Stopping jobs on VM 'fake-vm-cid'"},
+				{Name: "Detaching disk 'fake-disk-cid'"},
+				{Name: "Attaching disk 'fake-restored-disk-cid' to VM 'fake-vm-cid'"},
+				{Name: "Starting jobs on VM 'fake-vm-cid'"},
+			}))
+		})
+
+		It("does not touch the current disk when creating the disk fails", func() {
+			fixture.mockSnapshotManager.EXPECT().CreateDisk(snapshot, "fake-vm-cid").Return(nil, errors.New("fake-create-disk-error"))
+
+			err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-create-disk-error"))
+
+			Expect(fixture.fakeVM.StopCalled).To(Equal(0))
+			Expect(fixture.fakeVM.DetachDiskInputs).To(BeEmpty())
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.CurrentDiskID).To(Equal("fake-disk-id"))
+		})
+
+		It("attaches the current disk again and starts the jobs when attaching the restored disk fails", func() {
+			fixture.mockSnapshotManager.EXPECT().CreateDisk(snapshot, "fake-vm-cid").Return(restoredDisk, nil)
+			fixture.fakeVM.SetAttachDiskBehavior(restoredDisk, errors.New("fake-attach-error"))
+
+			err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-attach-error"))
+			Expect(err.Error()).To(ContainSubstring("rolled back to the original disk"))
+
+			Expect(fixture.fakeVM.AttachDiskInputs).To(HaveLen(2))
+			Expect(fixture.fakeVM.AttachDiskInputs[0].Disk).To(Equal(restoredDisk))
+			Expect(fixture.fakeVM.AttachDiskInputs[1].Disk.CID()).To(Equal("fake-disk-cid"))
+			Expect(fixture.fakeVM.StartCalled).To(Equal(1))
+			Expect(restoredDisk.DeleteCalledTimes).To(Equal(1))
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.CurrentDiskID).To(Equal("fake-disk-id"))
+			Expect(deploymentState.Disks[0].Orphaned).To(BeFalse())
+
+			Expect(fixture.fakeStage.PerformCalls[7:12]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Attaching disk 'fake-restored-disk-cid' to VM 'fake-vm-cid'", Error: fixture.fakeStage.PerformCalls[7].Error},
+				{Name: "Attaching disk 'fake-disk-cid' to VM 'fake-vm-cid'"},
+				{Name: "Starting jobs on VM 'fake-vm-cid'"},
+				{Name: "Waiting for jobs on VM 'fake-vm-cid' to be running"},
+				{Name: "Deleting disk 'fake-restored-disk-cid'"},
+			}))
+		})
+
+		It("starts the jobs again when detaching the current disk fails", func() {
+			fixture.mockSnapshotManager.EXPECT().CreateDisk(snapshot, "fake-vm-cid").Return(restoredDisk, nil)
+			fixture.fakeVM.SetDetachDiskBehavior(fakebidisk.NewFakeDisk("fake-disk-cid"), errors.New("fake-detach-error"))
+
+			err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-detach-error"))
+
+			Expect(fixture.fakeVM.AttachDiskInputs).To(BeEmpty())
+			Expect(fixture.fakeVM.StartCalled).To(Equal(1))
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.CurrentDiskID).To(Equal("fake-disk-id"))
+		})
+
+		It("returns an error when the snapshot is unknown", func() {
+			err := newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-unknown-snapshot-cid", false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Snapshot 'fake-unknown-snapshot-cid' not found"))
+		})
+
+		It("returns an error when no VM is deployed", func() {
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID: "fake-director-id",
+				Snapshots:  []biconfig.SnapshotRecord{snapshot},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = newDeploymentSnapshotter().RestoreSnapshot(fixture.fakeStage, "fake-snapshot-cid", false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No deployed VM found"))
+		})
+	})
+})
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"net/smtp"
+	"os"
 	"path/filepath"
 	"time"
 
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
@@ -12,16 +14,20 @@ import (
 	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bidepl "github.com/cloudfoundry/bosh-init/deployment"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	bihealth "github.com/cloudfoundry/bosh-init/deployment/health"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	biinstancestate "github.com/cloudfoundry/bosh-init/deployment/instance/state"
+	bilogs "github.com/cloudfoundry/bosh-init/deployment/logs"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
+	bisnapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	biindex "github.com/cloudfoundry/bosh-init/index"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
 	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
@@ -51,6 +57,7 @@ type factory struct {
 	logger                 boshlog.Logger
 	uuidGenerator          boshuuid.Generator
 	workspaceRootPath      string
+	interruptCh            <-chan struct{}
 	runner                 boshsys.CmdRunner
 	compressor             boshcmd.Compressor
 	agentClientFactory     bihttpagent.AgentClientFactory
@@ -60,6 +67,9 @@ type factory struct {
 	instanceManagerFactory biinstance.ManagerFactory
 	deploymentFactory      bidepl.Factory
 	blobstoreFactory       biblobstore.Factory
+	logsFetcher            bilogs.Fetcher
+	notifierFactory        bihealth.NotifierFactory
+	locker                 bilock.Locker
 	eventLogger            biui.Stage
 	releaseExtractor       birel.Extractor
 	releaseManager         birel.Manager
@@ -73,8 +83,10 @@ type factory struct {
 	cloudFactory           bicloud.Factory
 	stateBuilderFactory    biinstancestate.BuilderFactory
 	compiledPackageRepo    bistatepkg.CompiledPackageRepo
+	tarballCache           bitarball.Cache
 	tarballProvider        bitarball.Provider
 	cpiReleaseValidator    *bicpirel.Validator
+	stateEncryptor         *bicrypto.Encryptor
 }
 
 func NewFactory(
@@ -84,6 +96,7 @@ func NewFactory(
 	logger boshlog.Logger,
 	uuidGenerator boshuuid.Generator,
 	workspaceRootPath string,
+	interruptCh <-chan struct{},
 ) Factory {
 	f := &factory{
 		fs:                fs,
@@ -92,12 +105,33 @@ func NewFactory(
 		logger:            logger,
 		uuidGenerator:     uuidGenerator,
 		workspaceRootPath: workspaceRootPath,
+		interruptCh:       interruptCh,
 	}
 	f.commands = CommandList{
-		"deploy":  f.createDeployCmd,
-		"delete":  f.createDeleteCmd,
-		"help":    f.createHelpCmd,
-		"version": f.createVersionCmd,
+		"deploy":           f.createDeployCmd,
+		"delete":           f.createDeleteCmd,
+		"registry":         f.createRegistryCmd,
+		"ssh":              f.createSSHCmd,
+		"logs":             f.createLogsCmd,
+		"stop":             f.createStopCmd,
+		"start":            f.createStartCmd,
+		"restart":          f.createRestartCmd,
+		"recreate":         f.createRecreateCmd,
+		"instances":        f.createInstancesCmd,
+		"vms":              f.createInstancesCmd,
+		"watch":            f.createWatchCmd,
+		"take-snapshot":    f.createTakeSnapshotCmd,
+		"snapshots":        f.createSnapshotsCmd,
+		"delete-snapshot":  f.createDeleteSnapshotCmd,
+		"restore-snapshot": f.createRestoreSnapshotCmd,
+		"stemcells":        f.createStemcellsCmd,
+		"upload-stemcell":  f.createUploadStemcellCmd,
+		"clean-up":         f.createCleanUpCmd,
+		"inspect-release":  f.createInspectReleaseCmd,
+		"state":            f.createStateCmd,
+		"adopt":            f.createAdoptCmd,
+		"help":             f.createHelpCmd,
+		"version":          f.createVersionCmd,
 	}
 	return f
 }
@@ -118,7 +152,7 @@ func (f *factory) CreateCommand(name string) (Cmd, error) {
 
 func (f *factory) createDeployCmd() (Cmd, error) {
 	getter := func(deploymentManifestPath string) (DeploymentPreparer, error) {
-		f := &deploymentManagerFactory2{f: f, deploymentManifestPath: deploymentManifestPath}
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
 		deploymentPreparer, err := f.loadDeploymentPreparer()
 		if err != nil {
 			return deploymentPreparer, err
@@ -131,7 +165,7 @@ func (f *factory) createDeployCmd() (Cmd, error) {
 
 func (f *factory) createDeleteCmd() (Cmd, error) {
 	getter := func(deploymentManifestPath string) (DeploymentDeleter, error) {
-		f := &deploymentManagerFactory2{f: f, deploymentManifestPath: deploymentManifestPath}
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
 		deploymentDeleter, err := f.loadDeploymentDeleter()
 		if err != nil {
 			return deploymentDeleter, err
@@ -142,6 +176,204 @@ func (f *factory) createDeleteCmd() (Cmd, error) {
 	return NewDeleteCmd(f.ui, f.fs, f.logger, getter), nil
 }
 
+func (f *factory) createRegistryCmd() (Cmd, error) {
+	releaseSetAndInstallationManifestParser := ReleaseSetAndInstallationManifestParser{
+		ReleaseSetParser:   f.loadReleaseSetParser(),
+		InstallationParser: f.loadInstallationParser(),
+	}
+
+	return NewRegistryCmd(
+		f.ui,
+		f.fs,
+		releaseSetAndInstallationManifestParser,
+		f.loadRegistryServerManager(),
+		f.loadSSHTunnelFactory(),
+		f.loadStateEncryptor(),
+		f.logger,
+	), nil
+}
+
+func (f *factory) createSSHCmd() (Cmd, error) {
+	releaseSetAndInstallationManifestParser := ReleaseSetAndInstallationManifestParser{
+		ReleaseSetParser:   f.loadReleaseSetParser(),
+		InstallationParser: f.loadInstallationParser(),
+	}
+
+	return NewSSHCmd(
+		f.ui,
+		f.fs,
+		releaseSetAndInstallationManifestParser,
+		f.loadDeploymentParser(),
+		f.deploymentStateServiceProvider(),
+		f.loadAgentClientFactory(),
+		f.loadSSHTunnelFactory(),
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+		f.logger,
+	), nil
+}
+
+func (f *factory) createLogsCmd() (Cmd, error) {
+	releaseSetAndInstallationManifestParser := ReleaseSetAndInstallationManifestParser{
+		ReleaseSetParser:   f.loadReleaseSetParser(),
+		InstallationParser: f.loadInstallationParser(),
+	}
+
+	return NewLogsCmd(
+		f.ui,
+		f.fs,
+		releaseSetAndInstallationManifestParser,
+		f.deploymentStateServiceProvider(),
+		f.loadAgentClientFactory(),
+		f.loadBlobstoreFactory(),
+		f.loadLogsFetcher(),
+		f.logger,
+	), nil
+}
+
+func (f *factory) createStopCmd() (Cmd, error) {
+	return NewStopCmd(f.ui, f.fs, f.logger, f.instanceLifecycleProvider()), nil
+}
+
+func (f *factory) createStartCmd() (Cmd, error) {
+	return NewStartCmd(f.ui, f.fs, f.logger, f.instanceLifecycleProvider(), f.deploymentPreparerProvider()), nil
+}
+
+func (f *factory) createRestartCmd() (Cmd, error) {
+	return NewRestartCmd(f.ui, f.fs, f.logger, f.instanceLifecycleProvider()), nil
+}
+
+func (f *factory) createRecreateCmd() (Cmd, error) {
+	return NewRecreateCmd(f.ui, f.fs, f.logger, f.deploymentPreparerProvider()), nil
+}
+
+func (f *factory) createInstancesCmd() (Cmd, error) {
+	errUI := biui.NewWriterUI(os.Stderr, os.Stderr, f.logger)
+	return NewInstancesCmd(f.ui, errUI, f.fs, f.timeService, f.logger, f.instanceLifecycleWithUIProvider()), nil
+}
+
+func (f *factory) createWatchCmd() (Cmd, error) {
+	return NewWatchCmd(
+		f.ui,
+		f.fs,
+		f.timeService,
+		f.logger,
+		f.instanceLifecycleWithUIProvider(),
+		f.deploymentPreparerProvider(),
+		f.loadNotifierFactory(),
+		bihealth.NewMonitorFactory(f.timeService, f.logger),
+	), nil
+}
+
+func (f *factory) createTakeSnapshotCmd() (Cmd, error) {
+	return NewTakeSnapshotCmd(f.ui, f.fs, f.logger, f.deploymentSnapshotterProvider()), nil
+}
+
+func (f *factory) createSnapshotsCmd() (Cmd, error) {
+	return NewSnapshotsCmd(f.ui, f.fs, f.deploymentStateServiceProvider(), f.logger), nil
+}
+
+func (f *factory) createDeleteSnapshotCmd() (Cmd, error) {
+	return NewDeleteSnapshotCmd(f.ui, f.fs, f.logger, f.deploymentSnapshotterProvider()), nil
+}
+
+func (f *factory) createRestoreSnapshotCmd() (Cmd, error) {
+	return NewRestoreSnapshotCmd(f.ui, f.fs, f.logger, f.deploymentSnapshotterProvider()), nil
+}
+
+func (f *factory) createStateCmd() (Cmd, error) {
+	return NewStateCmd(f.ui, f.fs, f.deploymentStateServiceProvider(), f.loadLocker(), f.loadStateEncryptor(), f.logger), nil
+}
+
+func (f *factory) createInspectReleaseCmd() (Cmd, error) {
+	return NewInspectReleaseCmd(
+		f.ui,
+		f.fs,
+		f.loadCompressor(),
+		birel.NewValidator(f.fs),
+		f.loadTarballProvider(),
+		f.logger,
+	), nil
+}
+
+func (f *factory) createStemcellsCmd() (Cmd, error) {
+	return NewStemcellsCmd(f.ui, f.fs, f.deploymentStateServiceProvider(), f.logger), nil
+}
+
+func (f *factory) createUploadStemcellCmd() (Cmd, error) {
+	return NewUploadStemcellCmd(f.ui, f.fs, f.logger, f.stemcellUploaderProvider()), nil
+}
+
+func (f *factory) createCleanUpCmd() (Cmd, error) {
+	return NewCleanUpCmd(f.ui, f.fs, f.logger, f.deploymentCleanerProvider()), nil
+}
+
+func (f *factory) createAdoptCmd() (Cmd, error) {
+	return NewAdoptCmd(f.ui, f.fs, f.logger, f.deploymentAdopterProvider()), nil
+}
+
+func (f *factory) deploymentStateServiceProvider() func(string) biconfig.DeploymentStateService {
+	return func(deploymentManifestPath string) biconfig.DeploymentStateService {
+		return biconfig.NewEncryptedFileSystemDeploymentStateService(
+			f.fs,
+			f.uuidGenerator,
+			f.logger,
+			biconfig.DeploymentStatePath(deploymentManifestPath),
+			f.loadStateEncryptor(),
+		)
+	}
+}
+
+func (f *factory) instanceLifecycleProvider() func(string) (InstanceLifecycle, error) {
+	provider := f.instanceLifecycleWithUIProvider()
+	return func(deploymentManifestPath string) (InstanceLifecycle, error) {
+		return provider(deploymentManifestPath, f.ui)
+	}
+}
+
+func (f *factory) instanceLifecycleWithUIProvider() func(string, biui.UI) (InstanceLifecycle, error) {
+	return func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error) {
+		f := &deploymentManagerFactory2{f: f, ui: ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadInstanceLifecycle()
+	}
+}
+
+func (f *factory) deploymentSnapshotterProvider() func(string) (DeploymentSnapshotter, error) {
+	return func(deploymentManifestPath string) (DeploymentSnapshotter, error) {
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadDeploymentSnapshotter()
+	}
+}
+
+func (f *factory) stemcellUploaderProvider() func(string) (StemcellUploader, error) {
+	return func(deploymentManifestPath string) (StemcellUploader, error) {
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadStemcellUploader()
+	}
+}
+
+func (f *factory) deploymentCleanerProvider() func(string) (DeploymentCleaner, error) {
+	return func(deploymentManifestPath string) (DeploymentCleaner, error) {
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadDeploymentCleaner()
+	}
+}
+
+func (f *factory) deploymentAdopterProvider() func(string) (DeploymentAdopter, error) {
+	return func(deploymentManifestPath string) (DeploymentAdopter, error) {
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadDeploymentAdopter()
+	}
+}
+
+func (f *factory) deploymentPreparerProvider() func(string) (DeploymentPreparer, error) {
+	return func(deploymentManifestPath string) (DeploymentPreparer, error) {
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadDeploymentPreparer()
+	}
+}
+
 func (f *factory) createHelpCmd() (Cmd, error) {
 	return NewHelpCmd(f.ui, f.commands), nil
 }
@@ -181,7 +413,7 @@ func (f *factory) loadRegistryServerManager() biregistry.ServerManager {
 		return f.registryServerManager
 	}
 
-	f.registryServerManager = biregistry.NewServerManager(f.logger)
+	f.registryServerManager = biregistry.NewEncryptedServerManager(f.fs, f.logger, f.loadStateEncryptor())
 	return f.registryServerManager
 }
 
@@ -190,7 +422,7 @@ func (f *factory) loadSSHTunnelFactory() bisshtunnel.Factory {
 		return f.sshTunnelFactory
 	}
 
-	f.sshTunnelFactory = bisshtunnel.NewFactory(f.logger)
+	f.sshTunnelFactory = bisshtunnel.NewFactory(f.fs, f.logger)
 	return f.sshTunnelFactory
 }
 
@@ -275,7 +507,7 @@ func (f *factory) loadAgentClientFactory() bihttpagent.AgentClientFactory {
 		return f.agentClientFactory
 	}
 
-	f.agentClientFactory = bihttpagent.NewAgentClientFactory(1*time.Second, f.logger)
+	f.agentClientFactory = bihttpagent.NewAgentClientFactory(1*time.Second, f.interruptCh, f.logger)
 	return f.agentClientFactory
 }
 
@@ -287,6 +519,34 @@ func (f *factory) loadBlobstoreFactory() biblobstore.Factory {
 	f.blobstoreFactory = biblobstore.NewBlobstoreFactory(f.uuidGenerator, f.fs, f.logger)
 	return f.blobstoreFactory
 }
+func (f *factory) loadLogsFetcher() bilogs.Fetcher {
+	if f.logsFetcher != nil {
+		return f.logsFetcher
+	}
+
+	f.logsFetcher = bilogs.NewFetcher(f.fs, f.loadCompressor(), bicrypto.NewSha1Calculator(f.fs), f.timeService, f.logger)
+	return f.logsFetcher
+}
+
+func (f *factory) loadLocker() bilock.Locker {
+	if f.locker != nil {
+		return f.locker
+	}
+
+	f.locker = bilock.NewLocker(f.fs, f.timeService, f.logger)
+	return f.locker
+}
+
+func (f *factory) loadNotifierFactory() bihealth.NotifierFactory {
+	if f.notifierFactory != nil {
+		return f.notifierFactory
+	}
+
+	httpClient := bihttpclient.NewHTTPClient(bihttpclient.CreateDefaultClient(nil), f.logger)
+	f.notifierFactory = bihealth.NewNotifierFactory(f.ui, f.fs, httpClient, smtp.SendMail, "localhost:25", "bosh-init@localhost", f.logger)
+	return f.notifierFactory
+}
+
 func (f *factory) loadCPIReleaseValidator() bicpirel.Validator {
 	if f.cpiReleaseValidator != nil {
 		return *f.cpiReleaseValidator
@@ -296,6 +556,29 @@ func (f *factory) loadCPIReleaseValidator() bicpirel.Validator {
 	return *f.cpiReleaseValidator
 }
 
+// loadStateEncryptor returns the encryptor for the deployment state and the manifest secrets,
+// or nil when neither BOSH_INIT_STATE_KEY_FILE nor BOSH_INIT_STATE_PASSPHRASE is set
+func (f *factory) loadStateEncryptor() bicrypto.Encryptor {
+	if f.stateEncryptor != nil {
+		return *f.stateEncryptor
+	}
+
+	var encryptor bicrypto.Encryptor
+	keyFilePath := os.Getenv(stateKeyFileEnv)
+	passphrase := os.Getenv(statePassphraseEnv)
+	if keyFilePath != "" {
+		if passphrase != "" {
+			f.logger.Warn("factory", "Both %s and %s are set, using the key file", stateKeyFileEnv, statePassphraseEnv)
+		}
+		encryptor = bicrypto.NewKeyFileEncryptor(f.fs, keyFilePath)
+	} else if passphrase != "" {
+		encryptor = bicrypto.NewPassphraseEncryptor(passphrase)
+	}
+
+	f.stateEncryptor = &encryptor
+	return *f.stateEncryptor
+}
+
 func (f *factory) loadReleaseExtractor() birel.Extractor {
 	if f.releaseExtractor != nil {
 		return f.releaseExtractor
@@ -311,14 +594,22 @@ func (f *factory) loadTarballProvider() bitarball.Provider {
 		return f.tarballProvider
 	}
 
-	tarballCacheBasePath := filepath.Join(f.workspaceRootPath, "downloads")
-	tarballCache := bitarball.NewCache(tarballCacheBasePath, f.fs, f.logger)
 	httpClient := bihttpclient.NewHTTPClient(bitarball.HTTPClient, f.logger)
 	sha1Calculator := bicrypto.NewSha1Calculator(f.fs)
-	f.tarballProvider = bitarball.NewProvider(tarballCache, f.fs, httpClient, sha1Calculator, 3, 500*time.Millisecond, f.logger)
+	f.tarballProvider = bitarball.NewProvider(f.loadTarballCache(), f.fs, httpClient, sha1Calculator, 3, 500*time.Millisecond, f.logger)
 	return f.tarballProvider
 }
 
+func (f *factory) loadTarballCache() bitarball.Cache {
+	if f.tarballCache != nil {
+		return f.tarballCache
+	}
+
+	tarballCacheBasePath := filepath.Join(f.workspaceRootPath, "downloads")
+	f.tarballCache = bitarball.NewCache(tarballCacheBasePath, f.fs, f.logger)
+	return f.tarballCache
+}
+
 func (f *factory) loadReleaseManager() birel.Manager {
 	if f.releaseManager != nil {
 		return f.releaseManager
@@ -343,7 +634,7 @@ func (f *factory) loadInstallationParser() biinstallmanifest.Parser {
 	}
 
 	uuidGenerator := boshuuid.NewGenerator()
-	f.installationParser = biinstallmanifest.NewParser(f.fs, uuidGenerator, f.logger, f.loadInstallationValidator())
+	f.installationParser = biinstallmanifest.NewParser(f.fs, uuidGenerator, f.logger, f.loadInstallationValidator(), f.loadStateEncryptor())
 	return f.installationParser
 }
 
@@ -394,14 +685,18 @@ func (f *factory) loadCloudFactory() bicloud.Factory {
 
 type deploymentManagerFactory2 struct {
 	f                             *factory
+	ui                            biui.UI
 	deploymentManifestPath        string
 	deploymentStateService        biconfig.DeploymentStateService
 	legacyDeploymentStateMigrator biconfig.LegacyDeploymentStateMigrator
 	vmRepo                        biconfig.VMRepo
 	stemcellRepo                  biconfig.StemcellRepo
 	diskRepo                      biconfig.DiskRepo
+	snapshotRepo                  biconfig.SnapshotRepo
+	cloudMigrationRepo            biconfig.CloudMigrationRepo
 	diskDeployer                  bivm.DiskDeployer
 	diskManagerFactory            bidisk.ManagerFactory
+	snapshotManagerFactory        bisnapshot.ManagerFactory
 	deploymentManagerFactory      bidepl.ManagerFactory
 	vmManagerFactory              bivm.ManagerFactory
 	stemcellManagerFactory        bistemcell.ManagerFactory
@@ -419,18 +714,30 @@ func (d *deploymentManagerFactory2) loadDeploymentPreparer() (DeploymentPreparer
 		return DeploymentPreparer{}, err
 	}
 
+	// the managers of the resources created while the deployment moves to another cloud provider
+	// record them apart from the current resources
+	moveFactory := &deploymentManagerFactory2{
+		f:                      d.f,
+		ui:                     d.ui,
+		deploymentManifestPath: d.deploymentManifestPath,
+		deploymentStateService: biconfig.NewCloudMigrationTargetStateService(d.loadDeploymentStateService()),
+	}
+
 	return NewDeploymentPreparer(
-		d.f.ui,
+		d.ui,
 		d.f.logger,
 		"DeploymentPreparer",
 		d.loadDeploymentStateService(),
 		d.loadLegacyDeploymentStateMigrator(),
+		d.loadCloudMigrationRepo(),
 		d.f.loadReleaseManager(),
 		deploymentRecord,
 		d.f.loadCloudFactory(),
 		d.loadStemcellManagerFactory(),
 		d.f.loadAgentClientFactory(),
 		d.loadVMManagerFactory(),
+		moveFactory.loadStemcellManagerFactory(),
+		moveFactory.loadVMManagerFactory(),
 		d.f.loadBlobstoreFactory(),
 		d.loadDeployer(),
 		d.deploymentManifestPath,
@@ -441,6 +748,8 @@ func (d *deploymentManagerFactory2) loadDeploymentPreparer() (DeploymentPreparer
 		d.loadDeploymentManifestParser(),
 		NewTempRootConfigurator(d.f.fs),
 		d.loadTargetProvider(),
+		d.f.loadLogsFetcher(),
+		d.f.loadLocker(),
 	), nil
 }
 
@@ -450,7 +759,7 @@ func (d *deploymentManagerFactory2) loadDeploymentDeleter() (DeploymentDeleter,
 		return nil, err
 	}
 	return NewDeploymentDeleter(
-		d.f.ui,
+		d.ui,
 		"DeploymentDeleter",
 		d.f.logger,
 		d.loadDeploymentStateService(),
@@ -459,6 +768,8 @@ func (d *deploymentManagerFactory2) loadDeploymentDeleter() (DeploymentDeleter,
 		d.f.loadAgentClientFactory(),
 		d.f.loadBlobstoreFactory(),
 		d.loadDeploymentManagerFactory(),
+		d.loadCloudMigrationRepo(),
+		d.f.loadDeploymentParser(),
 		d.deploymentManifestPath,
 		cpiInstaller,
 		d.loadCpiUninstaller(),
@@ -466,20 +777,104 @@ func (d *deploymentManagerFactory2) loadDeploymentDeleter() (DeploymentDeleter,
 		d.loadReleaseSetAndInstallationManifestParser(),
 		NewTempRootConfigurator(d.f.fs),
 		d.loadTargetProvider(),
+		d.f.loadLocker(),
+	), nil
+}
+
+func (d *deploymentManagerFactory2) loadInstanceLifecycle() (InstanceLifecycle, error) {
+	deploymentContext, err := d.loadDeploymentContext("InstanceLifecycle")
+	if err != nil {
+		return nil, err
+	}
+	return NewInstanceLifecycle(deploymentContext), nil
+}
+
+func (d *deploymentManagerFactory2) loadDeploymentSnapshotter() (DeploymentSnapshotter, error) {
+	deploymentContext, err := d.loadDeploymentContext("DeploymentSnapshotter")
+	if err != nil {
+		return nil, err
+	}
+	return NewDeploymentSnapshotter(
+		deploymentContext,
+		d.loadDiskRepo(),
+		d.loadSnapshotManagerFactory(),
+	), nil
+}
+
+func (d *deploymentManagerFactory2) loadStemcellUploader() (StemcellUploader, error) {
+	deploymentContext, err := d.loadDeploymentContext("StemcellUploader")
+	if err != nil {
+		return nil, err
+	}
+	return NewStemcellUploader(
+		deploymentContext,
+		d.loadStemcellManagerFactory(),
+		d.loadStemcellFetcher(),
+	), nil
+}
+
+func (d *deploymentManagerFactory2) loadDeploymentCleaner() (DeploymentCleaner, error) {
+	deploymentContext, err := d.loadDeploymentContext("DeploymentCleaner")
+	if err != nil {
+		return nil, err
+	}
+	return NewDeploymentCleaner(
+		deploymentContext,
+		d.loadCloudMigrationRepo(),
+		d.loadStemcellManagerFactory(),
+		d.loadDiskManagerFactory(),
+		d.f.loadTarballCache(),
 	), nil
 }
 
+func (d *deploymentManagerFactory2) loadDeploymentAdopter() (DeploymentAdopter, error) {
+	deploymentContext, err := d.loadDeploymentContext("DeploymentAdopter")
+	if err != nil {
+		return nil, err
+	}
+	return NewDeploymentAdopter(
+		deploymentContext,
+		d.loadDiskRepo(),
+		d.loadVMRepo(),
+		d.loadStemcellRepo(),
+		d.loadCloudMigrationRepo(),
+		d.loadStemcellFetcher(),
+	), nil
+}
+
+func (d *deploymentManagerFactory2) loadDeploymentContext(logTag string) (DeploymentContext, error) {
+	cpiInstaller, err := d.loadCpiInstaller()
+	if err != nil {
+		return DeploymentContext{}, err
+	}
+	return DeploymentContext{
+		UI:                                      d.ui,
+		LogTag:                                  logTag,
+		Logger:                                  d.f.logger,
+		DeploymentStateService:                  d.loadDeploymentStateService(),
+		ReleaseManager:                          d.f.loadReleaseManager(),
+		CloudFactory:                            d.f.loadCloudFactory(),
+		AgentClientFactory:                      d.f.loadAgentClientFactory(),
+		BlobstoreFactory:                        d.f.loadBlobstoreFactory(),
+		VMManagerFactory:                        d.loadVMManagerFactory(),
+		InstanceManagerFactory:                  d.f.loadInstanceManagerFactory(),
+		DeploymentParser:                        d.f.loadDeploymentParser(),
+		DeploymentManifestPath:                  d.deploymentManifestPath,
+		CpiInstaller:                            cpiInstaller,
+		ReleaseFetcher:                          d.loadReleaseFetcher(),
+		ReleaseSetAndInstallationManifestParser: d.loadReleaseSetAndInstallationManifestParser(),
+		TempRootConfigurator:                    NewTempRootConfigurator(d.f.fs),
+		TargetProvider:                          d.loadTargetProvider(),
+		Locker:                                  d.f.loadLocker(),
+	}, nil
+}
+
 func (d *deploymentManagerFactory2) loadDeploymentStateService() biconfig.DeploymentStateService {
 	if d.deploymentStateService != nil {
 		return d.deploymentStateService
 	}
 
-	d.deploymentStateService = biconfig.NewFileSystemDeploymentStateService(
-		d.f.fs,
-		d.f.uuidGenerator,
-		d.f.logger,
-		biconfig.DeploymentStatePath(d.deploymentManifestPath),
-	)
+	d.deploymentStateService = d.f.deploymentStateServiceProvider()(d.deploymentManifestPath)
 	return d.deploymentStateService
 }
 
@@ -521,12 +916,34 @@ func (d *deploymentManagerFactory2) loadDiskRepo() biconfig.DiskRepo {
 	return d.diskRepo
 }
 
+func (d *deploymentManagerFactory2) loadSnapshotRepo() biconfig.SnapshotRepo {
+	if d.snapshotRepo != nil {
+		return d.snapshotRepo
+	}
+	d.snapshotRepo = biconfig.NewSnapshotRepo(d.loadDeploymentStateService(), d.f.uuidGenerator)
+	return d.snapshotRepo
+}
+
+func (d *deploymentManagerFactory2) loadCloudMigrationRepo() biconfig.CloudMigrationRepo {
+	if d.cloudMigrationRepo != nil {
+		return d.cloudMigrationRepo
+	}
+	d.cloudMigrationRepo = biconfig.NewCloudMigrationRepo(d.loadDeploymentStateService())
+	return d.cloudMigrationRepo
+}
+
 func (d *deploymentManagerFactory2) loadDiskDeployer() bivm.DiskDeployer {
 	if d.diskDeployer != nil {
 		return d.diskDeployer
 	}
 
-	d.diskDeployer = bivm.NewDiskDeployer(d.loadDiskManagerFactory(), d.loadDiskRepo(), d.f.logger)
+	d.diskDeployer = bivm.NewDiskDeployer(
+		d.loadDiskManagerFactory(),
+		d.loadSnapshotManagerFactory(),
+		d.loadDiskRepo(),
+		biconfig.NewDiskMigrationRepo(d.loadDeploymentStateService()),
+		d.f.logger,
+	)
 	return d.diskDeployer
 }
 
@@ -539,6 +956,15 @@ func (d *deploymentManagerFactory2) loadDiskManagerFactory() bidisk.ManagerFacto
 	return d.diskManagerFactory
 }
 
+func (d *deploymentManagerFactory2) loadSnapshotManagerFactory() bisnapshot.ManagerFactory {
+	if d.snapshotManagerFactory != nil {
+		return d.snapshotManagerFactory
+	}
+
+	d.snapshotManagerFactory = bisnapshot.NewManagerFactory(d.loadDiskRepo(), d.loadSnapshotRepo(), d.f.timeService, d.f.logger)
+	return d.snapshotManagerFactory
+}
+
 func (d *deploymentManagerFactory2) loadDeploymentManagerFactory() bidepl.ManagerFactory {
 	if d.deploymentManagerFactory != nil {
 		return d.deploymentManagerFactory
@@ -565,6 +991,7 @@ func (d *deploymentManagerFactory2) loadVMManagerFactory() bivm.ManagerFactory {
 		d.loadDiskDeployer(),
 		d.f.uuidGenerator,
 		d.f.fs,
+		d.f.timeService,
 		d.f.logger,
 	)
 	return d.vmManagerFactory
@@ -599,7 +1026,7 @@ func (d *deploymentManagerFactory2) loadInstallerFactory() biinstall.InstallerFa
 	}
 
 	d.installerFactory = biinstall.NewInstallerFactory(
-		d.f.ui,
+		d.ui,
 		d.f.loadCMDRunner(),
 		d.f.loadCompressor(),
 		d.f.loadReleaseJobResolver(),
@@ -640,7 +1067,8 @@ func (d *deploymentManagerFactory2) loadReleaseFetcher() birel.Fetcher {
 }
 
 func (d *deploymentManagerFactory2) loadStemcellFetcher() bistemcell.Fetcher {
-	stemcellReader := bistemcell.NewReader(d.f.loadCompressor(), d.f.fs)
+	digestVerifier := bicrypto.NewDigestVerifier(bicrypto.NewSha1Calculator(d.f.fs), bicrypto.NewSha256Calculator(d.f.fs))
+	stemcellReader := bistemcell.NewReader(d.f.loadCompressor(), d.f.fs, digestVerifier)
 	stemcellExtractor := bistemcell.NewExtractor(stemcellReader, d.f.fs)
 
 	return bistemcell.Fetcher{
@@ -37,6 +37,7 @@ var _ = Describe("cmd.Factory", func() {
 			logger,
 			uuidGenerator,
 			"/fake-path",
+			make(chan struct{}),
 		)
 	})
 
@@ -53,6 +54,164 @@ var _ = Describe("cmd.Factory", func() {
 			})
 		})
 
+		Describe("registry command", func() {
+			It("returns registry command", func() {
+				cmd, err := factory.CreateCommand("registry")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("registry"))
+			})
+		})
+
+		Describe("ssh command", func() {
+			It("returns ssh command", func() {
+				cmd, err := factory.CreateCommand("ssh")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("ssh"))
+			})
+		})
+
+		Describe("logs command", func() {
+			It("returns logs command", func() {
+				cmd, err := factory.CreateCommand("logs")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("logs"))
+			})
+		})
+
+		Describe("stop command", func() {
+			It("returns stop command", func() {
+				cmd, err := factory.CreateCommand("stop")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("stop"))
+			})
+		})
+
+		Describe("start command", func() {
+			It("returns start command", func() {
+				cmd, err := factory.CreateCommand("start")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("start"))
+			})
+		})
+
+		Describe("restart command", func() {
+			It("returns restart command", func() {
+				cmd, err := factory.CreateCommand("restart")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("restart"))
+			})
+		})
+
+		Describe("recreate command", func() {
+			It("returns recreate command", func() {
+				cmd, err := factory.CreateCommand("recreate")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("recreate"))
+			})
+		})
+
+		Describe("instances command", func() {
+			It("returns instances command", func() {
+				cmd, err := factory.CreateCommand("instances")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("instances"))
+			})
+
+			It("returns instances command for vms", func() {
+				cmd, err := factory.CreateCommand("vms")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("instances"))
+			})
+		})
+
+		Describe("watch command", func() {
+			It("returns watch command", func() {
+				cmd, err := factory.CreateCommand("watch")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("watch"))
+			})
+		})
+
+		Describe("take-snapshot command", func() {
+			It("returns take-snapshot command", func() {
+				cmd, err := factory.CreateCommand("take-snapshot")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("take-snapshot"))
+			})
+		})
+
+		Describe("snapshots command", func() {
+			It("returns snapshots command", func() {
+				cmd, err := factory.CreateCommand("snapshots")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("snapshots"))
+			})
+		})
+
+		Describe("delete-snapshot command", func() {
+			It("returns delete-snapshot command", func() {
+				cmd, err := factory.CreateCommand("delete-snapshot")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("delete-snapshot"))
+			})
+		})
+
+		Describe("restore-snapshot command", func() {
+			It("returns restore-snapshot command", func() {
+				cmd, err := factory.CreateCommand("restore-snapshot")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("restore-snapshot"))
+			})
+		})
+
+		Describe("stemcells command", func() {
+			It("returns stemcells command", func() {
+				cmd, err := factory.CreateCommand("stemcells")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("stemcells"))
+			})
+		})
+
+		Describe("upload-stemcell command", func() {
+			It("returns upload-stemcell command", func() {
+				cmd, err := factory.CreateCommand("upload-stemcell")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("upload-stemcell"))
+			})
+		})
+
+		Describe("clean-up command", func() {
+			It("returns clean-up command", func() {
+				cmd, err := factory.CreateCommand("clean-up")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("clean-up"))
+			})
+		})
+
+		Describe("inspect-release command", func() {
+			It("returns inspect-release command", func() {
+				cmd, err := factory.CreateCommand("inspect-release")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("inspect-release"))
+			})
+		})
+
+		Describe("state command", func() {
+			It("returns state command", func() {
+				cmd, err := factory.CreateCommand("state")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("state"))
+			})
+		})
+
+		Describe("adopt command", func() {
+			It("returns adopt command", func() {
+				cmd, err := factory.CreateCommand("adopt")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("adopt"))
+			})
+		})
+
 		Describe("delete command", func() {
 			It("returns delete command", func() {
 				cmd, err := factory.CreateCommand("delete")
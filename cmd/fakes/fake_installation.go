@@ -17,6 +17,14 @@ func (f *FakeInstallation) Job() biinstallation.InstalledJob {
 	return biinstallation.InstalledJob{}
 }
 
+func (f *FakeInstallation) CloudProviderJob(name string) (biinstallation.InstalledJob, bool) {
+	return biinstallation.InstalledJob{}, true
+}
+
+func (f *FakeInstallation) CloudProviders() []string {
+	return []string{}
+}
+
 func (f *FakeInstallation) WithRunningRegistry(logger boshlog.Logger, stage biui.Stage, fn func() error) error {
 	return fn()
 }
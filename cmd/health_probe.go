@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bihealth "github.com/cloudfoundry/bosh-init/deployment/health"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// HealthProbe checks the health of the deployed instance with a CPI installed once for all checks
+type HealthProbe interface {
+	bihealth.Checker
+
+	// Close deletes the rendered CPI jobs, the probe cannot be used afterwards
+	Close() error
+}
+
+type healthProbe struct {
+	deploymentStateService biconfig.DeploymentStateService
+	locker                 bilock.Locker
+	cloud                  bicloud.Cloud
+	agentClient            biagent.AgentClient
+	cleanup                func() error
+	logger                 boshlog.Logger
+	logTag                 string
+}
+
+func NewHealthProbe(
+	deploymentStateService biconfig.DeploymentStateService,
+	locker bilock.Locker,
+	cloud bicloud.Cloud,
+	agentClient biagent.AgentClient,
+	cleanup func() error,
+	logger boshlog.Logger,
+) HealthProbe {
+	return &healthProbe{
+		deploymentStateService: deploymentStateService,
+		locker:                 locker,
+		cloud:                  cloud,
+		agentClient:            agentClient,
+		cleanup:                cleanup,
+		logger:                 logger,
+		logTag:                 "healthProbe",
+	}
+}
+
+func (p *healthProbe) Check() (bihealth.State, error) {
+	vmCID, err := p.currentVMCID()
+	if err != nil {
+		return bihealth.StateUnknown, err
+	}
+
+	if vmCID == "" {
+		// nothing to recreate, e.g. after a hard stop
+		return bihealth.StateUnknown, bosherr.Error("No deployed VM found")
+	}
+
+	exists, err := p.cloud.HasVM(vmCID)
+	if err != nil {
+		return bihealth.StateUnknown, bosherr.WrapErrorf(err, "Checking existence of VM '%s'", vmCID)
+	}
+
+	if !exists {
+		return bihealth.StateMissing, nil
+	}
+
+	_, err = p.agentClient.Ping()
+	if err != nil {
+		p.logger.Warn(p.logTag, "Agent on VM '%s' is unresponsive: %s", vmCID, err.Error())
+		return bihealth.StateUnresponsive, nil
+	}
+
+	agentState, err := p.agentClient.GetState()
+	if err != nil {
+		p.logger.Warn(p.logTag, "Getting state of VM '%s': %s", vmCID, err.Error())
+		return bihealth.StateUnresponsive, nil
+	}
+
+	if agentState.JobState != "running" {
+		return bihealth.StateFailing, nil
+	}
+
+	return bihealth.StateRunning, nil
+}
+
+func (p *healthProbe) Close() error {
+	return p.cleanup()
+}
+
+// currentVMCID reads the VM CID from the deployment state for every check,
+// because the VM may have been recreated since the probe was created
+func (p *healthProbe) currentVMCID() (string, error) {
+	stateLock, err := acquireLock(p.locker, p.deploymentStateService.Path()+".lock", "deployment state", false)
+	if err != nil {
+		return "", err
+	}
+	defer releaseLock(stateLock, p.logger, p.logTag)
+
+	deploymentState, err := p.deploymentStateService.Load()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	return deploymentState.CurrentVMCID, nil
+}
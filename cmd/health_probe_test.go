@@ -0,0 +1,135 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
+	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bihealth "github.com/cloudfoundry/bosh-init/deployment/health"
+	mock_lock "github.com/cloudfoundry/bosh-init/lock/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	"github.com/golang/mock/gomock"
+)
+
+var _ = Describe("HealthProbe", func() {
+	var (
+		mockCtrl               *gomock.Controller
+		mockCloud              *mock_cloud.MockCloud
+		mockAgentClient        *mock_agentclient.MockAgentClient
+		mockLocker             *mock_lock.MockLocker
+		mockLock               *mock_lock.MockLock
+		deploymentStateService biconfig.DeploymentStateService
+		cleanedUp              int
+
+		probe bicmd.HealthProbe
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCloud = mock_cloud.NewMockCloud(mockCtrl)
+		mockAgentClient = mock_agentclient.NewMockAgentClient(mockCtrl)
+		mockLocker = mock_lock.NewMockLocker(mockCtrl)
+		mockLock = mock_lock.NewMockLock(mockCtrl)
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, "/fake-deployment-state.json")
+		err := deploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})
+		Expect(err).ToNot(HaveOccurred())
+
+		mockLocker.EXPECT().Lock("/fake-deployment-state.json.lock", false).Return(mockLock, nil).AnyTimes()
+		mockLock.EXPECT().Release().Return(nil).AnyTimes()
+
+		cleanedUp = 0
+		cleanup := func() error {
+			cleanedUp++
+			return nil
+		}
+
+		probe = bicmd.NewHealthProbe(deploymentStateService, mockLocker, mockCloud, mockAgentClient, cleanup, logger)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("reports a running instance when the VM exists and its jobs are running", func() {
+		mockCloud.EXPECT().HasVM("fake-vm-cid").Return(true, nil)
+		mockAgentClient.EXPECT().Ping().Return("pong", nil)
+		mockAgentClient.EXPECT().GetState().Return(biagentclient.AgentState{JobState: "running"}, nil)
+
+		state, err := probe.Check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(bihealth.StateRunning))
+	})
+
+	It("reports a failing instance when its jobs are not running", func() {
+		mockCloud.EXPECT().HasVM("fake-vm-cid").Return(true, nil)
+		mockAgentClient.EXPECT().Ping().Return("pong", nil)
+		mockAgentClient.EXPECT().GetState().Return(biagentclient.AgentState{JobState: "failing"}, nil)
+
+		state, err := probe.Check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(bihealth.StateFailing))
+	})
+
+	It("reports an unresponsive instance when the agent does not answer", func() {
+		mockCloud.EXPECT().HasVM("fake-vm-cid").Return(true, nil)
+		mockAgentClient.EXPECT().Ping().Return("", bosherr.Error("fake-ping-error"))
+
+		state, err := probe.Check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(bihealth.StateUnresponsive))
+	})
+
+	It("reports a missing instance when the VM does not exist", func() {
+		mockCloud.EXPECT().HasVM("fake-vm-cid").Return(false, nil)
+
+		state, err := probe.Check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(bihealth.StateMissing))
+	})
+
+	It("checks the VM currently recorded in the deployment state", func() {
+		err := deploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-recreated-vm-cid"})
+		Expect(err).ToNot(HaveOccurred())
+
+		mockCloud.EXPECT().HasVM("fake-recreated-vm-cid").Return(false, nil)
+
+		state, err := probe.Check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(bihealth.StateMissing))
+	})
+
+	It("returns an unknown state when there is no deployed VM", func() {
+		err := deploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+		Expect(err).ToNot(HaveOccurred())
+
+		state, err := probe.Check()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("No deployed VM found"))
+		Expect(state).To(Equal(bihealth.StateUnknown))
+	})
+
+	It("returns an unknown state when checking the VM fails", func() {
+		mockCloud.EXPECT().HasVM("fake-vm-cid").Return(false, bosherr.Error("fake-has-vm-error"))
+
+		state, err := probe.Check()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-has-vm-error"))
+		Expect(state).To(Equal(bihealth.StateUnknown))
+	})
+
+	It("cleans up the CPI installation when closed", func() {
+		err := probe.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cleanedUp).To(Equal(1))
+	})
+})
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	birelpkg "github.com/cloudfoundry/bosh-init/release/pkg"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const sha1Flag = "--sha1="
+
+type inspectReleaseCmd struct {
+	ui              biui.UI
+	fs              boshsys.FileSystem
+	compressor      boshcmd.Compressor
+	validator       birel.Validator
+	tarballProvider bitarball.Provider
+	logger          boshlog.Logger
+	logTag          string
+}
+
+func NewInspectReleaseCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	compressor boshcmd.Compressor,
+	validator birel.Validator,
+	tarballProvider bitarball.Provider,
+	logger boshlog.Logger,
+) Cmd {
+	return &inspectReleaseCmd{
+		ui:              ui,
+		fs:              fs,
+		compressor:      compressor,
+		validator:       validator,
+		tarballProvider: tarballProvider,
+		logger:          logger,
+		logTag:          "inspectReleaseCmd",
+	}
+}
+
+func (c *inspectReleaseCmd) Name() string {
+	return "inspect-release"
+}
+
+func (c *inspectReleaseCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Show the contents of a release tarball and all of its validation problems",
+		Usage:    "<release_tarball_path_or_url> [--sha1=<digest>]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *inspectReleaseCmd) Run(stage biui.Stage, args []string) error {
+	releaseSource, sha1, err := c.parseArgs(args)
+	if err != nil {
+		return err
+	}
+
+	releaseTarballPath, err := c.tarballPath(releaseSource, sha1, stage)
+	if err != nil {
+		return err
+	}
+
+	extractedReleasePath, err := c.fs.TempDir("bosh-init-release")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating temp directory to extract release '%s'", releaseTarballPath)
+	}
+	defer func() {
+		if removeErr := c.fs.RemoveAll(extractedReleasePath); removeErr != nil {
+			c.logger.Warn(c.logTag, "Failed to remove extracted release: %s", removeErr.Error())
+		}
+	}()
+
+	release, err := birel.NewReader(releaseTarballPath, extractedReleasePath, c.fs, c.compressor).Read()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading release from '%s'", releaseTarballPath)
+	}
+
+	c.printRelease(release)
+	c.printJobs(release)
+	problems := c.printPackages(release)
+
+	err = c.validator.Validate(release)
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	if len(problems) == 0 {
+		c.ui.PrintLinef("No problems found")
+		return nil
+	}
+
+	c.ui.PrintLinef("Problems")
+	for _, problem := range problems {
+		for _, line := range strings.Split(problem.Error(), "\n") {
+			c.ui.PrintLinef("  %s", line)
+		}
+	}
+
+	return bosherr.Errorf("Release '%s/%s' is not valid", release.Name(), release.Version())
+}
+
+func (c *inspectReleaseCmd) parseArgs(args []string) (string, string, error) {
+	var releaseSource, sha1 string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, sha1Flag) {
+			sha1 = strings.TrimPrefix(arg, sha1Flag)
+			continue
+		}
+		if releaseSource != "" {
+			c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+			return "", "", errors.New("Invalid usage - inspect-release command requires exactly 1 argument")
+		}
+		releaseSource = arg
+	}
+
+	if releaseSource == "" {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return "", "", errors.New("Invalid usage - inspect-release command requires exactly 1 argument")
+	}
+
+	return releaseSource, sha1, nil
+}
+
+func (c *inspectReleaseCmd) tarballPath(releaseSource string, sha1 string, stage biui.Stage) (string, error) {
+	if strings.Contains(releaseSource, "://") {
+		releaseRef := birelmanifest.ReleaseRef{
+			Name: path.Base(releaseSource),
+			URL:  releaseSource,
+			SHA1: sha1,
+		}
+		return c.tarballProvider.Get(releaseRef, stage)
+	}
+
+	releaseTarballPath, err := filepath.Abs(releaseSource)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Getting absolute path to release tarball '%s'", releaseSource)
+	}
+
+	if !c.fs.FileExists(releaseTarballPath) {
+		c.ui.ErrorLinef("Release tarball '%s' does not exist", releaseTarballPath)
+		return "", bosherr.Errorf("Release tarball does not exist at '%s'", releaseTarballPath)
+	}
+
+	return releaseTarballPath, nil
+}
+
+func (c *inspectReleaseCmd) printRelease(release birel.Release) {
+	c.ui.PrintLinef("Release '%s/%s'", release.Name(), release.Version())
+
+	commitHash := release.CommitHash()
+	if release.UncommittedChanges() {
+		commitHash += " (uncommitted changes)"
+	}
+	c.ui.PrintLinef("  Commit hash: %s", commitHash)
+
+	if !release.IsCompiled() {
+		c.ui.PrintLinef("  Compiled:    no")
+		return
+	}
+
+	stemcells := []string{}
+	for _, releasePackage := range release.Packages() {
+		if !containsString(stemcells, releasePackage.Stemcell) {
+			stemcells = append(stemcells, releasePackage.Stemcell)
+		}
+	}
+	c.ui.PrintLinef("  Compiled:    yes, against stemcell '%s'", strings.Join(stemcells, "', '"))
+}
+
+func (c *inspectReleaseCmd) printJobs(release birel.Release) {
+	c.ui.PrintLinef("Jobs")
+	for _, job := range release.Jobs() {
+		c.ui.PrintLinef("  %s", job.Name)
+
+		c.ui.PrintLinef("    Templates:")
+		for _, template := range sortedKeys(job.Templates) {
+			c.ui.PrintLinef("      %s -> %s", template, job.Templates[template])
+		}
+
+		c.ui.PrintLinef("    Packages: %s", strings.Join(job.PackageNames, ", "))
+
+		c.ui.PrintLinef("    Properties:")
+		propertyNames := []string{}
+		for propertyName := range job.Properties {
+			propertyNames = append(propertyNames, propertyName)
+		}
+		sort.Strings(propertyNames)
+		for _, propertyName := range propertyNames {
+			property := job.Properties[propertyName]
+			if property.Default == nil {
+				c.ui.PrintLinef("      %s", propertyName)
+			} else {
+				c.ui.PrintLinef("      %s (default: %s)", propertyName, formatPropertyDefault(property.Default))
+			}
+			if property.Description != "" {
+				c.ui.PrintLinef("        %s", property.Description)
+			}
+		}
+	}
+}
+
+// printPackages prints the packages in the order they would be compiled.
+// A dependency cycle is returned as a problem and the packages are printed in release order instead.
+func (c *inspectReleaseCmd) printPackages(release birel.Release) []error {
+	problems := []error{}
+
+	packages, err := birelpkg.Sort(release.Packages())
+	if err != nil {
+		problems = append(problems, bosherr.WrapError(err, "Sorting release packages"))
+		packages = release.Packages()
+		c.ui.PrintLinef("Packages")
+	} else {
+		c.ui.PrintLinef("Packages (in compilation order)")
+	}
+
+	for _, releasePackage := range packages {
+		dependencyNames := []string{}
+		for _, dependency := range releasePackage.Dependencies {
+			dependencyNames = append(dependencyNames, dependency.Name)
+		}
+
+		c.ui.PrintLinef("  %s", releasePackage.Name)
+		c.ui.PrintLinef("    Dependencies: %s", strings.Join(dependencyNames, ", "))
+		if releasePackage.IsCompiled() {
+			c.ui.PrintLinef("    Stemcell:     %s", releasePackage.Stemcell)
+		}
+	}
+
+	return problems
+}
+
+func formatPropertyDefault(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_tarball "github.com/cloudfoundry/bosh-init/installation/tarball/mocks"
+	"github.com/golang/mock/gomock"
+
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	fakecmd "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("InspectReleaseCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			fs                  *fakesys.FakeFileSystem
+			fakeUI              *fakebiui.FakeUI
+			fakeStage           *fakebiui.FakeStage
+			mockTarballProvider *mock_tarball.MockProvider
+
+			releaseTarballPath = "/fake/release.tgz"
+		)
+
+		var newInspectReleaseCmd = func() bicmd.Cmd {
+			logger := boshlog.NewLogger(boshlog.LevelNone)
+			return bicmd.NewInspectReleaseCmd(fakeUI, fs, fakecmd.NewFakeCompressor(), birel.NewValidator(fs), mockTarballProvider, logger)
+		}
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+			mockTarballProvider = mock_tarball.NewMockProvider(mockCtrl)
+
+			fs.TempDirDirs = []string{"/extracted-release-path"}
+			fs.WriteFileString(releaseTarballPath, "fake-tgz-contents")
+			fs.WriteFileString("/extracted-release-path/release.MF", `---
+name: fake-release-name
+version: fake-release-version
+commit_hash: abc123
+uncommitted_changes: true
+
+packages:
+- name: fake-package-1
+  fingerprint: fake-package-1-fingerprint
+  sha1: fake-package-1-sha1
+  dependencies: []
+- name: fake-package-2
+  fingerprint: fake-package-2-fingerprint
+  sha1: fake-package-2-sha1
+  dependencies: [fake-package-1]
+jobs:
+- name: fake-job
+  fingerprint: fake-job-fingerprint
+  sha1: fake-job-sha1
+`)
+			fs.WriteFileString("/extracted-release-path/extracted_jobs/fake-job/job.MF", `---
+name: fake-job
+templates:
+  ctl.erb: bin/ctl
+  config.yml.erb: config/config.yml
+packages:
+- fake-package-2
+properties:
+  fake.port:
+    description: The port to listen on
+    default: 8080
+  fake.password:
+    description: The password
+`)
+			fs.WriteFileString("/extracted-release-path/extracted_jobs/fake-job/monit", "")
+			fs.WriteFileString("/extracted-release-path/extracted_jobs/fake-job/templates/ctl.erb", "")
+			fs.WriteFileString("/extracted-release-path/extracted_jobs/fake-job/templates/config.yml.erb", "")
+		})
+
+		It("prints the release, its jobs and its packages in compilation order", func() {
+			err := newInspectReleaseCmd().Run(fakeStage, []string{releaseTarballPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(Equal([]string{
+				"Release 'fake-release-name/fake-release-version'",
+				"  Commit hash: abc123 (uncommitted changes)",
+				"  Compiled:    no",
+				"Jobs",
+				"  fake-job",
+				"    Templates:",
+				"      config.yml.erb -> config/config.yml",
+				"      ctl.erb -> bin/ctl",
+				"    Packages: fake-package-2",
+				"    Properties:",
+				"      fake.password",
+				"        The password",
+				"      fake.port (default: 8080)",
+				"        The port to listen on",
+				"Packages (in compilation order)",
+				"  fake-package-1",
+				"    Dependencies: ",
+				"  fake-package-2",
+				"    Dependencies: fake-package-1",
+				"No problems found",
+			}))
+		})
+
+		It("removes the extracted release", func() {
+			err := newInspectReleaseCmd().Run(fakeStage, []string{releaseTarballPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/extracted-release-path")).To(BeFalse())
+		})
+
+		It("prints all validation problems at once", func() {
+			fs.RemoveAll("/extracted-release-path/extracted_jobs/fake-job/monit")
+			fs.RemoveAll("/extracted-release-path/extracted_jobs/fake-job/templates/ctl.erb")
+
+			err := newInspectReleaseCmd().Run(fakeStage, []string{releaseTarballPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Release 'fake-release-name/fake-release-version' is not valid"))
+			Expect(fakeUI.Said).To(ContainElement("Problems"))
+			Expect(fakeUI.Said).To(ContainElement("  Validating release jobs: Job 'fake-job' is missing monit file"))
+			Expect(fakeUI.Said).To(ContainElement("  Job 'fake-job' is missing template '/extracted-release-path/extracted_jobs/fake-job/templates/ctl.erb'"))
+		})
+
+		It("downloads the release when given a URL", func() {
+			mockTarballProvider.EXPECT().Get(birelmanifest.ReleaseRef{
+				Name: "release.tgz",
+				URL:  "https://example.com/release.tgz",
+				SHA1: "fake-sha1",
+			}, fakeStage).Return(releaseTarballPath, nil)
+
+			err := newInspectReleaseCmd().Run(fakeStage, []string{"https://example.com/release.tgz", "--sha1=fake-sha1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Release 'fake-release-name/fake-release-version'"))
+		})
+
+		It("returns an error when the release tarball does not exist", func() {
+			err := newInspectReleaseCmd().Run(fakeStage, []string{"/fake/missing.tgz"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Release tarball does not exist at '/fake/missing.tgz'"))
+		})
+
+		It("returns an error when not given exactly one release", func() {
+			err := newInspectReleaseCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Invalid usage - inspect-release command requires exactly 1 argument"))
+		})
+	})
+})
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// InstanceLifecycle inspects, stops and starts the jobs of the deployed instance without redeploying it.
+type InstanceLifecycle interface {
+	// Status checks the deployed VM with the CPI and its agent and reports the state of its jobs.
+	// Returns false if there is no deployed VM.
+	Status(stage biui.Stage) (InstanceStatus, bool, error)
+
+	// Stop drains and stops the jobs on the deployed VM.
+	// When hard is true the VM is deleted afterwards, keeping its persistent disks.
+	// When skipDrain is true the drain scripts of the jobs are not run.
+	Stop(stage biui.Stage, hard bool, skipDrain bool, forceLock bool) error
+
+	// Start starts the jobs on the deployed VM and waits for them to be running.
+	// Returns false if there is no VM left to start, e.g. after a hard stop.
+	Start(stage biui.Stage, forceLock bool) (bool, error)
+
+	// Restart drains, stops and starts the jobs on the deployed VM.
+	Restart(stage biui.Stage, skipDrain bool, forceLock bool) error
+
+	// NewHealthProbe installs the CPI and creates the CPI and agent clients for the deployed instance once,
+	// so that the instance can be checked repeatedly without installing the CPI for every check.
+	// The probe must be closed to delete the rendered CPI jobs.
+	NewHealthProbe(stage biui.Stage, forceLock bool) (HealthProbe, error)
+}
+
+func NewInstanceLifecycle(deploymentContext DeploymentContext) InstanceLifecycle {
+	return &instanceLifecycle{
+		DeploymentContext: deploymentContext,
+	}
+}
+
+type instanceLifecycle struct {
+	DeploymentContext
+}
+
+// InstanceStatus describes the deployed instance as seen by the CPI and its agent
+type InstanceStatus struct {
+	Name            string           `json:"name"`
+	VMCID           string           `json:"vm_cid"`
+	VMExists        bool             `json:"vm_exists"`
+	AgentResponsive bool             `json:"agent_responsive"`
+	AgentID         string           `json:"agent_id"`
+	IPs             []string         `json:"ips"`
+	JobState        string           `json:"job_state"`
+	Processes       []ProcessStatus  `json:"processes"`
+	Disks           []DiskStatus     `json:"disks"`
+	Stemcell        ArtifactStatus   `json:"stemcell"`
+	Releases        []ArtifactStatus `json:"releases"`
+}
+
+type ProcessStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+type DiskStatus struct {
+	CID string `json:"cid"`
+	// Name is empty for the disk configured with persistent_disk or persistent_disk_pool
+	Name string `json:"name,omitempty"`
+	// Usage is the percentage of the disk in use, as reported by the agent, or empty if unknown
+	Usage string `json:"usage"`
+}
+
+// ArtifactStatus identifies a stemcell or release by name and version
+type ArtifactStatus struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func (l *instanceLifecycle) Status(stage biui.Stage) (InstanceStatus, bool, error) {
+	stateLock, err := l.lockDeploymentState(false)
+	if err != nil {
+		return InstanceStatus{}, false, err
+	}
+	defer releaseLock(stateLock, l.Logger, l.LogTag)
+
+	var status InstanceStatus
+	found, err := l.withCurrentVM(stage, false, func(vm bivm.VM, _ biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		deploymentState, err := l.DeploymentStateService.Load()
+		if err != nil {
+			return bosherr.WrapError(err, "Loading deployment state")
+		}
+
+		status = newInstanceStatus(vm.CID(), deploymentManifest, deploymentState)
+
+		stepName := fmt.Sprintf("Checking state of VM '%s'", vm.CID())
+		return stage.Perform(stepName, func() error {
+			status.VMExists, err = vm.Exists()
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Checking existence of VM '%s'", vm.CID())
+			}
+
+			if !status.VMExists {
+				return nil
+			}
+
+			_, err = vm.AgentClient().Ping()
+			if err != nil {
+				l.Logger.Warn(l.LogTag, "Agent on VM '%s' is unresponsive: %s", vm.CID(), err.Error())
+				return nil
+			}
+
+			agentState, err := vm.GetFullState()
+			if err != nil {
+				l.Logger.Warn(l.LogTag, "Getting state of VM '%s': %s", vm.CID(), err.Error())
+				return nil
+			}
+
+			status.AgentResponsive = true
+			status.setAgentState(agentState)
+			return nil
+		})
+	})
+
+	return status, found, err
+}
+
+func newInstanceStatus(vmCID string, deploymentManifest bideplmanifest.Manifest, deploymentState biconfig.DeploymentState) InstanceStatus {
+	jobName := "unknown"
+	if len(deploymentManifest.Jobs) > 0 {
+		jobName = deploymentManifest.Jobs[0].Name
+	}
+
+	status := InstanceStatus{
+		Name:      fmt.Sprintf("%s/0", jobName),
+		VMCID:     vmCID,
+		IPs:       []string{},
+		Processes: []ProcessStatus{},
+		Disks:     []DiskStatus{},
+		Releases:  []ArtifactStatus{},
+	}
+
+	currentDiskIDs := map[string]struct{}{deploymentState.CurrentDiskID: struct{}{}}
+	for _, diskID := range deploymentState.CurrentNamedDiskIDs {
+		currentDiskIDs[diskID] = struct{}{}
+	}
+
+	for _, diskRecord := range deploymentState.Disks {
+		if _, current := currentDiskIDs[diskRecord.ID]; current {
+			status.Disks = append(status.Disks, DiskStatus{CID: diskRecord.CID, Name: diskRecord.Name})
+		}
+	}
+
+	for _, stemcellRecord := range deploymentState.Stemcells {
+		if stemcellRecord.ID == deploymentState.CurrentStemcellID {
+			status.Stemcell = ArtifactStatus{Name: stemcellRecord.Name, Version: stemcellRecord.Version}
+		}
+	}
+
+	for _, releaseID := range deploymentState.CurrentReleaseIDs {
+		for _, releaseRecord := range deploymentState.Releases {
+			if releaseRecord.ID == releaseID {
+				status.Releases = append(status.Releases, ArtifactStatus{Name: releaseRecord.Name, Version: releaseRecord.Version})
+			}
+		}
+	}
+
+	return status
+}
+
+func (s *InstanceStatus) setAgentState(agentState biagent.AgentState) {
+	s.AgentID = agentState.AgentID
+	s.JobState = agentState.JobState
+
+	networkNames := []string{}
+	for networkName := range agentState.NetworkSpecs {
+		networkNames = append(networkNames, networkName)
+	}
+	sort.Strings(networkNames)
+	for _, networkName := range networkNames {
+		s.IPs = append(s.IPs, agentState.NetworkSpecs[networkName].IP)
+	}
+
+	for _, process := range agentState.Processes {
+		s.Processes = append(s.Processes, ProcessStatus{Name: process.Name, State: process.State})
+	}
+
+	// the agent only reports the usage of the disk currently mounted as the persistent disk
+	if persistentDiskVitals, found := agentState.Vitals.Disk["persistent"]; found {
+		for i := range s.Disks {
+			if s.Disks[i].Name == "" {
+				s.Disks[i].Usage = persistentDiskVitals.Percent
+			}
+		}
+	}
+}
+
+func (l *instanceLifecycle) Stop(stage biui.Stage, hard bool, skipDrain bool, forceLock bool) error {
+	stateLock, err := l.lockDeploymentState(forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, l.Logger, l.LogTag)
+
+	found, err := l.withCurrentVM(stage, forceLock, func(vm bivm.VM, instanceManager biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		drainOptions := biinstance.NewDrainOptions(deploymentManifest.Update, skipDrain)
+
+		if hard {
+			pingTimeout := 10 * time.Second
+			pingDelay := 500 * time.Millisecond
+			return instanceManager.DeleteAll(pingTimeout, pingDelay, drainOptions, stage)
+		}
+
+		if err := l.checkExists(vm); err != nil {
+			return err
+		}
+
+		return l.stopJobs(vm, drainOptions, stage)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		l.UI.PrintLinef("No deployed VM found")
+	}
+
+	return nil
+}
+
+func (l *instanceLifecycle) Start(stage biui.Stage, forceLock bool) (bool, error) {
+	stateLock, err := l.lockDeploymentState(forceLock)
+	if err != nil {
+		return false, err
+	}
+	defer releaseLock(stateLock, l.Logger, l.LogTag)
+
+	started := false
+	_, err = l.withCurrentVM(stage, forceLock, func(vm bivm.VM, _ biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		exists, err := vm.Exists()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking existence of VM '%s'", vm.CID())
+		}
+
+		if !exists {
+			l.Logger.Info(l.LogTag, "VM '%s' no longer exists", vm.CID())
+			return nil
+		}
+
+		started = true
+		return l.startJobs(vm, deploymentManifest, stage)
+	})
+
+	return started, err
+}
+
+func (l *instanceLifecycle) Restart(stage biui.Stage, skipDrain bool, forceLock bool) error {
+	stateLock, err := l.lockDeploymentState(forceLock)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, l.Logger, l.LogTag)
+
+	found, err := l.withCurrentVM(stage, forceLock, func(vm bivm.VM, _ biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		if err := l.checkExists(vm); err != nil {
+			return err
+		}
+
+		drainOptions := biinstance.NewDrainOptions(deploymentManifest.Update, skipDrain)
+		if err := l.stopJobs(vm, drainOptions, stage); err != nil {
+			return err
+		}
+
+		return l.startJobs(vm, deploymentManifest, stage)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return bosherr.Error("No deployed VM found")
+	}
+
+	return nil
+}
+
+func (l *instanceLifecycle) NewHealthProbe(stage biui.Stage, forceLock bool) (HealthProbe, error) {
+	stateLock, err := l.lockDeploymentState(forceLock)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock(stateLock, l.Logger, l.LogTag)
+
+	deploymentState, err := l.loadDeploymentState()
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := l.TargetProvider.NewTarget()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Determining installation target")
+	}
+
+	targetLock, err := acquireLock(l.Locker, target.LockPath(), "installation", forceLock)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock(targetLock, l.Logger, l.LogTag)
+
+	err = l.TempRootConfigurator.PrepareAndSetTempRoot(target.TmpPath(), l.Logger)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Setting temp root")
+	}
+
+	defer l.deleteExtractedReleases()
+
+	installationManifest, _, err := l.validateManifests(stage)
+	if err != nil {
+		return nil, err
+	}
+
+	installation, err := l.CpiInstaller.InstallCpiRelease(installationManifest, target, stage)
+	if err != nil {
+		return nil, err
+	}
+
+	// the locks are released before the probe is used, the installation is locked again to clean it up
+	cleanup := func() error {
+		targetLock, err := acquireLock(l.Locker, target.LockPath(), "installation", false)
+		if err != nil {
+			return err
+		}
+		defer releaseLock(targetLock, l.Logger, l.LogTag)
+
+		return l.CpiInstaller.CleanupCpiRelease(installation, target, stage)
+	}
+
+	// the registry is not started, checking the VM does not need it
+	cloud, err := l.CloudFactory.NewCloud(installation, deploymentState.DirectorID, deploymentState.CloudProvider)
+	if err != nil {
+		cleanupErr := l.CpiInstaller.CleanupCpiRelease(installation, target, stage)
+		if cleanupErr != nil {
+			l.Logger.Warn(l.LogTag, "Cleaning up the CPI installation: %s", cleanupErr.Error())
+		}
+		return nil, bosherr.WrapError(err, "Creating CPI client from CPI installation")
+	}
+
+	agentClient := l.AgentClientFactory.NewAgentClient(deploymentState.DirectorID, installationManifest.Mbus)
+
+	return NewHealthProbe(
+		l.DeploymentStateService,
+		l.Locker,
+		cloud,
+		agentClient,
+		cleanup,
+		l.Logger,
+	), nil
+}
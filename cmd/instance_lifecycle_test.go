@@ -0,0 +1,384 @@
+package cmd_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	"github.com/golang/mock/gomock"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+
+	fakebivm "github.com/cloudfoundry/bosh-init/deployment/vm/fakes"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("InstanceLifecycle", func() {
+	var (
+		mockCtrl *gomock.Controller
+		fixture  *deploymentContextFixture
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		fixture = newDeploymentContextFixture(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var newInstanceLifecycle = func() bicmd.InstanceLifecycle {
+		return bicmd.NewInstanceLifecycle(fixture.newDeploymentContext("instanceLifecycle"))
+	}
+
+	Describe("Status", func() {
+		BeforeEach(func() {
+			fixture.fakeDeploymentParser.ParseManifest.Jobs = []bideplmanifest.Job{{Name: "fake-job-name"}}
+			fixture.fakeVM.AgentClientReturn = fixture.mockAgentClient
+
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:        "fake-director-id",
+				CurrentVMCID:      "fake-vm-cid",
+				CurrentStemcellID: "fake-stemcell-id",
+				CurrentDiskID:     "fake-disk-id",
+				CurrentReleaseIDs: []string{"fake-release-id"},
+				CurrentNamedDiskIDs: map[string]string{
+					"data": "fake-data-disk-id",
+				},
+				Disks: []biconfig.DiskRecord{
+					{ID: "fake-old-disk-id", CID: "fake-old-disk-cid"},
+					{ID: "fake-disk-id", CID: "fake-disk-cid"},
+					{ID: "fake-data-disk-id", Name: "data", CID: "fake-data-disk-cid"},
+				},
+				Stemcells: []biconfig.StemcellRecord{
+					{ID: "fake-stemcell-id", Name: "fake-stemcell-name", Version: "fake-stemcell-version"},
+				},
+				Releases: []biconfig.ReleaseRecord{
+					{ID: "fake-release-id", Name: "fake-release-name", Version: "fake-release-version"},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reports the state of the VM, its agent and its jobs", func() {
+			fixture.mockAgentClient.EXPECT().Ping().Return("pong", nil)
+			fixture.fakeVM.GetFullStateResult = biagent.AgentState{
+				AgentID:  "fake-agent-id",
+				JobState: "running",
+				NetworkSpecs: map[string]biagentclient.NetworkSpec{
+					"z-network": {IP: "10.0.0.6"},
+					"a-network": {IP: "10.0.0.5"},
+				},
+				Processes: []biagent.ProcessState{
+					{Name: "fake-process", State: "running"},
+				},
+				Vitals: biagent.Vitals{
+					Disk: map[string]biagent.DiskVitals{
+						"persistent": {Percent: "42"},
+					},
+				},
+			}
+
+			status, found, err := newInstanceLifecycle().Status(fixture.fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(status).To(Equal(bicmd.InstanceStatus{
+				Name:            "fake-job-name/0",
+				VMCID:           "fake-vm-cid",
+				VMExists:        true,
+				AgentResponsive: true,
+				AgentID:         "fake-agent-id",
+				IPs:             []string{"10.0.0.5", "10.0.0.6"},
+				JobState:        "running",
+				Processes: []bicmd.ProcessStatus{
+					{Name: "fake-process", State: "running"},
+				},
+				Disks: []bicmd.DiskStatus{
+					{CID: "fake-disk-cid", Usage: "42"},
+					{CID: "fake-data-disk-cid", Name: "data"},
+				},
+				Stemcell: bicmd.ArtifactStatus{Name: "fake-stemcell-name", Version: "fake-stemcell-version"},
+				Releases: []bicmd.ArtifactStatus{
+					{Name: "fake-release-name", Version: "fake-release-version"},
+				},
+			}))
+
+			Expect(fixture.fakeStage.PerformCalls[2:3]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Checking state of VM 'fake-vm-cid'"},
+			}))
+		})
+
+		It("reports an unresponsive agent", func() {
+			fixture.mockAgentClient.EXPECT().Ping().Return("", errors.New("fake-ping-error"))
+
+			status, found, err := newInstanceLifecycle().Status(fixture.fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(status.VMExists).To(BeTrue())
+			Expect(status.AgentResponsive).To(BeFalse())
+			Expect(status.Disks).To(Equal([]bicmd.DiskStatus{
+				{CID: "fake-disk-cid"},
+				{CID: "fake-data-disk-cid", Name: "data"},
+			}))
+			Expect(fixture.fakeVM.GetFullStateCalled).To(Equal(0))
+		})
+
+		It("reports a VM that no longer exists without contacting its agent", func() {
+			fixture.fakeVM.ExistsFound = false
+
+			status, found, err := newInstanceLifecycle().Status(fixture.fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(status.VMExists).To(BeFalse())
+			Expect(status.AgentResponsive).To(BeFalse())
+		})
+
+		It("returns an error when checking the existence of the VM fails", func() {
+			fixture.fakeVM.ExistsErr = errors.New("fake-has-vm-error")
+
+			_, _, err := newInstanceLifecycle().Status(fixture.fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-has-vm-error"))
+		})
+
+		Context("when no VM is deployed", func() {
+			BeforeEach(func() {
+				err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns false", func() {
+				_, found, err := newInstanceLifecycle().Status(fixture.fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Stop", func() {
+		It("waits for the agent and stops the jobs on the deployed VM", func() {
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fixture.fakeVM.WaitUntilReadyInputs).To(Equal([]fakebivm.WaitUntilReadyInput{
+				{Timeout: 10 * time.Second, Delay: 500 * time.Millisecond},
+			}))
+			Expect(fixture.fakeVM.DrainInputs).To(Equal([]time.Duration{1 * time.Minute}))
+			Expect(fixture.fakeVM.StopCalled).To(Equal(1))
+
+			Expect(fixture.fakeStage.PerformCalls[2:5]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+				{Name: "Draining jobs on VM 'fake-vm-cid'"},
+				{Name: "Stopping jobs on VM 'fake-vm-cid'"},
+			}))
+		})
+
+		It("takes over the locks on the deployment state and the installation when forced", func() {
+			fixture.mockLocker.EXPECT().Lock("/deployment-dir/fake-deployment-manifest-state.json.lock", true).Return(fixture.mockLock, nil)
+			fixture.mockLocker.EXPECT().Lock("fake-install-dir/fake-installation-id.lock", true).Return(fixture.mockLock, nil)
+
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fixture.fakeVM.StopCalled).To(Equal(1))
+		})
+
+		It("does not stop the jobs when locking the deployment state fails", func() {
+			fixture.mockLocker.EXPECT().Lock("/deployment-dir/fake-deployment-manifest-state.json.lock", true).Return(nil, errors.New("fake-lock-error"))
+
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Locking the deployment state: fake-lock-error"))
+			Expect(fixture.fakeVM.StopCalled).To(Equal(0))
+		})
+
+		It("stops the jobs without draining them when draining is skipped", func() {
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, true, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fixture.fakeVM.DrainInputs).To(BeEmpty())
+			Expect(fixture.fakeVM.StopCalled).To(Equal(1))
+		})
+
+		It("does not stop the jobs when draining them fails", func() {
+			fixture.fakeVM.DrainErr = errors.New("fake-drain-error")
+
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+			Expect(fixture.fakeVM.StopCalled).To(Equal(0))
+		})
+
+		It("returns an error when stopping the jobs fails", func() {
+			fixture.fakeVM.StopErr = errors.New("fake-stop-error")
+
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-stop-error"))
+		})
+
+		It("returns an error when the VM no longer exists", func() {
+			fixture.fakeVM.ExistsFound = false
+
+			err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployed VM 'fake-vm-cid' no longer exists"))
+		})
+
+		Context("when --hard is given", func() {
+			It("deletes the VM, keeping its disks", func() {
+				drainOptions := biinstance.DrainOptions{MaxWait: 1 * time.Minute}
+				fixture.mockInstanceManager.EXPECT().DeleteAll(10*time.Second, 500*time.Millisecond, drainOptions, fixture.fakeStage).Return(nil)
+
+				err := newInstanceLifecycle().Stop(fixture.fakeStage, true, false, false)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("deletes the VM without draining the jobs when draining is skipped", func() {
+				drainOptions := biinstance.DrainOptions{Skip: true, MaxWait: 1 * time.Minute}
+				fixture.mockInstanceManager.EXPECT().DeleteAll(gomock.Any(), gomock.Any(), drainOptions, fixture.fakeStage).Return(nil)
+
+				err := newInstanceLifecycle().Stop(fixture.fakeStage, true, true, false)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns an error when deleting the VM fails", func() {
+				fixture.mockInstanceManager.EXPECT().DeleteAll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("fake-delete-error"))
+
+				err := newInstanceLifecycle().Stop(fixture.fakeStage, true, false, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-delete-error"))
+			})
+		})
+
+		Context("when no VM is deployed", func() {
+			BeforeEach(func() {
+				err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("does nothing", func() {
+				err := newInstanceLifecycle().Stop(fixture.fakeStage, true, false, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fixture.fakeUI.Said).To(ContainElement("No deployed VM found"))
+				Expect(fixture.fakeStage.PerformCalls).To(BeEmpty())
+			})
+		})
+
+		Context("when the deployment state does not exist", func() {
+			BeforeEach(func() {
+				err := fixture.fs.RemoveAll(biconfig.DeploymentStatePath(fixture.deploymentManifestPath))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns an error", func() {
+				err := newInstanceLifecycle().Stop(fixture.fakeStage, false, false, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("No deployment state found at '/deployment-dir/fake-deployment-manifest-state.json'"))
+			})
+		})
+	})
+
+	Describe("Start", func() {
+		It("starts the jobs on the deployed VM and waits for them to be running", func() {
+			started, err := newInstanceLifecycle().Start(fixture.fakeStage, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(started).To(BeTrue())
+
+			Expect(fixture.fakeVM.StartCalled).To(Equal(1))
+			Expect(fixture.fakeVM.WaitToBeRunningInputs).To(Equal([]fakebivm.WaitInput{
+				{MaxAttempts: 5, Delay: 1 * time.Second},
+			}))
+
+			Expect(fixture.fakeStage.PerformCalls[2:4]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Starting jobs on VM 'fake-vm-cid'"},
+				{Name: "Waiting for jobs on VM 'fake-vm-cid' to be running"},
+			}))
+		})
+
+		It("returns an error when starting the jobs fails", func() {
+			fixture.fakeVM.StartErr = errors.New("fake-start-error")
+
+			_, err := newInstanceLifecycle().Start(fixture.fakeStage, false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-start-error"))
+		})
+
+		It("returns false when the VM no longer exists", func() {
+			fixture.fakeVM.ExistsFound = false
+
+			started, err := newInstanceLifecycle().Start(fixture.fakeStage, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(started).To(BeFalse())
+			Expect(fixture.fakeVM.StartCalled).To(Equal(0))
+		})
+
+		Context("when no VM is deployed", func() {
+			BeforeEach(func() {
+				err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns false", func() {
+				started, err := newInstanceLifecycle().Start(fixture.fakeStage, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(started).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Restart", func() {
+		It("stops and starts the jobs on the deployed VM", func() {
+			err := newInstanceLifecycle().Restart(fixture.fakeStage, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fixture.fakeVM.StopCalled).To(Equal(1))
+			Expect(fixture.fakeVM.StartCalled).To(Equal(1))
+
+			Expect(fixture.fakeStage.PerformCalls[2:7]).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+				{Name: "Draining jobs on VM 'fake-vm-cid'"},
+				{Name: "Stopping jobs on VM 'fake-vm-cid'"},
+				{Name: "Starting jobs on VM 'fake-vm-cid'"},
+				{Name: "Waiting for jobs on VM 'fake-vm-cid' to be running"},
+			}))
+		})
+
+		It("restarts the jobs without draining them when draining is skipped", func() {
+			err := newInstanceLifecycle().Restart(fixture.fakeStage, true, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fixture.fakeVM.DrainInputs).To(BeEmpty())
+			Expect(fixture.fakeVM.StartCalled).To(Equal(1))
+		})
+
+		It("does not start the jobs when stopping them fails", func() {
+			fixture.fakeVM.StopErr = errors.New("fake-stop-error")
+
+			err := newInstanceLifecycle().Restart(fixture.fakeStage, false, false)
+			Expect(err).To(HaveOccurred())
+			Expect(fixture.fakeVM.StartCalled).To(Equal(0))
+		})
+
+		Context("when no VM is deployed", func() {
+			BeforeEach(func() {
+				err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns an error", func() {
+				err := newInstanceLifecycle().Restart(fixture.fakeStage, false, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("No deployed VM found"))
+			})
+		})
+	})
+})
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+type instancesCmd struct {
+	instanceLifecycleProvider func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error)
+	ui                        biui.UI
+	errUI                     biui.UI
+	fs                        boshsys.FileSystem
+	timeService               clock.Clock
+	logger                    boshlog.Logger
+	logTag                    string
+}
+
+type instancesOutput struct {
+	Instances []InstanceStatus `json:"instances"`
+}
+
+// NewInstancesCmd creates the command that prints the state of the deployed instance.
+// With --json all progress output is written to errUI, so that ui only receives the JSON document.
+func NewInstancesCmd(
+	ui biui.UI,
+	errUI biui.UI,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error),
+) Cmd {
+	return &instancesCmd{
+		ui:                        ui,
+		errUI:                     errUI,
+		fs:                        fs,
+		timeService:               timeService,
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		logger:                    logger,
+		logTag:                    "instancesCmd",
+	}
+}
+
+func (c *instancesCmd) Name() string {
+	return "instances"
+}
+
+func (c *instancesCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Show the VM, agent, job and process state of the deployed instance",
+		Usage:    "<deployment_manifest_path> [--json]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *instancesCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, jsonOutput, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	progressUI := c.ui
+	if jsonOutput {
+		progressUI = c.errUI
+		stage = biui.NewStage(c.errUI, c.timeService, c.logger)
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(progressUI, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath, progressUI)
+	if err != nil {
+		return err
+	}
+
+	status, found, err := instanceLifecycle.Status(stage)
+	if err != nil {
+		return err
+	}
+
+	output := instancesOutput{Instances: []InstanceStatus{}}
+	if found {
+		output.Instances = append(output.Instances, status)
+	}
+
+	if jsonOutput {
+		bytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return bosherr.WrapError(err, "Marshalling instances")
+		}
+		c.ui.PrintLinef("%s", string(bytes))
+		return nil
+	}
+
+	c.ui.PrintLinef("")
+	if !found {
+		c.ui.PrintLinef("No deployed VM found")
+		return nil
+	}
+
+	c.printStatus(status)
+	return nil
+}
+
+func (c *instancesCmd) printStatus(status InstanceStatus) {
+	c.ui.PrintLinef("Instance '%s'", status.Name)
+	c.ui.PrintLinef("  VM CID:    %s", status.VMCID)
+
+	switch {
+	case !status.VMExists:
+		c.ui.PrintLinef("  VM state:  missing")
+	case !status.AgentResponsive:
+		c.ui.PrintLinef("  VM state:  unresponsive agent")
+	default:
+		c.ui.PrintLinef("  VM state:  %s", status.JobState)
+		c.ui.PrintLinef("  Agent ID:  %s", status.AgentID)
+		c.ui.PrintLinef("  IPs:       %s", strings.Join(status.IPs, ", "))
+	}
+
+	c.ui.PrintLinef("  Stemcell:  %s/%s", status.Stemcell.Name, status.Stemcell.Version)
+
+	releases := []string{}
+	for _, release := range status.Releases {
+		releases = append(releases, release.Name+"/"+release.Version)
+	}
+	c.ui.PrintLinef("  Releases:  %s", strings.Join(releases, ", "))
+
+	for _, disk := range status.Disks {
+		usage := "unknown"
+		if disk.Usage != "" {
+			usage = disk.Usage + "%"
+		}
+		if disk.Name != "" {
+			c.ui.PrintLinef("  Disk:      %s (name: %s, usage: %s)", disk.CID, disk.Name, usage)
+		} else {
+			c.ui.PrintLinef("  Disk:      %s (usage: %s)", disk.CID, usage)
+		}
+	}
+
+	if len(status.Processes) > 0 {
+		c.ui.PrintLinef("  Processes:")
+		for _, process := range status.Processes {
+			c.ui.PrintLinef("    %-30s %s", process.Name, process.State)
+		}
+	}
+}
+
+func (c *instancesCmd) parseCmdInputs(args []string) (string, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "instances",
+		positional: 1,
+		flags:      []string{"--json"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", false, err
+	}
+	return parsed.positional[0], parsed.Flag("--json"), nil
+}
@@ -0,0 +1,171 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"time"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("InstancesCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+			fs                    *fakesys.FakeFileSystem
+			logger                boshlog.Logger
+			fakeUI                *fakebiui.FakeUI
+			fakeErrUI             *fakebiui.FakeUI
+			fakeStage             *fakebiui.FakeStage
+			providedUI            biui.UI
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+
+			status = bicmd.InstanceStatus{
+				Name:            "fake-job-name/0",
+				VMCID:           "fake-vm-cid",
+				VMExists:        true,
+				AgentResponsive: true,
+				AgentID:         "fake-agent-id",
+				IPs:             []string{"10.0.0.5"},
+				JobState:        "running",
+				Processes: []bicmd.ProcessStatus{
+					{Name: "fake-process", State: "running"},
+				},
+				Disks: []bicmd.DiskStatus{
+					{CID: "fake-disk-cid", Usage: "42"},
+				},
+				Stemcell: bicmd.ArtifactStatus{Name: "fake-stemcell-name", Version: "fake-stemcell-version"},
+				Releases: []bicmd.ArtifactStatus{
+					{Name: "fake-release-name", Version: "fake-release-version"},
+				},
+			}
+		)
+
+		var newInstancesCmd = func() bicmd.Cmd {
+			provider := func(path string, ui biui.UI) (bicmd.InstanceLifecycle, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				providedUI = ui
+				return mockInstanceLifecycle, nil
+			}
+
+			timeService := fakeclock.NewFakeClock(time.Now())
+			return bicmd.NewInstancesCmd(fakeUI, fakeErrUI, fs, timeService, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeErrUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("prints the state of the deployed instance", func() {
+			mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(status, true, nil)
+
+			err := newInstancesCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(providedUI == fakeUI).To(BeTrue())
+			Expect(fakeUI.Said).To(ContainElement("Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'"))
+			Expect(fakeUI.Said).To(ContainElement("Instance 'fake-job-name/0'"))
+			Expect(fakeUI.Said).To(ContainElement("  VM state:  running"))
+			Expect(fakeUI.Said).To(ContainElement("  IPs:       10.0.0.5"))
+			Expect(fakeUI.Said).To(ContainElement("  Disk:      fake-disk-cid (usage: 42%)"))
+			Expect(fakeUI.Said).To(ContainElement("  Stemcell:  fake-stemcell-name/fake-stemcell-version"))
+			Expect(fakeUI.Said).To(ContainElement("  Releases:  fake-release-name/fake-release-version"))
+		})
+
+		It("prints an unresponsive agent", func() {
+			status := bicmd.InstanceStatus{Name: "fake-job-name/0", VMCID: "fake-vm-cid", VMExists: true}
+			mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(status, true, nil)
+
+			err := newInstancesCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("  VM state:  unresponsive agent"))
+		})
+
+		It("prints that there is no deployed VM", func() {
+			mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(bicmd.InstanceStatus{}, false, nil)
+
+			err := newInstancesCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("No deployed VM found"))
+		})
+
+		Context("when --json is given", func() {
+			It("prints only the JSON document to the ui, and progress to the error ui", func() {
+				mockInstanceLifecycle.EXPECT().Status(gomock.Not(fakeStage)).Return(status, true, nil)
+
+				err := newInstancesCmd().Run(fakeStage, []string{deploymentManifestPath, "--json"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(providedUI == fakeErrUI).To(BeTrue())
+				Expect(fakeErrUI.Said).To(ContainElement("Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'"))
+				Expect(fakeUI.Said).To(HaveLen(1))
+
+				var output map[string][]bicmd.InstanceStatus
+				err = json.Unmarshal([]byte(fakeUI.Said[0]), &output)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(output).To(Equal(map[string][]bicmd.InstanceStatus{"instances": {status}}))
+			})
+
+			It("prints an empty list when there is no deployed VM", func() {
+				mockInstanceLifecycle.EXPECT().Status(gomock.Any()).Return(bicmd.InstanceStatus{}, false, nil)
+
+				err := newInstancesCmd().Run(fakeStage, []string{deploymentManifestPath, "--json"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUI.Said).To(Equal([]string{"{\n  \"instances\": []\n}"}))
+			})
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(bicmd.InstanceStatus{}, false, bosherr.Error("fake-status-error"))
+
+			err := newInstancesCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-status-error"))
+		})
+
+		It("returns an error when the deployment manifest does not exist", func() {
+			err := newInstancesCmd().Run(fakeStage, []string{"/garbage"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment manifest does not exist at '/garbage'"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newInstancesCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+
+			err = newInstancesCmd().Run(fakeStage, []string{"1", "2"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
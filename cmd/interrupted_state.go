@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// printInterruptedState lists the resources recorded in the deployment state after an interrupt,
+// and how to resume, so that nothing is left behind unnoticed
+func printInterruptedState(
+	ui biui.UI,
+	deploymentStateService biconfig.DeploymentStateService,
+	resumeHint string,
+	logger boshlog.Logger,
+	logTag string,
+) {
+	ui.ErrorLinef("")
+
+	if !deploymentStateService.Exists() {
+		ui.ErrorLinef("Interrupted, no resources are recorded in the deployment state.")
+		ui.ErrorLinef("%s", resumeHint)
+		return
+	}
+
+	deploymentState, err := deploymentStateService.Load()
+	if err != nil {
+		logger.Warn(logTag, "Loading deployment state after interrupt: %s", err.Error())
+		ui.ErrorLinef("Interrupted, the deployment state '%s' could not be loaded.", deploymentStateService.Path())
+		ui.ErrorLinef("%s", resumeHint)
+		return
+	}
+
+	move := deploymentState.CloudMigrationTarget
+	if deploymentState.CurrentVMCID == "" && len(deploymentState.Disks) == 0 && len(deploymentState.Stemcells) == 0 && move == nil {
+		ui.ErrorLinef("Interrupted, no resources are recorded in the deployment state.")
+		ui.ErrorLinef("%s", resumeHint)
+		return
+	}
+
+	ui.ErrorLinef("Interrupted, the deployment state records these resources:")
+	if deploymentState.CurrentVMCID != "" {
+		ui.ErrorLinef("  VM '%s'", deploymentState.CurrentVMCID)
+	}
+	for _, diskRecord := range deploymentState.Disks {
+		if diskRecord.Name != "" {
+			ui.ErrorLinef("  Disk '%s' (name: %s)", diskRecord.CID, diskRecord.Name)
+		} else {
+			ui.ErrorLinef("  Disk '%s'", diskRecord.CID)
+		}
+	}
+	for _, stemcellRecord := range deploymentState.Stemcells {
+		ui.ErrorLinef("  Stemcell '%s' (%s/%s)", stemcellRecord.CID, stemcellRecord.Name, stemcellRecord.Version)
+	}
+	if move != nil {
+		// the resources of an unfinished move to another cloud provider are recorded apart from the current ones
+		ui.ErrorLinef("and these resources of %s, which the deployment is moving to:", describeCloudProvider(move.CloudProvider))
+		if move.CurrentVMCID != "" {
+			ui.ErrorLinef("  VM '%s'", move.CurrentVMCID)
+		}
+		for _, diskRecord := range move.Disks {
+			if diskRecord.Name != "" {
+				ui.ErrorLinef("  Disk '%s' (name: %s)", diskRecord.CID, diskRecord.Name)
+			} else {
+				ui.ErrorLinef("  Disk '%s'", diskRecord.CID)
+			}
+		}
+		for _, stemcellRecord := range move.Stemcells {
+			ui.ErrorLinef("  Stemcell '%s' (%s/%s)", stemcellRecord.CID, stemcellRecord.Name, stemcellRecord.Version)
+		}
+	}
+	ui.ErrorLinef("%s", resumeHint)
+}
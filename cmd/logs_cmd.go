@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
+	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bilogs "github.com/cloudfoundry/bosh-init/deployment/logs"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type logsCmd struct {
+	ui                                      biui.UI
+	fs                                      boshsys.FileSystem
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentStateServiceProvider          func(deploymentManifestPath string) biconfig.DeploymentStateService
+	agentClientFactory                      bihttpagent.AgentClientFactory
+	blobstoreFactory                        biblobstore.Factory
+	logsFetcher                             bilogs.Fetcher
+	logger                                  boshlog.Logger
+	logTag                                  string
+}
+
+type logsCmdInputs struct {
+	deploymentManifestPath string
+	jobName                string
+	destinationDir         string
+}
+
+func NewLogsCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService,
+	agentClientFactory bihttpagent.AgentClientFactory,
+	blobstoreFactory biblobstore.Factory,
+	logsFetcher bilogs.Fetcher,
+	logger boshlog.Logger,
+) Cmd {
+	return &logsCmd{
+		ui:                                      ui,
+		fs:                                      fs,
+		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		deploymentStateServiceProvider:          deploymentStateServiceProvider,
+		agentClientFactory:                      agentClientFactory,
+		blobstoreFactory:                        blobstoreFactory,
+		logsFetcher:                             logsFetcher,
+		logger:                                  logger,
+		logTag:                                  "logsCmd",
+	}
+}
+
+func (c *logsCmd) Name() string {
+	return "logs"
+}
+
+func (c *logsCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Fetch job logs from the deployed VM",
+		Usage:    "<deployment_manifest_path> [--job <name>] [--dir <destination_dir>]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *logsCmd) Run(stage biui.Stage, args []string) error {
+	inputs, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, inputs.deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	destinationDir, err := filepath.Abs(inputs.destinationDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Getting absolute path to logs directory '%s'", inputs.destinationDir)
+	}
+
+	deploymentStateService := c.deploymentStateServiceProvider(manifestAbsFilePath)
+	if !deploymentStateService.Exists() {
+		return bosherr.Errorf("No deployment state found at '%s'", deploymentStateService.Path())
+	}
+
+	deploymentState, err := deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	if deploymentState.CurrentVMCID == "" {
+		return bosherr.Error("No deployed VM found")
+	}
+
+	_, installationManifest, err := c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	agentClient := c.agentClientFactory.NewAgentClient(deploymentState.DirectorID, installationManifest.Mbus)
+
+	blobstore, err := c.blobstoreFactory.Create(installationManifest.Mbus, bihttpclient.CreateDefaultClientInsecureSkipVerify())
+	if err != nil {
+		return bosherr.WrapError(err, "Creating blobstore client")
+	}
+
+	var logsDir string
+	err = stage.Perform("Fetching logs", func() error {
+		logsDir, err = c.logsFetcher.Fetch(agentClient, blobstore, inputs.jobName, destinationDir)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("Logs saved to '%s'", logsDir)
+
+	return nil
+}
+
+func (c *logsCmd) parseCmdInputs(args []string) (logsCmdInputs, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "logs",
+		positional: 1,
+		options:    []string{"--job", "--dir"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return logsCmdInputs{}, err
+	}
+
+	inputs := logsCmdInputs{
+		deploymentManifestPath: parsed.positional[0],
+		jobName:                parsed.Option("--job"),
+		destinationDir:         parsed.Option("--dir"),
+	}
+	if inputs.destinationDir == "" {
+		inputs.destinationDir = "."
+	}
+
+	return inputs, nil
+}
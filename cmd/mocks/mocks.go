@@ -1,13 +1,79 @@
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/cloudfoundry/bosh-init/cmd (interfaces: DeploymentDeleter)
+// Source: github.com/cloudfoundry/bosh-init/cmd (interfaces: DeploymentAdopter,DeploymentCleaner,DeploymentDeleter,DeploymentSnapshotter,HealthProbe,InstanceLifecycle,StemcellUploader)
 
 package mocks
 
 import (
+	cmd "github.com/cloudfoundry/bosh-init/cmd"
+	config "github.com/cloudfoundry/bosh-init/config"
+	health "github.com/cloudfoundry/bosh-init/deployment/health"
+	stemcell "github.com/cloudfoundry/bosh-init/stemcell"
 	ui "github.com/cloudfoundry/bosh-init/ui"
 	gomock "github.com/golang/mock/gomock"
 )
 
+// Mock of DeploymentAdopter interface
+type MockDeploymentAdopter struct {
+	ctrl     *gomock.Controller
+	recorder *_MockDeploymentAdopterRecorder
+}
+
+// Recorder for MockDeploymentAdopter (not exported)
+type _MockDeploymentAdopterRecorder struct {
+	mock *MockDeploymentAdopter
+}
+
+func NewMockDeploymentAdopter(ctrl *gomock.Controller) *MockDeploymentAdopter {
+	mock := &MockDeploymentAdopter{ctrl: ctrl}
+	mock.recorder = &_MockDeploymentAdopterRecorder{mock}
+	return mock
+}
+
+func (_m *MockDeploymentAdopter) EXPECT() *_MockDeploymentAdopterRecorder {
+	return _m.recorder
+}
+
+func (_m *MockDeploymentAdopter) Adopt(_param0 ui.Stage, _param1 string, _param2 string, _param3 string, _param4 bool) error {
+	ret := _m.ctrl.Call(_m, "Adopt", _param0, _param1, _param2, _param3, _param4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentAdopterRecorder) Adopt(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Adopt", arg0, arg1, arg2, arg3, arg4)
+}
+
+// Mock of DeploymentCleaner interface
+type MockDeploymentCleaner struct {
+	ctrl     *gomock.Controller
+	recorder *_MockDeploymentCleanerRecorder
+}
+
+// Recorder for MockDeploymentCleaner (not exported)
+type _MockDeploymentCleanerRecorder struct {
+	mock *MockDeploymentCleaner
+}
+
+func NewMockDeploymentCleaner(ctrl *gomock.Controller) *MockDeploymentCleaner {
+	mock := &MockDeploymentCleaner{ctrl: ctrl}
+	mock.recorder = &_MockDeploymentCleanerRecorder{mock}
+	return mock
+}
+
+func (_m *MockDeploymentCleaner) EXPECT() *_MockDeploymentCleanerRecorder {
+	return _m.recorder
+}
+
+func (_m *MockDeploymentCleaner) CleanUp(_param0 ui.Stage, _param1 bool, _param2 bool, _param3 bool) error {
+	ret := _m.ctrl.Call(_m, "CleanUp", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentCleanerRecorder) CleanUp(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CleanUp", arg0, arg1, arg2, arg3)
+}
+
 // Mock of DeploymentDeleter interface
 type MockDeploymentDeleter struct {
 	ctrl     *gomock.Controller
@@ -29,12 +95,214 @@ func (_m *MockDeploymentDeleter) EXPECT() *_MockDeploymentDeleterRecorder {
 	return _m.recorder
 }
 
-func (_m *MockDeploymentDeleter) DeleteDeployment(_param0 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "DeleteDeployment", _param0)
+func (_m *MockDeploymentDeleter) DeleteDeployment(_param0 ui.Stage, _param1 bool, _param2 bool) error {
+	ret := _m.ctrl.Call(_m, "DeleteDeployment", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentDeleterRecorder) DeleteDeployment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteDeployment", arg0, arg1, arg2)
+}
+
+// Mock of DeploymentSnapshotter interface
+type MockDeploymentSnapshotter struct {
+	ctrl     *gomock.Controller
+	recorder *_MockDeploymentSnapshotterRecorder
+}
+
+// Recorder for MockDeploymentSnapshotter (not exported)
+type _MockDeploymentSnapshotterRecorder struct {
+	mock *MockDeploymentSnapshotter
+}
+
+func NewMockDeploymentSnapshotter(ctrl *gomock.Controller) *MockDeploymentSnapshotter {
+	mock := &MockDeploymentSnapshotter{ctrl: ctrl}
+	mock.recorder = &_MockDeploymentSnapshotterRecorder{mock}
+	return mock
+}
+
+func (_m *MockDeploymentSnapshotter) EXPECT() *_MockDeploymentSnapshotterRecorder {
+	return _m.recorder
+}
+
+func (_m *MockDeploymentSnapshotter) DeleteSnapshot(_param0 ui.Stage, _param1 string, _param2 bool) error {
+	ret := _m.ctrl.Call(_m, "DeleteSnapshot", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentSnapshotterRecorder) DeleteSnapshot(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteSnapshot", arg0, arg1, arg2)
+}
+
+func (_m *MockDeploymentSnapshotter) RestoreSnapshot(_param0 ui.Stage, _param1 string, _param2 bool, _param3 bool) error {
+	ret := _m.ctrl.Call(_m, "RestoreSnapshot", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentSnapshotterRecorder) RestoreSnapshot(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RestoreSnapshot", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockDeploymentSnapshotter) TakeSnapshot(_param0 ui.Stage, _param1 string, _param2 bool) (config.SnapshotRecord, bool, error) {
+	ret := _m.ctrl.Call(_m, "TakeSnapshot", _param0, _param1, _param2)
+	ret0, _ := ret[0].(config.SnapshotRecord)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockDeploymentSnapshotterRecorder) TakeSnapshot(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "TakeSnapshot", arg0, arg1, arg2)
+}
+
+// Mock of HealthProbe interface
+type MockHealthProbe struct {
+	ctrl     *gomock.Controller
+	recorder *_MockHealthProbeRecorder
+}
+
+// Recorder for MockHealthProbe (not exported)
+type _MockHealthProbeRecorder struct {
+	mock *MockHealthProbe
+}
+
+func NewMockHealthProbe(ctrl *gomock.Controller) *MockHealthProbe {
+	mock := &MockHealthProbe{ctrl: ctrl}
+	mock.recorder = &_MockHealthProbeRecorder{mock}
+	return mock
+}
+
+func (_m *MockHealthProbe) EXPECT() *_MockHealthProbeRecorder {
+	return _m.recorder
+}
+
+func (_m *MockHealthProbe) Check() (health.State, error) {
+	ret := _m.ctrl.Call(_m, "Check")
+	ret0, _ := ret[0].(health.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockHealthProbeRecorder) Check() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Check")
+}
+
+func (_m *MockHealthProbe) Close() error {
+	ret := _m.ctrl.Call(_m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockHealthProbeRecorder) Close() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Close")
+}
+
+// Mock of InstanceLifecycle interface
+type MockInstanceLifecycle struct {
+	ctrl     *gomock.Controller
+	recorder *_MockInstanceLifecycleRecorder
+}
+
+// Recorder for MockInstanceLifecycle (not exported)
+type _MockInstanceLifecycleRecorder struct {
+	mock *MockInstanceLifecycle
+}
+
+func NewMockInstanceLifecycle(ctrl *gomock.Controller) *MockInstanceLifecycle {
+	mock := &MockInstanceLifecycle{ctrl: ctrl}
+	mock.recorder = &_MockInstanceLifecycleRecorder{mock}
+	return mock
+}
+
+func (_m *MockInstanceLifecycle) EXPECT() *_MockInstanceLifecycleRecorder {
+	return _m.recorder
+}
+
+func (_m *MockInstanceLifecycle) NewHealthProbe(_param0 ui.Stage, _param1 bool) (cmd.HealthProbe, error) {
+	ret := _m.ctrl.Call(_m, "NewHealthProbe", _param0, _param1)
+	ret0, _ := ret[0].(cmd.HealthProbe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) NewHealthProbe(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "NewHealthProbe", arg0, arg1)
+}
+
+func (_m *MockInstanceLifecycle) Restart(_param0 ui.Stage, _param1 bool, _param2 bool) error {
+	ret := _m.ctrl.Call(_m, "Restart", _param0, _param1, _param2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockDeploymentDeleterRecorder) DeleteDeployment(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteDeployment", arg0)
+func (_mr *_MockInstanceLifecycleRecorder) Restart(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Restart", arg0, arg1, arg2)
+}
+
+func (_m *MockInstanceLifecycle) Start(_param0 ui.Stage, _param1 bool) (bool, error) {
+	ret := _m.ctrl.Call(_m, "Start", _param0, _param1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Start(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Start", arg0, arg1)
+}
+
+func (_m *MockInstanceLifecycle) Status(_param0 ui.Stage) (cmd.InstanceStatus, bool, error) {
+	ret := _m.ctrl.Call(_m, "Status", _param0)
+	ret0, _ := ret[0].(cmd.InstanceStatus)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Status(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Status", arg0)
+}
+
+func (_m *MockInstanceLifecycle) Stop(_param0 ui.Stage, _param1 bool, _param2 bool, _param3 bool) error {
+	ret := _m.ctrl.Call(_m, "Stop", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Stop(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Stop", arg0, arg1, arg2, arg3)
+}
+
+// Mock of StemcellUploader interface
+type MockStemcellUploader struct {
+	ctrl     *gomock.Controller
+	recorder *_MockStemcellUploaderRecorder
+}
+
+// Recorder for MockStemcellUploader (not exported)
+type _MockStemcellUploaderRecorder struct {
+	mock *MockStemcellUploader
+}
+
+func NewMockStemcellUploader(ctrl *gomock.Controller) *MockStemcellUploader {
+	mock := &MockStemcellUploader{ctrl: ctrl}
+	mock.recorder = &_MockStemcellUploaderRecorder{mock}
+	return mock
+}
+
+func (_m *MockStemcellUploader) EXPECT() *_MockStemcellUploaderRecorder {
+	return _m.recorder
+}
+
+func (_m *MockStemcellUploader) UploadStemcell(_param0 ui.Stage, _param1 bool) (stemcell.CloudStemcell, error) {
+	ret := _m.ctrl.Call(_m, "UploadStemcell", _param0, _param1)
+	ret0, _ := ret[0].(stemcell.CloudStemcell)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockStemcellUploaderRecorder) UploadStemcell(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UploadStemcell", arg0, arg1)
 }
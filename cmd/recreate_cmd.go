@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type recreateCmd struct {
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error)
+	ui                         biui.UI
+	fs                         boshsys.FileSystem
+	logger                     boshlog.Logger
+	logTag                     string
+}
+
+func NewRecreateCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error),
+) Cmd {
+	return &recreateCmd{
+		ui:                         ui,
+		fs:                         fs,
+		deploymentPreparerProvider: deploymentPreparerProvider,
+		logger:                     logger,
+		logTag:                     "recreateCmd",
+	}
+}
+
+func (c *recreateCmd) Name() string {
+	return "recreate"
+}
+
+func (c *recreateCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Delete and recreate the VM of the deployed instance, keeping its persistent disks",
+		Usage:    "<deployment_manifest_path> [--skip-drain] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *recreateCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, deployOptions, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentPreparer, err := c.deploymentPreparerProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return deploymentPreparer.PrepareDeployment(stage, deployOptions)
+}
+
+func (c *recreateCmd) parseCmdInputs(args []string) (string, DeployOptions, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "recreate",
+		positional: 1,
+		flags:      []string{"--skip-drain", "--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", DeployOptions{}, err
+	}
+
+	deployOptions := DeployOptions{
+		Recreate:  true,
+		SkipDrain: parsed.Flag("--skip-drain"),
+		ForceLock: parsed.Flag("--force-lock"),
+	}
+	return parsed.positional[0], deployOptions, nil
+}
@@ -0,0 +1,69 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("RecreateCmd", func() {
+	Describe("Run", func() {
+		var (
+			fs        *fakesys.FakeFileSystem
+			logger    boshlog.Logger
+			fakeUI    *fakebiui.FakeUI
+			fakeStage *fakebiui.FakeStage
+
+			deploymentPreparerPaths []string
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newRecreateCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.DeploymentPreparer, error) {
+				deploymentPreparerPaths = append(deploymentPreparerPaths, path)
+				return bicmd.DeploymentPreparer{}, bosherr.Error("fake-preparer-error")
+			}
+
+			return bicmd.NewRecreateCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+			deploymentPreparerPaths = []string{}
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("prepares a deployment of the given manifest", func() {
+			err := newRecreateCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-preparer-error"))
+
+			Expect(deploymentPreparerPaths).To(Equal([]string{deploymentManifestPath}))
+			Expect(fakeUI.Said).To(ContainElement("Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'"))
+		})
+
+		It("returns an error when the deployment manifest does not exist", func() {
+			err := newRecreateCmd().Run(fakeStage, []string{"/garbage"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment manifest does not exist at '/garbage'"))
+			Expect(deploymentPreparerPaths).To(BeEmpty())
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newRecreateCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
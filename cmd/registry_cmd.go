@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type registryCmd struct {
+	ui                                      biui.UI
+	fs                                      boshsys.FileSystem
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	registryServerManager                   biregistry.ServerManager
+	sshTunnelFactory                        bisshtunnel.Factory
+	encryptor                               bicrypto.Encryptor
+	logger                                  boshlog.Logger
+	logTag                                  string
+}
+
+func NewRegistryCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	registryServerManager biregistry.ServerManager,
+	sshTunnelFactory bisshtunnel.Factory,
+	encryptor bicrypto.Encryptor,
+	logger boshlog.Logger,
+) Cmd {
+	return &registryCmd{
+		ui:                                      ui,
+		fs:                                      fs,
+		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		registryServerManager:                   registryServerManager,
+		sshTunnelFactory:                        sshTunnelFactory,
+		encryptor:                               encryptor,
+		logger:                                  logger,
+		logTag:                                  "registryCmd",
+	}
+}
+
+func (c *registryCmd) Name() string {
+	return "registry"
+}
+
+func (c *registryCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Serve or show the persisted registry of a deployment",
+		Usage:    "<serve|show> <deployment_manifest_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *registryCmd) Run(stage biui.Stage, args []string) error {
+	subcommand, deploymentManifestPath, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	if subcommand == "show" {
+		return c.show(manifestAbsFilePath)
+	}
+
+	return c.serve(manifestAbsFilePath, stage)
+}
+
+func (c *registryCmd) show(deploymentManifestPath string) error {
+	storePath := biconfig.RegistryStorePath(deploymentManifestPath)
+	c.ui.PrintLinef("Registry store: '%s'", storePath)
+
+	if !c.fs.FileExists(storePath) {
+		c.ui.PrintLinef("No registry contents found.")
+		return nil
+	}
+
+	registry := biregistry.NewEncryptedFileRegistry(storePath, c.fs, c.logger, c.encryptor)
+	instanceIDs, err := registry.Keys()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing registry instances")
+	}
+
+	for _, instanceID := range instanceIDs {
+		settings, _, err := registry.Get(instanceID)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Getting settings for instance '%s'", instanceID)
+		}
+
+		c.ui.PrintLinef("")
+		c.ui.PrintLinef("Instance '%s':", instanceID)
+		c.ui.PrintLinef("%s", c.indentJSON(settings))
+	}
+
+	return nil
+}
+
+func (c *registryCmd) serve(deploymentManifestPath string, stage biui.Stage) error {
+	var installationManifest biinstallmanifest.Manifest
+	err := stage.Perform("Validating installation manifest", func() error {
+		var err error
+		_, installationManifest, err = c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(deploymentManifestPath)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	registryConfig := installationManifest.Registry
+	if registryConfig.IsEmpty() {
+		return bosherr.Error("Registry is not used by this deployment, cloud_provider.ssh_tunnel must be provided")
+	}
+
+	if registryConfig.StorePath == "" {
+		return bosherr.Error("Registry contents are not persisted, cloud_provider.registry.persistent must be enabled")
+	}
+
+	if !c.fs.FileExists(registryConfig.StorePath) {
+		c.ui.ErrorLinef("Registry store '%s' does not exist, agents will not find their settings", registryConfig.StorePath)
+	}
+
+	var registryServer biregistry.Server
+	err = stage.Perform("Starting registry", func() error {
+		var err error
+		registryServer, err = c.registryServerManager.Start(registryConfig.ServerConfig())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := registryServer.Stop(); err != nil {
+			c.logger.Warn(c.logTag, "Failed to stop registry: %s", err.Error())
+		}
+	}()
+
+	sshTunnelOptions := bisshtunnel.Options{
+		Host:           registryConfig.SSHTunnel.Host,
+		Port:           registryConfig.SSHTunnel.Port,
+		User:           registryConfig.SSHTunnel.User,
+		Password:       registryConfig.SSHTunnel.Password,
+		PrivateKey:     registryConfig.SSHTunnel.PrivateKey,
+		Passphrase:     registryConfig.SSHTunnel.Passphrase,
+		UseAgent:       registryConfig.SSHTunnel.UseAgent,
+		KnownHostsFile: registryConfig.SSHTunnel.KnownHosts,
+		JumpHost: bisshtunnel.JumpHostOptions{
+			Host:       registryConfig.SSHTunnel.JumpHost.Host,
+			Port:       registryConfig.SSHTunnel.JumpHost.Port,
+			User:       registryConfig.SSHTunnel.JumpHost.User,
+			Password:   registryConfig.SSHTunnel.JumpHost.Password,
+			PrivateKey: registryConfig.SSHTunnel.JumpHost.PrivateKey,
+			Passphrase: registryConfig.SSHTunnel.JumpHost.Passphrase,
+		},
+		LocalForwardPort:  registryConfig.Port,
+		RemoteForwardPort: registryConfig.Port,
+	}
+	sshTunnel := c.sshTunnelFactory.NewSSHTunnel(sshTunnelOptions)
+	err = stage.Perform("Starting SSH tunnel", func() error {
+		sshReadyErrCh := make(chan error)
+		sshErrCh := make(chan error)
+		go sshTunnel.Start(sshReadyErrCh, sshErrCh)
+		go func() {
+			for err := range sshErrCh {
+				c.logger.Warn(c.logTag, "SSH tunnel error: %s", err.Error())
+			}
+		}()
+
+		err := <-sshReadyErrCh
+		if err != nil {
+			return bosherr.WrapError(err, "Starting SSH tunnel")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sshTunnel.Stop(); err != nil {
+			c.logger.Warn(c.logTag, "Failed to stop ssh tunnel: %s", err.Error())
+		}
+	}()
+
+	c.ui.PrintLinef("Serving registry on port %d through '%s'. Press Ctrl-C to stop.", registryConfig.Port, registryConfig.SSHTunnel.Host)
+	<-biui.InterruptCh(stage)
+
+	return nil
+}
+
+func (c *registryCmd) indentJSON(contents []byte) string {
+	var buffer bytes.Buffer
+	err := json.Indent(&buffer, contents, "", "  ")
+	if err != nil {
+		return string(contents)
+	}
+	return buffer.String()
+}
+
+func (c *registryCmd) parseCmdInputs(args []string) (string, string, error) {
+	if len(args) != 2 || (args[0] != "serve" && args[0] != "show") {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return "", "", errors.New("Invalid usage - registry command requires a subcommand (serve or show) and exactly 1 argument")
+	}
+	return args[0], args[1], nil
+}
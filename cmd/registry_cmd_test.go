@@ -0,0 +1,127 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
+	mock_registry "github.com/cloudfoundry/bosh-init/registry/mocks"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("RegistryCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			fs                        *fakesys.FakeFileSystem
+			logger                    boshlog.Logger
+			fakeUI                    *fakebiui.FakeUI
+			fakeStage                 *fakebiui.FakeStage
+			mockRegistryServerManager *mock_registry.MockServerManager
+			encryptor                 bicrypto.Encryptor
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+			registryStorePath      = "/deployment-dir/fake-deployment-manifest-registry.json"
+		)
+
+		var newRegistryCmd = func() bicmd.Cmd {
+			return bicmd.NewRegistryCmd(
+				fakeUI,
+				fs,
+				bicmd.ReleaseSetAndInstallationManifestParser{},
+				mockRegistryServerManager,
+				nil,
+				encryptor,
+				logger,
+			)
+		}
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+			mockRegistryServerManager = mock_registry.NewMockServerManager(mockCtrl)
+			encryptor = nil
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("requires a subcommand", func() {
+			err := newRegistryCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+
+		It("rejects unknown subcommands", func() {
+			err := newRegistryCmd().Run(fakeStage, []string{"fake-subcommand", deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+
+		Context("when the deployment manifest does not exist", func() {
+			It("returns an error", func() {
+				err := newRegistryCmd().Run(fakeStage, []string{"show", "/garbage"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Deployment manifest does not exist at '/garbage'"))
+				Expect(fakeUI.Errors).To(ContainElement("Deployment '/garbage' does not exist"))
+			})
+		})
+
+		Describe("show", func() {
+			Context("when registry contents were persisted", func() {
+				BeforeEach(func() {
+					fs.WriteFileString(registryStorePath, `{"fake-instance-id":"{\"agent_id\":\"fake-agent-id\"}"}`)
+				})
+
+				It("prints the settings of every instance", func() {
+					err := newRegistryCmd().Run(fakeStage, []string{"show", deploymentManifestPath})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeUI.Said).To(ContainElement("Registry store: '/deployment-dir/fake-deployment-manifest-registry.json'"))
+					Expect(fakeUI.Said).To(ContainElement("Instance 'fake-instance-id':"))
+					Expect(fakeUI.Said).To(ContainElement("{\n  \"agent_id\": \"fake-agent-id\"\n}"))
+				})
+			})
+
+			Context("when registry contents were persisted encrypted", func() {
+				BeforeEach(func() {
+					encryptor = bicrypto.NewPassphraseEncryptor("fake-passphrase")
+					_, err := biregistry.NewEncryptedFileRegistry(registryStorePath, fs, logger, encryptor).Save("fake-instance-id", []byte(`{"agent_id":"fake-agent-id"}`))
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("decrypts them with the deployment state encryptor", func() {
+					err := newRegistryCmd().Run(fakeStage, []string{"show", deploymentManifestPath})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeUI.Said).To(ContainElement("{\n  \"agent_id\": \"fake-agent-id\"\n}"))
+				})
+			})
+
+			Context("when no registry contents were persisted", func() {
+				It("says so", func() {
+					err := newRegistryCmd().Run(fakeStage, []string{"show", deploymentManifestPath})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeUI.Said).To(ContainElement("No registry contents found."))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type restartCmd struct {
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error)
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	logTag                    string
+}
+
+func NewRestartCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return &restartCmd{
+		ui:                        ui,
+		fs:                        fs,
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		logger:                    logger,
+		logTag:                    "restartCmd",
+	}
+}
+
+func (c *restartCmd) Name() string {
+	return "restart"
+}
+
+func (c *restartCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Restart the jobs of the deployed instance",
+		Usage:    "<deployment_manifest_path> [--skip-drain] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *restartCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, skipDrain, forceLock, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return instanceLifecycle.Restart(stage, skipDrain, forceLock)
+}
+
+func (c *restartCmd) parseCmdInputs(args []string) (string, bool, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "restart",
+		positional: 1,
+		flags:      []string{"--skip-drain", "--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", false, false, err
+	}
+	return parsed.positional[0], parsed.Flag("--skip-drain"), parsed.Flag("--force-lock"), nil
+}
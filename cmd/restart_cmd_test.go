@@ -0,0 +1,93 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("RestartCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+			fs                    *fakesys.FakeFileSystem
+			logger                boshlog.Logger
+			fakeUI                *fakebiui.FakeUI
+			fakeStage             *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newRestartCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.InstanceLifecycle, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockInstanceLifecycle, nil
+			}
+
+			return bicmd.NewRestartCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("restarts the jobs of the deployed instance", func() {
+			mockInstanceLifecycle.EXPECT().Restart(fakeStage, false, false).Return(nil)
+
+			err := newRestartCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("skips draining the jobs when --skip-drain is given", func() {
+			mockInstanceLifecycle.EXPECT().Restart(fakeStage, true, false).Return(nil)
+
+			err := newRestartCmd().Run(fakeStage, []string{deploymentManifestPath, "--skip-drain"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockInstanceLifecycle.EXPECT().Restart(fakeStage, false, true).Return(nil)
+
+			err := newRestartCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockInstanceLifecycle.EXPECT().Restart(fakeStage, false, false).Return(bosherr.Error("fake-restart-error"))
+
+			err := newRestartCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-restart-error"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newRestartCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
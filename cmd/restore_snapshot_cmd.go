@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type restoreSnapshotCmd struct {
+	deploymentSnapshotterProvider func(deploymentManifestPath string) (DeploymentSnapshotter, error)
+	ui                            biui.UI
+	fs                            boshsys.FileSystem
+	logger                        boshlog.Logger
+	logTag                        string
+}
+
+func NewRestoreSnapshotCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	deploymentSnapshotterProvider func(deploymentManifestPath string) (DeploymentSnapshotter, error),
+) Cmd {
+	return &restoreSnapshotCmd{
+		ui:                            ui,
+		fs:                            fs,
+		deploymentSnapshotterProvider: deploymentSnapshotterProvider,
+		logger:                        logger,
+		logTag:                        "restoreSnapshotCmd",
+	}
+}
+
+func (c *restoreSnapshotCmd) Name() string {
+	return "restore-snapshot"
+}
+
+func (c *restoreSnapshotCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Replace the persistent disk of the deployed instance with a disk created from a snapshot",
+		Usage:    "<deployment_manifest_path> <snapshot_cid> [--skip-drain] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *restoreSnapshotCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, snapshotCID, skipDrain, forceLock, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentSnapshotter, err := c.deploymentSnapshotterProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return deploymentSnapshotter.RestoreSnapshot(stage, snapshotCID, skipDrain, forceLock)
+}
+
+func (c *restoreSnapshotCmd) parseCmdInputs(args []string) (string, string, bool, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "restore-snapshot",
+		positional: 2,
+		flags:      []string{"--skip-drain", "--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", "", false, false, err
+	}
+	return parsed.positional[0], parsed.positional[1], parsed.Flag("--skip-drain"), parsed.Flag("--force-lock"), nil
+}
@@ -0,0 +1,93 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("RestoreSnapshotCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockDeploymentSnapshotter *mock_cmd.MockDeploymentSnapshotter
+			fs                        *fakesys.FakeFileSystem
+			logger                    boshlog.Logger
+			fakeUI                    *fakebiui.FakeUI
+			fakeStage                 *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newRestoreSnapshotCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.DeploymentSnapshotter, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockDeploymentSnapshotter, nil
+			}
+
+			return bicmd.NewRestoreSnapshotCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockDeploymentSnapshotter = mock_cmd.NewMockDeploymentSnapshotter(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("restores the snapshot", func() {
+			mockDeploymentSnapshotter.EXPECT().RestoreSnapshot(fakeStage, "fake-snapshot-cid", false, false).Return(nil)
+
+			err := newRestoreSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("skips draining the jobs when --skip-drain is given", func() {
+			mockDeploymentSnapshotter.EXPECT().RestoreSnapshot(fakeStage, "fake-snapshot-cid", true, false).Return(nil)
+
+			err := newRestoreSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid", "--skip-drain"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockDeploymentSnapshotter.EXPECT().RestoreSnapshot(fakeStage, "fake-snapshot-cid", false, true).Return(nil)
+
+			err := newRestoreSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid", "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockDeploymentSnapshotter.EXPECT().RestoreSnapshot(fakeStage, "fake-snapshot-cid", false, false).Return(bosherr.Error("fake-restore-error"))
+
+			err := newRestoreSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "fake-snapshot-cid"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-restore-error"))
+		})
+
+		It("returns err unless exactly 2 arguments are given", func() {
+			err := newRestoreSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
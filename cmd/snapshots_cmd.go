@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type snapshotsCmd struct {
+	ui                             biui.UI
+	fs                             boshsys.FileSystem
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService
+	logger                         boshlog.Logger
+	logTag                         string
+}
+
+func NewSnapshotsCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService,
+	logger boshlog.Logger,
+) Cmd {
+	return &snapshotsCmd{
+		ui:                             ui,
+		fs:                             fs,
+		deploymentStateServiceProvider: deploymentStateServiceProvider,
+		logger:                         logger,
+		logTag:                         "snapshotsCmd",
+	}
+}
+
+func (c *snapshotsCmd) Name() string {
+	return "snapshots"
+}
+
+func (c *snapshotsCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "List the snapshots of the persistent disks of the deployed instance",
+		Usage:    "<deployment_manifest_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *snapshotsCmd) Run(_ biui.Stage, args []string) error {
+	if len(args) != 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return errors.New("Invalid usage - snapshots command requires exactly 1 argument")
+	}
+
+	deploymentManifestPath := args[0]
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentStateService := c.deploymentStateServiceProvider(manifestAbsFilePath)
+	if !deploymentStateService.Exists() {
+		return bosherr.Errorf("No deployment state found at '%s'", deploymentStateService.Path())
+	}
+
+	deploymentState, err := deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	if len(deploymentState.Snapshots) == 0 {
+		c.ui.PrintLinef("No snapshots found")
+		return nil
+	}
+
+	for _, snapshot := range deploymentState.Snapshots {
+		c.ui.PrintLinef("Snapshot '%s'", snapshot.CID)
+		c.ui.PrintLinef("  Disk:       %s (%d MB)", snapshot.DiskCID, snapshot.Size)
+		c.ui.PrintLinef("  Created at: %s", snapshot.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	}
+
+	return nil
+}
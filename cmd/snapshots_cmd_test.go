@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("SnapshotsCmd", func() {
+	Describe("Run", func() {
+		var (
+			fs        *fakesys.FakeFileSystem
+			logger    boshlog.Logger
+			fakeUI    *fakebiui.FakeUI
+			fakeStage *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+			deploymentStatePath    = "/deployment-dir/fake-deployment-manifest-state.json"
+		)
+
+		var newSnapshotsCmd = func() bicmd.Cmd {
+			deploymentStateServiceProvider := func(path string) biconfig.DeploymentStateService {
+				return biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, biconfig.DeploymentStatePath(path))
+			}
+
+			return bicmd.NewSnapshotsCmd(fakeUI, fs, deploymentStateServiceProvider, logger)
+		}
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("lists the snapshots of the deployment", func() {
+			fs.WriteFileString(deploymentStatePath, `{
+				"director_id": "fake-director-id",
+				"snapshots": [
+					{"id": "fake-snapshot-id", "cid": "fake-snapshot-cid", "disk_cid": "fake-disk-cid", "size": 1024, "created_at": "2016-03-04T13:14:15Z"}
+				]
+			}`)
+
+			err := newSnapshotsCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(Equal([]string{
+				"Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'",
+				"Snapshot 'fake-snapshot-cid'",
+				"  Disk:       fake-disk-cid (1024 MB)",
+				"  Created at: 2016-03-04 13:14:15 UTC",
+			}))
+		})
+
+		It("tells the user when there are no snapshots", func() {
+			fs.WriteFileString(deploymentStatePath, `{"director_id": "fake-director-id"}`)
+
+			err := newSnapshotsCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("No snapshots found"))
+		})
+
+		It("returns an error when the deployment state does not exist", func() {
+			err := newSnapshotsCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No deployment state found at '/deployment-dir/fake-deployment-manifest-state.json'"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newSnapshotsCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	agentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	biinstancestate "github.com/cloudfoundry/bosh-init/deployment/instance/state"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type sshCmd struct {
+	ui                                      biui.UI
+	fs                                      boshsys.FileSystem
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentParser                        bideplmanifest.Parser
+	deploymentStateServiceProvider          func(deploymentManifestPath string) biconfig.DeploymentStateService
+	agentClientFactory                      bihttpagent.AgentClientFactory
+	sshTunnelFactory                        bisshtunnel.Factory
+	stdin                                   io.Reader
+	stdout                                  io.Writer
+	stderr                                  io.Writer
+	logger                                  boshlog.Logger
+	logTag                                  string
+}
+
+type sshCmdInputs struct {
+	deploymentManifestPath string
+	command                string
+	gateway                string
+	gatewayPrivateKey      string
+}
+
+func NewSSHCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	deploymentParser bideplmanifest.Parser,
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService,
+	agentClientFactory bihttpagent.AgentClientFactory,
+	sshTunnelFactory bisshtunnel.Factory,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	logger boshlog.Logger,
+) Cmd {
+	return &sshCmd{
+		ui:                                      ui,
+		fs:                                      fs,
+		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		deploymentParser:                        deploymentParser,
+		deploymentStateServiceProvider:          deploymentStateServiceProvider,
+		agentClientFactory:                      agentClientFactory,
+		sshTunnelFactory:                        sshTunnelFactory,
+		stdin:                                   stdin,
+		stdout:                                  stdout,
+		stderr:                                  stderr,
+		logger:                                  logger,
+		logTag:                                  "sshCmd",
+	}
+}
+
+func (c *sshCmd) Name() string {
+	return "ssh"
+}
+
+func (c *sshCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Open a shell or run a command on the deployed VM",
+		Usage:    "<deployment_manifest_path> [-c <command>] [--gateway <[user@]host[:port]>] [--gateway-private-key <path>]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *sshCmd) Run(stage biui.Stage, args []string) error {
+	inputs, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, inputs.deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentStateService := c.deploymentStateServiceProvider(manifestAbsFilePath)
+	if !deploymentStateService.Exists() {
+		return bosherr.Errorf("No deployment state found at '%s'", deploymentStateService.Path())
+	}
+
+	deploymentState, err := deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	if deploymentState.CurrentVMCID == "" {
+		return bosherr.Error("No deployed VM found")
+	}
+
+	_, installationManifest, err := c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	sshTunnelConfig := installationManifest.Registry.SSHTunnel
+	if sshTunnelConfig == (biinstallmanifest.SSHTunnel{}) {
+		return bosherr.Error("SSH credentials are not configured, cloud_provider.ssh_tunnel must be provided")
+	}
+
+	deploymentManifest, err := c.deploymentParser.Parse(manifestAbsFilePath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing deployment manifest '%s'", manifestAbsFilePath)
+	}
+
+	address, err := c.instanceAddress(deploymentManifest, deploymentState, installationManifest)
+	if err != nil {
+		return err
+	}
+
+	sshOptions := bisshtunnel.Options{
+		Host:           address,
+		Port:           sshTunnelConfig.Port,
+		User:           sshTunnelConfig.User,
+		Password:       sshTunnelConfig.Password,
+		PrivateKey:     sshTunnelConfig.PrivateKey,
+		Passphrase:     sshTunnelConfig.Passphrase,
+		UseAgent:       sshTunnelConfig.UseAgent,
+		KnownHostsFile: sshTunnelConfig.KnownHosts,
+		JumpHost: bisshtunnel.JumpHostOptions{
+			Host:       sshTunnelConfig.JumpHost.Host,
+			Port:       sshTunnelConfig.JumpHost.Port,
+			User:       sshTunnelConfig.JumpHost.User,
+			Password:   sshTunnelConfig.JumpHost.Password,
+			PrivateKey: sshTunnelConfig.JumpHost.PrivateKey,
+			Passphrase: sshTunnelConfig.JumpHost.Passphrase,
+		},
+	}
+	if sshOptions.Port == 0 {
+		sshOptions.Port = 22
+	}
+
+	if inputs.gateway != "" {
+		sshOptions.JumpHost, err = c.gatewayOptions(inputs.gateway, inputs.gatewayPrivateKey, sshTunnelConfig.User)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sshOptions.JumpHost.IsEmpty() {
+		c.ui.PrintLinef("Connecting to '%s@%s'", sshOptions.User, address)
+	} else {
+		c.ui.PrintLinef("Connecting to '%s@%s' through gateway '%s'", sshOptions.User, address, sshOptions.JumpHost.Host)
+	}
+
+	sshSession := c.sshTunnelFactory.NewSSHSession(sshOptions)
+	return sshSession.Run(inputs.command, c.stdin, c.stdout, c.stderr)
+}
+
+// instanceAddress returns the default address of the deployed instance, asking the agent for it on dynamic networks
+func (c *sshCmd) instanceAddress(
+	deploymentManifest bideplmanifest.Manifest,
+	deploymentState biconfig.DeploymentState,
+	installationManifest biinstallmanifest.Manifest,
+) (string, error) {
+	jobName := deploymentManifest.JobName()
+	networkInterfaces, err := deploymentManifest.NetworkInterfaces(jobName)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Finding networks for job '%s'", jobName)
+	}
+	networkRefs := biinstancestate.NewNetworkRefs(networkInterfaces)
+
+	var agentState agentclient.AgentState
+	for _, networkRef := range networkRefs {
+		if networkRef.IsDynamic() {
+			agentClient := c.agentClientFactory.NewAgentClient(deploymentState.DirectorID, installationManifest.Mbus)
+			agentState, err = agentClient.GetState()
+			if err != nil {
+				return "", bosherr.WrapError(err, "Getting the address of the deployed VM from the agent")
+			}
+			break
+		}
+	}
+
+	address, err := biinstancestate.DefaultAddress(networkRefs, agentState)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Determining the address of the deployed VM")
+	}
+
+	return address, nil
+}
+
+func (c *sshCmd) gatewayOptions(gateway, privateKey, defaultUser string) (bisshtunnel.JumpHostOptions, error) {
+	options := bisshtunnel.JumpHostOptions{
+		User:       defaultUser,
+		Host:       gateway,
+		Port:       22,
+		PrivateKey: privateKey,
+	}
+
+	if i := strings.Index(options.Host, "@"); i != -1 {
+		options.User = options.Host[:i]
+		options.Host = options.Host[i+1:]
+	}
+
+	if i := strings.LastIndex(options.Host, ":"); i != -1 {
+		port, err := strconv.Atoi(options.Host[i+1:])
+		if err != nil {
+			return bisshtunnel.JumpHostOptions{}, bosherr.WrapErrorf(err, "Parsing gateway port in '%s'", gateway)
+		}
+		options.Host = options.Host[:i]
+		options.Port = port
+	}
+
+	if options.PrivateKey != "" {
+		absPath, err := filepath.Abs(options.PrivateKey)
+		if err != nil {
+			return bisshtunnel.JumpHostOptions{}, bosherr.WrapErrorf(err, "Getting absolute path to gateway private key '%s'", options.PrivateKey)
+		}
+		options.PrivateKey = absPath
+	}
+
+	return options, nil
+}
+
+func (c *sshCmd) parseCmdInputs(args []string) (sshCmdInputs, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "ssh",
+		positional: 1,
+		options:    []string{"-c", "--gateway", "--gateway-private-key"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return sshCmdInputs{}, err
+	}
+
+	inputs := sshCmdInputs{
+		deploymentManifestPath: parsed.positional[0],
+		command:                parsed.Option("-c"),
+		gateway:                parsed.Option("--gateway"),
+		gatewayPrivateKey:      parsed.Option("--gateway-private-key"),
+	}
+
+	return inputs, nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type startCmd struct {
+	instanceLifecycleProvider  func(deploymentManifestPath string) (InstanceLifecycle, error)
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error)
+	ui                         biui.UI
+	fs                         boshsys.FileSystem
+	logger                     boshlog.Logger
+	logTag                     string
+}
+
+func NewStartCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error),
+) Cmd {
+	return &startCmd{
+		ui:                         ui,
+		fs:                         fs,
+		instanceLifecycleProvider:  instanceLifecycleProvider,
+		deploymentPreparerProvider: deploymentPreparerProvider,
+		logger:                     logger,
+		logTag:                     "startCmd",
+	}
+}
+
+func (c *startCmd) Name() string {
+	return "start"
+}
+
+func (c *startCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Start the jobs of the deployed instance, recreating its VM if it was deleted",
+		Usage:    "<deployment_manifest_path> [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *startCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, forceLock, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	started, err := instanceLifecycle.Start(stage, forceLock)
+	if err != nil {
+		return err
+	}
+
+	if started {
+		return nil
+	}
+
+	c.ui.PrintLinef("No deployed VM found, recreating it from the recorded deployment")
+
+	deploymentPreparer, err := c.deploymentPreparerProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return deploymentPreparer.PrepareDeployment(stage, DeployOptions{
+		Recreate:               true,
+		ForceLock:              forceLock,
+		RecordedDeploymentOnly: true,
+	})
+}
+
+func (c *startCmd) parseCmdInputs(args []string) (string, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "start",
+		positional: 1,
+		flags:      []string{"--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", false, err
+	}
+	return parsed.positional[0], parsed.Flag("--force-lock"), nil
+}
@@ -0,0 +1,109 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("StartCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+			fs                    *fakesys.FakeFileSystem
+			logger                boshlog.Logger
+			fakeUI                *fakebiui.FakeUI
+			fakeStage             *fakebiui.FakeStage
+
+			deploymentPreparerPaths []string
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newStartCmd = func() bicmd.Cmd {
+			instanceLifecycleProvider := func(path string) (bicmd.InstanceLifecycle, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockInstanceLifecycle, nil
+			}
+
+			deploymentPreparerProvider := func(path string) (bicmd.DeploymentPreparer, error) {
+				deploymentPreparerPaths = append(deploymentPreparerPaths, path)
+				return bicmd.DeploymentPreparer{}, bosherr.Error("fake-preparer-error")
+			}
+
+			return bicmd.NewStartCmd(fakeUI, fs, logger, instanceLifecycleProvider, deploymentPreparerProvider)
+		}
+
+		BeforeEach(func() {
+			mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+			deploymentPreparerPaths = []string{}
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("starts the jobs of the deployed instance", func() {
+			mockInstanceLifecycle.EXPECT().Start(fakeStage, false).Return(true, nil)
+
+			err := newStartCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentPreparerPaths).To(BeEmpty())
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockInstanceLifecycle.EXPECT().Start(fakeStage, true).Return(true, nil)
+
+			err := newStartCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockInstanceLifecycle.EXPECT().Start(fakeStage, false).Return(false, bosherr.Error("fake-start-error"))
+
+			err := newStartCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-start-error"))
+			Expect(deploymentPreparerPaths).To(BeEmpty())
+		})
+
+		Context("when there is no VM to start", func() {
+			It("recreates the VM from the recorded deployment", func() {
+				mockInstanceLifecycle.EXPECT().Start(fakeStage, false).Return(false, nil)
+
+				err := newStartCmd().Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("fake-preparer-error"))
+
+				Expect(deploymentPreparerPaths).To(Equal([]string{deploymentManifestPath}))
+				Expect(fakeUI.Said).To(ContainElement("No deployed VM found, recreating it from the recorded deployment"))
+			})
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newStartCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
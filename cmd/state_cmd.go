@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"gopkg.in/yaml.v2"
+)
+
+type stateCmd struct {
+	ui                             biui.UI
+	fs                             boshsys.FileSystem
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService
+	locker                         bilock.Locker
+	encryptor                      bicrypto.Encryptor
+	logger                         boshlog.Logger
+	logTag                         string
+}
+
+func NewStateCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService,
+	locker bilock.Locker,
+	encryptor bicrypto.Encryptor,
+	logger boshlog.Logger,
+) Cmd {
+	return &stateCmd{
+		ui:                             ui,
+		fs:                             fs,
+		deploymentStateServiceProvider: deploymentStateServiceProvider,
+		locker:                         locker,
+		encryptor:                      encryptor,
+		logger:                         logger,
+		logTag:                         "stateCmd",
+	}
+}
+
+func (c *stateCmd) Name() string {
+	return "state"
+}
+
+func (c *stateCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "List or roll back the saved revisions of the deployment state, encrypt or decrypt it, or encrypt manifest secrets",
+		Usage:    "history <deployment_manifest_path> | rollback <deployment_manifest_path> <revision> | encrypt <deployment_manifest_path> | decrypt <deployment_manifest_path> | encrypt-secrets <secrets_yml_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *stateCmd) Run(_ biui.Stage, args []string) error {
+	if len(args) < 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return errors.New("Invalid usage - state command requires a subcommand: history, rollback, encrypt, decrypt or encrypt-secrets")
+	}
+
+	switch args[0] {
+	case "history":
+		if len(args) != 2 {
+			c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+			return errors.New("Invalid usage - state history command requires exactly 1 argument")
+		}
+		return c.history(args[1])
+	case "rollback":
+		if len(args) != 3 {
+			c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+			return errors.New("Invalid usage - state rollback command requires exactly 2 arguments")
+		}
+		revision, err := strconv.Atoi(args[2])
+		if err != nil {
+			return bosherr.Errorf("Invalid usage - revision '%s' is not a number", args[2])
+		}
+		return c.rollback(args[1], revision)
+	case "encrypt", "decrypt", "encrypt-secrets":
+		if len(args) != 2 {
+			c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+			return bosherr.Errorf("Invalid usage - state %s command requires exactly 1 argument", args[0])
+		}
+		if c.encryptor == nil {
+			return bosherr.Errorf("State %s command requires a passphrase or key file, set %s or %s", args[0], statePassphraseEnv, stateKeyFileEnv)
+		}
+		switch args[0] {
+		case "encrypt":
+			return c.encrypt(args[1])
+		case "decrypt":
+			return c.decrypt(args[1])
+		default:
+			return c.encryptSecrets(args[1])
+		}
+	default:
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return bosherr.Errorf("Invalid usage - unknown state subcommand '%s', expected history, rollback, encrypt, decrypt or encrypt-secrets", args[0])
+	}
+}
+
+func (c *stateCmd) history(deploymentManifestPath string) error {
+	deploymentStateService, err := c.deploymentStateService(deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	history, err := deploymentStateService.History()
+	if err != nil {
+		return bosherr.WrapError(err, "Reading deployment state history")
+	}
+
+	for _, revision := range history {
+		if revision.Current {
+			c.ui.PrintLinef("Revision %d (current)", revision.Revision)
+		} else {
+			c.ui.PrintLinef("Revision %d", revision.Revision)
+		}
+		if !revision.SavedAt.IsZero() {
+			c.ui.PrintLinef("  Saved at: %s", revision.SavedAt.Format("2006-01-02 15:04:05 MST"))
+		}
+		c.ui.PrintLinef("  VM:       %s", valueOrNone(revision.CurrentVMCID))
+		c.ui.PrintLinef("  Disk:     %s", valueOrNone(currentDiskCID(revision.DeploymentState)))
+		c.ui.PrintLinef("  Stemcell: %s", valueOrNone(currentStemcell(revision.DeploymentState)))
+	}
+
+	return nil
+}
+
+func (c *stateCmd) rollback(deploymentManifestPath string, revision int) error {
+	deploymentStateService, err := c.deploymentStateService(deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	stateLock, err := acquireLock(c.locker, deploymentStateService.Path()+".lock", "deployment state", false)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, c.logger, c.logTag)
+
+	deploymentState, err := deploymentStateService.Rollback(revision)
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("Rolled back the deployment state to revision %d, the replaced state is kept in the history", revision)
+	c.ui.PrintLinef("  VM:       %s", valueOrNone(deploymentState.CurrentVMCID))
+	c.ui.PrintLinef("  Disk:     %s", valueOrNone(currentDiskCID(deploymentState)))
+	c.ui.PrintLinef("  Stemcell: %s", valueOrNone(currentStemcell(deploymentState)))
+	c.ui.PrintLinef("Nothing was changed in the cloud, run 'bosh-init deploy' to converge the deployment")
+
+	return nil
+}
+
+func (c *stateCmd) encrypt(deploymentManifestPath string) error {
+	deploymentStateService, err := c.deploymentStateService(deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	stateLock, err := acquireLock(c.locker, deploymentStateService.Path()+".lock", "deployment state", false)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, c.logger, c.logTag)
+
+	err = deploymentStateService.Encrypt()
+	if err != nil {
+		return bosherr.WrapError(err, "Encrypting deployment state")
+	}
+
+	c.ui.PrintLinef("Encrypted the deployment state and its history")
+	c.ui.PrintLinef("Keep %s or %s set for every bosh-init command on this deployment", statePassphraseEnv, stateKeyFileEnv)
+
+	return nil
+}
+
+func (c *stateCmd) decrypt(deploymentManifestPath string) error {
+	deploymentStateService, err := c.deploymentStateService(deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	stateLock, err := acquireLock(c.locker, deploymentStateService.Path()+".lock", "deployment state", false)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(stateLock, c.logger, c.logTag)
+
+	err = deploymentStateService.Decrypt()
+	if err != nil {
+		return bosherr.WrapError(err, "Decrypting deployment state")
+	}
+
+	c.ui.PrintLinef("Decrypted the deployment state and its history")
+	c.ui.PrintLinef("Unset %s and %s, otherwise the deployment state is encrypted again when it is next saved", statePassphraseEnv, stateKeyFileEnv)
+
+	return nil
+}
+
+// encryptSecrets prints the encrypted contents of a YAML file of cloud_provider properties,
+// to be used as cloud_provider.secrets in the deployment manifest
+func (c *stateCmd) encryptSecrets(secretsPath string) error {
+	contents, err := c.fs.ReadFile(secretsPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading secrets file '%s'", secretsPath)
+	}
+
+	var secrets map[interface{}]interface{}
+	err = yaml.Unmarshal(contents, &secrets)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Secrets file '%s' is not a YAML map", secretsPath)
+	}
+
+	encrypted, err := c.encryptor.Encrypt(contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Encrypting secrets")
+	}
+
+	c.ui.PrintLinef("Set cloud_provider.secrets in the deployment manifest to:")
+	c.ui.PrintLinef("%s", encrypted)
+
+	return nil
+}
+
+func (c *stateCmd) deploymentStateService(deploymentManifestPath string) (biconfig.DeploymentStateService, error) {
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentStateService := c.deploymentStateServiceProvider(manifestAbsFilePath)
+	if !deploymentStateService.Exists() {
+		return nil, bosherr.Errorf("No deployment state found at '%s'", deploymentStateService.Path())
+	}
+
+	return deploymentStateService, nil
+}
+
+func currentDiskCID(deploymentState biconfig.DeploymentState) string {
+	if deploymentState.CurrentDiskID == "" {
+		return ""
+	}
+	for _, disk := range deploymentState.Disks {
+		if disk.ID == deploymentState.CurrentDiskID {
+			return disk.CID
+		}
+	}
+	return ""
+}
+
+func currentStemcell(deploymentState biconfig.DeploymentState) string {
+	if deploymentState.CurrentStemcellID == "" {
+		return ""
+	}
+	for _, stemcell := range deploymentState.Stemcells {
+		if stemcell.ID == deploymentState.CurrentStemcellID {
+			return stemcell.Name + "/" + stemcell.Version
+		}
+	}
+	return ""
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,213 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_lock "github.com/cloudfoundry/bosh-init/lock/mocks"
+	"github.com/golang/mock/gomock"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("StateCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			fs                     *fakesys.FakeFileSystem
+			logger                 boshlog.Logger
+			fakeUI                 *fakebiui.FakeUI
+			fakeStage              *fakebiui.FakeStage
+			mockLocker             *mock_lock.MockLocker
+			mockLock               *mock_lock.MockLock
+			deploymentStateService biconfig.DeploymentStateService
+			encryptor              bicrypto.Encryptor
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+			deploymentStatePath    = "/deployment-dir/fake-deployment-manifest-state.json"
+		)
+
+		var newStateCmd = func() bicmd.Cmd {
+			deploymentStateServiceProvider := func(path string) biconfig.DeploymentStateService {
+				return biconfig.NewEncryptedFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, biconfig.DeploymentStatePath(path), encryptor)
+			}
+
+			return bicmd.NewStateCmd(fakeUI, fs, deploymentStateServiceProvider, mockLocker, encryptor, logger)
+		}
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+			mockLocker = mock_lock.NewMockLocker(mockCtrl)
+			mockLock = mock_lock.NewMockLock(mockCtrl)
+			encryptor = nil
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+
+			deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, deploymentStatePath)
+			err := deploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:        "fake-director-id",
+				CurrentVMCID:      "fake-vm-cid-1",
+				CurrentDiskID:     "fake-disk-id",
+				CurrentStemcellID: "fake-stemcell-id",
+				Disks:             []biconfig.DiskRecord{{ID: "fake-disk-id", CID: "fake-disk-cid"}},
+				Stemcells:         []biconfig.StemcellRecord{{ID: "fake-stemcell-id", Name: "fake-stemcell-name", Version: "1"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			err = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, deploymentStatePath).Save(biconfig.DeploymentState{
+				DirectorID:   "fake-director-id",
+				CurrentVMCID: "fake-vm-cid-2",
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Describe("history", func() {
+			It("lists the revisions of the deployment state", func() {
+				err := newStateCmd().Run(fakeStage, []string{"history", deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUI.Said).To(ContainElement("Revision 2 (current)"))
+				Expect(fakeUI.Said).To(ContainElement("  VM:       fake-vm-cid-2"))
+				Expect(fakeUI.Said).To(ContainElement("  Disk:     none"))
+				Expect(fakeUI.Said).To(ContainElement("Revision 1"))
+				Expect(fakeUI.Said).To(ContainElement("  VM:       fake-vm-cid-1"))
+				Expect(fakeUI.Said).To(ContainElement("  Disk:     fake-disk-cid"))
+				Expect(fakeUI.Said).To(ContainElement("  Stemcell: fake-stemcell-name/1"))
+			})
+
+			It("returns an error when the deployment has no state", func() {
+				fs.RemoveAll(deploymentStatePath)
+
+				err := newStateCmd().Run(fakeStage, []string{"history", deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("No deployment state found at '/deployment-dir/fake-deployment-manifest-state.json'"))
+			})
+		})
+
+		Describe("rollback", func() {
+			It("rolls the deployment state back to the revision while holding the deployment state lock", func() {
+				gomock.InOrder(
+					mockLocker.EXPECT().Lock(deploymentStatePath+".lock", false).Return(mockLock, nil),
+					mockLock.EXPECT().Release(),
+				)
+
+				err := newStateCmd().Run(fakeStage, []string{"rollback", deploymentManifestPath, "1"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUI.Said).To(ContainElement("Rolled back the deployment state to revision 1, the replaced state is kept in the history"))
+
+				deploymentState, err := deploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid-1"))
+			})
+
+			It("returns an error when the deployment state is locked", func() {
+				mockLocker.EXPECT().Lock(deploymentStatePath+".lock", false).Return(nil, bilock.HeldError{Path: deploymentStatePath + ".lock"})
+
+				err := newStateCmd().Run(fakeStage, []string{"rollback", deploymentManifestPath, "1"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Another bosh-init process is using the deployment state"))
+			})
+
+			It("returns an error when the revision is not a number", func() {
+				err := newStateCmd().Run(fakeStage, []string{"rollback", deploymentManifestPath, "latest"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Invalid usage - revision 'latest' is not a number"))
+			})
+		})
+
+		Describe("encrypt", func() {
+			It("encrypts the deployment state while holding the deployment state lock", func() {
+				encryptor = bicrypto.NewPassphraseEncryptor("fake-passphrase")
+				gomock.InOrder(
+					mockLocker.EXPECT().Lock(deploymentStatePath+".lock", false).Return(mockLock, nil),
+					mockLock.EXPECT().Release(),
+				)
+
+				err := newStateCmd().Run(fakeStage, []string{"encrypt", deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUI.Said).To(ContainElement("Encrypted the deployment state and its history"))
+
+				contents, err := fs.ReadFileString(deploymentStatePath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(contents).ToNot(ContainSubstring("fake-vm-cid-2"))
+
+				_, err = deploymentStateService.Load()
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("returns an error without a passphrase or key file", func() {
+				err := newStateCmd().Run(fakeStage, []string{"encrypt", deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("State encrypt command requires a passphrase or key file, set BOSH_INIT_STATE_PASSPHRASE or BOSH_INIT_STATE_KEY_FILE"))
+			})
+		})
+
+		Describe("decrypt", func() {
+			It("decrypts the deployment state while holding the deployment state lock", func() {
+				encryptor = bicrypto.NewPassphraseEncryptor("fake-passphrase")
+				mockLocker.EXPECT().Lock(deploymentStatePath+".lock", false).Return(mockLock, nil).Times(2)
+				mockLock.EXPECT().Release().Times(2)
+
+				err := newStateCmd().Run(fakeStage, []string{"encrypt", deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = newStateCmd().Run(fakeStage, []string{"decrypt", deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUI.Said).To(ContainElement("Decrypted the deployment state and its history"))
+
+				deploymentState, err := deploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid-2"))
+			})
+		})
+
+		Describe("encrypt-secrets", func() {
+			It("prints the encrypted secrets file", func() {
+				encryptor = bicrypto.NewPassphraseEncryptor("fake-passphrase")
+				fs.WriteFileString("/fake-secrets.yml", "fake-secret-name: fake-secret-value\n")
+
+				err := newStateCmd().Run(fakeStage, []string{"encrypt-secrets", "/fake-secrets.yml"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUI.Said).To(HaveLen(2))
+				Expect(fakeUI.Said[0]).To(Equal("Set cloud_provider.secrets in the deployment manifest to:"))
+
+				secrets, err := encryptor.Decrypt(fakeUI.Said[1])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(secrets)).To(Equal("fake-secret-name: fake-secret-value\n"))
+			})
+
+			It("returns an error when the secrets file is not a YAML map", func() {
+				encryptor = bicrypto.NewPassphraseEncryptor("fake-passphrase")
+				fs.WriteFileString("/fake-secrets.yml", "- fake-secret-value\n")
+
+				err := newStateCmd().Run(fakeStage, []string{"encrypt-secrets", "/fake-secrets.yml"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Secrets file '/fake-secrets.yml' is not a YAML map"))
+			})
+		})
+
+		It("returns an error for an unknown subcommand", func() {
+			err := newStateCmd().Run(fakeStage, []string{"show", deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Invalid usage - unknown state subcommand 'show', expected history, rollback, encrypt, decrypt or encrypt-secrets"))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type StemcellUploader interface {
+	// UploadStemcell uploads the stemcell of the deployment manifest without deploying it,
+	// so that the next deploy does not have to. The stemcell is not used until then.
+	UploadStemcell(stage biui.Stage, forceLock bool) (bistemcell.CloudStemcell, error)
+}
+
+func NewStemcellUploader(
+	deploymentContext DeploymentContext,
+	stemcellManagerFactory bistemcell.ManagerFactory,
+	stemcellFetcher bistemcell.Fetcher,
+) StemcellUploader {
+	return &stemcellUploader{
+		DeploymentContext:      deploymentContext,
+		stemcellManagerFactory: stemcellManagerFactory,
+		stemcellFetcher:        stemcellFetcher,
+	}
+}
+
+type stemcellUploader struct {
+	DeploymentContext
+	stemcellManagerFactory bistemcell.ManagerFactory
+	stemcellFetcher        bistemcell.Fetcher
+}
+
+func (u *stemcellUploader) UploadStemcell(stage biui.Stage, forceLock bool) (bistemcell.CloudStemcell, error) {
+	u.UI.PrintLinef("Deployment state: '%s'", u.DeploymentStateService.Path())
+
+	stateLock, err := u.lockDeploymentState(forceLock)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock(stateLock, u.Logger, u.LogTag)
+
+	// the stemcell may be uploaded before the first deploy, which creates the deployment state
+	deploymentState, err := u.DeploymentStateService.Load()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	var cloudStemcell bistemcell.CloudStemcell
+	err = u.withCloud(stage, deploymentState, forceLock, func(cloud bicloud.Cloud, _ bivm.Manager, _ biinstance.Manager, deploymentManifest bideplmanifest.Manifest) error {
+		extractedStemcell, err := u.stemcellFetcher.GetStemcell(deploymentManifest, stage)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			deleteErr := extractedStemcell.Delete()
+			if deleteErr != nil {
+				u.Logger.Warn(u.LogTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
+			}
+		}()
+
+		cloudStemcell, err = u.stemcellManagerFactory.NewManager(cloud).Upload(extractedStemcell, stage)
+		return err
+	})
+
+	return cloudStemcell, err
+}
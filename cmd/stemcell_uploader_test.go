@@ -0,0 +1,101 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("StemcellUploader", func() {
+	var (
+		mockCtrl *gomock.Controller
+		fixture  *deploymentContextFixture
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		fixture = newDeploymentContextFixture(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var newStemcellUploader = func() bicmd.StemcellUploader {
+		deploymentContext := fixture.newDeploymentContext("stemcellUploader")
+		return bicmd.NewStemcellUploader(
+			deploymentContext,
+			bistemcell.NewManagerFactory(biconfig.NewStemcellRepo(deploymentContext.DeploymentStateService, fixture.fakeUUIDGenerator)),
+			fixture.stemcellFetcher(),
+		)
+	}
+
+	Describe("UploadStemcell", func() {
+		BeforeEach(func() {
+			fixture.fakeDeploymentParser.ParseManifest.Jobs = []bideplmanifest.Job{{Name: "fake-job-name", ResourcePool: "fake-resource-pool"}}
+			fixture.fakeDeploymentParser.ParseManifest.ResourcePools = []bideplmanifest.ResourcePool{
+				{
+					Name:     "fake-resource-pool",
+					Stemcell: bideplmanifest.StemcellRef{URL: "file:///fake-stemcell.tgz"},
+				},
+			}
+			fixture.fs.WriteFileString("/fake-stemcell.tgz", "fake-tgz-content")
+
+			extractedStemcell := bistemcell.NewExtractedStemcell(
+				bistemcell.Manifest{
+					ImagePath:       "/fake-stemcell-extracted-dir/image",
+					Name:            "fake-stemcell-name",
+					Version:         "fake-stemcell-version",
+					CloudProperties: biproperty.Map{},
+				},
+				"/fake-stemcell-extracted-dir",
+				fixture.fs,
+			)
+			fixture.fakeStemcellExtractor.SetExtractBehavior("/fake-stemcell.tgz", extractedStemcell, nil)
+		})
+
+		It("uploads the stemcell without making it the current stemcell", func() {
+			fixture.fakeUUIDGenerator.GeneratedUUID = "fake-stemcell-id"
+			fixture.mockCloud.EXPECT().CreateStemcell("/fake-stemcell-extracted-dir/image", biproperty.Map{}).Return("fake-stemcell-cid", nil)
+
+			cloudStemcell, err := newStemcellUploader().UploadStemcell(fixture.fakeStage, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudStemcell.CID()).To(Equal("fake-stemcell-cid"))
+			Expect(cloudStemcell.Name()).To(Equal("fake-stemcell-name"))
+
+			deploymentState, err := fixture.setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Stemcells).To(Equal([]biconfig.StemcellRecord{
+				{ID: "fake-stemcell-id", Name: "fake-stemcell-name", Version: "fake-stemcell-version", CID: "fake-stemcell-cid"},
+			}))
+			Expect(deploymentState.CurrentStemcellID).To(BeEmpty())
+
+			Expect(fixture.fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{
+				Name: "Uploading stemcell 'fake-stemcell-name/fake-stemcell-version'",
+			}))
+		})
+
+		It("does not upload a stemcell that was already uploaded", func() {
+			err := fixture.setupDeploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID: "fake-director-id",
+				Stemcells: []biconfig.StemcellRecord{
+					{ID: "fake-stemcell-id", Name: "fake-stemcell-name", Version: "fake-stemcell-version", CID: "fake-stemcell-cid"},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			cloudStemcell, err := newStemcellUploader().UploadStemcell(fixture.fakeStage, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudStemcell.CID()).To(Equal("fake-stemcell-cid"))
+		})
+	})
+})
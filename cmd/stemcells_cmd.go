@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type stemcellsCmd struct {
+	ui                             biui.UI
+	fs                             boshsys.FileSystem
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService
+	logger                         boshlog.Logger
+	logTag                         string
+}
+
+func NewStemcellsCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	deploymentStateServiceProvider func(deploymentManifestPath string) biconfig.DeploymentStateService,
+	logger boshlog.Logger,
+) Cmd {
+	return &stemcellsCmd{
+		ui:                             ui,
+		fs:                             fs,
+		deploymentStateServiceProvider: deploymentStateServiceProvider,
+		logger:                         logger,
+		logTag:                         "stemcellsCmd",
+	}
+}
+
+func (c *stemcellsCmd) Name() string {
+	return "stemcells"
+}
+
+func (c *stemcellsCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "List the stemcells uploaded for the deployment",
+		Usage:    "<deployment_manifest_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *stemcellsCmd) Run(_ biui.Stage, args []string) error {
+	if len(args) != 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return errors.New("Invalid usage - stemcells command requires exactly 1 argument")
+	}
+
+	deploymentManifestPath := args[0]
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentStateService := c.deploymentStateServiceProvider(manifestAbsFilePath)
+	if !deploymentStateService.Exists() {
+		return bosherr.Errorf("No deployment state found at '%s'", deploymentStateService.Path())
+	}
+
+	deploymentState, err := deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	if len(deploymentState.Stemcells) == 0 {
+		c.ui.PrintLinef("No stemcells found")
+		return nil
+	}
+
+	for _, stemcell := range deploymentState.Stemcells {
+		if stemcell.ID == deploymentState.CurrentStemcellID {
+			c.ui.PrintLinef("Stemcell '%s/%s' (current)", stemcell.Name, stemcell.Version)
+		} else {
+			c.ui.PrintLinef("Stemcell '%s/%s'", stemcell.Name, stemcell.Version)
+		}
+		c.ui.PrintLinef("  CID: %s", stemcell.CID)
+	}
+
+	return nil
+}
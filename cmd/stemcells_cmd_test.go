@@ -0,0 +1,86 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("StemcellsCmd", func() {
+	Describe("Run", func() {
+		var (
+			fs        *fakesys.FakeFileSystem
+			logger    boshlog.Logger
+			fakeUI    *fakebiui.FakeUI
+			fakeStage *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+			deploymentStatePath    = "/deployment-dir/fake-deployment-manifest-state.json"
+		)
+
+		var newStemcellsCmd = func() bicmd.Cmd {
+			deploymentStateServiceProvider := func(path string) biconfig.DeploymentStateService {
+				return biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, biconfig.DeploymentStatePath(path))
+			}
+
+			return bicmd.NewStemcellsCmd(fakeUI, fs, deploymentStateServiceProvider, logger)
+		}
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("lists the stemcells of the deployment", func() {
+			fs.WriteFileString(deploymentStatePath, `{
+				"director_id": "fake-director-id",
+				"current_stemcell_id": "fake-stemcell-id-1",
+				"stemcells": [
+					{"id": "fake-stemcell-id-1", "name": "fake-stemcell-name", "version": "1", "cid": "fake-stemcell-cid-1"},
+					{"id": "fake-stemcell-id-2", "name": "fake-stemcell-name", "version": "2", "cid": "fake-stemcell-cid-2"}
+				]
+			}`)
+
+			err := newStemcellsCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(Equal([]string{
+				"Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'",
+				"Stemcell 'fake-stemcell-name/1' (current)",
+				"  CID: fake-stemcell-cid-1",
+				"Stemcell 'fake-stemcell-name/2'",
+				"  CID: fake-stemcell-cid-2",
+			}))
+		})
+
+		It("tells the user when there are no stemcells", func() {
+			fs.WriteFileString(deploymentStatePath, `{"director_id": "fake-director-id"}`)
+
+			err := newStemcellsCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("No stemcells found"))
+		})
+
+		It("returns an error when the deployment state does not exist", func() {
+			err := newStemcellsCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No deployment state found at '/deployment-dir/fake-deployment-manifest-state.json'"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newStemcellsCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type stopCmd struct {
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error)
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	logTag                    string
+}
+
+func NewStopCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return &stopCmd{
+		ui:                        ui,
+		fs:                        fs,
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		logger:                    logger,
+		logTag:                    "stopCmd",
+	}
+}
+
+func (c *stopCmd) Name() string {
+	return "stop"
+}
+
+func (c *stopCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Stop the jobs of the deployed instance (--hard also deletes the VM, keeping its persistent disks)",
+		Usage:    "<deployment_manifest_path> [--hard] [--skip-drain] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *stopCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, hard, skipDrain, forceLock, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return instanceLifecycle.Stop(stage, hard, skipDrain, forceLock)
+}
+
+func (c *stopCmd) parseCmdInputs(args []string) (string, bool, bool, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "stop",
+		positional: 1,
+		flags:      []string{"--hard", "--skip-drain", "--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", false, false, false, err
+	}
+	return parsed.positional[0], parsed.Flag("--hard"), parsed.Flag("--skip-drain"), parsed.Flag("--force-lock"), nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type takeSnapshotCmd struct {
+	deploymentSnapshotterProvider func(deploymentManifestPath string) (DeploymentSnapshotter, error)
+	ui                            biui.UI
+	fs                            boshsys.FileSystem
+	logger                        boshlog.Logger
+	logTag                        string
+}
+
+func NewTakeSnapshotCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	deploymentSnapshotterProvider func(deploymentManifestPath string) (DeploymentSnapshotter, error),
+) Cmd {
+	return &takeSnapshotCmd{
+		ui:                            ui,
+		fs:                            fs,
+		deploymentSnapshotterProvider: deploymentSnapshotterProvider,
+		logger:                        logger,
+		logTag:                        "takeSnapshotCmd",
+	}
+}
+
+func (c *takeSnapshotCmd) Name() string {
+	return "take-snapshot"
+}
+
+func (c *takeSnapshotCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Take a snapshot of the persistent disk of the deployed instance",
+		Usage:    "<deployment_manifest_path> [--disk <name>] [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *takeSnapshotCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, diskName, forceLock, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	deploymentSnapshotter, err := c.deploymentSnapshotterProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	snapshot, taken, err := deploymentSnapshotter.TakeSnapshot(stage, diskName, forceLock)
+	if err != nil {
+		return err
+	}
+
+	if !taken {
+		c.ui.PrintLinef("The CPI does not support disk snapshots")
+		return nil
+	}
+
+	c.ui.PrintLinef("Took snapshot '%s' of disk '%s'", snapshot.CID, snapshot.DiskCID)
+	return nil
+}
+
+func (c *takeSnapshotCmd) parseCmdInputs(args []string) (string, string, bool, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "take-snapshot",
+		positional: 1,
+		flags:      []string{"--force-lock"},
+		options:    []string{"--disk"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return "", "", false, err
+	}
+	return parsed.positional[0], parsed.Option("--disk"), parsed.Flag("--force-lock"), nil
+}
@@ -0,0 +1,111 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("TakeSnapshotCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockDeploymentSnapshotter *mock_cmd.MockDeploymentSnapshotter
+			fs                        *fakesys.FakeFileSystem
+			logger                    boshlog.Logger
+			fakeUI                    *fakebiui.FakeUI
+			fakeStage                 *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newTakeSnapshotCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.DeploymentSnapshotter, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockDeploymentSnapshotter, nil
+			}
+
+			return bicmd.NewTakeSnapshotCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockDeploymentSnapshotter = mock_cmd.NewMockDeploymentSnapshotter(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("takes a snapshot of the persistent disk", func() {
+			snapshot := biconfig.SnapshotRecord{CID: "fake-snapshot-cid", DiskCID: "fake-disk-cid"}
+			mockDeploymentSnapshotter.EXPECT().TakeSnapshot(fakeStage, "", false).Return(snapshot, true, nil)
+
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Took snapshot 'fake-snapshot-cid' of disk 'fake-disk-cid'"))
+		})
+
+		It("takes a snapshot of the persistent disk with the given name", func() {
+			snapshot := biconfig.SnapshotRecord{CID: "fake-snapshot-cid", DiskCID: "fake-disk-cid", DiskName: "data"}
+			mockDeploymentSnapshotter.EXPECT().TakeSnapshot(fakeStage, "data", false).Return(snapshot, true, nil)
+
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "--disk", "data"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("tells the user when the CPI does not support snapshots", func() {
+			mockDeploymentSnapshotter.EXPECT().TakeSnapshot(fakeStage, "", false).Return(biconfig.SnapshotRecord{}, false, nil)
+
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("The CPI does not support disk snapshots"))
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockDeploymentSnapshotter.EXPECT().TakeSnapshot(fakeStage, "", true).Return(biconfig.SnapshotRecord{}, true, nil)
+
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockDeploymentSnapshotter.EXPECT().TakeSnapshot(fakeStage, "", false).Return(biconfig.SnapshotRecord{}, false, bosherr.Error("fake-snapshot-error"))
+
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-snapshot-error"))
+		})
+
+		It("returns an error when the disk option has no value", func() {
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{deploymentManifestPath, "--disk"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("requires a value"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newTakeSnapshotCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type uploadStemcellCmd struct {
+	stemcellUploaderProvider func(deploymentManifestPath string) (StemcellUploader, error)
+	ui                       biui.UI
+	fs                       boshsys.FileSystem
+	logger                   boshlog.Logger
+	logTag                   string
+}
+
+func NewUploadStemcellCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	stemcellUploaderProvider func(deploymentManifestPath string) (StemcellUploader, error),
+) Cmd {
+	return &uploadStemcellCmd{
+		ui:                       ui,
+		fs:                       fs,
+		stemcellUploaderProvider: stemcellUploaderProvider,
+		logger:                   logger,
+		logTag:                   "uploadStemcellCmd",
+	}
+}
+
+func (c *uploadStemcellCmd) Name() string {
+	return "upload-stemcell"
+}
+
+func (c *uploadStemcellCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Upload the stemcell of the deployment manifest without deploying it",
+		Usage:    "<deployment_manifest_path> [--force-lock]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *uploadStemcellCmd) Run(stage biui.Stage, args []string) error {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "upload-stemcell",
+		positional: 1,
+		flags:      []string{"--force-lock"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return err
+	}
+
+	deploymentManifestPath := parsed.positional[0]
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	stemcellUploader, err := c.stemcellUploaderProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	cloudStemcell, err := stemcellUploader.UploadStemcell(stage, parsed.Flag("--force-lock"))
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("Stemcell '%s/%s' is uploaded as '%s', it will be used by the next deploy", cloudStemcell.Name(), cloudStemcell.Version(), cloudStemcell.CID())
+	return nil
+}
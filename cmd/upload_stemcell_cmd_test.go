@@ -0,0 +1,89 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebistemcell "github.com/cloudfoundry/bosh-init/stemcell/fakes"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("UploadStemcellCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockStemcellUploader *mock_cmd.MockStemcellUploader
+			fs                   *fakesys.FakeFileSystem
+			logger               boshlog.Logger
+			fakeUI               *fakebiui.FakeUI
+			fakeStage            *fakebiui.FakeStage
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newUploadStemcellCmd = func() bicmd.Cmd {
+			provider := func(path string) (bicmd.StemcellUploader, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockStemcellUploader, nil
+			}
+
+			return bicmd.NewUploadStemcellCmd(fakeUI, fs, logger, provider)
+		}
+
+		BeforeEach(func() {
+			mockStemcellUploader = mock_cmd.NewMockStemcellUploader(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("uploads the stemcell", func() {
+			cloudStemcell := fakebistemcell.NewFakeCloudStemcell("fake-stemcell-cid", "fake-stemcell-name", "fake-stemcell-version")
+			mockStemcellUploader.EXPECT().UploadStemcell(fakeStage, false).Return(cloudStemcell, nil)
+
+			err := newUploadStemcellCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Stemcell 'fake-stemcell-name/fake-stemcell-version' is uploaded as 'fake-stemcell-cid', it will be used by the next deploy"))
+		})
+
+		It("takes over the locks held by another process when --force-lock is given", func() {
+			mockStemcellUploader.EXPECT().UploadStemcell(fakeStage, true).Return(fakebistemcell.NewFakeCloudStemcell("fake-stemcell-cid", "fake-stemcell-name", "fake-stemcell-version"), nil)
+
+			err := newUploadStemcellCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error of the instance lifecycle", func() {
+			mockStemcellUploader.EXPECT().UploadStemcell(fakeStage, false).Return(nil, bosherr.Error("fake-upload-error"))
+
+			err := newUploadStemcellCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-upload-error"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newUploadStemcellCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"time"
+
+	bihealth "github.com/cloudfoundry/bosh-init/deployment/health"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+type watchCmd struct {
+	ui                         biui.UI
+	fs                         boshsys.FileSystem
+	timeService                clock.Clock
+	logger                     boshlog.Logger
+	instanceLifecycleProvider  func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error)
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error)
+	notifierFactory            bihealth.NotifierFactory
+	monitorFactory             bihealth.MonitorFactory
+	logTag                     string
+}
+
+type watchCmdInputs struct {
+	deploymentManifestPath string
+	interval               time.Duration
+	resurrectAfter         time.Duration
+	notifierConfig         bihealth.NotifierConfig
+}
+
+func NewWatchCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error),
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error),
+	notifierFactory bihealth.NotifierFactory,
+	monitorFactory bihealth.MonitorFactory,
+) Cmd {
+	return &watchCmd{
+		ui:                         ui,
+		fs:                         fs,
+		timeService:                timeService,
+		logger:                     logger,
+		instanceLifecycleProvider:  instanceLifecycleProvider,
+		deploymentPreparerProvider: deploymentPreparerProvider,
+		notifierFactory:            notifierFactory,
+		monitorFactory:             monitorFactory,
+		logTag:                     "watchCmd",
+	}
+}
+
+func (c *watchCmd) Name() string {
+	return "watch"
+}
+
+func (c *watchCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Watch the health of the deployed instance, send alerts and optionally recreate its VM",
+		Usage:    "<deployment_manifest_path> [--interval <duration>] [--resurrect-after <duration>] [--notify-webhook <url>] [--notify-email <address>] [--notify-log <path>]",
+		Env:      genericEnv,
+	}
+}
+
+func (c *watchCmd) Run(stage biui.Stage, args []string) error {
+	inputs, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := deploymentManifestAbsPath(c.ui, c.fs, inputs.deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	// the health checks run every interval, so their progress is not printed
+	quietUI := biui.NewWriterUI(ioutil.Discard, ioutil.Discard, c.logger)
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath, quietUI)
+	if err != nil {
+		return err
+	}
+
+	checker := &instanceHealthChecker{
+		instanceLifecycle: instanceLifecycle,
+		stage:             biui.NewStage(quietUI, c.timeService, c.logger),
+		logger:            c.logger,
+		logTag:            c.logTag,
+	}
+	defer checker.Close()
+
+	resurrector := &deploymentResurrector{
+		deploymentPreparerProvider: c.deploymentPreparerProvider,
+		deploymentManifestPath:     manifestAbsFilePath,
+		stage:                      stage,
+		checker:                    checker,
+	}
+	notifier := c.notifierFactory.NewNotifier(inputs.notifierConfig)
+
+	monitor := c.monitorFactory.NewMonitor(checker, resurrector, notifier, bihealth.MonitorOptions{
+		Name:           manifestAbsFilePath,
+		Interval:       inputs.interval,
+		ResurrectAfter: inputs.resurrectAfter,
+	})
+
+	c.ui.PrintLinef("Checking the deployed instance every %s, press Ctrl+C to stop", inputs.interval)
+	if inputs.resurrectAfter > 0 {
+		c.ui.PrintLinef("Recreating the VM once it has been missing or unresponsive for %s", inputs.resurrectAfter)
+	}
+
+	monitor.Watch(biui.InterruptCh(stage))
+
+	return nil
+}
+
+func (c *watchCmd) parseCmdInputs(args []string) (watchCmdInputs, error) {
+	parsed, err := parseArgs(argsSpec{
+		cmdName:    "watch",
+		positional: 1,
+		options:    []string{"--interval", "--resurrect-after", "--notify-webhook", "--notify-email", "--notify-log"},
+	}, args, c.logger, c.logTag)
+	if err != nil {
+		return watchCmdInputs{}, err
+	}
+
+	inputs := watchCmdInputs{
+		deploymentManifestPath: parsed.positional[0],
+		interval:               defaultWatchInterval,
+		notifierConfig: bihealth.NotifierConfig{
+			WebhookURLs:    parsed.Options("--notify-webhook"),
+			EmailAddresses: parsed.Options("--notify-email"),
+			LogFilePaths:   parsed.Options("--notify-log"),
+		},
+	}
+
+	for _, option := range []string{"--interval", "--resurrect-after"} {
+		value := parsed.Option(option)
+		if value == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+			return watchCmdInputs{}, bosherr.Errorf("Invalid usage - watch command option '%s' requires a positive duration like '30s' or '5m', got '%s'", option, value)
+		}
+
+		if option == "--interval" {
+			inputs.interval = duration
+		} else {
+			inputs.resurrectAfter = duration
+		}
+	}
+
+	return inputs, nil
+}
+
+// instanceHealthChecker checks the deployed instance with a health probe, which is created for the first check
+// and kept for the following ones, so that the CPI is not installed for every check
+type instanceHealthChecker struct {
+	instanceLifecycle InstanceLifecycle
+	stage             biui.Stage
+	probe             HealthProbe
+	logger            boshlog.Logger
+	logTag            string
+}
+
+func (c *instanceHealthChecker) Check() (bihealth.State, error) {
+	if c.probe == nil {
+		// checks do not take over the locks, a deploy or lifecycle command may be running
+		probe, err := c.instanceLifecycle.NewHealthProbe(c.stage, false)
+		if err != nil {
+			return bihealth.StateUnknown, err
+		}
+		c.probe = probe
+	}
+
+	return c.probe.Check()
+}
+
+// Close deletes the CPI installed for the probe, the next check creates a new probe
+func (c *instanceHealthChecker) Close() {
+	if c.probe == nil {
+		return
+	}
+
+	err := c.probe.Close()
+	if err != nil {
+		c.logger.Warn(c.logTag, "Closing health probe: %s", err.Error())
+	}
+	c.probe = nil
+}
+
+// deploymentResurrector recreates the deployed VM the same way the recreate command does
+type deploymentResurrector struct {
+	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error)
+	deploymentManifestPath     string
+	stage                      biui.Stage
+	checker                    *instanceHealthChecker
+}
+
+func (r *deploymentResurrector) Resurrect() error {
+	// deploying installs the CPI to the same installation and deletes its rendered jobs afterwards
+	r.checker.Close()
+
+	err := r.recreate()
+	if bilock.IsHeld(err) {
+		return bihealth.NewResurrectionSkippedError(err)
+	}
+
+	return err
+}
+
+// recreate never takes over the locks from a deploy or lifecycle command that may be running,
+// and only recreates the recorded deployment, changes to the manifest are left to the next deploy
+func (r *deploymentResurrector) recreate() error {
+	deploymentPreparer, err := r.deploymentPreparerProvider(r.deploymentManifestPath)
+	if err != nil {
+		return err
+	}
+
+	// the agent is unresponsive, so the drain scripts cannot be run
+	return deploymentPreparer.PrepareDeployment(r.stage, DeployOptions{Recreate: true, SkipDrain: true, RecordedDeploymentOnly: true})
+}
@@ -0,0 +1,301 @@
+package cmd_test
+
+import (
+	"time"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bihealth "github.com/cloudfoundry/bosh-init/deployment/health"
+	mock_health "github.com/cloudfoundry/bosh-init/deployment/health/mocks"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("WatchCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+			mockNotifierFactory   *mock_health.MockNotifierFactory
+			mockNotifier          *mock_health.MockNotifier
+			mockMonitorFactory    *mock_health.MockMonitorFactory
+			mockMonitor           *mock_health.MockMonitor
+			fs                    *fakesys.FakeFileSystem
+			logger                boshlog.Logger
+			fakeUI                *fakebiui.FakeUI
+			fakeStage             *fakebiui.FakeStage
+
+			deploymentPreparerPaths []string
+			deploymentPreparerErr   error
+
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		)
+
+		var newWatchCmd = func() bicmd.Cmd {
+			instanceLifecycleProvider := func(path string, _ biui.UI) (bicmd.InstanceLifecycle, error) {
+				Expect(path).To(Equal(deploymentManifestPath))
+				return mockInstanceLifecycle, nil
+			}
+
+			deploymentPreparerProvider := func(path string) (bicmd.DeploymentPreparer, error) {
+				deploymentPreparerPaths = append(deploymentPreparerPaths, path)
+				return bicmd.DeploymentPreparer{}, deploymentPreparerErr
+			}
+
+			timeService := fakeclock.NewFakeClock(time.Now())
+
+			return bicmd.NewWatchCmd(
+				fakeUI,
+				fs,
+				timeService,
+				logger,
+				instanceLifecycleProvider,
+				deploymentPreparerProvider,
+				mockNotifierFactory,
+				mockMonitorFactory,
+			)
+		}
+
+		BeforeEach(func() {
+			mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+			mockNotifierFactory = mock_health.NewMockNotifierFactory(mockCtrl)
+			mockNotifier = mock_health.NewMockNotifier(mockCtrl)
+			mockMonitorFactory = mock_health.NewMockMonitorFactory(mockCtrl)
+			mockMonitor = mock_health.NewMockMonitor(mockCtrl)
+			fs = fakesys.NewFakeFileSystem()
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fakeUI = &fakebiui.FakeUI{}
+			fakeStage = fakebiui.NewFakeStage()
+			deploymentPreparerPaths = []string{}
+			deploymentPreparerErr = bosherr.Error("fake-preparer-error")
+
+			fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+		})
+
+		It("watches the deployed instance until the stage is interrupted", func() {
+			fakeStage.Interrupt = make(chan struct{})
+
+			mockNotifierFactory.EXPECT().NewNotifier(bihealth.NotifierConfig{}).Return(mockNotifier)
+			mockMonitorFactory.EXPECT().NewMonitor(gomock.Any(), gomock.Any(), mockNotifier, bihealth.MonitorOptions{
+				Name:     deploymentManifestPath,
+				Interval: 30 * time.Second,
+			}).Return(mockMonitor)
+			mockMonitor.EXPECT().Watch(gomock.Any()).Do(func(stopCh <-chan struct{}) {
+				Expect(stopCh).ToNot(BeClosed())
+				close(fakeStage.Interrupt)
+				Expect(stopCh).To(BeClosed())
+			})
+
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'"))
+			Expect(fakeUI.Said).To(ContainElement("Checking the deployed instance every 30s, press Ctrl+C to stop"))
+		})
+
+		It("configures the interval, resurrection and notifiers", func() {
+			mockNotifierFactory.EXPECT().NewNotifier(bihealth.NotifierConfig{
+				WebhookURLs:    []string{"http://fake-webhook-1", "http://fake-webhook-2"},
+				EmailAddresses: []string{"ops@example.com"},
+				LogFilePaths:   []string{"/fake-alerts.log"},
+			}).Return(mockNotifier)
+			mockMonitorFactory.EXPECT().NewMonitor(gomock.Any(), gomock.Any(), mockNotifier, bihealth.MonitorOptions{
+				Name:           deploymentManifestPath,
+				Interval:       10 * time.Second,
+				ResurrectAfter: 5 * time.Minute,
+			}).Return(mockMonitor)
+			mockMonitor.EXPECT().Watch(gomock.Any())
+
+			err := newWatchCmd().Run(fakeStage, []string{
+				deploymentManifestPath,
+				"--interval", "10s",
+				"--resurrect-after", "5m",
+				"--notify-webhook", "http://fake-webhook-1",
+				"--notify-webhook", "http://fake-webhook-2",
+				"--notify-email", "ops@example.com",
+				"--notify-log", "/fake-alerts.log",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Recreating the VM once it has been missing or unresponsive for 5m0s"))
+		})
+
+		Describe("the health checker", func() {
+			var checker bihealth.Checker
+
+			BeforeEach(func() {
+				mockNotifierFactory.EXPECT().NewNotifier(gomock.Any()).Return(mockNotifier)
+				mockMonitorFactory.EXPECT().NewMonitor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+					func(c bihealth.Checker, _ bihealth.Resurrector, _ bihealth.Notifier, _ bihealth.MonitorOptions) {
+						checker = c
+					},
+				).Return(mockMonitor)
+				mockMonitor.EXPECT().Watch(gomock.Any())
+
+				err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("creates the health probe once and checks with it every time", func() {
+				mockProbe := mock_cmd.NewMockHealthProbe(mockCtrl)
+				mockInstanceLifecycle.EXPECT().NewHealthProbe(gomock.Any(), false).Return(mockProbe, nil).Times(1)
+
+				gomock.InOrder(
+					mockProbe.EXPECT().Check().Return(bihealth.StateRunning, nil),
+					mockProbe.EXPECT().Check().Return(bihealth.StateMissing, nil),
+				)
+
+				state, err := checker.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(bihealth.StateRunning))
+
+				state, err = checker.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(bihealth.StateMissing))
+			})
+
+			It("returns an unknown state and retries on the next check when creating the probe fails", func() {
+				mockProbe := mock_cmd.NewMockHealthProbe(mockCtrl)
+				gomock.InOrder(
+					mockInstanceLifecycle.EXPECT().NewHealthProbe(gomock.Any(), false).Return(nil, bosherr.Error("fake-probe-error")),
+					mockInstanceLifecycle.EXPECT().NewHealthProbe(gomock.Any(), false).Return(mockProbe, nil),
+				)
+				mockProbe.EXPECT().Check().Return(bihealth.StateRunning, nil)
+
+				state, err := checker.Check()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("fake-probe-error"))
+				Expect(state).To(Equal(bihealth.StateUnknown))
+
+				state, err = checker.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(bihealth.StateRunning))
+			})
+		})
+
+		It("recreates the VM by preparing a deployment of the manifest", func() {
+			var resurrector bihealth.Resurrector
+			mockNotifierFactory.EXPECT().NewNotifier(gomock.Any()).Return(mockNotifier)
+			mockMonitorFactory.EXPECT().NewMonitor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(_ bihealth.Checker, r bihealth.Resurrector, _ bihealth.Notifier, _ bihealth.MonitorOptions) {
+					resurrector = r
+				},
+			).Return(mockMonitor)
+			mockMonitor.EXPECT().Watch(gomock.Any())
+
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = resurrector.Resurrect()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-preparer-error"))
+			Expect(deploymentPreparerPaths).To(Equal([]string{deploymentManifestPath}))
+		})
+
+		It("skips recreating the VM when another process holds the locks", func() {
+			deploymentPreparerErr = bosherr.WrapError(bilock.HeldError{Path: "/fake-deployment-state.json.lock"}, "fake-wrapping-error")
+
+			var resurrector bihealth.Resurrector
+			mockNotifierFactory.EXPECT().NewNotifier(gomock.Any()).Return(mockNotifier)
+			mockMonitorFactory.EXPECT().NewMonitor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(_ bihealth.Checker, r bihealth.Resurrector, _ bihealth.Notifier, _ bihealth.MonitorOptions) {
+					resurrector = r
+				},
+			).Return(mockMonitor)
+			mockMonitor.EXPECT().Watch(gomock.Any())
+
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = resurrector.Resurrect()
+			Expect(err).To(BeAssignableToTypeOf(bihealth.ResurrectionSkippedError{}))
+		})
+
+		It("does not accept --force-lock, the locks held by other processes are never taken over", func() {
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath, "--force-lock"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("closes the health probe before recreating the VM and creates a new one for the next check", func() {
+			var (
+				checker     bihealth.Checker
+				resurrector bihealth.Resurrector
+			)
+			mockNotifierFactory.EXPECT().NewNotifier(gomock.Any()).Return(mockNotifier)
+			mockMonitorFactory.EXPECT().NewMonitor(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(c bihealth.Checker, r bihealth.Resurrector, _ bihealth.Notifier, _ bihealth.MonitorOptions) {
+					checker = c
+					resurrector = r
+				},
+			).Return(mockMonitor)
+
+			firstProbe := mock_cmd.NewMockHealthProbe(mockCtrl)
+			secondProbe := mock_cmd.NewMockHealthProbe(mockCtrl)
+			gomock.InOrder(
+				mockInstanceLifecycle.EXPECT().NewHealthProbe(gomock.Any(), false).Return(firstProbe, nil),
+				firstProbe.EXPECT().Check().Return(bihealth.StateMissing, nil),
+				firstProbe.EXPECT().Close().Return(nil),
+				mockInstanceLifecycle.EXPECT().NewHealthProbe(gomock.Any(), false).Return(secondProbe, nil),
+				secondProbe.EXPECT().Check().Return(bihealth.StateRunning, nil),
+				secondProbe.EXPECT().Close().Return(nil),
+			)
+
+			mockMonitor.EXPECT().Watch(gomock.Any()).Do(func(<-chan struct{}) {
+				state, err := checker.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(bihealth.StateMissing))
+
+				err = resurrector.Resurrect()
+				Expect(err).To(HaveOccurred())
+
+				state, err = checker.Check()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(bihealth.StateRunning))
+			})
+
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when a duration is invalid", func() {
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath, "--interval", "soon"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage - watch command option '--interval' requires a positive duration"))
+		})
+
+		It("returns an error when an option has no value", func() {
+			err := newWatchCmd().Run(fakeStage, []string{deploymentManifestPath, "--notify-webhook"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("requires a value"))
+		})
+
+		It("returns an error when the deployment manifest does not exist", func() {
+			err := newWatchCmd().Run(fakeStage, []string{"/garbage"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment manifest does not exist at '/garbage'"))
+		})
+
+		It("returns err unless exactly 1 argument is given", func() {
+			err := newWatchCmd().Run(fakeStage, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	bilock "github.com/cloudfoundry/bosh-init/lock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// acquireLock prevents other bosh-init processes from changing the deployment state or the installation
+// described by description while the returned lock is held
+func acquireLock(locker bilock.Locker, path string, description string, force bool) (bilock.Lock, error) {
+	lock, err := locker.Lock(path, force)
+	if err != nil {
+		if _, held := err.(bilock.HeldError); held {
+			return nil, bosherr.WrapErrorf(err, "Another bosh-init process is using the %s, use --force-lock if it is no longer running", description)
+		}
+		return nil, bosherr.WrapErrorf(err, "Locking the %s", description)
+	}
+
+	return lock, nil
+}
+
+func releaseLock(lock bilock.Lock, logger boshlog.Logger, logTag string) {
+	err := lock.Release()
+	if err != nil {
+		logger.Warn(logTag, "Releasing lock: %s", err.Error())
+	}
+}
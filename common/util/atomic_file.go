@@ -0,0 +1,65 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// WriteFileAtomically writes contents to a temporary file next to path, flushes it to disk and renames it to path,
+// so that path contains either the old or the new contents, even after a crash.
+// The file gets the given mode before any contents are written to it.
+func WriteFileAtomically(fs boshsys.FileSystem, path string, contents []byte, mode os.FileMode) error {
+	tmpPath := path + ".tmp"
+
+	err := fs.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating directory for '%s'", path)
+	}
+
+	file, err := fs.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening '%s'", tmpPath)
+	}
+
+	err = writeAndSync(fs, file, tmpPath, contents, mode)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = bosherr.WrapErrorf(closeErr, "Closing '%s'", tmpPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = fs.Rename(tmpPath, path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Renaming '%s' to '%s'", tmpPath, path)
+	}
+
+	return nil
+}
+
+func writeAndSync(fs boshsys.FileSystem, file boshsys.File, path string, contents []byte, mode os.FileMode) error {
+	// a temporary file left behind by an earlier crash keeps its mode when it is opened again
+	err := fs.Chmod(path, mode)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Changing permissions of '%s'", path)
+	}
+
+	_, err = file.Write(contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing '%s'", path)
+	}
+
+	if syncer, ok := file.(interface {
+		Sync() error
+	}); ok {
+		err = syncer.Sync()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Syncing '%s'", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package util_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-init/common/util"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteFileAtomically", func() {
+	var (
+		fs     boshsys.FileSystem
+		tmpDir string
+		path   string
+	)
+
+	BeforeEach(func() {
+		fs = boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+
+		var err error
+		tmpDir, err = ioutil.TempDir("", "atomic-file")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(tmpDir, "dir", "file.json")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("writes the contents with the mode and removes the temporary file", func() {
+		err := util.WriteFileAtomically(fs, path, []byte("fake-contents"), os.FileMode(0600))
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("fake-contents"))
+
+		info, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		_, err = os.Stat(path + ".tmp")
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("replaces existing contents and resets the mode of a leftover temporary file", func() {
+		err := util.WriteFileAtomically(fs, path, []byte("fake-old-contents"), os.FileMode(0600))
+		Expect(err).ToNot(HaveOccurred())
+		err = ioutil.WriteFile(path+".tmp", []byte("fake-leftover-contents-that-are-longer"), os.FileMode(0644))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = util.WriteFileAtomically(fs, path, []byte("fake-new-contents"), os.FileMode(0600))
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("fake-new-contents"))
+
+		info, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+})
@@ -0,0 +1,220 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type CloudMigrationRepo interface {
+	// StartMove prepares moving the deployment to cloudProvider. The current VM, disks, stemcells and snapshots
+	// stay current; the resources created with the CPI of cloudProvider are recorded in the cloud migration target
+	// until CompleteMove. Returns false if there is nothing to move, then cloudProvider is recorded right away.
+	//
+	// Returning to the previous cloud provider while its resources are kept for clean-up reuses them for the move.
+	// Deploying the current cloud provider again while a move is in progress abandons the move,
+	// the resources created for it are then kept for clean-up.
+	StartMove(cloudProvider string) (move CloudMigrationTargetRecord, moving bool, err error)
+
+	// CompleteMove makes the resources of the cloud migration target the current resources, once its VM is running.
+	// The previous resources move to the returned cloud migration record until clean-up deletes them.
+	CompleteMove() (CloudMigrationRecord, error)
+
+	// AbandonMove moves the resources of the cloud migration target to the cloud migration record,
+	// so that clean-up or delete deletes them. Returns false if no move is in progress.
+	AbandonMove() (CloudMigrationRecord, bool, error)
+
+	Find() (CloudMigrationRecord, bool, error)
+	Save(CloudMigrationRecord) error
+	Clear() error
+}
+
+type cloudMigrationRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewCloudMigrationRepo(deploymentStateService DeploymentStateService) CloudMigrationRepo {
+	return cloudMigrationRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r cloudMigrationRepo) StartMove(cloudProvider string) (CloudMigrationTargetRecord, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return CloudMigrationTargetRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	target := deploymentState.CloudMigrationTarget
+	if target != nil {
+		if target.CloudProvider == cloudProvider {
+			return *target, true, nil
+		}
+
+		if deploymentState.CloudProvider != cloudProvider {
+			return CloudMigrationTargetRecord{}, false, bosherr.Errorf("The deployment is moving to another cloud provider, deploy to that cloud provider or back to the current one first")
+		}
+
+		abandonMove(&deploymentState)
+		return CloudMigrationTargetRecord{}, false, r.save(deploymentState)
+	}
+
+	if deploymentState.CloudProvider == cloudProvider {
+		return CloudMigrationTargetRecord{}, false, nil
+	}
+
+	hasResources := deploymentState.CurrentVMCID != "" || len(deploymentState.Disks) > 0 || len(deploymentState.Stemcells) > 0 || len(deploymentState.Snapshots) > 0
+	if !hasResources {
+		deploymentState.CloudProvider = cloudProvider
+		return CloudMigrationTargetRecord{}, false, r.save(deploymentState)
+	}
+
+	if deploymentState.CurrentDiskMigration != nil {
+		return CloudMigrationTargetRecord{}, false, bosherr.Errorf("The migration of disk '%s' was interrupted, finish it before moving the deployment to another cloud provider", deploymentState.CurrentDiskMigration.OriginalDiskCID)
+	}
+
+	target = &CloudMigrationTargetRecord{
+		CloudProvider: cloudProvider,
+		Disks:         []DiskRecord{},
+		Stemcells:     []StemcellRecord{},
+	}
+
+	// the resources of only one previous cloud provider are kept
+	if previous := deploymentState.CloudMigration; previous != nil {
+		if previous.CloudProvider != cloudProvider {
+			return CloudMigrationTargetRecord{}, false, bosherr.Error("The resources of the previous cloud provider are not deleted yet, run 'bosh-init clean-up' before moving the deployment to another cloud provider")
+		}
+
+		// returning to the previous cloud provider: the deploy updates or replaces its VM,
+		// its disks are replaced by the copies of the current ones
+		target.CurrentVMCID = previous.VMCID
+		target.Disks = previous.Disks
+		target.Stemcells = previous.Stemcells
+		target.Snapshots = previous.Snapshots
+		deploymentState.CloudMigration = nil
+	}
+
+	deploymentState.CloudMigrationTarget = target
+	err = r.save(deploymentState)
+	if err != nil {
+		return CloudMigrationTargetRecord{}, false, err
+	}
+
+	return *target, true, nil
+}
+
+func (r cloudMigrationRepo) CompleteMove() (CloudMigrationRecord, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return CloudMigrationRecord{}, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	target := deploymentState.CloudMigrationTarget
+	if target == nil {
+		return CloudMigrationRecord{}, bosherr.Error("No move to another cloud provider in progress")
+	}
+
+	record := CloudMigrationRecord{
+		CloudProvider: deploymentState.CloudProvider,
+		VMCID:         deploymentState.CurrentVMCID,
+		Disks:         deploymentState.Disks,
+		Stemcells:     deploymentState.Stemcells,
+		Snapshots:     deploymentState.Snapshots,
+	}
+
+	deploymentState.CloudMigration = &record
+	deploymentState.CloudMigrationTarget = nil
+	deploymentState.CloudProvider = target.CloudProvider
+	deploymentState.CurrentVMCID = target.CurrentVMCID
+	deploymentState.CurrentVMConfig = target.CurrentVMConfig
+	deploymentState.CurrentStemcellID = target.CurrentStemcellID
+	deploymentState.CurrentDiskID = target.CurrentDiskID
+	deploymentState.CurrentNamedDiskIDs = target.CurrentNamedDiskIDs
+	deploymentState.CurrentDiskMigration = target.CurrentDiskMigration
+	deploymentState.Disks = target.Disks
+	deploymentState.Stemcells = target.Stemcells
+	deploymentState.Snapshots = target.Snapshots
+
+	err = r.save(deploymentState)
+	if err != nil {
+		return CloudMigrationRecord{}, err
+	}
+
+	return record, nil
+}
+
+func (r cloudMigrationRepo) AbandonMove() (CloudMigrationRecord, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return CloudMigrationRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	if deploymentState.CloudMigrationTarget == nil {
+		return CloudMigrationRecord{}, false, nil
+	}
+
+	abandonMove(&deploymentState)
+
+	err = r.save(deploymentState)
+	if err != nil {
+		return CloudMigrationRecord{}, false, err
+	}
+
+	return *deploymentState.CloudMigration, true, nil
+}
+
+func (r cloudMigrationRepo) Find() (CloudMigrationRecord, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return CloudMigrationRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	if deploymentState.CloudMigration == nil {
+		return CloudMigrationRecord{}, false, nil
+	}
+
+	return *deploymentState.CloudMigration, true, nil
+}
+
+func (r cloudMigrationRepo) Save(record CloudMigrationRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.CloudMigration = &record
+
+	return r.save(deploymentState)
+}
+
+func (r cloudMigrationRepo) Clear() error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.CloudMigration = nil
+
+	return r.save(deploymentState)
+}
+
+func (r cloudMigrationRepo) save(deploymentState DeploymentState) error {
+	err := r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+	return nil
+}
+
+// abandonMove moves the resources of the cloud migration target to the cloud migration record.
+// Starting a move takes over the cloud migration record, so there is none while a move is in progress.
+func abandonMove(deploymentState *DeploymentState) {
+	target := deploymentState.CloudMigrationTarget
+
+	deploymentState.CloudMigration = &CloudMigrationRecord{
+		CloudProvider: target.CloudProvider,
+		VMCID:         target.CurrentVMCID,
+		Disks:         target.Disks,
+		Stemcells:     target.Stemcells,
+		Snapshots:     target.Snapshots,
+	}
+	deploymentState.CloudMigrationTarget = nil
+}
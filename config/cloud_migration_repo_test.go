@@ -0,0 +1,309 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("CloudMigrationRepo", func() {
+	var (
+		deploymentStateService DeploymentStateService
+		repo                   CloudMigrationRepo
+		deploymentState        DeploymentState
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, &fakeuuid.FakeGenerator{}, logger, "/fake/path")
+		repo = NewCloudMigrationRepo(deploymentStateService)
+
+		deploymentState = DeploymentState{
+			DirectorID:          "fake-director-id",
+			CloudProvider:       "fake-source-cloud-provider",
+			CurrentVMCID:        "fake-vm-cid",
+			CurrentVMConfig:     &VMConfigRecord{StemcellCID: "fake-stemcell-cid"},
+			CurrentStemcellID:   "fake-stemcell-id",
+			CurrentDiskID:       "fake-disk-id",
+			CurrentNamedDiskIDs: map[string]string{"fake-disk-name": "fake-named-disk-id"},
+			Disks: []DiskRecord{
+				{ID: "fake-disk-id", CID: "fake-disk-cid"},
+				{ID: "fake-named-disk-id", Name: "fake-disk-name", CID: "fake-named-disk-cid"},
+			},
+			Stemcells: []StemcellRecord{{ID: "fake-stemcell-id", CID: "fake-stemcell-cid"}},
+			Snapshots: []SnapshotRecord{{ID: "fake-snapshot-id", CID: "fake-snapshot-cid", DiskCID: "fake-disk-cid"}},
+		}
+	})
+
+	Describe("StartMove", func() {
+		It("keeps the resources of the current cloud provider current and records the move", func() {
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			move, moving, err := repo.StartMove("fake-target-cloud-provider")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(moving).To(BeTrue())
+			Expect(move).To(Equal(CloudMigrationTargetRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				Disks:         []DiskRecord{},
+				Stemcells:     []StemcellRecord{},
+			}))
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudProvider).To(Equal("fake-source-cloud-provider"))
+			Expect(savedState.CloudMigrationTarget).To(Equal(&move))
+			Expect(savedState.CloudMigration).To(BeNil())
+			Expect(savedState.CurrentVMCID).To(Equal("fake-vm-cid"))
+			Expect(savedState.CurrentDiskID).To(Equal("fake-disk-id"))
+			Expect(savedState.CurrentNamedDiskIDs).To(Equal(deploymentState.CurrentNamedDiskIDs))
+			Expect(savedState.Disks).To(Equal(deploymentState.Disks))
+			Expect(savedState.Snapshots).To(Equal(deploymentState.Snapshots))
+		})
+
+		It("resumes the move to the same cloud provider", func() {
+			deploymentState.CloudMigrationTarget = &CloudMigrationTargetRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				CurrentVMCID:  "fake-target-vm-cid",
+				Disks:         []DiskRecord{},
+				Stemcells:     []StemcellRecord{},
+			}
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			move, moving, err := repo.StartMove("fake-target-cloud-provider")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(moving).To(BeTrue())
+			Expect(move.CurrentVMCID).To(Equal("fake-target-vm-cid"))
+		})
+
+		It("abandons the move when deploying the current cloud provider again", func() {
+			deploymentState.CloudMigrationTarget = &CloudMigrationTargetRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				CurrentVMCID:  "fake-target-vm-cid",
+				Disks:         []DiskRecord{{ID: "fake-target-disk-id", CID: "fake-target-disk-cid"}},
+				Stemcells:     []StemcellRecord{},
+			}
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, moving, err := repo.StartMove("fake-source-cloud-provider")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(moving).To(BeFalse())
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudMigrationTarget).To(BeNil())
+			Expect(savedState.CloudMigration).To(Equal(&CloudMigrationRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				VMCID:         "fake-target-vm-cid",
+				Disks:         []DiskRecord{{ID: "fake-target-disk-id", CID: "fake-target-disk-cid"}},
+				Stemcells:     []StemcellRecord{},
+			}))
+			Expect(savedState.CurrentVMCID).To(Equal("fake-vm-cid"))
+		})
+
+		It("returns an error when the deployment is moving to a third cloud provider", func() {
+			deploymentState.CloudMigrationTarget = &CloudMigrationTargetRecord{CloudProvider: "fake-target-cloud-provider"}
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = repo.StartMove("fake-other-cloud-provider")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("The deployment is moving to another cloud provider"))
+		})
+
+		It("reuses the kept resources when moving back to the previous cloud provider", func() {
+			previous := CloudMigrationRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				VMCID:         "fake-previous-vm-cid",
+				Disks:         []DiskRecord{{ID: "fake-previous-disk-id", CID: "fake-previous-disk-cid"}},
+				Stemcells:     []StemcellRecord{{ID: "fake-previous-stemcell-id", CID: "fake-previous-stemcell-cid"}},
+			}
+			deploymentState.CloudMigration = &previous
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			move, moving, err := repo.StartMove("fake-target-cloud-provider")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(moving).To(BeTrue())
+			Expect(move).To(Equal(CloudMigrationTargetRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				CurrentVMCID:  "fake-previous-vm-cid",
+				Disks:         previous.Disks,
+				Stemcells:     previous.Stemcells,
+			}))
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudMigration).To(BeNil())
+			Expect(savedState.CurrentVMCID).To(Equal("fake-vm-cid"))
+		})
+
+		It("does nothing when the cloud provider does not change", func() {
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, moving, err := repo.StartMove("fake-source-cloud-provider")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(moving).To(BeFalse())
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CurrentVMCID).To(Equal("fake-vm-cid"))
+			Expect(savedState.CloudMigrationTarget).To(BeNil())
+		})
+
+		It("only changes the cloud provider when no resources are recorded", func() {
+			err := deploymentStateService.Save(DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, moving, err := repo.StartMove("fake-target-cloud-provider")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(moving).To(BeFalse())
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudProvider).To(Equal("fake-target-cloud-provider"))
+			Expect(savedState.CloudMigrationTarget).To(BeNil())
+		})
+
+		It("returns an error when the resources of another previous cloud provider are not deleted yet", func() {
+			deploymentState.CloudMigration = &CloudMigrationRecord{CloudProvider: "fake-older-cloud-provider", VMCID: "fake-older-vm-cid"}
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = repo.StartMove("fake-target-cloud-provider")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("run 'bosh-init clean-up' before moving the deployment to another cloud provider"))
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudProvider).To(Equal("fake-source-cloud-provider"))
+			Expect(savedState.CloudMigrationTarget).To(BeNil())
+		})
+
+		It("returns an error when a disk migration was interrupted", func() {
+			deploymentState.CurrentDiskMigration = &DiskMigrationRecord{OriginalDiskCID: "fake-disk-cid"}
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = repo.StartMove("fake-target-cloud-provider")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("The migration of disk 'fake-disk-cid' was interrupted"))
+		})
+	})
+
+	Context("when a move is in progress", func() {
+		var target CloudMigrationTargetRecord
+
+		BeforeEach(func() {
+			target = CloudMigrationTargetRecord{
+				CloudProvider:       "fake-target-cloud-provider",
+				CurrentVMCID:        "fake-target-vm-cid",
+				CurrentVMConfig:     &VMConfigRecord{StemcellCID: "fake-target-stemcell-cid"},
+				CurrentStemcellID:   "fake-target-stemcell-id",
+				CurrentNamedDiskIDs: map[string]string{"fake-disk-name": "fake-target-disk-id"},
+				Disks:               []DiskRecord{{ID: "fake-target-disk-id", Name: "fake-disk-name", CID: "fake-target-disk-cid"}},
+				Stemcells:           []StemcellRecord{{ID: "fake-target-stemcell-id", CID: "fake-target-stemcell-cid"}},
+			}
+			deploymentState.CloudMigrationTarget = &target
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("makes the resources of the move current once it completes and keeps the previous ones", func() {
+			record, err := repo.CompleteMove()
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedRecord := CloudMigrationRecord{
+				CloudProvider: "fake-source-cloud-provider",
+				VMCID:         "fake-vm-cid",
+				Disks:         deploymentState.Disks,
+				Stemcells:     deploymentState.Stemcells,
+				Snapshots:     deploymentState.Snapshots,
+			}
+			Expect(record).To(Equal(expectedRecord))
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudProvider).To(Equal("fake-target-cloud-provider"))
+			Expect(savedState.CloudMigration).To(Equal(&expectedRecord))
+			Expect(savedState.CloudMigrationTarget).To(BeNil())
+			Expect(savedState.CurrentVMCID).To(Equal("fake-target-vm-cid"))
+			Expect(savedState.CurrentVMConfig).To(Equal(target.CurrentVMConfig))
+			Expect(savedState.CurrentStemcellID).To(Equal("fake-target-stemcell-id"))
+			Expect(savedState.CurrentDiskID).To(BeEmpty())
+			Expect(savedState.CurrentNamedDiskIDs).To(Equal(target.CurrentNamedDiskIDs))
+			Expect(savedState.Disks).To(Equal(target.Disks))
+			Expect(savedState.Stemcells).To(Equal(target.Stemcells))
+			Expect(savedState.Snapshots).To(BeEmpty())
+			Expect(savedState.DirectorID).To(Equal("fake-director-id"))
+		})
+
+		It("keeps the resources of the move for clean-up when it is abandoned", func() {
+			record, abandoned, err := repo.AbandonMove()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(abandoned).To(BeTrue())
+			Expect(record).To(Equal(CloudMigrationRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				VMCID:         "fake-target-vm-cid",
+				Disks:         target.Disks,
+				Stemcells:     target.Stemcells,
+			}))
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CloudProvider).To(Equal("fake-source-cloud-provider"))
+			Expect(savedState.CloudMigration).To(Equal(&record))
+			Expect(savedState.CloudMigrationTarget).To(BeNil())
+			Expect(savedState.CurrentVMCID).To(Equal("fake-vm-cid"))
+		})
+	})
+
+	It("does not abandon or complete a move when none is in progress", func() {
+		err := deploymentStateService.Save(deploymentState)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, abandoned, err := repo.AbandonMove()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(abandoned).To(BeFalse())
+
+		_, err = repo.CompleteMove()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("No move to another cloud provider in progress"))
+	})
+
+	It("finds no cloud migration when none was saved", func() {
+		_, found, err := repo.Find()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("finds, replaces and clears the saved cloud migration", func() {
+		record := CloudMigrationRecord{CloudProvider: "fake-source-cloud-provider", VMCID: "fake-vm-cid"}
+		err := repo.Save(record)
+		Expect(err).ToNot(HaveOccurred())
+
+		record.VMCID = ""
+		err = repo.Save(record)
+		Expect(err).ToNot(HaveOccurred())
+
+		foundRecord, found, err := repo.Find()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(foundRecord).To(Equal(record))
+
+		err = repo.Clear()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, found, err = repo.Find()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+})
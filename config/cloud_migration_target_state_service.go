@@ -0,0 +1,75 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type cloudMigrationTargetStateService struct {
+	DeploymentStateService
+}
+
+// NewCloudMigrationTargetStateService returns a deployment state service that loads and saves
+// the resources of the cloud migration target as if they were the current resources,
+// so that the repos built on it record the resources created with the CPI of the cloud provider the deployment moves to.
+// The director, installation, releases and manifest stay the ones of the deployment.
+func NewCloudMigrationTargetStateService(deploymentStateService DeploymentStateService) DeploymentStateService {
+	return cloudMigrationTargetStateService{
+		DeploymentStateService: deploymentStateService,
+	}
+}
+
+func (s cloudMigrationTargetStateService) Load() (DeploymentState, error) {
+	deploymentState, err := s.DeploymentStateService.Load()
+	if err != nil {
+		return DeploymentState{}, err
+	}
+
+	target := deploymentState.CloudMigrationTarget
+	if target == nil {
+		return DeploymentState{}, bosherr.Error("No move to another cloud provider in progress")
+	}
+
+	return DeploymentState{
+		DirectorID:           deploymentState.DirectorID,
+		InstallationID:       deploymentState.InstallationID,
+		CurrentVMCID:         target.CurrentVMCID,
+		CurrentVMConfig:      target.CurrentVMConfig,
+		CurrentStemcellID:    target.CurrentStemcellID,
+		CurrentDiskID:        target.CurrentDiskID,
+		CurrentNamedDiskIDs:  target.CurrentNamedDiskIDs,
+		CurrentDiskMigration: target.CurrentDiskMigration,
+		CurrentReleaseIDs:    deploymentState.CurrentReleaseIDs,
+		CurrentManifestSHA1:  deploymentState.CurrentManifestSHA1,
+		CloudProvider:        target.CloudProvider,
+		Disks:                target.Disks,
+		Stemcells:            target.Stemcells,
+		Releases:             deploymentState.Releases,
+		Snapshots:            target.Snapshots,
+	}, nil
+}
+
+func (s cloudMigrationTargetStateService) Save(targetState DeploymentState) error {
+	deploymentState, err := s.DeploymentStateService.Load()
+	if err != nil {
+		return err
+	}
+
+	if deploymentState.CloudMigrationTarget == nil {
+		return bosherr.Error("No move to another cloud provider in progress")
+	}
+
+	deploymentState.CloudMigrationTarget = &CloudMigrationTargetRecord{
+		CloudProvider:        deploymentState.CloudMigrationTarget.CloudProvider,
+		CurrentVMCID:         targetState.CurrentVMCID,
+		CurrentVMConfig:      targetState.CurrentVMConfig,
+		CurrentStemcellID:    targetState.CurrentStemcellID,
+		CurrentDiskID:        targetState.CurrentDiskID,
+		CurrentNamedDiskIDs:  targetState.CurrentNamedDiskIDs,
+		CurrentDiskMigration: targetState.CurrentDiskMigration,
+		Disks:                targetState.Disks,
+		Stemcells:            targetState.Stemcells,
+		Snapshots:            targetState.Snapshots,
+	}
+
+	return s.DeploymentStateService.Save(deploymentState)
+}
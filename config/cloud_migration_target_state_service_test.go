@@ -0,0 +1,99 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("CloudMigrationTargetStateService", func() {
+	var (
+		deploymentStateService DeploymentStateService
+		targetStateService     DeploymentStateService
+		deploymentState        DeploymentState
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, &fakeuuid.FakeGenerator{}, logger, "/fake/path")
+		targetStateService = NewCloudMigrationTargetStateService(deploymentStateService)
+
+		deploymentState = DeploymentState{
+			DirectorID:          "fake-director-id",
+			InstallationID:      "fake-installation-id",
+			CloudProvider:       "fake-source-cloud-provider",
+			CurrentVMCID:        "fake-vm-cid",
+			CurrentReleaseIDs:   []string{"fake-release-id"},
+			CurrentManifestSHA1: "fake-manifest-sha1",
+			Disks:               []DiskRecord{{ID: "fake-disk-id", CID: "fake-disk-cid"}},
+			Stemcells:           []StemcellRecord{},
+			Releases:            []ReleaseRecord{{ID: "fake-release-id", Name: "fake-release-name"}},
+		}
+	})
+
+	It("returns an error when no move is in progress", func() {
+		err := deploymentStateService.Save(deploymentState)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = targetStateService.Load()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("No move to another cloud provider in progress"))
+	})
+
+	Context("when a move is in progress", func() {
+		BeforeEach(func() {
+			deploymentState.CloudMigrationTarget = &CloudMigrationTargetRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				CurrentVMCID:  "fake-target-vm-cid",
+				Disks:         []DiskRecord{},
+				Stemcells:     []StemcellRecord{},
+			}
+			err := deploymentStateService.Save(deploymentState)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("loads the resources of the move as the current resources", func() {
+			targetState, err := targetStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targetState).To(Equal(DeploymentState{
+				DirectorID:          "fake-director-id",
+				InstallationID:      "fake-installation-id",
+				CloudProvider:       "fake-target-cloud-provider",
+				CurrentVMCID:        "fake-target-vm-cid",
+				CurrentReleaseIDs:   []string{"fake-release-id"},
+				CurrentManifestSHA1: "fake-manifest-sha1",
+				Disks:               []DiskRecord{},
+				Stemcells:           []StemcellRecord{},
+				Releases:            deploymentState.Releases,
+			}))
+		})
+
+		It("saves the resources of the move and leaves the current resources unchanged", func() {
+			targetState, err := targetStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+
+			targetState.CurrentVMCID = "fake-new-target-vm-cid"
+			targetState.Disks = []DiskRecord{{ID: "fake-target-disk-id", CID: "fake-target-disk-cid"}}
+			targetState.CurrentDiskID = "fake-target-disk-id"
+			err = targetStateService.Save(targetState)
+			Expect(err).ToNot(HaveOccurred())
+
+			savedState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(savedState.CurrentVMCID).To(Equal("fake-vm-cid"))
+			Expect(savedState.Disks).To(Equal(deploymentState.Disks))
+			Expect(savedState.CloudMigrationTarget).To(Equal(&CloudMigrationTargetRecord{
+				CloudProvider: "fake-target-cloud-provider",
+				CurrentVMCID:  "fake-new-target-vm-cid",
+				CurrentDiskID: "fake-target-disk-id",
+				Disks:         []DiskRecord{{ID: "fake-target-disk-id", CID: "fake-target-disk-cid"}},
+				Stemcells:     []StemcellRecord{},
+			}))
+		})
+	})
+})
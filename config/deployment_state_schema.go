@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// DeploymentStateHistorySize is the number of previous revisions kept next to the deployment state file
+const DeploymentStateHistorySize = 20
+
+// deploymentStateMigrations migrate the JSON of a deployment state file to the next schema version:
+// deploymentStateMigrations[i] migrates version i to version i+1.
+// Files written before the schema was versioned are version 0.
+var deploymentStateMigrations = []func(deploymentStateJSON map[string]interface{}) error{
+	// version 1 added 'schema_version', 'revision' and 'saved_at', the deployment state itself is unchanged
+	func(map[string]interface{}) error { return nil },
+	// version 2 added 'cloud_provider' and 'cloud_migration', older versions of bosh-init would use the wrong CPI
+	func(map[string]interface{}) error { return nil },
+	// version 3 added 'cloud_migration_target', older versions of bosh-init would not know about its resources
+	func(map[string]interface{}) error { return nil },
+}
+
+// DeploymentStateSchemaVersion is the schema version of the deployment state files written by this version of bosh-init
+var DeploymentStateSchemaVersion = len(deploymentStateMigrations)
+
+type deploymentStateFile struct {
+	SchemaVersion int       `json:"schema_version"`
+	Revision      int       `json:"revision"`
+	SavedAt       time.Time `json:"saved_at"`
+	DeploymentState
+}
+
+// encryptedDeploymentStateFile holds an encrypted deploymentStateFile.
+// The schema version and the revision are kept in plain text, so that the history can be kept without the key.
+type encryptedDeploymentStateFile struct {
+	SchemaVersion int    `json:"schema_version"`
+	Revision      int    `json:"revision"`
+	Encrypted     string `json:"encrypted"`
+}
+
+type deploymentStateFileRevision struct {
+	Revision int `json:"revision"`
+}
+
+// parseDeploymentStateFile unmarshals a deployment state file, migrating it to the current schema version
+func parseDeploymentStateFile(contents []byte) (deploymentStateFile, error) {
+	var deploymentStateJSON map[string]interface{}
+	err := json.Unmarshal(contents, &deploymentStateJSON)
+	if err != nil {
+		return deploymentStateFile{}, err
+	}
+
+	schemaVersion := 0
+	if version, found := deploymentStateJSON["schema_version"]; found {
+		floatVersion, ok := version.(float64)
+		if !ok {
+			return deploymentStateFile{}, bosherr.Errorf("Expected schema_version to be a number, got '%v'", version)
+		}
+		schemaVersion = int(floatVersion)
+	}
+
+	if schemaVersion > DeploymentStateSchemaVersion {
+		return deploymentStateFile{}, bosherr.Errorf("Deployment state schema version %d is newer than %d, the latest version supported by this bosh-init", schemaVersion, DeploymentStateSchemaVersion)
+	}
+
+	if schemaVersion < DeploymentStateSchemaVersion {
+		for version := schemaVersion; version < DeploymentStateSchemaVersion; version++ {
+			err = deploymentStateMigrations[version](deploymentStateJSON)
+			if err != nil {
+				return deploymentStateFile{}, bosherr.WrapErrorf(err, "Migrating deployment state from schema version %d to %d", version, version+1)
+			}
+		}
+		deploymentStateJSON["schema_version"] = DeploymentStateSchemaVersion
+
+		contents, err = json.Marshal(deploymentStateJSON)
+		if err != nil {
+			return deploymentStateFile{}, bosherr.WrapError(err, "Marshalling migrated deployment state")
+		}
+	}
+
+	var stateFile deploymentStateFile
+	err = json.Unmarshal(contents, &stateFile)
+	if err != nil {
+		return deploymentStateFile{}, err
+	}
+
+	return stateFile, nil
+}
@@ -1,20 +1,38 @@
 package config
 
 import (
+	"time"
+
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 )
 
 type DeploymentState struct {
-	DirectorID          string           `json:"director_id"`
-	InstallationID      string           `json:"installation_id"`
-	CurrentVMCID        string           `json:"current_vm_cid"`
-	CurrentStemcellID   string           `json:"current_stemcell_id"`
-	CurrentDiskID       string           `json:"current_disk_id"`
-	CurrentReleaseIDs   []string         `json:"current_release_ids"`
-	CurrentManifestSHA1 string           `json:"current_manifest_sha1"`
-	Disks               []DiskRecord     `json:"disks"`
-	Stemcells           []StemcellRecord `json:"stemcells"`
-	Releases            []ReleaseRecord  `json:"releases"`
+	DirectorID           string                `json:"director_id"`
+	InstallationID       string                `json:"installation_id"`
+	CurrentVMCID         string                `json:"current_vm_cid"`
+	CurrentVMConfig      *VMConfigRecord       `json:"current_vm_config,omitempty"`
+	CurrentStemcellID    string                `json:"current_stemcell_id"`
+	CurrentDiskID        string                `json:"current_disk_id"`
+	CurrentNamedDiskIDs  map[string]string     `json:"current_named_disk_ids,omitempty"` // disk names to disk record IDs
+	CurrentDiskMigration *DiskMigrationRecord  `json:"current_disk_migration,omitempty"`
+	CurrentReleaseIDs    []string              `json:"current_release_ids"`
+	CurrentManifestSHA1  string                `json:"current_manifest_sha1"`
+	CloudProvider        string                `json:"cloud_provider,omitempty"` // the named cloud provider of the VM, disks & stemcells, empty for the default one
+	CloudMigration       *CloudMigrationRecord `json:"cloud_migration,omitempty"`
+	// CloudMigrationTarget holds the resources of the cloud provider the deployment is moving to, until its VM is running
+	CloudMigrationTarget *CloudMigrationTargetRecord `json:"cloud_migration_target,omitempty"`
+	Disks                []DiskRecord                `json:"disks"`
+	Stemcells            []StemcellRecord            `json:"stemcells"`
+	Releases             []ReleaseRecord             `json:"releases"`
+	Snapshots            []SnapshotRecord            `json:"snapshots,omitempty"`
+}
+
+// VMConfigRecord is the cloud configuration the current VM was created with
+type VMConfigRecord struct {
+	StemcellCID     string                    `json:"stemcell_cid"`
+	CloudProperties biproperty.Map            `json:"cloud_properties"`
+	Networks        map[string]biproperty.Map `json:"networks"`
+	Env             biproperty.Map            `json:"env"`
 }
 
 type StemcellRecord struct {
@@ -24,11 +42,72 @@ type StemcellRecord struct {
 	CID     string `json:"cid"`
 }
 
+// DiskRecord is a persistent disk. Disks configured with persistent_disks have a name,
+// the disk configured with persistent_disk or persistent_disk_pool does not.
 type DiskRecord struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name,omitempty"`
+	CID             string         `json:"cid"`
+	Size            int            `json:"size"`
+	CloudProperties biproperty.Map `json:"cloud_properties"`
+	// Orphaned disks are no longer used, but are kept until clean-up deletes them
+	Orphaned bool `json:"orphaned,omitempty"`
+}
+
+type DiskMigrationPhase string
+
+const (
+	DiskMigrationCreated  DiskMigrationPhase = "created"
+	DiskMigrationAttached DiskMigrationPhase = "attached"
+	DiskMigrationCopied   DiskMigrationPhase = "copied"
+	DiskMigrationSwitched DiskMigrationPhase = "switched"
+)
+
+// DiskMigrationRecord tracks a persistent disk migration, so that it can be resumed after being interrupted.
+// The phase is the last step that completed.
+type DiskMigrationRecord struct {
+	DiskName        string             `json:"disk_name,omitempty"`
+	OriginalDiskCID string             `json:"original_disk_cid"`
+	NewDiskCID      string             `json:"new_disk_cid"`
+	VMCID           string             `json:"vm_cid"`
+	Phase           DiskMigrationPhase `json:"phase"`
+}
+
+// CloudMigrationRecord holds the resources that stayed in the previous cloud provider
+// when a deploy moved the deployment to another cloud provider, until clean-up deletes them
+type CloudMigrationRecord struct {
+	CloudProvider string           `json:"cloud_provider"`
+	VMCID         string           `json:"vm_cid,omitempty"`
+	Disks         []DiskRecord     `json:"disks"`
+	Stemcells     []StemcellRecord `json:"stemcells"`
+	Snapshots     []SnapshotRecord `json:"snapshots,omitempty"`
+}
+
+// CloudMigrationTargetRecord holds the resources that a deploy creates with the CPI of the cloud provider
+// the deployment moves to. They are kept apart from the current resources until the new VM is running,
+// then they become the current resources and the previous ones move to the cloud migration record.
+type CloudMigrationTargetRecord struct {
+	CloudProvider        string               `json:"cloud_provider"`
+	CurrentVMCID         string               `json:"current_vm_cid,omitempty"`
+	CurrentVMConfig      *VMConfigRecord      `json:"current_vm_config,omitempty"`
+	CurrentStemcellID    string               `json:"current_stemcell_id,omitempty"`
+	CurrentDiskID        string               `json:"current_disk_id,omitempty"`
+	CurrentNamedDiskIDs  map[string]string    `json:"current_named_disk_ids,omitempty"`
+	CurrentDiskMigration *DiskMigrationRecord `json:"current_disk_migration,omitempty"`
+	Disks                []DiskRecord         `json:"disks"`
+	Stemcells            []StemcellRecord     `json:"stemcells"`
+	Snapshots            []SnapshotRecord     `json:"snapshots,omitempty"`
+}
+
+// SnapshotRecord is a snapshot of a persistent disk, with the disk configuration needed to restore it
+type SnapshotRecord struct {
 	ID              string         `json:"id"`
 	CID             string         `json:"cid"`
+	DiskCID         string         `json:"disk_cid"`
+	DiskName        string         `json:"disk_name,omitempty"`
 	Size            int            `json:"size"`
 	CloudProperties biproperty.Map `json:"cloud_properties"`
+	CreatedAt       time.Time      `json:"created_at"`
 }
 
 type ReleaseRecord struct {
@@ -37,10 +116,24 @@ type ReleaseRecord struct {
 	Version string `json:"version"`
 }
 
+// DeploymentStateRevision is a deployment state as it was saved
+type DeploymentStateRevision struct {
+	Revision int
+	Current  bool
+	SavedAt  time.Time
+	DeploymentState
+}
+
 type DeploymentStateService interface {
 	Path() string
 	Exists() bool
 	Load() (DeploymentState, error)
 	Save(DeploymentState) error
+	History() ([]DeploymentStateRevision, error)
+	Rollback(revision int) (DeploymentState, error)
+	// Encrypt rewrites the deployment state file and its history encrypted
+	Encrypt() error
+	// Decrypt rewrites the deployment state file and its history in plain text
+	Decrypt() error
 	Cleanup() error
 }
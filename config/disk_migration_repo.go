@@ -0,0 +1,64 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type DiskMigrationRepo interface {
+	Find() (DiskMigrationRecord, bool, error)
+	Save(DiskMigrationRecord) error
+	Clear() error
+}
+
+type diskMigrationRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewDiskMigrationRepo(deploymentStateService DeploymentStateService) DiskMigrationRepo {
+	return diskMigrationRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r diskMigrationRepo) Find() (DiskMigrationRecord, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return DiskMigrationRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	if deploymentState.CurrentDiskMigration == nil {
+		return DiskMigrationRecord{}, false, nil
+	}
+
+	return *deploymentState.CurrentDiskMigration, true, nil
+}
+
+func (r diskMigrationRepo) Save(record DiskMigrationRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.CurrentDiskMigration = &record
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+	return nil
+}
+
+func (r diskMigrationRepo) Clear() error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.CurrentDiskMigration = nil
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("DiskMigrationRepo", func() {
+	var (
+		deploymentStateService DeploymentStateService
+		repo                   DiskMigrationRepo
+		record                 DiskMigrationRecord
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, &fakeuuid.FakeGenerator{}, logger, "/fake/path")
+		repo = NewDiskMigrationRepo(deploymentStateService)
+		record = DiskMigrationRecord{
+			OriginalDiskCID: "fake-original-disk-cid",
+			NewDiskCID:      "fake-new-disk-cid",
+			VMCID:           "fake-vm-cid",
+			Phase:           DiskMigrationAttached,
+		}
+	})
+
+	It("finds no migration when none was saved", func() {
+		_, found, err := repo.Find()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("finds the saved migration", func() {
+		err := repo.Save(record)
+		Expect(err).ToNot(HaveOccurred())
+
+		foundRecord, found, err := repo.Find()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(foundRecord).To(Equal(record))
+	})
+
+	It("replaces the saved migration", func() {
+		err := repo.Save(record)
+		Expect(err).ToNot(HaveOccurred())
+
+		record.Phase = DiskMigrationCopied
+		err = repo.Save(record)
+		Expect(err).ToNot(HaveOccurred())
+
+		deploymentState, err := deploymentStateService.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deploymentState.CurrentDiskMigration).To(Equal(&record))
+	})
+
+	It("clears the saved migration", func() {
+		err := repo.Save(record)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = repo.Clear()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, found, err := repo.Find()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+})
@@ -1,19 +1,25 @@
 package config
 
 import (
+	"sort"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
 )
 
 type DiskRepo interface {
+	// UpdateCurrent makes the disk the current disk, replacing the previous current disk of any name
 	UpdateCurrent(diskID string) error
-	FindCurrent() (DiskRecord, bool, error)
+	// FindCurrent returns the current disks: the unnamed disk first, followed by the named disks ordered by name
+	FindCurrent() ([]DiskRecord, error)
 	ClearCurrent() error
-	Save(cid string, size int, cloudProperties biproperty.Map) (DiskRecord, error)
+	Save(name string, cid string, size int, cloudProperties biproperty.Map) (DiskRecord, error)
 	Find(cid string) (DiskRecord, bool, error)
 	All() ([]DiskRecord, error)
 	Delete(DiskRecord) error
+	// Orphan keeps the disk until clean-up deletes it, instead of deleting it with the unused disks
+	Orphan(diskID string) error
 }
 
 type diskRepo struct {
@@ -28,7 +34,7 @@ func NewDiskRepo(deploymentStateService DeploymentStateService, uuidGenerator bo
 	}
 }
 
-func (r diskRepo) Save(cid string, size int, cloudProperties biproperty.Map) (DiskRecord, error) {
+func (r diskRepo) Save(name string, cid string, size int, cloudProperties biproperty.Map) (DiskRecord, error) {
 	config, records, err := r.load()
 	if err != nil {
 		return DiskRecord{}, err
@@ -40,6 +46,7 @@ func (r diskRepo) Save(cid string, size int, cloudProperties biproperty.Map) (Di
 	}
 
 	newRecord := DiskRecord{
+		Name:            name,
 		CID:             cid,
 		Size:            size,
 		CloudProperties: cloudProperties,
@@ -59,24 +66,36 @@ func (r diskRepo) Save(cid string, size int, cloudProperties biproperty.Map) (Di
 	return newRecord, nil
 }
 
-func (r diskRepo) FindCurrent() (DiskRecord, bool, error) {
+func (r diskRepo) FindCurrent() ([]DiskRecord, error) {
 	deploymentState, err := r.deploymentStateService.Load()
 	if err != nil {
-		return DiskRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+		return []DiskRecord{}, bosherr.WrapError(err, "Loading existing config")
 	}
 
-	currentDiskID := deploymentState.CurrentDiskID
-	if currentDiskID == "" {
-		return DiskRecord{}, false, nil
+	currentDiskIDs := []string{}
+	if deploymentState.CurrentDiskID != "" {
+		currentDiskIDs = append(currentDiskIDs, deploymentState.CurrentDiskID)
 	}
 
-	for _, oldRecord := range deploymentState.Disks {
-		if oldRecord.ID == currentDiskID {
-			return oldRecord, true, nil
+	names := []string{}
+	for name := range deploymentState.CurrentNamedDiskIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		currentDiskIDs = append(currentDiskIDs, deploymentState.CurrentNamedDiskIDs[name])
+	}
+
+	records := []DiskRecord{}
+	for _, currentDiskID := range currentDiskIDs {
+		for _, oldRecord := range deploymentState.Disks {
+			if oldRecord.ID == currentDiskID {
+				records = append(records, oldRecord)
+			}
 		}
 	}
 
-	return DiskRecord{}, false, nil
+	return records, nil
 }
 
 func (r diskRepo) UpdateCurrent(diskID string) error {
@@ -85,9 +104,11 @@ func (r diskRepo) UpdateCurrent(diskID string) error {
 		return bosherr.WrapError(err, "Loading existing config")
 	}
 
+	var record DiskRecord
 	found := false
 	for _, oldRecord := range deploymentState.Disks {
 		if oldRecord.ID == diskID {
+			record = oldRecord
 			found = true
 		}
 	}
@@ -95,7 +116,14 @@ func (r diskRepo) UpdateCurrent(diskID string) error {
 		return bosherr.Errorf("Verifying disk record exists with id '%s'", diskID)
 	}
 
-	deploymentState.CurrentDiskID = diskID
+	// the agent mounts a single persistent disk, so a renamed disk replaces the disk with the previous name
+	if record.Name == "" {
+		deploymentState.CurrentDiskID = diskID
+		deploymentState.CurrentNamedDiskIDs = nil
+	} else {
+		deploymentState.CurrentDiskID = ""
+		deploymentState.CurrentNamedDiskIDs = map[string]string{record.Name: diskID}
+	}
 
 	err = r.deploymentStateService.Save(deploymentState)
 	if err != nil {
@@ -141,6 +169,38 @@ func (r diskRepo) Delete(diskRecord DiskRecord) error {
 	if config.CurrentDiskID == diskRecord.ID {
 		config.CurrentDiskID = ""
 	}
+	for name, diskID := range config.CurrentNamedDiskIDs {
+		if diskID == diskRecord.ID {
+			delete(config.CurrentNamedDiskIDs, name)
+		}
+	}
+
+	err = r.deploymentStateService.Save(config)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
+
+func (r diskRepo) Orphan(diskID string) error {
+	config, records, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, record := range records {
+		if record.ID == diskID {
+			records[i].Orphaned = true
+			found = true
+		}
+	}
+	if !found {
+		return bosherr.Errorf("Verifying disk record exists with id '%s'", diskID)
+	}
+
+	config.Disks = records
 
 	err = r.deploymentStateService.Save(config)
 	if err != nil {
@@ -157,6 +217,7 @@ func (r diskRepo) ClearCurrent() error {
 	}
 
 	deploymentState.CurrentDiskID = ""
+	deploymentState.CurrentNamedDiskIDs = nil
 
 	err = r.deploymentStateService.Save(deploymentState)
 	if err != nil {
@@ -33,7 +33,7 @@ var _ = Describe("DiskRepo", func() {
 
 	Describe("Save", func() {
 		It("saves the disk record using the config service", func() {
-			record, err := repo.Save("fake-cid", 1024, cloudProperties)
+			record, err := repo.Save("", "fake-cid", 1024, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(record).To(Equal(DiskRecord{
 				ID:              "fake-uuid-1",
@@ -62,7 +62,7 @@ var _ = Describe("DiskRepo", func() {
 
 	Describe("Find", func() {
 		It("finds existing disk records", func() {
-			savedRecord, err := repo.Save("fake-cid", 1024, cloudProperties)
+			savedRecord, err := repo.Save("", "fake-cid", 1024, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 
 			foundRecord, found, err := repo.Find("fake-cid")
@@ -72,7 +72,7 @@ var _ = Describe("DiskRepo", func() {
 		})
 
 		It("when the disk is not in the records, returns not found", func() {
-			_, err := repo.Save("other-cid", 1024, cloudProperties)
+			_, err := repo.Save("", "other-cid", 1024, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 
 			_, found, err := repo.Find("fake-cid")
@@ -88,7 +88,7 @@ var _ = Describe("DiskRepo", func() {
 			)
 
 			BeforeEach(func() {
-				record, err := repo.Save("fake-cid", 1024, cloudProperties)
+				record, err := repo.Save("", "fake-cid", 1024, cloudProperties)
 				Expect(err).ToNot(HaveOccurred())
 				recordID = record.ID
 			})
@@ -106,7 +106,7 @@ var _ = Describe("DiskRepo", func() {
 
 		Context("when a disk record does not exists with the same ID", func() {
 			BeforeEach(func() {
-				_, err := repo.Save("fake-cid", 1024, cloudProperties)
+				_, err := repo.Save("", "fake-cid", 1024, cloudProperties)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -124,10 +124,10 @@ var _ = Describe("DiskRepo", func() {
 				diskID2 string
 			)
 			BeforeEach(func() {
-				_, err := repo.Save("fake-cid-1", 1024, cloudProperties)
+				_, err := repo.Save("", "fake-cid-1", 1024, cloudProperties)
 				Expect(err).ToNot(HaveOccurred())
 
-				record, err := repo.Save("fake-cid-2", 1024, cloudProperties)
+				record, err := repo.Save("", "fake-cid-2", 1024, cloudProperties)
 				Expect(err).ToNot(HaveOccurred())
 				diskID2 = record.ID
 
@@ -135,36 +135,103 @@ var _ = Describe("DiskRepo", func() {
 			})
 
 			It("returns existing disk", func() {
-				record, found, err := repo.FindCurrent()
+				records, err := repo.FindCurrent()
 				Expect(err).ToNot(HaveOccurred())
-				Expect(found).To(BeTrue())
-				Expect(record).To(Equal(DiskRecord{
-					ID:              diskID2,
-					CID:             "fake-cid-2",
-					Size:            1024,
-					CloudProperties: cloudProperties,
+				Expect(records).To(Equal([]DiskRecord{
+					{
+						ID:              diskID2,
+						CID:             "fake-cid-2",
+						Size:            1024,
+						CloudProperties: cloudProperties,
+					},
+				}))
+			})
+		})
+
+		Context("when current named disks exist", func() {
+			var (
+				unnamedRecord  DiskRecord
+				databaseRecord DiskRecord
+				blobsRecord    DiskRecord
+			)
+
+			BeforeEach(func() {
+				var err error
+				databaseRecord, err = repo.Save("database", "fake-cid-1", 1024, cloudProperties)
+				Expect(err).ToNot(HaveOccurred())
+
+				blobsRecord, err = repo.Save("blobs", "fake-cid-2", 2048, cloudProperties)
+				Expect(err).ToNot(HaveOccurred())
+
+				unnamedRecord, err = repo.Save("", "fake-cid-3", 512, cloudProperties)
+				Expect(err).ToNot(HaveOccurred())
+
+				deploymentState, err := deploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				deploymentState.CurrentDiskID = unnamedRecord.ID
+				deploymentState.CurrentNamedDiskIDs = map[string]string{
+					"database": databaseRecord.ID,
+					"blobs":    blobsRecord.ID,
+				}
+				Expect(deploymentStateService.Save(deploymentState)).To(Succeed())
+			})
+
+			It("returns the unnamed disk followed by the named disks ordered by name", func() {
+				records, err := repo.FindCurrent()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(Equal([]DiskRecord{unnamedRecord, blobsRecord, databaseRecord}))
+			})
+
+			It("replaces all of them with the new current disk", func() {
+				newDatabaseRecord, err := repo.Save("database", "fake-cid-4", 4096, cloudProperties)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = repo.UpdateCurrent(newDatabaseRecord.ID)
+				Expect(err).ToNot(HaveOccurred())
+
+				records, err := repo.FindCurrent()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(Equal([]DiskRecord{newDatabaseRecord}))
+
+				deploymentState, err := deploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.CurrentDiskID).To(BeEmpty())
+				Expect(deploymentState.CurrentNamedDiskIDs).To(Equal(map[string]string{
+					"database": newDatabaseRecord.ID,
 				}))
 			})
+
+			It("replaces the named disks with a new unnamed current disk", func() {
+				newRecord, err := repo.Save("", "fake-cid-4", 4096, cloudProperties)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = repo.UpdateCurrent(newRecord.ID)
+				Expect(err).ToNot(HaveOccurred())
+
+				records, err := repo.FindCurrent()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(Equal([]DiskRecord{newRecord}))
+			})
 		})
 
 		Context("when current disk does not exist", func() {
 			BeforeEach(func() {
-				_, err := repo.Save("fake-cid", 1024, cloudProperties)
+				_, err := repo.Save("", "fake-cid", 1024, cloudProperties)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
-			It("returns not found", func() {
-				_, found, err := repo.FindCurrent()
+			It("returns no disks", func() {
+				records, err := repo.FindCurrent()
 				Expect(err).ToNot(HaveOccurred())
-				Expect(found).To(BeFalse())
+				Expect(records).To(BeEmpty())
 			})
 		})
 
 		Context("when there are no disks", func() {
-			It("returns not found", func() {
-				_, found, err := repo.FindCurrent()
+			It("returns no disks", func() {
+				records, err := repo.FindCurrent()
 				Expect(err).ToNot(HaveOccurred())
-				Expect(found).To(BeFalse())
+				Expect(records).To(BeEmpty())
 			})
 		})
 	})
@@ -177,10 +244,10 @@ var _ = Describe("DiskRepo", func() {
 
 		BeforeEach(func() {
 			var err error
-			firstDisk, err = repo.Save("fake-cid-1", 1024, cloudProperties)
+			firstDisk, err = repo.Save("", "fake-cid-1", 1024, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 
-			secondDisk, err = repo.Save("fake-cid-2", 2048, cloudProperties)
+			secondDisk, err = repo.Save("", "fake-cid-2", 2048, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -203,10 +270,10 @@ var _ = Describe("DiskRepo", func() {
 		BeforeEach(func() {
 			var err error
 
-			firstDisk, err = repo.Save("fake-cid-1", 1024, cloudProperties)
+			firstDisk, err = repo.Save("", "fake-cid-1", 1024, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 
-			secondDisk, err = repo.Save("fake-cid-2", 2048, cloudProperties)
+			secondDisk, err = repo.Save("", "fake-cid-2", 2048, cloudProperties)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -237,13 +304,55 @@ var _ = Describe("DiskRepo", func() {
 					secondDisk,
 				}))
 
-				_, found, err := repo.FindCurrent()
+				records, err := repo.FindCurrent()
 				Expect(err).ToNot(HaveOccurred())
-				Expect(found).To(BeFalse())
+				Expect(records).To(BeEmpty())
+			})
+		})
+
+		Context("when the disk to be deleted is the current disk with its name", func() {
+			It("clears the current disk with that name", func() {
+				namedDisk, err := repo.Save("database", "fake-cid-3", 1024, cloudProperties)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(repo.UpdateCurrent(namedDisk.ID)).To(Succeed())
+
+				err = repo.Delete(namedDisk)
+				Expect(err).ToNot(HaveOccurred())
+
+				records, err := repo.FindCurrent()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(BeEmpty())
 			})
 		})
 	})
 
+	Describe("Orphan", func() {
+		It("marks the disk record as orphaned", func() {
+			firstDisk, err := repo.Save("", "fake-cid-1", 1024, cloudProperties)
+			Expect(err).ToNot(HaveOccurred())
+
+			secondDisk, err := repo.Save("", "fake-cid-2", 2048, cloudProperties)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = repo.Orphan(firstDisk.ID)
+			Expect(err).ToNot(HaveOccurred())
+
+			firstDisk.Orphaned = true
+			disks, err := repo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(disks).To(Equal([]DiskRecord{
+				firstDisk,
+				secondDisk,
+			}))
+		})
+
+		It("fails when the disk record does not exist", func() {
+			err := repo.Orphan("fake-unknown-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Verifying disk record exists with id 'fake-unknown-id'"))
+		})
+	})
+
 	Describe("ClearCurrent", func() {
 		It("updates disk cid", func() {
 			err := repo.ClearCurrent()
@@ -258,9 +367,9 @@ var _ = Describe("DiskRepo", func() {
 			}
 			Expect(deploymentState).To(Equal(expectedConfig))
 
-			_, found, err := repo.FindCurrent()
+			records, err := repo.FindCurrent()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(found).To(BeFalse())
+			Expect(records).To(BeEmpty())
 		})
 	})
 })
@@ -0,0 +1,51 @@
+package fakes
+
+import (
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+)
+
+// FakeDiskMigrationRepo keeps the migration record in memory
+type FakeDiskMigrationRepo struct {
+	Record *biconfig.DiskMigrationRecord
+
+	SaveInputs []biconfig.DiskMigrationRecord
+	SaveErr    error
+
+	ClearCalledTimes int
+	ClearErr         error
+
+	FindErr error
+}
+
+func NewFakeDiskMigrationRepo() *FakeDiskMigrationRepo {
+	return &FakeDiskMigrationRepo{
+		SaveInputs: []biconfig.DiskMigrationRecord{},
+	}
+}
+
+func (r *FakeDiskMigrationRepo) Find() (biconfig.DiskMigrationRecord, bool, error) {
+	if r.Record == nil {
+		return biconfig.DiskMigrationRecord{}, false, r.FindErr
+	}
+	return *r.Record, true, r.FindErr
+}
+
+func (r *FakeDiskMigrationRepo) Save(record biconfig.DiskMigrationRecord) error {
+	r.SaveInputs = append(r.SaveInputs, record)
+	if r.SaveErr != nil {
+		return r.SaveErr
+	}
+
+	r.Record = &record
+	return nil
+}
+
+func (r *FakeDiskMigrationRepo) Clear() error {
+	r.ClearCalledTimes++
+	if r.ClearErr != nil {
+		return r.ClearErr
+	}
+
+	r.Record = nil
+	return nil
+}
@@ -20,6 +20,13 @@ type FakeDiskRepo struct {
 	DeleteErr    error
 
 	allOutput diskRepoAllOutput
+
+	OrphanInputs []DiskRepoOrphanInput
+	OrphanErr    error
+}
+
+type DiskRepoOrphanInput struct {
+	DiskID string
 }
 
 type DiskRepoUpdateCurrentInput struct {
@@ -27,12 +34,12 @@ type DiskRepoUpdateCurrentInput struct {
 }
 
 type diskRepoFindCurrentOutput struct {
-	diskRecord biconfig.DiskRecord
-	found      bool
-	err        error
+	diskRecords []biconfig.DiskRecord
+	err         error
 }
 
 type DiskRepoSaveInput struct {
+	Name            string
 	CID             string
 	Size            int
 	CloudProperties biproperty.Map
@@ -74,16 +81,17 @@ func (r *FakeDiskRepo) UpdateCurrent(diskID string) error {
 	return r.updateErr
 }
 
-func (r *FakeDiskRepo) FindCurrent() (biconfig.DiskRecord, bool, error) {
-	return r.findCurrentOutput.diskRecord, r.findCurrentOutput.found, r.findCurrentOutput.err
+func (r *FakeDiskRepo) FindCurrent() ([]biconfig.DiskRecord, error) {
+	return r.findCurrentOutput.diskRecords, r.findCurrentOutput.err
 }
 
 func (r *FakeDiskRepo) ClearCurrent() error {
 	return nil
 }
 
-func (r *FakeDiskRepo) Save(cid string, size int, cloudProperties biproperty.Map) (biconfig.DiskRecord, error) {
+func (r *FakeDiskRepo) Save(name string, cid string, size int, cloudProperties biproperty.Map) (biconfig.DiskRecord, error) {
 	r.SaveInputs = append(r.SaveInputs, DiskRepoSaveInput{
+		Name:            name,
 		CID:             cid,
 		Size:            size,
 		CloudProperties: cloudProperties,
@@ -108,15 +116,21 @@ func (r *FakeDiskRepo) Delete(diskRecord biconfig.DiskRecord) error {
 	return r.DeleteErr
 }
 
+func (r *FakeDiskRepo) Orphan(diskID string) error {
+	r.OrphanInputs = append(r.OrphanInputs, DiskRepoOrphanInput{
+		DiskID: diskID,
+	})
+	return r.OrphanErr
+}
+
 func (r *FakeDiskRepo) SetUpdateBehavior(err error) {
 	r.updateErr = err
 }
 
-func (r *FakeDiskRepo) SetFindCurrentBehavior(diskRecord biconfig.DiskRecord, found bool, err error) {
+func (r *FakeDiskRepo) SetFindCurrentBehavior(diskRecords []biconfig.DiskRecord, err error) {
 	r.findCurrentOutput = diskRepoFindCurrentOutput{
-		diskRecord: diskRecord,
-		found:      found,
-		err:        err,
+		diskRecords: diskRecords,
+		err:         err,
 	}
 }
 
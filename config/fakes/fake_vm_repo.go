@@ -1,13 +1,21 @@
 package fakes
 
+import (
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+)
+
 type FakeVMRepo struct {
 	UpdateCurrentCID string
 	UpdateCurrentErr error
 
+	UpdateCurrentConfigConfig biconfig.VMConfigRecord
+	UpdateCurrentConfigErr    error
+
 	ClearCurrentCalled bool
 	ClearCurrentErr    error
 
-	findCurrentOutput vmRepoFindCurrentOutput
+	findCurrentOutput       vmRepoFindCurrentOutput
+	findCurrentConfigOutput vmRepoFindCurrentConfigOutput
 }
 
 type vmRepoFindCurrentOutput struct {
@@ -16,6 +24,12 @@ type vmRepoFindCurrentOutput struct {
 	err   error
 }
 
+type vmRepoFindCurrentConfigOutput struct {
+	config biconfig.VMConfigRecord
+	found  bool
+	err    error
+}
+
 func NewFakeVMRepo() *FakeVMRepo {
 	return &FakeVMRepo{}
 }
@@ -37,6 +51,23 @@ func (r *FakeVMRepo) UpdateCurrent(cid string) error {
 	return r.UpdateCurrentErr
 }
 
+func (r *FakeVMRepo) FindCurrentConfig() (config biconfig.VMConfigRecord, found bool, err error) {
+	return r.findCurrentConfigOutput.config, r.findCurrentConfigOutput.found, r.findCurrentConfigOutput.err
+}
+
+func (r *FakeVMRepo) SetFindCurrentConfigBehavior(config biconfig.VMConfigRecord, found bool, err error) {
+	r.findCurrentConfigOutput = vmRepoFindCurrentConfigOutput{
+		config: config,
+		found:  found,
+		err:    err,
+	}
+}
+
+func (r *FakeVMRepo) UpdateCurrentConfig(config biconfig.VMConfigRecord) error {
+	r.UpdateCurrentConfigConfig = config
+	return r.UpdateCurrentConfigErr
+}
+
 func (r *FakeVMRepo) ClearCurrent() error {
 	r.ClearCurrentCalled = true
 	return r.ClearCurrentErr
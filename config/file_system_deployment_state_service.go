@@ -3,9 +3,13 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	biutil "github.com/cloudfoundry/bosh-init/common/util"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -16,15 +20,27 @@ type fileSystemDeploymentStateService struct {
 	configPath    string
 	fs            boshsys.FileSystem
 	uuidGenerator boshuuid.Generator
+	encryptor     bicrypto.Encryptor
 	logger        boshlog.Logger
 	logTag        string
+
+	// revision is the revision written by this service, 0 until its first save.
+	// Every bosh-init command gets its own service, so each run adds one revision to the history.
+	revision int
 }
 
 func NewFileSystemDeploymentStateService(fs boshsys.FileSystem, uuidGenerator boshuuid.Generator, logger boshlog.Logger, deploymentStatePath string) DeploymentStateService {
+	return NewEncryptedFileSystemDeploymentStateService(fs, uuidGenerator, logger, deploymentStatePath, nil)
+}
+
+// NewEncryptedFileSystemDeploymentStateService returns a service that saves the deployment state encrypted with encryptor.
+// Encrypted and plain text deployment state files are both loaded; with a nil encryptor, the state is saved in plain text.
+func NewEncryptedFileSystemDeploymentStateService(fs boshsys.FileSystem, uuidGenerator boshuuid.Generator, logger boshlog.Logger, deploymentStatePath string, encryptor bicrypto.Encryptor) DeploymentStateService {
 	return &fileSystemDeploymentStateService{
 		configPath:    deploymentStatePath,
 		fs:            fs,
 		uuidGenerator: uuidGenerator,
+		encryptor:     encryptor,
 		logger:        logger,
 		logTag:        "config",
 	}
@@ -35,6 +51,21 @@ func DeploymentStatePath(deploymentManifestPath string) string {
 	return filepath.Join(filepath.Dir(deploymentManifestPath), fmt.Sprintf("%s-state.json", baseFileName))
 }
 
+func RegistryStorePath(deploymentManifestPath string) string {
+	baseFileName := filepath.Base(strings.TrimSuffix(deploymentManifestPath, filepath.Ext(deploymentManifestPath)))
+	return filepath.Join(filepath.Dir(deploymentManifestPath), fmt.Sprintf("%s-registry.json", baseFileName))
+}
+
+func RegistryCredentialsPath(deploymentManifestPath string) string {
+	baseFileName := filepath.Base(strings.TrimSuffix(deploymentManifestPath, filepath.Ext(deploymentManifestPath)))
+	return filepath.Join(filepath.Dir(deploymentManifestPath), fmt.Sprintf("%s-registry-credentials.json", baseFileName))
+}
+
+func RegistryInstanceCredentialsPath(deploymentManifestPath string) string {
+	baseFileName := filepath.Base(strings.TrimSuffix(deploymentManifestPath, filepath.Ext(deploymentManifestPath)))
+	return filepath.Join(filepath.Dir(deploymentManifestPath), fmt.Sprintf("%s-registry-instance-credentials.json", baseFileName))
+}
+
 func (s *fileSystemDeploymentStateService) Path() string {
 	return s.configPath
 }
@@ -53,16 +84,11 @@ func (s *fileSystemDeploymentStateService) Load() (DeploymentState, error) {
 	deploymentState := &DeploymentState{}
 
 	if s.fs.FileExists(s.configPath) {
-		deploymentStateFileContents, err := s.fs.ReadFile(s.configPath)
+		deploymentStateFile, err := s.readStateFile(s.configPath)
 		if err != nil {
-			return DeploymentState{}, bosherr.WrapErrorf(err, "Reading deployment state file '%s'", s.configPath)
-		}
-		s.logger.Debug(s.logTag, "Deployment File Contents %#s", deploymentStateFileContents)
-
-		err = json.Unmarshal(deploymentStateFileContents, deploymentState)
-		if err != nil {
-			return DeploymentState{}, bosherr.WrapErrorf(err, "Unmarshalling deployment state file '%s'", s.configPath)
+			return DeploymentState{}, err
 		}
+		deploymentState = &deploymentStateFile.DeploymentState
 	}
 
 	err := s.initDefaults(deploymentState)
@@ -73,26 +99,285 @@ func (s *fileSystemDeploymentStateService) Load() (DeploymentState, error) {
 	return *deploymentState, nil
 }
 
+// Save atomically replaces the deployment state file.
+// The first save of the service keeps the replaced state as a revision in the history, later saves update the same revision.
 func (s *fileSystemDeploymentStateService) Save(deploymentState DeploymentState) error {
 	if s.configPath == "" {
 		panic("configPath not yet set!")
 	}
 
-	s.logger.Debug(s.logTag, "Saving deployment state %#v", deploymentState)
+	if s.encryptor == nil {
+		s.logger.Debug(s.logTag, "Saving deployment state %#v", deploymentState)
+	} else {
+		s.logger.Debug(s.logTag, "Saving encrypted deployment state to '%s'", s.configPath)
+	}
+
+	if s.revision != 0 {
+		return s.writeStateFile(s.configPath, deploymentStateFile{
+			SchemaVersion:   DeploymentStateSchemaVersion,
+			Revision:        s.revision,
+			SavedAt:         time.Now().UTC(),
+			DeploymentState: deploymentState,
+		}, s.encryptor)
+	}
+
+	revision := 1
+	if s.fs.FileExists(s.configPath) {
+		previousRevision, err := s.keepRevision()
+		if err != nil {
+			return err
+		}
+		revision = previousRevision + 1
+	}
+
+	err := s.writeStateFile(s.configPath, deploymentStateFile{
+		SchemaVersion:   DeploymentStateSchemaVersion,
+		Revision:        revision,
+		SavedAt:         time.Now().UTC(),
+		DeploymentState: deploymentState,
+	}, s.encryptor)
+	if err != nil {
+		return err
+	}
+	s.revision = revision
+
+	oldestRevisionPath := s.revisionPath(revision - 1 - DeploymentStateHistorySize)
+	if s.fs.FileExists(oldestRevisionPath) {
+		err := s.fs.RemoveAll(oldestRevisionPath)
+		if err != nil {
+			s.logger.Warn(s.logTag, "Failed to remove deployment state revision '%s': %s", oldestRevisionPath, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// History returns the current deployment state and the revisions kept before it, newest first
+func (s *fileSystemDeploymentStateService) History() ([]DeploymentStateRevision, error) {
+	if !s.fs.FileExists(s.configPath) {
+		return []DeploymentStateRevision{}, nil
+	}
+
+	current, err := s.readRevision(s.configPath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []DeploymentStateRevision{current}
+	for revision := current.Revision - 1; revision >= 0 && revision >= current.Revision-DeploymentStateHistorySize; revision-- {
+		revisionPath := s.revisionPath(revision)
+		if !s.fs.FileExists(revisionPath) {
+			continue
+		}
+
+		deploymentStateRevision, err := s.readRevision(revisionPath, false)
+		if err != nil {
+			return nil, err
+		}
+		deploymentStateRevision.Revision = revision
+		history = append(history, deploymentStateRevision)
+	}
+
+	return history, nil
+}
+
+// Rollback saves a previous revision as the current deployment state.
+// The replaced state is kept in the history, so that the rollback can be reverted.
+func (s *fileSystemDeploymentStateService) Rollback(revision int) (DeploymentState, error) {
+	revisionPath := s.revisionPath(revision)
+	if !s.fs.FileExists(revisionPath) {
+		return DeploymentState{}, bosherr.Errorf("Deployment state revision %d does not exist at '%s'", revision, revisionPath)
+	}
 
-	jsonContent, err := json.MarshalIndent(deploymentState, "", "    ")
+	deploymentStateFile, err := s.readStateFile(revisionPath)
+	if err != nil {
+		return DeploymentState{}, err
+	}
+
+	s.revision = 0
+	err = s.Save(deploymentStateFile.DeploymentState)
+	if err != nil {
+		return DeploymentState{}, bosherr.WrapErrorf(err, "Rolling back to deployment state revision %d", revision)
+	}
+
+	return deploymentStateFile.DeploymentState, nil
+}
+
+func (s *fileSystemDeploymentStateService) readStateFile(path string) (deploymentStateFile, error) {
+	deploymentStateFileContents, err := s.fs.ReadFile(path)
+	if err != nil {
+		return deploymentStateFile{}, bosherr.WrapErrorf(err, "Reading deployment state file '%s'", path)
+	}
+
+	encryptedStateFile, encrypted := parseEncryptedDeploymentStateFile(deploymentStateFileContents)
+	if encrypted {
+		if s.encryptor == nil {
+			return deploymentStateFile{}, bosherr.Errorf("Deployment state file '%s' is encrypted, but no passphrase or key file was given", path)
+		}
+
+		deploymentStateFileContents, err = s.encryptor.Decrypt(encryptedStateFile.Encrypted)
+		if err != nil {
+			return deploymentStateFile{}, bosherr.WrapErrorf(err, "Decrypting deployment state file '%s'", path)
+		}
+	} else {
+		s.logger.Debug(s.logTag, "Deployment File Contents %#s", deploymentStateFileContents)
+	}
+
+	stateFile, err := parseDeploymentStateFile(deploymentStateFileContents)
+	if err != nil {
+		return deploymentStateFile{}, bosherr.WrapErrorf(err, "Unmarshalling deployment state file '%s'", path)
+	}
+
+	return stateFile, nil
+}
+
+// writeStateFile atomically writes the deployment state file to path, encrypted if encryptor is not nil
+func (s *fileSystemDeploymentStateService) writeStateFile(path string, stateFile deploymentStateFile, encryptor bicrypto.Encryptor) error {
+	jsonContent, err := json.MarshalIndent(stateFile, "", "    ")
 	if err != nil {
 		return bosherr.WrapError(err, "Marshalling deployment state into JSON")
 	}
 
-	err = s.fs.WriteFile(s.configPath, jsonContent)
+	if encryptor != nil {
+		encrypted, err := encryptor.Encrypt(jsonContent)
+		if err != nil {
+			return bosherr.WrapError(err, "Encrypting deployment state")
+		}
+
+		jsonContent, err = json.MarshalIndent(encryptedDeploymentStateFile{
+			SchemaVersion: stateFile.SchemaVersion,
+			Revision:      stateFile.Revision,
+			Encrypted:     encrypted,
+		}, "", "    ")
+		if err != nil {
+			return bosherr.WrapError(err, "Marshalling encrypted deployment state into JSON")
+		}
+	}
+
+	err = biutil.WriteFileAtomically(s.fs, path, jsonContent, os.FileMode(0644))
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Writing deployment state file '%s'", s.configPath)
+		return bosherr.WrapErrorf(err, "Writing deployment state file '%s'", path)
+	}
+
+	return nil
+}
+
+func (s *fileSystemDeploymentStateService) Encrypt() error {
+	if s.encryptor == nil {
+		return bosherr.Error("Encrypting the deployment state requires a passphrase or key file")
+	}
+
+	return s.rewriteStateFiles(s.encryptor)
+}
+
+func (s *fileSystemDeploymentStateService) Decrypt() error {
+	if s.encryptor == nil {
+		return bosherr.Error("Decrypting the deployment state requires a passphrase or key file")
+	}
+
+	return s.rewriteStateFiles(nil)
+}
+
+// rewriteStateFiles rewrites the deployment state file and the revisions in its history, encrypted if encryptor is not nil.
+// All files are read before any is rewritten, so that a wrong key does not leave a mix of keys behind.
+func (s *fileSystemDeploymentStateService) rewriteStateFiles(encryptor bicrypto.Encryptor) error {
+	if !s.fs.FileExists(s.configPath) {
+		return bosherr.Errorf("No deployment state found at '%s'", s.configPath)
+	}
+
+	current, err := s.readStateFile(s.configPath)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{s.configPath}
+	stateFiles := []deploymentStateFile{current}
+	for revision := current.Revision - 1; revision >= 0 && revision >= current.Revision-DeploymentStateHistorySize; revision-- {
+		revisionPath := s.revisionPath(revision)
+		if !s.fs.FileExists(revisionPath) {
+			continue
+		}
+
+		stateFile, err := s.readStateFile(revisionPath)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, revisionPath)
+		stateFiles = append(stateFiles, stateFile)
+	}
+
+	for i, path := range paths {
+		err = s.writeStateFile(path, stateFiles[i], encryptor)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+func (s *fileSystemDeploymentStateService) readRevision(path string, current bool) (DeploymentStateRevision, error) {
+	stateFile, err := s.readStateFile(path)
+	if err != nil {
+		return DeploymentStateRevision{}, err
+	}
+
+	return DeploymentStateRevision{
+		Revision:        stateFile.Revision,
+		Current:         current,
+		SavedAt:         stateFile.SavedAt,
+		DeploymentState: stateFile.DeploymentState,
+	}, nil
+}
+
+// keepRevision copies the current deployment state file into the history and returns its revision.
+// With an encryptor, a plain text state and its history are encrypted first, so that no revision is left readable.
+func (s *fileSystemDeploymentStateService) keepRevision() (int, error) {
+	contents, err := s.fs.ReadFile(s.configPath)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Reading deployment state file '%s'", s.configPath)
+	}
+
+	var revision deploymentStateFileRevision
+	err = json.Unmarshal(contents, &revision)
+	if err != nil {
+		// an unreadable state is kept too, it might be the only copy of it
+		s.logger.Warn(s.logTag, "Failed to read the revision of deployment state file '%s': %s", s.configPath, err.Error())
+	} else if _, encrypted := parseEncryptedDeploymentStateFile(contents); s.encryptor != nil && !encrypted {
+		s.logger.Info(s.logTag, "Encrypting deployment state file '%s' and its history", s.configPath)
+		err = s.rewriteStateFiles(s.encryptor)
+		if err != nil {
+			return 0, bosherr.WrapError(err, "Encrypting the deployment state history")
+		}
+
+		contents, err = s.fs.ReadFile(s.configPath)
+		if err != nil {
+			return 0, bosherr.WrapErrorf(err, "Reading deployment state file '%s'", s.configPath)
+		}
+	}
+
+	revisionPath := s.revisionPath(revision.Revision)
+	err = biutil.WriteFileAtomically(s.fs, revisionPath, contents, os.FileMode(0644))
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Writing deployment state revision '%s'", revisionPath)
+	}
+
+	return revision.Revision, nil
+}
+
+func parseEncryptedDeploymentStateFile(contents []byte) (encryptedDeploymentStateFile, bool) {
+	var encryptedStateFile encryptedDeploymentStateFile
+	if json.Unmarshal(contents, &encryptedStateFile) != nil {
+		return encryptedDeploymentStateFile{}, false
+	}
+
+	return encryptedStateFile, encryptedStateFile.Encrypted != ""
+}
+
+func (s *fileSystemDeploymentStateService) revisionPath(revision int) string {
+	return fmt.Sprintf("%s.%d", s.configPath, revision)
+}
+
 func (s *fileSystemDeploymentStateService) initDefaults(deploymentState *DeploymentState) error {
 	if deploymentState.DirectorID == "" {
 		uuid, err := s.uuidGenerator.Generate()
@@ -111,9 +396,24 @@ func (s *fileSystemDeploymentStateService) initDefaults(deploymentState *Deploym
 }
 
 func (s *fileSystemDeploymentStateService) Cleanup() error {
-	err := s.fs.RemoveAll(s.configPath)
+	history, err := s.History()
+	if err != nil {
+		s.logger.Warn(s.logTag, "Failed to read the deployment state history: %s", err.Error())
+	}
+	for _, revision := range history {
+		if revision.Current {
+			continue
+		}
+		err = s.fs.RemoveAll(s.revisionPath(revision.Revision))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Could not delete deployment state revision %s", s.revisionPath(revision.Revision))
+		}
+	}
+
+	err = s.fs.RemoveAll(s.configPath)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Could not delete deployment state file %s", s.configPath)
 	}
+	s.revision = 0
 	return nil
 }
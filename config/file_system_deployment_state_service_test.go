@@ -2,11 +2,14 @@ package config_test
 
 import (
 	. "github.com/cloudfoundry/bosh-init/config"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
@@ -22,12 +25,16 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 		fakeUUIDGenerator   *fakeuuid.FakeGenerator
 	)
 
+	// newService returns the service of another bosh-init run
+	newService := func() DeploymentStateService {
+		return NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, boshlog.NewLogger(boshlog.LevelNone), deploymentStatePath)
+	}
+
 	BeforeEach(func() {
 		fakeFs = fakesys.NewFakeFileSystem()
 		deploymentStatePath = "/some/deployment.json"
-		logger := boshlog.NewLogger(boshlog.LevelNone)
 		fakeUUIDGenerator = fakeuuid.NewFakeGenerator()
-		service = NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, deploymentStatePath)
+		service = newService()
 	})
 
 	Describe("DeploymentStatePath", func() {
@@ -128,6 +135,27 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 		})
 	})
 
+	Describe("Load with schema versions", func() {
+		It("migrates a deployment state file written before the schema was versioned", func() {
+			fakeFs.WriteFileString(deploymentStatePath, `{"director_id": "fake-director-id", "current_vm_cid": "fake-vm-cid"}`)
+
+			deploymentState, err := service.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploymentState).To(Equal(DeploymentState{
+				DirectorID:   "fake-director-id",
+				CurrentVMCID: "fake-vm-cid",
+			}))
+		})
+
+		It("returns an error when the schema version is newer than supported", func() {
+			fakeFs.WriteFileString(deploymentStatePath, `{"schema_version": 1000, "director_id": "fake-director-id"}`)
+
+			_, err := service.Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Deployment state schema version 1000 is newer than 3, the latest version supported by this bosh-init"))
+		})
+	})
+
 	Describe("Save", func() {
 		It("writes the deployment state to the deployment file", func() {
 			config := DeploymentState{
@@ -176,12 +204,17 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 				},
 			}
 			expectedDeploymentStateFileContents, err := json.MarshalIndent(deploymentState, "", "    ")
-			Expect(deploymentStateFileContents).To(Equal(string(expectedDeploymentStateFileContents)))
+			Expect(deploymentStateFileContents).To(MatchRegexp(`^{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
+`))
+			Expect(deploymentStateFileContents).To(HaveSuffix(string(expectedDeploymentStateFileContents)[2:]))
 		})
 
 		Context("when the deployment file cannot be written", func() {
 			BeforeEach(func() {
-				fakeFs.WriteFileError = errors.New("")
+				fakeFs.OpenFileErr = errors.New("")
 			})
 
 			It("returns an error when it cannot write the config file", func() {
@@ -193,6 +226,291 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 				Expect(err.Error()).To(ContainSubstring("Writing deployment state file '/some/deployment.json'"))
 			})
 		})
+
+		It("replaces the deployment state file atomically", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFs.RenameOldPaths).To(Equal([]string{"/some/deployment.json.tmp"}))
+			Expect(fakeFs.RenameNewPaths).To(Equal([]string{"/some/deployment.json"}))
+			Expect(fakeFs.FileExists("/some/deployment.json.tmp")).To(BeFalse())
+		})
+
+		It("keeps the deployment state replaced by another run as a revision", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+			Expect(err).NotTo(HaveOccurred())
+			err = newService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+			Expect(err).NotTo(HaveOccurred())
+
+			revisionContents, err := fakeFs.ReadFileString("/some/deployment.json.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revisionContents).To(ContainSubstring(`"current_vm_cid": "fake-vm-cid-1"`))
+
+			contents, err := fakeFs.ReadFileString(deploymentStatePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(ContainSubstring(`"revision": 2`))
+			Expect(contents).To(ContainSubstring(`"current_vm_cid": "fake-vm-cid-2"`))
+		})
+
+		It("keeps one revision per run, however often the run saves", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+			Expect(err).NotTo(HaveOccurred())
+
+			run := newService()
+			for i := 2; i <= DeploymentStateHistorySize+2; i++ {
+				err = run.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: fmt.Sprintf("fake-vm-cid-%d", i)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			history, err := run.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(HaveLen(2))
+			Expect(history[0].Revision).To(Equal(2))
+			Expect(history[0].CurrentVMCID).To(Equal(fmt.Sprintf("fake-vm-cid-%d", DeploymentStateHistorySize+2)))
+			Expect(history[1].Revision).To(Equal(1))
+			Expect(history[1].CurrentVMCID).To(Equal("fake-vm-cid-1"))
+		})
+
+		It("keeps only the last revisions", func() {
+			for i := 0; i < DeploymentStateHistorySize+2; i++ {
+				err := newService().Save(DeploymentState{DirectorID: "fake-director-id"})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(fakeFs.FileExists("/some/deployment.json.1")).To(BeFalse())
+			Expect(fakeFs.FileExists("/some/deployment.json.2")).To(BeTrue())
+			Expect(fakeFs.FileExists(fmt.Sprintf("/some/deployment.json.%d", DeploymentStateHistorySize+1))).To(BeTrue())
+		})
+	})
+
+	Describe("History", func() {
+		It("returns the current deployment state and the previous revisions, newest first", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+			Expect(err).NotTo(HaveOccurred())
+			err = newService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+			Expect(err).NotTo(HaveOccurred())
+
+			history, err := service.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(HaveLen(2))
+			Expect(history[0].Revision).To(Equal(2))
+			Expect(history[0].Current).To(BeTrue())
+			Expect(history[0].CurrentVMCID).To(Equal("fake-vm-cid-2"))
+			Expect(history[1].Revision).To(Equal(1))
+			Expect(history[1].Current).To(BeFalse())
+			Expect(history[1].CurrentVMCID).To(Equal("fake-vm-cid-1"))
+		})
+
+		It("returns no revisions when there is no deployment state", func() {
+			history, err := service.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(BeEmpty())
+		})
+	})
+
+	Describe("Rollback", func() {
+		BeforeEach(func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+			Expect(err).NotTo(HaveOccurred())
+			err = newService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("saves the revision as the current deployment state, keeping the replaced one", func() {
+			deploymentState, err := service.Rollback(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid-1"))
+
+			loadedState, err := service.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loadedState.CurrentVMCID).To(Equal("fake-vm-cid-1"))
+
+			history, err := service.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history[0].Revision).To(Equal(3))
+			Expect(history[1].Revision).To(Equal(2))
+			Expect(history[1].CurrentVMCID).To(Equal("fake-vm-cid-2"))
+		})
+
+		It("returns an error when the revision does not exist", func() {
+			_, err := service.Rollback(7)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment state revision 7 does not exist at '/some/deployment.json.7'"))
+		})
+	})
+
+	Describe("encryption", func() {
+		var (
+			encryptedService DeploymentStateService
+			logger           boshlog.Logger
+		)
+
+		newEncryptedService := func() DeploymentStateService {
+			return NewEncryptedFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, deploymentStatePath, bicrypto.NewPassphraseEncryptor("fake-passphrase"))
+		}
+
+		BeforeEach(func() {
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			encryptedService = newEncryptedService()
+		})
+
+		It("saves the deployment state encrypted, keeping the schema version and revision in plain text", func() {
+			err := encryptedService.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fakeFs.ReadFileString(deploymentStatePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).ToNot(ContainSubstring("fake-vm-cid"))
+			Expect(contents).To(ContainSubstring(`"revision": 1`))
+			Expect(contents).To(ContainSubstring(`"encrypted": "` + bicrypto.EncryptedPrefix))
+
+			deploymentState, err := encryptedService.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid"))
+		})
+
+		It("does not log the plain text deployment state", func() {
+			outBuffer := bytes.NewBufferString("")
+			debugLogger := boshlog.NewWriterLogger(boshlog.LevelDebug, outBuffer, outBuffer)
+			encryptedService = NewEncryptedFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, debugLogger, deploymentStatePath, bicrypto.NewPassphraseEncryptor("fake-passphrase"))
+
+			err := encryptedService.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(outBuffer.String()).To(ContainSubstring("Saving encrypted deployment state"))
+			Expect(outBuffer.String()).ToNot(ContainSubstring("fake-vm-cid"))
+		})
+
+		It("loads a plain text deployment state", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})
+			Expect(err).NotTo(HaveOccurred())
+
+			deploymentState, err := encryptedService.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid"))
+		})
+
+		It("returns an error when the deployment state is encrypted and no passphrase or key file was given", func() {
+			err := encryptedService.Save(DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = service.Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment state file '/some/deployment.json' is encrypted, but no passphrase or key file was given"))
+		})
+
+		It("returns an error when the passphrase is wrong", func() {
+			err := encryptedService.Save(DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			wrongService := NewEncryptedFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, deploymentStatePath, bicrypto.NewPassphraseEncryptor("wrong-passphrase"))
+			_, err = wrongService.Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Decryption failed"))
+		})
+
+		It("encrypts the plain text history before keeping the replaced deployment state in it", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+			Expect(err).NotTo(HaveOccurred())
+			err = newService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = encryptedService.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-3"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, path := range []string{deploymentStatePath, deploymentStatePath + ".1", deploymentStatePath + ".2"} {
+				contents, err := fakeFs.ReadFileString(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).ToNot(ContainSubstring("fake-vm-cid"))
+			}
+
+			history, err := encryptedService.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(HaveLen(3))
+			Expect(history[0].CurrentVMCID).To(Equal("fake-vm-cid-3"))
+			Expect(history[1].CurrentVMCID).To(Equal("fake-vm-cid-2"))
+			Expect(history[2].CurrentVMCID).To(Equal("fake-vm-cid-1"))
+		})
+
+		Describe("Encrypt", func() {
+			It("encrypts the deployment state and its history", func() {
+				err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+				Expect(err).NotTo(HaveOccurred())
+				err = newService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+				Expect(err).NotTo(HaveOccurred())
+
+				err = encryptedService.Encrypt()
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, path := range []string{deploymentStatePath, deploymentStatePath + ".1"} {
+					contents, err := fakeFs.ReadFileString(path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(contents).ToNot(ContainSubstring("fake-vm-cid"))
+				}
+
+				history, err := encryptedService.History()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(history).To(HaveLen(2))
+				Expect(history[0].Revision).To(Equal(2))
+				Expect(history[0].CurrentVMCID).To(Equal("fake-vm-cid-2"))
+				Expect(history[1].Revision).To(Equal(1))
+				Expect(history[1].CurrentVMCID).To(Equal("fake-vm-cid-1"))
+			})
+
+			It("returns an error without a passphrase or key file", func() {
+				err := service.Save(DeploymentState{DirectorID: "fake-director-id"})
+				Expect(err).NotTo(HaveOccurred())
+
+				err = service.Encrypt()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Encrypting the deployment state requires a passphrase or key file"))
+			})
+
+			It("returns an error when there is no deployment state", func() {
+				err := encryptedService.Encrypt()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("No deployment state found at '/some/deployment.json'"))
+			})
+		})
+
+		Describe("Decrypt", func() {
+			It("decrypts the deployment state and its history", func() {
+				err := encryptedService.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+				Expect(err).NotTo(HaveOccurred())
+				err = newEncryptedService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+				Expect(err).NotTo(HaveOccurred())
+
+				err = encryptedService.Decrypt()
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := fakeFs.ReadFileString(deploymentStatePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(ContainSubstring("fake-vm-cid-2"))
+				contents, err = fakeFs.ReadFileString(deploymentStatePath + ".1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(ContainSubstring("fake-vm-cid-1"))
+
+				deploymentState, err := service.Load()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deploymentState.CurrentVMCID).To(Equal("fake-vm-cid-2"))
+			})
+
+			It("leaves the files unchanged when a revision cannot be decrypted", func() {
+				err := encryptedService.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+				Expect(err).NotTo(HaveOccurred())
+				err = newEncryptedService().Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+				Expect(err).NotTo(HaveOccurred())
+
+				fakeFs.WriteFileString(deploymentStatePath+".1", `{"schema_version": 1, "revision": 1, "encrypted": "`+bicrypto.EncryptedPrefix+`AAAA"}`)
+
+				err = encryptedService.Decrypt()
+				Expect(err).To(HaveOccurred())
+
+				contents, err := fakeFs.ReadFileString(deploymentStatePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).ToNot(ContainSubstring("fake-vm-cid-2"))
+			})
+		})
 	})
 
 	Describe("Cleanup", func() {
@@ -208,6 +526,20 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 
 		})
 
+		It("deletes the previous revisions", func() {
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).NotTo(HaveOccurred())
+			err = newService().Save(DeploymentState{DirectorID: "fake-director-id"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeFs.FileExists("/some/deployment.json.1")).To(BeTrue())
+
+			err = service.Cleanup()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeFs.FileExists("/some/deployment.json.1")).To(BeFalse())
+			Expect(service.Exists()).To(BeFalse())
+		})
+
 		It("returns error if delete opertation fails to remove file", func() {
 			fakeFs.RemoveAllStub = func(_ string) error {
 				return errors.New("Could not do that Dave")
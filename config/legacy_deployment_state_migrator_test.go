@@ -112,6 +112,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "",
@@ -164,6 +167,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "fake-uuid-0",
     "installation_id": "",
     "current_vm_cid": "",
@@ -216,6 +222,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "",
@@ -268,6 +277,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "i-a1624150",
@@ -334,6 +346,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "i-a1624150",
@@ -386,6 +401,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "",
@@ -445,6 +463,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "",
@@ -497,6 +518,9 @@ registry_instances:
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(content).To(MatchRegexp(`{
+    "schema_version": 3,
+    "revision": 1,
+    "saved_at": "[^"]+",
     "director_id": "bm-5480c6bb-3ba8-449a-a262-a2e75fbe5daf",
     "installation_id": "",
     "current_vm_cid": "",
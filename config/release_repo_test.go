@@ -224,7 +224,7 @@ func rootDesc() {
 
 		Context("when the config service fails to save", func() {
 			BeforeEach(func() {
-				fs.WriteFileError = errors.New("kaboom")
+				fs.OpenFileErr = errors.New("kaboom")
 			})
 
 			It("returns an error", func() {
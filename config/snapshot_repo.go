@@ -0,0 +1,115 @@
+package config
+
+import (
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+)
+
+type SnapshotRepo interface {
+	Save(cid string, diskRecord DiskRecord, createdAt time.Time) (SnapshotRecord, error)
+	Find(cid string) (SnapshotRecord, bool, error)
+	All() ([]SnapshotRecord, error)
+	Delete(SnapshotRecord) error
+}
+
+type snapshotRepo struct {
+	deploymentStateService DeploymentStateService
+	uuidGenerator          boshuuid.Generator
+}
+
+func NewSnapshotRepo(deploymentStateService DeploymentStateService, uuidGenerator boshuuid.Generator) SnapshotRepo {
+	return snapshotRepo{
+		deploymentStateService: deploymentStateService,
+		uuidGenerator:          uuidGenerator,
+	}
+}
+
+func (r snapshotRepo) Save(cid string, diskRecord DiskRecord, createdAt time.Time) (SnapshotRecord, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return SnapshotRecord{}, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	oldRecord, found := r.find(deploymentState.Snapshots, cid)
+	if found {
+		return SnapshotRecord{}, bosherr.Errorf("Failed to save snapshot cid '%s', existing record found '%#v'", cid, oldRecord)
+	}
+
+	newRecord := SnapshotRecord{
+		CID:             cid,
+		DiskCID:         diskRecord.CID,
+		DiskName:        diskRecord.Name,
+		Size:            diskRecord.Size,
+		CloudProperties: diskRecord.CloudProperties,
+		CreatedAt:       createdAt,
+	}
+	newRecord.ID, err = r.uuidGenerator.Generate()
+	if err != nil {
+		return newRecord, bosherr.WrapError(err, "Generating snapshot id")
+	}
+
+	deploymentState.Snapshots = append(deploymentState.Snapshots, newRecord)
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return newRecord, bosherr.WrapError(err, "Saving new config")
+	}
+	return newRecord, nil
+}
+
+func (r snapshotRepo) Find(cid string) (SnapshotRecord, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return SnapshotRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	foundRecord, found := r.find(deploymentState.Snapshots, cid)
+	return foundRecord, found, nil
+}
+
+func (r snapshotRepo) All() ([]SnapshotRecord, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return []SnapshotRecord{}, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	if deploymentState.Snapshots == nil {
+		return []SnapshotRecord{}, nil
+	}
+
+	return deploymentState.Snapshots, nil
+}
+
+func (r snapshotRepo) Delete(snapshotRecord SnapshotRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	newRecords := []SnapshotRecord{}
+	for _, record := range deploymentState.Snapshots {
+		if record.ID != snapshotRecord.ID {
+			newRecords = append(newRecords, record)
+		}
+	}
+
+	deploymentState.Snapshots = newRecords
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
+
+func (r snapshotRepo) find(records []SnapshotRecord, cid string) (SnapshotRecord, bool) {
+	for _, existingRecord := range records {
+		if existingRecord.CID == cid {
+			return existingRecord, true
+		}
+	}
+	return SnapshotRecord{}, false
+}
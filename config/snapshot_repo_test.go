@@ -0,0 +1,127 @@
+package config_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("SnapshotRepo", func() {
+	var (
+		deploymentStateService DeploymentStateService
+		repo                   SnapshotRepo
+		fs                     *fakesys.FakeFileSystem
+		fakeUUIDGenerator      *fakeuuid.FakeGenerator
+		diskRecord             DiskRecord
+		createdAt              time.Time
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs = fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator = &fakeuuid.FakeGenerator{}
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		repo = NewSnapshotRepo(deploymentStateService, fakeUUIDGenerator)
+		diskRecord = DiskRecord{
+			ID:   "fake-disk-id",
+			Name: "fake-disk-name",
+			CID:  "fake-disk-cid",
+			Size: 1024,
+			CloudProperties: biproperty.Map{
+				"fake-cloud_property-key": "fake-cloud-property-value",
+			},
+		}
+		createdAt = time.Date(2016, time.March, 4, 13, 14, 15, 0, time.UTC)
+	})
+
+	Describe("Save", func() {
+		It("saves the snapshot record with the configuration of its disk", func() {
+			record, err := repo.Save("fake-snapshot-cid", diskRecord, createdAt)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(record).To(Equal(SnapshotRecord{
+				ID:              "fake-uuid-1",
+				CID:             "fake-snapshot-cid",
+				DiskCID:         "fake-disk-cid",
+				DiskName:        "fake-disk-name",
+				Size:            1024,
+				CloudProperties: diskRecord.CloudProperties,
+				CreatedAt:       createdAt,
+			}))
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Snapshots).To(Equal([]SnapshotRecord{record}))
+		})
+
+		It("returns an error when a snapshot with the same cid exists", func() {
+			_, err := repo.Save("fake-snapshot-cid", diskRecord, createdAt)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = repo.Save("fake-snapshot-cid", diskRecord, createdAt)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Failed to save snapshot cid 'fake-snapshot-cid'"))
+		})
+	})
+
+	Describe("Find", func() {
+		It("finds existing snapshot records", func() {
+			savedRecord, err := repo.Save("fake-snapshot-cid", diskRecord, createdAt)
+			Expect(err).ToNot(HaveOccurred())
+
+			foundRecord, found, err := repo.Find("fake-snapshot-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(foundRecord).To(Equal(savedRecord))
+		})
+
+		It("when the snapshot is not in the records, returns not found", func() {
+			_, found, err := repo.Find("fake-snapshot-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Describe("All", func() {
+		It("returns no snapshots when none were taken", func() {
+			snapshots, err := repo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+		})
+
+		It("returns all snapshots", func() {
+			firstSnapshot, err := repo.Save("fake-snapshot-cid-1", diskRecord, createdAt)
+			Expect(err).ToNot(HaveOccurred())
+
+			secondSnapshot, err := repo.Save("fake-snapshot-cid-2", diskRecord, createdAt.Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+
+			snapshots, err := repo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(Equal([]SnapshotRecord{firstSnapshot, secondSnapshot}))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("deletes the snapshot record", func() {
+			firstSnapshot, err := repo.Save("fake-snapshot-cid-1", diskRecord, createdAt)
+			Expect(err).ToNot(HaveOccurred())
+
+			secondSnapshot, err := repo.Save("fake-snapshot-cid-2", diskRecord, createdAt)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = repo.Delete(firstSnapshot)
+			Expect(err).ToNot(HaveOccurred())
+
+			snapshots, err := repo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(Equal([]SnapshotRecord{secondSnapshot}))
+		})
+	})
+})
@@ -7,6 +7,8 @@ import (
 type VMRepo interface {
 	FindCurrent() (cid string, found bool, err error)
 	UpdateCurrent(cid string) error
+	FindCurrentConfig() (config VMConfigRecord, found bool, err error)
+	UpdateCurrentConfig(config VMConfigRecord) error
 	ClearCurrent() error
 }
 
@@ -49,6 +51,34 @@ func (r vMRepo) UpdateCurrent(cid string) error {
 	return nil
 }
 
+func (r vMRepo) FindCurrentConfig() (VMConfigRecord, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return VMConfigRecord{}, false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	if deploymentState.CurrentVMCID == "" || deploymentState.CurrentVMConfig == nil {
+		return VMConfigRecord{}, false, nil
+	}
+
+	return *deploymentState.CurrentVMConfig, true, nil
+}
+
+func (r vMRepo) UpdateCurrentConfig(config VMConfigRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.CurrentVMConfig = &config
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+	return nil
+}
+
 func (r vMRepo) ClearCurrent() error {
 	deploymentState, err := r.deploymentStateService.Load()
 	if err != nil {
@@ -56,6 +86,7 @@ func (r vMRepo) ClearCurrent() error {
 	}
 
 	deploymentState.CurrentVMCID = ""
+	deploymentState.CurrentVMConfig = nil
 
 	err = r.deploymentStateService.Save(deploymentState)
 	if err != nil {
@@ -3,6 +3,7 @@ package config_test
 import (
 	. "github.com/cloudfoundry/bosh-init/config"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 	. "github.com/onsi/ginkgo"
@@ -65,6 +66,61 @@ var _ = Describe("VMRepo", func() {
 		})
 	})
 
+	Describe("FindCurrentConfig", func() {
+		Context("when the config of the current vm is set", func() {
+			BeforeEach(func() {
+				err := repo.UpdateCurrent("fake-vm-cid")
+				Expect(err).ToNot(HaveOccurred())
+
+				err = repo.UpdateCurrentConfig(VMConfigRecord{
+					StemcellCID:     "fake-stemcell-cid",
+					CloudProperties: biproperty.Map{"fake-cloud-property-key": "fake-cloud-property-value"},
+					Networks: map[string]biproperty.Map{
+						"fake-network-name": biproperty.Map{"ip": "fake-ip"},
+					},
+					Env: biproperty.Map{"fake-env-key": "fake-env-value"},
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the config", func() {
+				config, found, err := repo.FindCurrentConfig()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(config).To(Equal(VMConfigRecord{
+					StemcellCID:     "fake-stemcell-cid",
+					CloudProperties: biproperty.Map{"fake-cloud-property-key": "fake-cloud-property-value"},
+					Networks: map[string]biproperty.Map{
+						"fake-network-name": biproperty.Map{"ip": "fake-ip"},
+					},
+					Env: biproperty.Map{"fake-env-key": "fake-env-value"},
+				}))
+			})
+
+			It("is cleared with the current vm", func() {
+				err := repo.ClearCurrent()
+				Expect(err).ToNot(HaveOccurred())
+
+				_, found, err := repo.FindCurrentConfig()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+			})
+		})
+
+		Context("when the config of the current vm was not recorded", func() {
+			BeforeEach(func() {
+				err := repo.UpdateCurrent("fake-vm-cid")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns false", func() {
+				_, found, err := repo.FindCurrentConfig()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+
 	Describe("ClearCurrent", func() {
 		It("updates vm cid", func() {
 			err := repo.ClearCurrent()
@@ -16,20 +16,35 @@ type CpiInstaller struct {
 
 func (i CpiInstaller) ValidateCpiRelease(installationManifest biinstallmanifest.Manifest, stage biui.Stage) error {
 	return stage.Perform("Validating cpi release", func() error {
-		cpiReleaseName := installationManifest.Template.Release
-		cpiRelease, found := i.ReleaseManager.Find(cpiReleaseName)
-		if !found {
-			return bosherr.Errorf("installation release '%s' must refer to a provided release", cpiReleaseName)
+		err := i.validateCloudProvider(installationManifest.DefaultCloudProvider())
+		if err != nil {
+			return err
 		}
 
-		err := i.Validator.Validate(cpiRelease, installationManifest.Template.Name)
-		if err != nil {
-			return bosherr.WrapErrorf(err, "Invalid CPI release '%s'", cpiReleaseName)
+		for _, cloudProvider := range installationManifest.CloudProviders {
+			err = i.validateCloudProvider(cloudProvider)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Validating cloud provider '%s'", cloudProvider.Name)
+			}
 		}
 		return nil
 	})
 }
 
+func (i CpiInstaller) validateCloudProvider(cloudProvider biinstallmanifest.CloudProvider) error {
+	cpiReleaseName := cloudProvider.Template.Release
+	cpiRelease, found := i.ReleaseManager.Find(cpiReleaseName)
+	if !found {
+		return bosherr.Errorf("installation release '%s' must refer to a provided release", cpiReleaseName)
+	}
+
+	err := i.Validator.Validate(cpiRelease, cloudProvider.Template.Name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Invalid CPI release '%s'", cpiReleaseName)
+	}
+	return nil
+}
+
 func (i CpiInstaller) installCpiRelease(installer biinstall.Installer, installationManifest biinstallmanifest.Manifest, target biinstall.Target, stage biui.Stage) (biinstall.Installation, error) {
 	var installation biinstall.Installation
 	var err error
@@ -64,8 +79,20 @@ func (i CpiInstaller) WithInstalledCpiRelease(installationManifest biinstallmani
 	return
 }
 
+// InstallCpiRelease installs the CPI release for more than a single call, e.g. a whole watch session.
+// The rendered CPI jobs are kept until CleanupCpiRelease deletes them.
+func (i CpiInstaller) InstallCpiRelease(installationManifest biinstallmanifest.Manifest, target biinstall.Target, stage biui.Stage) (biinstall.Installation, error) {
+	return i.installCpiRelease(i.InstallerFactory.NewInstaller(target), installationManifest, target, stage)
+}
+
+// CleanupCpiRelease deletes the rendered CPI jobs of an installation made by InstallCpiRelease
+func (i CpiInstaller) CleanupCpiRelease(installation biinstall.Installation, target biinstall.Target, stage biui.Stage) error {
+	return i.cleanupInstall(installation, i.InstallerFactory.NewInstaller(target), stage)
+}
+
 func (i CpiInstaller) cleanupInstall(installation biinstall.Installation, installer biinstall.Installer, stage biui.Stage) error {
-	return stage.Perform("Cleaning up rendered CPI jobs", func() error {
+	// cleaning up is not skipped when interrupted
+	return biui.Uninterruptible(stage).Perform("Cleaning up rendered CPI jobs", func() error {
 		return installer.Cleanup(installation)
 	})
 }
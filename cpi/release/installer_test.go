@@ -212,4 +212,57 @@ var _ = Describe("Installer", func() {
 			})
 		})
 	})
+
+	Describe("InstallCpiRelease", func() {
+		var (
+			mockCtrl             *gomock.Controller
+			mockInstaller        *mocks.MockInstaller
+			mockInstallerFactory *mock_install.MockInstallerFactory
+			installStage         *fakeui.FakeStage
+			installation         *mocks.MockInstallation
+			target               biinstallation.Target
+			cpiInstaller         release.CpiInstaller
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockInstaller = mocks.NewMockInstaller(mockCtrl)
+			mockInstallerFactory = mock_install.NewMockInstallerFactory(mockCtrl)
+			installStage = fakeui.NewFakeStage()
+			installation = mocks.NewMockInstallation(mockCtrl)
+
+			target = biinstallation.NewTarget("fake-installation-path")
+			mockInstallerFactory.EXPECT().NewInstaller(target).Return(mockInstaller).AnyTimes()
+
+			cpiInstaller = release.CpiInstaller{
+				InstallerFactory: mockInstallerFactory,
+			}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("installs the CPI and keeps it until CleanupCpiRelease is called", func() {
+			mockInstaller.EXPECT().Install(biinstallationmanifest.Manifest{}, gomock.Any()).Return(installation, nil)
+
+			installed, err := cpiInstaller.InstallCpiRelease(biinstallationmanifest.Manifest{}, target, installStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(installed).To(Equal(installation))
+
+			mockInstaller.EXPECT().Cleanup(installation).Return(nil)
+
+			err = cpiInstaller.CleanupCpiRelease(installed, target, installStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(installStage.PerformCalls).To(ContainElement(&fakeui.PerformCall{Name: "Cleaning up rendered CPI jobs"}))
+		})
+
+		It("returns the error when installing the CPI fails", func() {
+			mockInstaller.EXPECT().Install(biinstallationmanifest.Manifest{}, gomock.Any()).Return(nil, errors.New("fake-install-error"))
+
+			_, err := cpiInstaller.InstallCpiRelease(biinstallationmanifest.Manifest{}, target, installStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-install-error"))
+		})
+	})
 })
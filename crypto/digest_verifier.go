@@ -0,0 +1,105 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	DigestAlgorithmSHA1   = "sha1"
+	DigestAlgorithmSHA256 = "sha256"
+)
+
+// Digest is the expected checksum of a file. Wherever a SHA1 is accepted a SHA256 can be given instead,
+// either prefixed with its algorithm ('sha256:<hex>') or as 64 hex characters.
+type Digest struct {
+	Algorithm string
+	Value     string
+}
+
+func ParseDigest(digest string) Digest {
+	if parts := strings.SplitN(digest, ":", 2); len(parts) == 2 {
+		return Digest{Algorithm: strings.ToLower(parts[0]), Value: parts[1]}
+	}
+
+	if len(digest) == sha256HexLength && isHex(digest) {
+		return Digest{Algorithm: DigestAlgorithmSHA256, Value: digest}
+	}
+
+	return Digest{Algorithm: DigestAlgorithmSHA1, Value: digest}
+}
+
+func (d Digest) String() string {
+	if d.Algorithm == DigestAlgorithmSHA1 {
+		return d.Value
+	}
+	return fmt.Sprintf("%s:%s", d.Algorithm, d.Value)
+}
+
+const sha256HexLength = 64
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// DigestMismatchError is returned when a file does not have the expected digest
+type DigestMismatchError struct {
+	Algorithm string
+	Actual    string
+	Expected  string
+}
+
+func (e DigestMismatchError) Error() string {
+	algorithm := strings.ToUpper(e.Algorithm)
+	return fmt.Sprintf("%s '%s' does not match expected %s '%s'", algorithm, e.Actual, algorithm, e.Expected)
+}
+
+type DigestVerifier interface {
+	// Verify returns a DigestMismatchError unless the file at filePath has the expected SHA1 or SHA256 digest
+	Verify(filePath string, expectedDigest string) error
+}
+
+type digestVerifier struct {
+	sha1Calculator   SHA1Calculator
+	sha256Calculator SHA256Calculator
+}
+
+func NewDigestVerifier(sha1Calculator SHA1Calculator, sha256Calculator SHA256Calculator) DigestVerifier {
+	return digestVerifier{
+		sha1Calculator:   sha1Calculator,
+		sha256Calculator: sha256Calculator,
+	}
+}
+
+func (v digestVerifier) Verify(filePath string, expectedDigest string) error {
+	digest := ParseDigest(expectedDigest)
+
+	var (
+		actual string
+		err    error
+	)
+	switch digest.Algorithm {
+	case DigestAlgorithmSHA1:
+		actual, err = v.sha1Calculator.Calculate(filePath)
+	case DigestAlgorithmSHA256:
+		actual, err = v.sha256Calculator.Calculate(filePath)
+	default:
+		return bosherr.Errorf("Unsupported digest algorithm '%s', expected '%s' or '%s'", digest.Algorithm, DigestAlgorithmSHA1, DigestAlgorithmSHA256)
+	}
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Calculating %s of '%s'", digest.Algorithm, filePath)
+	}
+
+	if !strings.EqualFold(actual, digest.Value) {
+		return DigestMismatchError{Algorithm: digest.Algorithm, Actual: actual, Expected: digest.Value}
+	}
+
+	return nil
+}
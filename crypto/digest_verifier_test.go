@@ -0,0 +1,69 @@
+package crypto_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/crypto"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DigestVerifier", func() {
+	var (
+		fs             *fakesys.FakeFileSystem
+		digestVerifier DigestVerifier
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		digestVerifier = NewDigestVerifier(NewSha1Calculator(fs), NewSha256Calculator(fs))
+
+		fs.RegisterOpenFile("/fake-archive-path", &fakesys.FakeFile{
+			Contents: []byte("fake-archive-contents"),
+			Stats:    &fakesys.FakeFileStats{FileType: fakesys.FakeFileTypeFile},
+		})
+	})
+
+	Describe("Verify", func() {
+		It("accepts a matching SHA1", func() {
+			err := digestVerifier.Verify("/fake-archive-path", "4603db250d7b5b78dfe17869649784353177b549")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts a matching SHA256 prefixed with its algorithm", func() {
+			err := digestVerifier.Verify("/fake-archive-path", "sha256:7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts a matching SHA256 without a prefix", func() {
+			err := digestVerifier.Verify("/fake-archive-path", "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns a mismatch error when the digest does not match", func() {
+			err := digestVerifier.Verify("/fake-archive-path", "sha256:fake-sha256")
+			Expect(err).To(Equal(DigestMismatchError{
+				Algorithm: "sha256",
+				Actual:    "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7",
+				Expected:  "fake-sha256",
+			}))
+			Expect(err.Error()).To(Equal("SHA256 '7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7' does not match expected SHA256 'fake-sha256'"))
+		})
+
+		It("returns an error for an unsupported algorithm", func() {
+			err := digestVerifier.Verify("/fake-archive-path", "md5:fake-md5")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Unsupported digest algorithm 'md5', expected 'sha1' or 'sha256'"))
+		})
+	})
+})
+
+var _ = Describe("ParseDigest", func() {
+	It("treats a digest without a prefix as a SHA1, unless it is as long as a SHA256", func() {
+		Expect(ParseDigest("fake-sha1")).To(Equal(Digest{Algorithm: "sha1", Value: "fake-sha1"}))
+		Expect(ParseDigest("sha1:fake-sha1")).To(Equal(Digest{Algorithm: "sha1", Value: "fake-sha1"}))
+		Expect(ParseDigest("7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7")).To(Equal(Digest{
+			Algorithm: "sha256",
+			Value:     "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7",
+		}))
+	})
+})
@@ -0,0 +1,169 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// EncryptedPrefix starts every value returned by Encryptor.Encrypt
+const EncryptedPrefix = "aes256gcm-pbkdf2:"
+
+const (
+	encryptionSaltLength      = 16
+	encryptionKeyLength       = 32
+	encryptionPBKDF2Iteration = 100000
+)
+
+// ErrDecryption is returned when a value cannot be decrypted with the given passphrase or key file
+var ErrDecryption = errors.New("Decryption failed, the passphrase or key file is wrong or the data is corrupted")
+
+type Encryptor interface {
+	// Encrypt returns the plaintext encrypted with AES-256-GCM, using a key derived from the passphrase with PBKDF2-SHA256.
+	// The result is text: EncryptedPrefix followed by the base64 of the salt, nonce and ciphertext.
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(encrypted string) ([]byte, error)
+}
+
+// IsEncrypted returns true if value was returned by Encryptor.Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+type passphraseEncryptor struct {
+	passphrase func() ([]byte, error)
+
+	lock sync.Mutex
+	salt []byte
+	// keys caches the keys derived for each salt, key derivation is deliberately slow
+	keys map[string][]byte
+}
+
+func NewPassphraseEncryptor(passphrase string) Encryptor {
+	return &passphraseEncryptor{
+		passphrase: func() ([]byte, error) { return []byte(passphrase), nil },
+		keys:       map[string][]byte{},
+	}
+}
+
+// NewKeyFileEncryptor uses the contents of the key file, without surrounding whitespace, as the passphrase.
+// The key file is read when the first value is encrypted or decrypted.
+func NewKeyFileEncryptor(fs boshsys.FileSystem, keyFilePath string) Encryptor {
+	return &passphraseEncryptor{
+		passphrase: func() ([]byte, error) {
+			contents, err := fs.ReadFile(keyFilePath)
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Reading key file '%s'", keyFilePath)
+			}
+
+			passphrase := bytes.TrimSpace(contents)
+			if len(passphrase) == 0 {
+				return nil, bosherr.Errorf("Key file '%s' is empty", keyFilePath)
+			}
+
+			return passphrase, nil
+		},
+		keys: map[string][]byte{},
+	}
+}
+
+func (e *passphraseEncryptor) Encrypt(plaintext []byte) (string, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.salt == nil {
+		salt := make([]byte, encryptionSaltLength)
+		_, err := rand.Read(salt)
+		if err != nil {
+			return "", bosherr.WrapError(err, "Generating salt")
+		}
+		e.salt = salt
+	}
+
+	gcm, err := e.cipher(e.salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Generating nonce")
+	}
+
+	sealed := append(append([]byte{}, e.salt...), nonce...)
+	sealed = gcm.Seal(sealed, nonce, plaintext, nil)
+
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (e *passphraseEncryptor) Decrypt(encrypted string) ([]byte, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !IsEncrypted(encrypted) {
+		return nil, bosherr.Errorf("Expected encrypted value to start with '%s'", EncryptedPrefix)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, EncryptedPrefix))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Decoding encrypted value")
+	}
+
+	if len(sealed) < encryptionSaltLength {
+		return nil, ErrDecryption
+	}
+	salt := sealed[:encryptionSaltLength]
+
+	gcm, err := e.cipher(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < encryptionSaltLength+gcm.NonceSize() {
+		return nil, ErrDecryption
+	}
+	nonce := sealed[encryptionSaltLength : encryptionSaltLength+gcm.NonceSize()]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed[encryptionSaltLength+gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	return plaintext, nil
+}
+
+func (e *passphraseEncryptor) cipher(salt []byte) (cipher.AEAD, error) {
+	key, found := e.keys[string(salt)]
+	if !found {
+		passphrase, err := e.passphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		key = pbkdf2.Key(passphrase, salt, encryptionPBKDF2Iteration, encryptionKeyLength, sha256.New)
+		e.keys[string(salt)] = key
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating AES cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating GCM cipher")
+	}
+
+	return gcm, nil
+}
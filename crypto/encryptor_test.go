@@ -0,0 +1,118 @@
+package crypto_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/crypto"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encryptor", func() {
+	Describe("NewPassphraseEncryptor", func() {
+		var encryptor Encryptor
+
+		BeforeEach(func() {
+			encryptor = NewPassphraseEncryptor("fake-passphrase")
+		})
+
+		It("decrypts what it encrypted", func() {
+			encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encrypted).To(HavePrefix(EncryptedPrefix))
+			Expect(encrypted).ToNot(ContainSubstring("fake-plaintext"))
+			Expect(IsEncrypted(encrypted)).To(BeTrue())
+
+			plaintext, err := encryptor.Decrypt(encrypted)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(plaintext)).To(Equal("fake-plaintext"))
+		})
+
+		It("encrypts the same plaintext differently every time", func() {
+			encrypted1, err := encryptor.Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+			encrypted2, err := encryptor.Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encrypted1).ToNot(Equal(encrypted2))
+		})
+
+		It("decrypts values encrypted by another encryptor with the same passphrase", func() {
+			encrypted, err := NewPassphraseEncryptor("fake-passphrase").Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+
+			plaintext, err := encryptor.Decrypt(encrypted)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(plaintext)).To(Equal("fake-plaintext"))
+		})
+
+		It("returns an error when the passphrase is wrong", func() {
+			encrypted, err := NewPassphraseEncryptor("wrong-passphrase").Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = encryptor.Decrypt(encrypted)
+			Expect(err).To(Equal(ErrDecryption))
+		})
+
+		It("returns an error when the encrypted value was modified", func() {
+			encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+
+			// change a character in the middle, the low bits of the last ones can be padding
+			modified := []byte(encrypted)
+			middle := len(modified) / 2
+			if modified[middle] == 'A' {
+				modified[middle] = 'B'
+			} else {
+				modified[middle] = 'A'
+			}
+
+			_, err = encryptor.Decrypt(string(modified))
+			Expect(err).To(Equal(ErrDecryption))
+		})
+
+		It("returns an error when the value is not encrypted", func() {
+			Expect(IsEncrypted("fake-plaintext")).To(BeFalse())
+
+			_, err := encryptor.Decrypt("fake-plaintext")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected encrypted value to start with '" + EncryptedPrefix + "'"))
+		})
+
+		It("returns an error when the encrypted value is truncated", func() {
+			_, err := encryptor.Decrypt(EncryptedPrefix + "AAAA")
+			Expect(err).To(Equal(ErrDecryption))
+		})
+	})
+
+	Describe("NewKeyFileEncryptor", func() {
+		var fs *fakesys.FakeFileSystem
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+		})
+
+		It("uses the contents of the key file without surrounding whitespace as the passphrase", func() {
+			fs.WriteFileString("/fake-key-file", "fake-passphrase\n")
+
+			encrypted, err := NewKeyFileEncryptor(fs, "/fake-key-file").Encrypt([]byte("fake-plaintext"))
+			Expect(err).ToNot(HaveOccurred())
+
+			plaintext, err := NewPassphraseEncryptor("fake-passphrase").Decrypt(encrypted)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(plaintext)).To(Equal("fake-plaintext"))
+		})
+
+		It("returns an error when the key file cannot be read", func() {
+			_, err := NewKeyFileEncryptor(fs, "/fake-key-file").Encrypt([]byte("fake-plaintext"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading key file '/fake-key-file'"))
+		})
+
+		It("returns an error when the key file is empty", func() {
+			fs.WriteFileString("/fake-key-file", " \n")
+
+			_, err := NewKeyFileEncryptor(fs, "/fake-key-file").Encrypt([]byte("fake-plaintext"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Key file '/fake-key-file' is empty"))
+		})
+	})
+})
@@ -2,10 +2,12 @@ package crypto
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
 	"hash"
 	"io"
 	"os"
+	"strings"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -15,20 +17,37 @@ type SHA1Calculator interface {
 	Calculate(filePath string) (string, error)
 }
 
-type sha1Calculator struct {
-	fs boshsys.FileSystem
+type SHA256Calculator interface {
+	Calculate(filePath string) (string, error)
+}
+
+// digestCalculator calculates the hex digest of a file, or of all files in a directory
+type digestCalculator struct {
+	fs        boshsys.FileSystem
+	newHash   func() hash.Hash
+	algorithm string
 }
 
 func NewSha1Calculator(fs boshsys.FileSystem) SHA1Calculator {
-	return sha1Calculator{
-		fs: fs,
+	return digestCalculator{
+		fs:        fs,
+		newHash:   sha1.New,
+		algorithm: "sha1",
+	}
+}
+
+func NewSha256Calculator(fs boshsys.FileSystem) SHA256Calculator {
+	return digestCalculator{
+		fs:        fs,
+		newHash:   sha256.New,
+		algorithm: "sha256",
 	}
 }
 
-func (c sha1Calculator) Calculate(filePath string) (string, error) {
+func (c digestCalculator) Calculate(filePath string) (string, error) {
 	file, err := c.fs.OpenFile(filePath, os.O_RDONLY, 0)
 	if err != nil {
-		return "", bosherr.WrapErrorf(err, "Calculating sha1 of '%s'", filePath)
+		return "", bosherr.WrapErrorf(err, "Calculating %s of '%s'", c.algorithm, filePath)
 	}
 	defer func() {
 		_ = file.Close()
@@ -36,17 +55,17 @@ func (c sha1Calculator) Calculate(filePath string) (string, error) {
 
 	fileInfo, err := file.Stat()
 	if err != nil {
-		return "", bosherr.WrapErrorf(err, "Opening file '%s' for sha1 calculation", filePath)
+		return "", bosherr.WrapErrorf(err, "Opening file '%s' for %s calculation", filePath, c.algorithm)
 	}
 
-	h := sha1.New()
+	h := c.newHash()
 
 	if fileInfo.IsDir() {
 		err = c.fs.Walk(filePath+"/", func(path string, info os.FileInfo, err error) error {
 			if !info.IsDir() {
-				err := c.populateSha1(path, h)
+				err := c.populateDigest(path, h)
 				if err != nil {
-					return bosherr.WrapErrorf(err, "Calculating directory SHA1 for %s", path)
+					return bosherr.WrapErrorf(err, "Calculating directory %s for %s", strings.ToUpper(c.algorithm), path)
 				}
 			}
 			return nil
@@ -55,19 +74,19 @@ func (c sha1Calculator) Calculate(filePath string) (string, error) {
 			return "", err
 		}
 	} else {
-		err = c.populateSha1(filePath, h)
+		err = c.populateDigest(filePath, h)
 		if err != nil {
-			return "", bosherr.WrapErrorf(err, "Calculating file SHA1 for %s", filePath)
+			return "", bosherr.WrapErrorf(err, "Calculating file %s for %s", strings.ToUpper(c.algorithm), filePath)
 		}
 	}
 
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
-func (c sha1Calculator) populateSha1(filePath string, hash hash.Hash) error {
+func (c digestCalculator) populateDigest(filePath string, hash hash.Hash) error {
 	file, err := c.fs.OpenFile(filePath, os.O_RDONLY, 0)
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Opening file '%s' for sha1 calculation", filePath)
+		return bosherr.WrapErrorf(err, "Opening file '%s' for %s calculation", filePath, c.algorithm)
 	}
 	defer func() {
 		_ = file.Close()
@@ -75,7 +94,7 @@ func (c sha1Calculator) populateSha1(filePath string, hash hash.Hash) error {
 
 	_, err = io.Copy(hash, file)
 	if err != nil {
-		return bosherr.WrapError(err, "Copying file for sha1 calculation")
+		return bosherr.WrapErrorf(err, "Copying file for %s calculation", c.algorithm)
 	}
 
 	return nil
@@ -1,6 +1,7 @@
 package deployment
 
 import (
+	"strings"
 	"time"
 
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
@@ -24,6 +25,8 @@ type Deployer interface {
 		biinstallmanifest.Registry,
 		bivm.Manager,
 		biblobstore.Blobstore,
+		bool,
+		bool,
 		biui.Stage,
 	) (Deployment, error)
 }
@@ -58,13 +61,33 @@ func (d *deployer) Deploy(
 	registryConfig biinstallmanifest.Registry,
 	vmManager bivm.Manager,
 	blobstore biblobstore.Blobstore,
+	recreate bool,
+	skipDrain bool,
 	deployStage biui.Stage,
 ) (Deployment, error) {
 	instanceManager := d.instanceManagerFactory.NewManager(cloud, vmManager, blobstore)
+	stemcells := []bistemcell.CloudStemcell{cloudStemcell}
+	drainOptions := biinstance.NewDrainOptions(deploymentManifest.Update, skipDrain)
+
+	if !recreate {
+		updateInPlace, err := d.canUpdateInPlace(vmManager, deploymentManifest, cloudStemcell, registryConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		if updateInPlace {
+			instances, disks, err := d.updateAllInstances(deploymentManifest, instanceManager, registryConfig, drainOptions, deployStage)
+			if err != nil {
+				return nil, err
+			}
+
+			return d.deploymentFactory.NewDeployment(instances, disks, stemcells), nil
+		}
+	}
 
 	pingTimeout := 10 * time.Second
 	pingDelay := 500 * time.Millisecond
-	if err := instanceManager.DeleteAll(pingTimeout, pingDelay, deployStage); err != nil {
+	if err := instanceManager.DeleteAll(pingTimeout, pingDelay, drainOptions, deployStage); err != nil {
 		return nil, err
 	}
 
@@ -73,7 +96,6 @@ func (d *deployer) Deploy(
 		return nil, err
 	}
 
-	stemcells := []bistemcell.CloudStemcell{cloudStemcell}
 	return d.deploymentFactory.NewDeployment(instances, disks, stemcells), nil
 }
 
@@ -87,14 +109,11 @@ func (d *deployer) createAllInstances(
 	instances := []biinstance.Instance{}
 	disks := []bidisk.Disk{}
 
-	if len(deploymentManifest.Jobs) != 1 {
-		return instances, disks, bosherr.Errorf("There must only be one job, found %d", len(deploymentManifest.Jobs))
+	if err := d.validateJobs(deploymentManifest); err != nil {
+		return instances, disks, err
 	}
 
 	for _, jobSpec := range deploymentManifest.Jobs {
-		if jobSpec.Instances != 1 {
-			return instances, disks, bosherr.Errorf("Job '%s' must have only one instance, found %d", jobSpec.Name, jobSpec.Instances)
-		}
 		for instanceID := 0; instanceID < jobSpec.Instances; instanceID++ {
 			instance, instanceDisks, err := instanceManager.Create(jobSpec.Name, instanceID, deploymentManifest, cloudStemcell, registryConfig, deployStage)
 			if err != nil {
@@ -112,3 +131,98 @@ func (d *deployer) createAllInstances(
 
 	return instances, disks, nil
 }
+
+// canUpdateInPlace returns true if the currently deployed VM still exists
+// and was created with the same stemcell, cloud properties, networks & env.
+// A registry that is not persistent lost the settings the CPI stored for the VM,
+// which the CPI needs to attach disks, so the VM is recreated then.
+func (d *deployer) canUpdateInPlace(
+	vmManager bivm.Manager,
+	deploymentManifest bideplmanifest.Manifest,
+	cloudStemcell bistemcell.CloudStemcell,
+	registryConfig biinstallmanifest.Registry,
+) (bool, error) {
+	vm, found, err := vmManager.FindCurrent()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Finding current VM")
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	exists, err := vm.Exists()
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Checking existence of VM '%s'", vm.CID())
+	}
+
+	if !exists {
+		d.logger.Info(d.logTag, "Recreating VM '%s': VM no longer exists", vm.CID())
+		return false, nil
+	}
+
+	if !registryConfig.IsEmpty() && registryConfig.StorePath == "" {
+		d.logger.Info(d.logTag, "Recreating VM '%s': registry is not persistent", vm.CID())
+		return false, nil
+	}
+
+	changes, err := vmManager.ConfigChanges(cloudStemcell, deploymentManifest)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Comparing configuration of VM '%s'", vm.CID())
+	}
+
+	if len(changes) > 0 {
+		d.logger.Info(d.logTag, "Recreating VM '%s': %s", vm.CID(), strings.Join(changes, ", "))
+		return false, nil
+	}
+
+	d.logger.Info(d.logTag, "Updating VM '%s' in place", vm.CID())
+	return true, nil
+}
+
+func (d *deployer) updateAllInstances(
+	deploymentManifest bideplmanifest.Manifest,
+	instanceManager biinstance.Manager,
+	registryConfig biinstallmanifest.Registry,
+	drainOptions biinstance.DrainOptions,
+	deployStage biui.Stage,
+) ([]biinstance.Instance, []bidisk.Disk, error) {
+	instances := []biinstance.Instance{}
+	disks := []bidisk.Disk{}
+
+	if err := d.validateJobs(deploymentManifest); err != nil {
+		return instances, disks, err
+	}
+
+	for _, jobSpec := range deploymentManifest.Jobs {
+		for instanceID := 0; instanceID < jobSpec.Instances; instanceID++ {
+			instance, instanceDisks, err := instanceManager.Update(jobSpec.Name, instanceID, deploymentManifest, registryConfig, drainOptions, deployStage)
+			if err != nil {
+				return instances, disks, bosherr.WrapErrorf(err, "Updating instance '%s/%d'", jobSpec.Name, instanceID)
+			}
+			instances = append(instances, instance)
+			disks = append(disks, instanceDisks...)
+
+			err = instance.UpdateJobs(deploymentManifest, deployStage)
+			if err != nil {
+				return instances, disks, err
+			}
+		}
+	}
+
+	return instances, disks, nil
+}
+
+func (d *deployer) validateJobs(deploymentManifest bideplmanifest.Manifest) error {
+	if len(deploymentManifest.Jobs) != 1 {
+		return bosherr.Errorf("There must only be one job, found %d", len(deploymentManifest.Jobs))
+	}
+
+	for _, jobSpec := range deploymentManifest.Jobs {
+		if jobSpec.Instances != 1 {
+			return bosherr.Errorf("Job '%s' must have only one instance, found %d", jobSpec.Name, jobSpec.Instances)
+		}
+	}
+
+	return nil
+}
@@ -6,7 +6,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-init/deployment"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
+	mock_httpagent "github.com/cloudfoundry/bosh-init/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_instance_state "github.com/cloudfoundry/bosh-init/deployment/instance/state/mocks"
@@ -85,6 +85,7 @@ var _ = Describe("Deployer", func() {
 					Start: 0,
 					End:   5478,
 				},
+				MaxDrainWait: 60000,
 			},
 			DiskPools: []bideplmanifest.DiskPool{
 				diskPool,
@@ -188,24 +189,136 @@ var _ = Describe("Deployer", func() {
 			fakeExistingVM = fakebivm.NewFakeVM("existing-vm-cid")
 			fakeVMManager.SetFindCurrentBehavior(fakeExistingVM, true, nil)
 			fakeExistingVM.AgentClientReturn = mockAgentClient
+			registryConfig.StorePath = "fake-registry-store-path"
 		})
 
-		It("deletes existing vm", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
-			Expect(err).NotTo(HaveOccurred())
+		Context("when the vm configuration changed", func() {
+			BeforeEach(func() {
+				fakeVMManager.ConfigChangesChanges = []string{"stemcell changed"}
+			})
+
+			It("deletes existing vm", func() {
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVMManager.ConfigChangesInputs).To(Equal([]fakebivm.ConfigChangesInput{
+					{Stemcell: cloudStemcell, Manifest: deploymentManifest},
+				}))
+				Expect(fakeExistingVM.DeleteCalled).To(Equal(1))
+
+				Expect(fakeExistingVM.DrainInputs).To(Equal([]time.Duration{1 * time.Minute}))
+				Expect(fakeStage.PerformCalls[:4]).To(Equal([]*fakebiui.PerformCall{
+					{Name: "Waiting for the agent on VM 'existing-vm-cid'"},
+					{Name: "Draining jobs on instance 'unknown/0'"},
+					{Name: "Stopping jobs on instance 'unknown/0'"},
+					{Name: "Deleting VM 'existing-vm-cid'"},
+				}))
+			})
+		})
 
-			Expect(fakeExistingVM.DeleteCalled).To(Equal(1))
+		Context("when the vm configuration is unchanged", func() {
+			It("updates the existing vm in place", func() {
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeExistingVM.DeleteCalled).To(Equal(0))
+				Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{}))
+
+				Expect(fakeExistingVM.StopCalled).To(Equal(2))
+				Expect(fakeExistingVM.ApplyInputs).To(Equal([]fakebivm.ApplyInput{
+					{ApplySpec: applySpec},
+				}))
+				Expect(fakeExistingVM.StartCalled).To(Equal(1))
+
+				Expect(fakeExistingVM.DrainForUpdateInputs).To(Equal([]fakebivm.DrainForUpdateInput{
+					{NewSpec: applySpec, MaxWait: 1 * time.Minute},
+				}))
+				Expect(fakeStage.PerformCalls[0:4]).To(Equal([]*fakebiui.PerformCall{
+					{Name: "Waiting for the agent on VM 'existing-vm-cid' to be ready"},
+					{Name: "Draining jobs on instance 'fake-job-name/0'"},
+					{Name: "Stopping jobs on instance 'fake-job-name/0'"},
+					{Name: "Updating instance 'fake-job-name/0'"},
+				}))
+				Expect(fakeExistingVM.UpdateDisksInputs).To(HaveLen(1))
+			})
 
-			Expect(fakeStage.PerformCalls[:3]).To(Equal([]*fakebiui.PerformCall{
-				{Name: "Waiting for the agent on VM 'existing-vm-cid'"},
-				{Name: "Stopping jobs on instance 'unknown/0'"},
-				{Name: "Deleting VM 'existing-vm-cid'"},
-			}))
+			Context("when skipping drain is requested", func() {
+				It("updates the existing vm without draining its jobs", func() {
+					_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, true, fakeStage)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeExistingVM.DrainForUpdateInputs).To(BeEmpty())
+					Expect(fakeExistingVM.StopCalled).To(Equal(2))
+				})
+			})
+
+			Context("when recreate is requested", func() {
+				It("deletes existing vm without comparing its configuration", func() {
+					_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, true, false, fakeStage)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeVMManager.ConfigChangesInputs).To(BeEmpty())
+					Expect(fakeExistingVM.DeleteCalled).To(Equal(1))
+					Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{
+						Stemcell: cloudStemcell,
+						Manifest: deploymentManifest,
+					}))
+				})
+			})
+		})
+
+		Context("when the registry is not persistent", func() {
+			BeforeEach(func() {
+				registryConfig.StorePath = ""
+			})
+
+			It("deletes existing vm without comparing its configuration", func() {
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVMManager.ConfigChangesInputs).To(BeEmpty())
+				Expect(fakeExistingVM.DeleteCalled).To(Equal(1))
+				Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{
+					Stemcell: cloudStemcell,
+					Manifest: deploymentManifest,
+				}))
+			})
+		})
+
+		Context("when the registry is not used", func() {
+			BeforeEach(func() {
+				registryConfig = biinstallmanifest.Registry{}
+			})
+
+			It("updates the existing vm in place", func() {
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeExistingVM.DeleteCalled).To(Equal(0))
+				Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{}))
+			})
+		})
+
+		Context("when the existing vm no longer exists", func() {
+			BeforeEach(func() {
+				fakeExistingVM.ExistsFound = false
+			})
+
+			It("creates a new vm", func() {
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVMManager.ConfigChangesInputs).To(BeEmpty())
+				Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{
+					Stemcell: cloudStemcell,
+					Manifest: deploymentManifest,
+				}))
+			})
 		})
 	})
 
 	It("creates a vm", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{
@@ -214,6 +327,13 @@ var _ = Describe("Deployer", func() {
 		}))
 	})
 
+	It("does not drain the jobs on a new vm", func() {
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeVM.DrainInputs).To(BeEmpty())
+	})
+
 	Context("when registry & ssh tunnel configs are not empty", func() {
 		BeforeEach(func() {
 			registryConfig = biinstallmanifest.Registry{
@@ -232,7 +352,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("starts the SSH tunnel", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeSSHTunnel.Started).To(BeTrue())
 			Expect(fakeSSHTunnelFactory.NewSSHTunnelOptions).To(Equal(bisshtunnel.Options{
@@ -252,7 +372,7 @@ var _ = Describe("Deployer", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-ssh-tunnel-start-error"))
 			})
@@ -260,7 +380,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("waits for the vm", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(fakeVM.WaitUntilReadyInputs).To(ContainElement(fakebivm.WaitUntilReadyInput{
 			Timeout: 10 * time.Minute,
@@ -269,7 +389,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("logs start and stop events to the eventLogger", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeStage.PerformCalls[1]).To(Equal(&fakebiui.PerformCall{
@@ -287,7 +407,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("logs start and stop events to the eventLogger", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-wait-error"))
 
@@ -299,7 +419,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("updates the vm", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVM.ApplyInputs).To(Equal([]fakebivm.ApplyInput{
@@ -309,14 +429,14 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("starts the agent", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVM.StartCalled).To(Equal(1))
 	})
 
 	It("waits until agent reports state as running", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVM.WaitToBeRunningInputs).To(ContainElement(fakebivm.WaitInput{
@@ -331,13 +451,13 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("returns an error", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 			Expect(err).To(HaveOccurred())
 		})
 	})
 
 	It("logs instance update ui stages", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeStage.PerformCalls[2:4]).To(Equal([]*fakebiui.PerformCall{
@@ -352,7 +472,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("fails with descriptive error", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("Applying the initial agent state: fake-apply-error"))
 		})
@@ -364,7 +484,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("logs start and stop events to the eventLogger", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-start-error"))
 
@@ -384,7 +504,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("logs start and stop events to the eventLogger", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, false, false, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-wait-running-error"))
 
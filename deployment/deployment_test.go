@@ -12,12 +12,14 @@ import (
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
 	mock_instance_state "github.com/cloudfoundry/bosh-init/deployment/instance/state/mocks"
 	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock"
 
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bisnapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
@@ -67,12 +69,15 @@ var _ = Describe("Deployment", func() {
 			deploymentFactory Factory
 
 			deployment Deployment
+
+			drainOptions = biinstance.DrainOptions{MaxWait: 1 * time.Minute}
 		)
 
 		var expectNormalFlow = func() {
 			gomock.InOrder(
 				mockCloud.EXPECT().HasVM("fake-vm-cid").Return(true, nil),
 				mockAgentClient.EXPECT().Ping().Return("any-state", nil),                   // ping to make sure agent is responsive
+				mockAgentClient.EXPECT().Drain("shutdown").Return(int64(0), nil),           // run drain scripts
 				mockAgentClient.EXPECT().Stop(),                                            // stop all jobs
 				mockAgentClient.EXPECT().ListDisk().Return([]string{"fake-disk-cid"}, nil), // get mounted disks to be unmounted
 				mockAgentClient.EXPECT().UnmountDisk("fake-disk-cid"),
@@ -134,10 +139,12 @@ var _ = Describe("Deployment", func() {
 		JustBeforeEach(func() {
 			// all these local factories & managers are just used to construct a Deployment based on the deployment state
 			diskManagerFactory := bidisk.NewManagerFactory(diskRepo, logger)
-			diskDeployer := bivm.NewDiskDeployer(diskManagerFactory, diskRepo, logger)
+			snapshotRepo := biconfig.NewSnapshotRepo(deploymentStateService, fakeRepoUUIDGenerator)
+			snapshotManagerFactory := bisnapshot.NewManagerFactory(diskRepo, snapshotRepo, clock.NewClock(), logger)
+			diskDeployer := bivm.NewDiskDeployer(diskManagerFactory, snapshotManagerFactory, diskRepo, biconfig.NewDiskMigrationRepo(deploymentStateService), logger)
 
-			vmManagerFactory := bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeUUIDGenerator, fs, logger)
-			sshTunnelFactory := bisshtunnel.NewFactory(logger)
+			vmManagerFactory := bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeUUIDGenerator, fs, clock.NewClock(), logger)
+			sshTunnelFactory := bisshtunnel.NewFactory(fs, logger)
 
 			mockStateBuilderFactory = mock_instance_state.NewMockBuilderFactory(mockCtrl)
 			mockStateBuilder = mock_instance_state.NewMockBuilder(mockCtrl)
@@ -188,18 +195,19 @@ var _ = Describe("Deployment", func() {
 			It("stops agent, unmounts disk, deletes vm, deletes disk, deletes stemcell", func() {
 				expectNormalFlow()
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("logs validation stages", func() {
 				expectNormalFlow()
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 					{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+					{Name: "Draining jobs on instance 'unknown/0'"},
 					{Name: "Stopping jobs on instance 'unknown/0'"},
 					{Name: "Unmounting disk 'fake-disk-cid'"},
 					{Name: "Deleting VM 'fake-vm-cid'"},
@@ -211,14 +219,14 @@ var _ = Describe("Deployment", func() {
 			It("clears current vm, disk and stemcell", func() {
 				expectNormalFlow()
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				_, found, err := vmRepo.FindCurrent()
 				Expect(found).To(BeFalse(), "should be no current VM")
 
-				_, found, err = diskRepo.FindCurrent()
-				Expect(found).To(BeFalse(), "should be no current disk")
+				currentDiskRecords, err := diskRepo.FindCurrent()
+				Expect(currentDiskRecords).To(BeEmpty(), "should be no current disk")
 
 				diskRecords, err := diskRepo.All()
 				Expect(err).ToNot(HaveOccurred())
@@ -250,7 +258,7 @@ var _ = Describe("Deployment", func() {
 						mockCloud.EXPECT().DeleteStemcell("fake-stemcell-cid"),
 					)
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -259,7 +267,7 @@ var _ = Describe("Deployment", func() {
 				JustBeforeEach(func() {
 					expectNormalFlow()
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 
 					// reset event log recording
@@ -267,7 +275,7 @@ var _ = Describe("Deployment", func() {
 				})
 
 				It("does not delete anything", func() {
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 
 					Expect(fakeStage.PerformCalls).To(BeEmpty())
@@ -288,7 +296,7 @@ var _ = Describe("Deployment", func() {
 			})
 
 			It("does not delete anything", func() {
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls).To(BeEmpty())
@@ -309,13 +317,14 @@ var _ = Describe("Deployment", func() {
 			It("stops the agent and deletes the VM", func() {
 				gomock.InOrder(
 					mockAgentClient.EXPECT().Ping().Return("any-state", nil),                   // ping to make sure agent is responsive
+					mockAgentClient.EXPECT().Drain("shutdown").Return(int64(0), nil),           // run drain scripts
 					mockAgentClient.EXPECT().Stop(),                                            // stop all jobs
 					mockAgentClient.EXPECT().ListDisk().Return([]string{"fake-disk-cid"}, nil), // get mounted disks to be unmounted
 					mockAgentClient.EXPECT().UnmountDisk("fake-disk-cid"),
 					mockCloud.EXPECT().DeleteVM("fake-vm-cid"),
 				)
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -327,7 +336,7 @@ var _ = Describe("Deployment", func() {
 				It("skips agent shutdown & deletes the VM (to ensure related resources are released by the CPI)", func() {
 					mockCloud.EXPECT().DeleteVM("fake-vm-cid")
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -337,7 +346,7 @@ var _ = Describe("Deployment", func() {
 						Message: "fake-vm-not-found-message",
 					}))
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -346,7 +355,7 @@ var _ = Describe("Deployment", func() {
 		Context("when a current disk exists", func() {
 			BeforeEach(func() {
 				deploymentStateService.Save(biconfig.DeploymentState{})
-				diskRecord, err := diskRepo.Save("fake-disk-cid", 100, nil)
+				diskRecord, err := diskRepo.Save("", "fake-disk-cid", 100, nil)
 				Expect(err).ToNot(HaveOccurred())
 				diskRepo.UpdateCurrent(diskRecord.ID)
 			})
@@ -354,7 +363,7 @@ var _ = Describe("Deployment", func() {
 			It("deletes the disk", func() {
 				mockCloud.EXPECT().DeleteDisk("fake-disk-cid")
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -362,7 +371,7 @@ var _ = Describe("Deployment", func() {
 				It("deletes the disk (to ensure related resources are released by the CPI)", func() {
 					mockCloud.EXPECT().DeleteDisk("fake-disk-cid")
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -372,7 +381,7 @@ var _ = Describe("Deployment", func() {
 						Message: "fake-disk-not-found-message",
 					}))
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -389,7 +398,7 @@ var _ = Describe("Deployment", func() {
 			It("deletes the stemcell", func() {
 				mockCloud.EXPECT().DeleteStemcell("fake-stemcell-cid")
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -397,7 +406,7 @@ var _ = Describe("Deployment", func() {
 				It("deletes the stemcell (to ensure related resources are released by the CPI)", func() {
 					mockCloud.EXPECT().DeleteStemcell("fake-stemcell-cid")
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -407,7 +416,7 @@ var _ = Describe("Deployment", func() {
 						Message: "fake-stemcell-not-found-message",
 					}))
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(drainOptions, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -11,12 +11,17 @@ import (
 
 type Disk interface {
 	CID() string
+	// Name is empty for the disk configured with persistent_disk or persistent_disk_pool
+	Name() string
+	// Size is the size of the disk in MB
+	Size() int
 	NeedsMigration(newSize int, newCloudProperties biproperty.Map) bool
 	Delete() error
 }
 
 type disk struct {
 	cid             string
+	name            string
 	size            int
 	cloudProperties biproperty.Map
 
@@ -31,6 +36,7 @@ func NewDisk(
 ) Disk {
 	return &disk{
 		cid:             diskRecord.CID,
+		name:            diskRecord.Name,
 		size:            diskRecord.Size,
 		cloudProperties: diskRecord.CloudProperties,
 		cloud:           cloud,
@@ -42,6 +48,14 @@ func (d *disk) CID() string {
 	return d.cid
 }
 
+func (d *disk) Name() string {
+	return d.name
+}
+
+func (d *disk) Size() int {
+	return d.size
+}
+
 func (d *disk) NeedsMigration(newSize int, newCloudProperties biproperty.Map) bool {
 	return d.size != newSize || !reflect.DeepEqual(d.cloudProperties, newCloudProperties)
 }
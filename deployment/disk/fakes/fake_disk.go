@@ -5,7 +5,9 @@ import (
 )
 
 type FakeDisk struct {
-	cid string
+	cid  string
+	name string
+	size int
 
 	NeedsMigrationInputs []NeedsMigrationInput
 	needsMigrationOutput needsMigrationOutput
@@ -30,10 +32,24 @@ func NewFakeDisk(cid string) *FakeDisk {
 	}
 }
 
+func NewFakeNamedDisk(name string, cid string) *FakeDisk {
+	disk := NewFakeDisk(cid)
+	disk.name = name
+	return disk
+}
+
 func (d *FakeDisk) CID() string {
 	return d.cid
 }
 
+func (d *FakeDisk) Name() string {
+	return d.name
+}
+
+func (d *FakeDisk) Size() int {
+	return d.size
+}
+
 func (d *FakeDisk) NeedsMigration(size int, cloudProperties biproperty.Map) bool {
 	d.NeedsMigrationInputs = append(d.NeedsMigrationInputs, NeedsMigrationInput{
 		Size:            size,
@@ -54,6 +70,10 @@ func (d *FakeDisk) SetNeedsMigrationBehavior(needsMigration bool) {
 	}
 }
 
+func (d *FakeDisk) SetSize(size int) {
+	d.size = size
+}
+
 func (d *FakeDisk) SetDeleteBehavior(err error) {
 	d.deleteErr = err
 }
@@ -13,13 +13,19 @@ type FakeManager struct {
 
 	findCurrentOutput findCurrentOutput
 
+	findOutputs map[string]findOutput
+
 	DeleteUnusedCalledTimes int
 	DeleteUnusedErr         error
 
+	DeleteOrphanedCalledTimes int
+	DeleteOrphanedErr         error
+
 	findUnusedOutput findUnusedOutput
 }
 
 type CreateInput struct {
+	Name       string
 	DiskPool   bideplmanifest.DiskPool
 	InstanceID string
 }
@@ -29,18 +35,27 @@ type findCurrentOutput struct {
 	Err   error
 }
 
+type findOutput struct {
+	disk  bidisk.Disk
+	found bool
+	err   error
+}
+
 type findUnusedOutput struct {
 	disks []bidisk.Disk
 	err   error
 }
 
 func NewFakeManager() *FakeManager {
-	return &FakeManager{}
+	return &FakeManager{
+		findOutputs: map[string]findOutput{},
+	}
 }
 
-func (m *FakeManager) Create(diskPool bideplmanifest.DiskPool, instanceID string) (bidisk.Disk, error) {
+func (m *FakeManager) Create(persistentDisk bideplmanifest.PersistentDisk, instanceID string) (bidisk.Disk, error) {
 	input := CreateInput{
-		DiskPool:   diskPool,
+		Name:       persistentDisk.Name,
+		DiskPool:   persistentDisk.DiskPool,
 		InstanceID: instanceID,
 	}
 	m.CreateInputs = append(m.CreateInputs, input)
@@ -52,6 +67,11 @@ func (m *FakeManager) FindCurrent() ([]bidisk.Disk, error) {
 	return m.findCurrentOutput.Disks, m.findCurrentOutput.Err
 }
 
+func (m *FakeManager) Find(cid string) (bidisk.Disk, bool, error) {
+	output := m.findOutputs[cid]
+	return output.disk, output.found, output.err
+}
+
 func (m *FakeManager) FindUnused() ([]bidisk.Disk, error) {
 	return m.findUnusedOutput.disks, m.findUnusedOutput.err
 }
@@ -61,6 +81,11 @@ func (m *FakeManager) DeleteUnused(eventLogStage biui.Stage) error {
 	return m.DeleteUnusedErr
 }
 
+func (m *FakeManager) DeleteOrphaned(eventLogStage biui.Stage) error {
+	m.DeleteOrphanedCalledTimes++
+	return m.DeleteOrphanedErr
+}
+
 func (m *FakeManager) SetFindCurrentBehavior(disks []bidisk.Disk, err error) {
 	m.findCurrentOutput = findCurrentOutput{
 		Disks: disks,
@@ -68,6 +93,14 @@ func (m *FakeManager) SetFindCurrentBehavior(disks []bidisk.Disk, err error) {
 	}
 }
 
+func (m *FakeManager) SetFindBehavior(cid string, disk bidisk.Disk, found bool, err error) {
+	m.findOutputs[cid] = findOutput{
+		disk:  disk,
+		found: found,
+		err:   err,
+	}
+}
+
 func (m *FakeManager) SetFindUnusedBehavior(
 	disks []bidisk.Disk,
 	err error,
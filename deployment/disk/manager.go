@@ -13,9 +13,13 @@ import (
 
 type Manager interface {
 	FindCurrent() ([]Disk, error)
-	Create(bideplmanifest.DiskPool, string) (Disk, error)
+	Find(cid string) (Disk, bool, error)
+	Create(bideplmanifest.PersistentDisk, string) (Disk, error)
+	// FindUnused returns the disks that are neither current nor orphaned
 	FindUnused() ([]Disk, error)
 	DeleteUnused(biui.Stage) error
+	// DeleteOrphaned deletes the disks that were kept until clean-up
+	DeleteOrphaned(biui.Stage) error
 }
 
 func NewManager(
@@ -41,12 +45,12 @@ type manager struct {
 func (m *manager) FindCurrent() ([]Disk, error) {
 	disks := []Disk{}
 
-	diskRecord, found, err := m.diskRepo.FindCurrent()
+	diskRecords, err := m.diskRepo.FindCurrent()
 	if err != nil {
 		return disks, bosherr.WrapError(err, "Reading disk record")
 	}
 
-	if found {
+	for _, diskRecord := range diskRecords {
 		disk := NewDisk(diskRecord, m.cloud, m.diskRepo)
 		disks = append(disks, disk)
 	}
@@ -54,10 +58,24 @@ func (m *manager) FindCurrent() ([]Disk, error) {
 	return disks, nil
 }
 
-func (m *manager) Create(diskPool bideplmanifest.DiskPool, vmCID string) (Disk, error) {
+func (m *manager) Find(cid string) (Disk, bool, error) {
+	diskRecord, found, err := m.diskRepo.Find(cid)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Reading disk record (cid=%s)", cid)
+	}
+
+	if !found {
+		return nil, false, nil
+	}
+
+	return NewDisk(diskRecord, m.cloud, m.diskRepo), true, nil
+}
+
+func (m *manager) Create(persistentDisk bideplmanifest.PersistentDisk, vmCID string) (Disk, error) {
+	diskPool := persistentDisk.DiskPool
 	diskCloudProperties := diskPool.CloudProperties
 
-	m.logger.Debug(m.logTag, "Creating disk")
+	m.logger.Debug(m.logTag, "Creating disk '%s'", persistentDisk.Name)
 	cid, err := m.cloud.CreateDisk(diskPool.DiskSize, diskCloudProperties, vmCID)
 	if err != nil {
 		return nil,
@@ -67,7 +85,7 @@ func (m *manager) Create(diskPool bideplmanifest.DiskPool, vmCID string) (Disk,
 			)
 	}
 
-	diskRecord, err := m.diskRepo.Save(cid, diskPool.DiskSize, diskCloudProperties)
+	diskRecord, err := m.diskRepo.Save(persistentDisk.Name, cid, diskPool.DiskSize, diskCloudProperties)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Saving deployment disk record")
 	}
@@ -85,13 +103,18 @@ func (m *manager) FindUnused() ([]Disk, error) {
 		return disks, bosherr.WrapError(err, "Getting all disk records")
 	}
 
-	currentDiskRecord, found, err := m.diskRepo.FindCurrent()
+	currentDiskRecords, err := m.diskRepo.FindCurrent()
 	if err != nil {
 		return disks, bosherr.WrapError(err, "Finding current disk record")
 	}
 
+	currentDiskIDs := map[string]struct{}{}
+	for _, currentDiskRecord := range currentDiskRecords {
+		currentDiskIDs[currentDiskRecord.ID] = struct{}{}
+	}
+
 	for _, diskRecord := range diskRecords {
-		if !found || diskRecord.ID != currentDiskRecord.ID {
+		if _, current := currentDiskIDs[diskRecord.ID]; !current && !diskRecord.Orphaned {
 			disks = append(disks, NewDisk(diskRecord, m.cloud, m.diskRepo))
 		}
 	}
@@ -105,9 +128,29 @@ func (m *manager) DeleteUnused(eventLoggerStage biui.Stage) error {
 		return bosherr.WrapError(err, "Finding unused disks")
 	}
 
+	return m.deleteDisks(disks, "unused", eventLoggerStage)
+}
+
+func (m *manager) DeleteOrphaned(eventLoggerStage biui.Stage) error {
+	diskRecords, err := m.diskRepo.All()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting all disk records")
+	}
+
+	disks := []Disk{}
+	for _, diskRecord := range diskRecords {
+		if diskRecord.Orphaned {
+			disks = append(disks, NewDisk(diskRecord, m.cloud, m.diskRepo))
+		}
+	}
+
+	return m.deleteDisks(disks, "orphaned", eventLoggerStage)
+}
+
+func (m *manager) deleteDisks(disks []Disk, kind string, eventLoggerStage biui.Stage) error {
 	for _, disk := range disks {
-		stepName := fmt.Sprintf("Deleting unused disk '%s'", disk.CID())
-		err = eventLoggerStage.Perform(stepName, func() error {
+		stepName := fmt.Sprintf("Deleting %s disk '%s'", kind, disk.CID())
+		err := eventLoggerStage.Perform(stepName, func() error {
 			err := disk.Delete()
 			cloudErr, ok := err.(bicloud.Error)
 			if ok && cloudErr.Type() == bicloud.DiskNotFoundError {
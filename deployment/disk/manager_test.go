@@ -40,16 +40,18 @@ var _ = Describe("Manager", func() {
 
 	Describe("Create", func() {
 		var (
-			diskPool bideplmanifest.DiskPool
+			persistentDisk bideplmanifest.PersistentDisk
 		)
 
 		BeforeEach(func() {
 
-			diskPool = bideplmanifest.DiskPool{
-				Name:     "fake-disk-pool-name",
-				DiskSize: 1024,
-				CloudProperties: biproperty.Map{
-					"fake-cloud-property-key": "fake-cloud-property-value",
+			persistentDisk = bideplmanifest.PersistentDisk{
+				DiskPool: bideplmanifest.DiskPool{
+					Name:     "fake-disk-pool-name",
+					DiskSize: 1024,
+					CloudProperties: biproperty.Map{
+						"fake-cloud-property-key": "fake-cloud-property-value",
+					},
 				},
 			}
 		})
@@ -60,13 +62,13 @@ var _ = Describe("Manager", func() {
 			})
 
 			It("returns a disk", func() {
-				disk, err := manager.Create(diskPool, "fake-vm-cid")
+				disk, err := manager.Create(persistentDisk, "fake-vm-cid")
 				Expect(err).ToNot(HaveOccurred())
 				Expect(disk.CID()).To(Equal("fake-disk-cid"))
 			})
 
 			It("saves the disk record", func() {
-				_, err := manager.Create(diskPool, "fake-vm-cid")
+				_, err := manager.Create(persistentDisk, "fake-vm-cid")
 				Expect(err).ToNot(HaveOccurred())
 
 				diskRecord, found, err := diskRepo.Find("fake-disk-cid")
@@ -82,6 +84,19 @@ var _ = Describe("Manager", func() {
 					},
 				}))
 			})
+
+			It("saves the name of a named disk", func() {
+				persistentDisk.Name = "fake-disk-name"
+
+				disk, err := manager.Create(persistentDisk, "fake-vm-cid")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(disk.Name()).To(Equal("fake-disk-name"))
+
+				diskRecord, found, err := diskRepo.Find("fake-disk-cid")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(diskRecord.Name).To(Equal("fake-disk-name"))
+			})
 		})
 
 		Context("when creating disk fails", func() {
@@ -90,7 +105,7 @@ var _ = Describe("Manager", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := manager.Create(diskPool, "fake-vm-cid")
+				_, err := manager.Create(persistentDisk, "fake-vm-cid")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-create-error"))
 			})
@@ -98,11 +113,11 @@ var _ = Describe("Manager", func() {
 
 		Context("when updating disk record fails", func() {
 			BeforeEach(func() {
-				fakeFs.WriteFileError = errors.New("fake-write-error")
+				fakeFs.OpenFileErr = errors.New("fake-write-error")
 			})
 
 			It("returns an error", func() {
-				_, err := manager.Create(diskPool, "fake-vm-cid")
+				_, err := manager.Create(persistentDisk, "fake-vm-cid")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-write-error"))
 			})
@@ -112,7 +127,7 @@ var _ = Describe("Manager", func() {
 	Describe("FindCurrent", func() {
 		Context("when disk already exists in disk repo", func() {
 			BeforeEach(func() {
-				diskRecord, err := diskRepo.Save("fake-existing-disk-cid", 1024, biproperty.Map{})
+				diskRecord, err := diskRepo.Save("", "fake-existing-disk-cid", 1024, biproperty.Map{})
 				Expect(err).ToNot(HaveOccurred())
 
 				err = diskRepo.UpdateCurrent(diskRecord.ID)
@@ -125,6 +140,20 @@ var _ = Describe("Manager", func() {
 				Expect(disks).To(HaveLen(1))
 				Expect(disks[0].CID()).To(Equal("fake-existing-disk-cid"))
 			})
+
+			It("returns the named disk that replaced the existing disk", func() {
+				fakeUUIDGenerator.GeneratedUUID = "fake-named-disk-uuid"
+				diskRecord, err := diskRepo.Save("fake-disk-name", "fake-named-disk-cid", 1024, biproperty.Map{})
+				Expect(err).ToNot(HaveOccurred())
+				err = diskRepo.UpdateCurrent(diskRecord.ID)
+				Expect(err).ToNot(HaveOccurred())
+
+				disks, err := manager.FindCurrent()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(disks).To(HaveLen(1))
+				Expect(disks[0].CID()).To(Equal("fake-named-disk-cid"))
+				Expect(disks[0].Name()).To(Equal("fake-disk-name"))
+			})
 		})
 
 		Context("when disk does not exists in disk repo", func() {
@@ -149,6 +178,24 @@ var _ = Describe("Manager", func() {
 		})
 	})
 
+	Describe("Find", func() {
+		It("returns the disk with the given cid", func() {
+			_, err := diskRepo.Save("", "fake-existing-disk-cid", 1024, biproperty.Map{})
+			Expect(err).ToNot(HaveOccurred())
+
+			disk, found, err := manager.Find("fake-existing-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(disk.CID()).To(Equal("fake-existing-disk-cid"))
+		})
+
+		It("returns false when there is no disk with the given cid", func() {
+			_, found, err := manager.Find("fake-unknown-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+
 	Describe("FindUnused", func() {
 		var (
 			firstDisk bidisk.Disk
@@ -157,23 +204,29 @@ var _ = Describe("Manager", func() {
 
 		BeforeEach(func() {
 			fakeUUIDGenerator.GeneratedUUID = "fake-guid-1"
-			firstDiskRecord, err := diskRepo.Save("fake-disk-cid-1", 1024, biproperty.Map{})
+			firstDiskRecord, err := diskRepo.Save("", "fake-disk-cid-1", 1024, biproperty.Map{})
 			Expect(err).ToNot(HaveOccurred())
 			firstDisk = NewDisk(firstDiskRecord, fakeCloud, diskRepo)
 
 			fakeUUIDGenerator.GeneratedUUID = "fake-guid-2"
-			_, err = diskRepo.Save("fake-disk-cid-2", 1024, biproperty.Map{})
+			_, err = diskRepo.Save("fake-disk-name", "fake-disk-cid-2", 1024, biproperty.Map{})
 			Expect(err).ToNot(HaveOccurred())
 			err = diskRepo.UpdateCurrent("fake-guid-2")
 			Expect(err).ToNot(HaveOccurred())
 
 			fakeUUIDGenerator.GeneratedUUID = "fake-guid-3"
-			thirdDiskRecord, err := diskRepo.Save("fake-disk-cid-3", 1024, biproperty.Map{})
+			thirdDiskRecord, err := diskRepo.Save("", "fake-disk-cid-3", 1024, biproperty.Map{})
 			Expect(err).ToNot(HaveOccurred())
 			thirdDisk = NewDisk(thirdDiskRecord, fakeCloud, diskRepo)
+
+			fakeUUIDGenerator.GeneratedUUID = "fake-guid-4"
+			_, err = diskRepo.Save("", "fake-disk-cid-4", 1024, biproperty.Map{})
+			Expect(err).ToNot(HaveOccurred())
+			err = diskRepo.Orphan("fake-guid-4")
+			Expect(err).ToNot(HaveOccurred())
 		})
 
-		It("returns unused disks from repo", func() {
+		It("returns unused disks from repo, except the orphaned disks", func() {
 			disks, err := manager.FindUnused()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -193,17 +246,17 @@ var _ = Describe("Manager", func() {
 			fakeStage = fakebiui.NewFakeStage()
 
 			fakeUUIDGenerator.GeneratedUUID = "fake-disk-id-1"
-			_, err := diskRepo.Save("fake-disk-cid-1", 100, nil)
+			_, err := diskRepo.Save("", "fake-disk-cid-1", 100, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			fakeUUIDGenerator.GeneratedUUID = "fake-disk-id-2"
-			secondDiskRecord, err = diskRepo.Save("fake-disk-cid-2", 100, nil)
+			secondDiskRecord, err = diskRepo.Save("", "fake-disk-cid-2", 100, nil)
 			Expect(err).ToNot(HaveOccurred())
 			err = diskRepo.UpdateCurrent(secondDiskRecord.ID)
 			Expect(err).ToNot(HaveOccurred())
 
 			fakeUUIDGenerator.GeneratedUUID = "fake-disk-id-3"
-			_, err = diskRepo.Save("fake-disk-cid-3", 100, nil)
+			_, err = diskRepo.Save("", "fake-disk-cid-3", 100, nil)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -221,10 +274,9 @@ var _ = Describe("Manager", func() {
 				{Name: "Deleting unused disk 'fake-disk-cid-3'"},
 			}))
 
-			currentRecord, found, err := diskRepo.FindCurrent()
+			currentRecords, err := diskRepo.FindCurrent()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(found).To(BeTrue())
-			Expect(currentRecord).To(Equal(secondDiskRecord))
+			Expect(currentRecords).To(Equal([]biconfig.DiskRecord{secondDiskRecord}))
 
 			records, err := diskRepo.All()
 			Expect(err).ToNot(HaveOccurred())
@@ -232,5 +284,52 @@ var _ = Describe("Manager", func() {
 				secondDiskRecord,
 			}))
 		})
+
+		It("keeps the orphaned disks", func() {
+			err := diskRepo.Orphan("fake-disk-id-3")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = manager.DeleteUnused(fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCloud.DeleteDiskInputs).To(Equal([]fakebicloud.DeleteDiskInput{
+				{DiskCID: "fake-disk-cid-1"},
+			}))
+		})
+	})
+
+	Describe("DeleteOrphaned", func() {
+		var fakeStage *fakebiui.FakeStage
+
+		BeforeEach(func() {
+			fakeStage = fakebiui.NewFakeStage()
+
+			fakeUUIDGenerator.GeneratedUUID = "fake-disk-id-1"
+			_, err := diskRepo.Save("", "fake-disk-cid-1", 100, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeUUIDGenerator.GeneratedUUID = "fake-disk-id-2"
+			_, err = diskRepo.Save("", "fake-disk-cid-2", 100, nil)
+			Expect(err).ToNot(HaveOccurred())
+			err = diskRepo.Orphan("fake-disk-id-2")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("deletes only the orphaned disks", func() {
+			err := manager.DeleteOrphaned(fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCloud.DeleteDiskInputs).To(Equal([]fakebicloud.DeleteDiskInput{
+				{DiskCID: "fake-disk-cid-2"},
+			}))
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Deleting orphaned disk 'fake-disk-cid-2'"},
+			}))
+
+			records, err := diskRepo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].CID).To(Equal("fake-disk-cid-1"))
+		})
 	})
 })
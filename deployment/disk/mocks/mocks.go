@@ -52,6 +52,16 @@ func (_mr *_MockDiskRecorder) Delete() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete")
 }
 
+func (_m *MockDisk) Name() string {
+	ret := _m.ctrl.Call(_m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+func (_mr *_MockDiskRecorder) Name() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Name")
+}
+
 func (_m *MockDisk) NeedsMigration(_param0 int, _param1 property.Map) bool {
 	ret := _m.ctrl.Call(_m, "NeedsMigration", _param0, _param1)
 	ret0, _ := ret[0].(bool)
@@ -62,6 +72,16 @@ func (_mr *_MockDiskRecorder) NeedsMigration(arg0, arg1 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "NeedsMigration", arg0, arg1)
 }
 
+func (_m *MockDisk) Size() int {
+	ret := _m.ctrl.Call(_m, "Size")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+func (_mr *_MockDiskRecorder) Size() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Size")
+}
+
 // Mock of Manager interface
 type MockManager struct {
 	ctrl     *gomock.Controller
@@ -83,7 +103,7 @@ func (_m *MockManager) EXPECT() *_MockManagerRecorder {
 	return _m.recorder
 }
 
-func (_m *MockManager) Create(_param0 manifest.DiskPool, _param1 string) (disk.Disk, error) {
+func (_m *MockManager) Create(_param0 manifest.PersistentDisk, _param1 string) (disk.Disk, error) {
 	ret := _m.ctrl.Call(_m, "Create", _param0, _param1)
 	ret0, _ := ret[0].(disk.Disk)
 	ret1, _ := ret[1].(error)
@@ -94,6 +114,16 @@ func (_mr *_MockManagerRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Create", arg0, arg1)
 }
 
+func (_m *MockManager) DeleteOrphaned(_param0 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "DeleteOrphaned", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockManagerRecorder) DeleteOrphaned(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteOrphaned", arg0)
+}
+
 func (_m *MockManager) DeleteUnused(_param0 ui.Stage) error {
 	ret := _m.ctrl.Call(_m, "DeleteUnused", _param0)
 	ret0, _ := ret[0].(error)
@@ -104,6 +134,18 @@ func (_mr *_MockManagerRecorder) DeleteUnused(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteUnused", arg0)
 }
 
+func (_m *MockManager) Find(_param0 string) (disk.Disk, bool, error) {
+	ret := _m.ctrl.Call(_m, "Find", _param0)
+	ret0, _ := ret[0].(disk.Disk)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockManagerRecorder) Find(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Find", arg0)
+}
+
 func (_m *MockManager) FindCurrent() ([]disk.Disk, error) {
 	ret := _m.ctrl.Call(_m, "FindCurrent")
 	ret0, _ := ret[0].([]disk.Disk)
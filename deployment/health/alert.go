@@ -0,0 +1,21 @@
+package health
+
+import (
+	"time"
+)
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Alert describes a change in the health of the deployed instance
+type Alert struct {
+	Severity  Severity  `json:"severity"`
+	Title     string    `json:"title"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// SendMailFunc has the signature of smtp.SendMail
+type SendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+type emailNotifier struct {
+	sendMail    SendMailFunc
+	smtpAddress string
+	from        string
+	to          []string
+}
+
+// NewEmailNotifier returns a Notifier that emails every alert through the SMTP server at smtpAddress,
+// without authentication.
+func NewEmailNotifier(sendMail SendMailFunc, smtpAddress string, from string, to []string) Notifier {
+	return &emailNotifier{
+		sendMail:    sendMail,
+		smtpAddress: smtpAddress,
+		from:        from,
+		to:          to,
+	}
+}
+
+func (n *emailNotifier) Notify(alert Alert) error {
+	headers := []string{
+		fmt.Sprintf("From: %s", n.from),
+		fmt.Sprintf("To: %s", strings.Join(n.to, ", ")),
+		fmt.Sprintf("Subject: [bosh-init] %s", alert.Title),
+		fmt.Sprintf("Date: %s", alert.CreatedAt.Format("Mon, 02 Jan 2006 15:04:05 -0700")),
+	}
+	body := fmt.Sprintf("Severity: %s\r\n\r\n%s\r\n", alert.Severity, alert.Summary)
+	message := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+
+	err := n.sendMail(n.smtpAddress, nil, n.from, n.to, []byte(message))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Sending alert email to '%s'", strings.Join(n.to, ", "))
+	}
+
+	return nil
+}
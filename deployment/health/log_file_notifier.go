@@ -0,0 +1,38 @@
+package health
+
+import (
+	"fmt"
+	"os"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type logFileNotifier struct {
+	path string
+	fs   boshsys.FileSystem
+}
+
+// NewLogFileNotifier returns a Notifier that appends every alert as a line to the file at path
+func NewLogFileNotifier(path string, fs boshsys.FileSystem) Notifier {
+	return &logFileNotifier{
+		path: path,
+		fs:   fs,
+	}
+}
+
+func (n *logFileNotifier) Notify(alert Alert) error {
+	file, err := n.fs.OpenFile(n.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening alert log '%s'", n.path)
+	}
+	defer file.Close()
+
+	line := fmt.Sprintf("%s [%s] %s: %s\n", alert.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), alert.Severity, alert.Title, alert.Summary)
+	_, err = file.Write([]byte(line))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to alert log '%s'", n.path)
+	}
+
+	return nil
+}
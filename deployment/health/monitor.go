@@ -0,0 +1,214 @@
+package health
+
+import (
+	"fmt"
+	"time"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+type State string
+
+const (
+	StateRunning      State = "running"
+	StateFailing      State = "failing"
+	StateUnresponsive State = "unresponsive"
+	StateMissing      State = "missing"
+	StateUnknown      State = "unknown"
+)
+
+// Checker determines the health of the deployed instance
+type Checker interface {
+	Check() (State, error)
+}
+
+// Resurrector recreates the deployed VM, reattaching its persistent disks.
+// Returns a ResurrectionSkippedError when the VM cannot be recreated for now, e.g. because another process is deploying it.
+type Resurrector interface {
+	Resurrect() error
+}
+
+// ResurrectionSkippedError is returned by a Resurrector that did not try to recreate the VM
+type ResurrectionSkippedError struct {
+	Reason error
+}
+
+func NewResurrectionSkippedError(reason error) ResurrectionSkippedError {
+	return ResurrectionSkippedError{Reason: reason}
+}
+
+func (e ResurrectionSkippedError) Error() string {
+	return e.Reason.Error()
+}
+
+type MonitorOptions struct {
+	// Name identifies the watched instance in alerts
+	Name string
+
+	Interval time.Duration
+
+	// ResurrectAfter is how long the VM may be missing or unresponsive before it is recreated.
+	// Zero disables resurrection.
+	ResurrectAfter time.Duration
+}
+
+type Monitor interface {
+	// Watch checks the health of the instance immediately and then every interval, until stopCh is closed.
+	// An alert is sent every time the health changes.
+	Watch(stopCh <-chan struct{})
+}
+
+type MonitorFactory interface {
+	NewMonitor(checker Checker, resurrector Resurrector, notifier Notifier, options MonitorOptions) Monitor
+}
+
+type monitorFactory struct {
+	timeService clock.Clock
+	logger      boshlog.Logger
+}
+
+func NewMonitorFactory(timeService clock.Clock, logger boshlog.Logger) MonitorFactory {
+	return &monitorFactory{
+		timeService: timeService,
+		logger:      logger,
+	}
+}
+
+func (f *monitorFactory) NewMonitor(checker Checker, resurrector Resurrector, notifier Notifier, options MonitorOptions) Monitor {
+	return &monitor{
+		checker:     checker,
+		resurrector: resurrector,
+		notifier:    notifier,
+		options:     options,
+		timeService: f.timeService,
+		logger:      f.logger,
+		logTag:      "healthMonitor",
+	}
+}
+
+type monitor struct {
+	checker     Checker
+	resurrector Resurrector
+	notifier    Notifier
+	options     MonitorOptions
+	timeService clock.Clock
+	logger      boshlog.Logger
+	logTag      string
+
+	// lastState is empty until the first check, or after a resurrection
+	lastState State
+	downSince time.Time
+}
+
+func (m *monitor) Watch(stopCh <-chan struct{}) {
+	for {
+		m.check()
+
+		timer := m.timeService.NewTimer(m.options.Interval)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+func (m *monitor) check() {
+	state, err := m.checker.Check()
+	if err != nil {
+		m.logger.Warn(m.logTag, "Checking health of '%s': %s", m.options.Name, err.Error())
+		state = StateUnknown
+	}
+
+	now := m.timeService.Now()
+	m.logger.Debug(m.logTag, "Instance '%s' is %s", m.options.Name, state)
+
+	if state != m.lastState {
+		// a healthy instance is not worth an alert when watching starts
+		if m.lastState != "" || state != StateRunning {
+			m.notify(m.stateAlert(state, err))
+		}
+
+		if isDown(state) && !isDown(m.lastState) {
+			m.downSince = now
+		}
+	}
+	m.lastState = state
+
+	if m.options.ResurrectAfter > 0 && isDown(state) && now.Sub(m.downSince) >= m.options.ResurrectAfter {
+		m.resurrect(state)
+	}
+}
+
+func (m *monitor) resurrect(state State) {
+	m.notify(Alert{
+		Severity: SeverityWarning,
+		Title:    fmt.Sprintf("Recreating '%s'", m.options.Name),
+		Summary:  fmt.Sprintf("The VM has been %s for at least %s, recreating it with its persistent disks", state, m.options.ResurrectAfter),
+	})
+
+	err := m.resurrector.Resurrect()
+	if skipErr, skipped := err.(ResurrectionSkippedError); skipped {
+		m.logger.Warn(m.logTag, "Skipped recreating '%s': %s", m.options.Name, skipErr.Error())
+		m.notify(Alert{
+			Severity: SeverityWarning,
+			Title:    fmt.Sprintf("Skipped recreating '%s'", m.options.Name),
+			Summary:  skipErr.Error(),
+		})
+		// try again once the VM has been down for another ResurrectAfter
+		m.downSince = m.timeService.Now()
+		return
+	}
+	if err != nil {
+		m.logger.Error(m.logTag, "Recreating '%s': %s", m.options.Name, err.Error())
+		m.notify(Alert{
+			Severity: SeverityCritical,
+			Title:    fmt.Sprintf("Failed to recreate '%s'", m.options.Name),
+			Summary:  err.Error(),
+		})
+		// try again once the VM has been down for another ResurrectAfter
+		m.downSince = m.timeService.Now()
+		return
+	}
+
+	m.notify(Alert{
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("Recreated '%s'", m.options.Name),
+		Summary:  "The VM was recreated and its jobs are running",
+	})
+	m.lastState = ""
+}
+
+func (m *monitor) stateAlert(state State, checkErr error) Alert {
+	switch state {
+	case StateRunning:
+		return Alert{Severity: SeverityInfo, Title: fmt.Sprintf("'%s' is running", m.options.Name), Summary: "All jobs are running"}
+	case StateFailing:
+		return Alert{Severity: SeverityWarning, Title: fmt.Sprintf("'%s' is failing", m.options.Name), Summary: "The agent reports that some jobs are not running"}
+	case StateUnresponsive:
+		return Alert{Severity: SeverityCritical, Title: fmt.Sprintf("'%s' is unresponsive", m.options.Name), Summary: "The VM exists but its agent does not respond"}
+	case StateMissing:
+		return Alert{Severity: SeverityCritical, Title: fmt.Sprintf("'%s' is missing", m.options.Name), Summary: "The VM no longer exists according to the CPI"}
+	default:
+		summary := "The health of the instance could not be determined"
+		if checkErr != nil {
+			summary = checkErr.Error()
+		}
+		return Alert{Severity: SeverityWarning, Title: fmt.Sprintf("'%s' could not be checked", m.options.Name), Summary: summary}
+	}
+}
+
+func (m *monitor) notify(alert Alert) {
+	alert.CreatedAt = m.timeService.Now()
+	err := m.notifier.Notify(alert)
+	if err != nil {
+		m.logger.Warn(m.logTag, "Sending alert '%s': %s", alert.Title, err.Error())
+	}
+}
+
+// isDown returns true for the states that warrant recreating the VM
+func isDown(state State) bool {
+	return state == StateMissing || state == StateUnresponsive
+}
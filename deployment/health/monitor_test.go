@@ -0,0 +1,239 @@
+package health_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/deployment/health"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_health "github.com/cloudfoundry/bosh-init/deployment/health/mocks"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("Monitor", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		mockChecker     *mock_health.MockChecker
+		mockResurrector *mock_health.MockResurrector
+		mockNotifier    *mock_health.MockNotifier
+		fakeClock       *fakeclock.FakeClock
+		options         MonitorOptions
+
+		startTime = time.Date(2016, time.March, 4, 13, 14, 15, 0, time.UTC)
+		interval  = 30 * time.Second
+
+		stopCh chan struct{}
+		doneCh chan struct{}
+	)
+
+	BeforeEach(func() {
+		mockChecker = mock_health.NewMockChecker(mockCtrl)
+		mockResurrector = mock_health.NewMockResurrector(mockCtrl)
+		mockNotifier = mock_health.NewMockNotifier(mockCtrl)
+		fakeClock = fakeclock.NewFakeClock(startTime)
+		options = MonitorOptions{Name: "fake-instance", Interval: interval}
+
+		stopCh = make(chan struct{})
+		doneCh = make(chan struct{})
+	})
+
+	// startWatching returns once the first check is done and the monitor waits for the next one
+	var startWatching = func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		monitor := NewMonitorFactory(fakeClock, logger).NewMonitor(mockChecker, mockResurrector, mockNotifier, options)
+
+		go func() {
+			monitor.Watch(stopCh)
+			close(doneCh)
+		}()
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+	}
+
+	var nextCheck = func() {
+		fakeClock.Increment(interval)
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+	}
+
+	var stopWatching = func() {
+		close(stopCh)
+		Eventually(doneCh).Should(BeClosed())
+	}
+
+	var alertAt = func(elapsed time.Duration, severity Severity, title, summary string) Alert {
+		return Alert{Severity: severity, Title: title, Summary: summary, CreatedAt: startTime.Add(elapsed)}
+	}
+
+	It("alerts when the instance becomes unresponsive and when it recovers", func() {
+		gomock.InOrder(
+			mockChecker.EXPECT().Check().Return(StateRunning, nil),
+			mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+			mockNotifier.EXPECT().Notify(alertAt(30*time.Second, SeverityCritical, "'fake-instance' is unresponsive", "The VM exists but its agent does not respond")),
+			mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+			mockChecker.EXPECT().Check().Return(StateRunning, nil),
+			mockNotifier.EXPECT().Notify(alertAt(90*time.Second, SeverityInfo, "'fake-instance' is running", "All jobs are running")),
+		)
+
+		startWatching()
+		nextCheck()
+		nextCheck()
+		nextCheck()
+		stopWatching()
+	})
+
+	It("alerts on the first check when the instance is not running", func() {
+		mockChecker.EXPECT().Check().Return(StateMissing, nil)
+		mockNotifier.EXPECT().Notify(alertAt(0, SeverityCritical, "'fake-instance' is missing", "The VM no longer exists according to the CPI"))
+
+		startWatching()
+		stopWatching()
+	})
+
+	It("alerts when the jobs are failing", func() {
+		mockChecker.EXPECT().Check().Return(StateFailing, nil)
+		mockNotifier.EXPECT().Notify(alertAt(0, SeverityWarning, "'fake-instance' is failing", "The agent reports that some jobs are not running"))
+
+		startWatching()
+		stopWatching()
+	})
+
+	It("alerts when the health cannot be checked", func() {
+		mockChecker.EXPECT().Check().Return(StateRunning, errors.New("fake-check-error"))
+		mockNotifier.EXPECT().Notify(alertAt(0, SeverityWarning, "'fake-instance' could not be checked", "fake-check-error"))
+
+		startWatching()
+		stopWatching()
+	})
+
+	It("keeps watching when sending an alert fails", func() {
+		gomock.InOrder(
+			mockChecker.EXPECT().Check().Return(StateMissing, nil),
+			mockNotifier.EXPECT().Notify(gomock.Any()).Return(errors.New("fake-notify-error")),
+			mockChecker.EXPECT().Check().Return(StateRunning, nil),
+			mockNotifier.EXPECT().Notify(gomock.Any()),
+		)
+
+		startWatching()
+		nextCheck()
+		stopWatching()
+	})
+
+	It("does not recreate the VM by default", func() {
+		mockChecker.EXPECT().Check().Return(StateUnresponsive, nil).Times(4)
+		mockNotifier.EXPECT().Notify(gomock.Any())
+
+		startWatching()
+		nextCheck()
+		nextCheck()
+		nextCheck()
+		stopWatching()
+	})
+
+	Context("when resurrection is enabled", func() {
+		BeforeEach(func() {
+			options.ResurrectAfter = 1 * time.Minute
+		})
+
+		It("recreates the VM once it has been unresponsive for long enough", func() {
+			gomock.InOrder(
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockChecker.EXPECT().Check().Return(StateMissing, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockChecker.EXPECT().Check().Return(StateMissing, nil),
+				mockNotifier.EXPECT().Notify(alertAt(60*time.Second, SeverityWarning, "Recreating 'fake-instance'", "The VM has been missing for at least 1m0s, recreating it with its persistent disks")),
+				mockResurrector.EXPECT().Resurrect(),
+				mockNotifier.EXPECT().Notify(alertAt(60*time.Second, SeverityInfo, "Recreated 'fake-instance'", "The VM was recreated and its jobs are running")),
+				mockChecker.EXPECT().Check().Return(StateRunning, nil),
+			)
+
+			startWatching()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			stopWatching()
+		})
+
+		It("alerts and retries later when recreating the VM is skipped", func() {
+			gomock.InOrder(
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockResurrector.EXPECT().Resurrect().Return(NewResurrectionSkippedError(errors.New("fake-lock-held-error"))),
+				mockNotifier.EXPECT().Notify(alertAt(60*time.Second, SeverityWarning, "Skipped recreating 'fake-instance'", "fake-lock-held-error")),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockResurrector.EXPECT().Resurrect(),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+			)
+
+			startWatching()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			stopWatching()
+		})
+
+		It("retries once the VM has been down for long enough again when recreating it fails", func() {
+			gomock.InOrder(
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockResurrector.EXPECT().Resurrect().Return(errors.New("fake-resurrect-error")),
+				mockNotifier.EXPECT().Notify(alertAt(60*time.Second, SeverityCritical, "Failed to recreate 'fake-instance'", "fake-resurrect-error")),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockChecker.EXPECT().Check().Return(StateUnresponsive, nil),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+				mockResurrector.EXPECT().Resurrect(),
+				mockNotifier.EXPECT().Notify(gomock.Any()),
+			)
+
+			startWatching()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			stopWatching()
+		})
+
+		It("does not recreate the VM when only its jobs are failing", func() {
+			mockChecker.EXPECT().Check().Return(StateFailing, nil).Times(4)
+			mockNotifier.EXPECT().Notify(gomock.Any())
+
+			startWatching()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			stopWatching()
+		})
+
+		It("does not recreate the VM when its health cannot be checked", func() {
+			mockChecker.EXPECT().Check().Return(StateUnknown, errors.New("fake-check-error")).Times(4)
+			mockNotifier.EXPECT().Notify(gomock.Any())
+
+			startWatching()
+			nextCheck()
+			nextCheck()
+			nextCheck()
+			stopWatching()
+		})
+	})
+})
@@ -0,0 +1,59 @@
+package health
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// Notifier delivers alerts to an operator
+type Notifier interface {
+	Notify(Alert) error
+}
+
+type multiNotifier struct {
+	notifiers []Notifier
+	logger    boshlog.Logger
+	logTag    string
+}
+
+// NewMultiNotifier returns a Notifier that delivers every alert to all the given notifiers,
+// even if some of them fail.
+func NewMultiNotifier(notifiers []Notifier, logger boshlog.Logger) Notifier {
+	return &multiNotifier{
+		notifiers: notifiers,
+		logger:    logger,
+		logTag:    "multiNotifier",
+	}
+}
+
+func (n *multiNotifier) Notify(alert Alert) error {
+	errs := []error{}
+	for _, notifier := range n.notifiers {
+		err := notifier.Notify(alert)
+		if err != nil {
+			n.logger.Warn(n.logTag, "Delivering alert '%s': %s", alert.Title, err.Error())
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return bosherr.NewMultiError(errs...)
+	}
+
+	return nil
+}
+
+type uiNotifier struct {
+	ui biui.UI
+}
+
+// NewUINotifier returns a Notifier that prints alerts to the terminal
+func NewUINotifier(ui biui.UI) Notifier {
+	return &uiNotifier{ui: ui}
+}
+
+func (n *uiNotifier) Notify(alert Alert) error {
+	n.ui.PrintLinef("%s [%s] %s: %s", alert.CreatedAt.Format("2006-01-02 15:04:05"), alert.Severity, alert.Title, alert.Summary)
+	return nil
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// NotifierConfig lists where alerts are delivered in addition to the terminal
+type NotifierConfig struct {
+	WebhookURLs    []string
+	EmailAddresses []string
+	LogFilePaths   []string
+}
+
+type NotifierFactory interface {
+	NewNotifier(NotifierConfig) Notifier
+}
+
+type notifierFactory struct {
+	ui          biui.UI
+	fs          boshsys.FileSystem
+	httpClient  bihttpclient.HTTPClient
+	sendMail    SendMailFunc
+	smtpAddress string
+	emailFrom   string
+	logger      boshlog.Logger
+}
+
+func NewNotifierFactory(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	httpClient bihttpclient.HTTPClient,
+	sendMail SendMailFunc,
+	smtpAddress string,
+	emailFrom string,
+	logger boshlog.Logger,
+) NotifierFactory {
+	return &notifierFactory{
+		ui:          ui,
+		fs:          fs,
+		httpClient:  httpClient,
+		sendMail:    sendMail,
+		smtpAddress: smtpAddress,
+		emailFrom:   emailFrom,
+		logger:      logger,
+	}
+}
+
+func (f *notifierFactory) NewNotifier(config NotifierConfig) Notifier {
+	notifiers := []Notifier{NewUINotifier(f.ui)}
+
+	for _, url := range config.WebhookURLs {
+		notifiers = append(notifiers, NewWebhookNotifier(url, f.httpClient))
+	}
+
+	if len(config.EmailAddresses) > 0 {
+		notifiers = append(notifiers, NewEmailNotifier(f.sendMail, f.smtpAddress, f.emailFrom, config.EmailAddresses))
+	}
+
+	for _, path := range config.LogFilePaths {
+		notifiers = append(notifiers, NewLogFileNotifier(path, f.fs))
+	}
+
+	return NewMultiNotifier(notifiers, f.logger)
+}
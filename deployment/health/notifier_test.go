@@ -0,0 +1,192 @@
+package health_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/smtp"
+	"os"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/deployment/health"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_health "github.com/cloudfoundry/bosh-init/deployment/health/mocks"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+	fakebihttpclient "github.com/cloudfoundry/bosh-utils/httpclient/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Notifiers", func() {
+	var (
+		alert = Alert{
+			Severity:  SeverityCritical,
+			Title:     "'fake-instance' is missing",
+			Summary:   "fake-summary",
+			CreatedAt: time.Date(2016, time.March, 4, 13, 14, 15, 0, time.UTC),
+		}
+	)
+
+	Describe("MultiNotifier", func() {
+		var mockCtrl *gomock.Controller
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("delivers the alert to all notifiers, even if some of them fail", func() {
+			failingNotifier := mock_health.NewMockNotifier(mockCtrl)
+			otherNotifier := mock_health.NewMockNotifier(mockCtrl)
+			failingNotifier.EXPECT().Notify(alert).Return(errors.New("fake-notify-error"))
+			otherNotifier.EXPECT().Notify(alert)
+
+			notifier := NewMultiNotifier([]Notifier{failingNotifier, otherNotifier}, boshlog.NewLogger(boshlog.LevelNone))
+			err := notifier.Notify(alert)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-notify-error"))
+		})
+	})
+
+	Describe("UINotifier", func() {
+		It("prints the alert", func() {
+			fakeUI := &fakebiui.FakeUI{}
+
+			err := NewUINotifier(fakeUI).Notify(alert)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(Equal([]string{"2016-03-04 13:14:15 [critical] 'fake-instance' is missing: fake-summary"}))
+		})
+	})
+
+	Describe("WebhookNotifier", func() {
+		var fakeHTTPClient *fakebihttpclient.FakeHTTPClient
+
+		BeforeEach(func() {
+			fakeHTTPClient = fakebihttpclient.NewFakeHTTPClient()
+		})
+
+		It("posts the alert as JSON", func() {
+			fakeHTTPClient.SetPostBehavior("", 200, nil)
+
+			err := NewWebhookNotifier("http://fake-webhook", fakeHTTPClient).Notify(alert)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeHTTPClient.PostInputs).To(HaveLen(1))
+			Expect(fakeHTTPClient.PostInputs[0].Endpoint).To(Equal("http://fake-webhook"))
+
+			var payload map[string]string
+			err = json.Unmarshal(fakeHTTPClient.PostInputs[0].Payload, &payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal(map[string]string{
+				"severity":   "critical",
+				"title":      "'fake-instance' is missing",
+				"summary":    "fake-summary",
+				"created_at": "2016-03-04T13:14:15Z",
+			}))
+		})
+
+		It("returns an error when posting fails", func() {
+			fakeHTTPClient.SetPostBehavior("", 0, errors.New("fake-post-error"))
+
+			err := NewWebhookNotifier("http://fake-webhook", fakeHTTPClient).Notify(alert)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-post-error"))
+		})
+
+		It("returns an error when the webhook does not respond with success", func() {
+			fakeHTTPClient.SetPostBehavior("", 500, nil)
+
+			err := NewWebhookNotifier("http://fake-webhook", fakeHTTPClient).Notify(alert)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Posting alert to webhook 'http://fake-webhook': unexpected status code 500"))
+		})
+	})
+
+	Describe("EmailNotifier", func() {
+		type sendMailInput struct {
+			Addr    string
+			From    string
+			To      []string
+			Message string
+		}
+
+		var (
+			sendMailInputs []sendMailInput
+			sendMailErr    error
+			sendMail       SendMailFunc
+		)
+
+		BeforeEach(func() {
+			sendMailInputs = []sendMailInput{}
+			sendMailErr = nil
+			sendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+				sendMailInputs = append(sendMailInputs, sendMailInput{Addr: addr, From: from, To: to, Message: string(msg)})
+				return sendMailErr
+			}
+		})
+
+		It("emails the alert through the SMTP server", func() {
+			notifier := NewEmailNotifier(sendMail, "localhost:25", "bosh-init@localhost", []string{"ops@example.com", "oncall@example.com"})
+
+			err := notifier.Notify(alert)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sendMailInputs).To(Equal([]sendMailInput{
+				{
+					Addr: "localhost:25",
+					From: "bosh-init@localhost",
+					To:   []string{"ops@example.com", "oncall@example.com"},
+					Message: "From: bosh-init@localhost\r\n" +
+						"To: ops@example.com, oncall@example.com\r\n" +
+						"Subject: [bosh-init] 'fake-instance' is missing\r\n" +
+						"Date: Fri, 04 Mar 2016 13:14:15 +0000\r\n" +
+						"\r\n" +
+						"Severity: critical\r\n" +
+						"\r\n" +
+						"fake-summary\r\n",
+				},
+			}))
+		})
+
+		It("returns an error when sending the email fails", func() {
+			sendMailErr = errors.New("fake-send-mail-error")
+
+			err := NewEmailNotifier(sendMail, "localhost:25", "bosh-init@localhost", []string{"ops@example.com"}).Notify(alert)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-send-mail-error"))
+		})
+	})
+
+	Describe("LogFileNotifier", func() {
+		var fs *fakesys.FakeFileSystem
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+		})
+
+		It("appends the alert to the log file", func() {
+			err := NewLogFileNotifier("/fake-alerts.log", fs).Notify(alert)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/fake-alerts.log")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(Equal("2016-03-04T13:14:15Z [critical] 'fake-instance' is missing: fake-summary\n"))
+
+			fileStats := fs.GetFileTestStat("/fake-alerts.log")
+			Expect(fileStats.Flags & os.O_APPEND).ToNot(BeZero())
+		})
+
+		It("returns an error when the log file cannot be opened", func() {
+			fs.OpenFileErr = errors.New("fake-open-error")
+
+			err := NewLogFileNotifier("/fake-alerts.log", fs).Notify(alert)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-open-error"))
+		})
+	})
+})
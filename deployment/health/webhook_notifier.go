@@ -0,0 +1,40 @@
+package health
+
+import (
+	"encoding/json"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+type webhookNotifier struct {
+	url        string
+	httpClient bihttpclient.HTTPClient
+}
+
+// NewWebhookNotifier returns a Notifier that POSTs every alert as a JSON document to url
+func NewWebhookNotifier(url string, httpClient bihttpclient.HTTPClient) Notifier {
+	return &webhookNotifier{
+		url:        url,
+		httpClient: httpClient,
+	}
+}
+
+func (n *webhookNotifier) Notify(alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling alert")
+	}
+
+	response, err := n.httpClient.Post(n.url, payload)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Posting alert to webhook '%s'", n.url)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return bosherr.Errorf("Posting alert to webhook '%s': unexpected status code %d", n.url, response.StatusCode)
+	}
+
+	return nil
+}
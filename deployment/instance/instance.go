@@ -21,15 +21,31 @@ type Instance interface {
 	ID() int
 	Disks() ([]bidisk.Disk, error)
 	WaitUntilReady(biinstallmanifest.Registry, biui.Stage) error
+	StopJobs(bideplmanifest.Manifest, DrainOptions, biui.Stage) error
 	UpdateDisks(bideplmanifest.Manifest, biui.Stage) ([]bidisk.Disk, error)
 	UpdateJobs(bideplmanifest.Manifest, biui.Stage) error
 	Delete(
 		pingTimeout time.Duration,
 		pingDelay time.Duration,
+		drainOptions DrainOptions,
 		stage biui.Stage,
 	) error
 }
 
+// DrainOptions controls whether and for how long the drain scripts of the jobs run before the jobs are stopped
+type DrainOptions struct {
+	Skip    bool
+	MaxWait time.Duration
+}
+
+// NewDrainOptions returns the drain options for the update block of the deployment manifest
+func NewDrainOptions(update bideplmanifest.Update, skip bool) DrainOptions {
+	return DrainOptions{
+		Skip:    skip,
+		MaxWait: time.Duration(update.MaxDrainWait) * time.Millisecond,
+	}
+}
+
 type instance struct {
 	jobName          string
 	id               int
@@ -37,8 +53,10 @@ type instance struct {
 	vmManager        bivm.Manager
 	sshTunnelFactory bisshtunnel.Factory
 	stateBuilder     biinstancestate.Builder
-	logger           boshlog.Logger
-	logTag           string
+	// newAgentState is the state built by StopJobs for the drain scripts, it is applied by UpdateJobs
+	newAgentState biinstancestate.State
+	logger        boshlog.Logger
+	logTag        string
 }
 
 func NewInstance(
@@ -86,11 +104,22 @@ func (i *instance) WaitUntilReady(
 	err := stage.Perform(stepName, func() error {
 		if !registryConfig.IsEmpty() {
 			sshTunnelOptions := bisshtunnel.Options{
-				Host:              registryConfig.SSHTunnel.Host,
-				Port:              registryConfig.SSHTunnel.Port,
-				User:              registryConfig.SSHTunnel.User,
-				Password:          registryConfig.SSHTunnel.Password,
-				PrivateKey:        registryConfig.SSHTunnel.PrivateKey,
+				Host:           registryConfig.SSHTunnel.Host,
+				Port:           registryConfig.SSHTunnel.Port,
+				User:           registryConfig.SSHTunnel.User,
+				Password:       registryConfig.SSHTunnel.Password,
+				PrivateKey:     registryConfig.SSHTunnel.PrivateKey,
+				Passphrase:     registryConfig.SSHTunnel.Passphrase,
+				UseAgent:       registryConfig.SSHTunnel.UseAgent,
+				KnownHostsFile: registryConfig.SSHTunnel.KnownHosts,
+				JumpHost: bisshtunnel.JumpHostOptions{
+					Host:       registryConfig.SSHTunnel.JumpHost.Host,
+					Port:       registryConfig.SSHTunnel.JumpHost.Port,
+					User:       registryConfig.SSHTunnel.JumpHost.User,
+					Password:   registryConfig.SSHTunnel.JumpHost.Password,
+					PrivateKey: registryConfig.SSHTunnel.JumpHost.PrivateKey,
+					Passphrase: registryConfig.SSHTunnel.JumpHost.Passphrase,
+				},
 				LocalForwardPort:  registryConfig.Port,
 				RemoteForwardPort: registryConfig.Port,
 			}
@@ -110,19 +139,49 @@ func (i *instance) WaitUntilReady(
 			}
 		}
 
-		return i.vm.WaitUntilReady(10*time.Minute, 500*time.Millisecond)
+		return i.vm.WaitUntilReady(10*time.Minute, 500*time.Millisecond, biui.InterruptCh(stage))
 	})
 
 	return err
 }
 
+// StopJobs drains and stops the jobs, so that the disks of the instance can be updated while they are not in use.
+// The drain scripts are given the spec that UpdateJobs applies afterwards.
+func (i *instance) StopJobs(deploymentManifest bideplmanifest.Manifest, drainOptions DrainOptions, stage biui.Stage) error {
+	if drainOptions.Skip {
+		i.logger.Info(i.logTag, "Skipping drain of jobs on instance '%s/%d'", i.jobName, i.id)
+	} else {
+		// the networks of the VM are unchanged, so the current state resolves the addresses of the new one
+		currentAgentState, err := i.vm.GetState()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Getting state for instance '%s/%d'", i.jobName, i.id)
+		}
+
+		newAgentState, err := i.stateBuilder.Build(i.jobName, i.id, deploymentManifest, stage, currentAgentState)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Building state for instance '%s/%d'", i.jobName, i.id)
+		}
+		i.newAgentState = newAgentState
+
+		stepName := fmt.Sprintf("Draining jobs on instance '%s/%d'", i.jobName, i.id)
+		err = stage.Perform(stepName, func() error {
+			return i.vm.DrainForUpdate(newAgentState.ToApplySpec(), drainOptions.MaxWait, biui.InterruptCh(stage))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.stopJobs(stage)
+}
+
 func (i *instance) UpdateDisks(deploymentManifest bideplmanifest.Manifest, stage biui.Stage) ([]bidisk.Disk, error) {
-	diskPool, err := deploymentManifest.DiskPool(i.jobName)
+	persistentDisks, err := deploymentManifest.PersistentDisks(i.jobName)
 	if err != nil {
-		return []bidisk.Disk{}, bosherr.WrapError(err, "Getting disk pool")
+		return []bidisk.Disk{}, bosherr.WrapError(err, "Getting persistent disks")
 	}
 
-	disks, err := i.vm.UpdateDisks(diskPool, stage)
+	disks, err := i.vm.UpdateDisks(persistentDisks, stage)
 	if err != nil {
 		return disks, bosherr.WrapError(err, "Updating disks")
 	}
@@ -134,27 +193,11 @@ func (i *instance) UpdateJobs(
 	deploymentManifest bideplmanifest.Manifest,
 	stage biui.Stage,
 ) error {
-	initialAgentState, err := i.stateBuilder.BuildInitialState(i.jobName, i.id, deploymentManifest)
-	if err != nil {
-		return bosherr.WrapErrorf(err, "Building initial state for instance '%s/%d'", i.jobName, i.id)
-	}
-
-	// apply it to agent to force it to load networking details
-	err = i.vm.Apply(initialAgentState.ToApplySpec())
+	newAgentState, err := i.buildState(deploymentManifest, stage)
 	if err != nil {
-		return bosherr.WrapError(err, "Applying the initial agent state")
-	}
-
-	// now that the agent will tell us the address, get new state
-	resolvedAgentState, err := i.vm.GetState()
-	if err != nil {
-		return bosherr.WrapErrorf(err, "Getting state for instance '%s/%d'", i.jobName, i.id)
+		return err
 	}
 
-	newAgentState, err := i.stateBuilder.Build(i.jobName, i.id, deploymentManifest, stage, resolvedAgentState)
-	if err != nil {
-		return bosherr.WrapErrorf(err, "Building state for instance '%s/%d'", i.jobName, i.id)
-	}
 	stepName := fmt.Sprintf("Updating instance '%s/%d'", i.jobName, i.id)
 	err = stage.Perform(stepName, func() error {
 		err = i.vm.Stop()
@@ -200,9 +243,41 @@ func (i *instance) UpdateJobs(
 	return err
 }
 
+// buildState returns the state built by StopJobs, or builds it after applying the initial state
+func (i *instance) buildState(deploymentManifest bideplmanifest.Manifest, stage biui.Stage) (biinstancestate.State, error) {
+	if i.newAgentState != nil {
+		return i.newAgentState, nil
+	}
+
+	initialAgentState, err := i.stateBuilder.BuildInitialState(i.jobName, i.id, deploymentManifest)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Building initial state for instance '%s/%d'", i.jobName, i.id)
+	}
+
+	// apply it to agent to force it to load networking details
+	err = i.vm.Apply(initialAgentState.ToApplySpec())
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Applying the initial agent state")
+	}
+
+	// now that the agent will tell us the address, get new state
+	resolvedAgentState, err := i.vm.GetState()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Getting state for instance '%s/%d'", i.jobName, i.id)
+	}
+
+	newAgentState, err := i.stateBuilder.Build(i.jobName, i.id, deploymentManifest, stage, resolvedAgentState)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Building state for instance '%s/%d'", i.jobName, i.id)
+	}
+
+	return newAgentState, nil
+}
+
 func (i *instance) Delete(
 	pingTimeout time.Duration,
 	pingDelay time.Duration,
+	drainOptions DrainOptions,
 	stage biui.Stage,
 ) error {
 	vmExists, err := i.vm.Exists()
@@ -211,7 +286,7 @@ func (i *instance) Delete(
 	}
 
 	if vmExists {
-		if err = i.shutdown(pingTimeout, pingDelay, stage); err != nil {
+		if err = i.shutdown(pingTimeout, pingDelay, drainOptions, stage); err != nil {
 			return err
 		}
 	}
@@ -231,11 +306,12 @@ func (i *instance) Delete(
 func (i *instance) shutdown(
 	pingTimeout time.Duration,
 	pingDelay time.Duration,
+	drainOptions DrainOptions,
 	stage biui.Stage,
 ) error {
 	stepName := fmt.Sprintf("Waiting for the agent on VM '%s'", i.vm.CID())
 	waitingForAgentErr := stage.Perform(stepName, func() error {
-		if err := i.vm.WaitUntilReady(pingTimeout, pingDelay); err != nil {
+		if err := i.vm.WaitUntilReady(pingTimeout, pingDelay, biui.InterruptCh(stage)); err != nil {
 			return bosherr.WrapError(err, "Agent unreachable")
 		}
 		return nil
@@ -245,6 +321,9 @@ func (i *instance) shutdown(
 		return nil
 	}
 
+	if err := i.drainJobs(drainOptions, stage); err != nil {
+		return err
+	}
 	if err := i.stopJobs(stage); err != nil {
 		return err
 	}
@@ -262,8 +341,25 @@ func (i *instance) waitUntilJobsAreRunning(updateWatchTime bideplmanifest.WatchT
 
 	stepName := fmt.Sprintf("Waiting for instance '%s/%d' to be running", i.jobName, i.id)
 	return stage.Perform(stepName, func() error {
-		time.Sleep(start)
-		return i.vm.WaitToBeRunning(maxAttempts, delayBetweenAttempts)
+		interruptCh := biui.InterruptCh(stage)
+		select {
+		case <-time.After(start):
+		case <-interruptCh:
+			return biui.NewInterruptedWaitError("waiting for the jobs to start")
+		}
+		return i.vm.WaitToBeRunning(maxAttempts, delayBetweenAttempts, interruptCh)
+	})
+}
+
+func (i *instance) drainJobs(drainOptions DrainOptions, stage biui.Stage) error {
+	if drainOptions.Skip {
+		i.logger.Info(i.logTag, "Skipping drain of jobs on instance '%s/%d'", i.jobName, i.id)
+		return nil
+	}
+
+	stepName := fmt.Sprintf("Draining jobs on instance '%s/%d'", i.jobName, i.id)
+	return stage.Perform(stepName, func() error {
+		return i.vm.Drain(drainOptions.MaxWait, biui.InterruptCh(stage))
 	})
 }
 
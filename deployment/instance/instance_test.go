@@ -51,8 +51,9 @@ var _ = Describe("Instance", func() {
 
 		instance Instance
 
-		pingTimeout = 1 * time.Second
-		pingDelay   = 500 * time.Millisecond
+		pingTimeout  = 1 * time.Second
+		pingDelay    = 500 * time.Millisecond
+		drainOptions = DrainOptions{MaxWait: 10 * time.Minute}
 
 		jobName  = "fake-job-name"
 		jobIndex = 0
@@ -87,7 +88,7 @@ var _ = Describe("Instance", func() {
 
 	Describe("Delete", func() {
 		It("checks if the agent on the vm is responsive", func() {
-			err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+			err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeVM.WaitUntilReadyInputs).To(ContainElement(fakebivm.WaitUntilReadyInput{
@@ -97,18 +98,19 @@ var _ = Describe("Instance", func() {
 		})
 
 		It("deletes existing vm", func() {
-			err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+			err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeVM.DeleteCalled).To(Equal(1))
 		})
 
 		It("logs start and stop events", func() {
-			err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+			err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 				{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+				{Name: "Draining jobs on instance 'fake-job-name/0'"},
 				{Name: "Stopping jobs on instance 'fake-job-name/0'"},
 				{Name: "Deleting VM 'fake-vm-cid'"},
 			}))
@@ -116,7 +118,7 @@ var _ = Describe("Instance", func() {
 
 		Context("when agent is responsive", func() {
 			It("logs waiting for the agent event", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0]).To(Equal(&fakebiui.PerformCall{
@@ -125,18 +127,50 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("stops vm", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeVM.StopCalled).To(Equal(1))
 			})
 
+			It("drains the jobs for at most the maximum drain wait", func() {
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeVM.DrainInputs).To(Equal([]time.Duration{10 * time.Minute}))
+			})
+
+			Context("when draining is skipped", func() {
+				It("stops the jobs without draining them", func() {
+					err := instance.Delete(pingTimeout, pingDelay, DrainOptions{Skip: true}, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeVM.DrainInputs).To(BeEmpty())
+					Expect(fakeVM.StopCalled).To(Equal(1))
+					Expect(fakeStage.PerformCalls).ToNot(ContainElement(&fakebiui.PerformCall{Name: "Draining jobs on instance 'fake-job-name/0'"}))
+				})
+			})
+
+			Context("when draining fails", func() {
+				BeforeEach(func() {
+					fakeVM.DrainErr = bosherr.Error("fake-drain-error")
+				})
+
+				It("returns an error without stopping the jobs", func() {
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+
+					Expect(fakeVM.StopCalled).To(Equal(0))
+				})
+			})
+
 			It("unmounts vm disks", func() {
 				firstDisk := fakebidisk.NewFakeDisk("fake-disk-1")
 				secondDisk := fakebidisk.NewFakeDisk("fake-disk-2")
 				fakeVM.ListDisksDisks = []bidisk.Disk{firstDisk, secondDisk}
 
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeVM.UnmountDiskInputs).To(Equal([]fakebivm.UnmountDiskInput{
@@ -144,7 +178,7 @@ var _ = Describe("Instance", func() {
 					{Disk: secondDisk},
 				}))
 
-				Expect(fakeStage.PerformCalls[2:4]).To(Equal([]*fakebiui.PerformCall{
+				Expect(fakeStage.PerformCalls[3:5]).To(Equal([]*fakebiui.PerformCall{
 					{Name: "Unmounting disk 'fake-disk-1'"},
 					{Name: "Unmounting disk 'fake-disk-2'"},
 				}))
@@ -160,12 +194,13 @@ var _ = Describe("Instance", func() {
 				})
 
 				It("returns an error", func() {
-					err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-stop-error"))
 
 					Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 						{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+						{Name: "Draining jobs on instance 'fake-job-name/0'"},
 						{
 							Name:  "Stopping jobs on instance 'fake-job-name/0'",
 							Error: stopError,
@@ -181,13 +216,13 @@ var _ = Describe("Instance", func() {
 				})
 
 				It("returns an error", func() {
-					err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-unmount-error"))
 
-					Expect(fakeStage.PerformCalls[2].Name).To(Equal("Unmounting disk 'fake-disk'"))
-					Expect(fakeStage.PerformCalls[2].Error).To(HaveOccurred())
-					Expect(fakeStage.PerformCalls[2].Error.Error()).To(Equal("Unmounting disk 'fake-disk' from VM 'fake-vm-cid': fake-unmount-error"))
+					Expect(fakeStage.PerformCalls[3].Name).To(Equal("Unmounting disk 'fake-disk'"))
+					Expect(fakeStage.PerformCalls[3].Error).To(HaveOccurred())
+					Expect(fakeStage.PerformCalls[3].Error.Error()).To(Equal("Unmounting disk 'fake-disk' from VM 'fake-vm-cid': fake-unmount-error"))
 				})
 			})
 		})
@@ -198,7 +233,7 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("logs failed event", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0].Name).To(Equal("Waiting for the agent on VM 'fake-vm-cid'"))
@@ -216,12 +251,13 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("returns an error", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-delete-error"))
 
 				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 					{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+					{Name: "Draining jobs on instance 'fake-job-name/0'"},
 					{Name: "Stopping jobs on instance 'fake-job-name/0'"},
 					{
 						Name:  "Deleting VM 'fake-vm-cid'",
@@ -241,14 +277,14 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("deletes existing vm", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeVM.DeleteCalled).To(Equal(1))
 			})
 
 			It("does not contact the agent", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeVM.WaitUntilReadyInputs).To(HaveLen(0))
@@ -257,7 +293,7 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("logs vm delete as skipped", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0].Name).To(Equal("Deleting VM 'fake-vm-cid'"))
@@ -325,6 +361,32 @@ var _ = Describe("Instance", func() {
 			Expect(fakeVM.StartCalled).To(Equal(1))
 		})
 
+		It("does not drain the jobs", func() {
+			err := instance.UpdateJobs(deploymentManifest, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.DrainInputs).To(BeEmpty())
+			Expect(fakeVM.DrainForUpdateInputs).To(BeEmpty())
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Updating instance 'fake-job-name/0'"))
+		})
+
+		Context("when the jobs were stopped for the update", func() {
+			It("applies the state built for the drain scripts without building it again", func() {
+				expectStateBuild.Times(1)
+				expectStateBuildInitialState.Times(0)
+
+				err := instance.StopJobs(deploymentManifest, drainOptions, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = instance.UpdateJobs(deploymentManifest, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVM.ApplyInputs).To(Equal([]fakebivm.ApplyInput{
+					{ApplySpec: applySpec},
+				}))
+			})
+		})
+
 		It("waits until agent reports state as running", func() {
 			err := instance.UpdateJobs(deploymentManifest, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
@@ -451,6 +513,76 @@ var _ = Describe("Instance", func() {
 		})
 	})
 
+	Describe("StopJobs", func() {
+		var (
+			deploymentManifest bideplmanifest.Manifest
+			applySpec          bias.ApplySpec
+			fakeAgentState     agentclient.AgentState
+		)
+
+		BeforeEach(func() {
+			deploymentManifest = bideplmanifest.Manifest{Name: "fake-deployment-name"}
+			applySpec = bias.ApplySpec{Deployment: "fake-deployment-name"}
+
+			fakeAgentState = agentclient.AgentState{JobState: "running"}
+			fakeVM.GetStateResult = fakeAgentState
+		})
+
+		It("drains the jobs with the new spec and then stops them", func() {
+			mockStateBuilder.EXPECT().Build(jobName, jobIndex, deploymentManifest, fakeStage, fakeAgentState).Return(mockState, nil)
+			mockState.EXPECT().ToApplySpec().Return(applySpec)
+
+			err := instance.StopJobs(deploymentManifest, drainOptions, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.DrainForUpdateInputs).To(Equal([]fakebivm.DrainForUpdateInput{
+				{NewSpec: applySpec, MaxWait: 10 * time.Minute},
+			}))
+			Expect(fakeVM.DrainInputs).To(BeEmpty())
+			Expect(fakeVM.StopCalled).To(Equal(1))
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Draining jobs on instance 'fake-job-name/0'"},
+				{Name: "Stopping jobs on instance 'fake-job-name/0'"},
+			}))
+		})
+
+		It("only stops the jobs when draining is skipped", func() {
+			err := instance.StopJobs(deploymentManifest, DrainOptions{Skip: true}, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.DrainForUpdateInputs).To(BeEmpty())
+			Expect(fakeVM.StopCalled).To(Equal(1))
+		})
+
+		Context("when building the new state fails", func() {
+			It("neither drains nor stops the jobs", func() {
+				mockStateBuilder.EXPECT().Build(jobName, jobIndex, deploymentManifest, fakeStage, fakeAgentState).Return(nil, bosherr.Error("fake-build-error"))
+
+				err := instance.StopJobs(deploymentManifest, drainOptions, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-build-error"))
+				Expect(fakeVM.DrainForUpdateInputs).To(BeEmpty())
+				Expect(fakeVM.StopCalled).To(Equal(0))
+			})
+		})
+
+		Context("when draining fails", func() {
+			BeforeEach(func() {
+				fakeVM.DrainForUpdateErr = bosherr.Error("fake-drain-error")
+			})
+
+			It("does not stop the jobs", func() {
+				mockStateBuilder.EXPECT().Build(jobName, jobIndex, deploymentManifest, fakeStage, fakeAgentState).Return(mockState, nil)
+				mockState.EXPECT().ToApplySpec().Return(applySpec)
+
+				err := instance.StopJobs(deploymentManifest, drainOptions, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+				Expect(fakeVM.StopCalled).To(Equal(0))
+			})
+		})
+	})
+
 	Describe("WaitUntilReady", func() {
 		var (
 			registryConfig biinstallmanifest.Registry
@@ -27,9 +27,18 @@ type Manager interface {
 		registryConfig biinstallmanifest.Registry,
 		eventLoggerStage biui.Stage,
 	) (Instance, []bidisk.Disk, error)
+	Update(
+		jobName string,
+		id int,
+		deploymentManifest bideplmanifest.Manifest,
+		registryConfig biinstallmanifest.Registry,
+		drainOptions DrainOptions,
+		eventLoggerStage biui.Stage,
+	) (Instance, []bidisk.Disk, error)
 	DeleteAll(
 		pingTimeout time.Duration,
 		pingDelay time.Duration,
+		drainOptions DrainOptions,
 		eventLoggerStage biui.Stage,
 	) error
 }
@@ -133,9 +142,47 @@ func (m *manager) Create(
 	return instance, disks, err
 }
 
+// Update reuses the currently deployed VM for the instance, so that only its disks and jobs need to be updated.
+// The jobs are drained and stopped before the disks are updated.
+func (m *manager) Update(
+	jobName string,
+	id int,
+	deploymentManifest bideplmanifest.Manifest,
+	registryConfig biinstallmanifest.Registry,
+	drainOptions DrainOptions,
+	eventLoggerStage biui.Stage,
+) (Instance, []bidisk.Disk, error) {
+	vm, found, err := m.vmManager.FindCurrent()
+	if err != nil {
+		return nil, []bidisk.Disk{}, bosherr.WrapError(err, "Finding currently deployed instances")
+	}
+
+	if !found {
+		return nil, []bidisk.Disk{}, bosherr.Errorf("No current VM found for instance '%s/%d'", jobName, id)
+	}
+
+	instance := m.instanceFactory.NewInstance(jobName, id, vm, m.vmManager, m.sshTunnelFactory, m.blobstore, m.logger)
+
+	if err := instance.WaitUntilReady(registryConfig, eventLoggerStage); err != nil {
+		return instance, []bidisk.Disk{}, bosherr.WrapError(err, "Waiting until instance is ready")
+	}
+
+	if err := instance.StopJobs(deploymentManifest, drainOptions, eventLoggerStage); err != nil {
+		return instance, []bidisk.Disk{}, bosherr.WrapError(err, "Stopping instance jobs")
+	}
+
+	disks, err := instance.UpdateDisks(deploymentManifest, eventLoggerStage)
+	if err != nil {
+		return instance, disks, bosherr.WrapError(err, "Updating instance disks")
+	}
+
+	return instance, disks, err
+}
+
 func (m *manager) DeleteAll(
 	pingTimeout time.Duration,
 	pingDelay time.Duration,
+	drainOptions DrainOptions,
 	eventLoggerStage biui.Stage,
 ) error {
 	instances, err := m.FindCurrent()
@@ -144,7 +191,7 @@ func (m *manager) DeleteAll(
 	}
 
 	for _, instance := range instances {
-		if err = instance.Delete(pingTimeout, pingDelay, eventLoggerStage); err != nil {
+		if err = instance.Delete(pingTimeout, pingDelay, drainOptions, eventLoggerStage); err != nil {
 			return bosherr.WrapErrorf(err, "Deleting existing instance '%s/%d'", instance.JobName(), instance.ID())
 		}
 	}
@@ -295,8 +295,8 @@ var _ = Describe("Manager", func() {
 
 			Expect(fakeVM.UpdateDisksInputs).To(Equal([]fakebivm.UpdateDisksInput{
 				{
-					DiskPool: diskPool,
-					Stage:    fakeStage,
+					PersistentDisks: []bideplmanifest.PersistentDisk{{DiskPool: diskPool}},
+					Stage:           fakeStage,
 				},
 			}))
 		})
@@ -412,4 +412,160 @@ var _ = Describe("Manager", func() {
 			})
 		})
 	})
+
+	Describe("Update", func() {
+		var (
+			mockAgentClient    *mock_agentclient.MockAgentClient
+			fakeVM             *fakebivm.FakeVM
+			diskPool           bideplmanifest.DiskPool
+			deploymentManifest bideplmanifest.Manifest
+			registry           biinstallmanifest.Registry
+			drainOptions       DrainOptions
+
+			expectedDisk *fakebidisk.FakeDisk
+		)
+
+		BeforeEach(func() {
+			diskPool = bideplmanifest.DiskPool{
+				Name:     "fake-persistent-disk-pool-name",
+				DiskSize: 1024,
+			}
+
+			deploymentManifest = bideplmanifest.Manifest{
+				DiskPools: []bideplmanifest.DiskPool{
+					diskPool,
+				},
+				Jobs: []bideplmanifest.Job{
+					{
+						Name:               "fake-job-name",
+						PersistentDiskPool: "fake-persistent-disk-pool-name",
+						Instances:          1,
+					},
+				},
+			}
+
+			registry = biinstallmanifest.Registry{}
+			drainOptions = DrainOptions{MaxWait: 1 * time.Minute}
+
+			fakeVM = fakebivm.NewFakeVM("fake-vm-cid")
+			fakeVMManager.SetFindCurrentBehavior(fakeVM, true, nil)
+
+			mockAgentClient = mock_agentclient.NewMockAgentClient(mockCtrl)
+			fakeVM.AgentClientReturn = mockAgentClient
+
+			mockStateBuilderFactory.EXPECT().NewBuilder(mockBlobstore, mockAgentClient).Return(mockStateBuilder).AnyTimes()
+			mockStateBuilder.EXPECT().Build("fake-job-name", 0, deploymentManifest, fakeStage, agentclient.AgentState{}).Return(mockState, nil).AnyTimes()
+			mockState.EXPECT().ToApplySpec().Return(bias.ApplySpec{}).AnyTimes()
+
+			expectedDisk = fakebidisk.NewFakeDisk("fake-disk-cid")
+			fakeVM.UpdateDisksDisks = []bidisk.Disk{expectedDisk}
+		})
+
+		It("returns an Instance that wraps the current VM", func() {
+			// without draining no state is built before the jobs are updated
+			instance, _, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, DrainOptions{Skip: true}, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedInstance := NewInstance(
+				"fake-job-name",
+				0,
+				fakeVM,
+				fakeVMManager,
+				fakeSSHTunnelFactory,
+				mockStateBuilder,
+				logger,
+			)
+			Expect(instance).To(Equal(expectedInstance))
+			Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{}))
+		})
+
+		It("waits for the vm and updates its disks", func() {
+			_, disks, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, drainOptions, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(disks).To(Equal([]bidisk.Disk{expectedDisk}))
+
+			Expect(fakeVM.WaitUntilReadyInputs).To(Equal([]fakebivm.WaitUntilReadyInput{
+				{
+					Timeout: 10 * time.Minute,
+					Delay:   500 * time.Millisecond,
+				},
+			}))
+			Expect(fakeVM.UpdateDisksInputs).To(Equal([]fakebivm.UpdateDisksInput{
+				{
+					PersistentDisks: []bideplmanifest.PersistentDisk{{DiskPool: diskPool}},
+					Stage:           fakeStage,
+				},
+			}))
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Waiting for the agent on VM 'fake-vm-cid' to be ready"},
+				{Name: "Draining jobs on instance 'fake-job-name/0'"},
+				{Name: "Stopping jobs on instance 'fake-job-name/0'"},
+			}))
+		})
+
+		It("drains and stops the jobs before updating the disks", func() {
+			fakeVM.UpdateDisksErr = errors.New("fake-update-disks-error")
+
+			_, _, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, drainOptions, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-update-disks-error"))
+
+			Expect(fakeVM.DrainForUpdateInputs).To(HaveLen(1))
+			Expect(fakeVM.StopCalled).To(Equal(1))
+			Expect(fakeVM.UpdateDisksInputs).To(HaveLen(1))
+		})
+
+		Context("when stopping the jobs fails", func() {
+			BeforeEach(func() {
+				fakeVM.StopErr = errors.New("fake-stop-error")
+			})
+
+			It("does not update the disks", func() {
+				_, _, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, drainOptions, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-stop-error"))
+
+				Expect(fakeVM.UpdateDisksInputs).To(BeEmpty())
+			})
+		})
+
+		Context("when draining the jobs fails", func() {
+			BeforeEach(func() {
+				fakeVM.DrainForUpdateErr = errors.New("fake-drain-error")
+			})
+
+			It("neither stops the jobs nor updates the disks", func() {
+				_, _, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, drainOptions, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+
+				Expect(fakeVM.StopCalled).To(Equal(0))
+				Expect(fakeVM.UpdateDisksInputs).To(BeEmpty())
+			})
+		})
+
+		Context("when there is no current VM", func() {
+			BeforeEach(func() {
+				fakeVMManager.SetFindCurrentBehavior(nil, false, nil)
+			})
+
+			It("returns an error", func() {
+				_, _, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, drainOptions, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("No current VM found for instance 'fake-job-name/0'"))
+			})
+		})
+
+		Context("when finding the current VM fails", func() {
+			BeforeEach(func() {
+				fakeVMManager.SetFindCurrentBehavior(nil, false, errors.New("fake-find-error"))
+			})
+
+			It("returns an error", func() {
+				_, _, err := manager.Update("fake-job-name", 0, deploymentManifest, registry, drainOptions, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-error"))
+			})
+		})
+	})
 })
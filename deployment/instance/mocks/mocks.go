@@ -1,12 +1,15 @@
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/cloudfoundry/bosh-init/deployment/instance (interfaces: Instance,Manager)
+// Source: github.com/cloudfoundry/bosh-init/deployment/instance (interfaces: Instance,Manager,ManagerFactory)
 
 package mocks
 
 import (
+	blobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	cloud "github.com/cloudfoundry/bosh-init/cloud"
 	disk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	instance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	manifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	vm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	manifest0 "github.com/cloudfoundry/bosh-init/installation/manifest"
 	stemcell "github.com/cloudfoundry/bosh-init/stemcell"
 	ui "github.com/cloudfoundry/bosh-init/ui"
@@ -35,14 +38,14 @@ func (_m *MockInstance) EXPECT() *_MockInstanceRecorder {
 	return _m.recorder
 }
 
-func (_m *MockInstance) Delete(_param0 time.Duration, _param1 time.Duration, _param2 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "Delete", _param0, _param1, _param2)
+func (_m *MockInstance) Delete(_param0 time.Duration, _param1 time.Duration, _param2 instance.DrainOptions, _param3 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "Delete", _param0, _param1, _param2, _param3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockInstanceRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1, arg2)
+func (_mr *_MockInstanceRecorder) Delete(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1, arg2, arg3)
 }
 
 func (_m *MockInstance) Disks() ([]disk.Disk, error) {
@@ -76,6 +79,16 @@ func (_mr *_MockInstanceRecorder) JobName() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "JobName")
 }
 
+func (_m *MockInstance) StopJobs(_param0 manifest.Manifest, _param1 instance.DrainOptions, _param2 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "StopJobs", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) StopJobs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopJobs", arg0, arg1, arg2)
+}
+
 func (_m *MockInstance) UpdateDisks(_param0 manifest.Manifest, _param1 ui.Stage) ([]disk.Disk, error) {
 	ret := _m.ctrl.Call(_m, "UpdateDisks", _param0, _param1)
 	ret0, _ := ret[0].([]disk.Disk)
@@ -140,14 +153,14 @@ func (_mr *_MockManagerRecorder) Create(arg0, arg1, arg2, arg3, arg4, arg5 inter
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Create", arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
-func (_m *MockManager) DeleteAll(_param0 time.Duration, _param1 time.Duration, _param2 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "DeleteAll", _param0, _param1, _param2)
+func (_m *MockManager) DeleteAll(_param0 time.Duration, _param1 time.Duration, _param2 instance.DrainOptions, _param3 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "DeleteAll", _param0, _param1, _param2, _param3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockManagerRecorder) DeleteAll(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteAll", arg0, arg1, arg2)
+func (_mr *_MockManagerRecorder) DeleteAll(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteAll", arg0, arg1, arg2, arg3)
 }
 
 func (_m *MockManager) FindCurrent() ([]instance.Instance, error) {
@@ -160,3 +173,46 @@ func (_m *MockManager) FindCurrent() ([]instance.Instance, error) {
 func (_mr *_MockManagerRecorder) FindCurrent() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "FindCurrent")
 }
+
+func (_m *MockManager) Update(_param0 string, _param1 int, _param2 manifest.Manifest, _param3 manifest0.Registry, _param4 instance.DrainOptions, _param5 ui.Stage) (instance.Instance, []disk.Disk, error) {
+	ret := _m.ctrl.Call(_m, "Update", _param0, _param1, _param2, _param3, _param4, _param5)
+	ret0, _ := ret[0].(instance.Instance)
+	ret1, _ := ret[1].([]disk.Disk)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockManagerRecorder) Update(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Update", arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// Mock of ManagerFactory interface
+type MockManagerFactory struct {
+	ctrl     *gomock.Controller
+	recorder *_MockManagerFactoryRecorder
+}
+
+// Recorder for MockManagerFactory (not exported)
+type _MockManagerFactoryRecorder struct {
+	mock *MockManagerFactory
+}
+
+func NewMockManagerFactory(ctrl *gomock.Controller) *MockManagerFactory {
+	mock := &MockManagerFactory{ctrl: ctrl}
+	mock.recorder = &_MockManagerFactoryRecorder{mock}
+	return mock
+}
+
+func (_m *MockManagerFactory) EXPECT() *_MockManagerFactoryRecorder {
+	return _m.recorder
+}
+
+func (_m *MockManagerFactory) NewManager(_param0 cloud.Cloud, _param1 vm.Manager, _param2 blobstore.Blobstore) instance.Manager {
+	ret := _m.ctrl.Call(_m, "NewManager", _param0, _param1, _param2)
+	ret0, _ := ret[0].(instance.Manager)
+	return ret0
+}
+
+func (_mr *_MockManagerFactoryRecorder) NewManager(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "NewManager", arg0, arg1, arg2)
+}
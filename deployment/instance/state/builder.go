@@ -76,12 +76,12 @@ func (b *builder) Build(jobName string, instanceID int, deploymentManifest bidep
 		releaseJobProperties[releaseJob.Name] = releaseJob.Properties
 	}
 
-	defaultAddress, err := b.defaultAddress(initialState.NetworkInterfaces(), agentState)
+	defaultAddress, err := DefaultAddress(initialState.NetworkInterfaces(), agentState)
 	if err != nil {
 		return nil, err
 	}
 
-	renderedJobTemplates, err := b.renderJobTemplates(releaseJobs, releaseJobProperties, deploymentJob.Properties, deploymentManifest.Properties, deploymentManifest.Name, defaultAddress, stage)
+	renderedJobTemplates, err := b.renderJobTemplates(releaseJobs, releaseJobProperties, deploymentJob.Properties, deploymentManifest.Properties, deploymentManifest.Name, defaultAddress, deploymentManifest.PersistentDiskNames(jobName), stage)
 	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Rendering job templates for instance '%s/%d'", jobName, instanceID)
 	}
@@ -140,20 +140,7 @@ func (b *builder) BuildInitialState(jobName string, instanceID int, deploymentMa
 		return nil, bosherr.WrapErrorf(err, "Finding networks for job '%s", jobName)
 	}
 
-	// convert map to array
-	networkRefs := make([]NetworkRef, 0, len(networkInterfaces))
-	for networkName, networkInterface := range networkInterfaces {
-		genericMap := make(map[string]interface{}, len(networkInterface))
-
-		for k, v := range networkInterface {
-			genericMap[k] = v
-		}
-
-		networkRefs = append(networkRefs, NetworkRef{
-			Name:      networkName,
-			Interface: genericMap,
-		})
-	}
+	networkRefs := NewNetworkRefs(networkInterfaces)
 
 	return &state{
 		deploymentName: deploymentManifest.Name,
@@ -184,6 +171,7 @@ func (b *builder) renderJobTemplates(
 	globalProperties biproperty.Map,
 	deploymentName string,
 	address string,
+	persistentDiskNames []string,
 	stage biui.Stage,
 ) (renderedJobs, error) {
 	var (
@@ -191,7 +179,7 @@ func (b *builder) renderJobTemplates(
 		blobID                 string
 	)
 	err := stage.Perform("Rendering job templates", func() error {
-		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, persistentDiskNames)
 		if err != nil {
 			return err
 		}
@@ -220,7 +208,27 @@ func (b *builder) renderJobTemplates(
 	}, nil
 }
 
-func (b *builder) defaultAddress(networkRefs []NetworkRef, agentState agentclient.AgentState) (string, error) {
+// NewNetworkRefs converts the network interfaces of a deployment job to network references
+func NewNetworkRefs(networkInterfaces map[string]biproperty.Map) []NetworkRef {
+	networkRefs := make([]NetworkRef, 0, len(networkInterfaces))
+	for networkName, networkInterface := range networkInterfaces {
+		genericMap := make(map[string]interface{}, len(networkInterface))
+
+		for k, v := range networkInterface {
+			genericMap[k] = v
+		}
+
+		networkRefs = append(networkRefs, NetworkRef{
+			Name:      networkName,
+			Interface: genericMap,
+		})
+	}
+	return networkRefs
+}
+
+// DefaultAddress returns the IP of the instance on its default gateway network.
+// The agent state is only consulted for dynamic networks.
+func DefaultAddress(networkRefs []NetworkRef, agentState agentclient.AgentState) (string, error) {
 
 	if (networkRefs == nil) || (len(networkRefs) == 0) {
 		return "", errors.New("Must specify network")
@@ -246,7 +254,7 @@ func (b *builder) defaultAddress(networkRefs []NetworkRef, agentState agentclien
 }
 
 func networkIp(networkRef NetworkRef, agentState agentclient.AgentState) string {
-	if "dynamic" == networkRef.Interface["type"].(string) {
+	if networkRef.IsDynamic() {
 		return agentState.NetworkSpecs[networkRef.Name].IP
 	}
 
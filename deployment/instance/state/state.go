@@ -19,6 +19,11 @@ type NetworkRef struct {
 	Interface map[string]interface{}
 }
 
+func (n NetworkRef) IsDynamic() bool {
+	networkType, _ := n.Interface["type"].(string)
+	return networkType == "dynamic"
+}
+
 // JobRef is a reference to a rendered job.
 // Individual JobRefs do not have Archives because they are aggregated in RenderedJobListArchive.
 type JobRef struct {
@@ -0,0 +1,98 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+// Fetcher retrieves the job logs of a deployed VM and extracts them locally
+type Fetcher interface {
+	// Fetch asks the agent to bundle the logs of the given job (or all jobs, if jobName is empty),
+	// downloads the bundle from the blobstore and extracts it into a new directory inside destinationDir.
+	// Returns the path to the directory containing the extracted logs.
+	Fetch(agentClient biagent.AgentClient, blobstore biblobstore.Blobstore, jobName string, destinationDir string) (string, error)
+}
+
+type fetcher struct {
+	fs             boshsys.FileSystem
+	compressor     boshcmd.Compressor
+	sha1Calculator bicrypto.SHA1Calculator
+	timeService    clock.Clock
+	logger         boshlog.Logger
+	logTag         string
+}
+
+func NewFetcher(
+	fs boshsys.FileSystem,
+	compressor boshcmd.Compressor,
+	sha1Calculator bicrypto.SHA1Calculator,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Fetcher {
+	return &fetcher{
+		fs:             fs,
+		compressor:     compressor,
+		sha1Calculator: sha1Calculator,
+		timeService:    timeService,
+		logger:         logger,
+		logTag:         "logsFetcher",
+	}
+}
+
+func (f *fetcher) Fetch(agentClient biagent.AgentClient, blobstore biblobstore.Blobstore, jobName string, destinationDir string) (string, error) {
+	filters := []string{"**/*"}
+	if jobName != "" {
+		filters = []string{fmt.Sprintf("%s/**/*", jobName)}
+	}
+
+	f.logger.Debug(f.logTag, "Fetching job logs with filters %#v", filters)
+	logsRef, err := agentClient.FetchLogs("job", filters)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Bundling logs on the deployed VM")
+	}
+
+	localBlob, err := blobstore.Get(logsRef.BlobstoreID)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Downloading logs bundle '%s'", logsRef.BlobstoreID)
+	}
+	defer localBlob.DeleteSilently()
+
+	if logsRef.SHA1 != "" {
+		sha1, err := f.sha1Calculator.Calculate(localBlob.Path())
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Calculating sha1 of logs bundle '%s'", logsRef.BlobstoreID)
+		}
+
+		if sha1 != logsRef.SHA1 {
+			return "", bosherr.Errorf("Logs bundle '%s' has sha1 '%s', expected '%s'", logsRef.BlobstoreID, sha1, logsRef.SHA1)
+		}
+	}
+
+	logsDirName := fmt.Sprintf("logs-%s", f.timeService.Now().Format("20060102-150405"))
+	if jobName != "" {
+		logsDirName = fmt.Sprintf("logs-%s-%s", jobName, f.timeService.Now().Format("20060102-150405"))
+	}
+	logsDir := filepath.Join(destinationDir, logsDirName)
+
+	err = f.fs.MkdirAll(logsDir, os.ModePerm)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Creating logs directory '%s'", logsDir)
+	}
+
+	err = f.compressor.DecompressFileToDir(localBlob.Path(), logsDir, boshcmd.CompressorOptions{})
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Extracting logs bundle to '%s'", logsDir)
+	}
+
+	return logsDir, nil
+}
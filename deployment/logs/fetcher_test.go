@@ -0,0 +1,139 @@
+package logs_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/deployment/logs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
+	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
+	fakeboshcmd "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Fetcher", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		fs                 *fakesys.FakeFileSystem
+		fakeCompressor     *fakeboshcmd.FakeCompressor
+		fakeSHA1Calculator *fakebicrypto.FakeSha1Calculator
+		mockAgentClient    *mock_agentclient.MockAgentClient
+		mockBlobstore      *mock_blobstore.MockBlobstore
+		logger             boshlog.Logger
+		fetcher            Fetcher
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fakeCompressor = fakeboshcmd.NewFakeCompressor()
+		fakeSHA1Calculator = fakebicrypto.NewFakeSha1Calculator()
+		mockAgentClient = mock_agentclient.NewMockAgentClient(mockCtrl)
+		mockBlobstore = mock_blobstore.NewMockBlobstore(mockCtrl)
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		timeService := fakeclock.NewFakeClock(time.Date(2016, time.March, 4, 13, 14, 15, 0, time.UTC))
+
+		fetcher = NewFetcher(fs, fakeCompressor, fakeSHA1Calculator, timeService, logger)
+
+		fs.WriteFileString("/fake-logs.tgz", "fake-logs-bundle")
+		fakeSHA1Calculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateInput{
+			"/fake-logs.tgz": {Sha1: "fake-logs-sha1"},
+		})
+	})
+
+	Describe("Fetch", func() {
+		var expectGet = func() {
+			localBlob := biblobstore.NewLocalBlob("/fake-logs.tgz", fs, logger)
+			mockBlobstore.EXPECT().Get("fake-logs-blob-id").Return(localBlob, nil)
+		}
+
+		It("fetches the logs of all jobs and extracts them into a timestamped directory", func() {
+			mockAgentClient.EXPECT().FetchLogs("job", []string{"**/*"}).Return(biagentclient.BlobRef{
+				BlobstoreID: "fake-logs-blob-id",
+				SHA1:        "fake-logs-sha1",
+			}, nil)
+			expectGet()
+
+			logsDir, err := fetcher.Fetch(mockAgentClient, mockBlobstore, "", "/fake-dest")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logsDir).To(Equal("/fake-dest/logs-20160304-131415"))
+
+			Expect(fs.FileExists("/fake-dest/logs-20160304-131415")).To(BeTrue())
+			Expect(fakeCompressor.DecompressFileToDirTarballPaths).To(Equal([]string{"/fake-logs.tgz"}))
+			Expect(fakeCompressor.DecompressFileToDirDirs).To(Equal([]string{"/fake-dest/logs-20160304-131415"}))
+		})
+
+		It("only fetches the logs of the given job", func() {
+			mockAgentClient.EXPECT().FetchLogs("job", []string{"fake-job/**/*"}).Return(biagentclient.BlobRef{
+				BlobstoreID: "fake-logs-blob-id",
+			}, nil)
+			expectGet()
+
+			logsDir, err := fetcher.Fetch(mockAgentClient, mockBlobstore, "fake-job", "/fake-dest")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logsDir).To(Equal("/fake-dest/logs-fake-job-20160304-131415"))
+		})
+
+		It("deletes the downloaded logs bundle", func() {
+			mockAgentClient.EXPECT().FetchLogs(gomock.Any(), gomock.Any()).Return(biagentclient.BlobRef{
+				BlobstoreID: "fake-logs-blob-id",
+			}, nil)
+			expectGet()
+
+			_, err := fetcher.Fetch(mockAgentClient, mockBlobstore, "", "/fake-dest")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/fake-logs.tgz")).To(BeFalse())
+		})
+
+		It("returns an error when the agent fails to bundle the logs", func() {
+			mockAgentClient.EXPECT().FetchLogs(gomock.Any(), gomock.Any()).Return(biagentclient.BlobRef{}, errors.New("fake-fetch-logs-error"))
+
+			_, err := fetcher.Fetch(mockAgentClient, mockBlobstore, "", "/fake-dest")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-fetch-logs-error"))
+		})
+
+		It("returns an error when the logs bundle does not match the sha1 reported by the agent", func() {
+			mockAgentClient.EXPECT().FetchLogs(gomock.Any(), gomock.Any()).Return(biagentclient.BlobRef{
+				BlobstoreID: "fake-logs-blob-id",
+				SHA1:        "fake-other-sha1",
+			}, nil)
+			expectGet()
+
+			_, err := fetcher.Fetch(mockAgentClient, mockBlobstore, "", "/fake-dest")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Logs bundle 'fake-logs-blob-id' has sha1 'fake-logs-sha1', expected 'fake-other-sha1'"))
+			Expect(fakeCompressor.DecompressFileToDirTarballPaths).To(BeEmpty())
+		})
+
+		It("returns an error when extracting the logs bundle fails", func() {
+			mockAgentClient.EXPECT().FetchLogs(gomock.Any(), gomock.Any()).Return(biagentclient.BlobRef{
+				BlobstoreID: "fake-logs-blob-id",
+			}, nil)
+			expectGet()
+			fakeCompressor.DecompressFileToDirErr = errors.New("fake-decompress-error")
+
+			_, err := fetcher.Fetch(mockAgentClient, mockBlobstore, "", "/fake-dest")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-decompress-error"))
+		})
+	})
+})
@@ -62,6 +62,10 @@ func (m *manager) Cleanup(stage biui.Stage) error {
 		return err
 	}
 
+	if err := m.diskManager.DeleteOrphaned(stage); err != nil {
+		return err
+	}
+
 	if err := m.stemcellManager.DeleteUnused(stage); err != nil {
 		return err
 	}
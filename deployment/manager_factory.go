@@ -1,7 +1,7 @@
 package deployment
 
 import (
-	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
@@ -11,7 +11,7 @@ import (
 )
 
 type ManagerFactory interface {
-	NewManager(bicloud.Cloud, biagentclient.AgentClient, biblobstore.Blobstore) Manager
+	NewManager(bicloud.Cloud, biagent.AgentClient, biblobstore.Blobstore) Manager
 }
 
 type managerFactory struct {
@@ -38,7 +38,7 @@ func NewManagerFactory(
 	}
 }
 
-func (f *managerFactory) NewManager(cloud bicloud.Cloud, agentClient biagentclient.AgentClient, blobstore biblobstore.Blobstore) Manager {
+func (f *managerFactory) NewManager(cloud bicloud.Cloud, agentClient biagent.AgentClient, blobstore biblobstore.Blobstore) Manager {
 	vmManager := f.vmManagerFactory.NewManager(cloud, agentClient)
 	instanceManager := f.instanceManagerFactory.NewManager(cloud, vmManager, blobstore)
 	diskManager := f.diskManagerFactory.NewManager(cloud)
@@ -14,11 +14,13 @@ import (
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
 
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bisnapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
@@ -185,10 +187,12 @@ var _ = Describe("Manager", func() {
 
 		JustBeforeEach(func() {
 			diskManagerFactory := bidisk.NewManagerFactory(diskRepo, logger)
-			diskDeployer := bivm.NewDiskDeployer(diskManagerFactory, diskRepo, logger)
+			snapshotRepo := biconfig.NewSnapshotRepo(deploymentStateService, fakeRepoUUIDGenerator)
+			snapshotManagerFactory := bisnapshot.NewManagerFactory(diskRepo, snapshotRepo, clock.NewClock(), logger)
+			diskDeployer := bivm.NewDiskDeployer(diskManagerFactory, snapshotManagerFactory, diskRepo, biconfig.NewDiskMigrationRepo(deploymentStateService), logger)
 
-			vmManagerFactory := bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeUUIDGenerator, fs, logger)
-			sshTunnelFactory := bisshtunnel.NewFactory(logger)
+			vmManagerFactory := bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeUUIDGenerator, fs, clock.NewClock(), logger)
+			sshTunnelFactory := bisshtunnel.NewFactory(fs, logger)
 
 			mockStateBuilderFactory = mock_instance_state.NewMockBuilderFactory(mockCtrl)
 
@@ -210,7 +214,7 @@ var _ = Describe("Manager", func() {
 
 			BeforeEach(func() {
 				var err error
-				currentDiskRecord, err = diskRepo.Save("fake-disk-cid", 100, nil)
+				currentDiskRecord, err = diskRepo.Save("", "fake-disk-cid", 100, nil)
 				Expect(err).ToNot(HaveOccurred())
 				err = diskRepo.UpdateCurrent(currentDiskRecord.ID)
 				Expect(err).ToNot(HaveOccurred())
@@ -225,10 +229,9 @@ var _ = Describe("Manager", func() {
 				err := deploymentManager.Cleanup(fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
-				diskRecord, found, err := diskRepo.FindCurrent()
+				diskRecords, err := diskRepo.FindCurrent()
 				Expect(err).ToNot(HaveOccurred())
-				Expect(found).To(BeTrue())
-				Expect(diskRecord).To(Equal(currentDiskRecord))
+				Expect(diskRecords).To(Equal([]biconfig.DiskRecord{currentDiskRecord}))
 
 				stemcellRecord, found, err := stemcellRepo.FindCurrent()
 				Expect(err).ToNot(HaveOccurred())
@@ -246,7 +249,7 @@ var _ = Describe("Manager", func() {
 
 		Context("orphan disk records exist", func() {
 			BeforeEach(func() {
-				_, err := diskRepo.Save("orphan-disk-cid", 100, nil)
+				_, err := diskRepo.Save("", "orphan-disk-cid", 100, nil)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -272,6 +275,27 @@ var _ = Describe("Manager", func() {
 				}))
 			})
 
+			It("deletes the disks kept until clean-up", func() {
+				keptDiskRecord, err := diskRepo.Save("", "kept-disk-cid", 100, nil)
+				Expect(err).ToNot(HaveOccurred())
+				err = diskRepo.Orphan(keptDiskRecord.ID)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockCloud.EXPECT().DeleteDisk("orphan-disk-cid")
+				mockCloud.EXPECT().DeleteDisk("kept-disk-cid")
+
+				err = deploymentManager.Cleanup(fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeStage.PerformCalls).To(ContainElement(&fakebiui.PerformCall{
+					Name: "Deleting orphaned disk 'kept-disk-cid'",
+				}))
+
+				diskRecords, err := diskRepo.All()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskRecords).To(BeEmpty(), "expected no disk records")
+			})
+
 			Context("when disks have been deleted manually (in the infrastructure)", func() {
 				It("deletes the unused disks, ignoring DiskNotFoundError", func() {
 					mockCloud.EXPECT().DeleteDisk("orphan-disk-cid").Return(bicloud.NewCPIError("delete_disk", bicloud.CmdError{
@@ -8,4 +8,18 @@ type DiskPool struct {
 	Name            string
 	DiskSize        int
 	CloudProperties biproperty.Map
+
+	// SnapshotBeforeMigration takes a snapshot of the existing disk before its content is migrated to a new disk
+	SnapshotBeforeMigration bool
+
+	// CloudProvider is the name of the cloud provider of the installation manifest that creates the disk, empty for the default one.
+	// It must be the cloud provider of the VM the disk is attached to.
+	CloudProvider string
+}
+
+// PersistentDisk is a persistent disk of a job with the disk pool it is created from.
+// The disk configured with persistent_disk or persistent_disk_pool has no name.
+type PersistentDisk struct {
+	Name     string
+	DiskPool DiskPool
 }
@@ -12,10 +12,18 @@ type Job struct {
 	Networks           []JobNetwork
 	PersistentDisk     int
 	PersistentDiskPool string
+	PersistentDisks    []JobPersistentDisk
 	ResourcePool       string
 	Properties         biproperty.Map
 }
 
+// JobPersistentDisk is a named persistent disk of a job, sized either by DiskSize or by a disk pool
+type JobPersistentDisk struct {
+	Name     string
+	DiskSize int
+	DiskPool string
+}
+
 type JobLifecycle string
 
 const (
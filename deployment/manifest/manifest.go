@@ -17,6 +17,9 @@ type Manifest struct {
 
 type Update struct {
 	UpdateWatchTime WatchTime
+
+	// MaxDrainWait is the longest time, in milliseconds, to wait for the drain scripts of the jobs to finish
+	MaxDrainWait int
 }
 
 // NetworkInterfaces returns a map of network names to network interfaces.
@@ -59,6 +62,15 @@ func (d Manifest) Stemcell(jobName string) (StemcellRef, error) {
 	return resourcePool.Stemcell, nil
 }
 
+// CloudProvider returns the name of the cloud provider of the resource pool of the job, empty for the default one
+func (d Manifest) CloudProvider(jobName string) (string, error) {
+	resourcePool, err := d.ResourcePool(jobName)
+	if err != nil {
+		return "", err
+	}
+	return resourcePool.CloudProvider, nil
+}
+
 func (d Manifest) ResourcePool(jobName string) (ResourcePool, error) {
 	job, found := d.FindJobByName(jobName)
 	if !found {
@@ -74,31 +86,69 @@ func (d Manifest) ResourcePool(jobName string) (ResourcePool, error) {
 	return ResourcePool{}, err
 }
 
-func (d Manifest) DiskPool(jobName string) (DiskPool, error) {
+// PersistentDisks returns the persistent disks of the job, in the order they are configured.
+// A job with persistent_disk or persistent_disk_pool has a single disk without a name.
+func (d Manifest) PersistentDisks(jobName string) ([]PersistentDisk, error) {
 	job, found := d.FindJobByName(jobName)
 	if !found {
-		return DiskPool{}, bosherr.Errorf("Could not find job with name: %s", jobName)
+		return []PersistentDisk{}, bosherr.Errorf("Could not find job with name: %s", jobName)
+	}
+
+	if len(job.PersistentDisks) > 0 {
+		disks := []PersistentDisk{}
+		for _, jobDisk := range job.PersistentDisks {
+			diskPool, err := d.diskPool(jobName, jobDisk.DiskPool, jobDisk.DiskSize)
+			if err != nil {
+				return []PersistentDisk{}, err
+			}
+			disks = append(disks, PersistentDisk{Name: jobDisk.Name, DiskPool: diskPool})
+		}
+		return disks, nil
 	}
 
-	if job.PersistentDiskPool != "" {
+	if job.PersistentDiskPool == "" && job.PersistentDisk <= 0 {
+		return []PersistentDisk{}, nil
+	}
+
+	diskPool, err := d.diskPool(jobName, job.PersistentDiskPool, job.PersistentDisk)
+	if err != nil {
+		return []PersistentDisk{}, err
+	}
+
+	return []PersistentDisk{{DiskPool: diskPool}}, nil
+}
+
+// PersistentDiskNames returns the names of the named persistent disks of the job
+func (d Manifest) PersistentDiskNames(jobName string) []string {
+	names := []string{}
+
+	job, found := d.FindJobByName(jobName)
+	if !found {
+		return names
+	}
+
+	for _, jobDisk := range job.PersistentDisks {
+		names = append(names, jobDisk.Name)
+	}
+	return names
+}
+
+func (d Manifest) diskPool(jobName string, diskPoolName string, diskSize int) (DiskPool, error) {
+	if diskPoolName != "" {
 		for _, diskPool := range d.DiskPools {
-			if diskPool.Name == job.PersistentDiskPool {
+			if diskPool.Name == diskPoolName {
 				return diskPool, nil
 			}
 		}
-		err := bosherr.Errorf("Could not find persistent disk pool '%s' for job '%s'", job.PersistentDiskPool, jobName)
+		err := bosherr.Errorf("Could not find persistent disk pool '%s' for job '%s'", diskPoolName, jobName)
 		return DiskPool{}, err
 	}
 
-	if job.PersistentDisk > 0 {
-		diskPool := DiskPool{
-			DiskSize:        job.PersistentDisk,
-			CloudProperties: biproperty.Map{},
-		}
-		return diskPool, nil
+	diskPool := DiskPool{
+		DiskSize:        diskSize,
+		CloudProperties: biproperty.Map{},
 	}
-
-	return DiskPool{}, nil
+	return diskPool, nil
 }
 
 func (d Manifest) networkMap() map[string]Network {
@@ -228,7 +228,7 @@ var _ = Describe("Manifest", func() {
 		})
 	})
 
-	Describe("DiskPool", func() {
+	Describe("PersistentDisks", func() {
 		Context("when the deployment has disk_pools", func() {
 			BeforeEach(func() {
 				deploymentManifest = Manifest{
@@ -257,15 +257,19 @@ var _ = Describe("Manifest", func() {
 				}
 			})
 
-			It("is the disk pool", func() {
-				diskPool, err := deploymentManifest.DiskPool("fake-job-name")
+			It("is an unnamed disk with the disk pool", func() {
+				disks, err := deploymentManifest.PersistentDisks("fake-job-name")
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(diskPool).To(Equal(DiskPool{
-					Name:     "fake-disk-pool-name-2",
-					DiskSize: 2048,
-					CloudProperties: biproperty.Map{
-						"fake-disk-prop-key-2": "fake-disk-prop-value-1",
+				Expect(disks).To(Equal([]PersistentDisk{
+					{
+						DiskPool: DiskPool{
+							Name:     "fake-disk-pool-name-2",
+							DiskSize: 2048,
+							CloudProperties: biproperty.Map{
+								"fake-disk-prop-key-2": "fake-disk-prop-value-1",
+							},
+						},
 					},
 				}))
 			})
@@ -283,14 +287,18 @@ var _ = Describe("Manifest", func() {
 				}
 			})
 
-			It("is a new disk pool with the specified persistent disk size", func() {
-				diskPool, err := deploymentManifest.DiskPool("fake-job-name")
+			It("is an unnamed disk with a new disk pool with the specified persistent disk size", func() {
+				disks, err := deploymentManifest.PersistentDisks("fake-job-name")
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(diskPool).To(Equal(DiskPool{
-					Name:            "",
-					DiskSize:        1024,
-					CloudProperties: biproperty.Map{},
+				Expect(disks).To(Equal([]PersistentDisk{
+					{
+						DiskPool: DiskPool{
+							Name:            "",
+							DiskSize:        1024,
+							CloudProperties: biproperty.Map{},
+						},
+					},
 				}))
 			})
 		})
@@ -318,14 +326,18 @@ var _ = Describe("Manifest", func() {
 			})
 
 			It("returns the deployment disk pool", func() {
-				diskPool, err := deploymentManifest.DiskPool("fake-job-name")
+				disks, err := deploymentManifest.PersistentDisks("fake-job-name")
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(diskPool).To(Equal(DiskPool{
-					Name:     "fake-disk-pool-name-1",
-					DiskSize: 1024,
-					CloudProperties: biproperty.Map{
-						"fake-disk-prop-key-1": "fake-disk-prop-value-1",
+				Expect(disks).To(Equal([]PersistentDisk{
+					{
+						DiskPool: DiskPool{
+							Name:     "fake-disk-pool-name-1",
+							DiskSize: 1024,
+							CloudProperties: biproperty.Map{
+								"fake-disk-prop-key-1": "fake-disk-prop-value-1",
+							},
+						},
 					},
 				}))
 			})
@@ -344,13 +356,75 @@ var _ = Describe("Manifest", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := deploymentManifest.DiskPool("fake-job-name")
+				_, err := deploymentManifest.PersistentDisks("fake-job-name")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("Could not find persistent disk pool 'fake-disk-pool-name-1' for job 'fake-job-name'"))
 			})
 		})
 
-		Context("when job does not have persistent_disk_pool or persistent_disk", func() {
+		Context("when job has persistent_disks", func() {
+			BeforeEach(func() {
+				deploymentManifest = Manifest{
+					DiskPools: []DiskPool{
+						{
+							Name:     "fake-disk-pool-name-1",
+							DiskSize: 4096,
+							CloudProperties: biproperty.Map{
+								"fake-disk-prop-key-1": "fake-disk-prop-value-1",
+							},
+						},
+					},
+					Jobs: []Job{
+						{
+							Name: "fake-job-name",
+							PersistentDisks: []JobPersistentDisk{
+								{Name: "fake-blobstore-disk", DiskPool: "fake-disk-pool-name-1"},
+								{Name: "fake-database-disk", DiskSize: 1024},
+							},
+						},
+					},
+				}
+			})
+
+			It("returns a named disk for each of them, in order", func() {
+				disks, err := deploymentManifest.PersistentDisks("fake-job-name")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(disks).To(Equal([]PersistentDisk{
+					{
+						Name: "fake-blobstore-disk",
+						DiskPool: DiskPool{
+							Name:     "fake-disk-pool-name-1",
+							DiskSize: 4096,
+							CloudProperties: biproperty.Map{
+								"fake-disk-prop-key-1": "fake-disk-prop-value-1",
+							},
+						},
+					},
+					{
+						Name: "fake-database-disk",
+						DiskPool: DiskPool{
+							DiskSize:        1024,
+							CloudProperties: biproperty.Map{},
+						},
+					},
+				}))
+			})
+
+			It("returns their names", func() {
+				Expect(deploymentManifest.PersistentDiskNames("fake-job-name")).To(Equal([]string{"fake-blobstore-disk", "fake-database-disk"}))
+			})
+
+			It("returns an error when a disk pool does not exist", func() {
+				deploymentManifest.Jobs[0].PersistentDisks[0].DiskPool = "fake-missing-disk-pool"
+
+				_, err := deploymentManifest.PersistentDisks("fake-job-name")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Could not find persistent disk pool 'fake-missing-disk-pool' for job 'fake-job-name'"))
+			})
+		})
+
+		Context("when job does not have persistent_disk_pool, persistent_disk or persistent_disks", func() {
 			BeforeEach(func() {
 				deploymentManifest = Manifest{
 					Jobs: []Job{
@@ -361,10 +435,11 @@ var _ = Describe("Manifest", func() {
 				}
 			})
 
-			It("returns an empty disk pool", func() {
-				diskPool, err := deploymentManifest.DiskPool("fake-job-name")
+			It("returns no disks", func() {
+				disks, err := deploymentManifest.PersistentDisks("fake-job-name")
 				Expect(err).ToNot(HaveOccurred())
-				Expect(diskPool).To(Equal(DiskPool{}))
+				Expect(disks).To(BeEmpty())
+				Expect(deploymentManifest.PersistentDiskNames("fake-job-name")).To(BeEmpty())
 			})
 		})
 	})
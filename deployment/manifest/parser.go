@@ -32,6 +32,7 @@ type manifest struct {
 
 type UpdateSpec struct {
 	UpdateWatchTime *string `yaml:"update_watch_time"`
+	MaxDrainWait    *int    `yaml:"max_drain_wait"`
 }
 
 type network struct {
@@ -58,12 +59,15 @@ type resourcePool struct {
 	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
 	Env             map[interface{}]interface{} `yaml:"env"`
 	Stemcell        stemcellRef                 `yaml:"stemcell"`
+	CloudProvider   string                      `yaml:"cloud_provider"`
 }
 
 type diskPool struct {
-	Name            string                      `yaml:"name"`
-	DiskSize        int                         `yaml:"disk_size"`
-	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
+	Name                    string                      `yaml:"name"`
+	DiskSize                int                         `yaml:"disk_size"`
+	CloudProperties         map[interface{}]interface{} `yaml:"cloud_properties"`
+	SnapshotBeforeMigration bool                        `yaml:"snapshot_before_migration"`
+	CloudProvider           string                      `yaml:"cloud_provider"`
 }
 
 type job struct {
@@ -73,12 +77,19 @@ type job struct {
 	Templates          []releaseJobRef
 	Jobs               []releaseJobRef `yaml:"jobs"`
 	Networks           []jobNetwork
-	PersistentDisk     int    `yaml:"persistent_disk"`
-	PersistentDiskPool string `yaml:"persistent_disk_pool"`
-	ResourcePool       string `yaml:"resource_pool"`
+	PersistentDisk     int                 `yaml:"persistent_disk"`
+	PersistentDiskPool string              `yaml:"persistent_disk_pool"`
+	PersistentDisks    []jobPersistentDisk `yaml:"persistent_disks"`
+	ResourcePool       string              `yaml:"resource_pool"`
 	Properties         map[interface{}]interface{}
 }
 
+type jobPersistentDisk struct {
+	Name     string `yaml:"name"`
+	DiskSize int    `yaml:"disk_size"`
+	DiskPool string `yaml:"disk_pool"`
+}
+
 type releaseJobRef struct {
 	Name    string
 	Release string
@@ -99,12 +110,16 @@ type jobNetwork struct {
 	StaticIPs []string `yaml:"static_ips"`
 }
 
+// DefaultMaxDrainWait is used when the update block of the manifest does not set max_drain_wait
+const DefaultMaxDrainWait = 600000
+
 var boshDeploymentDefaults = Manifest{
 	Update: Update{
 		UpdateWatchTime: WatchTime{
 			Start: 0,
 			End:   300000,
 		},
+		MaxDrainWait: DefaultMaxDrainWait,
 	},
 }
 
@@ -186,9 +201,11 @@ func (p *parser) parseDeploymentManifest(depManifest manifest, path string) (Man
 			return Manifest{}, bosherr.WrapError(err, "Parsing update watch time")
 		}
 
-		deployment.Update = Update{
-			UpdateWatchTime: updateWatchTime,
-		}
+		deployment.Update.UpdateWatchTime = updateWatchTime
+	}
+
+	if depManifest.Update.MaxDrainWait != nil {
+		deployment.Update.MaxDrainWait = *depManifest.Update.MaxDrainWait
 	}
 
 	return deployment, nil
@@ -206,6 +223,14 @@ func (p *parser) parseJobManifests(rawJobs []job) ([]Job, error) {
 			ResourcePool:       rawJob.ResourcePool,
 		}
 
+		for _, rawDisk := range rawJob.PersistentDisks {
+			job.PersistentDisks = append(job.PersistentDisks, JobPersistentDisk{
+				Name:     rawDisk.Name,
+				DiskSize: rawDisk.DiskSize,
+				DiskPool: rawDisk.DiskPool,
+			})
+		}
+
 		if len(rawJob.Templates) > 0 && len(rawJob.Jobs) > 0 {
 			return jobs, bosherr.Error("Deployment specifies both templates and jobs keys for instance_group " + job.Name + ", only one is allowed")
 		}
@@ -311,9 +336,10 @@ func (p *parser) parseResourcePoolManifests(rawResourcePools []resourcePool, pat
 	resourcePools := make([]ResourcePool, len(rawResourcePools), len(rawResourcePools))
 	for i, rawResourcePool := range rawResourcePools {
 		resourcePool := ResourcePool{
-			Name:     rawResourcePool.Name,
-			Network:  rawResourcePool.Network,
-			Stemcell: StemcellRef(rawResourcePool.Stemcell),
+			Name:          rawResourcePool.Name,
+			Network:       rawResourcePool.Network,
+			Stemcell:      StemcellRef(rawResourcePool.Stemcell),
+			CloudProvider: rawResourcePool.CloudProvider,
 		}
 
 		cloudProperties, err := biproperty.BuildMap(rawResourcePool.CloudProperties)
@@ -343,8 +369,10 @@ func (p *parser) parseDiskPoolManifests(rawDiskPools []diskPool) ([]DiskPool, er
 	diskPools := make([]DiskPool, len(rawDiskPools), len(rawDiskPools))
 	for i, rawDiskPool := range rawDiskPools {
 		diskPool := DiskPool{
-			Name:     rawDiskPool.Name,
-			DiskSize: rawDiskPool.DiskSize,
+			Name:                    rawDiskPool.Name,
+			DiskSize:                rawDiskPool.DiskSize,
+			SnapshotBeforeMigration: rawDiskPool.SnapshotBeforeMigration,
+			CloudProvider:           rawDiskPool.CloudProvider,
 		}
 
 		cloudProperties, err := biproperty.BuildMap(rawDiskPool.CloudProperties)
@@ -55,6 +55,7 @@ var _ = Describe("Parser", func() {
 name: fake-deployment-name
 update:
   update_watch_time: 2000-7000
+  max_drain_wait: 120000
 resource_pools:
 - name: fake-resource-pool-name
   cloud_properties:
@@ -64,6 +65,7 @@ resource_pools:
       password: secret
   stemcell:
     url: http://fake-stemcell-url
+  cloud_provider: fake-cloud-provider-name
 networks:
 - name: fake-network-name
   type: dynamic
@@ -85,6 +87,8 @@ disk_pools:
   disk_size: 2048
   cloud_properties:
     fake-disk-pool-cloud-property-key: fake-disk-pool-cloud-property-value
+  snapshot_before_migration: true
+  cloud_provider: fake-cloud-provider-name
 jobs:
 - name: bosh
   networks:
@@ -116,6 +120,7 @@ properties:
 					Start: 2000,
 					End:   7000,
 				},
+				MaxDrainWait: 120000,
 			},
 			Networks: []Network{
 				{
@@ -159,6 +164,7 @@ properties:
 					Stemcell: StemcellRef{
 						URL: "http://fake-stemcell-url",
 					},
+					CloudProvider: "fake-cloud-provider-name",
 				},
 			},
 			DiskPools: []DiskPool{
@@ -168,6 +174,8 @@ properties:
 					CloudProperties: biproperty.Map{
 						"fake-disk-pool-cloud-property-key": "fake-disk-pool-cloud-property-value",
 					},
+					SnapshotBeforeMigration: true,
+					CloudProvider:           "fake-cloud-provider-name",
 				},
 			},
 			Jobs: []Job{
@@ -238,6 +246,7 @@ resource_pools:
 				},
 				Update: Update{
 					UpdateWatchTime: WatchTime{Start: 0, End: 300000},
+					MaxDrainWait:    600000,
 				},
 			}))
 		})
@@ -281,6 +290,7 @@ resource_pools:
 					},
 					Update: Update{
 						UpdateWatchTime: WatchTime{Start: 0, End: 300000},
+						MaxDrainWait:    600000,
 					},
 				}))
 			})
@@ -323,6 +333,7 @@ resource_pools:
 					},
 					Update: Update{
 						UpdateWatchTime: WatchTime{Start: 0, End: 300000},
+						MaxDrainWait:    600000,
 					},
 				}))
 			})
@@ -366,6 +377,7 @@ resource_pools:
 					},
 					Update: Update{
 						UpdateWatchTime: WatchTime{Start: 0, End: 300000},
+						MaxDrainWait:    600000,
 					},
 				}))
 			})
@@ -520,6 +532,31 @@ instance_groups:
 		})
 	})
 
+	Context("when a job has persistent_disks", func() {
+		BeforeEach(func() {
+			contents := `
+---
+jobs:
+- name: jobby
+  persistent_disks:
+  - name: blobstore
+    disk_pool: fake-disk-pool-name
+  - name: database
+    disk_size: 1024
+`
+			fakeFs.WriteFileString(comboManifestPath, contents)
+		})
+
+		It("parses the named disks", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentManifest.Jobs[0].PersistentDisks).To(Equal([]JobPersistentDisk{
+				{Name: "blobstore", DiskPool: "fake-disk-pool-name"},
+				{Name: "database", DiskSize: 1024},
+			}))
+		})
+	})
+
 	Context("when jobs is defined inside an instance_group, treats it as templates", func() {
 		BeforeEach(func() {
 			contents := `
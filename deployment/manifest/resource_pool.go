@@ -10,6 +10,9 @@ type ResourcePool struct {
 	CloudProperties biproperty.Map
 	Env             biproperty.Map
 	Stemcell        StemcellRef
+
+	// CloudProvider is the name of the cloud provider of the installation manifest that creates the VM, empty for the default one
+	CloudProvider string
 }
 
 type StemcellRef struct {
@@ -69,6 +69,10 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 		}
 	}
 
+	if deploymentManifest.Update.MaxDrainWait < 0 {
+		errs = append(errs, bosherr.Error("update.max_drain_wait must be >= 0"))
+	}
+
 	if len(deploymentManifest.Jobs) > 1 {
 		errs = append(errs, bosherr.Error("jobs must be of size 1"))
 	}
@@ -85,6 +89,11 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 				errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk_pool must be the name of a disk pool", idx))
 			}
 		}
+		if len(job.PersistentDisks) > 0 && (job.PersistentDisk != 0 || job.PersistentDiskPool != "") {
+			errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks cannot be combined with persistent_disk or persistent_disk_pool", idx))
+		}
+		errs = append(errs, v.validateJobPersistentDisks(job.PersistentDisks, deploymentManifest, idx)...)
+		errs = append(errs, v.validateJobCloudProvider(job, deploymentManifest, idx)...)
 		if job.Instances < 0 {
 			errs = append(errs, bosherr.Errorf("jobs[%d].instances must be >= 0", idx))
 		}
@@ -169,6 +178,85 @@ func (v *validator) networkNames(deploymentManifest Manifest) map[string]struct{
 	return names
 }
 
+func (v *validator) validateJobPersistentDisks(jobDisks []JobPersistentDisk, deploymentManifest Manifest, jobIdx int) []error {
+	errs := []error{}
+	names := map[string]struct{}{}
+
+	// the agent mounts a single persistent disk
+	if len(jobDisks) > 1 {
+		errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks must have only one disk, found %d", jobIdx, len(jobDisks)))
+	}
+
+	for idx, jobDisk := range jobDisks {
+		if v.isBlank(jobDisk.Name) {
+			errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks[%d].name must be provided", jobIdx, idx))
+		} else if _, ok := names[jobDisk.Name]; ok {
+			errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks[%d].name '%s' must be unique", jobIdx, idx, jobDisk.Name))
+		}
+		names[jobDisk.Name] = struct{}{}
+
+		if jobDisk.DiskPool != "" {
+			if jobDisk.DiskSize != 0 {
+				errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks[%d] must specify either disk_size or disk_pool", jobIdx, idx))
+			}
+			if _, ok := v.diskPoolNames(deploymentManifest)[jobDisk.DiskPool]; !ok {
+				errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks[%d].disk_pool must be the name of a disk pool", jobIdx, idx))
+			}
+		} else if jobDisk.DiskSize <= 0 {
+			errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks[%d].disk_size must be > 0", jobIdx, idx))
+		}
+	}
+
+	return errs
+}
+
+// validateJobCloudProvider checks that the disk pools of the job have the cloud provider of its resource pool,
+// a disk can only be attached to a VM of the same cloud
+func (v *validator) validateJobCloudProvider(job Job, deploymentManifest Manifest, jobIdx int) []error {
+	errs := []error{}
+
+	resourcePool, found := v.findResourcePool(deploymentManifest, job.ResourcePool)
+	if !found {
+		return errs
+	}
+
+	if diskPool, found := v.findDiskPool(deploymentManifest, job.PersistentDiskPool); found && diskPool.CloudProvider != resourcePool.CloudProvider {
+		errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk_pool '%s' must have the cloud_provider '%s' of resource pool '%s'",
+			jobIdx, diskPool.Name, resourcePool.CloudProvider, resourcePool.Name))
+	}
+
+	for idx, jobDisk := range job.PersistentDisks {
+		if diskPool, found := v.findDiskPool(deploymentManifest, jobDisk.DiskPool); found && diskPool.CloudProvider != resourcePool.CloudProvider {
+			errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disks[%d].disk_pool '%s' must have the cloud_provider '%s' of resource pool '%s'",
+				jobIdx, idx, diskPool.Name, resourcePool.CloudProvider, resourcePool.Name))
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) findResourcePool(deploymentManifest Manifest, name string) (ResourcePool, bool) {
+	for _, resourcePool := range deploymentManifest.ResourcePools {
+		if resourcePool.Name == name {
+			return resourcePool, true
+		}
+	}
+	return ResourcePool{}, false
+}
+
+func (v *validator) findDiskPool(deploymentManifest Manifest, name string) (DiskPool, bool) {
+	if name == "" {
+		return DiskPool{}, false
+	}
+
+	for _, diskPool := range deploymentManifest.DiskPools {
+		if diskPool.Name == name {
+			return diskPool, true
+		}
+	}
+	return DiskPool{}, false
+}
+
 func (v *validator) diskPoolNames(deploymentManifest Manifest) map[string]struct{} {
 	names := make(map[string]struct{})
 	for _, diskPool := range deploymentManifest.DiskPools {
@@ -285,6 +285,18 @@ var _ = Describe("Validator", func() {
 			Expect(err.Error()).To(ContainSubstring("disk_pools[0].disk_size must be > 0"))
 		})
 
+		It("validates update max drain wait", func() {
+			deploymentManifest := Manifest{
+				Update: Update{
+					MaxDrainWait: -1,
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("update.max_drain_wait must be >= 0"))
+		})
+
 		Describe("networks", func() {
 			It("validates name is present", func() {
 				deploymentManifest := Manifest{
@@ -642,6 +654,108 @@ var _ = Describe("Validator", func() {
 			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disk_pool must be the name of a disk pool"))
 		})
 
+		It("validates job persistent_disks", func() {
+			deploymentManifest := Manifest{
+				Jobs: []Job{
+					{
+						PersistentDisks: []JobPersistentDisk{
+							{Name: "", DiskSize: 1024},
+							{Name: "fake-disk", DiskSize: 0},
+							{Name: "fake-disk", DiskPool: "non-existent-disk-pool"},
+							{Name: "fake-other-disk", DiskSize: 1024, DiskPool: "fake-disk-pool"},
+						},
+					},
+				},
+				DiskPools: []DiskPool{
+					{
+						Name: "fake-disk-pool",
+					},
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[0].name must be provided"))
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[1].disk_size must be > 0"))
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[2].name 'fake-disk' must be unique"))
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[2].disk_pool must be the name of a disk pool"))
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[3] must specify either disk_size or disk_pool"))
+		})
+
+		It("validates job persistent_disks has only one disk", func() {
+			deploymentManifest := Manifest{
+				Jobs: []Job{
+					{
+						PersistentDisks: []JobPersistentDisk{
+							{Name: "fake-disk", DiskSize: 1024},
+							{Name: "fake-other-disk", DiskSize: 1024},
+						},
+					},
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks must have only one disk, found 2"))
+
+			deploymentManifest.Jobs[0].PersistentDisks = deploymentManifest.Jobs[0].PersistentDisks[:1]
+
+			err = validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).ToNot(ContainSubstring("jobs[0].persistent_disks"))
+		})
+
+		It("validates the disk pools of a job have the cloud provider of its resource pool", func() {
+			deploymentManifest := Manifest{
+				ResourcePools: []ResourcePool{
+					{Name: "fake-resource-pool", CloudProvider: "fake-cloud-provider"},
+				},
+				DiskPools: []DiskPool{
+					{Name: "fake-default-disk-pool"},
+					{Name: "fake-other-disk-pool", CloudProvider: "fake-other-cloud-provider"},
+					{Name: "fake-disk-pool", CloudProvider: "fake-cloud-provider"},
+				},
+				Jobs: []Job{
+					{
+						ResourcePool: "fake-resource-pool",
+						PersistentDisks: []JobPersistentDisk{
+							{Name: "fake-disk", DiskPool: "fake-default-disk-pool"},
+							{Name: "fake-other-disk", DiskPool: "fake-other-disk-pool"},
+							{Name: "fake-valid-disk", DiskPool: "fake-disk-pool"},
+						},
+					},
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[0].disk_pool 'fake-default-disk-pool' must have the cloud_provider 'fake-cloud-provider' of resource pool 'fake-resource-pool'"))
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks[1].disk_pool 'fake-other-disk-pool' must have the cloud_provider 'fake-cloud-provider' of resource pool 'fake-resource-pool'"))
+			Expect(err.Error()).ToNot(ContainSubstring("jobs[0].persistent_disks[2]"))
+
+			deploymentManifest.Jobs[0].PersistentDisks = nil
+			deploymentManifest.Jobs[0].PersistentDiskPool = "fake-other-disk-pool"
+
+			err = validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disk_pool 'fake-other-disk-pool' must have the cloud_provider 'fake-cloud-provider' of resource pool 'fake-resource-pool'"))
+		})
+
+		It("validates job persistent_disks is not combined with persistent_disk", func() {
+			deploymentManifest := Manifest{
+				Jobs: []Job{
+					{
+						PersistentDisk:  1024,
+						PersistentDisks: []JobPersistentDisk{{Name: "fake-disk", DiskSize: 1024}},
+					},
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disks cannot be combined with persistent_disk or persistent_disk_pool"))
+		})
+
 		It("validates job resource pool is provided", func() {
 			deploymentManifest := Manifest{
 				Jobs: []Job{{}},
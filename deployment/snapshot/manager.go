@@ -0,0 +1,128 @@
+package snapshot
+
+import (
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	"github.com/pivotal-golang/clock"
+)
+
+// SnapshotCloudProperty is the disk cloud property that makes the CPI create a disk from the snapshot with the CID
+// instead of an empty disk. CPIs that do not know it create an empty disk.
+const SnapshotCloudProperty = "snapshot_id"
+
+// Manager takes, deletes and restores snapshots of persistent disks
+type Manager interface {
+	// Take snapshots the disk and records the snapshot in the deployment state.
+	// Returns false if the CPI does not support snapshots.
+	Take(disk bidisk.Disk) (biconfig.SnapshotRecord, bool, error)
+
+	Delete(snapshot biconfig.SnapshotRecord) error
+
+	// CreateDisk creates a new persistent disk from the snapshot for the VM, with the name of the snapshotted disk,
+	// by passing the snapshot to create_disk in the SnapshotCloudProperty cloud property.
+	// The new disk is recorded in the deployment state, but does not become the current disk.
+	CreateDisk(snapshot biconfig.SnapshotRecord, vmCID string) (bidisk.Disk, error)
+}
+
+type manager struct {
+	cloud        bicloud.Cloud
+	diskRepo     biconfig.DiskRepo
+	snapshotRepo biconfig.SnapshotRepo
+	timeService  clock.Clock
+	logger       boshlog.Logger
+	logTag       string
+}
+
+func NewManager(
+	cloud bicloud.Cloud,
+	diskRepo biconfig.DiskRepo,
+	snapshotRepo biconfig.SnapshotRepo,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Manager {
+	return &manager{
+		cloud:        cloud,
+		diskRepo:     diskRepo,
+		snapshotRepo: snapshotRepo,
+		timeService:  timeService,
+		logger:       logger,
+		logTag:       "snapshotManager",
+	}
+}
+
+func (m *manager) Take(disk bidisk.Disk) (biconfig.SnapshotRecord, bool, error) {
+	diskRecord, found, err := m.diskRepo.Find(disk.CID())
+	if err != nil {
+		return biconfig.SnapshotRecord{}, false, bosherr.WrapErrorf(err, "Finding disk record (cid=%s)", disk.CID())
+	}
+
+	if !found {
+		return biconfig.SnapshotRecord{}, false, bosherr.Errorf("Failed to find disk record for disk '%s'", disk.CID())
+	}
+
+	cid, err := m.cloud.SnapshotDisk(disk.CID(), bicloud.SnapshotMetadata{Director: "bosh-init"})
+	if err != nil {
+		if isNotImplemented(err) {
+			m.logger.Info(m.logTag, "CPI does not support taking snapshots of disk '%s'", disk.CID())
+			return biconfig.SnapshotRecord{}, false, nil
+		}
+		return biconfig.SnapshotRecord{}, false, bosherr.WrapErrorf(err, "Taking snapshot of disk '%s'", disk.CID())
+	}
+
+	snapshotRecord, err := m.snapshotRepo.Save(cid, diskRecord, m.timeService.Now())
+	if err != nil {
+		return biconfig.SnapshotRecord{}, false, bosherr.WrapError(err, "Saving deployment snapshot record")
+	}
+
+	return snapshotRecord, true, nil
+}
+
+func (m *manager) Delete(snapshot biconfig.SnapshotRecord) error {
+	err := m.cloud.DeleteSnapshot(snapshot.CID)
+	if err != nil {
+		if !isNotImplemented(err) {
+			return bosherr.WrapErrorf(err, "Deleting snapshot '%s'", snapshot.CID)
+		}
+		m.logger.Warn(m.logTag, "CPI does not support deleting snapshot '%s', only its record is deleted", snapshot.CID)
+	}
+
+	err = m.snapshotRepo.Delete(snapshot)
+	if err != nil {
+		return bosherr.WrapError(err, "Deleting snapshot record")
+	}
+
+	return nil
+}
+
+func (m *manager) CreateDisk(snapshot biconfig.SnapshotRecord, vmCID string) (bidisk.Disk, error) {
+	m.logger.Debug(m.logTag, "Creating disk from snapshot '%s'", snapshot.CID)
+
+	// the CPI contract has no method to create a disk from a snapshot, create_disk takes the snapshot as a cloud property
+	cloudProperties := biproperty.Map{}
+	for key, value := range snapshot.CloudProperties {
+		cloudProperties[key] = value
+	}
+	cloudProperties[SnapshotCloudProperty] = snapshot.CID
+
+	cid, err := m.cloud.CreateDisk(snapshot.Size, cloudProperties, vmCID)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Creating disk from snapshot '%s'", snapshot.CID)
+	}
+
+	// the disk is recorded with the cloud properties of its disk pool, so that deploy does not migrate it
+	diskRecord, err := m.diskRepo.Save(snapshot.DiskName, cid, snapshot.Size, snapshot.CloudProperties)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Saving deployment disk record")
+	}
+
+	return bidisk.NewDisk(diskRecord, m.cloud, m.diskRepo), nil
+}
+
+func isNotImplemented(err error) bool {
+	cloudErr, ok := err.(bicloud.Error)
+	return ok && cloudErr.Type() == bicloud.NotImplementedError
+}
@@ -0,0 +1,37 @@
+package snapshot
+
+import (
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+type ManagerFactory interface {
+	NewManager(bicloud.Cloud) Manager
+}
+
+type managerFactory struct {
+	diskRepo     biconfig.DiskRepo
+	snapshotRepo biconfig.SnapshotRepo
+	timeService  clock.Clock
+	logger       boshlog.Logger
+}
+
+func NewManagerFactory(
+	diskRepo biconfig.DiskRepo,
+	snapshotRepo biconfig.SnapshotRepo,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) ManagerFactory {
+	return &managerFactory{
+		diskRepo:     diskRepo,
+		snapshotRepo: snapshotRepo,
+		timeService:  timeService,
+		logger:       logger,
+	}
+}
+
+func (f *managerFactory) NewManager(cloud bicloud.Cloud) Manager {
+	return NewManager(cloud, f.diskRepo, f.snapshotRepo, f.timeService, f.logger)
+}
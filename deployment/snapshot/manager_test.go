@@ -0,0 +1,189 @@
+package snapshot_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/deployment/snapshot"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
+	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("Manager", func() {
+	var (
+		manager                Manager
+		fakeCloud              *fakebicloud.FakeCloud
+		deploymentStateService biconfig.DeploymentStateService
+		diskRepo               biconfig.DiskRepo
+		snapshotRepo           biconfig.SnapshotRepo
+		diskRecord             biconfig.DiskRecord
+
+		now = time.Date(2016, time.March, 4, 13, 14, 15, 0, time.UTC)
+
+		notImplementedErr = bicloud.NewCPIError("fake-method", bicloud.CmdError{Type: bicloud.NotImplementedError})
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fakeFs := fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, "/fake/path")
+		diskRepo = biconfig.NewDiskRepo(deploymentStateService, fakeUUIDGenerator)
+		snapshotRepo = biconfig.NewSnapshotRepo(deploymentStateService, fakeUUIDGenerator)
+		fakeCloud = fakebicloud.NewFakeCloud()
+
+		manager = NewManagerFactory(diskRepo, snapshotRepo, fakeclock.NewFakeClock(now), logger).NewManager(fakeCloud)
+
+		var err error
+		diskRecord, err = diskRepo.Save("fake-disk-name", "fake-disk-cid", 1024, biproperty.Map{"fake-cloud-property-key": "fake-cloud-property-value"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("Take", func() {
+		It("snapshots the disk and records the snapshot with the configuration of the disk", func() {
+			fakeCloud.SnapshotDiskCID = "fake-snapshot-cid"
+
+			snapshot, taken, err := manager.Take(fakebidisk.NewFakeDisk("fake-disk-cid"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(taken).To(BeTrue())
+			Expect(snapshot.CID).To(Equal("fake-snapshot-cid"))
+			Expect(snapshot.DiskCID).To(Equal("fake-disk-cid"))
+			Expect(snapshot.Size).To(Equal(1024))
+			Expect(snapshot.CloudProperties).To(Equal(diskRecord.CloudProperties))
+			Expect(snapshot.CreatedAt).To(Equal(now))
+
+			Expect(fakeCloud.SnapshotDiskInputs).To(Equal([]fakebicloud.SnapshotDiskInput{
+				{DiskCID: "fake-disk-cid", Metadata: bicloud.SnapshotMetadata{Director: "bosh-init"}},
+			}))
+
+			snapshots, err := snapshotRepo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(Equal([]biconfig.SnapshotRecord{snapshot}))
+		})
+
+		It("does not take a snapshot when the CPI does not support snapshots", func() {
+			fakeCloud.SnapshotDiskErr = notImplementedErr
+
+			_, taken, err := manager.Take(fakebidisk.NewFakeDisk("fake-disk-cid"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(taken).To(BeFalse())
+
+			snapshots, err := snapshotRepo.All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+		})
+
+		It("returns an error when taking the snapshot fails", func() {
+			fakeCloud.SnapshotDiskErr = errors.New("fake-snapshot-error")
+
+			_, _, err := manager.Take(fakebidisk.NewFakeDisk("fake-disk-cid"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-snapshot-error"))
+		})
+
+		It("returns an error when the disk is not recorded", func() {
+			_, _, err := manager.Take(fakebidisk.NewFakeDisk("fake-unknown-disk-cid"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Failed to find disk record for disk 'fake-unknown-disk-cid'"))
+			Expect(fakeCloud.SnapshotDiskInputs).To(BeEmpty())
+		})
+	})
+
+	Describe("Delete", func() {
+		var snapshot biconfig.SnapshotRecord
+
+		BeforeEach(func() {
+			var err error
+			snapshot, err = snapshotRepo.Save("fake-snapshot-cid", diskRecord, now)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("deletes the snapshot and its record", func() {
+			err := manager.Delete(snapshot)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeCloud.DeleteSnapshotInputs).To(Equal([]fakebicloud.DeleteSnapshotInput{{SnapshotCID: "fake-snapshot-cid"}}))
+
+			_, found, err := snapshotRepo.Find("fake-snapshot-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("deletes the record when the CPI does not support deleting snapshots", func() {
+			fakeCloud.DeleteSnapshotErr = notImplementedErr
+
+			err := manager.Delete(snapshot)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, found, err := snapshotRepo.Find("fake-snapshot-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("keeps the record when deleting the snapshot fails", func() {
+			fakeCloud.DeleteSnapshotErr = errors.New("fake-delete-snapshot-error")
+
+			err := manager.Delete(snapshot)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-delete-snapshot-error"))
+
+			_, found, err := snapshotRepo.Find("fake-snapshot-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Describe("CreateDisk", func() {
+		var snapshot biconfig.SnapshotRecord
+
+		BeforeEach(func() {
+			var err error
+			snapshot, err = snapshotRepo.Save("fake-snapshot-cid", diskRecord, now)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("creates a disk from the snapshot with the name and configuration of the snapshotted disk", func() {
+			fakeCloud.CreateDiskCID = "fake-restored-disk-cid"
+
+			disk, err := manager.CreateDisk(snapshot, "fake-vm-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(disk.CID()).To(Equal("fake-restored-disk-cid"))
+
+			expectedCloudProperties := biproperty.Map{"snapshot_id": "fake-snapshot-cid"}
+			for key, value := range diskRecord.CloudProperties {
+				expectedCloudProperties[key] = value
+			}
+			Expect(fakeCloud.CreateDiskInput).To(Equal(fakebicloud.CreateDiskInput{
+				Size:            1024,
+				CloudProperties: expectedCloudProperties,
+				InstanceID:      "fake-vm-cid",
+			}))
+
+			diskRecord, found, err := diskRepo.Find("fake-restored-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(diskRecord.Name).To(Equal("fake-disk-name"))
+			Expect(diskRecord.Size).To(Equal(1024))
+			Expect(diskRecord.CloudProperties).ToNot(HaveKey("snapshot_id"))
+			Expect(disk.Name()).To(Equal("fake-disk-name"))
+		})
+
+		It("returns an error when creating the disk fails", func() {
+			fakeCloud.CreateDiskErr = errors.New("fake-create-error")
+
+			_, err := manager.CreateDisk(snapshot, "fake-vm-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Creating disk from snapshot 'fake-snapshot-cid'"))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package sshtunnel
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const openSSHPrivateKeyType = "OPENSSH PRIVATE KEY"
+
+type passphrasePrompt func(privateKeyPath string) ([]byte, error)
+
+func promptForPassphrase(privateKeyPath string) ([]byte, error) {
+	stdin := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(stdin) {
+		return nil, bosherr.Errorf("Private key '%s' is encrypted and no passphrase was provided", privateKeyPath)
+	}
+
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", privateKeyPath)
+	passphrase, err := terminal.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading passphrase")
+	}
+
+	return passphrase, nil
+}
+
+func (d *dialer) authMethods(password, privateKey, passphrase string) ([]ssh.AuthMethod, error) {
+	authMethods := []ssh.AuthMethod{}
+
+	if d.agent != nil {
+		d.logger.Debug(d.logTag, "Adding ssh-agent auth method to ssh tunnel config")
+		authMethods = append(authMethods, ssh.PublicKeysCallback(d.agent.Signers))
+	}
+
+	if privateKey != "" {
+		d.logger.Debug(d.logTag, "Reading private key file '%s'", privateKey)
+		keyContents, err := d.fs.ReadFile(privateKey)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading private key file '%s'", privateKey)
+		}
+
+		d.logger.Debug(d.logTag, "Parsing private key file '%s'", privateKey)
+		signer, err := parsePrivateKey(privateKey, keyContents, passphrase, d.passphrasePrompt)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing private key file '%s'", privateKey)
+		}
+
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+
+	if password != "" {
+		d.logger.Debug(d.logTag, "Adding password auth method to ssh tunnel config")
+
+		keyboardInteractiveChallenge := func(
+			user,
+			instruction string,
+			questions []string,
+			echos []bool,
+		) (answers []string, err error) {
+			if len(questions) == 0 {
+				return []string{}, nil
+			}
+			return []string{password}, nil
+		}
+		authMethods = append(authMethods, ssh.KeyboardInteractive(keyboardInteractiveChallenge))
+		authMethods = append(authMethods, ssh.Password(password))
+	}
+
+	return authMethods, nil
+}
+
+func (d *dialer) connectAgent() error {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return bosherr.Error("SSH_AUTH_SOCK is not set, ssh-agent is not running")
+	}
+
+	d.logger.Debug(d.logTag, "Connecting to ssh-agent at '%s'", socket)
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Connecting to ssh-agent at '%s'", socket)
+	}
+
+	d.agentConn = conn
+	d.agent = agent.NewClient(conn)
+
+	return nil
+}
+
+// parsePrivateKey parses a PEM private key, decrypting it with the passphrase
+// (or one obtained from prompt) if it is encrypted.
+// Keys in the OpenSSH format (BEGIN OPENSSH PRIVATE KEY), which ssh-keygen writes by default,
+// are not supported and have to be converted to PEM first.
+func parsePrivateKey(privateKeyPath string, keyContents []byte, passphrase string, prompt passphrasePrompt) (ssh.Signer, error) {
+	block, _ := pem.Decode(keyContents)
+	if block != nil && block.Type == openSSHPrivateKeyType {
+		return nil, bosherr.Errorf("Private key '%s' is in the OpenSSH format, which is not supported, convert it to PEM with 'ssh-keygen -p -m PEM -f %s'", privateKeyPath, privateKeyPath)
+	}
+
+	if block == nil || !x509.IsEncryptedPEMBlock(block) {
+		return ssh.ParsePrivateKey(keyContents)
+	}
+
+	passphraseBytes := []byte(passphrase)
+	if passphrase == "" {
+		var err error
+		passphraseBytes, err = prompt(privateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	decrypted, err := x509.DecryptPEMBlock(block, passphraseBytes)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Decrypting private key")
+	}
+
+	return ssh.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: decrypted}))
+}
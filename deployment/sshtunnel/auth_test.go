@@ -0,0 +1,112 @@
+package sshtunnel
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parsePrivateKey", func() {
+	var (
+		privateKey   *rsa.PrivateKey
+		encryptedPEM []byte
+		promptedFor  string
+	)
+
+	prompt := func(passphrase string, err error) passphrasePrompt {
+		return func(privateKeyPath string) ([]byte, error) {
+			promptedFor = privateKeyPath
+			return []byte(passphrase), err
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).ToNot(HaveOccurred())
+
+		block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(privateKey), []byte("fake-passphrase"), x509.PEMCipherAES256)
+		Expect(err).ToNot(HaveOccurred())
+		encryptedPEM = pem.EncodeToMemory(block)
+		promptedFor = ""
+	})
+
+	It("parses unencrypted keys without prompting", func() {
+		plainPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+
+		signer, err := parsePrivateKey("/fake-key", plainPEM, "", prompt("", errors.New("should not prompt")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer.PublicKey().Type()).To(Equal("ssh-rsa"))
+		Expect(promptedFor).To(BeEmpty())
+	})
+
+	It("decrypts encrypted keys with the configured passphrase", func() {
+		signer, err := parsePrivateKey("/fake-key", encryptedPEM, "fake-passphrase", prompt("", errors.New("should not prompt")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer.PublicKey().Type()).To(Equal("ssh-rsa"))
+		Expect(promptedFor).To(BeEmpty())
+	})
+
+	It("prompts for the passphrase when none is configured", func() {
+		_, err := parsePrivateKey("/fake-key", encryptedPEM, "", prompt("fake-passphrase", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(promptedFor).To(Equal("/fake-key"))
+	})
+
+	It("returns an error when the passphrase is wrong", func() {
+		_, err := parsePrivateKey("/fake-key", encryptedPEM, "wrong-passphrase", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error when prompting fails", func() {
+		_, err := parsePrivateKey("/fake-key", encryptedPEM, "", prompt("", errors.New("fake-prompt-error")))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-prompt-error"))
+	})
+
+	It("returns an error telling how to convert keys in the OpenSSH format", func() {
+		openSSHPEM := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte("fake-key")})
+
+		_, err := parsePrivateKey("/fake-key", openSSHPEM, "", prompt("", errors.New("should not prompt")))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("ssh-keygen -p -m PEM -f /fake-key"))
+		Expect(promptedFor).To(BeEmpty())
+	})
+})
+
+var _ = Describe("authMethods", func() {
+	var (
+		fs     *fakesys.FakeFileSystem
+		dialer *dialer
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		dialer = newDialer(Options{}, fs, logger)
+	})
+
+	It("reads the private key through the file system", func() {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		err = fs.WriteFile("/fake-key", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}))
+		Expect(err).ToNot(HaveOccurred())
+
+		authMethods, err := dialer.authMethods("", "/fake-key", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authMethods).To(HaveLen(1))
+	})
+
+	It("returns an error when the private key cannot be read", func() {
+		_, err := dialer.authMethods("", "/missing-key", "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading private key file '/missing-key'"))
+	})
+})
@@ -0,0 +1,135 @@
+package sshtunnel
+
+import (
+	"fmt"
+	"net"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// dialer opens ssh connections described by Options, directly or through a jump host
+type dialer struct {
+	options          Options
+	passphrasePrompt passphrasePrompt
+	agent            agent.Agent
+	agentConn        net.Conn
+	jumpClient       *ssh.Client
+	fs               boshsys.FileSystem
+	logger           boshlog.Logger
+	logTag           string
+}
+
+func newDialer(options Options, fs boshsys.FileSystem, logger boshlog.Logger) *dialer {
+	return &dialer{
+		options:          options,
+		passphrasePrompt: promptForPassphrase,
+		fs:               fs,
+		logger:           logger,
+		logTag:           "sshDialer",
+	}
+}
+
+// ClientConfigs returns the client configs for the remote host and, if configured, the jump host
+func (d *dialer) ClientConfigs() (*ssh.ClientConfig, *ssh.ClientConfig, error) {
+	if d.options.UseAgent && d.agent == nil {
+		err := d.connectAgent()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	authMethods, err := d.authMethods(d.options.Password, d.options.PrivateKey, d.options.Passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User:            d.options.User,
+		Auth:            authMethods,
+		HostKeyCallback: d.hostKeyCallback(),
+	}
+
+	if d.options.JumpHost.IsEmpty() {
+		return sshConfig, nil, nil
+	}
+
+	jumpAuthMethods, err := d.authMethods(d.options.JumpHost.Password, d.options.JumpHost.PrivateKey, d.options.JumpHost.Passphrase)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Configuring jump host authentication")
+	}
+
+	jumpConfig := &ssh.ClientConfig{
+		User:            d.options.JumpHost.User,
+		Auth:            jumpAuthMethods,
+		HostKeyCallback: d.hostKeyCallback(),
+	}
+
+	return sshConfig, jumpConfig, nil
+}
+
+// Close closes the connections to the ssh-agent and the jump host
+func (d *dialer) Close() {
+	if d.agentConn != nil {
+		if err := d.agentConn.Close(); err != nil {
+			d.logger.Warn(d.logTag, "Failed to close ssh-agent connection: %s", err.Error())
+		}
+		d.agentConn = nil
+		d.agent = nil
+	}
+
+	if d.jumpClient != nil {
+		if err := d.jumpClient.Close(); err != nil {
+			d.logger.Warn(d.logTag, "Failed to close jump host connection: %s", err.Error())
+		}
+		d.jumpClient = nil
+	}
+}
+
+// Dial connects to the remote host, directly or through the jump host if one is configured
+func (d *dialer) Dial(remoteAddr string, sshConfig, jumpConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	if jumpConfig == nil {
+		return ssh.Dial("tcp", remoteAddr, sshConfig)
+	}
+
+	jumpPort := d.options.JumpHost.Port
+	if jumpPort == 0 {
+		jumpPort = 22
+	}
+
+	jumpAddr := fmt.Sprintf("%s:%d", d.options.JumpHost.Host, jumpPort)
+	d.logger.Debug(d.logTag, "Dialing jump host at %s", jumpAddr)
+	jumpClient, err := ssh.Dial("tcp", jumpAddr, jumpConfig)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Dialing jump host '%s'", jumpAddr)
+	}
+
+	netConn, err := jumpClient.Dial("tcp", remoteAddr)
+	if err != nil {
+		jumpClient.Close()
+		return nil, bosherr.WrapErrorf(err, "Dialing '%s' through jump host", remoteAddr)
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(netConn, remoteAddr, sshConfig)
+	if err != nil {
+		netConn.Close()
+		jumpClient.Close()
+		return nil, err
+	}
+
+	d.jumpClient = jumpClient
+
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+func (d *dialer) hostKeyCallback() func(string, net.Addr, ssh.PublicKey) error {
+	if d.options.KnownHostsFile == "" {
+		d.logger.Debug(d.logTag, "No known hosts file configured, host keys are not verified")
+		return nil
+	}
+
+	return newKnownHosts(d.options.KnownHostsFile, d.fs, d.logger).HostKeyCallback
+}
@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	"io"
+)
+
+type FakeSession struct {
+	RunInputs []RunInput
+	runErr    error
+	runOutput string
+}
+
+type RunInput struct {
+	Command string
+}
+
+func NewFakeSession() *FakeSession {
+	return &FakeSession{}
+}
+
+func (s *FakeSession) Run(command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	s.RunInputs = append(s.RunInputs, RunInput{Command: command})
+
+	if s.runOutput != "" {
+		stdout.Write([]byte(s.runOutput))
+	}
+
+	return s.runErr
+}
+
+func (s *FakeSession) SetRunBehavior(output string, err error) {
+	s.runOutput = output
+	s.runErr = err
+}
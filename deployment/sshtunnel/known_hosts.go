@@ -0,0 +1,145 @@
+package sshtunnel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"golang.org/x/crypto/ssh"
+)
+
+// knownHosts verifies host keys against an OpenSSH style known_hosts file.
+// Keys of hosts that are not in the file yet are trusted and appended to it.
+type knownHosts struct {
+	path   string
+	fs     boshsys.FileSystem
+	lock   sync.Mutex
+	logger boshlog.Logger
+	logTag string
+}
+
+func newKnownHosts(path string, fs boshsys.FileSystem, logger boshlog.Logger) *knownHosts {
+	return &knownHosts{
+		path:   path,
+		fs:     fs,
+		logger: logger,
+		logTag: "knownHosts",
+	}
+}
+
+func (k *knownHosts) HostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	host := knownHostsAddress(hostname)
+
+	keys, err := k.load()
+	if err != nil {
+		return err
+	}
+
+	hostKeys, found := keys[host]
+	if found {
+		for _, hostKey := range hostKeys {
+			if hostKey.Type() == key.Type() && bytes.Equal(hostKey.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+
+		return bosherr.Errorf("Host key verification failed for '%s': the %s key does not match the one in '%s'", host, key.Type(), k.path)
+	}
+
+	k.logger.Warn(k.logTag, "Permanently adding '%s' (%s) to the list of known hosts '%s'", host, key.Type(), k.path)
+
+	return k.add(host, key)
+}
+
+func (k *knownHosts) load() (map[string][]ssh.PublicKey, error) {
+	keys := map[string][]ssh.PublicKey{}
+
+	if !k.fs.FileExists(k.path) {
+		return keys, nil
+	}
+
+	contents, err := k.fs.ReadFile(k.path)
+	if err != nil {
+		return keys, bosherr.WrapErrorf(err, "Reading known hosts file '%s'", k.path)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") || strings.HasPrefix(line, "|") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(fields[1]))
+		if err != nil {
+			k.logger.Debug(k.logTag, "Skipping unparseable known hosts entry for '%s': %s", fields[0], err.Error())
+			continue
+		}
+
+		for _, host := range strings.Split(fields[0], ",") {
+			keys[host] = append(keys[host], key)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return keys, bosherr.WrapErrorf(err, "Reading known hosts file '%s'", k.path)
+	}
+
+	return keys, nil
+}
+
+func (k *knownHosts) add(host string, key ssh.PublicKey) error {
+	contents := []byte{}
+	if k.fs.FileExists(k.path) {
+		var err error
+		contents, err = k.fs.ReadFile(k.path)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading known hosts file '%s'", k.path)
+		}
+	}
+
+	contents = append(contents, []byte(fmt.Sprintf("%s %s", host, ssh.MarshalAuthorizedKey(key)))...)
+
+	err := k.fs.WriteFile(k.path, contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing known hosts file '%s'", k.path)
+	}
+
+	err = k.fs.Chmod(k.path, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Setting permissions of known hosts file '%s'", k.path)
+	}
+
+	return nil
+}
+
+// knownHostsAddress formats an address the way OpenSSH records it: the bare host for port 22, [host]:port otherwise
+func knownHostsAddress(hostname string) string {
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		return hostname
+	}
+
+	if portNum, err := strconv.Atoi(port); err == nil && portNum == 22 {
+		return host
+	}
+
+	return fmt.Sprintf("[%s]:%s", host, port)
+}
@@ -0,0 +1,102 @@
+package sshtunnel
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"golang.org/x/crypto/ssh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("knownHosts", func() {
+	var (
+		tmpDir         string
+		knownHostsPath string
+		hosts          *knownHosts
+		hostKey        ssh.PublicKey
+		otherHostKey   ssh.PublicKey
+	)
+
+	generatePublicKey := func() ssh.PublicKey {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+		Expect(err).ToNot(HaveOccurred())
+		return publicKey
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "known-hosts")
+		Expect(err).ToNot(HaveOccurred())
+
+		knownHostsPath = filepath.Join(tmpDir, "known_hosts")
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		hosts = newKnownHosts(knownHostsPath, boshsys.NewOsFileSystem(logger), logger)
+
+		hostKey = generatePublicKey()
+		otherHostKey = generatePublicKey()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Context("when the host is unknown", func() {
+		It("trusts the key and records it", func() {
+			err := hosts.HostKeyCallback("10.0.0.5:22", nil, hostKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(knownHostsPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(Equal("10.0.0.5 " + string(ssh.MarshalAuthorizedKey(hostKey))))
+		})
+
+		It("records non-standard ports in brackets", func() {
+			err := hosts.HostKeyCallback("10.0.0.5:2222", nil, hostKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(knownHostsPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(HavePrefix("[10.0.0.5]:2222 "))
+		})
+	})
+
+	Context("when the host is known", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(knownHostsPath, []byte("# comment\nother-host,10.0.0.5 "+string(ssh.MarshalAuthorizedKey(hostKey))), 0600)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts the recorded key", func() {
+			err := hosts.HostKeyCallback("10.0.0.5:22", nil, hostKey)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("rejects a different key", func() {
+			err := hosts.HostKeyCallback("10.0.0.5:22", nil, otherHostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Host key verification failed for '10.0.0.5'"))
+		})
+
+		It("records the keys of unknown hosts after the existing ones, readable only by the user", func() {
+			err := hosts.HostKeyCallback("10.0.0.6:22", nil, otherHostKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(knownHostsPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(Equal("# comment\nother-host,10.0.0.5 " + string(ssh.MarshalAuthorizedKey(hostKey)) + "10.0.0.6 " + string(ssh.MarshalAuthorizedKey(otherHostKey))))
+
+			fileInfo, err := os.Stat(knownHostsPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileInfo.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+	})
+})
@@ -0,0 +1,123 @@
+package sshtunnel
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type SSHSession interface {
+	// Run runs the command on the remote host, or an interactive shell if the command is empty
+	Run(command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+}
+
+type sshSession struct {
+	options Options
+	dialer  *dialer
+	logger  boshlog.Logger
+	logTag  string
+}
+
+func (s *sshSession) Run(command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	defer s.dialer.Close()
+
+	sshConfig, jumpConfig, err := s.dialer.ClientConfigs()
+	if err != nil {
+		return err
+	}
+
+	remoteAddr := fmt.Sprintf("%s:%d", s.options.Host, s.options.Port)
+	s.logger.Debug(s.logTag, "Dialing remote server at %s", remoteAddr)
+	client, err := s.dialer.Dial(remoteAddr, sshConfig, jumpConfig)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Connecting to '%s'", remoteAddr)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return bosherr.WrapError(err, "Opening ssh session")
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	if command != "" {
+		s.logger.Debug(s.logTag, "Running command '%s'", command)
+		return s.exitError(session.Run(command))
+	}
+
+	restoreTerminal, err := s.requestPty(session, stdin)
+	if err != nil {
+		return err
+	}
+	defer restoreTerminal()
+
+	err = session.Shell()
+	if err != nil {
+		return bosherr.WrapError(err, "Starting remote shell")
+	}
+
+	return s.exitError(session.Wait())
+}
+
+// requestPty allocates a remote terminal when stdin is a local terminal, putting the local one into raw mode
+func (s *sshSession) requestPty(session *ssh.Session, stdin io.Reader) (func(), error) {
+	noop := func() {}
+
+	file, ok := stdin.(*os.File)
+	if !ok || !terminal.IsTerminal(int(file.Fd())) {
+		return noop, nil
+	}
+	fd := int(file.Fd())
+
+	width, height, err := terminal.GetSize(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	termType := os.Getenv("TERM")
+	if termType == "" {
+		termType = "xterm"
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	err = session.RequestPty(termType, height, width, modes)
+	if err != nil {
+		return noop, bosherr.WrapError(err, "Requesting remote terminal")
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return noop, bosherr.WrapError(err, "Setting local terminal to raw mode")
+	}
+
+	return func() {
+		if err := terminal.Restore(fd, state); err != nil {
+			s.logger.Warn(s.logTag, "Failed to restore terminal: %s", err.Error())
+		}
+	}, nil
+}
+
+func (s *sshSession) exitError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if exitErr, ok := err.(*ssh.ExitError); ok {
+		return bosherr.Errorf("Remote command exited with status %d", exitErr.ExitStatus())
+	}
+
+	return bosherr.WrapError(err, "Running remote command")
+}
@@ -3,7 +3,6 @@ package sshtunnel
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"strings"
 	"time"
@@ -26,53 +25,17 @@ type sshTunnel struct {
 	timeService              clock.Clock
 	startDialDelay           time.Duration
 	options                  Options
+	dialer                   *dialer
 	remoteListener           net.Listener
 	logger                   boshlog.Logger
 	logTag                   string
 }
 
 func (s *sshTunnel) Start(readyErrCh chan<- error, errCh chan<- error) {
-	authMethods := []ssh.AuthMethod{}
-
-	if s.options.PrivateKey != "" {
-		s.logger.Debug(s.logTag, "Reading private key file '%s'", s.options.PrivateKey)
-		keyContents, err := ioutil.ReadFile(s.options.PrivateKey)
-		if err != nil {
-			readyErrCh <- bosherr.WrapErrorf(err, "Reading private key file '%s'", s.options.PrivateKey)
-			return
-		}
-
-		s.logger.Debug(s.logTag, "Parsing private key file '%s'", s.options.PrivateKey)
-		signer, err := ssh.ParsePrivateKey(keyContents)
-		if err != nil {
-			readyErrCh <- bosherr.WrapErrorf(err, "Parsing private key file '%s'", s.options.PrivateKey)
-			return
-		}
-
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
-	}
-
-	if s.options.Password != "" {
-		s.logger.Debug(s.logTag, "Adding password auth method to ssh tunnel config")
-
-		keyboardInteractiveChallenge := func(
-			user,
-			instruction string,
-			questions []string,
-			echos []bool,
-		) (answers []string, err error) {
-			if len(questions) == 0 {
-				return []string{}, nil
-			}
-			return []string{s.options.Password}, nil
-		}
-		authMethods = append(authMethods, ssh.KeyboardInteractive(keyboardInteractiveChallenge))
-		authMethods = append(authMethods, ssh.Password(s.options.Password))
-	}
-
-	sshConfig := &ssh.ClientConfig{
-		User: s.options.User,
-		Auth: authMethods,
+	sshConfig, jumpConfig, err := s.dialer.ClientConfigs()
+	if err != nil {
+		readyErrCh <- err
+		return
 	}
 
 	s.logger.Debug(s.logTag, "Dialing remote server at %s:%d", s.options.Host, s.options.Port)
@@ -85,11 +48,9 @@ func (s *sshTunnel) Start(readyErrCh chan<- error, errCh chan<- error) {
 	}
 
 	var conn *ssh.Client
-	var err error
 	for i := 0; ; i++ {
 		s.logger.Debug(s.logTag, "Making attempt #%d", i)
-		conn, err = ssh.Dial("tcp", remoteAddr, sshConfig)
-
+		conn, err = s.dialer.Dial(remoteAddr, sshConfig, jumpConfig)
 		if err == nil {
 			break
 		}
@@ -162,6 +123,8 @@ func (s *sshTunnel) Start(readyErrCh chan<- error, errCh chan<- error) {
 }
 
 func (s *sshTunnel) Stop() error {
+	s.dialer.Close()
+
 	if s.remoteListener == nil {
 		return nil
 	}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"github.com/pivotal-golang/clock"
 )
 
@@ -14,24 +15,53 @@ type Options struct {
 	PrivateKey string
 	Password   string
 
+	// Passphrase decrypts PrivateKey; it is prompted for on the terminal when needed and empty.
+	Passphrase string
+
+	// UseAgent adds the keys of the ssh-agent listening on SSH_AUTH_SOCK.
+	UseAgent bool
+
+	// KnownHostsFile verifies host keys, recording the keys of unknown hosts on first use.
+	// Host keys are not verified when empty.
+	KnownHostsFile string
+
+	JumpHost JumpHostOptions
+
 	LocalForwardPort  int
 	RemoteForwardPort int
 }
 
+// JumpHostOptions describe an intermediate host the connection to the tunnel host is made through
+type JumpHostOptions struct {
+	Host       string
+	Port       int
+	User       string
+	PrivateKey string
+	Password   string
+	Passphrase string
+}
+
+func (o JumpHostOptions) IsEmpty() bool {
+	return o == JumpHostOptions{}
+}
+
 func (o Options) IsEmpty() bool {
 	return o == Options{}
 }
 
 type Factory interface {
 	NewSSHTunnel(Options) SSHTunnel
+	NewSSHSession(Options) SSHSession
 }
 
 type factory struct {
+	fs     boshsys.FileSystem
 	logger boshlog.Logger
 }
 
-func NewFactory(logger boshlog.Logger) Factory {
+func NewFactory(fs boshsys.FileSystem, logger boshlog.Logger) Factory {
 	return &factory{
+		fs:     fs,
 		logger: logger,
 	}
 }
@@ -44,7 +74,17 @@ func (s *factory) NewSSHTunnel(options Options) SSHTunnel {
 		startDialDelay:           500 * time.Millisecond,
 		timeService:              timeService,
 		options:                  options,
+		dialer:                   newDialer(options, s.fs, s.logger),
 		logger:                   s.logger,
 		logTag:                   "sshTunnel",
 	}
 }
+
+func (s *factory) NewSSHSession(options Options) SSHSession {
+	return &sshSession{
+		options: options,
+		dialer:  newDialer(options, s.fs, s.logger),
+		logger:  s.logger,
+		logTag:  "sshSession",
+	}
+}
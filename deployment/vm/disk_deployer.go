@@ -2,60 +2,95 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bisnapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
+// migratedDiskUsageTolerance is the share of the size of the larger disk by which the space and the inodes
+// used on a migrated disk may differ from the original disk. The agent reports the usage in whole percents,
+// and the overhead of the file system depends on the size of the disk.
+const migratedDiskUsageTolerance = 0.03
+
+// diskUsage is the usage of a disk estimated from its vitals. Space is in MB; Inodes scales the inode percentage
+// with the disk size like Space, since file systems create a number of inodes proportional to their size.
+type diskUsage struct {
+	Space        float64
+	Inodes       float64
+	InodePercent string
+}
+
 // DiskDeployer is in the vm package to avoid a [disk -> vm -> disk] dependency cycle
 type DiskDeployer interface {
-	Deploy(diskPool bideplmanifest.DiskPool, cloud bicloud.Cloud, vm VM, eventLoggerStage biui.Stage) ([]bidisk.Disk, error)
+	// Deploy creates, attaches or migrates the persistent disk. The agent mounts a single persistent disk,
+	// so a current disk with another name is migrated to a new disk with the configured name.
+	Deploy(persistentDisks []bideplmanifest.PersistentDisk, cloud bicloud.Cloud, vm VM, eventLoggerStage biui.Stage) ([]bidisk.Disk, error)
 }
 
 type diskDeployer struct {
-	diskRepo           biconfig.DiskRepo
-	diskManagerFactory bidisk.ManagerFactory
-	diskManager        bidisk.Manager
-	logger             boshlog.Logger
-	logTag             string
+	diskRepo               biconfig.DiskRepo
+	diskMigrationRepo      biconfig.DiskMigrationRepo
+	diskManagerFactory     bidisk.ManagerFactory
+	diskManager            bidisk.Manager
+	snapshotManagerFactory bisnapshot.ManagerFactory
+	snapshotManager        bisnapshot.Manager
+	logger                 boshlog.Logger
+	logTag                 string
 }
 
-func NewDiskDeployer(diskManagerFactory bidisk.ManagerFactory, diskRepo biconfig.DiskRepo, logger boshlog.Logger) DiskDeployer {
+func NewDiskDeployer(
+	diskManagerFactory bidisk.ManagerFactory,
+	snapshotManagerFactory bisnapshot.ManagerFactory,
+	diskRepo biconfig.DiskRepo,
+	diskMigrationRepo biconfig.DiskMigrationRepo,
+	logger boshlog.Logger,
+) DiskDeployer {
 	return &diskDeployer{
-		diskManagerFactory: diskManagerFactory,
-		diskRepo:           diskRepo,
-		logger:             logger,
-		logTag:             "diskDeployer",
+		diskManagerFactory:     diskManagerFactory,
+		snapshotManagerFactory: snapshotManagerFactory,
+		diskRepo:               diskRepo,
+		diskMigrationRepo:      diskMigrationRepo,
+		logger:                 logger,
+		logTag:                 "diskDeployer",
 	}
 }
 
-func (d *diskDeployer) Deploy(diskPool bideplmanifest.DiskPool, cloud bicloud.Cloud, vm VM, stage biui.Stage) ([]bidisk.Disk, error) {
-	if diskPool.DiskSize == 0 {
+func (d *diskDeployer) Deploy(persistentDisks []bideplmanifest.PersistentDisk, cloud bicloud.Cloud, vm VM, stage biui.Stage) ([]bidisk.Disk, error) {
+	if len(persistentDisks) == 0 {
 		return []bidisk.Disk{}, nil
 	}
 
+	if len(persistentDisks) > 1 {
+		return []bidisk.Disk{}, bosherr.Error("Multiple persistent disks not supported")
+	}
+	persistentDisk := persistentDisks[0]
+
 	d.diskManager = d.diskManagerFactory.NewManager(cloud)
+	d.snapshotManager = d.snapshotManagerFactory.NewManager(cloud)
 	disks, err := d.diskManager.FindCurrent()
 	if err != nil {
 		return disks, bosherr.WrapError(err, "Finding existing disk")
 	}
 
 	if len(disks) > 1 {
-		return disks, bosherr.WrapError(err, "Multiple current disks not supported")
+		return disks, bosherr.Error("Multiple current disks not supported")
 
 	} else if len(disks) == 1 {
-		disks, err = d.deployExistingDisk(disks[0], diskPool, vm, stage)
+		disks, err = d.deployExistingDisk(disks[0], persistentDisk, vm, stage)
 		if err != nil {
 			return disks, err
 		}
 
 	} else {
-		disks, err = d.deployNewDisk(diskPool, vm, stage)
+		disks, err = d.deployNewDisk(persistentDisk, vm, stage)
 		if err != nil {
 			return disks, err
 		}
@@ -69,7 +104,7 @@ func (d *diskDeployer) Deploy(diskPool bideplmanifest.DiskPool, cloud bicloud.Cl
 	return disks, nil
 }
 
-func (d *diskDeployer) deployExistingDisk(disk bidisk.Disk, diskPool bideplmanifest.DiskPool, vm VM, stage biui.Stage) ([]bidisk.Disk, error) {
+func (d *diskDeployer) deployExistingDisk(disk bidisk.Disk, persistentDisk bideplmanifest.PersistentDisk, vm VM, stage biui.Stage) ([]bidisk.Disk, error) {
 	disks := []bidisk.Disk{}
 
 	// the disk is already part of the deployment, and should already be attached
@@ -81,8 +116,28 @@ func (d *diskDeployer) deployExistingDisk(disk bidisk.Disk, diskPool bideplmanif
 		return disks, err
 	}
 
-	if disk.NeedsMigration(diskPool.DiskSize, diskPool.CloudProperties) {
-		disk, err = d.migrateDisk(disk, diskPool, vm, stage)
+	migration, found, err := d.diskMigrationRepo.Find()
+	if err != nil {
+		return disks, bosherr.WrapError(err, "Finding interrupted disk migration")
+	}
+
+	if found {
+		disk, err = d.resumeMigration(disk, migration, vm, stage)
+		if err != nil {
+			return disks, err
+		}
+
+		disks[0] = disk
+	}
+
+	diskPool := persistentDisk.DiskPool
+	renamed := disk.Name() != persistentDisk.Name
+	if renamed {
+		d.logger.Info(d.logTag, "Migrating disk '%s' with name '%s' to a disk with name '%s'", disk.CID(), disk.Name(), persistentDisk.Name)
+	}
+
+	if renamed || disk.NeedsMigration(diskPool.DiskSize, diskPool.CloudProperties) {
+		disk, err = d.migrateDisk(disk, persistentDisk, vm, stage)
 		if err != nil {
 			return disks, err
 		}
@@ -94,10 +149,10 @@ func (d *diskDeployer) deployExistingDisk(disk bidisk.Disk, diskPool bideplmanif
 	return disks, nil
 }
 
-func (d *diskDeployer) deployNewDisk(diskPool bideplmanifest.DiskPool, vm VM, stage biui.Stage) ([]bidisk.Disk, error) {
+func (d *diskDeployer) deployNewDisk(persistentDisk bideplmanifest.PersistentDisk, vm VM, stage biui.Stage) ([]bidisk.Disk, error) {
 	disks := []bidisk.Disk{}
 
-	disk, err := d.createDisk(diskPool, vm, stage)
+	disk, err := d.createDisk(persistentDisk, vm, stage)
 	if err != nil {
 		return disks, err
 	}
@@ -120,58 +175,330 @@ func (d *diskDeployer) deployNewDisk(diskPool bideplmanifest.DiskPool, vm VM, st
 
 func (d *diskDeployer) migrateDisk(
 	originalDisk bidisk.Disk,
-	diskPool bideplmanifest.DiskPool,
+	persistentDisk bideplmanifest.PersistentDisk,
 	vm VM,
 	stage biui.Stage,
 ) (newDisk bidisk.Disk, err error) {
 	d.logger.Debug(d.logTag, "Migrating disk '%s'", originalDisk.CID())
 
-	err = stage.Perform("Creating disk", func() error {
-		newDisk, err = d.diskManager.Create(diskPool, vm.CID())
-		return err
-	})
+	if persistentDisk.DiskPool.SnapshotBeforeMigration {
+		err = d.snapshotDisk(originalDisk, stage)
+		if err != nil {
+			return originalDisk, err
+		}
+	}
+
+	newDisk, err = d.createDisk(persistentDisk, vm, stage)
 	if err != nil {
-		return newDisk, err
+		return originalDisk, err
 	}
 
-	stageName := fmt.Sprintf("Attaching disk '%s' to VM '%s'", newDisk.CID(), vm.CID())
-	err = stage.Perform(stageName, func() error {
-		return vm.AttachDisk(newDisk)
-	})
+	migration := biconfig.DiskMigrationRecord{
+		DiskName:        originalDisk.Name(),
+		OriginalDiskCID: originalDisk.CID(),
+		NewDiskCID:      newDisk.CID(),
+		VMCID:           vm.CID(),
+		Phase:           biconfig.DiskMigrationCreated,
+	}
+	err = d.saveMigration(migration)
 	if err != nil {
-		return newDisk, err
+		return originalDisk, err
+	}
+
+	return d.runMigration(originalDisk, newDisk, migration, vm, stage)
+}
+
+// resumeMigration continues a migration that was interrupted by a previous deploy
+func (d *diskDeployer) resumeMigration(
+	currentDisk bidisk.Disk,
+	migration biconfig.DiskMigrationRecord,
+	vm VM,
+	stage biui.Stage,
+) (bidisk.Disk, error) {
+	if currentDisk.CID() != migration.OriginalDiskCID && currentDisk.CID() != migration.NewDiskCID {
+		d.logger.Warn(d.logTag, "Discarding migration from disk '%s' to disk '%s', the current disk is '%s'",
+			migration.OriginalDiskCID, migration.NewDiskCID, currentDisk.CID())
+		return currentDisk, d.clearMigration()
+	}
+
+	originalDisk, originalFound, err := d.diskManager.Find(migration.OriginalDiskCID)
+	if err != nil {
+		return currentDisk, bosherr.WrapError(err, "Finding original disk of the interrupted migration")
+	}
+
+	newDisk, newFound, err := d.diskManager.Find(migration.NewDiskCID)
+	if err != nil {
+		return currentDisk, bosherr.WrapError(err, "Finding new disk of the interrupted migration")
+	}
+
+	if !originalFound || !newFound {
+		d.logger.Warn(d.logTag, "Discarding migration from disk '%s' to disk '%s', one of the disks no longer exists",
+			migration.OriginalDiskCID, migration.NewDiskCID)
+		return currentDisk, d.clearMigration()
+	}
+
+	// the agent mounts are lost with the VM, so the content is copied again to the new disk
+	if migration.VMCID != vm.CID() && migration.Phase != biconfig.DiskMigrationSwitched {
+		migration.Phase = biconfig.DiskMigrationCreated
+		migration.VMCID = vm.CID()
+	}
+
+	d.logger.Info(d.logTag, "Resuming migration from disk '%s' to disk '%s' after phase '%s'",
+		migration.OriginalDiskCID, migration.NewDiskCID, migration.Phase)
+
+	return d.runMigration(originalDisk, newDisk, migration, vm, stage)
+}
+
+// runMigration performs the phases of the migration that follow its recorded phase.
+// A migration that fails while creating, attaching or copying the new disk is resumed by the next deploy,
+// a migration that fails verifying the copy or switching to the new disk is rolled back to the original disk.
+func (d *diskDeployer) runMigration(
+	originalDisk bidisk.Disk,
+	newDisk bidisk.Disk,
+	migration biconfig.DiskMigrationRecord,
+	vm VM,
+	stage biui.Stage,
+) (bidisk.Disk, error) {
+	var err error
+
+	switch migration.Phase {
+	case biconfig.DiskMigrationCreated:
+		err = d.attachDisk(newDisk, vm, stage)
+		if err != nil {
+			return originalDisk, err
+		}
+
+		migration.Phase = biconfig.DiskMigrationAttached
+		err = d.saveMigration(migration)
+		if err != nil {
+			return originalDisk, err
+		}
+
+		fallthrough
+	case biconfig.DiskMigrationAttached:
+		usage, verifiable, err := d.copyDisk(originalDisk, newDisk, vm, stage)
+		if err != nil {
+			return originalDisk, err
+		}
+
+		err = d.verifyDisk(originalDisk, newDisk, usage, verifiable, vm, stage)
+		if err != nil {
+			return originalDisk, d.rollbackMigration(err, originalDisk, newDisk, vm, stage)
+		}
+
+		migration.Phase = biconfig.DiskMigrationCopied
+		err = d.saveMigration(migration)
+		if err != nil {
+			return originalDisk, err
+		}
+
+		fallthrough
+	case biconfig.DiskMigrationCopied:
+		err = d.switchDisk(originalDisk, newDisk, vm, stage)
+		if err != nil {
+			return originalDisk, d.rollbackMigration(err, originalDisk, newDisk, vm, stage)
+		}
+
+		migration.Phase = biconfig.DiskMigrationSwitched
+		err = d.saveMigration(migration)
+		if err != nil {
+			return newDisk, err
+		}
+
+		fallthrough
+	case biconfig.DiskMigrationSwitched:
+		stageName := fmt.Sprintf("Deleting disk '%s'", originalDisk.CID())
+		err = stage.Perform(stageName, func() error {
+			return originalDisk.Delete()
+		})
+		if err != nil {
+			return newDisk, err
+		}
+
+		return newDisk, d.clearMigration()
+	default:
+		return originalDisk, bosherr.Errorf("Unknown phase '%s' of the migration of disk '%s'", migration.Phase, originalDisk.CID())
 	}
+}
 
-	stageName = fmt.Sprintf("Migrating disk content from '%s' to '%s'", originalDisk.CID(), newDisk.CID())
+// copyDisk copies the content of the original disk to the new disk,
+// returning the usage of the original disk if the agent reports it in its vitals
+func (d *diskDeployer) copyDisk(originalDisk bidisk.Disk, newDisk bidisk.Disk, vm VM, stage biui.Stage) (usage diskUsage, verifiable bool, err error) {
+	stageName := fmt.Sprintf("Migrating disk content from '%s' to '%s'", originalDisk.CID(), newDisk.CID())
 	err = stage.Perform(stageName, func() error {
+		usage, verifiable, err = d.persistentDiskUsage(originalDisk, vm)
+		if err != nil {
+			return err
+		}
+
 		return vm.MigrateDisk()
 	})
+
+	return usage, verifiable, err
+}
+
+// verifyDisk checks that the new disk uses about as much space and as many inodes as the original disk did
+func (d *diskDeployer) verifyDisk(originalDisk bidisk.Disk, newDisk bidisk.Disk, originalUsage diskUsage, verifiable bool, vm VM, stage biui.Stage) error {
+	stageName := fmt.Sprintf("Verifying disk content of '%s'", newDisk.CID())
+	return stage.Perform(stageName, func() error {
+		if !verifiable {
+			return biui.NewSkipStageError(bosherr.Errorf("Getting usage of disk '%s'", newDisk.CID()), "Not reported by the agent")
+		}
+
+		newUsage, verifiable, err := d.persistentDiskUsage(newDisk, vm)
+		if err != nil {
+			return err
+		}
+
+		if !verifiable {
+			return biui.NewSkipStageError(bosherr.Errorf("Getting usage of disk '%s'", newDisk.CID()), "Not reported by the agent")
+		}
+
+		tolerance := migratedDiskUsageTolerance * math.Max(float64(originalDisk.Size()), float64(newDisk.Size()))
+
+		if math.Abs(newUsage.Space-originalUsage.Space) > tolerance {
+			return bosherr.Errorf("Disk '%s' uses about %.0fMB after the migration, but disk '%s' used about %.0fMB",
+				newDisk.CID(), newUsage.Space, originalDisk.CID(), originalUsage.Space)
+		}
+
+		if math.Abs(newUsage.Inodes-originalUsage.Inodes) > tolerance {
+			return bosherr.Errorf("Disk '%s' uses %s%% of its inodes after the migration, but disk '%s' used %s%% of its inodes",
+				newDisk.CID(), newUsage.InodePercent, originalDisk.CID(), originalUsage.InodePercent)
+		}
+
+		return nil
+	})
+}
+
+// persistentDiskUsage estimates the usage of the disk from the vitals of the persistent disk in the full state of the agent.
+// The agent only reports the disk mounted as the persistent disk, which is the original disk before the migration
+// and the new disk after it. verifiable is false when the agent does not report the disk.
+func (d *diskDeployer) persistentDiskUsage(disk bidisk.Disk, vm VM) (usage diskUsage, verifiable bool, err error) {
+	if disk.Size() == 0 {
+		return diskUsage{}, false, nil
+	}
+
+	agentState, err := vm.GetFullState()
+	if err != nil {
+		return diskUsage{}, false, bosherr.WrapErrorf(err, "Getting usage of disk '%s'", disk.CID())
+	}
+
+	vitals, found := agentState.Vitals.Disk["persistent"]
+	if !found {
+		return diskUsage{}, false, nil
+	}
+
+	percent, err := strconv.ParseFloat(vitals.Percent, 64)
 	if err != nil {
-		return newDisk, err
+		d.logger.Warn(d.logTag, "Ignoring the usage '%s' of disk '%s' in the vitals of the agent: %s", vitals.Percent, disk.CID(), err.Error())
+		return diskUsage{}, false, nil
 	}
 
-	err = d.updateCurrentDiskRecord(newDisk)
+	inodePercent, err := strconv.ParseFloat(vitals.InodePercent, 64)
 	if err != nil {
-		return newDisk, err
+		d.logger.Warn(d.logTag, "Ignoring the inode usage '%s' of disk '%s' in the vitals of the agent: %s", vitals.InodePercent, disk.CID(), err.Error())
+		return diskUsage{}, false, nil
 	}
 
-	stageName = fmt.Sprintf("Detaching disk '%s'", originalDisk.CID())
-	err = stage.Perform(stageName, func() error {
+	size := float64(disk.Size())
+	return diskUsage{
+		Space:        percent * size / 100,
+		Inodes:       inodePercent * size / 100,
+		InodePercent: vitals.InodePercent,
+	}, true, nil
+}
+
+// switchDisk makes the new disk the current disk and detaches the original disk
+func (d *diskDeployer) switchDisk(originalDisk bidisk.Disk, newDisk bidisk.Disk, vm VM, stage biui.Stage) error {
+	err := d.updateCurrentDiskRecord(newDisk)
+	if err != nil {
+		return err
+	}
+
+	stageName := fmt.Sprintf("Detaching disk '%s'", originalDisk.CID())
+	return stage.Perform(stageName, func() error {
 		return vm.DetachDisk(originalDisk)
 	})
+}
+
+// rollbackMigration makes the original disk the current disk again and deletes the new disk.
+// The returned error includes the error that caused the rollback.
+func (d *diskDeployer) rollbackMigration(cause error, originalDisk bidisk.Disk, newDisk bidisk.Disk, vm VM, stage biui.Stage) error {
+	d.logger.Warn(d.logTag, "Rolling back migration from disk '%s' to disk '%s': %s", originalDisk.CID(), newDisk.CID(), cause.Error())
+
+	stageName := fmt.Sprintf("Detaching disk '%s'", newDisk.CID())
+	err := stage.Perform(stageName, func() error {
+		err := vm.UnmountDisk(newDisk)
+		if err != nil {
+			return bosherr.WrapError(err, "Unmounting disk")
+		}
+
+		return vm.DetachDisk(newDisk)
+	})
 	if err != nil {
-		return newDisk, err
+		return bosherr.WrapErrorf(cause, "Rolling back disk migration failed (%s)", err.Error())
 	}
 
-	stageName = fmt.Sprintf("Deleting disk '%s'", originalDisk.CID())
+	err = d.attachDisk(originalDisk, vm, stage)
+	if err != nil {
+		return bosherr.WrapErrorf(cause, "Rolling back disk migration failed (%s)", err.Error())
+	}
+
+	err = d.updateCurrentDiskRecord(originalDisk)
+	if err != nil {
+		return bosherr.WrapErrorf(cause, "Rolling back disk migration failed (%s)", err.Error())
+	}
+
+	err = d.clearMigration()
+	if err != nil {
+		return bosherr.WrapErrorf(cause, "Rolling back disk migration failed (%s)", err.Error())
+	}
+
+	stageName = fmt.Sprintf("Deleting disk '%s'", newDisk.CID())
 	err = stage.Perform(stageName, func() error {
-		return originalDisk.Delete()
+		return newDisk.Delete()
 	})
 	if err != nil {
-		return newDisk, err
+		// the new disk is no longer current, so the next deploy deletes it
+		d.logger.Warn(d.logTag, "Deleting disk '%s' after rolling back the migration: %s", newDisk.CID(), err.Error())
 	}
 
-	return newDisk, nil
+	return bosherr.WrapErrorf(cause, "Migrating disk '%s' (rolled back to the original disk)", originalDisk.CID())
+}
+
+func (d *diskDeployer) saveMigration(migration biconfig.DiskMigrationRecord) error {
+	err := d.diskMigrationRepo.Save(migration)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving disk migration record")
+	}
+
+	return nil
+}
+
+func (d *diskDeployer) clearMigration() error {
+	err := d.diskMigrationRepo.Clear()
+	if err != nil {
+		return bosherr.WrapError(err, "Clearing disk migration record")
+	}
+
+	return nil
+}
+
+func (d *diskDeployer) snapshotDisk(disk bidisk.Disk, stage biui.Stage) error {
+	stageName := fmt.Sprintf("Taking snapshot of disk '%s'", disk.CID())
+	return stage.Perform(stageName, func() error {
+		snapshot, taken, err := d.snapshotManager.Take(disk)
+		if err != nil {
+			return err
+		}
+
+		if !taken {
+			return biui.NewSkipStageError(bosherr.Errorf("Taking snapshot of disk '%s'", disk.CID()), "Not supported by the CPI")
+		}
+
+		d.logger.Info(d.logTag, "Took snapshot '%s' of disk '%s' before migrating it", snapshot.CID, disk.CID())
+		return nil
+	})
 }
 
 func (d *diskDeployer) updateCurrentDiskRecord(disk bidisk.Disk) error {
@@ -192,9 +519,14 @@ func (d *diskDeployer) updateCurrentDiskRecord(disk bidisk.Disk) error {
 	return nil
 }
 
-func (d *diskDeployer) createDisk(diskPool bideplmanifest.DiskPool, vm VM, stage biui.Stage) (disk bidisk.Disk, err error) {
-	err = stage.Perform("Creating disk", func() error {
-		disk, err = d.diskManager.Create(diskPool, vm.CID())
+func (d *diskDeployer) createDisk(persistentDisk bideplmanifest.PersistentDisk, vm VM, stage biui.Stage) (disk bidisk.Disk, err error) {
+	stageName := "Creating disk"
+	if persistentDisk.Name != "" {
+		stageName = fmt.Sprintf("Creating disk '%s'", persistentDisk.Name)
+	}
+
+	err = stage.Perform(stageName, func() error {
+		disk, err = d.diskManager.Create(persistentDisk, vm.CID())
 		return err
 	})
 
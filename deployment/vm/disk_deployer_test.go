@@ -3,15 +3,19 @@ package vm_test
 import (
 	. "github.com/cloudfoundry/bosh-init/deployment/vm"
 
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	mock_snapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot/mocks"
+
 	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
 	fakebiconfig "github.com/cloudfoundry/bosh-init/config/fakes"
 	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
@@ -20,15 +24,28 @@ import (
 )
 
 var _ = Describe("DiskDeployer", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
 	var (
-		diskDeployer    DiskDeployer
-		fakeDiskManager *fakebidisk.FakeManager
-		diskPool        bideplmanifest.DiskPool
-		cloud           *fakebicloud.FakeCloud
-		fakeStage       *fakebiui.FakeStage
-		fakeVM          *fakebivm.FakeVM
-		fakeDisk        *fakebidisk.FakeDisk
-		fakeDiskRepo    *fakebiconfig.FakeDiskRepo
+		diskDeployer        DiskDeployer
+		fakeDiskManager     *fakebidisk.FakeManager
+		mockSnapshotManager *mock_snapshot.MockManager
+		diskPool            bideplmanifest.DiskPool
+		persistentDisks     []bideplmanifest.PersistentDisk
+		cloud               *fakebicloud.FakeCloud
+		fakeStage           *fakebiui.FakeStage
+		fakeVM              *fakebivm.FakeVM
+		fakeDisk            *fakebidisk.FakeDisk
+		fakeDiskRepo        *fakebiconfig.FakeDiskRepo
+		fakeMigrationRepo   *fakebiconfig.FakeDiskMigrationRepo
 	)
 
 	BeforeEach(func() {
@@ -41,12 +58,19 @@ var _ = Describe("DiskDeployer", func() {
 		fakeDiskManager.CreateDisk = fakeDisk
 		fakeDiskManagerFactory.NewManagerManager = fakeDiskManager
 
+		mockSnapshotManagerFactory := mock_snapshot.NewMockManagerFactory(mockCtrl)
+		mockSnapshotManager = mock_snapshot.NewMockManager(mockCtrl)
+		mockSnapshotManagerFactory.EXPECT().NewManager(cloud).Return(mockSnapshotManager).AnyTimes()
+
 		logger := boshlog.NewLogger(boshlog.LevelNone)
 		fakeStage = fakebiui.NewFakeStage()
 		fakeDiskRepo = fakebiconfig.NewFakeDiskRepo()
+		fakeMigrationRepo = fakebiconfig.NewFakeDiskMigrationRepo()
 		diskDeployer = NewDiskDeployer(
 			fakeDiskManagerFactory,
+			mockSnapshotManagerFactory,
 			fakeDiskRepo,
+			fakeMigrationRepo,
 			logger,
 		)
 
@@ -67,6 +91,7 @@ var _ = Describe("DiskDeployer", func() {
 					"fake-disk-pool-cloud-property-key": "fake-disk-pool-cloud-property-value",
 				},
 			}
+			persistentDisks = []bideplmanifest.PersistentDisk{{DiskPool: diskPool}}
 		})
 
 		Context("when primary disk already exists", func() {
@@ -83,7 +108,7 @@ var _ = Describe("DiskDeployer", func() {
 			})
 
 			It("does not create primary disk", func() {
-				disks, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeDiskManager.CreateInputs).To(BeEmpty())
@@ -96,7 +121,7 @@ var _ = Describe("DiskDeployer", func() {
 				})
 
 				It("does not log the create disk event", func() {
-					disks, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(disks).To(Equal([]bidisk.Disk{existingDisk}))
 
@@ -106,6 +131,109 @@ var _ = Describe("DiskDeployer", func() {
 				})
 			})
 
+			Context("when an interrupted migration is recorded", func() {
+				var secondaryDisk *fakebidisk.FakeDisk
+
+				BeforeEach(func() {
+					secondaryDisk = fakebidisk.NewFakeDisk("fake-secondary-disk-cid")
+					fakeDiskManager.SetFindBehavior("fake-existing-disk-cid", existingDisk, true, nil)
+					fakeDiskManager.SetFindBehavior("fake-secondary-disk-cid", secondaryDisk, true, nil)
+					fakeDiskRepo.SetFindBehavior("fake-secondary-disk-cid", biconfig.DiskRecord{ID: "fake-secondary-disk-id"}, true, nil)
+					fakeVM.SetAttachDiskBehavior(secondaryDisk, nil)
+
+					fakeMigrationRepo.Record = &biconfig.DiskMigrationRecord{
+						OriginalDiskCID: "fake-existing-disk-cid",
+						NewDiskCID:      "fake-secondary-disk-cid",
+						VMCID:           "fake-vm-cid",
+						Phase:           biconfig.DiskMigrationAttached,
+					}
+				})
+
+				It("resumes the migration after the recorded phase", func() {
+					disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(disks).To(Equal([]bidisk.Disk{secondaryDisk}))
+
+					Expect(fakeDiskManager.CreateInputs).To(BeEmpty())
+					Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(1))
+					Expect(fakeMigrationRepo.Record).To(BeNil())
+
+					Expect(fakeStage.PerformCalls[1].Name).To(Equal("Migrating disk content from 'fake-existing-disk-cid' to 'fake-secondary-disk-cid'"))
+				})
+
+				It("deletes the original disk when the new disk was made current already", func() {
+					fakeMigrationRepo.Record.Phase = biconfig.DiskMigrationSwitched
+					fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{secondaryDisk}, nil)
+
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(existingDisk.DeleteCalledTimes).To(Equal(1))
+					Expect(fakeMigrationRepo.Record).To(BeNil())
+				})
+
+				It("does not copy the content again once it was copied", func() {
+					fakeMigrationRepo.Record.Phase = biconfig.DiskMigrationCopied
+
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(0))
+
+					Expect(fakeStage.PerformCalls[1:]).To(Equal([]*fakebiui.PerformCall{
+						{Name: "Detaching disk 'fake-existing-disk-cid'"},
+						{Name: "Deleting disk 'fake-existing-disk-cid'"},
+					}))
+				})
+
+				It("only deletes the original disk once the new disk is current", func() {
+					fakeMigrationRepo.Record.Phase = biconfig.DiskMigrationSwitched
+					fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{secondaryDisk}, nil)
+
+					disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(disks).To(Equal([]bidisk.Disk{secondaryDisk}))
+
+					Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+						{Name: "Attaching disk 'fake-secondary-disk-cid' to VM 'fake-vm-cid'"},
+						{Name: "Deleting disk 'fake-existing-disk-cid'"},
+					}))
+					Expect(fakeMigrationRepo.Record).To(BeNil())
+				})
+
+				It("copies the content again when the VM was recreated", func() {
+					fakeMigrationRepo.Record.Phase = biconfig.DiskMigrationCopied
+					fakeMigrationRepo.Record.VMCID = "fake-old-vm-cid"
+
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(1))
+
+					Expect(fakeStage.PerformCalls[1]).To(Equal(&fakebiui.PerformCall{
+						Name: "Attaching disk 'fake-secondary-disk-cid' to VM 'fake-vm-cid'",
+					}))
+					Expect(fakeMigrationRepo.SaveInputs[0].VMCID).To(Equal("fake-vm-cid"))
+				})
+
+				It("discards the migration when the current disk is not part of it", func() {
+					fakeMigrationRepo.Record.OriginalDiskCID = "fake-other-disk-cid"
+					existingDisk.SetNeedsMigrationBehavior(false)
+
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fakeMigrationRepo.Record).To(BeNil())
+					Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(0))
+				})
+
+				It("discards the migration when one of its disks no longer exists", func() {
+					fakeDiskManager.SetFindBehavior("fake-secondary-disk-cid", nil, false, nil)
+					existingDisk.SetNeedsMigrationBehavior(false)
+
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fakeMigrationRepo.Record).To(BeNil())
+					Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(0))
+				})
+			})
+
 			Context("when disk needs migration", func() {
 				var secondaryDisk *fakebidisk.FakeDisk
 
@@ -122,7 +250,7 @@ var _ = Describe("DiskDeployer", func() {
 				})
 
 				It("creates secondary disk", func() {
-					disks, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(disks).To(Equal([]bidisk.Disk{secondaryDisk}))
 
@@ -139,7 +267,7 @@ var _ = Describe("DiskDeployer", func() {
 				})
 
 				It("attaches secondary disk", func() {
-					_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{
 						{Disk: existingDisk},
@@ -152,7 +280,7 @@ var _ = Describe("DiskDeployer", func() {
 				})
 
 				It("migrates from primary to secondary disk", func() {
-					_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(1))
 
@@ -162,19 +290,19 @@ var _ = Describe("DiskDeployer", func() {
 				})
 
 				It("detaches primary disk", func() {
-					_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{
 						{Disk: existingDisk},
 					}))
 
-					Expect(fakeStage.PerformCalls[4]).To(Equal(&fakebiui.PerformCall{
+					Expect(fakeStage.PerformCalls[5]).To(Equal(&fakebiui.PerformCall{
 						Name: "Detaching disk 'fake-existing-disk-cid'",
 					}))
 				})
 
 				It("promotes secondary disk as primary", func() {
-					_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 					Expect(err).NotTo(HaveOccurred())
 
 					// existing disk must be current until after migration
@@ -184,13 +312,159 @@ var _ = Describe("DiskDeployer", func() {
 					}))
 				})
 
+				It("deletes the primary disk", func() {
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(existingDisk.DeleteCalledTimes).To(Equal(1))
+					Expect(secondaryDisk.DeleteCalledTimes).To(Equal(0))
+
+					Expect(fakeStage.PerformCalls[6]).To(Equal(&fakebiui.PerformCall{
+						Name: "Deleting disk 'fake-existing-disk-cid'",
+					}))
+				})
+
+				It("records each phase of the migration and clears it once done", func() {
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).NotTo(HaveOccurred())
+
+					migration := biconfig.DiskMigrationRecord{
+						OriginalDiskCID: "fake-existing-disk-cid",
+						NewDiskCID:      "fake-secondary-disk-cid",
+						VMCID:           "fake-vm-cid",
+					}
+					phases := []biconfig.DiskMigrationPhase{}
+					for _, record := range fakeMigrationRepo.SaveInputs {
+						phases = append(phases, record.Phase)
+						record.Phase = ""
+						Expect(record).To(Equal(migration))
+					}
+					Expect(phases).To(Equal([]biconfig.DiskMigrationPhase{
+						biconfig.DiskMigrationCreated,
+						biconfig.DiskMigrationAttached,
+						biconfig.DiskMigrationCopied,
+						biconfig.DiskMigrationSwitched,
+					}))
+
+					Expect(fakeMigrationRepo.Record).To(BeNil())
+				})
+
+				It("skips verifying the migrated content and deletes the primary disk when the agent does not report the disk usage", func() {
+					_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeStage.PerformCalls[4].Name).To(Equal("Verifying disk content of 'fake-secondary-disk-cid'"))
+					Expect(fakeStage.PerformCalls[4].SkipError).To(HaveOccurred())
+					Expect(fakeStage.PerformCalls[4].SkipError.Error()).To(ContainSubstring("Not reported by the agent"))
+					Expect(existingDisk.DeleteCalledTimes).To(Equal(1))
+				})
+
+				Context("when the agent reports the disk usage in its vitals", func() {
+					BeforeEach(func() {
+						existingDisk.SetSize(1024)
+						secondaryDisk.SetSize(2048)
+					})
+
+					It("verifies that the migrated disk uses about as much space and as many inodes as the primary disk", func() {
+						fakeVM.AddGetFullStateBehavior(persistentDiskVitals("50", "10"), nil)
+						fakeVM.AddGetFullStateBehavior(persistentDiskVitals("26", "5"), nil)
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeVM.GetFullStateCalled).To(Equal(2))
+						Expect(existingDisk.DeleteCalledTimes).To(Equal(1))
+
+						Expect(fakeStage.PerformCalls[4]).To(Equal(&fakebiui.PerformCall{
+							Name: "Verifying disk content of 'fake-secondary-disk-cid'",
+						}))
+					})
+
+					It("rolls back to the primary disk when the inode usage differs", func() {
+						fakeVM.AddGetFullStateBehavior(persistentDiskVitals("50", "10"), nil)
+						fakeVM.AddGetFullStateBehavior(persistentDiskVitals("25", "1"), nil)
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("Disk 'fake-secondary-disk-cid' uses 1% of its inodes after the migration, but disk 'fake-existing-disk-cid' used 10% of its inodes"))
+						Expect(existingDisk.DeleteCalledTimes).To(Equal(0))
+					})
+
+					It("rolls back to the primary disk when the space usage differs", func() {
+						fakeVM.AddGetFullStateBehavior(persistentDiskVitals("50", "10"), nil)
+						fakeVM.AddGetFullStateBehavior(persistentDiskVitals("5", "5"), nil)
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("rolled back to the original disk"))
+						Expect(err.Error()).To(ContainSubstring("Disk 'fake-secondary-disk-cid' uses about 102MB after the migration, but disk 'fake-existing-disk-cid' used about 512MB"))
+
+						Expect(fakeVM.UnmountDiskInputs).To(Equal([]fakebivm.UnmountDiskInput{
+							{Disk: secondaryDisk},
+						}))
+						Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{
+							{Disk: secondaryDisk},
+						}))
+						Expect(fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{
+							{Disk: existingDisk},
+							{Disk: secondaryDisk},
+							{Disk: existingDisk},
+						}))
+						Expect(fakeDiskRepo.UpdateCurrentInputs).To(Equal([]fakebiconfig.DiskRepoUpdateCurrentInput{
+							{DiskID: "fake-existing-disk-id"},
+						}))
+						Expect(secondaryDisk.DeleteCalledTimes).To(Equal(1))
+						Expect(existingDisk.DeleteCalledTimes).To(Equal(0))
+						Expect(fakeMigrationRepo.Record).To(BeNil())
+					})
+				})
+
+				Context("when the disk pool requests a snapshot before migration", func() {
+					BeforeEach(func() {
+						persistentDisks[0].DiskPool.SnapshotBeforeMigration = true
+					})
+
+					It("snapshots the existing disk before creating the secondary disk", func() {
+						mockSnapshotManager.EXPECT().Take(existingDisk).Return(biconfig.SnapshotRecord{CID: "fake-snapshot-cid"}, true, nil)
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(fakeStage.PerformCalls[1]).To(Equal(&fakebiui.PerformCall{
+							Name: "Taking snapshot of disk 'fake-existing-disk-cid'",
+						}))
+						Expect(fakeStage.PerformCalls[2]).To(Equal(&fakebiui.PerformCall{
+							Name: "Creating disk",
+						}))
+					})
+
+					It("skips the snapshot and migrates the disk when the CPI does not support snapshots", func() {
+						mockSnapshotManager.EXPECT().Take(existingDisk).Return(biconfig.SnapshotRecord{}, false, nil)
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(1))
+
+						Expect(fakeStage.PerformCalls[1].Name).To(Equal("Taking snapshot of disk 'fake-existing-disk-cid'"))
+						Expect(fakeStage.PerformCalls[1].SkipError).To(HaveOccurred())
+					})
+
+					It("returns an error and leaves the existing disk attached when taking the snapshot fails", func() {
+						mockSnapshotManager.EXPECT().Take(existingDisk).Return(biconfig.SnapshotRecord{}, false, bosherr.Error("fake-snapshot-error"))
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-snapshot-error"))
+						Expect(fakeDiskManager.CreateInputs).To(BeEmpty())
+						Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{}))
+					})
+				})
+
 				Context("when disk creation fails", func() {
 					BeforeEach(func() {
 						fakeDiskManager.CreateErr = bosherr.Error("fake-create-disk-error")
 					})
 
 					It("returns error and leaves the existing disk attached", func() {
-						_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 						Expect(err).To(HaveOccurred())
 						Expect(err.Error()).To(ContainSubstring("fake-create-disk-error"))
 						Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{}))
@@ -207,7 +481,7 @@ var _ = Describe("DiskDeployer", func() {
 					})
 
 					It("returns error and leaves the existing disk attached", func() {
-						_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 						Expect(err).To(HaveOccurred())
 						Expect(err.Error()).To(ContainSubstring("fake-attach-disk-error"))
 						Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{}))
@@ -223,7 +497,7 @@ var _ = Describe("DiskDeployer", func() {
 					})
 				})
 
-				Context("when detaching the new disk fails", func() {
+				Context("when detaching the primary disk fails", func() {
 					var (
 						detachError = bosherr.Error("fake-detach-disk-error")
 					)
@@ -232,22 +506,42 @@ var _ = Describe("DiskDeployer", func() {
 						fakeVM.SetDetachDiskBehavior(existingDisk, detachError)
 					})
 
-					It("returns error", func() {
-						_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+					It("rolls back to the primary disk", func() {
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 						Expect(err).To(HaveOccurred())
 						Expect(err.Error()).To(ContainSubstring("fake-detach-disk-error"))
+						Expect(err.Error()).To(ContainSubstring("rolled back to the original disk"))
 
-						Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
-							{Name: "Attaching disk 'fake-existing-disk-cid' to VM 'fake-vm-cid'"},
-							{Name: "Creating disk"},
-							{Name: "Attaching disk 'fake-secondary-disk-cid' to VM 'fake-vm-cid'"},
+						Expect(fakeDiskRepo.UpdateCurrentInputs).To(Equal([]fakebiconfig.DiskRepoUpdateCurrentInput{
+							{DiskID: "fake-secondary-disk-id"},
+							{DiskID: "fake-existing-disk-id"},
+						}))
+						Expect(existingDisk.DeleteCalledTimes).To(Equal(0))
+						Expect(secondaryDisk.DeleteCalledTimes).To(Equal(1))
+						Expect(fakeMigrationRepo.Record).To(BeNil())
+
+						Expect(fakeStage.PerformCalls[3:]).To(Equal([]*fakebiui.PerformCall{
 							{Name: "Migrating disk content from 'fake-existing-disk-cid' to 'fake-secondary-disk-cid'"},
+							fakeStage.PerformCalls[4],
 							{
 								Name:  "Detaching disk 'fake-existing-disk-cid'",
 								Error: detachError,
 							},
+							{Name: "Detaching disk 'fake-secondary-disk-cid'"},
+							{Name: "Attaching disk 'fake-existing-disk-cid' to VM 'fake-vm-cid'"},
+							{Name: "Deleting disk 'fake-secondary-disk-cid'"},
 						}))
 					})
+
+					It("returns both errors when the rollback fails", func() {
+						fakeVM.SetDetachDiskBehavior(secondaryDisk, bosherr.Error("fake-rollback-error"))
+
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-detach-disk-error"))
+						Expect(err.Error()).To(ContainSubstring("fake-rollback-error"))
+						Expect(fakeMigrationRepo.Record.Phase).To(Equal(biconfig.DiskMigrationCopied))
+					})
 				})
 
 				Context("when migration to the new disk fails", func() {
@@ -260,10 +554,11 @@ var _ = Describe("DiskDeployer", func() {
 					})
 
 					It("returns error and leaves the existing disk attached", func() {
-						_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+						_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 						Expect(err).To(HaveOccurred())
 						Expect(err.Error()).To(ContainSubstring("fake-migrate-disk-error"))
 						Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{}))
+						Expect(fakeMigrationRepo.Record.Phase).To(Equal(biconfig.DiskMigrationAttached))
 
 						Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 							{Name: "Attaching disk 'fake-existing-disk-cid' to VM 'fake-vm-cid'"},
@@ -281,7 +576,7 @@ var _ = Describe("DiskDeployer", func() {
 
 		Context("when disk does not exist", func() {
 			It("creates a persistent disk", func() {
-				disks, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(disks).To(Equal([]bidisk.Disk{fakeDisk}))
 
@@ -294,7 +589,7 @@ var _ = Describe("DiskDeployer", func() {
 			})
 
 			It("sets the new disk as current", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeDiskRepo.UpdateCurrentInputs).To(Equal([]fakebiconfig.DiskRepoUpdateCurrentInput{
@@ -303,17 +598,18 @@ var _ = Describe("DiskDeployer", func() {
 			})
 
 			It("logs the create disk event", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0]).To(Equal(&fakebiui.PerformCall{
 					Name: "Creating disk",
 				}))
 			})
+
 		})
 
 		It("attaches the primary disk", func() {
-			_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+			_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{
 				{
@@ -323,7 +619,7 @@ var _ = Describe("DiskDeployer", func() {
 		})
 
 		It("logs attaching primary disk event", func() {
-			_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+			_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
@@ -333,7 +629,7 @@ var _ = Describe("DiskDeployer", func() {
 		})
 
 		It("removes unused disks", func() {
-			_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+			_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(fakeDiskManager.DeleteUnusedCalledTimes).To(Equal(1))
@@ -345,7 +641,7 @@ var _ = Describe("DiskDeployer", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-delete-error"))
 			})
@@ -361,13 +657,13 @@ var _ = Describe("DiskDeployer", func() {
 			})
 
 			It("return an error", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-create-disk-error"))
 			})
 
 			It("logs start and stop events to the eventLogger", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).To(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
@@ -389,13 +685,13 @@ var _ = Describe("DiskDeployer", func() {
 			})
 
 			It("return an error", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-attach-disk-error"))
 			})
 
 			It("logs start and failed events to the eventLogger", func() {
-				_, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+				_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 				Expect(err).To(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
@@ -409,13 +705,111 @@ var _ = Describe("DiskDeployer", func() {
 		})
 	})
 
-	Context("when the disk pool size is 0", func() {
+	Context("when the persistent disk is named", func() {
+		var dataDisk *fakebidisk.FakeDisk
+
 		BeforeEach(func() {
-			diskPool = bideplmanifest.DiskPool{}
+			diskPool = bideplmanifest.DiskPool{Name: "fake-data-disk-pool-name", DiskSize: 1024}
+			persistentDisks = []bideplmanifest.PersistentDisk{{Name: "data", DiskPool: diskPool}}
+
+			dataDisk = fakebidisk.NewFakeNamedDisk("data", "fake-data-disk-cid")
+			fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{dataDisk}, nil)
+			fakeVM.SetAttachDiskBehavior(dataDisk, nil)
+			fakeDiskRepo.SetFindBehavior("fake-data-disk-cid", biconfig.DiskRecord{ID: "fake-data-disk-id"}, true, nil)
+		})
+
+		It("mounts the disk like a single persistent disk", func() {
+			disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(disks).To(Equal([]bidisk.Disk{dataDisk}))
+
+			Expect(fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{
+				{Disk: dataDisk},
+			}))
+			Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(0))
+		})
+
+		Context("when the current disk has another name", func() {
+			var newDisk *fakebidisk.FakeDisk
+
+			BeforeEach(func() {
+				persistentDisks = []bideplmanifest.PersistentDisk{{Name: "logs", DiskPool: diskPool}}
+				newDisk = fakebidisk.NewFakeNamedDisk("logs", "fake-new-disk-cid")
+				fakeDiskManager.CreateDisk = newDisk
+			})
+
+			It("migrates the current disk to a disk with the new name", func() {
+				disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(disks).To(Equal([]bidisk.Disk{newDisk}))
+
+				Expect(fakeDiskManager.CreateInputs).To(Equal([]fakebidisk.CreateInput{
+					{
+						Name:       "logs",
+						DiskPool:   diskPool,
+						InstanceID: "fake-vm-cid",
+					},
+				}))
+				Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(1))
+				Expect(fakeMigrationRepo.SaveInputs[0].DiskName).To(Equal("data"))
+				Expect(fakeDiskRepo.UpdateCurrentInputs).To(Equal([]fakebiconfig.DiskRepoUpdateCurrentInput{
+					{DiskID: "fake-new-disk-id"},
+				}))
+				Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{
+					{Disk: dataDisk},
+				}))
+			})
+		})
+
+		It("resumes an interrupted migration of a disk with another name", func() {
+			persistentDisks = []bideplmanifest.PersistentDisk{{Name: "logs", DiskPool: diskPool}}
+			newDisk := fakebidisk.NewFakeNamedDisk("logs", "fake-new-disk-cid")
+			fakeDiskManager.SetFindBehavior("fake-data-disk-cid", dataDisk, true, nil)
+			fakeDiskManager.SetFindBehavior("fake-new-disk-cid", newDisk, true, nil)
+			fakeMigrationRepo.Record = &biconfig.DiskMigrationRecord{
+				DiskName:        "data",
+				OriginalDiskCID: "fake-data-disk-cid",
+				NewDiskCID:      "fake-new-disk-cid",
+				VMCID:           "fake-vm-cid",
+				Phase:           biconfig.DiskMigrationAttached,
+			}
+
+			disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(disks).To(Equal([]bidisk.Disk{newDisk}))
+
+			Expect(fakeDiskManager.CreateInputs).To(BeEmpty())
+			Expect(fakeVM.MigrateDiskCalledTimes).To(Equal(1))
+			Expect(fakeMigrationRepo.Record).To(BeNil())
+		})
+
+		It("returns an error when there are multiple current disks", func() {
+			oldDisk := fakebidisk.NewFakeNamedDisk("old", "fake-old-disk-cid")
+			fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{dataDisk, oldDisk}, nil)
+
+			_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Multiple current disks not supported"))
+			Expect(fakeStage.PerformCalls).To(BeEmpty())
+		})
+
+		It("returns an error when multiple persistent disks are configured", func() {
+			persistentDisks = append(persistentDisks, bideplmanifest.PersistentDisk{Name: "logs", DiskPool: diskPool})
+
+			_, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Multiple persistent disks not supported"))
+			Expect(fakeStage.PerformCalls).To(BeEmpty())
+		})
+	})
+
+	Context("when there are no persistent disks", func() {
+		BeforeEach(func() {
+			persistentDisks = []bideplmanifest.PersistentDisk{}
 		})
 
 		It("does not create a persistent disk", func() {
-			disks, err := diskDeployer.Deploy(diskPool, cloud, fakeVM, fakeStage)
+			disks, err := diskDeployer.Deploy(persistentDisks, cloud, fakeVM, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(disks).To(Equal([]bidisk.Disk{}))
 
@@ -423,3 +817,13 @@ var _ = Describe("DiskDeployer", func() {
 		})
 	})
 })
+
+func persistentDiskVitals(percent string, inodePercent string) biagent.AgentState {
+	return biagent.AgentState{
+		Vitals: biagent.Vitals{
+			Disk: map[string]biagent.DiskVitals{
+				"persistent": {Percent: percent, InodePercent: inodePercent},
+			},
+		},
+	}
+}
@@ -16,9 +16,18 @@ type FakeManager struct {
 	CreateVM    bivm.VM
 	CreateErr   error
 
+	ConfigChangesInputs  []ConfigChangesInput
+	ConfigChangesChanges []string
+	ConfigChangesErr     error
+
 	findCurrentBehaviour findCurrentOutput
 }
 
+type ConfigChangesInput struct {
+	Stemcell bistemcell.CloudStemcell
+	Manifest bideplmanifest.Manifest
+}
+
 type findCurrentOutput struct {
 	vm    bivm.VM
 	found bool
@@ -43,6 +52,15 @@ func (m *FakeManager) Create(stemcell bistemcell.CloudStemcell, deploymentManife
 	return m.CreateVM, m.CreateErr
 }
 
+func (m *FakeManager) ConfigChanges(stemcell bistemcell.CloudStemcell, deploymentManifest bideplmanifest.Manifest) ([]string, error) {
+	m.ConfigChangesInputs = append(m.ConfigChangesInputs, ConfigChangesInput{
+		Stemcell: stemcell,
+		Manifest: deploymentManifest,
+	})
+
+	return m.ConfigChangesChanges, m.ConfigChangesErr
+}
+
 func (m *FakeManager) SetFindCurrentBehavior(vm bivm.VM, found bool, err error) {
 	m.findCurrentBehaviour = findCurrentOutput{
 		vm:    vm,
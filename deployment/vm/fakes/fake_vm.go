@@ -5,6 +5,7 @@ import (
 
 	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	biui "github.com/cloudfoundry/bosh-init/ui"
@@ -17,7 +18,7 @@ type FakeVM struct {
 	ExistsFound  bool
 	ExistsErr    error
 
-	AgentClientReturn biagentclient.AgentClient
+	AgentClientReturn biagent.AgentClient
 
 	UpdateDisksInputs []UpdateDisksInput
 	UpdateDisksDisks  []bidisk.Disk
@@ -47,6 +48,12 @@ type FakeVM struct {
 	StopCalled int
 	StopErr    error
 
+	DrainInputs []time.Duration
+	DrainErr    error
+
+	DrainForUpdateInputs []DrainForUpdateInput
+	DrainForUpdateErr    error
+
 	ListDisksDisks []bidisk.Disk
 	ListDisksErr   error
 
@@ -62,11 +69,21 @@ type FakeVM struct {
 	GetStateResult biagentclient.AgentState
 	GetStateCalled int
 	GetStateErr    error
+
+	GetFullStateResult  biagent.AgentState
+	GetFullStateCalled  int
+	GetFullStateErr     error
+	getFullStateOutputs []getFullStateOutput
 }
 
 type UpdateDisksInput struct {
-	DiskPool bideplmanifest.DiskPool
-	Stage    biui.Stage
+	PersistentDisks []bideplmanifest.PersistentDisk
+	Stage           biui.Stage
+}
+
+type DrainForUpdateInput struct {
+	NewSpec bias.ApplySpec
+	MaxWait time.Duration
 }
 
 type ApplyInput struct {
@@ -95,6 +112,11 @@ type UnmountDiskInput struct {
 	Disk bidisk.Disk
 }
 
+type getFullStateOutput struct {
+	state biagent.AgentState
+	err   error
+}
+
 func NewFakeVM(cid string) *FakeVM {
 	return &FakeVM{
 		ExistsFound:           true,
@@ -120,11 +142,11 @@ func (vm *FakeVM) Exists() (bool, error) {
 	return vm.ExistsFound, vm.ExistsErr
 }
 
-func (vm *FakeVM) AgentClient() biagentclient.AgentClient {
+func (vm *FakeVM) AgentClient() biagent.AgentClient {
 	return vm.AgentClientReturn
 }
 
-func (vm *FakeVM) WaitUntilReady(timeout time.Duration, delay time.Duration) error {
+func (vm *FakeVM) WaitUntilReady(timeout time.Duration, delay time.Duration, interruptCh <-chan struct{}) error {
 	vm.WaitUntilReadyInputs = append(vm.WaitUntilReadyInputs, WaitUntilReadyInput{
 		Timeout: timeout,
 		Delay:   delay,
@@ -132,10 +154,10 @@ func (vm *FakeVM) WaitUntilReady(timeout time.Duration, delay time.Duration) err
 	return vm.WaitUntilReadyErr
 }
 
-func (vm *FakeVM) UpdateDisks(diskPool bideplmanifest.DiskPool, eventLoggerStage biui.Stage) ([]bidisk.Disk, error) {
+func (vm *FakeVM) UpdateDisks(persistentDisks []bideplmanifest.PersistentDisk, eventLoggerStage biui.Stage) ([]bidisk.Disk, error) {
 	vm.UpdateDisksInputs = append(vm.UpdateDisksInputs, UpdateDisksInput{
-		DiskPool: diskPool,
-		Stage:    eventLoggerStage,
+		PersistentDisks: persistentDisks,
+		Stage:           eventLoggerStage,
 	})
 	return vm.UpdateDisksDisks, vm.UpdateDisksErr
 }
@@ -153,7 +175,7 @@ func (vm *FakeVM) Start() error {
 	return vm.StartErr
 }
 
-func (vm *FakeVM) WaitToBeRunning(maxAttempts int, delay time.Duration) error {
+func (vm *FakeVM) WaitToBeRunning(maxAttempts int, delay time.Duration, interruptCh <-chan struct{}) error {
 	vm.WaitToBeRunningInputs = append(vm.WaitToBeRunningInputs, WaitInput{
 		MaxAttempts: maxAttempts,
 		Delay:       delay,
@@ -196,6 +218,19 @@ func (vm *FakeVM) Stop() error {
 	return vm.StopErr
 }
 
+func (vm *FakeVM) Drain(maxWait time.Duration, interruptCh <-chan struct{}) error {
+	vm.DrainInputs = append(vm.DrainInputs, maxWait)
+	return vm.DrainErr
+}
+
+func (vm *FakeVM) DrainForUpdate(newSpec bias.ApplySpec, maxWait time.Duration, interruptCh <-chan struct{}) error {
+	vm.DrainForUpdateInputs = append(vm.DrainForUpdateInputs, DrainForUpdateInput{
+		NewSpec: newSpec,
+		MaxWait: maxWait,
+	})
+	return vm.DrainForUpdateErr
+}
+
 func (vm *FakeVM) Disks() ([]bidisk.Disk, error) {
 	return vm.ListDisksDisks, vm.ListDisksErr
 }
@@ -222,3 +257,24 @@ func (vm *FakeVM) GetState() (biagentclient.AgentState, error) {
 	vm.GetStateCalled++
 	return vm.GetStateResult, vm.GetStateErr
 }
+
+func (vm *FakeVM) GetFullState() (biagent.AgentState, error) {
+	vm.GetFullStateCalled++
+
+	if len(vm.getFullStateOutputs) == 0 {
+		return vm.GetFullStateResult, vm.GetFullStateErr
+	}
+
+	output := vm.getFullStateOutputs[0]
+	vm.getFullStateOutputs = vm.getFullStateOutputs[1:]
+	return output.state, output.err
+}
+
+// AddGetFullStateBehavior queues the result of the next call to GetFullState.
+// Without queued results, GetFullStateResult and GetFullStateErr are returned.
+func (vm *FakeVM) AddGetFullStateBehavior(state biagent.AgentState, err error) {
+	vm.getFullStateOutputs = append(vm.getFullStateOutputs, getFullStateOutput{
+		state: state,
+		err:   err,
+	})
+}
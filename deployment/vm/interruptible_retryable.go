@@ -0,0 +1,32 @@
+package vm
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
+)
+
+// interruptibleRetryable stops the retry strategy once the interrupt channel is closed,
+// so that waiting for the agent does not outlast an interrupt
+type interruptibleRetryable struct {
+	retryable   boshretry.Retryable
+	interruptCh <-chan struct{}
+	waitName    string
+}
+
+func newInterruptibleRetryable(retryable boshretry.Retryable, interruptCh <-chan struct{}, waitName string) boshretry.Retryable {
+	return &interruptibleRetryable{
+		retryable:   retryable,
+		interruptCh: interruptCh,
+		waitName:    waitName,
+	}
+}
+
+func (r *interruptibleRetryable) Attempt() (bool, error) {
+	select {
+	case <-r.interruptCh:
+		return false, biui.NewInterruptedWaitError(r.waitName)
+	default:
+	}
+
+	return r.retryable.Attempt()
+}
@@ -1,33 +1,40 @@
 package vm
 
 import (
-	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	"bytes"
+	"encoding/json"
+
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
+	bihttpagent "github.com/cloudfoundry/bosh-init/agentclient/http"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
-	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/pivotal-golang/clock"
 )
 
 type Manager interface {
 	FindCurrent() (VM, bool, error)
 	Create(bistemcell.CloudStemcell, bideplmanifest.Manifest) (VM, error)
+	// ConfigChanges lists how the cloud configuration required by the deployment manifest differs from the one
+	// the current VM was created with. No changes means the current VM can be updated in place.
+	ConfigChanges(bistemcell.CloudStemcell, bideplmanifest.Manifest) ([]string, error)
 }
 
 type manager struct {
 	vmRepo             biconfig.VMRepo
 	stemcellRepo       biconfig.StemcellRepo
 	diskDeployer       DiskDeployer
-	agentClient        biagentclient.AgentClient
+	agentClient        biagent.AgentClient
 	agentClientFactory bihttpagent.AgentClientFactory
 	cloud              bicloud.Cloud
 	uuidGenerator      boshuuid.Generator
 	fs                 boshsys.FileSystem
+	timeService        clock.Clock
 	logger             boshlog.Logger
 	logTag             string
 }
@@ -36,10 +43,11 @@ func NewManager(
 	vmRepo biconfig.VMRepo,
 	stemcellRepo biconfig.StemcellRepo,
 	diskDeployer DiskDeployer,
-	agentClient biagentclient.AgentClient,
+	agentClient biagent.AgentClient,
 	cloud bicloud.Cloud,
 	uuidGenerator boshuuid.Generator,
 	fs boshsys.FileSystem,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) Manager {
 	return &manager{
@@ -50,6 +58,7 @@ func NewManager(
 		diskDeployer:  diskDeployer,
 		uuidGenerator: uuidGenerator,
 		fs:            fs,
+		timeService:   timeService,
 		logger:        logger,
 		logTag:        "vmManager",
 	}
@@ -73,31 +82,59 @@ func (m *manager) FindCurrent() (VM, bool, error) {
 		m.agentClient,
 		m.cloud,
 		m.fs,
+		m.timeService,
 		m.logger,
 	)
 
 	return vm, true, err
 }
 
-func (m *manager) Create(stemcell bistemcell.CloudStemcell, deploymentManifest bideplmanifest.Manifest) (VM, error) {
-	jobName := deploymentManifest.JobName()
-	networkInterfaces, err := deploymentManifest.NetworkInterfaces(jobName)
-	m.logger.Debug(m.logTag, "Creating VM with network interfaces: %#v", networkInterfaces)
+func (m *manager) ConfigChanges(stemcell bistemcell.CloudStemcell, deploymentManifest bideplmanifest.Manifest) ([]string, error) {
+	currentConfig, found, err := m.vmRepo.FindCurrentConfig()
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Getting network spec")
+		return nil, bosherr.WrapError(err, "Finding config of currently deployed vm")
 	}
 
-	resourcePool, err := deploymentManifest.ResourcePool(jobName)
+	if !found {
+		return []string{"configuration of the current VM is unknown"}, nil
+	}
+
+	desiredConfig, err := m.vmConfig(stemcell, deploymentManifest)
 	if err != nil {
-		return nil, bosherr.WrapErrorf(err, "Getting resource pool for job '%s'", jobName)
+		return nil, err
 	}
 
+	changes := []string{}
+	if currentConfig.StemcellCID != desiredConfig.StemcellCID {
+		changes = append(changes, "stemcell changed")
+	}
+	if !sameJSON(currentConfig.CloudProperties, desiredConfig.CloudProperties) {
+		changes = append(changes, "cloud properties changed")
+	}
+	if !sameJSON(currentConfig.Networks, desiredConfig.Networks) {
+		changes = append(changes, "networks changed")
+	}
+	if !sameJSON(currentConfig.Env, desiredConfig.Env) {
+		changes = append(changes, "env changed")
+	}
+
+	m.logger.Debug(m.logTag, "Changes to the current VM configuration: %#v", changes)
+	return changes, nil
+}
+
+func (m *manager) Create(stemcell bistemcell.CloudStemcell, deploymentManifest bideplmanifest.Manifest) (VM, error) {
+	config, err := m.vmConfig(stemcell, deploymentManifest)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Debug(m.logTag, "Creating VM with network interfaces: %#v", config.Networks)
+
 	agentID, err := m.uuidGenerator.Generate()
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Generating agent ID")
 	}
 
-	cid, err := m.createAndRecordVm(agentID, stemcell, resourcePool, networkInterfaces)
+	cid, err := m.createAndRecordVm(agentID, config)
 	if err != nil {
 		return nil, err
 	}
@@ -126,16 +163,43 @@ func (m *manager) Create(stemcell bistemcell.CloudStemcell, deploymentManifest b
 		m.agentClient,
 		m.cloud,
 		m.fs,
+		m.timeService,
 		m.logger,
 	)
 
 	return vm, nil
 }
 
-func (m *manager) createAndRecordVm(agentID string, stemcell bistemcell.CloudStemcell, resourcePool bideplmanifest.ResourcePool, networkInterfaces map[string]biproperty.Map) (string, error) {
-	cid, err := m.cloud.CreateVM(agentID, stemcell.CID(), resourcePool.CloudProperties, networkInterfaces, resourcePool.Env)
+// vmConfig returns the cloud configuration of the VM for the job in the deployment manifest
+func (m *manager) vmConfig(stemcell bistemcell.CloudStemcell, deploymentManifest bideplmanifest.Manifest) (biconfig.VMConfigRecord, error) {
+	return NewConfigRecord(stemcell.CID(), deploymentManifest)
+}
+
+// NewConfigRecord returns the cloud configuration of a VM created with the stemcell for the job in the deployment manifest
+func NewConfigRecord(stemcellCID string, deploymentManifest bideplmanifest.Manifest) (biconfig.VMConfigRecord, error) {
+	jobName := deploymentManifest.JobName()
+	networkInterfaces, err := deploymentManifest.NetworkInterfaces(jobName)
+	if err != nil {
+		return biconfig.VMConfigRecord{}, bosherr.WrapError(err, "Getting network spec")
+	}
+
+	resourcePool, err := deploymentManifest.ResourcePool(jobName)
+	if err != nil {
+		return biconfig.VMConfigRecord{}, bosherr.WrapErrorf(err, "Getting resource pool for job '%s'", jobName)
+	}
+
+	return biconfig.VMConfigRecord{
+		StemcellCID:     stemcellCID,
+		CloudProperties: resourcePool.CloudProperties,
+		Networks:        networkInterfaces,
+		Env:             resourcePool.Env,
+	}, nil
+}
+
+func (m *manager) createAndRecordVm(agentID string, config biconfig.VMConfigRecord) (string, error) {
+	cid, err := m.cloud.CreateVM(agentID, config.StemcellCID, config.CloudProperties, config.Networks, config.Env)
 	if err != nil {
-		return "", bosherr.WrapErrorf(err, "Creating vm with stemcell cid '%s'", stemcell.CID())
+		return "", bosherr.WrapErrorf(err, "Creating vm with stemcell cid '%s'", config.StemcellCID)
 	}
 
 	// Record vm info immediately so we don't leak it
@@ -144,5 +208,26 @@ func (m *manager) createAndRecordVm(agentID string, stemcell bistemcell.CloudSte
 		return "", bosherr.WrapError(err, "Updating current vm record")
 	}
 
+	err = m.vmRepo.UpdateCurrentConfig(config)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Updating current vm config record")
+	}
+
 	return cid, nil
 }
+
+// sameJSON compares values by their JSON encoding, so that values loaded from the deployment state
+// (e.g. float64 numbers, []interface{} lists) match the ones parsed from the deployment manifest
+func sameJSON(a, b interface{}) bool {
+	aJSON, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+
+	bJSON, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(aJSON, bJSON)
+}
@@ -1,16 +1,17 @@
 package vm
 
 import (
-	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/pivotal-golang/clock"
 )
 
 type ManagerFactory interface {
-	NewManager(cloud bicloud.Cloud, agentClient biagentclient.AgentClient) Manager
+	NewManager(cloud bicloud.Cloud, agentClient biagent.AgentClient) Manager
 }
 
 type managerFactory struct {
@@ -19,6 +20,7 @@ type managerFactory struct {
 	diskDeployer  DiskDeployer
 	uuidGenerator boshuuid.Generator
 	fs            boshsys.FileSystem
+	timeService   clock.Clock
 	logger        boshlog.Logger
 }
 
@@ -28,6 +30,7 @@ func NewManagerFactory(
 	diskDeployer DiskDeployer,
 	uuidGenerator boshuuid.Generator,
 	fs boshsys.FileSystem,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) ManagerFactory {
 	return &managerFactory{
@@ -36,11 +39,12 @@ func NewManagerFactory(
 		diskDeployer:  diskDeployer,
 		uuidGenerator: uuidGenerator,
 		fs:            fs,
+		timeService:   timeService,
 		logger:        logger,
 	}
 }
 
-func (f *managerFactory) NewManager(cloud bicloud.Cloud, agentClient biagentclient.AgentClient) Manager {
+func (f *managerFactory) NewManager(cloud bicloud.Cloud, agentClient biagent.AgentClient) Manager {
 	return NewManager(
 		f.vmRepo,
 		f.stemcellRepo,
@@ -49,6 +53,7 @@ func (f *managerFactory) NewManager(cloud bicloud.Cloud, agentClient biagentclie
 		cloud,
 		f.uuidGenerator,
 		f.fs,
+		f.timeService,
 		f.logger,
 	)
 }
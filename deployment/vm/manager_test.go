@@ -2,8 +2,9 @@ package vm_test
 
 import (
 	"errors"
+	"time"
 
-	fakebiagentclient "github.com/cloudfoundry/bosh-agent/agentclient/fakes"
+	fakebiagentclient "github.com/cloudfoundry/bosh-init/agentclient/fakes"
 	"github.com/cloudfoundry/bosh-init/cloud"
 	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
@@ -18,6 +19,7 @@ import (
 	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("Manager", func() {
@@ -35,6 +37,7 @@ var _ = Describe("Manager", func() {
 		fakeAgentClient           *fakebiagentclient.FakeAgentClient
 		stemcell                  bistemcell.CloudStemcell
 		fs                        *fakesys.FakeFileSystem
+		fakeTimeService           *fakeclock.FakeClock
 	)
 
 	BeforeEach(func() {
@@ -43,6 +46,7 @@ var _ = Describe("Manager", func() {
 		fakeCloud = fakebicloud.NewFakeCloud()
 		fakeAgentClient = &fakebiagentclient.FakeAgentClient{}
 		fakeVMRepo = fakebiconfig.NewFakeVMRepo()
+		fakeTimeService = fakeclock.NewFakeClock(time.Now())
 
 		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
 		deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
@@ -56,6 +60,7 @@ var _ = Describe("Manager", func() {
 			fakeDiskDeployer,
 			fakeUUIDGenerator,
 			fs,
+			fakeTimeService,
 			logger,
 		).NewManager(fakeCloud, fakeAgentClient)
 
@@ -124,6 +129,7 @@ var _ = Describe("Manager", func() {
 				fakeAgentClient,
 				fakeCloud,
 				fs,
+				fakeTimeService,
 				logger,
 			)
 			Expect(vm).To(Equal(expectedVM))
@@ -159,6 +165,18 @@ var _ = Describe("Manager", func() {
 			Expect(fakeVMRepo.UpdateCurrentCID).To(Equal("fake-vm-cid"))
 		})
 
+		It("records the cloud configuration of the vm", func() {
+			_, err := manager.Create(stemcell, deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeVMRepo.UpdateCurrentConfigConfig).To(Equal(biconfig.VMConfigRecord{
+				StemcellCID:     "fake-stemcell-cid",
+				CloudProperties: expectedCloudProperties,
+				Networks:        expectedNetworkInterfaces,
+				Env:             expectedEnv,
+			}))
+		})
+
 		Context("when setting vm metadata fails", func() {
 			BeforeEach(func() {
 				fakeCloud.SetVMMetadataError = errors.New("fake-set-metadata-error")
@@ -201,4 +219,73 @@ var _ = Describe("Manager", func() {
 			})
 		})
 	})
+
+	Describe("ConfigChanges", func() {
+		var currentConfig biconfig.VMConfigRecord
+
+		BeforeEach(func() {
+			// as loaded back from the deployment state file
+			currentConfig = biconfig.VMConfigRecord{
+				StemcellCID: "fake-stemcell-cid",
+				CloudProperties: biproperty.Map{
+					"fake-cloud-property-key": "fake-cloud-property-value",
+				},
+				Networks: map[string]biproperty.Map{
+					"fake-network-name": biproperty.Map{
+						"type":             "dynamic",
+						"ip":               "fake-ip",
+						"cloud_properties": map[string]interface{}{},
+						"default":          []interface{}{"dns", "gateway"},
+					},
+				},
+				Env: biproperty.Map{
+					"fake-env-key": "fake-env-value",
+				},
+			}
+		})
+
+		It("returns no changes when the vm was created with the same configuration", func() {
+			fakeVMRepo.SetFindCurrentConfigBehavior(currentConfig, true, nil)
+
+			changes, err := manager.ConfigChanges(stemcell, deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+		})
+
+		It("returns the changed parts of the configuration", func() {
+			currentConfig.StemcellCID = "fake-old-stemcell-cid"
+			currentConfig.CloudProperties = biproperty.Map{"fake-cloud-property-key": "fake-old-value"}
+			currentConfig.Env = biproperty.Map{}
+			fakeVMRepo.SetFindCurrentConfigBehavior(currentConfig, true, nil)
+
+			changes, err := manager.ConfigChanges(stemcell, deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]string{"stemcell changed", "cloud properties changed", "env changed"}))
+		})
+
+		It("returns a change when the networks changed", func() {
+			currentConfig.Networks["fake-network-name"]["ip"] = "fake-old-ip"
+			fakeVMRepo.SetFindCurrentConfigBehavior(currentConfig, true, nil)
+
+			changes, err := manager.ConfigChanges(stemcell, deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]string{"networks changed"}))
+		})
+
+		It("returns a change when the configuration of the vm was not recorded", func() {
+			fakeVMRepo.SetFindCurrentConfigBehavior(biconfig.VMConfigRecord{}, false, nil)
+
+			changes, err := manager.ConfigChanges(stemcell, deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]string{"configuration of the current VM is unknown"}))
+		})
+
+		It("returns an error when finding the configuration fails", func() {
+			fakeVMRepo.SetFindCurrentConfigBehavior(biconfig.VMConfigRecord{}, false, errors.New("fake-find-error"))
+
+			_, err := manager.ConfigChanges(stemcell, deploymentManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-find-error"))
+		})
+	})
 })
@@ -5,6 +5,7 @@ import (
 
 	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biagent "github.com/cloudfoundry/bosh-init/agentclient"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
@@ -20,13 +21,22 @@ import (
 type VM interface {
 	CID() string
 	Exists() (bool, error)
-	AgentClient() biagentclient.AgentClient
-	WaitUntilReady(timeout time.Duration, delay time.Duration) error
+	AgentClient() biagent.AgentClient
+	// WaitUntilReady pings the agent until it responds, for at most timeout. It stops once interruptCh is closed.
+	WaitUntilReady(timeout time.Duration, delay time.Duration, interruptCh <-chan struct{}) error
 	Start() error
 	Stop() error
+	// Drain runs the drain scripts of the jobs before they are shut down and waits for them to finish, for at most maxWait.
+	// It stops waiting once interruptCh is closed.
+	Drain(maxWait time.Duration, interruptCh <-chan struct{}) error
+	// DrainForUpdate runs the drain scripts of the jobs before the new spec is applied, for at most maxWait.
+	// It stops waiting once interruptCh is closed.
+	DrainForUpdate(newSpec bias.ApplySpec, maxWait time.Duration, interruptCh <-chan struct{}) error
 	Apply(bias.ApplySpec) error
-	UpdateDisks(bideplmanifest.DiskPool, biui.Stage) ([]bidisk.Disk, error)
-	WaitToBeRunning(maxAttempts int, delay time.Duration) error
+	UpdateDisks([]bideplmanifest.PersistentDisk, biui.Stage) ([]bidisk.Disk, error)
+	// WaitToBeRunning asks the agent for the job state until the jobs are running. It stops once interruptCh is closed.
+	WaitToBeRunning(maxAttempts int, delay time.Duration, interruptCh <-chan struct{}) error
+	// AttachDisk attaches the disk and mounts it as the single persistent disk of the VM
 	AttachDisk(bidisk.Disk) error
 	DetachDisk(bidisk.Disk) error
 	Disks() ([]bidisk.Disk, error)
@@ -35,6 +45,8 @@ type VM interface {
 	RunScript(script string, options map[string]interface{}) error
 	Delete() error
 	GetState() (biagentclient.AgentState, error)
+	// GetFullState returns the state of the agent including its processes, vitals and last applied spec
+	GetFullState() (biagent.AgentState, error)
 }
 
 type vm struct {
@@ -42,9 +54,10 @@ type vm struct {
 	vmRepo       biconfig.VMRepo
 	stemcellRepo biconfig.StemcellRepo
 	diskDeployer DiskDeployer
-	agentClient  biagentclient.AgentClient
+	agentClient  biagent.AgentClient
 	cloud        bicloud.Cloud
 	fs           boshsys.FileSystem
+	timeService  clock.Clock
 	logger       boshlog.Logger
 	logTag       string
 }
@@ -54,9 +67,10 @@ func NewVM(
 	vmRepo biconfig.VMRepo,
 	stemcellRepo biconfig.StemcellRepo,
 	diskDeployer DiskDeployer,
-	agentClient biagentclient.AgentClient,
+	agentClient biagent.AgentClient,
 	cloud bicloud.Cloud,
 	fs boshsys.FileSystem,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) VM {
 	return &vm{
@@ -67,6 +81,7 @@ func NewVM(
 		agentClient:  agentClient,
 		cloud:        cloud,
 		fs:           fs,
+		timeService:  timeService,
 		logger:       logger,
 		logTag:       "vm",
 	}
@@ -84,14 +99,13 @@ func (vm *vm) Exists() (bool, error) {
 	return exists, nil
 }
 
-func (vm *vm) AgentClient() biagentclient.AgentClient {
+func (vm *vm) AgentClient() biagent.AgentClient {
 	return vm.agentClient
 }
 
-func (vm *vm) WaitUntilReady(timeout time.Duration, delay time.Duration) error {
-	agentPingRetryable := biagentclient.NewPingRetryable(vm.agentClient)
-	timeService := clock.NewClock() //TODO: inject timeService
-	agentPingRetryStrategy := boshretry.NewTimeoutRetryStrategy(timeout, delay, agentPingRetryable, timeService, vm.logger)
+func (vm *vm) WaitUntilReady(timeout time.Duration, delay time.Duration, interruptCh <-chan struct{}) error {
+	agentPingRetryable := newInterruptibleRetryable(biagentclient.NewPingRetryable(vm.agentClient), interruptCh, "waiting for the agent")
+	agentPingRetryStrategy := boshretry.NewTimeoutRetryStrategy(timeout, delay, agentPingRetryable, vm.timeService, vm.logger)
 	return agentPingRetryStrategy.Try()
 }
 
@@ -115,6 +129,67 @@ func (vm *vm) Stop() error {
 	return nil
 }
 
+func (vm *vm) Drain(maxWait time.Duration, interruptCh <-chan struct{}) error {
+	return vm.drain(maxWait, interruptCh, "shutdown")
+}
+
+func (vm *vm) DrainForUpdate(newSpec bias.ApplySpec, maxWait time.Duration, interruptCh <-chan struct{}) error {
+	return vm.drain(maxWait, interruptCh, "update", newSpec)
+}
+
+func (vm *vm) drain(maxWait time.Duration, interruptCh <-chan struct{}, drainType string, newSpec ...bias.ApplySpec) error {
+	deadline := vm.timeService.Now().Add(maxWait)
+
+	vm.logger.Debug(vm.logTag, "Draining jobs for %s", drainType)
+	waitTime, err := vm.agentClient.Drain(drainType, newSpec...)
+	if err != nil {
+		return bosherr.WrapError(err, "Draining jobs")
+	}
+
+	// a negative wait time means the drain script is dynamic and has to be asked for its status after waiting
+	for waitTime < 0 {
+		deadlineReached, err := vm.waitForDrain(-waitTime, deadline, interruptCh)
+		if err != nil || deadlineReached {
+			return err
+		}
+
+		vm.logger.Debug(vm.logTag, "Checking drain status")
+		waitTime, err = vm.agentClient.Drain("status")
+		if err != nil {
+			return bosherr.WrapError(err, "Checking drain status")
+		}
+	}
+
+	_, err = vm.waitForDrain(waitTime, deadline, interruptCh)
+
+	return err
+}
+
+// waitForDrain waits for the given number of seconds, but not past the deadline and not after interruptCh is closed.
+// Returns true if the deadline was reached.
+func (vm *vm) waitForDrain(seconds int64, deadline time.Time, interruptCh <-chan struct{}) (bool, error) {
+	wait := time.Duration(seconds) * time.Second
+	remaining := deadline.Sub(vm.timeService.Now())
+	deadlineReached := wait > remaining
+	if deadlineReached {
+		vm.logger.Warn(vm.logTag, "Drain wants to wait %s, but the maximum drain wait leaves %s; continuing after that", wait, remaining)
+		if remaining <= 0 {
+			return true, nil
+		}
+		wait = remaining
+	}
+
+	timer := vm.timeService.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return deadlineReached, nil
+	case <-interruptCh:
+		return true, biui.NewInterruptedWaitError("waiting for the drain scripts")
+	}
+}
+
 func (vm *vm) Apply(newState bias.ApplySpec) error {
 	vm.logger.Debug(vm.logTag, "Sending apply message to the agent with '%#v'", newState)
 	err := vm.agentClient.Apply(newState)
@@ -125,16 +200,16 @@ func (vm *vm) Apply(newState bias.ApplySpec) error {
 	return nil
 }
 
-func (vm *vm) UpdateDisks(diskPool bideplmanifest.DiskPool, eventLoggerStage biui.Stage) ([]bidisk.Disk, error) {
-	disks, err := vm.diskDeployer.Deploy(diskPool, vm.cloud, vm, eventLoggerStage)
+func (vm *vm) UpdateDisks(persistentDisks []bideplmanifest.PersistentDisk, eventLoggerStage biui.Stage) ([]bidisk.Disk, error) {
+	disks, err := vm.diskDeployer.Deploy(persistentDisks, vm.cloud, vm, eventLoggerStage)
 	if err != nil {
 		return disks, bosherr.WrapError(err, "Deploying disk")
 	}
 	return disks, nil
 }
 
-func (vm *vm) WaitToBeRunning(maxAttempts int, delay time.Duration) error {
-	agentGetStateRetryable := biagentclient.NewGetStateRetryable(vm.agentClient)
+func (vm *vm) WaitToBeRunning(maxAttempts int, delay time.Duration, interruptCh <-chan struct{}) error {
+	agentGetStateRetryable := newInterruptibleRetryable(biagentclient.NewGetStateRetryable(vm.agentClient), interruptCh, "waiting for the jobs to be running")
 	agentGetStateRetryStrategy := boshretry.NewAttemptRetryStrategy(maxAttempts, delay, agentGetStateRetryable, vm.logger)
 	return agentGetStateRetryStrategy.Try()
 }
@@ -223,3 +298,12 @@ func (vm *vm) GetState() (biagentclient.AgentState, error) {
 
 	return agentState, nil
 }
+
+func (vm *vm) GetFullState() (biagent.AgentState, error) {
+	agentState, err := vm.agentClient.GetFullState()
+	if err != nil {
+		return agentState, bosherr.WrapError(err, "Getting full vm state")
+	}
+
+	return agentState, nil
+}
@@ -2,6 +2,7 @@ package vm_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/cloudfoundry/bosh-init/deployment/vm"
 	. "github.com/onsi/ginkgo"
@@ -13,11 +14,13 @@ import (
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	biui "github.com/cloudfoundry/bosh-init/ui"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
 
-	fakebiagentclient "github.com/cloudfoundry/bosh-agent/agentclient/fakes"
+	fakebiagentclient "github.com/cloudfoundry/bosh-init/agentclient/fakes"
 	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
 	fakebiconfig "github.com/cloudfoundry/bosh-init/config/fakes"
 	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
@@ -34,8 +37,9 @@ var _ = Describe("VM", func() {
 		fakeAgentClient  *fakebiagentclient.FakeAgentClient
 		fakeCloud        *fakebicloud.FakeCloud
 		applySpec        bias.ApplySpec
-		diskPool         bideplmanifest.DiskPool
+		persistentDisks  []bideplmanifest.PersistentDisk
 		fs               *fakesys.FakeFileSystem
+		fakeTimeService  *fakeclock.FakeClock
 		logger           boshlog.Logger
 	)
 
@@ -47,11 +51,16 @@ var _ = Describe("VM", func() {
 			Deployment: "fake-deployment-name",
 		}
 
-		diskPool = bideplmanifest.DiskPool{
-			Name:     "fake-persistent-disk-pool-name",
-			DiskSize: 1024,
-			CloudProperties: biproperty.Map{
-				"fake-disk-pool-cloud-property-key": "fake-disk-pool-cloud-property-value",
+		persistentDisks = []bideplmanifest.PersistentDisk{
+			{
+				Name: "fake-disk-name",
+				DiskPool: bideplmanifest.DiskPool{
+					Name:     "fake-persistent-disk-pool-name",
+					DiskSize: 1024,
+					CloudProperties: biproperty.Map{
+						"fake-disk-pool-cloud-property-key": "fake-disk-pool-cloud-property-value",
+					},
+				},
 			},
 		}
 
@@ -61,6 +70,7 @@ var _ = Describe("VM", func() {
 		fakeVMRepo = fakebiconfig.NewFakeVMRepo()
 		fakeStemcellRepo = fakebiconfig.NewFakeStemcellRepo()
 		fakeDiskDeployer = fakebivm.NewFakeDiskDeployer()
+		fakeTimeService = fakeclock.NewFakeClock(time.Now())
 		vm = NewVM(
 			"fake-vm-cid",
 			fakeVMRepo,
@@ -69,6 +79,7 @@ var _ = Describe("VM", func() {
 			fakeAgentClient,
 			fakeCloud,
 			fs,
+			fakeTimeService,
 			logger,
 		)
 	})
@@ -111,13 +122,13 @@ var _ = Describe("VM", func() {
 		It("delegates to DiskDeployer.Deploy", func() {
 			fakeStage := fakebiui.NewFakeStage()
 
-			disks, err := vm.UpdateDisks(diskPool, fakeStage)
+			disks, err := vm.UpdateDisks(persistentDisks, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(disks).To(Equal(expectedDisks))
 
 			Expect(fakeDiskDeployer.DeployInputs).To(Equal([]fakebivm.DeployInput{
 				{
-					DiskPool:         diskPool,
+					PersistentDisks:  persistentDisks,
 					Cloud:            fakeCloud,
 					VM:               vm,
 					EventLoggerStage: fakeStage,
@@ -146,6 +157,137 @@ var _ = Describe("VM", func() {
 		})
 	})
 
+	Describe("Drain", func() {
+		var (
+			maxWait     time.Duration
+			drainDone   chan error
+			interruptCh chan struct{}
+		)
+
+		BeforeEach(func() {
+			maxWait = 10 * time.Minute
+			drainDone = make(chan error, 1)
+			interruptCh = make(chan struct{})
+		})
+
+		var startDrain = func() {
+			go func() {
+				drainDone <- vm.Drain(maxWait, interruptCh)
+			}()
+		}
+
+		var waitFor = func(d time.Duration) {
+			Eventually(fakeTimeService.WatcherCount).Should(Equal(1))
+			fakeTimeService.Increment(d)
+			Eventually(fakeTimeService.WatcherCount).Should(Equal(0))
+		}
+
+		It("runs the drain scripts and waits for the time they return", func() {
+			fakeAgentClient.DrainReturns(5, nil)
+
+			startDrain()
+			waitFor(5 * time.Second)
+
+			Eventually(drainDone).Should(Receive(BeNil()))
+			Expect(fakeAgentClient.DrainCallCount()).To(Equal(1))
+			Expect(fakeAgentClient.DrainArgsForCall(0)).To(Equal("shutdown"))
+		})
+
+		It("polls the drain status while the drain scripts return negative wait times", func() {
+			waitTimes := []int64{-2, -3, 0}
+			fakeAgentClient.DrainStub = func(drainType string, _ ...bias.ApplySpec) (int64, error) {
+				waitTime := waitTimes[0]
+				waitTimes = waitTimes[1:]
+				return waitTime, nil
+			}
+
+			startDrain()
+			waitFor(2 * time.Second)
+			waitFor(3 * time.Second)
+
+			Eventually(drainDone).Should(Receive(BeNil()))
+			Expect(fakeAgentClient.DrainCallCount()).To(Equal(3))
+			Expect(fakeAgentClient.DrainArgsForCall(0)).To(Equal("shutdown"))
+			Expect(fakeAgentClient.DrainArgsForCall(1)).To(Equal("status"))
+			Expect(fakeAgentClient.DrainArgsForCall(2)).To(Equal("status"))
+		})
+
+		It("does not wait longer than the maximum drain wait", func() {
+			fakeAgentClient.DrainReturns(-300, nil)
+
+			startDrain()
+			waitFor(5 * time.Minute)
+			waitFor(5 * time.Minute)
+
+			Eventually(drainDone).Should(Receive(BeNil()))
+			Expect(fakeAgentClient.DrainCallCount()).To(Equal(3))
+		})
+
+		It("stops waiting for the drain scripts when interrupted", func() {
+			fakeAgentClient.DrainReturns(-300, nil)
+
+			startDrain()
+			Eventually(fakeTimeService.WatcherCount).Should(Equal(1))
+			close(interruptCh)
+
+			var err error
+			Eventually(drainDone).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Interrupted while waiting for the drain scripts"))
+			Expect(fakeAgentClient.DrainCallCount()).To(Equal(1))
+			Expect(fakeTimeService.WatcherCount()).To(Equal(0))
+		})
+
+		Context("when draining fails", func() {
+			BeforeEach(func() {
+				fakeAgentClient.DrainReturns(0, errors.New("fake-drain-error"))
+			})
+
+			It("returns an error", func() {
+				err := vm.Drain(maxWait, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+			})
+		})
+	})
+
+	Describe("DrainForUpdate", func() {
+		It("runs the drain scripts with the spec that will be applied", func() {
+			newSpec := bias.ApplySpec{Deployment: "fake-deployment-name"}
+			fakeAgentClient.DrainReturns(0, nil)
+
+			err := vm.DrainForUpdate(newSpec, 10*time.Minute, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeAgentClient.DrainCallCount()).To(Equal(1))
+			drainType, specs := fakeAgentClient.DrainArgsForCall(0)
+			Expect(drainType).To(Equal("update"))
+			Expect(specs).To(Equal([]bias.ApplySpec{newSpec}))
+		})
+
+		It("polls the drain status while the drain scripts return negative wait times", func() {
+			waitTimes := []int64{-2, 0}
+			fakeAgentClient.DrainStub = func(drainType string, _ ...bias.ApplySpec) (int64, error) {
+				waitTime := waitTimes[0]
+				waitTimes = waitTimes[1:]
+				return waitTime, nil
+			}
+
+			drainDone := make(chan error, 1)
+			go func() {
+				drainDone <- vm.DrainForUpdate(bias.ApplySpec{}, 10*time.Minute, nil)
+			}()
+			Eventually(fakeTimeService.WatcherCount).Should(Equal(1))
+			fakeTimeService.Increment(2 * time.Second)
+
+			Eventually(drainDone).Should(Receive(BeNil()))
+			Expect(fakeAgentClient.DrainCallCount()).To(Equal(2))
+			drainType, specs := fakeAgentClient.DrainArgsForCall(1)
+			Expect(drainType).To(Equal("status"))
+			Expect(specs).To(BeEmpty())
+		})
+	})
+
 	Describe("Apply", func() {
 		It("sends apply spec to the agent", func() {
 			err := vm.Apply(applySpec)
@@ -189,6 +331,7 @@ var _ = Describe("VM", func() {
 	Describe("WaitToBeRunning", func() {
 		var invocations int
 		BeforeEach(func() {
+			invocations = 0
 			responses := []struct {
 				state biagentclient.AgentState
 				err   error
@@ -205,10 +348,21 @@ var _ = Describe("VM", func() {
 		})
 
 		It("waits until agent reports state as running", func() {
-			err := vm.WaitToBeRunning(5, 0)
+			err := vm.WaitToBeRunning(5, 0, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(invocations).To(Equal(3))
 		})
+
+		It("stops waiting once interrupted", func() {
+			interruptCh := make(chan struct{})
+			close(interruptCh)
+
+			err := vm.WaitToBeRunning(5, 0, interruptCh)
+			Expect(err).To(HaveOccurred())
+			Expect(biui.IsInterrupted(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("Interrupted while waiting for the jobs to be running"))
+			Expect(invocations).To(Equal(0))
+		})
 	})
 
 	Describe("AttachDisk", func() {
@@ -3,14 +3,26 @@ package index
 import (
 	"encoding/json"
 	"reflect"
+	"sync"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+// FileIndex is an Index persisted as a JSON file.
+// The file is read once, on first use, and rewritten atomically (temp file + rename) on every Save,
+// so that a crash never leaves a partially written index behind.
+// Concurrent use of a FileIndex is safe; concurrent processes must be kept apart by the
+// installation lock, otherwise the last one to save wins.
 type FileIndex struct {
 	path string
 	fs   boshsys.FileSystem
+
+	lock    sync.Mutex
+	loaded  bool
+	entries []indexEntry
+	// positions maps the canonical JSON of each key to its position in entries
+	positions map[string]int
 }
 
 type indexEntry struct {
@@ -18,42 +30,42 @@ type indexEntry struct {
 	Value json.RawMessage
 }
 
-func NewFileIndex(path string, fs boshsys.FileSystem) FileIndex {
-	return FileIndex{path: path, fs: fs}
+func NewFileIndex(path string, fs boshsys.FileSystem) *FileIndex {
+	return &FileIndex{path: path, fs: fs}
 }
 
-func (ri FileIndex) Find(key interface{}, value interface{}) error {
-	rawEntries, err := ri.readRawEntries()
+func (ri *FileIndex) Find(key interface{}, value interface{}) error {
+	ri.lock.Lock()
+	defer ri.lock.Unlock()
+
+	err := ri.load()
 	if err != nil {
 		return err
 	}
 
-	rawKey, err := ri.structToMap(key)
+	keyID, _, err := ri.keyID(key)
 	if err != nil {
 		return err
 	}
 
-	for _, rawEntry := range rawEntries {
-		if reflect.DeepEqual(rawEntry.Key, rawKey) {
-			err := json.Unmarshal(rawEntry.Value, value)
-			if err != nil {
-				return err
-			}
-
-			return nil
-		}
+	i, found := ri.positions[keyID]
+	if !found {
+		return ErrNotFound
 	}
 
-	return ErrNotFound
+	return json.Unmarshal(ri.entries[i].Value, value)
 }
 
-func (ri FileIndex) Save(key interface{}, value interface{}) error {
-	rawEntries, err := ri.readRawEntries()
+func (ri *FileIndex) Save(key interface{}, value interface{}) error {
+	ri.lock.Lock()
+	defer ri.lock.Unlock()
+
+	err := ri.load()
 	if err != nil {
 		return err
 	}
 
-	rawKey, err := ri.structToMap(key)
+	keyID, rawKey, err := ri.keyID(key)
 	if err != nil {
 		return err
 	}
@@ -63,33 +75,74 @@ func (ri FileIndex) Save(key interface{}, value interface{}) error {
 		return err
 	}
 
-	foundI := -1
+	entries := make([]indexEntry, len(ri.entries), len(ri.entries)+1)
+	copy(entries, ri.entries)
 
-	for i, rawEntry := range rawEntries {
-		if reflect.DeepEqual(rawEntry.Key, rawKey) {
-			foundI = i
-			break
-		}
-	}
-
-	if foundI >= 0 {
-		rawEntries[foundI].Value = rawValue
+	i, found := ri.positions[keyID]
+	if found {
+		entries[i].Value = rawValue
 	} else {
-		rawEntries = append(rawEntries, indexEntry{
+		i = len(entries)
+		entries = append(entries, indexEntry{
 			Key:   rawKey,
 			Value: rawValue,
 		})
 	}
 
-	err = ri.writeRawEntries(rawEntries)
+	err = ri.writeRawEntries(entries)
 	if err != nil {
 		return err
 	}
 
+	ri.entries = entries
+	ri.positions[keyID] = i
+
 	return nil
 }
 
-func (ri FileIndex) readRawEntries() ([]indexEntry, error) {
+func (ri *FileIndex) load() error {
+	if ri.loaded {
+		return nil
+	}
+
+	entries, err := ri.readRawEntries()
+	if err != nil {
+		return err
+	}
+
+	positions := map[string]int{}
+	for i, entry := range entries {
+		keyBytes, err := json.Marshal(entry.Key)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Marshalling index key %#v", entry.Key)
+		}
+		positions[string(keyBytes)] = i
+	}
+
+	ri.entries = entries
+	ri.positions = positions
+	ri.loaded = true
+
+	return nil
+}
+
+// keyID returns the key as it is stored in the index file (a map of field names to values)
+// and its canonical JSON, which does not depend on the order of the fields
+func (ri *FileIndex) keyID(key interface{}) (string, map[string]interface{}, error) {
+	rawKey, err := ri.structToMap(key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	keyBytes, err := json.Marshal(rawKey)
+	if err != nil {
+		return "", nil, bosherr.WrapErrorf(err, "Marshalling index key %#v", key)
+	}
+
+	return string(keyBytes), rawKey, nil
+}
+
+func (ri *FileIndex) readRawEntries() ([]indexEntry, error) {
 	var entries []indexEntry
 
 	if ri.fs.FileExists(ri.path) {
@@ -107,28 +160,34 @@ func (ri FileIndex) readRawEntries() ([]indexEntry, error) {
 	return entries, nil
 }
 
-func (ri FileIndex) writeRawEntries(entries []indexEntry) error {
+func (ri *FileIndex) writeRawEntries(entries []indexEntry) error {
 	bytes, err := json.Marshal(entries)
 	if err != nil {
 		return bosherr.WrapError(err, "Marshalling index entries")
 	}
 
-	err = ri.fs.WriteFile(ri.path, bytes)
+	tmpPath := ri.path + ".tmp"
+
+	err = ri.fs.WriteFile(tmpPath, bytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing index file %s", tmpPath)
+	}
+
+	err = ri.fs.Rename(tmpPath, ri.path)
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Writing index file %s", ri.path)
+		return bosherr.WrapErrorf(err, "Replacing index file %s", ri.path)
 	}
 
 	return nil
 }
 
-func (ri FileIndex) structToMap(s interface{}) (map[string]interface{}, error) {
+func (ri *FileIndex) structToMap(s interface{}) (map[string]interface{}, error) {
 	res := map[string]interface{}{}
 	st := reflect.TypeOf(s)
 	stv := reflect.ValueOf(s)
 
 	if stv.Kind() != reflect.Struct {
-		return res, bosherr.Errorf(
-			"Must be reflect.Struct: %#v (%#v)", stv, ri.kindToStr(stv.Kind()))
+		return res, bosherr.Errorf("Must be reflect.Struct: %#v (%s)", stv, stv.Kind())
 	}
 
 	for i := 0; i < st.NumField(); i++ {
@@ -137,77 +196,3 @@ func (ri FileIndex) structToMap(s interface{}) (map[string]interface{}, error) {
 
 	return res, nil
 }
-
-func (ri FileIndex) mapToStruct(m map[string]interface{}, t interface{}) (reflect.Value, error) {
-	return ri.mapToNewStruct(m, reflect.ValueOf(t).Elem().Type())
-}
-
-func (ri FileIndex) mapToStructFromSlice(m map[string]interface{}, t interface{}) (reflect.Value, error) {
-	slice := reflect.ValueOf(t).Elem()
-
-	if slice.Kind() != reflect.Slice {
-		return reflect.Value{}, bosherr.Errorf(
-			"Must be reflect.Slice: %#v (%#v)",
-			slice, ri.kindToStr(slice.Kind()),
-		)
-	}
-
-	return ri.mapToNewStruct(m, slice.Type().Elem())
-}
-
-func (ri FileIndex) mapToNewStruct(m map[string]interface{}, t reflect.Type) (reflect.Value, error) {
-	if t.Kind() != reflect.Struct {
-		return reflect.Value{}, bosherr.Errorf(
-			"Must be reflect.Struct: %#v (%#v)",
-			t, ri.kindToStr(t.Kind()),
-		)
-	}
-
-	newStruct := reflect.New(t).Elem()
-
-	for k, v := range m {
-		f := newStruct.FieldByName(k)
-		if f.IsValid() && f.CanSet() {
-			// todo float64 -> int
-			// todo pointer values
-			// todo slices
-			f.Set(reflect.ValueOf(v))
-		}
-	}
-
-	return newStruct, nil
-}
-
-var kindToStrMap = map[reflect.Kind]string{
-	reflect.Invalid:       "Invalid",
-	reflect.Bool:          "Bool",
-	reflect.Int:           "Int",
-	reflect.Int8:          "Int8",
-	reflect.Int16:         "Int16",
-	reflect.Int32:         "Int32",
-	reflect.Int64:         "Int64",
-	reflect.Uint:          "Uint",
-	reflect.Uint8:         "Uint8",
-	reflect.Uint16:        "Uint16",
-	reflect.Uint32:        "Uint32",
-	reflect.Uint64:        "Uint64",
-	reflect.Uintptr:       "Uintptr",
-	reflect.Float32:       "Float32",
-	reflect.Float64:       "Float64",
-	reflect.Complex64:     "Complex64",
-	reflect.Complex128:    "Complex128",
-	reflect.Array:         "Array",
-	reflect.Chan:          "Chan",
-	reflect.Func:          "Func",
-	reflect.Interface:     "Interface",
-	reflect.Map:           "Map",
-	reflect.Ptr:           "Ptr",
-	reflect.Slice:         "Slice",
-	reflect.String:        "String",
-	reflect.Struct:        "Struct",
-	reflect.UnsafePointer: "UnsafePointer",
-}
-
-func (ri FileIndex) kindToStr(k reflect.Kind) string {
-	return kindToStrMap[k]
-}
@@ -1,6 +1,8 @@
 package index_test
 
 import (
+	"fmt"
+
 	. "github.com/cloudfoundry/bosh-init/index"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -12,7 +14,7 @@ var _ = Describe("FileIndex", func() {
 	var (
 		fs            boshsys.FileSystem
 		indexFilePath string
-		index         FileIndex
+		index         *FileIndex
 	)
 
 	BeforeEach(func() {
@@ -150,7 +152,7 @@ var _ = Describe("FileIndex", func() {
 
 		Context("when a new FileIndex is constructed backed by the same file", func() {
 			var (
-				index2 FileIndex
+				index2 *FileIndex
 			)
 
 			BeforeEach(func() {
@@ -170,5 +172,56 @@ var _ = Describe("FileIndex", func() {
 				Expect(value).To(Equal(Value{Name: "value-1", Count: 1}))
 			})
 		})
+
+		It("updates the value of an existing key", func() {
+			err := index.Save(Key{Key: "key-1"}, Value{Name: "value-1", Count: 1})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = index.Save(Key{Key: "key-1"}, Value{Name: "value-2", Count: 2})
+			Expect(err).ToNot(HaveOccurred())
+
+			var value Value
+			err = NewFileIndex(indexFilePath, fs).Find(Key{Key: "key-1"}, &value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(Value{Name: "value-2", Count: 2}))
+		})
+
+		It("finds entries in an existing index file", func() {
+			err := fs.WriteFileString(indexFilePath, `[{"Key":{"Key":"key-1"},"Value":{"Name":"value-1","Count":1}}]`)
+			Expect(err).ToNot(HaveOccurred())
+
+			var value Value
+			err = index.Find(Key{Key: "key-1"}, &value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(Value{Name: "value-1", Count: 1}))
+		})
+
+		It("replaces the index file without leaving a temporary file behind", func() {
+			err := index.Save(Key{Key: "key-1"}, Value{Name: "value-1", Count: 1})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists(indexFilePath)).To(BeTrue())
+			Expect(fs.FileExists(indexFilePath + ".tmp")).To(BeFalse())
+		})
+
+		It("keeps all entries saved concurrently", func() {
+			done := make(chan error)
+			for i := 0; i < 20; i++ {
+				go func(i int) {
+					done <- index.Save(Key{Key: fmt.Sprintf("key-%d", i)}, Value{Name: "value", Count: float64(i)})
+				}(i)
+			}
+			for i := 0; i < 20; i++ {
+				Expect(<-done).ToNot(HaveOccurred())
+			}
+
+			index2 := NewFileIndex(indexFilePath, fs)
+			for i := 0; i < 20; i++ {
+				var value Value
+				err := index2.Find(Key{Key: fmt.Sprintf("key-%d", i)}, &value)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal(Value{Name: "value", Count: float64(i)}))
+			}
+		})
 	})
 })
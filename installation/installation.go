@@ -11,6 +11,10 @@ import (
 type Installation interface {
 	Target() Target
 	Job() InstalledJob
+	// CloudProviderJob returns the CPI job of the named cloud provider, or the default one for an empty name
+	CloudProviderJob(name string) (InstalledJob, bool)
+	// CloudProviders returns the names of the named cloud providers
+	CloudProviders() []string
 	WithRunningRegistry(boshlog.Logger, biui.Stage, func() error) error
 	StartRegistry() error
 	StopRegistry() error
@@ -19,6 +23,7 @@ type Installation interface {
 type installation struct {
 	target                Target
 	job                   InstalledJob
+	cloudProviderJobs     map[string]InstalledJob
 	manifest              biinstallmanifest.Manifest
 	registryServerManager biregistry.ServerManager
 
@@ -28,12 +33,14 @@ type installation struct {
 func NewInstallation(
 	target Target,
 	job InstalledJob,
+	cloudProviderJobs map[string]InstalledJob,
 	manifest biinstallmanifest.Manifest,
 	registryServerManager biregistry.ServerManager,
 ) Installation {
 	return &installation{
 		target:                target,
 		job:                   job,
+		cloudProviderJobs:     cloudProviderJobs,
 		manifest:              manifest,
 		registryServerManager: registryServerManager,
 	}
@@ -47,6 +54,23 @@ func (i *installation) Job() InstalledJob {
 	return i.job
 }
 
+func (i *installation) CloudProviderJob(name string) (InstalledJob, bool) {
+	if name == "" {
+		return i.job, true
+	}
+
+	job, found := i.cloudProviderJobs[name]
+	return job, found
+}
+
+func (i *installation) CloudProviders() []string {
+	names := []string{}
+	for _, cloudProvider := range i.manifest.CloudProviders {
+		names = append(names, cloudProvider.Name)
+	}
+	return names
+}
+
 func (i *installation) WithRunningRegistry(logger boshlog.Logger, stage biui.Stage, fn func() error) error {
 	err := stage.Perform("Starting registry", func() error {
 		return i.StartRegistry()
@@ -59,7 +83,8 @@ func (i *installation) WithRunningRegistry(logger boshlog.Logger, stage biui.Sta
 }
 
 func (i *installation) stopRegistryNice(logger boshlog.Logger, stage biui.Stage) {
-	err := stage.Perform("Stopping registry", func() error {
+	// the registry is stopped even when interrupted
+	err := biui.Uninterruptible(stage).Perform("Stopping registry", func() error {
 		return i.StopRegistry()
 	})
 	if err != nil {
@@ -73,7 +98,7 @@ func (i *installation) StartRegistry() error {
 			return bosherr.Error("Registry already started")
 		}
 		config := i.manifest.Registry
-		registryServer, err := i.registryServerManager.Start(config.Username, config.Password, config.Host, config.Port)
+		registryServer, err := i.registryServerManager.Start(config.ServerConfig())
 		if err != nil {
 			return bosherr.WrapError(err, "Starting registry")
 		}
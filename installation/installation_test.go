@@ -7,6 +7,7 @@ import (
 	. "github.com/cloudfoundry/bosh-init/installation"
 
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	mock_registry "github.com/cloudfoundry/bosh-init/registry/mocks"
 	test_support_mocks "github.com/cloudfoundry/bosh-init/test_support/mocks"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
@@ -38,7 +39,7 @@ var _ = Describe("Installation", func() {
 	)
 
 	var newInstalation = func() Installation {
-		return NewInstallation(target, installedJob, manifest, mockRegistryServerManager)
+		return NewInstallation(target, installedJob, map[string]InstalledJob{}, manifest, mockRegistryServerManager)
 	}
 
 	BeforeEach(func() {
@@ -70,7 +71,7 @@ var _ = Describe("Installation", func() {
 			}
 
 			spy = test_support_mocks.NewMockSpy(mockCtrl)
-			startCall = mockRegistryServerManager.EXPECT().Start("fake-username", "fake-password", "fake-host", 123).Return(mockRegistryServer, nil)
+			startCall = mockRegistryServerManager.EXPECT().Start(biregistry.ServerConfig{Username: "fake-username", Password: "fake-password", Host: "fake-host", Port: 123}).Return(mockRegistryServer, nil)
 			spyCall = spy.EXPECT().Record()
 			stopCall = mockRegistryServer.EXPECT().Stop().Return(nil)
 
@@ -184,7 +185,7 @@ var _ = Describe("Installation", func() {
 			})
 
 			It("starts the registry", func() {
-				mockRegistryServerManager.EXPECT().Start("fake-username", "fake-password", "fake-host", 123).Return(mockRegistryServer, nil)
+				mockRegistryServerManager.EXPECT().Start(biregistry.ServerConfig{Username: "fake-username", Password: "fake-password", Host: "fake-host", Port: 123}).Return(mockRegistryServer, nil)
 
 				err := newInstalation().StartRegistry()
 				Expect(err).NotTo(HaveOccurred())
@@ -192,7 +193,7 @@ var _ = Describe("Installation", func() {
 
 			Context("when starting registry fails", func() {
 				BeforeEach(func() {
-					mockRegistryServerManager.EXPECT().Start("fake-username", "fake-password", "fake-host", 123).Return(nil, errors.New("fake-registry-start-error"))
+					mockRegistryServerManager.EXPECT().Start(biregistry.ServerConfig{Username: "fake-username", Password: "fake-password", Host: "fake-host", Port: 123}).Return(nil, errors.New("fake-registry-start-error"))
 				})
 
 				It("returns an error", func() {
@@ -229,7 +230,7 @@ var _ = Describe("Installation", func() {
 
 				installation = newInstalation()
 
-				mockRegistryServerManager.EXPECT().Start("fake-username", "fake-password", "fake-host", 123).Return(mockRegistryServer, nil)
+				mockRegistryServerManager.EXPECT().Start(biregistry.ServerConfig{Username: "fake-username", Password: "fake-password", Host: "fake-host", Port: 123}).Return(mockRegistryServer, nil)
 				err := installation.StartRegistry()
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -8,6 +8,7 @@ import (
 	"github.com/cloudfoundry/bosh-init/installation/blobextract"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	biregistry "github.com/cloudfoundry/bosh-init/registry"
+	bireljob "github.com/cloudfoundry/bosh-init/release/job"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -68,7 +69,17 @@ func (i *installer) Install(manifest biinstallmanifest.Manifest, stage biui.Stag
 		return nil, bosherr.WrapError(err, "Resolving jobs from manifest")
 	}
 
-	compiledPackages, err := i.packageCompiler.For(jobs, stage)
+	allJobs := jobs
+	cloudProviderJobs := make([][]bireljob.Job, len(manifest.CloudProviders))
+	for index, cloudProvider := range manifest.CloudProviders {
+		cloudProviderJobs[index], err = i.jobResolver.From(manifest.WithCloudProvider(cloudProvider))
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Resolving jobs of cloud provider '%s' from manifest", cloudProvider.Name)
+		}
+		allJobs = append(allJobs, cloudProviderJobs[index]...)
+	}
+
+	compiledPackages, err := i.packageCompiler.For(allJobs, stage)
 	if err != nil {
 		return nil, err
 	}
@@ -80,20 +91,29 @@ func (i *installer) Install(manifest biinstallmanifest.Manifest, stage biui.Stag
 		return nil, err
 	}
 
-	renderedJobRefs, err := i.jobRenderer.RenderAndUploadFrom(manifest, jobs, stage)
+	installedJob, err := i.renderAndInstallJob(manifest, jobs, i.target.JobsPath(), stage)
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Rendering and uploading Jobs")
+		return nil, err
 	}
 
-	renderedCPIJob := renderedJobRefs[0]
-	installedJob, err := i.installJob(renderedCPIJob, stage)
-	if err != nil {
-		return nil, bosherr.WrapErrorf(err, "Installing job '%s' for CPI release", renderedCPIJob.Name)
+	installedCloudProviderJobs := map[string]InstalledJob{}
+	for index, cloudProvider := range manifest.CloudProviders {
+		installedCloudProviderJob, err := i.renderAndInstallJob(
+			manifest.WithCloudProvider(cloudProvider),
+			cloudProviderJobs[index],
+			i.target.CloudProviderJobsPath(cloudProvider.Name),
+			stage,
+		)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Installing cloud provider '%s'", cloudProvider.Name)
+		}
+		installedCloudProviderJobs[cloudProvider.Name] = installedCloudProviderJob
 	}
 
 	return NewInstallation(
 		i.target,
 		installedJob,
+		installedCloudProviderJobs,
 		manifest,
 		i.registryServerManager,
 	), nil
@@ -101,7 +121,35 @@ func (i *installer) Install(manifest biinstallmanifest.Manifest, stage biui.Stag
 
 func (i *installer) Cleanup(installation Installation) error {
 	job := installation.Job()
-	return i.blobExtractor.Cleanup(job.BlobstoreID, job.Path)
+	err := i.blobExtractor.Cleanup(job.BlobstoreID, job.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, cloudProvider := range installation.CloudProviders() {
+		job, _ := installation.CloudProviderJob(cloudProvider)
+		err = i.blobExtractor.Cleanup(job.BlobstoreID, job.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *installer) renderAndInstallJob(manifest biinstallmanifest.Manifest, jobs []bireljob.Job, jobsPath string, stage biui.Stage) (InstalledJob, error) {
+	renderedJobRefs, err := i.jobRenderer.RenderAndUploadFrom(manifest, jobs, stage)
+	if err != nil {
+		return InstalledJob{}, bosherr.WrapError(err, "Rendering and uploading Jobs")
+	}
+
+	renderedCPIJob := renderedJobRefs[0]
+	installedJob, err := i.installJob(renderedCPIJob, jobsPath, stage)
+	if err != nil {
+		return InstalledJob{}, bosherr.WrapErrorf(err, "Installing job '%s' for CPI release", renderedCPIJob.Name)
+	}
+
+	return installedJob, nil
 }
 
 func (i *installer) installPackages(compiledPackages []CompiledPackageRef) error {
@@ -114,10 +162,10 @@ func (i *installer) installPackages(compiledPackages []CompiledPackageRef) error
 	return nil
 }
 
-func (i *installer) installJob(renderedJobRef RenderedJobRef, stage biui.Stage) (installedJob InstalledJob, err error) {
+func (i *installer) installJob(renderedJobRef RenderedJobRef, jobsPath string, stage biui.Stage) (installedJob InstalledJob, err error) {
 	err = stage.Perform(fmt.Sprintf("Installing job '%s'", renderedJobRef.Name), func() error {
 		var stageErr error
-		jobDir := filepath.Join(i.target.JobsPath(), renderedJobRef.Name)
+		jobDir := filepath.Join(jobsPath, renderedJobRef.Name)
 
 		stageErr = i.blobExtractor.Extract(renderedJobRef.BlobstoreID, renderedJobRef.SHA1, jobDir)
 		if stageErr != nil {
@@ -125,6 +125,56 @@ var _ = Describe("Installer", func() {
 			})
 		})
 
+		Context("with named cloud providers", func() {
+			var (
+				cloudProvider         biinstallmanifest.CloudProvider
+				cloudProviderManifest biinstallmanifest.Manifest
+				cloudProviderJobs     []bireljob.Job
+			)
+
+			BeforeEach(func() {
+				cloudProvider = biinstallmanifest.CloudProvider{
+					Name:       "fake-cloud-provider-name",
+					Template:   biinstallmanifest.ReleaseJobRef{Name: "cpi", Release: "fake-other-release-name"},
+					Properties: biproperty.Map{"fake-property-name": "fake-property-value"},
+				}
+				installationManifest.CloudProviders = []biinstallmanifest.CloudProvider{cloudProvider}
+				cloudProviderManifest = installationManifest.WithCloudProvider(cloudProvider)
+				cloudProviderJobs = []bireljob.Job{{Name: "cpi", Fingerprint: "fake-other-fingerprint"}}
+			})
+
+			JustBeforeEach(func() {
+				mockJobResolver.EXPECT().From(cloudProviderManifest).Return(cloudProviderJobs, nil)
+				mockPackageCompiler.EXPECT().For(append(releaseJobs, cloudProviderJobs...), fakeStage).Return([]CompiledPackageRef{}, nil)
+				mockJobRenderer.EXPECT().RenderAndUploadFrom(installationManifest, releaseJobs, fakeStage).Return(renderedJobRefs, nil)
+				mockJobRenderer.EXPECT().RenderAndUploadFrom(cloudProviderManifest, cloudProviderJobs, fakeStage).Return([]RenderedJobRef{
+					NewRenderedJobRef("cpi", "fake-other-fingerprint", "fake-other-blobstore-id", "fake-other-sha1"),
+				}, nil)
+			})
+
+			It("installs the CPI job of each cloud provider into its own jobs directory", func() {
+				installation, err := installer.Install(installationManifest, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeExtractor.ExtractCallCount()).To(Equal(2))
+				blobstoreID, _, targetDir := fakeExtractor.ExtractArgsForCall(1)
+				Expect(blobstoreID).To(Equal("fake-other-blobstore-id"))
+				Expect(targetDir).To(Equal("fake-installation-path/cloud_providers/fake-cloud-provider-name/jobs/cpi"))
+
+				job, found := installation.CloudProviderJob("fake-cloud-provider-name")
+				Expect(found).To(BeTrue())
+				Expect(job.Path).To(Equal("fake-installation-path/cloud_providers/fake-cloud-provider-name/jobs/cpi"))
+
+				job, found = installation.CloudProviderJob("")
+				Expect(found).To(BeTrue())
+				Expect(job).To(Equal(installation.Job()))
+
+				_, found = installation.CloudProviderJob("fake-unknown-name")
+				Expect(found).To(BeFalse())
+				Expect(installation.CloudProviders()).To(Equal([]string{"fake-cloud-provider-name"}))
+			})
+		})
+
 		Context("when rendering jobs errors", func() {
 			JustBeforeEach(func() {
 				err := errors.New("OMG - no ruby found!!")
@@ -144,6 +194,7 @@ var _ = Describe("Installer", func() {
 			installation = NewInstallation(
 				target,
 				installedJob,
+				map[string]InstalledJob{},
 				installationManifest,
 				mockRegistryServerManager,
 			)
@@ -160,6 +211,29 @@ var _ = Describe("Installer", func() {
 			Expect(extractedBlobPath).To(Equal(installedJob.Path))
 		})
 
+		It("cleans up the installed jobs of the cloud providers", func() {
+			installationManifest.CloudProviders = []biinstallmanifest.CloudProvider{{Name: "fake-cloud-provider-name"}}
+			cloudProviderJob := NewInstalledJob(
+				NewRenderedJobRef("cpi", "fake-other-fingerprint", "fake-other-blobstore-id", "fake-other-sha1"),
+				"/extracted-release-path/cloud_providers/fake-cloud-provider-name/jobs/cpi",
+			)
+			installation = NewInstallation(
+				target,
+				installedJob,
+				map[string]InstalledJob{"fake-cloud-provider-name": cloudProviderJob},
+				installationManifest,
+				mockRegistryServerManager,
+			)
+
+			err := installer.Cleanup(installation)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeExtractor.CleanupCallCount()).To(Equal(2))
+			blobstoreID, extractedBlobPath := fakeExtractor.CleanupArgsForCall(1)
+			Expect(blobstoreID).To(Equal("fake-other-blobstore-id"))
+			Expect(extractedBlobPath).To(Equal(cloudProviderJob.Path))
+		})
+
 		It("returns errors when cleaning up installed jobs", func() {
 			fakeExtractor.CleanupReturns(errors.New("nope"))
 
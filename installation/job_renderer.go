@@ -79,7 +79,7 @@ func (b *jobRenderer) renderJobTemplates(
 ) ([]RenderedJobRef, error) {
 	renderedJobRefs := make([]RenderedJobRef, 0, len(releaseJobs))
 	err := stage.Perform("Rendering job templates", func() error {
-		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, "")
+		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, "", []string{})
 		if err != nil {
 			return err
 		}
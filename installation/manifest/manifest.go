@@ -1,6 +1,10 @@
 package manifest
 
 import (
+	"fmt"
+	"net/url"
+
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 )
 
@@ -10,6 +14,8 @@ type Manifest struct {
 	Properties biproperty.Map
 	Mbus       string
 	Registry   Registry
+	// CloudProviders are the named CPI configurations, Template and Properties are the default one
+	CloudProviders []CloudProvider
 }
 
 type ReleaseJobRef struct {
@@ -17,16 +23,132 @@ type ReleaseJobRef struct {
 	Release string
 }
 
+// CloudProvider is a CPI configuration that resource pools and disk pools select by name.
+// The default CPI configuration has an empty name.
+type CloudProvider struct {
+	Name       string
+	Template   ReleaseJobRef
+	Properties biproperty.Map
+}
+
+// DefaultCloudProvider returns the CPI configuration of cloud_provider
+func (m Manifest) DefaultCloudProvider() CloudProvider {
+	return CloudProvider{
+		Template:   m.Template,
+		Properties: m.Properties,
+	}
+}
+
+// FindCloudProvider returns the CPI configuration with the name, or the default one for an empty name
+func (m Manifest) FindCloudProvider(name string) (CloudProvider, bool) {
+	if name == "" {
+		return m.DefaultCloudProvider(), true
+	}
+
+	for _, cloudProvider := range m.CloudProviders {
+		if cloudProvider.Name == name {
+			return cloudProvider, true
+		}
+	}
+
+	return CloudProvider{}, false
+}
+
+// WithCloudProvider returns the manifest with the CPI job and properties of the cloud provider as the default ones
+func (m Manifest) WithCloudProvider(cloudProvider CloudProvider) Manifest {
+	m.Template = cloudProvider.Template
+	m.Properties = cloudProvider.Properties
+	return m
+}
+
+// CPIReleases returns the names of the releases of all the CPI configurations, without duplicates
+func (m Manifest) CPIReleases() []string {
+	releases := []string{m.Template.Release}
+	for _, cloudProvider := range m.CloudProviders {
+		found := false
+		for _, release := range releases {
+			if release == cloudProvider.Template.Release {
+				found = true
+				break
+			}
+		}
+		if !found {
+			releases = append(releases, cloudProvider.Template.Release)
+		}
+	}
+	return releases
+}
+
 type Registry struct {
 	Username  string
 	Password  string
 	Host      string
 	Port      int
 	SSHTunnel SSHTunnel
+	StorePath string
+	TLS       RegistryTLS
+	AuditLog  string
+
+	// AgentCredentials are generated for every run and given to the agent of the VM the run creates,
+	// the registry binds them to the CID of that VM
+	AgentCredentials biregistry.Credentials
+
+	// InstanceCredentialsPath is the file the credentials bound to the VM CIDs are persisted to, next to the StorePath
+	InstanceCredentialsPath string
+}
+
+type RegistryTLS struct {
+	Enabled     bool
+	Certificate string
+	PrivateKey  string
+
+	// CertificatePEM and PrivateKeyPEM are the generated certificate, when no certificate files are configured
+	CertificatePEM string
+	PrivateKeyPEM  string
 }
 
 func (r Registry) IsEmpty() bool {
-	return r == Registry{}
+	return r.Username == "" && r.Password == "" && r.Host == "" && r.Port == 0 && r.SSHTunnel == SSHTunnel{}
+}
+
+// AgentEndpoint is the registry URL the agent reads its settings from, with the agent credentials
+func (r Registry) AgentEndpoint() string {
+	scheme := "http"
+	if r.TLS.Enabled {
+		scheme = "https"
+	}
+
+	endpoint := url.URL{Scheme: scheme, Host: fmt.Sprintf("%s:%d", r.Host, r.Port)}
+	if r.AgentCredentials.Username != "" {
+		endpoint.User = url.UserPassword(r.AgentCredentials.Username, r.AgentCredentials.Password)
+	}
+
+	return endpoint.String()
+}
+
+func (r Registry) ServerConfig() biregistry.ServerConfig {
+	config := biregistry.ServerConfig{
+		Username:  r.Username,
+		Password:  r.Password,
+		Host:      r.Host,
+		Port:      r.Port,
+		StorePath: r.StorePath,
+		TLS: biregistry.TLSConfig{
+			Enabled:         r.TLS.Enabled,
+			CertificatePath: r.TLS.Certificate,
+			PrivateKeyPath:  r.TLS.PrivateKey,
+		},
+		AgentCredentials:        r.AgentCredentials,
+		InstanceCredentialsPath: r.InstanceCredentialsPath,
+		AuditLogPath:            r.AuditLog,
+	}
+
+	if r.TLS.CertificatePEM != "" {
+		config.TLS.CertificatePEM = []byte(r.TLS.CertificatePEM)
+		config.TLS.PrivateKeyPEM = []byte(r.TLS.PrivateKeyPEM)
+	}
+
+	return config
 }
 
 type SSHTunnel struct {
@@ -35,6 +157,19 @@ type SSHTunnel struct {
 	Port       int
 	Password   string
 	PrivateKey string `yaml:"private_key"`
+	Passphrase string
+	UseAgent   bool        `yaml:"use_agent"`
+	KnownHosts string      `yaml:"known_hosts"`
+	JumpHost   SSHJumpHost `yaml:"jump_host"`
+}
+
+type SSHJumpHost struct {
+	User       string
+	Host       string
+	Port       int
+	Password   string
+	PrivateKey string `yaml:"private_key"`
+	Passphrase string
 }
 
 func (m *Manifest) PopulateRegistry(username string, password string, host string, port int, sshTunnel SSHTunnel) {
@@ -2,6 +2,9 @@ package manifest
 
 import (
 	biutil "github.com/cloudfoundry/bosh-init/common/util"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -21,18 +24,35 @@ type parser struct {
 	logger        boshlog.Logger
 	logTag        string
 	validator     Validator
+	encryptor     bicrypto.Encryptor
 }
 
 type manifest struct {
-	Name          string
-	CloudProvider installation `yaml:"cloud_provider"`
+	Name           string
+	CloudProvider  installation    `yaml:"cloud_provider"`
+	CloudProviders []cloudProvider `yaml:"cloud_providers"`
 }
 
 type installation struct {
 	Template   template
 	Properties map[interface{}]interface{}
-	SSHTunnel  SSHTunnel `yaml:"ssh_tunnel"`
-	Mbus       string
+	// Secrets are encrypted YAML properties, merged into Properties
+	Secrets   string
+	SSHTunnel SSHTunnel `yaml:"ssh_tunnel"`
+	Mbus      string
+	Registry  registry
+}
+
+type registry struct {
+	Persistent bool
+	TLS        registryTLS `yaml:"tls"`
+	AuditLog   string      `yaml:"audit_log"`
+}
+
+type registryTLS struct {
+	Enabled     bool
+	Certificate string
+	PrivateKey  string `yaml:"private_key"`
 }
 
 func (i installation) HasSSHTunnel() bool {
@@ -44,13 +64,24 @@ type template struct {
 	Release string
 }
 
-func NewParser(fs boshsys.FileSystem, uuidGenerator boshuuid.Generator, logger boshlog.Logger, validator Validator) Parser {
+// cloudProvider is a named CPI configuration, its properties and secrets are merged into the ones of cloud_provider
+type cloudProvider struct {
+	Name       string
+	Template   template
+	Properties map[interface{}]interface{}
+	Secrets    string
+}
+
+// NewParser returns a parser that decrypts cloud_provider.secrets with encryptor.
+// With a nil encryptor, manifests with secrets cannot be parsed.
+func NewParser(fs boshsys.FileSystem, uuidGenerator boshuuid.Generator, logger boshlog.Logger, validator Validator, encryptor bicrypto.Encryptor) Parser {
 	return &parser{
 		fs:            fs,
 		uuidGenerator: uuidGenerator,
 		logger:        logger,
 		logTag:        "deploymentParser",
 		validator:     validator,
+		encryptor:     encryptor,
 	}
 }
 
@@ -65,6 +96,13 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 	if err != nil {
 		return Manifest{}, bosherr.WrapError(err, "Unmarshalling installation manifest")
 	}
+	secrets := comboManifest.CloudProvider.Secrets
+	comboManifest.CloudProvider.Secrets = ""
+	cloudProviderSecrets := make([]string, len(comboManifest.CloudProviders))
+	for i := range comboManifest.CloudProviders {
+		cloudProviderSecrets[i] = comboManifest.CloudProviders[i].Secrets
+		comboManifest.CloudProviders[i].Secrets = ""
+	}
 	p.logger.Debug(p.logTag, "Parsed installation manifest: %#v", comboManifest)
 
 	if comboManifest.CloudProvider.SSHTunnel.PrivateKey != "" {
@@ -74,6 +112,20 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 		}
 	}
 
+	if comboManifest.CloudProvider.SSHTunnel.KnownHosts != "" {
+		comboManifest.CloudProvider.SSHTunnel.KnownHosts, err = biutil.AbsolutifyPath(path, comboManifest.CloudProvider.SSHTunnel.KnownHosts, p.fs)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Expanding known_hosts path")
+		}
+	}
+
+	if comboManifest.CloudProvider.SSHTunnel.JumpHost.PrivateKey != "" {
+		comboManifest.CloudProvider.SSHTunnel.JumpHost.PrivateKey, err = biutil.AbsolutifyPath(path, comboManifest.CloudProvider.SSHTunnel.JumpHost.PrivateKey, p.fs)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Expanding jump_host private_key path")
+		}
+	}
+
 	installationManifest := Manifest{
 		Name: comboManifest.Name,
 		Template: ReleaseJobRef{
@@ -89,12 +141,47 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 	}
 	installationManifest.Properties = properties
 
+	if secrets != "" {
+		secretProperties, err := p.decryptSecrets(secrets)
+		if err != nil {
+			return Manifest{}, err
+		}
+		mergeProperties(installationManifest.Properties, secretProperties)
+	}
+
 	if comboManifest.CloudProvider.HasSSHTunnel() {
-		password, err := p.uuidGenerator.Generate()
+		registryConfig := comboManifest.CloudProvider.Registry
+
+		// the VMs keep using the credentials and the certificate they were given, so a persistent registry generates them only once
+		credentialsPath := ""
+		if registryConfig.Persistent {
+			credentialsPath = biconfig.RegistryCredentialsPath(path)
+		}
+		credentials, err := p.registryCredentials(credentialsPath, registryConfig)
 		if err != nil {
-			return Manifest{}, bosherr.WrapError(err, "Generating registry password")
+			return Manifest{}, err
+		}
+
+		installationManifest.PopulateRegistry(credentials.Username, credentials.Password, "127.0.0.1", 6901, comboManifest.CloudProvider.SSHTunnel)
+
+		if registryConfig.Persistent {
+			installationManifest.Registry.StorePath = biconfig.RegistryStorePath(path)
+			installationManifest.Registry.InstanceCredentialsPath = biconfig.RegistryInstanceCredentialsPath(path)
+		}
+
+		err = p.populateRegistrySecurity(path, registryConfig, credentials, &installationManifest)
+		if err != nil {
+			return Manifest{}, err
 		}
-		installationManifest.PopulateRegistry("registry", password, "127.0.0.1", 6901, comboManifest.CloudProvider.SSHTunnel)
+	}
+
+	// named cloud providers also get the registry properties, so they are parsed last
+	for i, rawCloudProvider := range comboManifest.CloudProviders {
+		cloudProvider, err := p.parseCloudProvider(rawCloudProvider, cloudProviderSecrets[i], installationManifest.Properties)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Parsing cloud_providers[%d]", i)
+		}
+		installationManifest.CloudProviders = append(installationManifest.CloudProviders, cloudProvider)
 	}
 
 	err = p.validator.Validate(installationManifest, releaseSetManifest)
@@ -104,3 +191,139 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 
 	return installationManifest, nil
 }
+
+func (p *parser) parseCloudProvider(rawCloudProvider cloudProvider, secrets string, defaultProperties biproperty.Map) (CloudProvider, error) {
+	ownProperties, err := biproperty.BuildMap(rawCloudProvider.Properties)
+	if err != nil {
+		return CloudProvider{}, bosherr.WrapErrorf(err, "Parsing properties: %#v", rawCloudProvider.Properties)
+	}
+
+	properties := copyProperties(defaultProperties)
+	mergeProperties(properties, ownProperties)
+
+	if secrets != "" {
+		secretProperties, err := p.decryptSecrets(secrets)
+		if err != nil {
+			return CloudProvider{}, err
+		}
+		mergeProperties(properties, secretProperties)
+	}
+
+	return CloudProvider{
+		Name: rawCloudProvider.Name,
+		Template: ReleaseJobRef{
+			Name:    rawCloudProvider.Template.Name,
+			Release: rawCloudProvider.Template.Release,
+		},
+		Properties: properties,
+	}, nil
+}
+
+func (p *parser) decryptSecrets(secrets string) (biproperty.Map, error) {
+	if !bicrypto.IsEncrypted(secrets) {
+		return nil, bosherr.Error("Expected cloud_provider.secrets to be encrypted with 'bosh-init state encrypt-secrets'")
+	}
+
+	if p.encryptor == nil {
+		return nil, bosherr.Error("Manifest contains encrypted cloud_provider.secrets, but no passphrase or key file was given")
+	}
+
+	contents, err := p.encryptor.Decrypt(secrets)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Decrypting cloud_provider.secrets")
+	}
+
+	var rawSecrets map[interface{}]interface{}
+	err = yaml.Unmarshal(contents, &rawSecrets)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling decrypted cloud_provider.secrets")
+	}
+
+	secretProperties, err := biproperty.BuildMap(rawSecrets)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing decrypted cloud_provider.secrets")
+	}
+
+	return secretProperties, nil
+}
+
+// mergeProperties deep merges source into destination, values of source win
+func mergeProperties(destination, source biproperty.Map) {
+	for key, value := range source {
+		sourceMap, sourceIsMap := value.(biproperty.Map)
+		destinationMap, destinationIsMap := destination[key].(biproperty.Map)
+		if sourceIsMap && destinationIsMap {
+			mergeProperties(destinationMap, sourceMap)
+		} else {
+			destination[key] = value
+		}
+	}
+}
+
+// copyProperties copies the nested maps of properties, so that merging into the copy does not change properties
+func copyProperties(properties biproperty.Map) biproperty.Map {
+	propertiesCopy := biproperty.Map{}
+	for key, value := range properties {
+		if valueMap, isMap := value.(biproperty.Map); isMap {
+			propertiesCopy[key] = copyProperties(valueMap)
+		} else {
+			propertiesCopy[key] = value
+		}
+	}
+	return propertiesCopy
+}
+
+func (p *parser) populateRegistrySecurity(path string, registryConfig registry, credentials registryCredentials, installationManifest *Manifest) error {
+	var err error
+
+	tlsConfig := RegistryTLS{Enabled: registryConfig.TLS.Enabled}
+	if registryConfig.TLS.Certificate != "" {
+		tlsConfig.Certificate, err = biutil.AbsolutifyPath(path, registryConfig.TLS.Certificate, p.fs)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Expanding registry tls certificate path")
+		}
+	}
+	if registryConfig.TLS.PrivateKey != "" {
+		tlsConfig.PrivateKey, err = biutil.AbsolutifyPath(path, registryConfig.TLS.PrivateKey, p.fs)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Expanding registry tls private_key path")
+		}
+	}
+	if tlsConfig.Enabled && tlsConfig.Certificate == "" && tlsConfig.PrivateKey == "" {
+		tlsConfig.CertificatePEM = credentials.Certificate
+		tlsConfig.PrivateKeyPEM = credentials.PrivateKey
+	}
+	installationManifest.Registry.TLS = tlsConfig
+
+	if registryConfig.AuditLog != "" {
+		installationManifest.Registry.AuditLog, err = biutil.AbsolutifyPath(path, registryConfig.AuditLog, p.fs)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Expanding registry audit_log path")
+		}
+	}
+
+	// the agent of a VM created by this run reads its settings with the agent credentials,
+	// the agents of the VMs created earlier keep using the credentials they were given
+	agentPassword, err := p.uuidGenerator.Generate()
+	if err != nil {
+		return bosherr.WrapError(err, "Generating registry agent password")
+	}
+	installationManifest.Registry.AgentCredentials = biregistry.Credentials{Username: "agent", Password: agentPassword}
+
+	// the CPI passes the endpoint to the agent, so it carries the agent credentials instead of the admin credentials
+	registryProperties, _ := installationManifest.Properties["registry"].(biproperty.Map)
+	registryProperties["endpoint"] = installationManifest.Registry.AgentEndpoint()
+
+	if tlsConfig.Enabled {
+		caCertificate := tlsConfig.CertificatePEM
+		if tlsConfig.Certificate != "" {
+			caCertificate, err = p.fs.ReadFileString(tlsConfig.Certificate)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Reading registry tls certificate '%s'", tlsConfig.Certificate)
+			}
+		}
+		registryProperties["ca_cert"] = caCertificate
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	biutil "github.com/cloudfoundry/bosh-init/common/util"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	biregistry "github.com/cloudfoundry/bosh-init/registry"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// registryCredentials are generated once for a persistent registry and kept next to its store,
+// so that `registry serve` and later deploys accept the credentials and the certificate the existing VMs were given
+type registryCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Certificate and PrivateKey are the PEM encoded self-signed certificate, when no certificate files are configured
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"private_key,omitempty"`
+}
+
+// registryCertificateRenewal is how long before it expires the generated certificate is regenerated
+const registryCertificateRenewal = 30 * 24 * time.Hour
+
+// registryCredentials returns the credentials of the registry, generating the missing ones.
+// With a path, they are loaded from and saved to it; otherwise they are generated for every run.
+func (p *parser) registryCredentials(path string, registryConfig registry) (registryCredentials, error) {
+	credentials := registryCredentials{}
+	found := false
+	encrypted := false
+	if path != "" {
+		var err error
+		credentials, found, encrypted, err = p.loadRegistryCredentials(path)
+		if err != nil {
+			return registryCredentials{}, err
+		}
+	}
+
+	generated, err := p.generateRegistryCredentials(&credentials, registryConfig)
+	if err != nil {
+		return registryCredentials{}, err
+	}
+
+	if path == "" {
+		return credentials, nil
+	}
+
+	// plain text credentials are encrypted once a passphrase is given, like the deployment state
+	if generated || (found && !encrypted && p.encryptor != nil) {
+		err = p.saveRegistryCredentials(path, credentials)
+		if err != nil {
+			return registryCredentials{}, err
+		}
+	}
+
+	return credentials, nil
+}
+
+// generateRegistryCredentials fills in the credentials that were not generated yet; it returns whether any were
+func (p *parser) generateRegistryCredentials(credentials *registryCredentials, registryConfig registry) (bool, error) {
+	generated := false
+
+	if credentials.Username == "" {
+		password, err := p.uuidGenerator.Generate()
+		if err != nil {
+			return false, bosherr.WrapError(err, "Generating registry password")
+		}
+		credentials.Username = "registry"
+		credentials.Password = password
+		generated = true
+	}
+
+	tlsConfig := registryConfig.TLS
+	if tlsConfig.Enabled && tlsConfig.Certificate == "" && tlsConfig.PrivateKey == "" {
+		renew := credentials.Certificate == ""
+		if !renew {
+			expiring, err := biregistry.CertificateExpiresBefore([]byte(credentials.Certificate), time.Now().Add(registryCertificateRenewal))
+			if err != nil {
+				return false, bosherr.WrapError(err, "Checking registry certificate expiry")
+			}
+			if expiring {
+				p.logger.Info(p.logTag, "Regenerating registry certificate, it expires in less than %s", registryCertificateRenewal)
+				renew = true
+			}
+		}
+
+		if renew {
+			certificatePEM, privateKeyPEM, err := biregistry.GenerateCertificate("127.0.0.1")
+			if err != nil {
+				return false, bosherr.WrapError(err, "Generating registry certificate")
+			}
+			credentials.Certificate = string(certificatePEM)
+			credentials.PrivateKey = string(privateKeyPEM)
+			generated = true
+		}
+	}
+
+	return generated, nil
+}
+
+// loadRegistryCredentials reads the registry credentials from path; found is false when they were not saved yet
+func (p *parser) loadRegistryCredentials(path string) (credentials registryCredentials, found bool, encrypted bool, err error) {
+	if !p.fs.FileExists(path) {
+		return registryCredentials{}, false, false, nil
+	}
+
+	bytes, err := p.fs.ReadFile(path)
+	if err != nil {
+		return registryCredentials{}, false, false, bosherr.WrapErrorf(err, "Reading registry credentials file '%s'", path)
+	}
+
+	encrypted = bicrypto.IsEncrypted(string(bytes))
+	if encrypted {
+		if p.encryptor == nil {
+			return registryCredentials{}, false, false, bosherr.Errorf("Registry credentials file '%s' is encrypted, but no passphrase or key file was given", path)
+		}
+
+		bytes, err = p.encryptor.Decrypt(string(bytes))
+		if err != nil {
+			return registryCredentials{}, false, false, bosherr.WrapErrorf(err, "Decrypting registry credentials file '%s'", path)
+		}
+	}
+
+	err = json.Unmarshal(bytes, &credentials)
+	if err != nil {
+		return registryCredentials{}, false, false, bosherr.WrapErrorf(err, "Unmarshalling registry credentials file '%s'", path)
+	}
+
+	return credentials, true, encrypted, nil
+}
+
+// saveRegistryCredentials atomically writes the registry credentials to path, encrypted like the registry store
+// and readable only by the owner
+func (p *parser) saveRegistryCredentials(path string, credentials registryCredentials) error {
+	bytes, err := json.MarshalIndent(credentials, "", "    ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling registry credentials")
+	}
+
+	if p.encryptor != nil {
+		encrypted, err := p.encryptor.Encrypt(bytes)
+		if err != nil {
+			return bosherr.WrapError(err, "Encrypting registry credentials")
+		}
+		bytes = []byte(encrypted)
+	}
+
+	err = biutil.WriteFileAtomically(p.fs, path, bytes, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing registry credentials file '%s'", path)
+	}
+
+	return nil
+}
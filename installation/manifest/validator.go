@@ -40,6 +40,29 @@ func (v *validator) Validate(manifest Manifest, releaseSetManifest birelsetmanif
 		errs = append(errs, bosherr.Errorf("cloud_provider.template.release '%s' must refer to a release in releases", cpiReleaseName))
 	}
 
+	names := map[string]struct{}{}
+	for i, cloudProvider := range manifest.CloudProviders {
+		if v.isBlank(cloudProvider.Name) {
+			errs = append(errs, bosherr.Errorf("cloud_providers[%d].name must be provided", i))
+		} else if _, found := names[cloudProvider.Name]; found {
+			errs = append(errs, bosherr.Errorf("cloud_providers[%d].name '%s' must be unique", i, cloudProvider.Name))
+		}
+		names[cloudProvider.Name] = struct{}{}
+
+		if v.isBlank(cloudProvider.Template.Name) {
+			errs = append(errs, bosherr.Errorf("cloud_providers[%d].template.name must be provided", i))
+		}
+
+		if v.isBlank(cloudProvider.Template.Release) {
+			errs = append(errs, bosherr.Errorf("cloud_providers[%d].template.release must be provided", i))
+		}
+
+		_, found := releaseSetManifest.FindByName(cloudProvider.Template.Release)
+		if !found {
+			errs = append(errs, bosherr.Errorf("cloud_providers[%d].template.release '%s' must refer to a release in releases", i, cloudProvider.Template.Release))
+		}
+	}
+
 	if len(errs) > 0 {
 		return bosherr.NewMultiError(errs...)
 	}
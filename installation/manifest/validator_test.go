@@ -101,5 +101,50 @@ var _ = Describe("Validator", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("cloud_provider.template.release 'not-provided-valid-release-name' must refer to a release in releases"))
 		})
+
+		It("does not error if the cloud providers are valid", func() {
+			manifest := validManifest
+			manifest.CloudProviders = []CloudProvider{
+				{Name: "fake-cloud-provider-1", Template: ReleaseJobRef{Name: "cpi", Release: "provided-valid-release-name"}},
+				{Name: "fake-cloud-provider-2", Template: ReleaseJobRef{Name: "other-cpi", Release: "provided-valid-release-name"}},
+			}
+
+			err := validator.Validate(manifest, releaseSetManifest)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("validates cloud providers must be fully specified", func() {
+			manifest := validManifest
+			manifest.CloudProviders = []CloudProvider{{}}
+
+			err := validator.Validate(manifest, releaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cloud_providers[0].name must be provided"))
+			Expect(err.Error()).To(ContainSubstring("cloud_providers[0].template.name must be provided"))
+			Expect(err.Error()).To(ContainSubstring("cloud_providers[0].template.release must be provided"))
+		})
+
+		It("validates cloud provider names are unique", func() {
+			manifest := validManifest
+			manifest.CloudProviders = []CloudProvider{
+				{Name: "fake-cloud-provider", Template: ReleaseJobRef{Name: "cpi", Release: "provided-valid-release-name"}},
+				{Name: "fake-cloud-provider", Template: ReleaseJobRef{Name: "cpi", Release: "provided-valid-release-name"}},
+			}
+
+			err := validator.Validate(manifest, releaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cloud_providers[1].name 'fake-cloud-provider' must be unique"))
+		})
+
+		It("validates the release of a cloud provider is available", func() {
+			manifest := validManifest
+			manifest.CloudProviders = []CloudProvider{
+				{Name: "fake-cloud-provider", Template: ReleaseJobRef{Name: "cpi", Release: "not-provided-valid-release-name"}},
+			}
+
+			err := validator.Validate(manifest, releaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cloud_providers[0].template.release 'not-provided-valid-release-name' must refer to a release in releases"))
+		})
 	})
 })
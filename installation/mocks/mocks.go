@@ -33,6 +33,27 @@ func (_m *MockInstallation) EXPECT() *_MockInstallationRecorder {
 	return _m.recorder
 }
 
+func (_m *MockInstallation) CloudProviderJob(_param0 string) (installation.InstalledJob, bool) {
+	ret := _m.ctrl.Call(_m, "CloudProviderJob", _param0)
+	ret0, _ := ret[0].(installation.InstalledJob)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+func (_mr *_MockInstallationRecorder) CloudProviderJob(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CloudProviderJob", arg0)
+}
+
+func (_m *MockInstallation) CloudProviders() []string {
+	ret := _m.ctrl.Call(_m, "CloudProviders")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+func (_mr *_MockInstallationRecorder) CloudProviders() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CloudProviders")
+}
+
 func (_m *MockInstallation) Job() installation.InstalledJob {
 	ret := _m.ctrl.Call(_m, "Job")
 	ret0, _ := ret[0].(installation.InstalledJob)
@@ -15,6 +15,9 @@ type Cache interface {
 	Get(source Source) (path string, found bool)
 	Path(source Source) (path string)
 	Save(sourcePath string, source Source) error
+
+	// Prune deletes the cached tarballs of all sources except keep, and returns their paths
+	Prune(keep []Source) (deletedPaths []string, err error)
 }
 
 type cache struct {
@@ -63,3 +66,50 @@ func (c *cache) Path(source Source) string {
 	filename := fmt.Sprintf("%x-%s", string(urlSHA1[:]), source.GetSHA1())
 	return filepath.Join(c.basePath, filename)
 }
+
+func (c *cache) Prune(keep []Source) ([]string, error) {
+	deletedPaths := []string{}
+	if !c.fs.FileExists(c.basePath) {
+		return deletedPaths, nil
+	}
+
+	keptPaths := map[string]struct{}{}
+	for _, source := range keep {
+		keptPaths[c.Path(source)] = struct{}{}
+	}
+
+	cachedPaths := []string{}
+	err := c.fs.Walk(c.basePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != c.basePath {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		cachedPaths = append(cachedPaths, path)
+		return nil
+	})
+	if err != nil {
+		return deletedPaths, bosherr.WrapErrorf(err, "Listing cached tarballs in '%s'", c.basePath)
+	}
+
+	for _, cachedPath := range cachedPaths {
+		if _, kept := keptPaths[cachedPath]; kept {
+			continue
+		}
+
+		c.logger.Debug(c.logTag, "Deleting cached tarball '%s'", cachedPath)
+		err = c.fs.RemoveAll(cachedPath)
+		if err != nil {
+			return deletedPaths, bosherr.WrapErrorf(err, "Deleting cached tarball '%s'", cachedPath)
+		}
+		deletedPaths = append(deletedPaths, cachedPath)
+	}
+
+	return deletedPaths, nil
+}
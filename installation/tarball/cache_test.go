@@ -96,4 +96,50 @@ var _ = Describe("Cache", func() {
 		})).To(Equal("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1"))
 		Expect(fs.FileExists("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1")).To(BeTrue())
 	})
+
+	Describe("Prune", func() {
+		var (
+			keptSource    *fakeSource
+			removedSource *fakeSource
+		)
+
+		BeforeEach(func() {
+			keptSource = &fakeSource{sha1: "fake-sha1", url: "http://foo.bar.com", description: "kept tarball"}
+			removedSource = &fakeSource{sha1: "fake-sha1", url: "http://baz.bar.com", description: "removed tarball"}
+
+			fs.WriteFileString("kept-source-path", "")
+			err := cache.Save("kept-source-path", keptSource)
+			Expect(err).ToNot(HaveOccurred())
+
+			fs.WriteFileString("removed-source-path", "")
+			err = cache.Save("removed-source-path", removedSource)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("deletes the cached tarballs of all other sources", func() {
+			deletedPaths, err := cache.Prune([]Source{keptSource})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deletedPaths).To(Equal([]string{cache.Path(removedSource)}))
+
+			_, found := cache.Get(keptSource)
+			Expect(found).To(BeTrue())
+			_, found = cache.Get(removedSource)
+			Expect(found).To(BeFalse())
+		})
+
+		It("deletes all cached tarballs when no sources are kept", func() {
+			deletedPaths, err := cache.Prune(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deletedPaths).To(HaveLen(2))
+			Expect(fs.FileExists(cache.Path(keptSource))).To(BeFalse())
+			Expect(fs.FileExists(cache.Path(removedSource))).To(BeFalse())
+		})
+
+		It("does nothing when nothing was cached", func() {
+			cache = NewCache("/fake-empty-base-path", fs, boshlog.NewLogger(boshlog.LevelNone))
+			deletedPaths, err := cache.Prune(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deletedPaths).To(BeEmpty())
+		})
+	})
 })
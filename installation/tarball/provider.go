@@ -42,7 +42,7 @@ type provider struct {
 	cache            Cache
 	fs               boshsys.FileSystem
 	httpClient       bihttpclient.HTTPClient
-	sha1Calculator   bicrypto.SHA1Calculator
+	digestVerifier   bicrypto.DigestVerifier
 	downloadAttempts int
 	delayTimeout     time.Duration
 	logger           boshlog.Logger
@@ -62,7 +62,7 @@ func NewProvider(
 		cache:            cache,
 		fs:               fs,
 		httpClient:       httpClient,
-		sha1Calculator:   sha1Calculator,
+		digestVerifier:   bicrypto.NewDigestVerifier(sha1Calculator, bicrypto.NewSha256Calculator(fs)),
 		downloadAttempts: downloadAttempts,
 		delayTimeout:     delayTimeout,
 		logger:           logger,
@@ -77,7 +77,15 @@ func (p *provider) Get(source Source, stage biui.Stage) (string, error) {
 		expandedPath, err := p.fs.ExpandPath(filePath)
 		if err != nil {
 			p.logger.Warn(p.logTag, "Failed to expand file path %s, using original URL", filePath)
-			return filePath, nil
+			expandedPath = filePath
+		}
+
+		// local files are only verified when a sha1 (or SHA256) is given, unlike downloads which require one
+		if source.GetSHA1() != "" {
+			err = p.digestVerifier.Verify(expandedPath, source.GetSHA1())
+			if err != nil {
+				return "", bosherr.WrapErrorf(err, "Verifying %s from '%s'", source.Description(), source.GetURL())
+			}
 		}
 
 		p.logger.Debug(p.logTag, "Using the tarball from file source: '%s'", filePath)
@@ -141,13 +149,10 @@ func (p *provider) downloadRetryable(source Source) boshretry.Retryable {
 			return true, bosherr.WrapError(err, "Saving downloaded bits to temporary file")
 		}
 
-		downloadedSha1, err := p.sha1Calculator.Calculate(downloadedFile.Name())
+		// the expected sha1 can also be a SHA256
+		err = p.digestVerifier.Verify(downloadedFile.Name(), source.GetSHA1())
 		if err != nil {
-			return true, bosherr.WrapError(err, "Calculating sha1 for downloaded file")
-		}
-
-		if downloadedSha1 != source.GetSHA1() {
-			return true, bosherr.Errorf("SHA1 of downloaded file '%s' does not match expected SHA1 '%s'", downloadedSha1, source.GetSHA1())
+			return true, bosherr.WrapError(err, "Verifying downloaded file")
 		}
 
 		err = p.cache.Save(downloadedFile.Name(), source)
@@ -42,6 +42,9 @@ var _ = Describe("Provider", func() {
 				source = newFakeSource("file://fake-file", "fake-sha1", "fake-description")
 				fs.WriteFileString("expanded-file-path", "")
 				fs.ExpandPathExpanded = "expanded-file-path"
+				sha1Calculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateInput{
+					"expanded-file-path": {Sha1: "fake-sha1"},
+				})
 			})
 
 			It("returns expanded path to file", func() {
@@ -49,6 +52,36 @@ var _ = Describe("Provider", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(path).To(Equal("expanded-file-path"))
 			})
+
+			Context("when the file does not match the sha1", func() {
+				BeforeEach(func() {
+					sha1Calculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateInput{
+						"expanded-file-path": {Sha1: "fake-other-sha1"},
+					})
+				})
+
+				It("returns an error naming the expected and actual sha1", func() {
+					_, err := provider.Get(source, fakeStage)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Verifying fake-description from 'file://fake-file'"))
+					Expect(err.Error()).To(ContainSubstring("SHA1 'fake-other-sha1' does not match expected SHA1 'fake-sha1'"))
+				})
+			})
+
+			Context("when no sha1 is given", func() {
+				BeforeEach(func() {
+					source = newFakeSource("file://fake-file", "", "fake-description")
+					sha1Calculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateInput{
+						"expanded-file-path": {Err: errors.New("fake-calculate-error")},
+					})
+				})
+
+				It("does not verify the file", func() {
+					path, err := provider.Get(source, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(path).To(Equal("expanded-file-path"))
+				})
+			})
 		})
 
 		Context("when URL starts with http(s)://", func() {
@@ -38,6 +38,17 @@ func (t Target) JobsPath() string {
 	return filepath.Join(t.path, "jobs")
 }
 
+// CloudProviderJobsPath is where the CPI job of the named cloud provider is installed,
+// so that cloud providers with the same CPI job do not share its rendered config
+func (t Target) CloudProviderJobsPath(cloudProvider string) string {
+	return filepath.Join(t.path, "cloud_providers", cloudProvider, "jobs")
+}
+
 func (t Target) TmpPath() string {
 	return filepath.Join(t.path, "tmp")
 }
+
+// LockPath is next to the installation directory, which is deleted on uninstall
+func (t Target) LockPath() string {
+	return t.path + ".lock"
+}
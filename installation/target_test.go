@@ -32,5 +32,9 @@ var _ = Describe("Target", func() {
 		It("returns the temp path", func() {
 			Expect(target.TmpPath()).To(Equal("/home/fake/madcow/tmp"))
 		})
+
+		It("returns the lock path", func() {
+			Expect(target.LockPath()).To(Equal("/home/fake/madcow.lock"))
+		})
 	})
 })
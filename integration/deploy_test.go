@@ -17,14 +17,16 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
+	mock_httpagent "github.com/cloudfoundry/bosh-init/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
 	mock_instance_state "github.com/cloudfoundry/bosh-init/deployment/instance/state/mocks"
+	mock_logs "github.com/cloudfoundry/bosh-init/deployment/logs/mocks"
 	mock_install "github.com/cloudfoundry/bosh-init/installation/mocks"
 	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
 	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock"
 
 	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
@@ -35,11 +37,13 @@ import (
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bisnapshot "github.com/cloudfoundry/bosh-init/deployment/snapshot"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bilock "github.com/cloudfoundry/bosh-init/lock"
 	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	bireljob "github.com/cloudfoundry/bosh-init/release/job"
@@ -295,6 +299,7 @@ cloud_provider:
 						"password": "registry-password",
 						"host":     "127.0.0.1",
 						"port":     6901,
+						"endpoint": "http://127.0.0.1:6901",
 					},
 				},
 			}
@@ -305,7 +310,7 @@ cloud_provider:
 			installedJob.Name = "fake-cpi-release-job-name"
 			installedJob.Path = filepath.Join(target.JobsPath(), "fake-cpi-release-job-name")
 
-			installation := biinstall.NewInstallation(target, installedJob, installationManifest, registryServerManager)
+			installation := biinstall.NewInstallation(target, installedJob, map[string]biinstall.InstalledJob{}, installationManifest, registryServerManager)
 
 			mockInstallerFactory.EXPECT().NewInstaller(target).Return(mockInstaller).AnyTimes()
 
@@ -313,7 +318,7 @@ cloud_provider:
 				Expect(fakeStage.SubStages).To(ContainElement(stage))
 			}).Return(installation, nil).AnyTimes()
 			mockInstaller.EXPECT().Cleanup(installation).AnyTimes()
-			mockCloudFactory.EXPECT().NewCloud(installation, directorID).Return(mockCloud, nil).AnyTimes()
+			mockCloudFactory.EXPECT().NewCloud(installation, directorID, "").Return(mockCloud, nil).AnyTimes()
 		}
 
 		var writeStemcellReleaseTarball = func() {
@@ -382,7 +387,7 @@ cloud_provider:
 			fakeRegistryUUIDGenerator = fakeuuid.NewFakeGenerator()
 			fakeRegistryUUIDGenerator.GeneratedUUID = "registry-password"
 			installationValidator := biinstallmanifest.NewValidator(logger)
-			installationParser := biinstallmanifest.NewParser(fs, fakeRegistryUUIDGenerator, logger, installationValidator)
+			installationParser := biinstallmanifest.NewParser(fs, fakeRegistryUUIDGenerator, logger, installationValidator, nil)
 
 			deploymentValidator := bideplmanifest.NewValidator(logger)
 
@@ -407,8 +412,16 @@ cloud_provider:
 				deploymentRecord := bidepl.NewRecord(deploymentRepo, releaseRepo, stemcellRepo, fakeSHA1Calculator)
 				stemcellManagerFactory = bistemcell.NewManagerFactory(stemcellRepo)
 				diskManagerFactory = bidisk.NewManagerFactory(diskRepo, logger)
-				diskDeployer = bivm.NewDiskDeployer(diskManagerFactory, diskRepo, logger)
-				vmManagerFactory = bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeAgentIDGenerator, fs, logger)
+				snapshotRepo := biconfig.NewSnapshotRepo(deploymentStateService, fakeRepoUUIDGenerator)
+				snapshotManagerFactory := bisnapshot.NewManagerFactory(diskRepo, snapshotRepo, clock.NewClock(), logger)
+				diskDeployer = bivm.NewDiskDeployer(diskManagerFactory, snapshotManagerFactory, diskRepo, biconfig.NewDiskMigrationRepo(deploymentStateService), logger)
+				vmManagerFactory = bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeAgentIDGenerator, fs, clock.NewClock(), logger)
+				moveStateService := biconfig.NewCloudMigrationTargetStateService(deploymentStateService)
+				moveDiskRepo := biconfig.NewDiskRepo(moveStateService, fakeRepoUUIDGenerator)
+				moveStemcellRepo := biconfig.NewStemcellRepo(moveStateService, fakeRepoUUIDGenerator)
+				moveSnapshotManagerFactory := bisnapshot.NewManagerFactory(moveDiskRepo, biconfig.NewSnapshotRepo(moveStateService, fakeRepoUUIDGenerator), clock.NewClock(), logger)
+				moveDiskDeployer := bivm.NewDiskDeployer(bidisk.NewManagerFactory(moveDiskRepo, logger), moveSnapshotManagerFactory, moveDiskRepo, biconfig.NewDiskMigrationRepo(moveStateService), logger)
+				moveVMManagerFactory := bivm.NewManagerFactory(biconfig.NewVMRepo(moveStateService), moveStemcellRepo, moveDiskDeployer, fakeAgentIDGenerator, fs, clock.NewClock(), logger)
 				deployer := bidepl.NewDeployer(
 					vmManagerFactory,
 					instanceManagerFactory,
@@ -456,12 +469,15 @@ cloud_provider:
 					"deployCmd",
 					deploymentStateService,
 					legacyDeploymentStateMigrator,
+					biconfig.NewCloudMigrationRepo(deploymentStateService),
 					releaseManager,
 					deploymentRecord,
 					mockCloudFactory,
 					stemcellManagerFactory,
 					mockAgentClientFactory,
 					vmManagerFactory,
+					bistemcell.NewManagerFactory(moveStemcellRepo),
+					moveVMManagerFactory,
 					mockBlobstoreFactory,
 					deployer,
 					deploymentManifestPath,
@@ -472,6 +488,8 @@ cloud_provider:
 					deploymentManifestParser,
 					tempRootConfigurator,
 					targetProvider,
+					mock_logs.NewMockFetcher(mockCtrl),
+					bilock.NewLocker(fs, clock.NewClock(), logger),
 				), nil
 			}
 
@@ -796,13 +814,13 @@ cloud_provider:
 			mockInstallerFactory = mock_install.NewMockInstallerFactory(mockCtrl)
 			mockCloudFactory = mock_cloud.NewMockFactory(mockCtrl)
 
-			sshTunnelFactory = bisshtunnel.NewFactory(logger)
+			sshTunnelFactory = bisshtunnel.NewFactory(fs, logger)
 
 			fakeRepoUUIDGenerator = fakeuuid.NewFakeGenerator()
 
 			mockCloud = mock_cloud.NewMockCloud(mockCtrl)
 
-			registryServerManager = biregistry.NewServerManager(logger)
+			registryServerManager = biregistry.NewServerManager(fs, logger)
 
 			mockReleaseExtractor = mock_release.NewMockExtractor(mockCtrl)
 			releaseManager = birel.NewManager(logger)
@@ -987,14 +1005,14 @@ cloud_provider:
 						err := newDeployCmd().Run(fakeStage, []string{deploymentManifestPath})
 						Expect(err).ToNot(HaveOccurred())
 
-						diskRecord, found, err := diskRepo.FindCurrent()
+						currentDiskRecords, err := diskRepo.FindCurrent()
 						Expect(err).ToNot(HaveOccurred())
-						Expect(found).To(BeTrue())
-						Expect(diskRecord.CID).To(Equal("fake-disk-cid-3"))
+						Expect(currentDiskRecords).To(HaveLen(1))
+						Expect(currentDiskRecords[0].CID).To(Equal("fake-disk-cid-3"))
 
 						diskRecords, err := diskRepo.All()
 						Expect(err).ToNot(HaveOccurred())
-						Expect(diskRecords).To(Equal([]biconfig.DiskRecord{diskRecord}))
+						Expect(diskRecords).To(Equal(currentDiskRecords))
 					})
 				})
 			})
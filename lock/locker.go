@@ -0,0 +1,314 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+// Holder identifies the process holding a lock
+type Holder struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("'%s' (PID %d on host '%s') since %s", h.Command, h.PID, h.Hostname, h.StartedAt.Format(time.RFC3339))
+}
+
+func (h Holder) isSameProcess(other Holder) bool {
+	return h.PID == other.PID && h.Hostname == other.Hostname && h.StartedAt.Equal(other.StartedAt)
+}
+
+// HeldError is returned when a lock is held by another process that may still be running
+type HeldError struct {
+	Path   string
+	Holder Holder
+}
+
+func (e HeldError) Error() string {
+	return fmt.Sprintf("Lock '%s' is held by %s", e.Path, e.Holder)
+}
+
+// IsHeld returns true if err or any error it wraps is a HeldError
+func IsHeld(err error) bool {
+	for err != nil {
+		switch typedErr := err.(type) {
+		case HeldError:
+			return true
+		case bosherr.ComplexError:
+			err = typedErr.Cause
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+type Lock interface {
+	// Release deletes the lock file, unless the lock was taken over by another process
+	Release() error
+}
+
+type Locker interface {
+	// Lock creates the lock file at path, recording the current process as its holder.
+	// A lock left behind by a process that is no longer running on this host is taken over.
+	// Returns a HeldError if the lock is held by another process, unless force is true.
+	Lock(path string, force bool) (Lock, error)
+}
+
+type locker struct {
+	fs          boshsys.FileSystem
+	timeService clock.Clock
+	logger      boshlog.Logger
+	logTag      string
+}
+
+func NewLocker(fs boshsys.FileSystem, timeService clock.Clock, logger boshlog.Logger) Locker {
+	return &locker{
+		fs:          fs,
+		timeService: timeService,
+		logger:      logger,
+		logTag:      "locker",
+	}
+}
+
+func (l *locker) Lock(path string, force bool) (Lock, error) {
+	holder, err := l.currentHolder()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.create(path, holder)
+	if err == nil {
+		return &lock{path: path, holder: holder, fs: l.fs, logger: l.logger, logTag: l.logTag}, nil
+	}
+
+	if !os.IsExist(err) {
+		return nil, bosherr.WrapErrorf(err, "Creating lock file '%s'", path)
+	}
+
+	existingHolder, err := readHolder(l.fs, path)
+	switch {
+	case err != nil && force:
+		// the holder is unknown, so the lock is taken over as long as the lock file stays unreadable
+		l.logger.Warn(l.logTag, "Forcefully taking over lock '%s' with an unreadable holder: %s", path, err.Error())
+	case err != nil:
+		return nil, err
+	case force:
+		l.logger.Warn(l.logTag, "Forcefully taking over lock '%s' held by %s", path, existingHolder)
+	case l.isStale(existingHolder, holder.Hostname):
+		l.logger.Warn(l.logTag, "Taking over stale lock '%s' held by %s", path, existingHolder)
+	default:
+		return nil, HeldError{Path: path, Holder: existingHolder}
+	}
+
+	err = l.takeOver(path, existingHolder, holder, force)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lock{path: path, holder: holder, fs: l.fs, logger: l.logger, logTag: l.logTag}, nil
+}
+
+// takeOver replaces the lock file with one recording holder, if it is still held by previousHolder.
+// The process taking over a lock holds a second lock file while it checks and replaces the lock file,
+// so that only one of the processes that found the same previous holder replaces it.
+func (l *locker) takeOver(path string, previousHolder Holder, holder Holder, force bool) error {
+	takeOverPath := path + ".takeover"
+	err := l.lockTakeOver(takeOverPath, path, holder, force)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := l.fs.RemoveAll(takeOverPath)
+		if err != nil {
+			l.logger.Warn(l.logTag, "Failed to remove lock file '%s': %s", takeOverPath, err.Error())
+		}
+	}()
+
+	if l.fs.FileExists(path) {
+		currentHolder, err := readHolder(l.fs, path)
+		if err != nil && !force {
+			return err
+		}
+
+		if err == nil && !currentHolder.isSameProcess(previousHolder) {
+			// another process took over the lock in the meantime
+			return HeldError{Path: path, Holder: currentHolder}
+		}
+
+		err = l.fs.RemoveAll(path)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing lock file '%s'", path)
+		}
+	}
+
+	err = l.create(path, holder)
+	if err != nil {
+		if os.IsExist(err) {
+			// another process locked it after the previous holder released it
+			return l.heldError(path)
+		}
+		return bosherr.WrapErrorf(err, "Creating lock file '%s'", path)
+	}
+
+	return nil
+}
+
+// lockTakeOver creates the lock file of a takeover. It is left behind only if the process exits while taking over,
+// in which case it is removed with force.
+func (l *locker) lockTakeOver(takeOverPath string, path string, holder Holder, force bool) error {
+	err := l.create(takeOverPath, holder)
+	if err == nil {
+		return nil
+	}
+
+	if !os.IsExist(err) {
+		return bosherr.WrapErrorf(err, "Creating lock file '%s'", takeOverPath)
+	}
+
+	if !force {
+		if !l.fs.FileExists(takeOverPath) {
+			// the other takeover has finished
+			return l.heldError(path)
+		}
+		return l.heldError(takeOverPath)
+	}
+
+	l.logger.Warn(l.logTag, "Forcefully removing lock file '%s'", takeOverPath)
+	err = l.fs.RemoveAll(takeOverPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing lock file '%s'", takeOverPath)
+	}
+
+	err = l.create(takeOverPath, holder)
+	if err != nil {
+		if os.IsExist(err) {
+			return l.heldError(takeOverPath)
+		}
+		return bosherr.WrapErrorf(err, "Creating lock file '%s'", takeOverPath)
+	}
+
+	return nil
+}
+
+// heldError returns a HeldError describing the holder of the lock file at path
+func (l *locker) heldError(path string) error {
+	existingHolder, err := readHolder(l.fs, path)
+	if err != nil {
+		return err
+	}
+	return HeldError{Path: path, Holder: existingHolder}
+}
+
+func (l *locker) currentHolder() (Holder, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return Holder{}, bosherr.WrapError(err, "Getting hostname")
+	}
+
+	return Holder{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		Command:   strings.Join(os.Args, " "),
+		StartedAt: l.timeService.Now().UTC(),
+	}, nil
+}
+
+// create writes the holder to a temporary file and links it to path, so that the lock file never exists without its holder.
+// It fails with an error satisfying os.IsExist if the lock file already exists.
+func (l *locker) create(path string, holder Holder) error {
+	contents, err := json.Marshal(holder)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling lock holder")
+	}
+
+	err = l.fs.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating lock directory '%s'", filepath.Dir(path))
+	}
+
+	tmpPath := fmt.Sprintf("%s.%s-%d-%d.tmp", path, holder.Hostname, holder.PID, holder.StartedAt.UnixNano())
+	err = l.fs.WriteFile(tmpPath, contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing lock file '%s'", tmpPath)
+	}
+	defer func() {
+		err := l.fs.RemoveAll(tmpPath)
+		if err != nil {
+			l.logger.Warn(l.logTag, "Failed to remove lock file '%s': %s", tmpPath, err.Error())
+		}
+	}()
+
+	// unlike a rename, a hard link fails if the lock file exists; the file system abstraction has no links
+	return os.Link(tmpPath, path)
+}
+
+// isStale returns true if the holder was running on this host and no longer is.
+// Processes on other hosts, e.g. sharing the state file over NFS, cannot be checked.
+func (l *locker) isStale(holder Holder, hostname string) bool {
+	if holder.Hostname != hostname {
+		return false
+	}
+
+	err := syscall.Kill(holder.PID, syscall.Signal(0))
+	return err == syscall.ESRCH
+}
+
+func readHolder(fs boshsys.FileSystem, path string) (Holder, error) {
+	contents, err := fs.ReadFile(path)
+	if err != nil {
+		return Holder{}, bosherr.WrapErrorf(err, "Reading lock file '%s'", path)
+	}
+
+	var holder Holder
+	err = json.Unmarshal(contents, &holder)
+	if err != nil {
+		return Holder{}, bosherr.WrapErrorf(err, "Unmarshalling lock file '%s'", path)
+	}
+
+	return holder, nil
+}
+
+type lock struct {
+	path   string
+	holder Holder
+	fs     boshsys.FileSystem
+	logger boshlog.Logger
+	logTag string
+}
+
+func (l *lock) Release() error {
+	if !l.fs.FileExists(l.path) {
+		l.logger.Warn(l.logTag, "Lock file '%s' was removed by another process", l.path)
+		return nil
+	}
+
+	holder, err := readHolder(l.fs, l.path)
+	if err != nil {
+		// nobody else can hold the lock through an unreadable lock file
+		l.logger.Warn(l.logTag, "Removing lock file '%s' with an unreadable holder: %s", l.path, err.Error())
+	} else if !holder.isSameProcess(l.holder) {
+		l.logger.Warn(l.logTag, "Not releasing lock '%s', which was taken over by %s", l.path, holder)
+		return nil
+	}
+
+	err = l.fs.RemoveAll(l.path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing lock file '%s'", l.path)
+	}
+
+	return nil
+}
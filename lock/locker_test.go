@@ -0,0 +1,239 @@
+package lock_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/lock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("Locker", func() {
+	var (
+		fs       boshsys.FileSystem
+		locker   Locker
+		tmpDir   string
+		lockPath string
+		hostname string
+
+		startedAt = time.Date(2016, time.March, 4, 13, 14, 15, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs = boshsys.NewOsFileSystem(logger)
+		locker = NewLocker(fs, fakeclock.NewFakeClock(startedAt), logger)
+
+		var err error
+		tmpDir, err = ioutil.TempDir("", "bosh-init-lock")
+		Expect(err).ToNot(HaveOccurred())
+		lockPath = filepath.Join(tmpDir, "fake-state.json.lock")
+
+		hostname, err = os.Hostname()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	var readHolder = func() Holder {
+		contents, err := fs.ReadFile(lockPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		var holder Holder
+		err = json.Unmarshal(contents, &holder)
+		Expect(err).ToNot(HaveOccurred())
+		return holder
+	}
+
+	var writeHolder = func(holder Holder) {
+		contents, err := json.Marshal(holder)
+		Expect(err).ToNot(HaveOccurred())
+		err = fs.WriteFile(lockPath, contents)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// deadPID returns the PID of a process that is no longer running
+	var deadPID = func() int {
+		cmd := exec.Command("true")
+		err := cmd.Run()
+		Expect(err).ToNot(HaveOccurred())
+		return cmd.Process.Pid
+	}
+
+	It("records the current process as the holder of the lock", func() {
+		_, err := locker.Lock(lockPath, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		holder := readHolder()
+		Expect(holder.PID).To(Equal(os.Getpid()))
+		Expect(holder.Hostname).To(Equal(hostname))
+		Expect(holder.Command).ToNot(BeEmpty())
+		Expect(holder.StartedAt.Equal(startedAt)).To(BeTrue())
+	})
+
+	It("returns an error describing the holder when the lock is held by a running process", func() {
+		writeHolder(Holder{PID: os.Getpid(), Hostname: hostname, Command: "bosh-init deploy fake-manifest.yml", StartedAt: startedAt})
+
+		_, err := locker.Lock(lockPath, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(BeAssignableToTypeOf(HeldError{}))
+		Expect(err.Error()).To(ContainSubstring("is held by 'bosh-init deploy fake-manifest.yml'"))
+		Expect(err.Error()).To(ContainSubstring("since 2016-03-04T13:14:15Z"))
+		Expect(IsHeld(bosherr.WrapError(err, "fake-wrapping-error"))).To(BeTrue())
+		Expect(IsHeld(bosherr.Error("fake-other-error"))).To(BeFalse())
+	})
+
+	It("takes over a lock held by a process that is no longer running", func() {
+		writeHolder(Holder{PID: deadPID(), Hostname: hostname, Command: "bosh-init deploy fake-manifest.yml"})
+
+		_, err := locker.Lock(lockPath, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readHolder().PID).To(Equal(os.Getpid()))
+	})
+
+	It("does not take over a lock held by a process on another host", func() {
+		writeHolder(Holder{PID: deadPID(), Hostname: "fake-other-host", Command: "bosh-init deploy fake-manifest.yml"})
+
+		_, err := locker.Lock(lockPath, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(BeAssignableToTypeOf(HeldError{}))
+	})
+
+	It("takes over a lock held by a running process when forced", func() {
+		writeHolder(Holder{PID: os.Getpid(), Hostname: "fake-other-host", Command: "bosh-init deploy fake-manifest.yml"})
+
+		_, err := locker.Lock(lockPath, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readHolder().Hostname).To(Equal(hostname))
+	})
+
+	It("lets only one of the processes that found the same stale lock take it over", func() {
+		writeHolder(Holder{PID: deadPID(), Hostname: hostname, Command: "bosh-init deploy fake-manifest.yml"})
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		errs := make(chan error)
+		for i := 0; i < 10; i++ {
+			otherLocker := NewLocker(fs, fakeclock.NewFakeClock(startedAt.Add(time.Duration(i)*time.Second)), logger)
+			go func() {
+				_, err := otherLocker.Lock(lockPath, false)
+				errs <- err
+			}()
+		}
+
+		lockedTimes := 0
+		for i := 0; i < 10; i++ {
+			err := <-errs
+			if err == nil {
+				lockedTimes++
+			} else {
+				Expect(err).To(BeAssignableToTypeOf(HeldError{}))
+			}
+		}
+		Expect(lockedTimes).To(Equal(1))
+		Expect(fs.FileExists(lockPath + ".takeover")).To(BeFalse())
+	})
+
+	It("removes the lock file of an interrupted takeover when forced", func() {
+		writeHolder(Holder{PID: deadPID(), Hostname: hostname, Command: "bosh-init deploy fake-manifest.yml"})
+		contents, err := json.Marshal(Holder{PID: deadPID(), Hostname: hostname, Command: "bosh-init deploy fake-manifest.yml"})
+		Expect(err).ToNot(HaveOccurred())
+		err = fs.WriteFile(lockPath+".takeover", contents)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = locker.Lock(lockPath, false)
+		Expect(err).To(BeAssignableToTypeOf(HeldError{}))
+
+		_, err = locker.Lock(lockPath, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readHolder().PID).To(Equal(os.Getpid()))
+		Expect(fs.FileExists(lockPath + ".takeover")).To(BeFalse())
+	})
+
+	It("never leaves a lock file without its holder", func() {
+		_, err := locker.Lock(lockPath, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		matches, err := filepath.Glob(filepath.Join(tmpDir, "*"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(Equal([]string{lockPath}))
+	})
+
+	It("returns an error when the holder of the lock cannot be read", func() {
+		err := fs.WriteFileString(lockPath, "")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = locker.Lock(lockPath, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unmarshalling lock file"))
+	})
+
+	It("takes over a lock whose holder cannot be read when forced", func() {
+		err := fs.WriteFileString(lockPath, "")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = locker.Lock(lockPath, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readHolder().PID).To(Equal(os.Getpid()))
+	})
+
+	Describe("Release", func() {
+		It("deletes the lock file", func() {
+			lock, err := locker.Lock(lockPath, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = lock.Release()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists(lockPath)).To(BeFalse())
+
+			_, err = locker.Lock(lockPath, false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("keeps the lock file when the lock was taken over", func() {
+			lock, err := locker.Lock(lockPath, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			otherHolder := Holder{PID: os.Getpid(), Hostname: "fake-other-host", Command: "bosh-init deploy fake-manifest.yml"}
+			writeHolder(otherHolder)
+
+			err = lock.Release()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readHolder().Hostname).To(Equal("fake-other-host"))
+		})
+
+		It("deletes the lock file when its holder cannot be read", func() {
+			lock, err := locker.Lock(lockPath, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString(lockPath, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = lock.Release()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists(lockPath)).To(BeFalse())
+		})
+
+		It("succeeds when the lock file was removed", func() {
+			lock, err := locker.Lock(lockPath, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.RemoveAll(lockPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = lock.Release()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -31,6 +31,7 @@ func main() {
 	ui := biui.NewConsoleUI(logger)
 
 	timeService := clock.NewClock()
+	interruptCh := handleInterrupts(ui, logger)
 
 	cmdFactory := bicmd.NewFactory(
 		fileSystem,
@@ -39,10 +40,11 @@ func main() {
 		logger,
 		boshuuid.NewGenerator(),
 		workspaceRootPath,
+		interruptCh,
 	)
 
 	cmdRunner := bicmd.NewRunner(cmdFactory)
-	stage := biui.NewStage(ui, timeService, logger)
+	stage := biui.NewInterruptibleStage(ui, timeService, interruptCh, logger)
 	err := cmdRunner.Run(stage, os.Args[1:]...)
 	if err != nil {
 		displayHelpFunc := func() {
@@ -103,6 +105,28 @@ func newSignalableFileLogger(logPath string, level boshlog.LogLevel) boshlog.Log
 	return newSignalableLogger(logfileLogger)
 }
 
+// handleInterrupts returns a channel that is closed on the first SIGINT or SIGTERM, so that the current step
+// can finish and clean up. A second signal exits immediately.
+func handleInterrupts(ui biui.UI, logger boshlog.Logger) <-chan struct{} {
+	signalCh := make(chan os.Signal, 2)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+
+	interruptCh := make(chan struct{})
+	go func() {
+		sig := <-signalCh
+		logger.Warn(mainLogTag, "Received %s, stopping after the current step", sig)
+		ui.ErrorLinef("")
+		ui.ErrorLinef("Interrupted, stopping after the current step. Interrupt again to exit immediately, which may leave resources that are not recorded in the deployment state.")
+		close(interruptCh)
+
+		sig = <-signalCh
+		logger.Error(mainLogTag, "Received %s again, exiting immediately", sig)
+		os.Exit(130)
+	}()
+
+	return interruptCh
+}
+
 func fail(err error, ui biui.UI, logger boshlog.Logger, callback func()) {
 	logger.Error(mainLogTag, err.Error())
 	ui.ErrorLinef("")
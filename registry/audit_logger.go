@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// auditLogger records every registry request to the logger and, if configured, to an append-only file
+type auditLogger struct {
+	path   string
+	file   boshsys.File
+	lock   sync.Mutex
+	fs     boshsys.FileSystem
+	logger boshlog.Logger
+	logTag string
+}
+
+func newAuditLogger(path string, fs boshsys.FileSystem, logger boshlog.Logger) *auditLogger {
+	return &auditLogger{
+		path:   path,
+		fs:     fs,
+		logger: logger,
+		logTag: "registryAudit",
+	}
+}
+
+func (l *auditLogger) Open() error {
+	if l.path == "" {
+		return nil
+	}
+
+	file, err := l.fs.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening audit log file '%s'", l.path)
+	}
+	l.file = file
+
+	return nil
+}
+
+func (l *auditLogger) Record(method, path, remoteAddr, username string, statusCode int) {
+	if username == "" {
+		username = "-"
+	}
+
+	entry := fmt.Sprintf("%s %s from %s user %s: %d", method, path, remoteAddr, username, statusCode)
+	l.logger.Info(l.logTag, entry)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return
+	}
+
+	_, err := l.file.Write([]byte(fmt.Sprintf("%s %s\n", time.Now().UTC().Format(time.RFC3339), entry)))
+	if err != nil {
+		l.logger.Warn(l.logTag, "Failed to write audit log entry: %s", err.Error())
+	}
+}
+
+func (l *auditLogger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
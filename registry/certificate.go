@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// CertificateValidity is how long a generated certificate is valid
+const CertificateValidity = 365 * 24 * time.Hour
+
+// GenerateCertificate creates a self-signed certificate valid for the registry host, it is also the CA agents verify the registry with.
+// The certificate and its private key are PEM encoded.
+func GenerateCertificate(host string) ([]byte, []byte, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Generating private key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Generating serial number")
+	}
+
+	notBefore := time.Now().Add(-1 * time.Hour)
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"bosh-init"}, CommonName: "bosh-init registry"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(CertificateValidity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else if host != "" {
+		template.DNSNames = []string{host}
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Creating certificate")
+	}
+
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+
+	return certificatePEM, privateKeyPEM, nil
+}
+
+// CertificateExpiresBefore returns whether the PEM encoded certificate is no longer valid at t
+func CertificateExpiresBefore(certificatePEM []byte, t time.Time) (bool, error) {
+	block, _ := pem.Decode(certificatePEM)
+	if block == nil {
+		return false, bosherr.Error("Decoding certificate PEM")
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Parsing certificate")
+	}
+
+	return certificate.NotAfter.Before(t), nil
+}
@@ -0,0 +1,40 @@
+package registry_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-init/registry"
+)
+
+var _ = Describe("Certificate", func() {
+	Describe("CertificateExpiresBefore", func() {
+		var certificatePEM []byte
+
+		BeforeEach(func() {
+			var err error
+			certificatePEM, _, err = GenerateCertificate("127.0.0.1")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns false while the generated certificate is valid", func() {
+			expiring, err := CertificateExpiresBefore(certificatePEM, time.Now().Add(CertificateValidity-2*time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expiring).To(BeFalse())
+		})
+
+		It("returns true once the generated certificate expired", func() {
+			expiring, err := CertificateExpiresBefore(certificatePEM, time.Now().Add(CertificateValidity))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expiring).To(BeTrue())
+		})
+
+		It("returns an error for an invalid certificate", func() {
+			_, err := CertificateExpiresBefore([]byte("fake-certificate"), time.Now())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Decoding certificate PEM"))
+		})
+	})
+})
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+
+	biutil "github.com/cloudfoundry/bosh-init/common/util"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type fileRegistry struct {
+	path      string
+	fs        boshsys.FileSystem
+	encryptor bicrypto.Encryptor
+	lock      sync.Mutex
+	logger    boshlog.Logger
+	logTag    string
+}
+
+// NewFileRegistry returns a Registry that keeps its contents in a JSON file,
+// so that agents can retrieve their settings after bosh-init has exited.
+// The file is removed once the last entry is deleted.
+func NewFileRegistry(path string, fs boshsys.FileSystem, logger boshlog.Logger) Registry {
+	return NewEncryptedFileRegistry(path, fs, logger, nil)
+}
+
+// NewEncryptedFileRegistry returns a file Registry that saves its contents encrypted with encryptor, like the deployment state.
+// Encrypted and plain text files are both loaded; with a nil encryptor, the contents are saved in plain text.
+func NewEncryptedFileRegistry(path string, fs boshsys.FileSystem, logger boshlog.Logger, encryptor bicrypto.Encryptor) Registry {
+	return &fileRegistry{
+		path:      path,
+		fs:        fs,
+		encryptor: encryptor,
+		logger:    logger,
+		logTag:    "fileRegistry",
+	}
+}
+
+func (r *fileRegistry) Save(key string, value []byte) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	contents, err := r.load()
+	if err != nil {
+		return false, err
+	}
+
+	_, exists := contents[key]
+	contents[key] = string(value)
+
+	err = r.save(contents)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *fileRegistry) Get(key string) ([]byte, bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	contents, err := r.load()
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, exists := contents[key]
+	if !exists {
+		return nil, false, nil
+	}
+
+	return []byte(value), true, nil
+}
+
+func (r *fileRegistry) Delete(key string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	contents, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	delete(contents, key)
+
+	if len(contents) == 0 {
+		r.logger.Debug(r.logTag, "Removing empty registry file '%s'", r.path)
+		err = r.fs.RemoveAll(r.path)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing registry file '%s'", r.path)
+		}
+		return nil
+	}
+
+	return r.save(contents)
+}
+
+func (r *fileRegistry) Keys() ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	contents, err := r.load()
+	if err != nil {
+		return []string{}, err
+	}
+
+	keys := make([]string, 0, len(contents))
+	for key := range contents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (r *fileRegistry) load() (map[string]string, error) {
+	contents := map[string]string{}
+
+	if !r.fs.FileExists(r.path) {
+		return contents, nil
+	}
+
+	bytes, err := r.fs.ReadFile(r.path)
+	if err != nil {
+		return contents, bosherr.WrapErrorf(err, "Reading registry file '%s'", r.path)
+	}
+
+	if bicrypto.IsEncrypted(string(bytes)) {
+		if r.encryptor == nil {
+			return contents, bosherr.Errorf("Registry file '%s' is encrypted, but no passphrase or key file was given", r.path)
+		}
+
+		bytes, err = r.encryptor.Decrypt(string(bytes))
+		if err != nil {
+			return contents, bosherr.WrapErrorf(err, "Decrypting registry file '%s'", r.path)
+		}
+	}
+
+	err = json.Unmarshal(bytes, &contents)
+	if err != nil {
+		return contents, bosherr.WrapErrorf(err, "Unmarshalling registry file '%s'", r.path)
+	}
+
+	return contents, nil
+}
+
+// save atomically replaces the registry file, readable only by the owner since it holds the agent settings
+func (r *fileRegistry) save(contents map[string]string) error {
+	bytes, err := json.MarshalIndent(contents, "", "    ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling registry contents")
+	}
+
+	if r.encryptor != nil {
+		encrypted, err := r.encryptor.Encrypt(bytes)
+		if err != nil {
+			return bosherr.WrapError(err, "Encrypting registry contents")
+		}
+		bytes = []byte(encrypted)
+	}
+
+	err = biutil.WriteFileAtomically(r.fs, r.path, bytes, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing registry file '%s'", r.path)
+	}
+
+	return nil
+}
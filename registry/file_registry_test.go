@@ -0,0 +1,167 @@
+package registry_test
+
+import (
+	"errors"
+	"os"
+
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	. "github.com/cloudfoundry/bosh-init/registry"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("FileRegistry", func() {
+	var (
+		fs       *fakesys.FakeFileSystem
+		registry Registry
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		registry = NewFileRegistry("/fake-path/registry.json", fs, logger)
+	})
+
+	Describe("Save", func() {
+		It("persists the settings", func() {
+			updated, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated).To(BeFalse())
+
+			Expect(fs.FileExists("/fake-path/registry.json")).To(BeTrue())
+
+			reloadedRegistry := NewFileRegistry("/fake-path/registry.json", fs, boshlog.NewLogger(boshlog.LevelNone))
+			settings, found, err := reloadedRegistry.Get("fake-instance-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(settings).To(Equal([]byte("fake-settings")))
+		})
+
+		It("replaces the file atomically and makes it readable only by the owner", func() {
+			_, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.RenameOldPaths).To(Equal([]string{"/fake-path/registry.json.tmp"}))
+			Expect(fs.RenameNewPaths).To(Equal([]string{"/fake-path/registry.json"}))
+			Expect(fs.GetFileTestStat("/fake-path/registry.json").FileMode).To(Equal(os.FileMode(0600)))
+		})
+
+		It("reports when existing settings are updated", func() {
+			_, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+			Expect(err).ToNot(HaveOccurred())
+
+			updated, err := registry.Save("fake-instance-id", []byte("fake-new-settings"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated).To(BeTrue())
+
+			settings, _, err := registry.Get("fake-instance-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings).To(Equal([]byte("fake-new-settings")))
+		})
+
+		Context("when writing the file fails", func() {
+			BeforeEach(func() {
+				fs.OpenFileErr = errors.New("fake-write-error")
+			})
+
+			It("returns an error", func() {
+				_, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-write-error"))
+			})
+		})
+	})
+
+	Describe("Get", func() {
+		It("returns not found when the file does not exist", func() {
+			_, found, err := registry.Get("fake-instance-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns an error when the file is not valid", func() {
+			fs.WriteFileString("/fake-path/registry.json", "not-json")
+
+			_, _, err := registry.Get("fake-instance-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling registry file '/fake-path/registry.json'"))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("removes the settings", func() {
+			_, err := registry.Save("fake-instance-id-1", []byte("fake-settings-1"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = registry.Save("fake-instance-id-2", []byte("fake-settings-2"))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = registry.Delete("fake-instance-id-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			keys, err := registry.Keys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"fake-instance-id-2"}))
+		})
+
+		It("removes the file once the last settings are deleted", func() {
+			_, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = registry.Delete("fake-instance-id")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/fake-path/registry.json")).To(BeFalse())
+		})
+	})
+
+	Context("with an encryptor", func() {
+		var encryptor bicrypto.Encryptor
+
+		BeforeEach(func() {
+			encryptor = bicrypto.NewPassphraseEncryptor("fake-passphrase")
+			registry = NewEncryptedFileRegistry("/fake-path/registry.json", fs, boshlog.NewLogger(boshlog.LevelNone), encryptor)
+		})
+
+		It("saves the settings encrypted", func() {
+			_, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/fake-path/registry.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(HavePrefix(bicrypto.EncryptedPrefix))
+			Expect(contents).ToNot(ContainSubstring("fake-settings"))
+
+			settings, found, err := registry.Get("fake-instance-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(settings).To(Equal([]byte("fake-settings")))
+		})
+
+		It("loads a plain text file and encrypts it on the next save", func() {
+			fs.WriteFileString("/fake-path/registry.json", `{"fake-instance-id":"fake-settings"}`)
+
+			_, err := registry.Save("fake-instance-id-2", []byte("fake-settings-2"))
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/fake-path/registry.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(HavePrefix(bicrypto.EncryptedPrefix))
+
+			keys, err := registry.Keys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"fake-instance-id", "fake-instance-id-2"}))
+		})
+
+		It("returns an error when reading an encrypted file without an encryptor", func() {
+			_, err := registry.Save("fake-instance-id", []byte("fake-settings"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = NewFileRegistry("/fake-path/registry.json", fs, boshlog.NewLogger(boshlog.LevelNone)).Get("fake-instance-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Registry file '/fake-path/registry.json' is encrypted, but no passphrase or key file was given"))
+		})
+	})
+})
@@ -1,38 +1,59 @@
 package registry
 
 import (
-	"encoding/base64"
+	"crypto/subtle"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 type instanceHandler struct {
-	username string
-	password string
-	registry Registry
-	logger   boshlog.Logger
-	logTag   string
+	username            string
+	password            string
+	agentCredentials    Credentials
+	instanceCredentials Registry
+	registry            Registry
+	auditLogger         *auditLogger
+	logger              boshlog.Logger
+	logTag              string
 }
 
 func newInstanceHandler(
 	username string,
 	password string,
+	agentCredentials Credentials,
+	instanceCredentials Registry,
 	registry Registry,
+	auditLogger *auditLogger,
 	logger boshlog.Logger,
 ) *instanceHandler {
 	return &instanceHandler{
-		username: username,
-		password: password,
-		registry: registry,
-		logger:   logger,
-		logTag:   "registryInstanceHandler",
+		username:            username,
+		password:            password,
+		agentCredentials:    agentCredentials,
+		instanceCredentials: instanceCredentials,
+		registry:            registry,
+		auditLogger:         auditLogger,
+		logger:              logger,
+		logTag:              "registryInstanceHandler",
 	}
 }
 
+// statusRecorder remembers the response status code for the audit log
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
 type SettingsResponse struct {
 	Settings string `json:"settings"`
 	Status   string `json:"status"`
@@ -40,6 +61,14 @@ type SettingsResponse struct {
 
 func (h *instanceHandler) HandleFunc(w http.ResponseWriter, req *http.Request) {
 	h.logger.Debug(h.logTag, "Received %s %s", req.Method, req.URL.Path)
+
+	recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	defer func() {
+		username, _, _ := req.BasicAuth()
+		h.auditLogger.Record(req.Method, req.URL.Path, req.RemoteAddr, username, recorder.statusCode)
+	}()
+	w = recorder
+
 	instanceID, ok := h.getInstanceID(req)
 	if !ok {
 		h.logger.Debug(h.logTag, "Instance ID not found in request:", req.Method)
@@ -66,7 +95,25 @@ func (h *instanceHandler) HandleFunc(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h *instanceHandler) HandleGet(instanceID string, w http.ResponseWriter, req *http.Request) {
-	settingsJSON, ok := h.registry.Get(instanceID)
+	authorized, err := h.isAuthorizedForInstance(instanceID, req)
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed to get credentials for instance %s: %s", instanceID, err.Error())
+		h.handleInternalServerError(w)
+		return
+	}
+
+	if !authorized {
+		h.handleUnauthorized(w)
+		return
+	}
+
+	settingsJSON, ok, err := h.registry.Get(instanceID)
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed to get settings for instance %s: %s", instanceID, err.Error())
+		h.handleInternalServerError(w)
+		return
+	}
+
 	if !ok {
 		h.logger.Debug(h.logTag, "No settings for %s found", instanceID)
 		h.handleNotFound(w)
@@ -108,12 +155,26 @@ func (h *instanceHandler) HandlePut(instanceID string, w http.ResponseWriter, re
 
 	h.logger.Debug(h.logTag, "Saving settings to registry for instance %s: %s", instanceID, string(reqBody))
 
-	isUpdated := h.registry.Save(instanceID, reqBody)
+	isUpdated, err := h.registry.Save(instanceID, reqBody)
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed to save settings for instance %s: %s", instanceID, err.Error())
+		h.handleInternalServerError(w)
+		return
+	}
+
 	if isUpdated {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	// the CPI saves the settings of a VM when it creates it, and the agent of that VM got the agent credentials
+	err = h.bindAgentCredentials(instanceID)
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed to save credentials for instance %s: %s", instanceID, err.Error())
+		h.handleInternalServerError(w)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -124,7 +185,19 @@ func (h *instanceHandler) HandleDelete(instanceID string, w http.ResponseWriter,
 	}
 
 	h.logger.Debug(h.logTag, "Deleting settings for instance %s", instanceID)
-	h.registry.Delete(instanceID)
+	err := h.registry.Delete(instanceID)
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed to delete settings for instance %s: %s", instanceID, err.Error())
+		h.handleInternalServerError(w)
+		return
+	}
+
+	err = h.instanceCredentials.Delete(instanceID)
+	if err != nil {
+		h.logger.Error(h.logTag, "Failed to delete credentials for instance %s: %s", instanceID, err.Error())
+		h.handleInternalServerError(w)
+		return
+	}
 }
 
 func (h *instanceHandler) handleUnauthorized(w http.ResponseWriter) {
@@ -145,10 +218,60 @@ func (h *instanceHandler) getInstanceID(req *http.Request) (string, bool) {
 }
 
 func (h *instanceHandler) isAuthorized(req *http.Request) bool {
-	auth := h.username + ":" + h.password
-	expectedAuthorizationHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
+	return h.hasCredentials(req, Credentials{Username: h.username, Password: h.password})
+}
 
-	return expectedAuthorizationHeader == req.Header.Get("Authorization")
+// isAuthorizedForInstance allows the admin and the credentials bound to the instance to read its settings.
+// Anybody may read the settings of an instance without bound credentials, e.g. of a VM created before they were bound.
+func (h *instanceHandler) isAuthorizedForInstance(instanceID string, req *http.Request) (bool, error) {
+	if h.isAuthorized(req) {
+		return true, nil
+	}
+
+	credentialsJSON, found, err := h.instanceCredentials.Get(instanceID)
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return true, nil
+	}
+
+	var credentials Credentials
+	err = json.Unmarshal(credentialsJSON, &credentials)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Unmarshalling instance credentials")
+	}
+
+	return h.hasCredentials(req, credentials), nil
+}
+
+// bindAgentCredentials restricts reading the settings of the instance to the agent credentials, if there are any
+func (h *instanceHandler) bindAgentCredentials(instanceID string) error {
+	if h.agentCredentials.Username == "" {
+		return nil
+	}
+
+	credentialsJSON, err := json.Marshal(h.agentCredentials)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling instance credentials")
+	}
+
+	h.logger.Debug(h.logTag, "Binding the agent credentials to instance %s", instanceID)
+	_, err = h.instanceCredentials.Save(instanceID, credentialsJSON)
+	return err
+}
+
+func (h *instanceHandler) hasCredentials(req *http.Request, credentials Credentials) bool {
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(credentials.Username)) == 1
+	passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(credentials.Password)) == 1
+
+	return usernameMatches && passwordMatches
 }
 
 func (h *instanceHandler) handleNotFound(w http.ResponseWriter) {
@@ -176,3 +299,16 @@ func (h *instanceHandler) handleBadRequest(w http.ResponseWriter) {
 		h.logger.Warn(h.logTag, "Failed to write response: %s", err.Error())
 	}
 }
+
+func (h *instanceHandler) handleInternalServerError(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusInternalServerError)
+	settingsJSON, err := json.Marshal(SettingsResponse{Status: "error"})
+	if err != nil {
+		h.logger.Warn(h.logTag, "Failed to marshal 'internal server error' settings response %s", err.Error())
+		return
+	}
+	_, err = w.Write(settingsJSON)
+	if err != nil {
+		h.logger.Warn(h.logTag, "Failed to write response: %s", err.Error())
+	}
+}
@@ -1,40 +1,108 @@
 package registry
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+type ServerConfig struct {
+	Username string
+	Password string
+	Host     string
+	Port     int
+
+	// StorePath is the file the registry contents are persisted to; empty keeps them in memory.
+	StorePath string
+
+	TLS TLSConfig
+
+	// AgentCredentials are the credentials the agent of a VM created while the server runs reads its settings with.
+	// They are bound to the VM CID the CPI first saves settings for, and only they and the admin credentials
+	// can read the settings of that VM afterwards.
+	AgentCredentials Credentials
+
+	// InstanceCredentialsPath is the file the credentials bound to the VM CIDs are persisted to; empty keeps them in memory.
+	InstanceCredentialsPath string
+
+	// AuditLogPath is a file every request is additionally recorded to; empty logs only to the logger.
+	AuditLogPath string
+}
+
+type TLSConfig struct {
+	Enabled bool
+
+	// CertificatePath and PrivateKeyPath are PEM files; a self-signed certificate is generated when they and
+	// CertificatePEM and PrivateKeyPEM are empty.
+	CertificatePath string
+	PrivateKeyPath  string
+
+	// CertificatePEM and PrivateKeyPEM are used instead of the files, e.g. for a certificate generated earlier
+	CertificatePEM []byte
+	PrivateKeyPEM  []byte
+}
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 type ServerManager interface {
-	Start(string, string, string, int) (Server, error)
+	Start(ServerConfig) (Server, error)
 }
 
 type serverManager struct {
-	logger boshlog.Logger
-	logTag string
+	fs        boshsys.FileSystem
+	encryptor bicrypto.Encryptor
+	logger    boshlog.Logger
+	logTag    string
+}
+
+func NewServerManager(fs boshsys.FileSystem, logger boshlog.Logger) ServerManager {
+	return NewEncryptedServerManager(fs, logger, nil)
 }
 
-func NewServerManager(logger boshlog.Logger) ServerManager {
+// NewEncryptedServerManager returns a ServerManager whose servers save the registry store encrypted with encryptor
+func NewEncryptedServerManager(fs boshsys.FileSystem, logger boshlog.Logger, encryptor bicrypto.Encryptor) ServerManager {
 	return &serverManager{
-		logger: logger,
-		logTag: "registryServer",
+		fs:        fs,
+		encryptor: encryptor,
+		logger:    logger,
+		logTag:    "registryServer",
 	}
 }
 
 // Create starts a new server on a goroutine and returns it
 // The returned error is only for starting. Error while running is logged.
-func (s *serverManager) Start(username string, password string, host string, port int) (Server, error) {
+func (s *serverManager) Start(config ServerConfig) (Server, error) {
+	var registry Registry
+	if config.StorePath == "" {
+		registry = NewRegistry()
+	} else {
+		registry = NewEncryptedFileRegistry(config.StorePath, s.fs, s.logger, s.encryptor)
+	}
+
+	var instanceCredentials Registry
+	if config.InstanceCredentialsPath == "" {
+		instanceCredentials = NewRegistry()
+	} else {
+		instanceCredentials = NewEncryptedFileRegistry(config.InstanceCredentialsPath, s.fs, s.logger, s.encryptor)
+	}
+
 	startedCh := make(chan error)
 	server := &server{
+		fs:     s.fs,
 		logger: s.logger,
 		logTag: "registryServer",
 	}
 	go func() {
-		err := server.start(username, password, host, port, startedCh)
+		err := server.start(config, registry, instanceCredentials, startedCh)
 		if err != nil {
 			s.logger.Debug(s.logTag, "Registry error occurred: %s", err.Error())
 		}
@@ -55,9 +123,11 @@ type Server interface {
 }
 
 type server struct {
-	listener net.Listener
-	logger   boshlog.Logger
-	logTag   string
+	listener    net.Listener
+	auditLogger *auditLogger
+	fs          boshsys.FileSystem
+	logger      boshlog.Logger
+	logTag      string
 }
 
 func NewServer(logger boshlog.Logger) Server {
@@ -67,28 +137,90 @@ func NewServer(logger boshlog.Logger) Server {
 	}
 }
 
-func (s *server) start(username string, password string, host string, port int, readyErrCh chan error) error {
-	s.logger.Debug(s.logTag, "Starting registry server at %s:%d", host, port)
-	var err error
-	s.listener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+func (s *server) start(config ServerConfig, registry Registry, instanceCredentials Registry, readyErrCh chan error) error {
+	s.logger.Debug(s.logTag, "Starting registry server at %s:%d", config.Host, config.Port)
+
+	var tlsConfig *tls.Config
+	if config.TLS.Enabled {
+		certificate, err := s.loadCertificate(config)
+		if err != nil {
+			readyErrCh <- bosherr.WrapError(err, "Loading registry TLS certificate")
+			return nil
+		}
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	s.auditLogger = newAuditLogger(config.AuditLogPath, s.fs, s.logger)
+	err := s.auditLogger.Open()
 	if err != nil {
+		readyErrCh <- bosherr.WrapError(err, "Opening registry audit log")
+		return nil
+	}
+
+	s.listener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", config.Host, config.Port))
+	if err != nil {
+		if closeErr := s.auditLogger.Close(); closeErr != nil {
+			s.logger.Warn(s.logTag, "Failed to close registry audit log: %s", closeErr.Error())
+		}
 		readyErrCh <- bosherr.WrapError(err, "Starting registry listener")
 		return nil
 	}
 
+	if tlsConfig != nil {
+		s.listener = tls.NewListener(s.listener, tlsConfig)
+	}
+
 	readyErrCh <- nil
 
 	httpServer := http.Server{}
 	mux := http.NewServeMux()
 	httpServer.Handler = mux
 
-	registry := NewRegistry()
-	instanceHandler := newInstanceHandler(username, password, registry, s.logger)
+	instanceHandler := newInstanceHandler(config.Username, config.Password, config.AgentCredentials, instanceCredentials, registry, s.auditLogger, s.logger)
 	mux.HandleFunc("/instances/", instanceHandler.HandleFunc)
 
 	return httpServer.Serve(s.listener)
 }
 
+func (s *server) loadCertificate(config ServerConfig) (tls.Certificate, error) {
+	if len(config.TLS.CertificatePEM) > 0 || len(config.TLS.PrivateKeyPEM) > 0 {
+		certificate, err := tls.X509KeyPair(config.TLS.CertificatePEM, config.TLS.PrivateKeyPEM)
+		if err != nil {
+			return tls.Certificate{}, bosherr.WrapError(err, "Parsing certificate and private key")
+		}
+		return certificate, nil
+	}
+
+	if config.TLS.CertificatePath == "" && config.TLS.PrivateKeyPath == "" {
+		s.logger.Debug(s.logTag, "Generating self-signed registry certificate for '%s'", config.Host)
+		certificatePEM, privateKeyPEM, err := GenerateCertificate(config.Host)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		return tls.X509KeyPair(certificatePEM, privateKeyPEM)
+	}
+
+	certificatePEM, err := s.fs.ReadFile(config.TLS.CertificatePath)
+	if err != nil {
+		return tls.Certificate{}, bosherr.WrapErrorf(err, "Reading certificate file '%s'", config.TLS.CertificatePath)
+	}
+
+	privateKeyPEM, err := s.fs.ReadFile(config.TLS.PrivateKeyPath)
+	if err != nil {
+		return tls.Certificate{}, bosherr.WrapErrorf(err, "Reading private key file '%s'", config.TLS.PrivateKeyPath)
+	}
+
+	certificate, err := tls.X509KeyPair(certificatePEM, privateKeyPEM)
+	if err != nil {
+		return tls.Certificate{}, bosherr.WrapError(err, "Parsing certificate and private key")
+	}
+
+	return certificate, nil
+}
+
 func (s *server) Stop() error {
 	if s.listener == nil {
 		return bosherr.Error("Stopping not-started registry server")
@@ -100,5 +232,12 @@ func (s *server) Stop() error {
 		return bosherr.WrapError(err, "Stopping registry server")
 	}
 
+	if s.auditLogger != nil {
+		err = s.auditLogger.Close()
+		if err != nil {
+			s.logger.Warn(s.logTag, "Failed to close registry audit log: %s", err.Error())
+		}
+	}
+
 	return nil
 }
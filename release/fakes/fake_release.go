@@ -6,13 +6,15 @@ import (
 )
 
 type FakeRelease struct {
-	ReleaseName       string
-	ReleaseVersion    string
-	ReleaseJobs       []bireljob.Job
-	ReleasePackages   []*birelpkg.Package
-	ReleaseIsCompiled bool
-	DeleteCalled      bool
-	DeleteErr         error
+	ReleaseName        string
+	ReleaseVersion     string
+	ReleaseJobs        []bireljob.Job
+	ReleasePackages    []*birelpkg.Package
+	ReleaseIsCompiled  bool
+	ReleaseCommitHash  string
+	ReleaseUncommitted bool
+	DeleteCalled       bool
+	DeleteErr          error
 }
 
 func NewFakeRelease() *FakeRelease {
@@ -54,3 +56,7 @@ func (r *FakeRelease) Exists() bool {
 }
 
 func (r *FakeRelease) IsCompiled() bool { return r.ReleaseIsCompiled }
+
+func (r *FakeRelease) CommitHash() string { return r.ReleaseCommitHash }
+
+func (r *FakeRelease) UncommittedChanges() bool { return r.ReleaseUncommitted }
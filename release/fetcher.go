@@ -37,6 +37,10 @@ func (f Fetcher) DownloadAndExtract(releaseRef manifest.ReleaseRef, stage ui.Sta
 		if release.Name() != releaseRef.Name {
 			return bosherr.Errorf("Release name '%s' does not match the name in release tarball '%s'", releaseRef.Name, release.Name())
 		}
+
+		if releaseRef.IsVersionPinned() && release.Version() != releaseRef.Version {
+			return bosherr.Errorf("Release version '%s' does not match the version in release tarball '%s'", releaseRef.Version, release.Version())
+		}
 		f.releaseManager.Add(release)
 
 		return nil
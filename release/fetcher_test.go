@@ -0,0 +1,110 @@
+package release_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/release"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_tarball "github.com/cloudfoundry/bosh-init/installation/tarball/mocks"
+	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
+	"github.com/golang/mock/gomock"
+
+	fakebirel "github.com/cloudfoundry/bosh-init/release/fakes"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("Fetcher", func() {
+	var (
+		mockCtrl             *gomock.Controller
+		mockTarballProvider  *mock_tarball.MockProvider
+		mockReleaseExtractor *mock_release.MockExtractor
+		mockReleaseManager   *mock_release.MockManager
+		fakeStage            *fakebiui.FakeStage
+		releaseRef           birelmanifest.ReleaseRef
+		release              *fakebirel.FakeRelease
+		fetcher              Fetcher
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockTarballProvider = mock_tarball.NewMockProvider(mockCtrl)
+		mockReleaseExtractor = mock_release.NewMockExtractor(mockCtrl)
+		mockReleaseManager = mock_release.NewMockManager(mockCtrl)
+		fakeStage = fakebiui.NewFakeStage()
+
+		releaseRef = birelmanifest.ReleaseRef{
+			Name: "fake-release-name",
+			URL:  "file:///fake-release.tgz",
+		}
+		release = fakebirel.New("fake-release-name", "fake-release-version")
+
+		mockTarballProvider.EXPECT().Get(gomock.Any(), fakeStage).Return("/fake-release.tgz", nil).AnyTimes()
+		mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(release, nil).AnyTimes()
+
+		fetcher = NewFetcher(mockTarballProvider, mockReleaseExtractor, mockReleaseManager)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("DownloadAndExtract", func() {
+		It("adds the extracted release to the release manager", func() {
+			mockReleaseManager.EXPECT().Add(release)
+
+			err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when the release ref is pinned to the version of the tarball", func() {
+			BeforeEach(func() {
+				releaseRef.Version = "fake-release-version"
+			})
+
+			It("adds the extracted release to the release manager", func() {
+				mockReleaseManager.EXPECT().Add(release)
+
+				err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the release ref is pinned to another version", func() {
+			BeforeEach(func() {
+				releaseRef.Version = "fake-other-version"
+			})
+
+			It("returns an error naming the expected and actual version", func() {
+				err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Release version 'fake-other-version' does not match the version in release tarball 'fake-release-version'"))
+			})
+		})
+
+		Context("when the release ref asks for the latest version", func() {
+			BeforeEach(func() {
+				releaseRef.Version = "latest"
+			})
+
+			It("accepts any version in the tarball", func() {
+				mockReleaseManager.EXPECT().Add(release)
+
+				err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the name in the tarball does not match", func() {
+			BeforeEach(func() {
+				releaseRef.Name = "fake-other-name"
+			})
+
+			It("returns an error", func() {
+				err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Release name 'fake-other-name' does not match the name in release tarball 'fake-release-name'"))
+			})
+		})
+	})
+})
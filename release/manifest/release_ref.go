@@ -5,9 +5,10 @@ import (
 )
 
 type ReleaseRef struct {
-	Name string
-	URL  string
-	SHA1 string
+	Name    string
+	Version string
+	URL     string
+	SHA1    string
 }
 
 func (r ReleaseRef) GetURL() string {
@@ -18,6 +19,11 @@ func (r ReleaseRef) GetSHA1() string {
 	return r.SHA1
 }
 
+// IsVersionPinned returns true if the release must have Version, like in BOSH manifests 'latest' accepts any version
+func (r ReleaseRef) IsVersionPinned() bool {
+	return r.Version != "" && r.Version != "latest"
+}
+
 func (r ReleaseRef) Description() string {
 	return fmt.Sprintf("release '%s'", r.Name)
 }
@@ -8,8 +8,27 @@ type Package struct {
 	Dependencies  []*Package
 	ExtractedPath string
 	ArchivePath   string
+
+	// Source is the source package shipped next to a compiled package, if any.
+	// Its dependencies are not set, the dependencies of the compiled package are used.
+	Source *Package
 }
 
 func (p Package) String() string {
 	return p.Name
 }
+
+// IsCompiled returns true if the package was compiled against a stemcell
+func (p Package) IsCompiled() bool {
+	return p.Stemcell != ""
+}
+
+// UseSource replaces a compiled package with its source package, so that it is compiled.
+// Jobs refer to the same package, so they use the source package too.
+func (p *Package) UseSource() {
+	p.SHA1 = p.Source.SHA1
+	p.Stemcell = ""
+	p.ExtractedPath = p.Source.ExtractedPath
+	p.ArchivePath = p.Source.ArchivePath
+	p.Source = nil
+}
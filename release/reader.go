@@ -90,6 +90,9 @@ func (r *reader) newReleaseFromManifest(releaseManifest birelmanifest.Manifest)
 		extractedPath: r.extractedReleasePath,
 		fs:            r.fs,
 		isCompiled:    isCompiledRelease,
+
+		commitHash:         releaseManifest.CommitHash,
+		uncommittedChanges: releaseManifest.UncommittedChanges,
 	}
 
 	return release, nil
@@ -144,30 +147,44 @@ func (r *reader) findPackageByName(packages []*birelpkg.Package, pkgName string)
 }
 
 func (r *reader) newPackagesFromManifestPackages(releaseManifest birelmanifest.Manifest) ([]*birelpkg.Package, bool, error) {
+	if len(releaseManifest.CompiledPackages) == 0 {
+		packages, err := r.readPackages(releaseManifest.Packages, "packages", "extracted_packages", false)
+		return packages, false, err
+	}
 
-	manifestPackages := releaseManifest.Packages
-	isCompiledPackage := false
+	packages, err := r.readPackages(releaseManifest.CompiledPackages, "compiled_packages", "extracted_packages", true)
+	if err != nil {
+		return []*birelpkg.Package{}, true, err
+	}
+
+	if len(releaseManifest.Packages) > 0 {
+		// the source packages are compiled instead of compiled packages that do not match the stemcell
+		sourcePackages, err := r.readPackages(releaseManifest.Packages, "packages", "extracted_source_packages", false)
+		if err != nil {
+			return []*birelpkg.Package{}, true, bosherr.WrapError(err, "Reading source packages")
+		}
 
-	if len(releaseManifest.Packages) > 0 && len(releaseManifest.CompiledPackages) > 0 {
-		return []*birelpkg.Package{}, isCompiledPackage, bosherr.Errorf("Release '%s' contains compiled and non-compiled pacakges", releaseManifest.Name)
-	} else if len(releaseManifest.CompiledPackages) > 0 {
-		manifestPackages = releaseManifest.CompiledPackages
-		isCompiledPackage = true
+		for _, pkg := range packages {
+			sourcePackage, found := r.findPackageByName(sourcePackages, pkg.Name)
+			if !found || sourcePackage.Fingerprint != pkg.Fingerprint {
+				return []*birelpkg.Package{}, true, bosherr.Errorf("Release '%s' contains compiled and non-compiled pacakges, but compiled package '%s' does not have a matching source package", releaseManifest.Name, pkg.Name)
+			}
+			pkg.Source = sourcePackage
+		}
 	}
 
+	return packages, true, nil
+}
+
+func (r *reader) readPackages(manifestPackages []birelmanifest.PackageRef, packagesDirectory string, extractedPackagesDirectory string, isCompiledPackage bool) ([]*birelpkg.Package, error) {
 	packages := []*birelpkg.Package{}
 	errors := []error{}
 	packageRepo := &birelpkg.PackageRepo{}
 
-	packagesDirectory := "packages"
-	if isCompiledPackage {
-		packagesDirectory = "compiled_packages"
-	}
-
 	for _, manifestPackage := range manifestPackages {
 		pkg := packageRepo.FindOrCreatePackage(manifestPackage.Name)
 
-		extractedPackagePath := path.Join(r.extractedReleasePath, "extracted_packages", manifestPackage.Name)
+		extractedPackagePath := path.Join(r.extractedReleasePath, extractedPackagesDirectory, manifestPackage.Name)
 		err := r.fs.MkdirAll(extractedPackagePath, os.ModeDir|0700)
 		if err != nil {
 			errors = append(errors, bosherr.WrapError(err, "Creating extracted package path"))
@@ -199,8 +216,8 @@ func (r *reader) newPackagesFromManifestPackages(releaseManifest birelmanifest.M
 	}
 
 	if len(errors) > 0 {
-		return []*birelpkg.Package{}, isCompiledPackage, bosherr.NewMultiError(errors...)
+		return []*birelpkg.Package{}, bosherr.NewMultiError(errors...)
 	}
 
-	return packages, isCompiledPackage, nil
+	return packages, nil
 }
@@ -85,6 +85,8 @@ packages:
 							}
 							Expect(release.Name()).To(Equal("fake-release"))
 							Expect(release.Version()).To(Equal("fake-version"))
+							Expect(release.CommitHash()).To(Equal("abc123"))
+							Expect(release.UncommittedChanges()).To(BeTrue())
 							Expect(release.Jobs()).To(Equal([]bireljob.Job{
 								{
 									Name:          "fake-job",
@@ -355,6 +357,70 @@ compiled_packages:
 				})
 			})
 
+			Context("when the compiled release also contains source packages", func() {
+				BeforeEach(func() {
+					fakeFs.WriteFileString(
+						"/extracted/release/release.MF",
+						`---
+name: fake-release
+version: fake-version
+
+jobs:
+- name: fake-job
+  version: fake-job-version
+  fingerprint: fake-job-fingerprint
+  sha1: fake-job-sha
+
+compiled_packages:
+- name: fake-package
+  version: fake-package-version
+  fingerprint: fake-package-fingerprint
+  sha1: fake-compiled-package-sha
+  stemcell: centos/8547
+packages:
+- name: fake-package
+  version: fake-package-version
+  fingerprint: fake-package-fingerprint
+  sha1: fake-package-sha
+`,
+					)
+					fakeFs.WriteFileString(
+						"/extracted/release/extracted_jobs/fake-job/job.MF",
+						`---
+name: fake-job
+packages:
+- fake-package
+`,
+					)
+				})
+
+				It("reads the source package of each compiled package", func() {
+					release, err := reader.Read()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(release.IsCompiled()).To(BeTrue())
+
+					Expect(release.Packages()).To(Equal([]*birelpkg.Package{
+						{
+							Name:          "fake-package",
+							Fingerprint:   "fake-package-fingerprint",
+							SHA1:          "fake-compiled-package-sha",
+							Stemcell:      "centos/8547",
+							Dependencies:  []*birelpkg.Package{},
+							ExtractedPath: "/extracted/release/extracted_packages/fake-package",
+							ArchivePath:   "/extracted/release/compiled_packages/fake-package.tgz",
+							Source: &birelpkg.Package{
+								Name:          "fake-package",
+								Fingerprint:   "fake-package-fingerprint",
+								SHA1:          "fake-package-sha",
+								Dependencies:  []*birelpkg.Package{},
+								ExtractedPath: "/extracted/release/extracted_source_packages/fake-package",
+								ArchivePath:   "/extracted/release/packages/fake-package.tgz",
+							},
+						},
+					}))
+				})
+			})
+
 			Context("when the compiled release manifest is invalid", func() {
 				BeforeEach(func() {
 					fakeFs.WriteFileString(
@@ -394,7 +460,7 @@ packages:
 				It("returns an error when release contains compiled and non compiled packages", func() {
 					_, err := reader.Read()
 					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("Release 'fake-release' contains compiled and non-compiled pacakges"))
+					Expect(err.Error()).To(ContainSubstring("Release 'fake-release' contains compiled and non-compiled pacakges, but compiled package 'fake-compiled-package' does not have a matching source package"))
 				})
 
 			})
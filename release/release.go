@@ -14,6 +14,9 @@ type release struct {
 	extractedPath string
 	fs            boshsys.FileSystem
 	isCompiled    bool
+
+	commitHash         string
+	uncommittedChanges bool
 }
 
 type Release interface {
@@ -25,6 +28,8 @@ type Release interface {
 	Delete() error
 	Exists() bool
 	IsCompiled() bool
+	CommitHash() string
+	UncommittedChanges() bool
 }
 
 func NewRelease(
@@ -77,3 +82,7 @@ func (r *release) Exists() bool {
 }
 
 func (r *release) IsCompiled() bool { return r.isCompiled }
+
+func (r *release) CommitHash() string { return r.commitHash }
+
+func (r *release) UncommittedChanges() bool { return r.uncommittedChanges }
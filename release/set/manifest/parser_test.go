@@ -134,6 +134,34 @@ releases:
 			})
 		})
 	})
+
+	Context("when a release is pinned to a version", func() {
+		BeforeEach(func() {
+			fakeFs.WriteFileString(comboManifestPath, `
+---
+releases:
+- name: fake-release-name-1
+  version: fake-version-1
+  url: file:///absolute-path/fake-release-1.tgz
+`)
+		})
+
+		It("parses the version", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deploymentManifest).To(Equal(manifest.Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{
+						Name:    "fake-release-name-1",
+						Version: "fake-version-1",
+						URL:     "file:///absolute-path/fake-release-1.tgz",
+					},
+				},
+			}))
+		})
+	})
+
 	Context("when release url points to an http url", func() {
 		BeforeEach(func() {
 			fakeFs.WriteFileString(comboManifestPath, `
@@ -0,0 +1,67 @@
+package release
+
+import (
+	"strings"
+
+	birelpkg "github.com/cloudfoundry/bosh-init/release/pkg"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// StemcellCompatibility decides which stemcells a compiled package can be used with
+type StemcellCompatibility string
+
+const (
+	// StemcellCompatibilityExact requires the same stemcell OS and version the package was compiled against
+	StemcellCompatibilityExact StemcellCompatibility = "exact"
+
+	// StemcellCompatibilityMajor requires the same stemcell OS and major version (e.g. 3012.1 can use packages compiled against 3012)
+	StemcellCompatibilityMajor StemcellCompatibility = "major"
+)
+
+func ParseStemcellCompatibility(compatibility string) (StemcellCompatibility, error) {
+	switch StemcellCompatibility(compatibility) {
+	case StemcellCompatibilityExact, StemcellCompatibilityMajor:
+		return StemcellCompatibility(compatibility), nil
+	}
+	return "", bosherr.Errorf("Unknown stemcell compatibility '%s', expected '%s' or '%s'", compatibility, StemcellCompatibilityExact, StemcellCompatibilityMajor)
+}
+
+// IsCompatible returns true if a package compiled against compiledOsAndVersion can be used on stemcellOsAndVersion.
+// Both are '<os>/<version>'.
+func (c StemcellCompatibility) IsCompatible(compiledOsAndVersion string, stemcellOsAndVersion string) bool {
+	compiledOs, compiledVersion := splitOsAndVersion(compiledOsAndVersion)
+	stemcellOs, stemcellVersion := splitOsAndVersion(stemcellOsAndVersion)
+
+	if compiledOs != stemcellOs {
+		return false
+	}
+
+	if c == StemcellCompatibilityMajor {
+		return majorVersion(compiledVersion) == majorVersion(stemcellVersion)
+	}
+
+	return compiledVersion == stemcellVersion
+}
+
+// IncompatiblePackages returns the compiled packages of the release that can not be used on the stemcell
+func (c StemcellCompatibility) IncompatiblePackages(release Release, stemcellOsAndVersion string) []*birelpkg.Package {
+	incompatiblePackages := []*birelpkg.Package{}
+	for _, pkg := range release.Packages() {
+		if pkg.IsCompiled() && !c.IsCompatible(pkg.Stemcell, stemcellOsAndVersion) {
+			incompatiblePackages = append(incompatiblePackages, pkg)
+		}
+	}
+	return incompatiblePackages
+}
+
+func splitOsAndVersion(osAndVersion string) (string, string) {
+	parts := strings.SplitN(strings.ToLower(osAndVersion), "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func majorVersion(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
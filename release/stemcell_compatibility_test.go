@@ -0,0 +1,60 @@
+package release_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/release"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	birelpkg "github.com/cloudfoundry/bosh-init/release/pkg"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("StemcellCompatibility", func() {
+	Describe("ParseStemcellCompatibility", func() {
+		It("parses the known policies", func() {
+			Expect(ParseStemcellCompatibility("exact")).To(Equal(StemcellCompatibilityExact))
+			Expect(ParseStemcellCompatibility("major")).To(Equal(StemcellCompatibilityMajor))
+		})
+
+		It("returns an error for an unknown policy", func() {
+			_, err := ParseStemcellCompatibility("fake-policy")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Unknown stemcell compatibility 'fake-policy', expected 'exact' or 'major'"))
+		})
+	})
+
+	Describe("IsCompatible", func() {
+		It("requires the same OS and version with the exact policy", func() {
+			Expect(StemcellCompatibilityExact.IsCompatible("ubuntu-trusty/3012", "Ubuntu-Trusty/3012")).To(BeTrue())
+			Expect(StemcellCompatibilityExact.IsCompatible("ubuntu-trusty/3012", "ubuntu-trusty/3012.1")).To(BeFalse())
+			Expect(StemcellCompatibilityExact.IsCompatible("ubuntu-trusty/3012", "centos-7/3012")).To(BeFalse())
+		})
+
+		It("requires the same OS and major version with the major policy", func() {
+			Expect(StemcellCompatibilityMajor.IsCompatible("ubuntu-trusty/3012", "ubuntu-trusty/3012.1")).To(BeTrue())
+			Expect(StemcellCompatibilityMajor.IsCompatible("ubuntu-trusty/3012.2", "ubuntu-trusty/3012.1")).To(BeTrue())
+			Expect(StemcellCompatibilityMajor.IsCompatible("ubuntu-trusty/3012", "ubuntu-trusty/3013")).To(BeFalse())
+			Expect(StemcellCompatibilityMajor.IsCompatible("ubuntu-trusty/3012", "centos-7/3012.1")).To(BeFalse())
+		})
+	})
+
+	Describe("IncompatiblePackages", func() {
+		It("returns every compiled package that can not be used on the stemcell", func() {
+			compatiblePackage := &birelpkg.Package{Name: "fake-package-1", Stemcell: "ubuntu-trusty/3012"}
+			incompatiblePackage := &birelpkg.Package{Name: "fake-package-2", Stemcell: "ubuntu-trusty/3013"}
+			sourcePackage := &birelpkg.Package{Name: "fake-package-3"}
+			release := NewRelease(
+				"fake-release-name",
+				"fake-release-version",
+				nil,
+				[]*birelpkg.Package{compatiblePackage, incompatiblePackage, sourcePackage},
+				"/some/release/path",
+				fakesys.NewFakeFileSystem(),
+				true,
+			)
+
+			Expect(StemcellCompatibilityExact.IncompatiblePackages(release, "ubuntu-trusty/3012")).To(Equal([]*birelpkg.Package{incompatiblePackage}))
+			Expect(StemcellCompatibilityMajor.IncompatiblePackages(release, "ubuntu-trusty/3013.1")).To(Equal([]*birelpkg.Package{compatiblePackage}))
+		})
+	})
+})
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"path"
-	"sort"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -118,7 +117,6 @@ func (v *validator) validateReleaseJobs(release Release) error {
 
 func (v *validator) validateReleasePackages(release Release) error {
 	errs := []error{}
-	stemcells := map[string]string{}
 
 	for _, pkg := range release.Packages() {
 		if pkg.Name == "" {
@@ -133,22 +131,10 @@ func (v *validator) validateReleasePackages(release Release) error {
 			errs = append(errs, fmt.Errorf("Package '%s' sha1 is missing", pkg.Name))
 		}
 
-		if release.IsCompiled() {
-			if pkg.Stemcell == "" {
-				errs = append(errs, fmt.Errorf("Compiled package '%s' stemcell is missing", pkg.Name))
-			} else {
-				stemcells[pkg.Stemcell] = pkg.Stemcell
-			}
-		}
-	}
-
-	if release.IsCompiled() && len(stemcells) > 1 {
-		keys := []string{}
-		for k := range stemcells {
-			keys = append(keys, k)
+		// each compiled package is checked against the deployment stemcell when deploying
+		if release.IsCompiled() && pkg.Stemcell == "" {
+			errs = append(errs, fmt.Errorf("Compiled package '%s' stemcell is missing", pkg.Name))
 		}
-		sort.Strings(keys)
-		errs = append(errs, fmt.Errorf("Packages were compiled against different stemcells: %v", keys))
 	}
 
 	if len(errs) > 0 {
@@ -239,7 +239,7 @@ var _ = Describe("Validator", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("returns an error if compiled pacakges stemcell versions are different", func() {
+		It("allows compiled packages to be compiled against different stemcells", func() {
 			fakeFs.WriteFileString("/some/job/path/monit", "")
 			fakeFs.WriteFileString("/some/job/path/templates/fake-job-1-template", "")
 			release := NewRelease(
@@ -283,8 +283,7 @@ var _ = Describe("Validator", func() {
 			validator := NewValidator(fakeFs)
 
 			err := validator.Validate(release)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("Packages were compiled against different stemcells: [fake-compiled-package-stemcell-1/1 fake-compiled-package-stemcell-2/2]"))
+			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("returns an  error if a compiled pacakge stemcell field is empty", func() {
@@ -143,10 +143,10 @@ var _ = Describe("CompiledPackageRepo", func() {
 
 		Context("when reading from index fails", func() {
 			It("returns error", func() {
-				err := compiledPackageRepo.Save(pkg, record)
+				fakeFS.WriteFileString("/index_file", "[]")
 				fakeFS.ReadFileError = errors.New("fake-error")
 
-				_, _, err = compiledPackageRepo.Find(pkg)
+				_, _, err := compiledPackageRepo.Find(pkg)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("Finding compiled package"))
 			})
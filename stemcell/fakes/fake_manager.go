@@ -16,6 +16,9 @@ type FakeManager struct {
 
 	DeleteUnusedCalledTimes int
 	DeleteUnusedErr         error
+
+	DeleteUnusedExceptLatestCalledTimes int
+	DeleteUnusedExceptLatestErr         error
 }
 
 type UploadInput struct {
@@ -67,6 +70,11 @@ func (m *FakeManager) DeleteUnused(eventLoggerStage biui.Stage) error {
 	return m.DeleteUnusedErr
 }
 
+func (m *FakeManager) DeleteUnusedExceptLatest(eventLoggerStage biui.Stage) error {
+	m.DeleteUnusedExceptLatestCalledTimes++
+	return m.DeleteUnusedExceptLatestErr
+}
+
 func (m *FakeManager) SetUploadBehavior(
 	extractedStemcell bistemcell.ExtractedStemcell,
 	stage biui.Stage,
@@ -14,6 +14,7 @@ type Manager interface {
 	Upload(ExtractedStemcell, biui.Stage) (CloudStemcell, error)
 	FindUnused() ([]CloudStemcell, error)
 	DeleteUnused(biui.Stage) error
+	DeleteUnusedExceptLatest(biui.Stage) error
 }
 
 type manager struct {
@@ -50,6 +51,10 @@ func (m *manager) FindCurrent() ([]CloudStemcell, error) {
 func (m *manager) Upload(extractedStemcell ExtractedStemcell, uploadStage biui.Stage) (cloudStemcell CloudStemcell, err error) {
 	manifest := extractedStemcell.Manifest()
 	stageName := fmt.Sprintf("Uploading stemcell '%s/%s'", manifest.Name, manifest.Version)
+	if manifest.IsLight() {
+		// the CPI only looks up the cloud image referenced by the cloud properties
+		stageName = fmt.Sprintf("Registering light stemcell '%s/%s'", manifest.Name, manifest.Version)
+	}
 	err = uploadStage.Perform(stageName, func() error {
 		foundStemcellRecord, found, err := m.repo.Find(manifest.Name, manifest.Version)
 		if err != nil {
@@ -111,9 +116,28 @@ func (m *manager) DeleteUnused(deleteStage biui.Stage) error {
 		return bosherr.WrapError(err, "Finding unused stemcells")
 	}
 
+	return m.deleteStemcells(stemcells, deleteStage)
+}
+
+// DeleteUnusedExceptLatest deletes the unused stemcells, except the one uploaded last,
+// which may have been uploaded ahead of the next deploy
+func (m *manager) DeleteUnusedExceptLatest(deleteStage biui.Stage) error {
+	stemcells, err := m.FindUnused()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding unused stemcells")
+	}
+
+	if len(stemcells) == 0 {
+		return nil
+	}
+
+	return m.deleteStemcells(stemcells[:len(stemcells)-1], deleteStage)
+}
+
+func (m *manager) deleteStemcells(stemcells []CloudStemcell, deleteStage biui.Stage) error {
 	for _, stemcell := range stemcells {
 		stepName := fmt.Sprintf("Deleting unused stemcell '%s'", stemcell.CID())
-		err = deleteStage.Perform(stepName, func() error {
+		err := deleteStage.Perform(stepName, func() error {
 			err := stemcell.Delete()
 			cloudErr, ok := err.(bicloud.Error)
 			if ok && cloudErr.Type() == bicloud.StemcellNotFoundError {
@@ -118,6 +118,32 @@ var _ = Describe("Manager", func() {
 			}))
 		})
 
+		It("prints a registering ui stage for a light stemcell", func() {
+			lightStemcell := NewExtractedStemcell(
+				Manifest{
+					Name:            "fake-stemcell-name",
+					Version:         "fake-stemcell-version",
+					ImagePath:       "fake-image-path",
+					StemcellFormats: []string{"aws-light"},
+					CloudProperties: biproperty.Map{
+						"ami": biproperty.Map{"us-east-1": "fake-ami"},
+					},
+				},
+				tempExtractionDir,
+				fs,
+			)
+
+			_, err := manager.Upload(lightStemcell, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Registering light stemcell 'fake-stemcell-name/fake-stemcell-version'"},
+			}))
+			Expect(fakeCloud.CreateStemcellInputs[0].CloudProperties).To(Equal(biproperty.Map{
+				"ami": biproperty.Map{"us-east-1": "fake-ami"},
+			}))
+		})
+
 		It("when the upload fails, prints failed uploading ui stage", func() {
 			fakeCloud.CreateStemcellErr = errors.New("fake-create-error")
 			_, err := manager.Upload(expectedExtractedStemcell, fakeStage)
@@ -130,7 +156,7 @@ var _ = Describe("Manager", func() {
 		})
 
 		It("when the stemcellRepo save fails, logs uploading start and failure events to the eventLogger", func() {
-			fs.WriteFileError = errors.New("fake-save-error")
+			fs.OpenFileErr = errors.New("fake-save-error")
 			_, err := manager.Upload(expectedExtractedStemcell, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-save-error"))
@@ -294,5 +320,26 @@ var _ = Describe("Manager", func() {
 				secondStemcellRecord,
 			}))
 		})
+
+		Describe("DeleteUnusedExceptLatest", func() {
+			It("keeps the unused stemcell uploaded last", func() {
+				err := manager.DeleteUnusedExceptLatest(fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCloud.DeleteStemcellInputs).To(Equal([]fakebicloud.DeleteStemcellInput{
+					{StemcellCID: "fake-stemcell-cid-1"},
+				}))
+
+				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+					{Name: "Deleting unused stemcell 'fake-stemcell-cid-1'"},
+				}))
+
+				records, err := stemcellRepo.All()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(HaveLen(2))
+				Expect(records[0]).To(Equal(secondStemcellRecord))
+				Expect(records[1].CID).To(Equal("fake-stemcell-cid-3"))
+			})
+		})
 	})
 })
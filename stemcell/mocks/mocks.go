@@ -111,6 +111,16 @@ func (_mr *_MockManagerRecorder) DeleteUnused(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteUnused", arg0)
 }
 
+func (_m *MockManager) DeleteUnusedExceptLatest(_param0 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "DeleteUnusedExceptLatest", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockManagerRecorder) DeleteUnusedExceptLatest(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteUnusedExceptLatest", arg0)
+}
+
 func (_m *MockManager) FindCurrent() ([]stemcell.CloudStemcell, error) {
 	ret := _m.ctrl.Call(_m, "FindCurrent")
 	ret0, _ := ret[0].([]stemcell.CloudStemcell)
@@ -1,20 +1,30 @@
 package stemcell
 
 import (
-	"github.com/pivotal-golang/yaml"
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
+	"github.com/pivotal-golang/yaml"
+
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+const stemcellManifestName = "stemcell.MF"
+
 type manifest struct {
 	Name            string
 	Version         string
 	OS              string `yaml:"operating_system"`
 	SHA1            string
+	StemcellFormats []string                    `yaml:"stemcell_formats"`
 	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
 }
 
@@ -25,28 +35,28 @@ type Reader interface {
 }
 
 type reader struct {
-	compressor boshcmd.Compressor
-	fs         boshsys.FileSystem
+	compressor     boshcmd.Compressor
+	fs             boshsys.FileSystem
+	digestVerifier bicrypto.DigestVerifier
 }
 
-func NewReader(compressor boshcmd.Compressor, fs boshsys.FileSystem) Reader {
-	return reader{compressor: compressor, fs: fs}
+func NewReader(compressor boshcmd.Compressor, fs boshsys.FileSystem, digestVerifier bicrypto.DigestVerifier) Reader {
+	return reader{
+		compressor:     compressor,
+		fs:             fs,
+		digestVerifier: digestVerifier,
+	}
 }
 
+// Read parses the stemcell manifest straight from the tarball, before extracting it.
+// Only the manifest of a light stemcell is extracted, its image is a placeholder for the cloud image.
 func (s reader) Read(stemcellTarballPath string, extractedPath string) (ExtractedStemcell, error) {
-	err := s.compressor.DecompressFileToDir(stemcellTarballPath, extractedPath, boshcmd.CompressorOptions{})
+	manifestContents, err := s.readManifestFromTarball(stemcellTarballPath)
 	if err != nil {
-		return nil, bosherr.WrapErrorf(err, "Extracting stemcell from '%s' to '%s'", stemcellTarballPath, extractedPath)
+		return nil, err
 	}
 
 	var rawManifest manifest
-	manifestPath := filepath.Join(extractedPath, "stemcell.MF")
-
-	manifestContents, err := s.fs.ReadFile(manifestPath)
-	if err != nil {
-		return nil, bosherr.WrapErrorf(err, "Reading stemcell manifest '%s'", manifestPath)
-	}
-
 	err = yaml.Unmarshal(manifestContents, &rawManifest)
 	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Parsing stemcell manifest: %s", manifestContents)
@@ -57,6 +67,8 @@ func (s reader) Read(stemcellTarballPath string, extractedPath string) (Extracte
 		Version: rawManifest.Version,
 		OS:      rawManifest.OS,
 		SHA1:    rawManifest.SHA1,
+
+		StemcellFormats: rawManifest.StemcellFormats,
 	}
 
 	cloudProperties, err := biproperty.BuildMap(rawManifest.CloudProperties)
@@ -67,6 +79,28 @@ func (s reader) Read(stemcellTarballPath string, extractedPath string) (Extracte
 
 	manifest.ImagePath = filepath.Join(extractedPath, "image")
 
+	if manifest.IsLight() {
+		if len(manifest.CloudProperties) == 0 {
+			return nil, bosherr.Errorf("Light stemcell '%s/%s' must reference a cloud image in its cloud_properties", manifest.Name, manifest.Version)
+		}
+
+		manifestPath := filepath.Join(extractedPath, stemcellManifestName)
+		err = s.fs.WriteFile(manifestPath, manifestContents)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Writing stemcell manifest '%s'", manifestPath)
+		}
+	} else {
+		err = s.compressor.DecompressFileToDir(stemcellTarballPath, extractedPath, boshcmd.CompressorOptions{})
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Extracting stemcell from '%s' to '%s'", stemcellTarballPath, extractedPath)
+		}
+
+		err = s.verifyImage(manifest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	stemcell := NewExtractedStemcell(
 		manifest,
 		extractedPath,
@@ -75,3 +109,58 @@ func (s reader) Read(stemcellTarballPath string, extractedPath string) (Extracte
 
 	return stemcell, nil
 }
+
+// readManifestFromTarball returns the contents of the stemcell.MF in the stemcell tarball, without extracting the tarball
+func (s reader) readManifestFromTarball(stemcellTarballPath string) ([]byte, error) {
+	file, err := s.fs.OpenFile(stemcellTarballPath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Opening stemcell tarball '%s'", stemcellTarballPath)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading stemcell tarball '%s'", stemcellTarballPath)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, bosherr.Errorf("Stemcell tarball '%s' does not contain a %s", stemcellTarballPath, stemcellManifestName)
+		}
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading stemcell tarball '%s'", stemcellTarballPath)
+		}
+
+		if filepath.Clean(header.Name) != stemcellManifestName {
+			continue
+		}
+
+		manifestContents, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading %s from stemcell tarball '%s'", stemcellManifestName, stemcellTarballPath)
+		}
+
+		return manifestContents, nil
+	}
+}
+
+// verifyImage checks the image against the SHA1 or SHA256 in the stemcell manifest
+func (s reader) verifyImage(manifest Manifest) error {
+	if manifest.SHA1 == "" {
+		return nil
+	}
+
+	if !s.fs.FileExists(manifest.ImagePath) {
+		return bosherr.Errorf("Stemcell image '%s' does not exist", manifest.ImagePath)
+	}
+
+	err := s.digestVerifier.Verify(manifest.ImagePath, manifest.SHA1)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Verifying stemcell image '%s'", manifest.ImagePath)
+	}
+
+	return nil
+}
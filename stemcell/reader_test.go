@@ -1,8 +1,12 @@
 package stemcell_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"errors"
 
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	. "github.com/cloudfoundry/bosh-init/stemcell"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -19,12 +23,28 @@ var _ = Describe("Reader", func() {
 		fs             *fakesys.FakeFileSystem
 	)
 
+	// writeStemcellTarball writes a stemcell tarball that only contains the given stemcell.MF
+	var writeStemcellTarball = func(manifestContents string) {
+		var tarball bytes.Buffer
+		gzipWriter := gzip.NewWriter(&tarball)
+		tarWriter := tar.NewWriter(gzipWriter)
+		err := tarWriter.WriteHeader(&tar.Header{Name: "./stemcell.MF", Mode: 0644, Size: int64(len(manifestContents))})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = tarWriter.Write([]byte(manifestContents))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tarWriter.Close()).To(Succeed())
+		Expect(gzipWriter.Close()).To(Succeed())
+
+		fs.WriteFile("fake-stemcell-path", tarball.Bytes())
+	}
+
 	BeforeEach(func() {
 		compressor = fakecmd.NewFakeCompressor()
 		fs = fakesys.NewFakeFileSystem()
-		stemcellReader = NewReader(compressor, fs)
+		digestVerifier := bicrypto.NewDigestVerifier(bicrypto.NewSha1Calculator(fs), bicrypto.NewSha256Calculator(fs))
+		stemcellReader = NewReader(compressor, fs, digestVerifier)
 
-		manifestContents := `
+		writeStemcellTarball(`
 ---
 name: fake-stemcell-name
 version: '2690'
@@ -32,8 +52,7 @@ cloud_properties:
   infrastructure: aws
   ami:
     us-east-1: fake-ami-version
-    `
-		fs.WriteFileString("fake-extracted-path/stemcell.MF", manifestContents)
+    `)
 	})
 
 	It("extracts the stemcells from a stemcell path", func() {
@@ -64,6 +83,95 @@ cloud_properties:
 		Expect(stemcell).To(Equal(expectedStemcell))
 	})
 
+	Context("when the stemcell manifest has a sha1", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("fake-extracted-path/image", "fake-image-contents")
+		})
+
+		var writeManifestWithSHA1 = func(sha1 string) {
+			writeStemcellTarball(`---
+name: fake-stemcell-name
+version: '2690'
+sha1: ` + sha1 + `
+`)
+		}
+
+		It("verifies the image against a SHA1", func() {
+			writeManifestWithSHA1("f8e9d8968ee3c0b6d088a2f9d60fba05ca06f705")
+
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("verifies the image against a SHA256", func() {
+			writeManifestWithSHA1("sha256:b79d1ea51883a80a66b40ebd473c210509d2bba21ff3981ff2be56b39866cbe1")
+
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when the image does not match", func() {
+			writeManifestWithSHA1("fake-sha1")
+
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Verifying stemcell image 'fake-extracted-path/image'"))
+			Expect(err.Error()).To(ContainSubstring("does not match expected SHA1 'fake-sha1'"))
+		})
+
+		It("returns an error when the image does not exist", func() {
+			writeManifestWithSHA1("fake-sha1")
+			fs.RemoveAll("fake-extracted-path/image")
+
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Stemcell image 'fake-extracted-path/image' does not exist"))
+		})
+	})
+
+	Context("when the stemcell is light", func() {
+		BeforeEach(func() {
+			writeStemcellTarball(`---
+name: fake-stemcell-name
+version: '2690'
+sha1: fake-placeholder-sha1
+stemcell_formats: [aws-light]
+cloud_properties:
+  ami:
+    us-east-1: fake-ami-version
+`)
+		})
+
+		It("reads the stemcell formats", func() {
+			stemcell, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stemcell.Manifest().StemcellFormats).To(Equal([]string{"aws-light"}))
+			Expect(stemcell.Manifest().IsLight()).To(BeTrue())
+		})
+
+		It("extracts only the stemcell manifest and does not verify the placeholder image", func() {
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compressor.DecompressFileToDirTarballPaths).To(BeEmpty())
+
+			manifestContents, err := fs.ReadFileString("fake-extracted-path/stemcell.MF")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifestContents).To(ContainSubstring("stemcell_formats: [aws-light]"))
+		})
+
+		It("returns an error when it does not reference a cloud image", func() {
+			writeStemcellTarball(`---
+name: fake-stemcell-name
+version: '2690'
+stemcell_formats: [aws-light]
+`)
+
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Light stemcell 'fake-stemcell-name/2690' must reference a cloud image in its cloud_properties"))
+		})
+	})
+
 	Context("when extracting stemcell fails", func() {
 		BeforeEach(func() {
 			compressor.DecompressFileToDirErr = errors.New("fake-decompress-error")
@@ -76,21 +184,37 @@ cloud_properties:
 		})
 	})
 
-	Context("when reading stemcell manifest fails", func() {
+	Context("when opening the stemcell tarball fails", func() {
+		BeforeEach(func() {
+			fs.OpenFileErr = errors.New("fake-open-error")
+		})
+
+		It("returns an error", func() {
+			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-open-error"))
+		})
+	})
+
+	Context("when the stemcell tarball has no stemcell manifest", func() {
 		BeforeEach(func() {
-			fs.ReadFileError = errors.New("fake-read-error")
+			var tarball bytes.Buffer
+			gzipWriter := gzip.NewWriter(&tarball)
+			Expect(tar.NewWriter(gzipWriter).Close()).To(Succeed())
+			Expect(gzipWriter.Close()).To(Succeed())
+			fs.WriteFile("fake-stemcell-path", tarball.Bytes())
 		})
 
 		It("returns an error", func() {
 			_, err := stemcellReader.Read("fake-stemcell-path", "fake-extracted-path")
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("fake-read-error"))
+			Expect(err.Error()).To(Equal("Stemcell tarball 'fake-stemcell-path' does not contain a stemcell.MF"))
 		})
 	})
 
 	Context("when parsing stemcell manifest fails", func() {
 		BeforeEach(func() {
-			fs.WriteFileString("fake-extracted-path/stemcell.MF", "<not-a-yaml>")
+			writeStemcellTarball("<not-a-yaml>")
 		})
 
 		It("returns an error", func() {
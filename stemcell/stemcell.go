@@ -2,6 +2,7 @@ package stemcell
 
 import (
 	"fmt"
+	"strings"
 
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -52,5 +53,17 @@ type Manifest struct {
 	Version         string
 	OS              string
 	SHA1            string
+	StemcellFormats []string
 	CloudProperties biproperty.Map
 }
+
+// IsLight returns true for a light stemcell, whose image is a reference to a cloud image that
+// was uploaded in advance, e.g. an AMI. It does not need to be uploaded by the CPI.
+func (m Manifest) IsLight() bool {
+	for _, format := range m.StemcellFormats {
+		if strings.HasSuffix(format, "-light") {
+			return true
+		}
+	}
+	return false
+}
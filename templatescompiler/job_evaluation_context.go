@@ -17,6 +17,7 @@ type jobEvaluationContext struct {
 	globalProperties     biproperty.Map
 	deploymentName       string
 	address              string
+	persistentDiskNames  []string
 	logger               boshlog.Logger
 	logTag               string
 }
@@ -32,6 +33,9 @@ type RootContext struct {
 	Deployment string     `json:"deployment"`
 	Address    string     `json:"address,omitempty"`
 
+	// Names of the job's persistent disks, e.g. <% spec.persistent_disks.each do |name| %>
+	PersistentDisks []string `json:"persistent_disks"`
+
 	// Usually is accessed with <%= spec.networks.default.ip %>
 	NetworkContexts map[string]networkContext `json:"networks"`
 
@@ -59,6 +63,7 @@ func NewJobEvaluationContext(
 	globalProperties biproperty.Map,
 	deploymentName string,
 	address string,
+	persistentDiskNames []string,
 	logger boshlog.Logger,
 ) bierbrenderer.TemplateEvaluationContext {
 	return jobEvaluationContext{
@@ -68,6 +73,7 @@ func NewJobEvaluationContext(
 		globalProperties:     globalProperties,
 		deploymentName:       deploymentName,
 		address:              address,
+		persistentDiskNames:  persistentDiskNames,
 		logger:               logger,
 		logTag:               "jobEvaluationContext",
 	}
@@ -88,6 +94,11 @@ func (ec jobEvaluationContext) MarshalJSON() ([]byte, error) {
 		ClusterProperties: ec.jobProperties,
 		JobProperties:     ec.releaseJobProperties,
 		DefaultProperties: defaultProperties,
+		PersistentDisks:   []string{},
+	}
+
+	if ec.persistentDiskNames != nil {
+		context.PersistentDisks = ec.persistentDiskNames
 	}
 
 	if len(ec.address) > 0 {
@@ -24,6 +24,7 @@ var _ = Describe("JobEvaluationContext", func() {
 		jobProperties           *biproperty.Map
 		instanceGroupProperties biproperty.Map
 		deploymentProperties    biproperty.Map
+		persistentDiskNames     []string
 	)
 	BeforeEach(func() {
 		generatedContext = RootContext{}
@@ -45,6 +46,8 @@ var _ = Describe("JobEvaluationContext", func() {
 		instanceGroupProperties = biproperty.Map{}
 
 		jobProperties = nil
+
+		persistentDiskNames = nil
 	})
 
 	JustBeforeEach(func() {
@@ -57,6 +60,7 @@ var _ = Describe("JobEvaluationContext", func() {
 			deploymentProperties,
 			"fake-deployment-name",
 			"1.2.3.4",
+			persistentDiskNames,
 			logger,
 		)
 
@@ -87,6 +91,20 @@ var _ = Describe("JobEvaluationContext", func() {
 		Expect(generatedContext.Bootstrap).To(Equal(true))
 	})
 
+	It("it has no persistent disks in the spec by default", func() {
+		Expect(generatedContext.PersistentDisks).To(Equal([]string{}))
+	})
+
+	Context("when the job has named persistent disks", func() {
+		BeforeEach(func() {
+			persistentDiskNames = []string{"data", "logs"}
+		})
+
+		It("it has the persistent disk names available in the spec", func() {
+			Expect(generatedContext.PersistentDisks).To(Equal([]string{"data", "logs"}))
+		})
+	})
+
 	var erbRenderer erbrenderer.ERBRenderer
 	getValueFor := func(key string) string {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
@@ -115,6 +133,7 @@ var _ = Describe("JobEvaluationContext", func() {
 			deploymentProperties,
 			"fake-deployment-name",
 			"1.2.3.4",
+			persistentDiskNames,
 			logger,
 		)
 
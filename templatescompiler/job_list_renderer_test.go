@@ -36,6 +36,7 @@ var _ = Describe("JobListRenderer", func() {
 		globalProperties     biproperty.Map
 		deploymentName       string
 		address              string
+		persistentDiskNames  []string
 
 		renderedJobs []*mock_template.MockRenderedJob
 
@@ -72,6 +73,7 @@ var _ = Describe("JobListRenderer", func() {
 
 		deploymentName = "fake-deployment-name"
 		address = "1.2.3.4"
+		persistentDiskNames = []string{"fake-disk-name"}
 
 		renderedJobs = []*mock_template.MockRenderedJob{
 			mock_template.NewMockRenderedJob(mockCtrl),
@@ -82,13 +84,13 @@ var _ = Describe("JobListRenderer", func() {
 	})
 
 	JustBeforeEach(func() {
-		mockJobRenderer.EXPECT().Render(releaseJobs[0], releaseJobProperties[releaseJobs[0].Name], jobProperties, globalProperties, deploymentName, address).Return(renderedJobs[0], nil)
-		expectRender1 = mockJobRenderer.EXPECT().Render(releaseJobs[1], releaseJobProperties[releaseJobs[1].Name], jobProperties, globalProperties, deploymentName, address).Return(renderedJobs[1], nil)
+		mockJobRenderer.EXPECT().Render(releaseJobs[0], releaseJobProperties[releaseJobs[0].Name], jobProperties, globalProperties, deploymentName, address, persistentDiskNames).Return(renderedJobs[0], nil)
+		expectRender1 = mockJobRenderer.EXPECT().Render(releaseJobs[1], releaseJobProperties[releaseJobs[1].Name], jobProperties, globalProperties, deploymentName, address, persistentDiskNames).Return(renderedJobs[1], nil)
 	})
 
 	Describe("Render", func() {
 		It("returns a new RenderedJobList with all the RenderedJobs", func() {
-			renderedJobList, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+			renderedJobList, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, persistentDiskNames)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(renderedJobList.All()).To(Equal([]RenderedJob{
 				renderedJobs[0],
@@ -104,7 +106,7 @@ var _ = Describe("JobListRenderer", func() {
 			It("returns an error and cleans up any sucessfully rendered jobs", func() {
 				renderedJobs[0].EXPECT().DeleteSilently()
 
-				_, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+				_, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, persistentDiskNames)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-render-error"))
 			})
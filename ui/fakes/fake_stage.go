@@ -7,6 +7,9 @@ import (
 type FakeStage struct {
 	PerformCalls []*PerformCall
 	SubStages    []*FakeStage
+
+	// Interrupt is returned by InterruptCh, closing it interrupts the waits of the stage
+	Interrupt chan struct{}
 }
 
 type PerformCall struct {
@@ -43,8 +46,13 @@ func (s *FakeStage) Perform(name string, closure func() error) error {
 	return err
 }
 
+func (s *FakeStage) InterruptCh() <-chan struct{} {
+	return s.Interrupt
+}
+
 func (s *FakeStage) PerformComplex(name string, closure func(biui.Stage) error) error {
 	subStage := NewFakeStage()
+	subStage.Interrupt = s.Interrupt
 
 	// lazily instantiate to make matching simple stages easier
 	if s.SubStages == nil {
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// InterruptedError is returned by a stage that did not start a step because the process was interrupted,
+// or by a wait within a step that stopped early
+type InterruptedError struct {
+	stepName string
+	waitName string
+}
+
+func NewInterruptedError(stepName string) InterruptedError {
+	return InterruptedError{
+		stepName: stepName,
+	}
+}
+
+// NewInterruptedWaitError is returned by a wait that stopped because the process was interrupted
+func NewInterruptedWaitError(waitName string) InterruptedError {
+	return InterruptedError{
+		waitName: waitName,
+	}
+}
+
+func (e InterruptedError) Error() string {
+	if e.waitName != "" {
+		return fmt.Sprintf("Interrupted while %s", e.waitName)
+	}
+	return fmt.Sprintf("Interrupted before '%s'", e.stepName)
+}
+
+// IsInterrupted returns true if err or any error it wraps is an InterruptedError
+func IsInterrupted(err error) bool {
+	for err != nil {
+		switch typedErr := err.(type) {
+		case InterruptedError:
+			return true
+		case bosherr.ComplexError:
+			err = typedErr.Cause
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ type Stage interface {
 type stage struct {
 	ui          UI
 	timeService clock.Clock
+	interruptCh <-chan struct{}
 	logger      boshlog.Logger
 	logTag      string
 
@@ -23,16 +24,46 @@ type stage struct {
 }
 
 func NewStage(ui UI, timeService clock.Clock, logger boshlog.Logger) Stage {
+	return NewInterruptibleStage(ui, timeService, nil, logger)
+}
+
+// NewInterruptibleStage returns a stage that stops starting new steps once interruptCh is closed.
+// The step in progress is allowed to finish, so that e.g. the CID of a created VM is still recorded.
+func NewInterruptibleStage(ui UI, timeService clock.Clock, interruptCh <-chan struct{}, logger boshlog.Logger) Stage {
 	return &stage{
 		ui:          ui,
 		timeService: timeService,
+		interruptCh: interruptCh,
 		logger:      logger,
 		logTag:      "stage",
 		simpleMode:  true,
 	}
 }
 
+// InterruptCh returns the channel that is closed once the process performing the stage is interrupted, so that long waits
+// within a step can stop early. Waiting on it blocks forever for stages that are not interrupted, e.g. when cleaning up.
+func InterruptCh(s Stage) <-chan struct{} {
+	if interruptible, ok := s.(interface {
+		InterruptCh() <-chan struct{}
+	}); ok {
+		return interruptible.InterruptCh()
+	}
+	return nil
+}
+
+// Uninterruptible returns a stage that performs its steps even after an interrupt, for cleaning up
+func Uninterruptible(s Stage) Stage {
+	if interruptibleStage, ok := s.(*stage); ok {
+		return uninterruptibleStage{stage: interruptibleStage}
+	}
+	return s
+}
+
 func (s *stage) Perform(name string, closure func() error) error {
+	return s.perform(name, closure, true)
+}
+
+func (s *stage) perform(name string, closure func() error, interruptible bool) error {
 	if !s.simpleMode {
 		// enter simple mode (only line break if exiting complex mode)
 		s.ui.PrintLinef("")
@@ -40,6 +71,11 @@ func (s *stage) Perform(name string, closure func() error) error {
 	}
 
 	s.ui.BeginLinef("%s...", name)
+	if interruptible && s.isInterrupted() {
+		s.ui.EndLinef(" Interrupted")
+		return NewInterruptedError(name)
+	}
+
 	startTime := s.timeService.Now()
 	err := closure()
 	if err != nil {
@@ -56,13 +92,26 @@ func (s *stage) Perform(name string, closure func() error) error {
 }
 
 func (s *stage) PerformComplex(name string, closure func(Stage) error) error {
+	return s.performComplex(name, closure, true)
+}
+
+func (s *stage) performComplex(name string, closure func(Stage) error, interruptible bool) error {
 	// exit simple mode (always line break when entering a new complex stage)
 	s.ui.PrintLinef("")
 	s.simpleMode = false
 
+	if interruptible && s.isInterrupted() {
+		s.ui.PrintLinef("Interrupted before %s", name)
+		return NewInterruptedError(name)
+	}
+
 	s.ui.PrintLinef("Started %s", name)
 	startTime := s.timeService.Now()
-	err := closure(s.newSubStage())
+	subStage := s.newSubStage()
+	if !interruptible {
+		subStage = Uninterruptible(subStage)
+	}
+	err := closure(subStage)
 	if err != nil {
 		s.ui.PrintLinef("Failed %s (%s)", name, s.elapsedSince(startTime))
 		return err
@@ -77,6 +126,32 @@ func (s *stage) elapsedSince(startTime time.Time) string {
 	return biuifmt.Duration(duration)
 }
 
+func (s *stage) isInterrupted() bool {
+	select {
+	case <-s.interruptCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// InterruptCh returns the channel that is closed once the process is interrupted
+func (s *stage) InterruptCh() <-chan struct{} {
+	return s.interruptCh
+}
+
 func (s *stage) newSubStage() Stage {
-	return NewStage(NewIndentingUI(s.ui), s.timeService, s.logger)
+	return NewInterruptibleStage(NewIndentingUI(s.ui), s.timeService, s.interruptCh, s.logger)
+}
+
+type uninterruptibleStage struct {
+	stage *stage
+}
+
+func (s uninterruptibleStage) Perform(name string, closure func() error) error {
+	return s.stage.perform(name, closure, false)
+}
+
+func (s uninterruptibleStage) PerformComplex(name string, closure func(Stage) error) error {
+	return s.stage.performComplex(name, closure, false)
 }
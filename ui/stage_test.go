@@ -203,4 +203,100 @@ Failed Complex stage 1 (00:01:00)
 			Expect(actionsPerformed).To(Equal([]string{"1"}))
 		})
 	})
+
+	Describe("when interrupted", func() {
+		var interruptCh chan struct{}
+
+		BeforeEach(func() {
+			interruptCh = make(chan struct{})
+			stage = NewInterruptibleStage(ui, fakeTimeService, interruptCh, logger)
+		})
+
+		It("finishes the step in progress and does not start the next step", func() {
+			actionsPerformed := []string{}
+
+			err := stage.PerformComplex("Complex stage 1", func(stage Stage) error {
+				err := stage.Perform("Simple stage A", func() error {
+					actionsPerformed = append(actionsPerformed, "A")
+					close(interruptCh)
+					fakeTimeService.Increment(time.Minute)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				return stage.Perform("Simple stage B", func() error {
+					actionsPerformed = append(actionsPerformed, "B")
+					return nil
+				})
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(Equal(NewInterruptedError("Simple stage B")))
+			Expect(err.Error()).To(Equal("Interrupted before 'Simple stage B'"))
+
+			expectedOutput := `
+Started Complex stage 1
+  Simple stage A... Finished (00:01:00)
+  Simple stage B... Interrupted
+Failed Complex stage 1 (00:01:00)
+`
+			Expect(uiOut.String()).To(Equal(expectedOutput))
+			Expect(actionsPerformed).To(Equal([]string{"A"}))
+		})
+
+		It("does not start a complex stage", func() {
+			close(interruptCh)
+
+			err := stage.PerformComplex("Complex stage 1", func(stage Stage) error {
+				Fail("should not be performed")
+				return nil
+			})
+			Expect(err).To(Equal(NewInterruptedError("Complex stage 1")))
+			Expect(uiOut.String()).To(Equal("\nInterrupted before Complex stage 1\n"))
+		})
+
+		It("performs the steps of an uninterruptible stage, e.g. to clean up", func() {
+			close(interruptCh)
+			actionsPerformed := []string{}
+
+			err := Uninterruptible(stage).PerformComplex("Complex stage 1", func(stage Stage) error {
+				return stage.Perform("Simple stage A", func() error {
+					actionsPerformed = append(actionsPerformed, "A")
+					return nil
+				})
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actionsPerformed).To(Equal([]string{"A"}))
+		})
+
+		It("gives the steps the interrupt channel to stop long waits, except for uninterruptible stages", func() {
+			err := stage.PerformComplex("Complex stage 1", func(stage Stage) error {
+				Expect(InterruptCh(stage)).ToNot(BeClosed())
+				close(interruptCh)
+				Expect(InterruptCh(stage)).To(BeClosed())
+				Expect(InterruptCh(Uninterruptible(stage))).To(BeNil())
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("IsInterrupted", func() {
+	It("finds an interrupted error wrapped by other errors", func() {
+		err := bosherr.WrapError(bosherr.WrapError(NewInterruptedError("fake-step"), "fake-inner"), "fake-outer")
+		Expect(IsInterrupted(err)).To(BeTrue())
+	})
+
+	It("finds an interrupted wait", func() {
+		err := bosherr.WrapError(NewInterruptedWaitError("waiting for the agent"), "fake-outer")
+		Expect(IsInterrupted(err)).To(BeTrue())
+		Expect(err.Error()).To(Equal("fake-outer: Interrupted while waiting for the agent"))
+	})
+
+	It("returns false for other errors", func() {
+		Expect(IsInterrupted(bosherr.WrapError(bosherr.Error("fake-cause"), "fake-outer"))).To(BeFalse())
+		Expect(IsInterrupted(nil)).To(BeFalse())
+	})
 })